@@ -2,19 +2,30 @@ package basic
 
 import "time"
 
-//go:generate go run ../../../sudo-gen layerbroker -tests -json
+//go:generate go run github.com/bobcob7/sudo-gen layerbroker -tests -json -merge-patch -json-patch -http -sighup -provenance -audit -history=10 -groups=defaults,file,env -redact -constant-time-secrets -bench -examples
+//go:generate go run github.com/bobcob7/sudo-gen defaults -tests
+//go:generate go run github.com/bobcob7/sudo-gen options -validate=ValidateEnums -tests
+//go:generate go run github.com/bobcob7/sudo-gen flagvalue -tests
+//go:generate go run github.com/bobcob7/sudo-gen flags -tests
+//go:generate go run github.com/bobcob7/sudo-gen hash -tests
+//go:generate go run github.com/bobcob7/sudo-gen canonical -tests
+//go:generate go run github.com/bobcob7/sudo-gen manager -tests
+//go:generate go run github.com/bobcob7/sudo-gen context -tenant -tests
+//go:generate go run github.com/bobcob7/sudo-gen envdoc -tests
+//go:generate go run github.com/bobcob7/sudo-gen enum -tests
 type Config struct {
 	// Basic types
-	Name        string  `json:"name,omitempty"`
-	Port        int     `json:"port,omitempty"`
-	MaxRetries  int32   `json:"max_retries,omitempty"`
+	Name        string  `json:"name,omitempty" env:"APP_NAME"`                                     // Service name in logs. Default: "app"
+	Port        int     `json:"port,omitempty" default:"8080" env:"PORT" sudo:"flag=service.port"` // Port to listen on
+	MaxRetries  int32   `json:"max_retries,omitempty" default:"3" env:"MAX_RETRIES"`
 	Timeout     int64   `json:"timeout,omitempty"`
-	Rate        float64 `json:"rate,omitempty"`
-	Enabled     bool    `json:"enabled,omitempty"`
+	Rate        float64 `json:"rate,omitempty" default:"0.5" sudo:"epsilon=1e-9"`
+	Enabled     bool    `json:"enabled,omitempty" sudo:"flag=service.enabled"`
 	Description *string `json:"description,omitempty"`
+	LogLevel    string  `json:"log_level,omitempty" default:"info" env:"LOG_LEVEL" sudo:"enum=debug|info|warn|error"`
 
 	// Slice types
-	Hosts []string `json:"hosts,omitempty"`
+	Hosts []string `json:"hosts,omitempty" default:"localhost" env:"HOSTS"` // Comma-separated
 	Tags  []Tag    `json:"tags,omitempty"`
 
 	// Map types
@@ -22,7 +33,7 @@ type Config struct {
 	Metadata map[string]any    `json:"metadata,omitempty"`
 
 	// Nested struct
-	Database *DatabaseConfig `json:"database,omitempty"`
+	Database *DatabaseConfig `json:"database,omitempty" envPrefix:"DB_"`
 
 	// Time
 	CreatedAt time.Time  `json:"created_at,omitempty"`
@@ -31,13 +42,23 @@ type Config struct {
 
 // DatabaseConfig represents database connection settings.
 type DatabaseConfig struct {
-	Host     string `json:"host,omitempty"`
-	Port     int    `json:"port,omitempty"`
+	Driver   Driver `json:"driver,omitempty" sudo:"flag=database.driver"`
+	Host     string `json:"host,omitempty" default:"localhost" env:"HOST" sudo:"flag=database.host"`
+	Port     int    `json:"port,omitempty" default:"5432" env:"PORT"`
 	Username string `json:"username,omitempty"`
-	Password string `json:"password,omitempty"`
-	SSLMode  string `json:"ssl_mode,omitempty"`
+	Password string `json:"password,omitempty" env:"PASSWORD,required" sudo:"secret"`
+	SSLMode  string `json:"ssl_mode,omitempty" default:"disable" sudo:"enum=disable|require|verify-full"`
 }
 
+// Driver is the database driver of a connection.
+type Driver string
+
+const (
+	DriverPostgres Driver = "postgres"
+	DriverMySQL    Driver = "mysql"
+	DriverSQLite   Driver = "sqlite"
+)
+
 // Tag represents a key-value tag.
 type Tag struct {
 	Key   string `json:"key,omitempty"`
@@ -0,0 +1,255 @@
+// Code generated by sudo-gen canonical. DO NOT EDIT.
+// Generated by sudo-gen (devel): canonical -tests
+
+package basic
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// MarshalCanonical returns the canonical JSON encoding of c, for signing and for
+// comparing values across services. The output is byte-stable: object keys are
+// sorted, every field is written regardless of omitempty, floats use the shortest
+// representation that round-trips, and times are RFC 3339 in UTC.
+func (c *Config) MarshalCanonical() []byte {
+	w := &configCanonicalEncoder{}
+	c.canonical(w)
+	return w.b
+}
+
+// canonical appends the canonical JSON encoding of c to w.
+func (c *Config) canonical(w *configCanonicalEncoder) {
+	if c == nil {
+		w.null()
+		return
+	}
+	w.b = append(w.b, "{\"created_at\":"...)
+	w.time(c.CreatedAt)
+	w.b = append(w.b, ",\"database\":"...)
+	c.Database.canonical(w)
+	w.b = append(w.b, ",\"description\":"...)
+	if c.Description == nil {
+		w.null()
+	} else {
+		w.string(*c.Description)
+	}
+	w.b = append(w.b, ",\"enabled\":"...)
+	w.bool(c.Enabled)
+	w.b = append(w.b, ",\"hosts\":"...)
+	w.b = append(w.b, '[')
+	for i := range c.Hosts {
+		if i > 0 {
+			w.b = append(w.b, ',')
+		}
+		w.string(c.Hosts[i])
+	}
+	w.b = append(w.b, ']')
+	w.b = append(w.b, ",\"labels\":"...)
+	w.b = append(w.b, '{')
+	for i, k := range slices.Sorted(maps.Keys(c.Labels)) {
+		if i > 0 {
+			w.b = append(w.b, ',')
+		}
+		w.string(k)
+		w.b = append(w.b, ':')
+		v := c.Labels[k]
+		w.string(v)
+	}
+	w.b = append(w.b, '}')
+	w.b = append(w.b, ",\"log_level\":"...)
+	w.string(c.LogLevel)
+	w.b = append(w.b, ",\"max_retries\":"...)
+	w.int(int64(c.MaxRetries))
+	w.b = append(w.b, ",\"metadata\":"...)
+	w.b = append(w.b, '{')
+	for i, k := range slices.Sorted(maps.Keys(c.Metadata)) {
+		if i > 0 {
+			w.b = append(w.b, ',')
+		}
+		w.string(k)
+		w.b = append(w.b, ':')
+		v := c.Metadata[k]
+		w.value(v)
+	}
+	w.b = append(w.b, '}')
+	w.b = append(w.b, ",\"name\":"...)
+	w.string(c.Name)
+	w.b = append(w.b, ",\"port\":"...)
+	w.int(int64(c.Port))
+	w.b = append(w.b, ",\"rate\":"...)
+	w.float(c.Rate, 64)
+	w.b = append(w.b, ",\"tags\":"...)
+	w.b = append(w.b, '[')
+	for i := range c.Tags {
+		if i > 0 {
+			w.b = append(w.b, ',')
+		}
+		c.Tags[i].canonical(w)
+	}
+	w.b = append(w.b, ']')
+	w.b = append(w.b, ",\"timeout\":"...)
+	w.int(int64(c.Timeout))
+	w.b = append(w.b, ",\"updated_at\":"...)
+	if c.UpdatedAt == nil {
+		w.null()
+	} else {
+		w.time(*c.UpdatedAt)
+	}
+	w.b = append(w.b, '}')
+}
+
+// MarshalCanonical returns the canonical JSON encoding of c, for signing and for
+// comparing values across services. The output is byte-stable: object keys are
+// sorted, every field is written regardless of omitempty, floats use the shortest
+// representation that round-trips, and times are RFC 3339 in UTC.
+func (c *Tag) MarshalCanonical() []byte {
+	w := &configCanonicalEncoder{}
+	c.canonical(w)
+	return w.b
+}
+
+// canonical appends the canonical JSON encoding of c to w.
+func (c *Tag) canonical(w *configCanonicalEncoder) {
+	if c == nil {
+		w.null()
+		return
+	}
+	w.b = append(w.b, "{\"key\":"...)
+	w.string(c.Key)
+	w.b = append(w.b, ",\"value\":"...)
+	w.string(c.Value)
+	w.b = append(w.b, '}')
+}
+
+// MarshalCanonical returns the canonical JSON encoding of c, for signing and for
+// comparing values across services. The output is byte-stable: object keys are
+// sorted, every field is written regardless of omitempty, floats use the shortest
+// representation that round-trips, and times are RFC 3339 in UTC.
+func (c *DatabaseConfig) MarshalCanonical() []byte {
+	w := &configCanonicalEncoder{}
+	c.canonical(w)
+	return w.b
+}
+
+// canonical appends the canonical JSON encoding of c to w.
+func (c *DatabaseConfig) canonical(w *configCanonicalEncoder) {
+	if c == nil {
+		w.null()
+		return
+	}
+	w.b = append(w.b, "{\"driver\":"...)
+	w.value(c.Driver)
+	w.b = append(w.b, ",\"host\":"...)
+	w.string(c.Host)
+	w.b = append(w.b, ",\"password\":"...)
+	w.string(c.Password)
+	w.b = append(w.b, ",\"port\":"...)
+	w.int(int64(c.Port))
+	w.b = append(w.b, ",\"ssl_mode\":"...)
+	w.string(c.SSLMode)
+	w.b = append(w.b, ",\"username\":"...)
+	w.string(c.Username)
+	w.b = append(w.b, '}')
+}
+
+// configCanonicalEncoder appends values to a buffer as canonical JSON.
+type configCanonicalEncoder struct {
+	b []byte
+}
+
+func (w *configCanonicalEncoder) null() {
+	w.b = append(w.b, "null"...)
+}
+
+func (w *configCanonicalEncoder) bool(v bool) {
+	w.b = strconv.AppendBool(w.b, v)
+}
+
+func (w *configCanonicalEncoder) int(v int64) {
+	w.b = strconv.AppendInt(w.b, v, 10)
+}
+
+func (w *configCanonicalEncoder) uint(v uint64) {
+	w.b = strconv.AppendUint(w.b, v, 10)
+}
+
+// float writes f as encoding/json does, except that -0 is written as 0. NaN and
+// infinities have no JSON representation and are written as null.
+func (w *configCanonicalEncoder) float(f float64, bits int) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		w.null()
+		return
+	}
+	if f == 0 {
+		f = 0
+	}
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	w.b = strconv.AppendFloat(w.b, f, format, -1, bits)
+	if format == 'e' {
+		// Shorten e-09 to e-9
+		if n := len(w.b); n >= 4 && w.b[n-4] == 'e' && w.b[n-3] == '-' && w.b[n-2] == '0' {
+			w.b[n-2] = w.b[n-1]
+			w.b = w.b[:n-1]
+		}
+	}
+}
+
+// string writes s as a JSON string, escaping only quotes, backslashes and control
+// characters. Invalid UTF-8 is replaced with U+FFFD.
+func (w *configCanonicalEncoder) string(s string) {
+	const hex = "0123456789abcdef"
+	w.b = append(w.b, '"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			w.b = append(w.b, '\\', byte(r))
+		case r == '\n':
+			w.b = append(w.b, '\\', 'n')
+		case r == '\r':
+			w.b = append(w.b, '\\', 'r')
+		case r == '\t':
+			w.b = append(w.b, '\\', 't')
+		case r < 0x20:
+			w.b = append(w.b, '\\', 'u', '0', '0', hex[r>>4], hex[r&0xf])
+		default:
+			w.b = utf8.AppendRune(w.b, r)
+		}
+	}
+	w.b = append(w.b, '"')
+}
+
+// time writes t as an RFC 3339 string in UTC, so that times that are Equal encode
+// the same.
+func (w *configCanonicalEncoder) time(t time.Time) {
+	w.string(t.UTC().Format(time.RFC3339Nano))
+}
+
+func (w *configCanonicalEncoder) bytes(v []byte) {
+	w.string(base64.StdEncoding.EncodeToString(v))
+}
+
+// value writes a value of a type without a known encoding with encoding/json, which
+// sorts map keys. Values encoding/json can't marshal are written as null.
+func (w *configCanonicalEncoder) value(v any) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		w.null()
+		return
+	}
+	w.b = append(w.b, bytes.TrimSuffix(buf.Bytes(), []byte("\n"))...)
+}
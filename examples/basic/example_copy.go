@@ -1,9 +1,11 @@
 // Code generated by sudo-gen copy. DO NOT EDIT.
+// Generated by sudo-gen (devel): layerbroker -tests -json -merge-patch -json-patch -http -sighup -provenance -audit -history=10 -groups=defaults,file,env -redact -constant-time-secrets -bench -examples
 
 package basic
 
 import (
 	"maps"
+	"slices"
 )
 
 // Copy creates a deep copy of the Config.
@@ -22,6 +24,7 @@ func (c *Config) Copy() *Config {
 		v := *c.Description
 		dst.Description = &v
 	}
+	dst.LogLevel = c.LogLevel
 	if c.Hosts != nil {
 		dst.Hosts = make([]string, len(c.Hosts))
 		copy(dst.Hosts, c.Hosts)
@@ -39,7 +42,7 @@ func (c *Config) Copy() *Config {
 	if c.Metadata != nil {
 		dst.Metadata = make(map[string]any, len(c.Metadata))
 		for k, v := range c.Metadata {
-			dst.Metadata[k] = deepCopyConfigAny(v)
+			dst.Metadata[k] = sudogenDeepCopyAny(v)
 		}
 	}
 	if c.Database != nil {
@@ -53,36 +56,6 @@ func (c *Config) Copy() *Config {
 	return dst
 }
 
-func deepCopyConfigAny(v any) any {
-	if v == nil {
-		return nil
-	}
-	switch val := v.(type) {
-	case map[string]any:
-		m := make(map[string]any, len(val))
-		for k, v := range val {
-			m[k] = deepCopyConfigAny(v)
-		}
-		return m
-	case []any:
-		s := make([]any, len(val))
-		for i, v := range val {
-			s[i] = deepCopyConfigAny(v)
-		}
-		return s
-	case []string:
-		s := make([]string, len(val))
-		copy(s, val)
-		return s
-	case []int:
-		s := make([]int, len(val))
-		copy(s, val)
-		return s
-	default:
-		return val
-	}
-}
-
 func (c *Tag) Copy() *Tag {
 	if c == nil {
 		return nil
@@ -98,10 +71,173 @@ func (c *DatabaseConfig) Copy() *DatabaseConfig {
 		return nil
 	}
 	dst := &DatabaseConfig{}
+	dst.Driver = c.Driver
+	dst.Host = c.Host
+	dst.Port = c.Port
+	dst.Username = c.Username
+	dst.Password = c.Password
+	dst.SSLMode = c.SSLMode
+	return dst
+}
+
+// CopyInto deep copies c into dst, reusing the slices, maps and nested structs
+// dst already holds where possible instead of allocating new ones. dst must not share
+// memory with values still in use elsewhere. If c is nil, dst is reset to the zero value.
+func (c *Config) CopyInto(dst *Config) {
+	if c == nil {
+		*dst = Config{}
+		return
+	}
+	dst.Name = c.Name
+	dst.Port = c.Port
+	dst.MaxRetries = c.MaxRetries
+	dst.Timeout = c.Timeout
+	dst.Rate = c.Rate
+	dst.Enabled = c.Enabled
+	if c.Description == nil {
+		dst.Description = nil
+	} else if dst.Description == nil {
+		v := *c.Description
+		dst.Description = &v
+	} else {
+		*dst.Description = *c.Description
+	}
+	dst.LogLevel = c.LogLevel
+	if c.Hosts == nil {
+		dst.Hosts = nil
+	} else {
+		if dst.Hosts == nil {
+			dst.Hosts = make([]string, 0, len(c.Hosts))
+		}
+		dst.Hosts = append(slices.Grow(dst.Hosts[:0], len(c.Hosts)), c.Hosts...)
+	}
+	if c.Tags == nil {
+		dst.Tags = nil
+	} else {
+		if dst.Tags == nil {
+			dst.Tags = make([]Tag, 0, len(c.Tags))
+		}
+		dst.Tags = slices.Grow(dst.Tags[:0], len(c.Tags))[:len(c.Tags)]
+		for i := range c.Tags {
+			c.Tags[i].CopyInto(&dst.Tags[i])
+		}
+	}
+	if c.Labels == nil {
+		dst.Labels = nil
+	} else {
+		if dst.Labels == nil {
+			dst.Labels = make(map[string]string, len(c.Labels))
+		} else {
+			clear(dst.Labels)
+		}
+		maps.Copy(dst.Labels, c.Labels)
+	}
+	if c.Metadata == nil {
+		dst.Metadata = nil
+	} else {
+		if dst.Metadata == nil {
+			dst.Metadata = make(map[string]any, len(c.Metadata))
+		} else {
+			clear(dst.Metadata)
+		}
+		for k, v := range c.Metadata {
+			dst.Metadata[k] = sudogenDeepCopyAny(v)
+		}
+	}
+	if c.Database == nil {
+		dst.Database = nil
+	} else {
+		if dst.Database == nil {
+			dst.Database = new(DatabaseConfig)
+		}
+		c.Database.CopyInto(dst.Database)
+	}
+	dst.CreatedAt = c.CreatedAt
+	if c.UpdatedAt == nil {
+		dst.UpdatedAt = nil
+	} else if dst.UpdatedAt == nil {
+		v := *c.UpdatedAt
+		dst.UpdatedAt = &v
+	} else {
+		*dst.UpdatedAt = *c.UpdatedAt
+	}
+}
+
+// CopyInto deep copies c into dst, reusing the slices, maps and nested structs
+// dst already holds where possible instead of allocating new ones. dst must not share
+// memory with values still in use elsewhere. If c is nil, dst is reset to the zero value.
+func (c *Tag) CopyInto(dst *Tag) {
+	if c == nil {
+		*dst = Tag{}
+		return
+	}
+	dst.Key = c.Key
+	dst.Value = c.Value
+}
+
+// CopyInto deep copies c into dst, reusing the slices, maps and nested structs
+// dst already holds where possible instead of allocating new ones. dst must not share
+// memory with values still in use elsewhere. If c is nil, dst is reset to the zero value.
+func (c *DatabaseConfig) CopyInto(dst *DatabaseConfig) {
+	if c == nil {
+		*dst = DatabaseConfig{}
+		return
+	}
+	dst.Driver = c.Driver
 	dst.Host = c.Host
 	dst.Port = c.Port
 	dst.Username = c.Username
 	dst.Password = c.Password
 	dst.SSLMode = c.SSLMode
+}
+
+// Redacted returns a deep copy of the Config with the fields tagged
+// sudo:"secret" cleared, including those of nested structs, for logging and display.
+func (c *Config) Redacted() *Config {
+	dst := c.Copy()
+	dst.redactSecrets()
 	return dst
 }
+
+// redactSecrets clears the fields of c tagged sudo:"secret" in place.
+func (c *Config) redactSecrets() {
+	if c == nil {
+		return
+	}
+	for i := range c.Tags {
+		c.Tags[i].redactSecrets()
+	}
+	c.Database.redactSecrets()
+}
+
+// Redacted returns a deep copy of the Tag with the fields tagged
+// sudo:"secret" cleared, including those of nested structs, for logging and display.
+func (c *Tag) Redacted() *Tag {
+	dst := c.Copy()
+	dst.redactSecrets()
+	return dst
+}
+
+// redactSecrets clears the fields of c tagged sudo:"secret" in place.
+func (c *Tag) redactSecrets() {
+	if c == nil {
+		return
+	}
+}
+
+// Redacted returns a deep copy of the DatabaseConfig with the fields tagged
+// sudo:"secret" cleared, including those of nested structs, for logging and display.
+func (c *DatabaseConfig) Redacted() *DatabaseConfig {
+	dst := c.Copy()
+	dst.redactSecrets()
+	return dst
+}
+
+// redactSecrets clears the fields of c tagged sudo:"secret" in place.
+func (c *DatabaseConfig) redactSecrets() {
+	if c == nil {
+		return
+	}
+	var zero DatabaseConfig
+	c.Password = zero.Password
+}
@@ -1,4 +1,5 @@
 // Code generated by sudo-gen copy. DO NOT EDIT.
+// Generated by sudo-gen (devel): layerbroker -tests -json -merge-patch -json-patch -http -sighup -provenance -audit -history=10 -groups=defaults,file,env -redact -constant-time-secrets -bench -examples
 
 package basic
 
@@ -401,3 +402,30 @@ func TestDatabaseConfigCopyEmpty(t *testing.T) {
 		t.Error("copy should be a different pointer")
 	}
 }
+
+func TestConfigCopyIntoReuse(t *testing.T) {
+	dst := &Config{Hosts: make([]string, 0, 16)}
+	c := &Config{Hosts: make([]string, 2)}
+	c.CopyInto(dst)
+	if len(dst.Hosts) != 2 || cap(dst.Hosts) != 16 {
+		t.Errorf("expected Hosts to be copied into the existing slice, got len %d cap %d", len(dst.Hosts), cap(dst.Hosts))
+	}
+	if &dst.Hosts[0] == &c.Hosts[0] {
+		t.Error("Hosts should not share the source's backing array")
+	}
+	(&Config{}).CopyInto(dst)
+	if dst.Hosts != nil {
+		t.Error("nil Hosts should be copied as nil")
+	}
+}
+
+func TestDatabaseConfigRedacted(t *testing.T) {
+	c := &DatabaseConfig{Password: "secret"}
+	got := c.Redacted()
+	if got.Password != "" {
+		t.Errorf("Password = %q after Redacted, want empty", got.Password)
+	}
+	if c.Password != "secret" {
+		t.Error("Redacted should not modify the original")
+	}
+}
@@ -0,0 +1,59 @@
+// Code generated by sudo-gen defaults. DO NOT EDIT.
+// Generated by sudo-gen (devel): defaults -tests
+
+package basic
+
+// DefaultConfig returns a Config with every declared default applied.
+// It is intended as the base layer of a layer broker, e.g. NewConfigLayerBroker(DefaultConfig()).
+func DefaultConfig() *Config {
+	c := &Config{}
+	c.SetDefaults()
+	return c
+}
+
+// SetDefaults sets every zero-valued field of c that declares a default.
+// Fields that already hold a value are left untouched.
+func (c *Config) SetDefaults() {
+	if c == nil {
+		return
+	}
+	if c.Name == "" {
+		c.Name = "app"
+	}
+	if c.Port == 0 {
+		c.Port = 8080
+	}
+	if c.MaxRetries == 0 {
+		c.MaxRetries = int32(3)
+	}
+	if c.Rate == 0 {
+		c.Rate = float64(0.5)
+	}
+	if c.LogLevel == "" {
+		c.LogLevel = "info"
+	}
+	if c.Hosts == nil {
+		c.Hosts = []string{"localhost"}
+	}
+	if c.Database == nil {
+		c.Database = &DatabaseConfig{}
+	}
+	c.Database.SetDefaults()
+}
+
+// SetDefaults sets every zero-valued field of c that declares a default.
+// Fields that already hold a value are left untouched.
+func (c *DatabaseConfig) SetDefaults() {
+	if c == nil {
+		return
+	}
+	if c.Host == "" {
+		c.Host = "localhost"
+	}
+	if c.Port == 0 {
+		c.Port = 5432
+	}
+	if c.SSLMode == "" {
+		c.SSLMode = "disable"
+	}
+}
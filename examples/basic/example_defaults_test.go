@@ -0,0 +1,60 @@
+// Code generated by sudo-gen defaults. DO NOT EDIT.
+// Generated by sudo-gen (devel): defaults -tests
+
+package basic
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDefaultConfig(t *testing.T) {
+	c := DefaultConfig()
+	if c == nil {
+		t.Fatal("DefaultConfig() returned nil")
+	}
+	if c.Name != "app" {
+		t.Errorf("Name = %v, want %v", c.Name, "app")
+	}
+	if c.Port != 8080 {
+		t.Errorf("Port = %v, want %v", c.Port, 8080)
+	}
+	if c.MaxRetries != int32(3) {
+		t.Errorf("MaxRetries = %v, want %v", c.MaxRetries, int32(3))
+	}
+	if c.Rate != float64(0.5) {
+		t.Errorf("Rate = %v, want %v", c.Rate, float64(0.5))
+	}
+	if c.LogLevel != "info" {
+		t.Errorf("LogLevel = %v, want %v", c.LogLevel, "info")
+	}
+}
+
+func TestConfigSetDefaultsIdempotent(t *testing.T) {
+	c := DefaultConfig()
+	c.SetDefaults()
+	if !reflect.DeepEqual(c, DefaultConfig()) {
+		t.Error("SetDefaults changed an already defaulted value")
+	}
+}
+
+func TestConfigSetDefaultsNil(t *testing.T) {
+	var c *Config
+	c.SetDefaults() // Should not panic
+}
+
+func TestConfigSetDefaultsKeepsName(t *testing.T) {
+	c := &Config{Name: "custom"}
+	c.SetDefaults()
+	if c.Name != "custom" {
+		t.Errorf("Name = %q, want %q", c.Name, "custom")
+	}
+}
+
+func TestConfigSetDefaultsKeepsLogLevel(t *testing.T) {
+	c := &Config{LogLevel: "custom"}
+	c.SetDefaults()
+	if c.LogLevel != "custom" {
+		t.Errorf("LogLevel = %q, want %q", c.LogLevel, "custom")
+	}
+}
@@ -1,7 +1,12 @@
 // Code generated by sudo-gen equals. DO NOT EDIT.
+// Generated by sudo-gen (devel): layerbroker -tests -json -merge-patch -json-patch -http -sighup -provenance -audit -history=10 -groups=defaults,file,env -redact -constant-time-secrets -bench -examples
 
 package basic
 
+import (
+	"crypto/subtle"
+)
+
 // Equal returns true if c and other have the same values.
 func (c *Config) Equal(other *Config) bool {
 	if c == other {
@@ -22,7 +27,7 @@ func (c *Config) Equal(other *Config) bool {
 	if c.Timeout != other.Timeout {
 		return false
 	}
-	if c.Rate != other.Rate {
+	if !sudogenFloatEqual(c.Rate, other.Rate, 1e-9) {
 		return false
 	}
 	if c.Enabled != other.Enabled {
@@ -34,6 +39,9 @@ func (c *Config) Equal(other *Config) bool {
 	if c.Description != nil && *c.Description != *other.Description {
 		return false
 	}
+	if c.LogLevel != other.LogLevel {
+		return false
+	}
 	if len(c.Hosts) != len(other.Hosts) {
 		return false
 	}
@@ -70,7 +78,7 @@ func (c *Config) Equal(other *Config) bool {
 		if !ok {
 			return false
 		}
-		if !equalAny(v, ov) {
+		if !sudogenEqualAny(v, ov) {
 			return false
 		}
 	}
@@ -114,6 +122,9 @@ func (c *DatabaseConfig) Equal(other *DatabaseConfig) bool {
 	if c == nil || other == nil {
 		return false
 	}
+	if c.Driver != other.Driver {
+		return false
+	}
 	if c.Host != other.Host {
 		return false
 	}
@@ -123,7 +134,7 @@ func (c *DatabaseConfig) Equal(other *DatabaseConfig) bool {
 	if c.Username != other.Username {
 		return false
 	}
-	if c.Password != other.Password {
+	if subtle.ConstantTimeCompare([]byte(c.Password), []byte(other.Password)) != 1 {
 		return false
 	}
 	if c.SSLMode != other.SSLMode {
@@ -131,75 +142,3 @@ func (c *DatabaseConfig) Equal(other *DatabaseConfig) bool {
 	}
 	return true
 }
-
-func equalAny(a, b any) bool {
-	if a == nil && b == nil {
-		return true
-	}
-	if a == nil || b == nil {
-		return false
-	}
-	switch av := a.(type) {
-	case map[string]any:
-		bv, ok := b.(map[string]any)
-		if !ok || len(av) != len(bv) {
-			return false
-		}
-		for k, v := range av {
-			if ov, ok := bv[k]; !ok || !equalAny(v, ov) {
-				return false
-			}
-		}
-		return true
-	case []any:
-		bv, ok := b.([]any)
-		if !ok || len(av) != len(bv) {
-			return false
-		}
-		for i := range av {
-			if !equalAny(av[i], bv[i]) {
-				return false
-			}
-		}
-		return true
-	case []string:
-		bv, ok := b.([]string)
-		if !ok || len(av) != len(bv) {
-			return false
-		}
-		for i := range av {
-			if av[i] != bv[i] {
-				return false
-			}
-		}
-		return true
-	case []int:
-		bv, ok := b.([]int)
-		if !ok || len(av) != len(bv) {
-			return false
-		}
-		for i := range av {
-			if av[i] != bv[i] {
-				return false
-			}
-		}
-		return true
-	case string:
-		bv, ok := b.(string)
-		return ok && av == bv
-	case int:
-		bv, ok := b.(int)
-		return ok && av == bv
-	case int64:
-		bv, ok := b.(int64)
-		return ok && av == bv
-	case float64:
-		bv, ok := b.(float64)
-		return ok && av == bv
-	case bool:
-		bv, ok := b.(bool)
-		return ok && av == bv
-	default:
-		return a == b
-	}
-}
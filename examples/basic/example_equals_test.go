@@ -1,8 +1,10 @@
 // Code generated by sudo-gen equals. DO NOT EDIT.
+// Generated by sudo-gen (devel): layerbroker -tests -json -merge-patch -json-patch -http -sighup -provenance -audit -history=10 -groups=defaults,file,env -redact -constant-time-secrets -bench -examples
 
 package basic
 
 import (
+	"math"
 	"testing"
 )
 
@@ -39,6 +41,20 @@ func TestConfigEqualEmptyStructs(t *testing.T) {
 	}
 }
 
+func TestConfigEqualRateEpsilon(t *testing.T) {
+	a := &Config{Rate: 1}
+	if !a.Equal(&Config{Rate: 1 + 1e-9/2}) {
+		t.Error("Rate within 1e-9 should compare equal")
+	}
+	if a.Equal(&Config{Rate: 2 + 2*1e-9}) {
+		t.Error("Rate farther apart than 1e-9 should not compare equal")
+	}
+	a.Rate = float64(math.NaN())
+	if !a.Equal(&Config{Rate: float64(math.NaN())}) {
+		t.Error("NaN Rate should compare equal")
+	}
+}
+
 func TestTagEqualBothNil(t *testing.T) {
 	var a, b *Tag
 	if !a.Equal(b) {
@@ -104,3 +120,13 @@ func TestDatabaseConfigEqualEmptyStructs(t *testing.T) {
 		t.Error("two empty structs should be equal")
 	}
 }
+
+func TestDatabaseConfigEqualSecretPassword(t *testing.T) {
+	a := &DatabaseConfig{Password: "secret"}
+	if !a.Equal(&DatabaseConfig{Password: "secret"}) {
+		t.Error("equal secrets should compare equal")
+	}
+	if a.Equal(&DatabaseConfig{Password: "secreT"}) {
+		t.Error("different secrets should not compare equal")
+	}
+}
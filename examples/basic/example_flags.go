@@ -0,0 +1,108 @@
+// Code generated by sudo-gen flags. DO NOT EDIT.
+// Generated by sudo-gen (devel): flags -tests
+
+package basic
+
+import (
+	"fmt"
+)
+
+// ConfigFlagProvider looks up feature flag values by key, in the style of
+// LaunchDarkly or OpenFeature clients. Each method reports false when the flag has no
+// value for the current context, leaving the field it overrides unchanged.
+type ConfigFlagProvider interface {
+	BoolFlag(key string) (bool, bool)
+	IntFlag(key string) (int64, bool)
+	FloatFlag(key string) (float64, bool)
+	StringFlag(key string) (string, bool)
+}
+
+// ConfigFlagOverrides holds the Config fields overridden by feature flags.
+// A nil field has no flag value.
+type ConfigFlagOverrides struct {
+	Port           *int    // service.port
+	Enabled        *bool   // service.enabled
+	DatabaseDriver *Driver // database.driver
+	DatabaseHost   *string // database.host
+}
+
+// LookupConfigFlags reads every flag bound to a Config field from provider.
+func LookupConfigFlags(provider ConfigFlagProvider) (*ConfigFlagOverrides, error) {
+	o := &ConfigFlagOverrides{}
+	if v, ok := provider.IntFlag("service.port"); ok {
+		value := int(v)
+		o.Port = &value
+	}
+	if v, ok := provider.BoolFlag("service.enabled"); ok {
+		o.Enabled = &v
+	}
+	if v, ok := provider.StringFlag("database.driver"); ok {
+		var value Driver
+		if err := value.Set(v); err != nil {
+			return nil, fmt.Errorf("flag %q: %w", "database.driver", err)
+		}
+		o.DatabaseDriver = &value
+	}
+	if v, ok := provider.StringFlag("database.host"); ok {
+		o.DatabaseHost = &v
+	}
+	return o, nil
+}
+
+// Partial returns the overrides as a partial holding only the flags that have values.
+func (o *ConfigFlagOverrides) Partial() *ConfigPartial {
+	p := &ConfigPartial{}
+	if o.Port != nil {
+		p.Port = o.Port
+	}
+	if o.Enabled != nil {
+		p.Enabled = o.Enabled
+	}
+	if o.DatabaseDriver != nil {
+		if p.Database == nil {
+			p.Database = &DatabaseConfigPartial{}
+		}
+		p.Database.Driver = o.DatabaseDriver
+	}
+	if o.DatabaseHost != nil {
+		if p.Database == nil {
+			p.Database = &DatabaseConfigPartial{}
+		}
+		p.Database.Host = o.DatabaseHost
+	}
+	return p
+}
+
+// ConfigFlagLayer keeps a top broker layer in sync with a flag provider.
+type ConfigFlagLayer struct {
+	// Layer holds the overrides. It is a top layer, so the flags take priority over
+	// every other layer of the broker.
+	Layer    *ConfigLayer
+	provider ConfigFlagProvider
+}
+
+// ApplyConfigFlags overlays the flags from provider on broker as a new top layer.
+// Call Refresh on the result whenever the provider reports a flag change.
+func ApplyConfigFlags(broker *ConfigLayerBroker, provider ConfigFlagProvider) (*ConfigFlagLayer, error) {
+	o, err := LookupConfigFlags(provider)
+	if err != nil {
+		return nil, err
+	}
+	l := &ConfigFlagLayer{Layer: broker.TopLayer(), provider: provider}
+	if err := l.Layer.Replace(o.Partial()); err != nil {
+		l.Layer.Remove()
+		return nil, err
+	}
+	return l, nil
+}
+
+// Refresh re-reads the flags and replaces the layer with them, so flags that no longer
+// have a value stop overriding their fields. The layer is unchanged if a flag is invalid
+// or the broker's validator rejects the result.
+func (l *ConfigFlagLayer) Refresh() error {
+	o, err := LookupConfigFlags(l.provider)
+	if err != nil {
+		return err
+	}
+	return l.Layer.Replace(o.Partial())
+}
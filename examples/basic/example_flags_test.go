@@ -0,0 +1,91 @@
+// Code generated by sudo-gen flags. DO NOT EDIT.
+// Generated by sudo-gen (devel): flags -tests
+
+package basic
+
+import (
+	"testing"
+)
+
+// configTestFlags is a ConfigFlagProvider backed by a map of flag values.
+type configTestFlags map[string]any
+
+func (f configTestFlags) BoolFlag(key string) (bool, bool) {
+	v, ok := f[key].(bool)
+	return v, ok
+}
+
+func (f configTestFlags) IntFlag(key string) (int64, bool) {
+	v, ok := f[key].(int64)
+	return v, ok
+}
+
+func (f configTestFlags) FloatFlag(key string) (float64, bool) {
+	v, ok := f[key].(float64)
+	return v, ok
+}
+
+func (f configTestFlags) StringFlag(key string) (string, bool) {
+	v, ok := f[key].(string)
+	return v, ok
+}
+
+// configFlagConfig returns the config holding just the given overrides.
+func configFlagConfig(t *testing.T, flags configTestFlags) *Config {
+	t.Helper()
+	o, err := LookupConfigFlags(flags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Config{}
+	c.ApplyPartial(o.Partial())
+	return c
+}
+
+func TestLookupConfigFlagsUnset(t *testing.T) {
+	o, err := LookupConfigFlags(configTestFlags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *o != (ConfigFlagOverrides{}) {
+		t.Errorf("expected no overrides, got %+v", o)
+	}
+}
+
+func TestApplyConfigFlags(t *testing.T) {
+	flags := configTestFlags{
+		"service.port":    int64(42),
+		"service.enabled": true,
+		"database.driver": "flag",
+		"database.host":   "flag",
+	}
+	broker := NewConfigLayerBroker(nil)
+	l, err := ApplyConfigFlags(broker, flags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A layer created after the flag layer must not override it
+	other := configTestFlags{
+		"service.port":    int64(7),
+		"service.enabled": false,
+		"database.driver": "layer",
+		"database.host":   "layer",
+	}
+	o, err := LookupConfigFlags(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker.Layer().Set(o.Partial())
+	if want := configFlagConfig(t, flags); !broker.Get().Equal(want) {
+		t.Errorf("expected the flags to take priority, got %+v, want %+v", broker.Get(), want)
+	}
+	for key, value := range other {
+		flags[key] = value
+	}
+	if err := l.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if want := configFlagConfig(t, other); !broker.Get().Equal(want) {
+		t.Errorf("expected refreshed flags, got %+v, want %+v", broker.Get(), want)
+	}
+}
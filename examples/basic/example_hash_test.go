@@ -0,0 +1,83 @@
+// Code generated by sudo-gen hash. DO NOT EDIT.
+// Generated by sudo-gen (devel): hash -tests
+
+package basic
+
+import (
+	"testing"
+)
+
+func TestConfigHashEmpty(t *testing.T) {
+	a, b := &Config{}, &Config{}
+	if a.Hash() != b.Hash() {
+		t.Error("two empty structs should hash the same")
+	}
+	var nilValue *Config
+	if nilValue.Hash() == a.Hash() {
+		t.Error("nil should not hash like an empty struct")
+	}
+}
+
+func TestConfigHashName(t *testing.T) {
+	a := &Config{Name: "a"}
+	if a.Hash() != (&Config{Name: "a"}).Hash() {
+		t.Error("equal values should hash the same")
+	}
+	if a.Hash() == (&Config{Name: "b"}).Hash() {
+		t.Error("expected a different hash after changing Name")
+	}
+}
+
+func TestConfigHashLabelsOrder(t *testing.T) {
+	a := &Config{Labels: map[string]string{}}
+	b := &Config{Labels: map[string]string{}}
+	for i := range 20 {
+		a.Labels[string(rune('a'+i))] = "value"
+		b.Labels[string(rune('a'+19-i))] = "value"
+	}
+	if a.Hash() != b.Hash() {
+		t.Error("maps with the same entries should hash the same")
+	}
+}
+
+func TestTagHashEmpty(t *testing.T) {
+	a, b := &Tag{}, &Tag{}
+	if a.Hash() != b.Hash() {
+		t.Error("two empty structs should hash the same")
+	}
+	var nilValue *Tag
+	if nilValue.Hash() == a.Hash() {
+		t.Error("nil should not hash like an empty struct")
+	}
+}
+
+func TestTagHashKey(t *testing.T) {
+	a := &Tag{Key: "a"}
+	if a.Hash() != (&Tag{Key: "a"}).Hash() {
+		t.Error("equal values should hash the same")
+	}
+	if a.Hash() == (&Tag{Key: "b"}).Hash() {
+		t.Error("expected a different hash after changing Key")
+	}
+}
+
+func TestDatabaseConfigHashEmpty(t *testing.T) {
+	a, b := &DatabaseConfig{}, &DatabaseConfig{}
+	if a.Hash() != b.Hash() {
+		t.Error("two empty structs should hash the same")
+	}
+	var nilValue *DatabaseConfig
+	if nilValue.Hash() == a.Hash() {
+		t.Error("nil should not hash like an empty struct")
+	}
+}
+
+func TestDatabaseConfigHashHost(t *testing.T) {
+	a := &DatabaseConfig{Host: "a"}
+	if a.Hash() != (&DatabaseConfig{Host: "a"}).Hash() {
+		t.Error("equal values should hash the same")
+	}
+	if a.Hash() == (&DatabaseConfig{Host: "b"}).Hash() {
+		t.Error("expected a different hash after changing Host")
+	}
+}
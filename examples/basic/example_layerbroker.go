@@ -1,4 +1,5 @@
 // Code generated by sudo-gen layerbroker. DO NOT EDIT.
+// Generated by sudo-gen (devel): layerbroker -tests -json -merge-patch -json-patch -http -sighup -provenance -audit -history=10 -groups=defaults,file,env -redact -constant-time-secrets -bench -examples
 
 // ConfigLayerBroker Overview
 //
@@ -54,7 +55,24 @@
 //	defer unsub() // Clean up when done
 //
 // Subscribers are only notified when the value actually changes. Setting the same
-// value again does not trigger a notification.
+// value again does not trigger a notification. Subscribe observes the whole config
+// instead of a single field.
+//
+// # Validation
+//
+// Pass WithConfigValidator to check every config a layer change would produce.
+// A change that fails validation is undone, the current config stays in place, and
+// the layer method returns a *ConfigValidationError naming the layer:
+//
+//	broker := NewConfigLayerBroker(nil, WithConfigValidator(func(cfg Config) error {
+//	    if cfg.Name == "" {
+//	        return errors.New("name is required")
+//	    }
+//	    return nil
+//	}))
+//	if err := broker.Layer().Named("env").Set(envPartial); errors.Is(err, ErrConfigValidationFailed) {
+//	    log.Println("rejected:", err)
+//	}
 //
 // # Thread Safety
 //
@@ -68,11 +86,16 @@
 //
 // This generated code requires the following to also be generated:
 //   - ConfigPartial (from: sudo-gen merge)
-//   - Config.Copy() (from: sudo-gen copy)
+//   - Config.Copy() and CopyInto() (from: sudo-gen copy)
 package basic
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -85,6 +108,12 @@ type ConfigLayerBroker struct {
 	mu              sync.Mutex // protects subscribers, layers, and serializes writes
 	nextSubID       int
 	layers          []*ConfigLayer
+	created         int                // Number of layers ever created, for default layer names
+	validate        func(Config) error // Set by WithConfigValidator
+	audit           ConfigAuditSink    // Set by WithConfigAuditSink
+	history         [10]ConfigSnapshot // Ring buffer of recent configs, see History
+	snapshots       int                // Number of configs ever recorded in history
+	subscribers     map[int]func(*Config)
 	subsName        map[int]func(string)
 	subsPort        map[int]func(int)
 	subsMaxRetries  map[int]func(int32)
@@ -92,6 +121,7 @@ type ConfigLayerBroker struct {
 	subsRate        map[int]func(float64)
 	subsEnabled     map[int]func(bool)
 	subsDescription map[int]func(*string)
+	subsLogLevel    map[int]func(string)
 	subsHosts       map[int]func([]string)
 	subsTags        map[int]func([]Tag)
 	subsLabels      map[int]func(map[string]string)
@@ -101,14 +131,86 @@ type ConfigLayerBroker struct {
 	subsUpdatedAt   map[int]func(*time.Time)
 }
 
+// ErrConfigValidationFailed is matched by every error returned for a layer change
+// that the validator of a ConfigLayerBroker rejected.
+var ErrConfigValidationFailed = errors.New("config validation failed")
+
+// ConfigValidationError reports a layer change rejected by the validator of a
+// ConfigLayerBroker. It matches ErrConfigValidationFailed with errors.Is and
+// unwraps to the error returned by the validator.
+type ConfigValidationError struct {
+	Layer string // Name of the layer whose change was rejected
+	Err   error
+}
+
+func (e *ConfigValidationError) Error() string {
+	return "layer " + strconv.Quote(e.Layer) + ": " + ErrConfigValidationFailed.Error() + ": " + e.Err.Error()
+}
+
+func (e *ConfigValidationError) Is(target error) bool {
+	return target == ErrConfigValidationFailed
+}
+
+func (e *ConfigValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ConfigLayerBrokerOption configures a broker created by NewConfigLayerBroker.
+type ConfigLayerBrokerOption func(*ConfigLayerBroker)
+
+// WithConfigValidator makes the broker check every config a layer change
+// produces with validate. A change that fails validation is undone, keeping the
+// current config, and the layer method returns a *ConfigValidationError. Changes
+// that leave the config as it is are not validated, and neither is the initial config.
+func WithConfigValidator(validate func(Config) error) ConfigLayerBrokerOption {
+	return func(b *ConfigLayerBroker) {
+		b.validate = validate
+	}
+}
+
+// ConfigAuditEntry records a layer change the broker applied.
+type ConfigAuditEntry struct {
+	Layer   string              // Name of the layer (see Named)
+	Actor   string              // Who changed the layer (see As); empty if unknown
+	Time    time.Time           // When the change took effect
+	Changes []ConfigFieldChange // Top-level fields the change altered; empty if it altered none
+}
+
+// ConfigAuditSink receives an entry for every layer change a ConfigLayerBroker
+// applies, including the changes that leave the config as it is, such as values a
+// higher layer overrides. Changes rejected by the validator are not audited.
+type ConfigAuditSink interface {
+	// Audit is called with the broker locked, in the order the changes are applied,
+	// so it must not call the broker. The values of entry must not be modified.
+	Audit(entry ConfigAuditEntry)
+}
+
+// ConfigAuditFunc adapts a function to a ConfigAuditSink.
+type ConfigAuditFunc func(entry ConfigAuditEntry)
+
+// Audit calls f(entry).
+func (f ConfigAuditFunc) Audit(entry ConfigAuditEntry) {
+	f(entry)
+}
+
+// WithConfigAuditSink makes the broker report every layer change it applies to
+// sink, along with the fields the change altered. Fields holding values tagged
+// sudo:"secret" are reported with both values replaced by "[REDACTED]".
+func WithConfigAuditSink(sink ConfigAuditSink) ConfigLayerBrokerOption {
+	return func(b *ConfigLayerBroker) {
+		b.audit = sink
+	}
+}
+
 // NewConfigLayerBroker creates a new LayerBroker wrapping the given config.
 // If cfg is nil, an empty config is used.
-func NewConfigLayerBroker(cfg *Config) *ConfigLayerBroker {
+func NewConfigLayerBroker(cfg *Config, opts ...ConfigLayerBrokerOption) *ConfigLayerBroker {
 	if cfg == nil {
 		cfg = &Config{}
 	}
 	b := &ConfigLayerBroker{
 		base:            cfg.Copy(),
+		subscribers:     make(map[int]func(*Config)),
 		subsName:        make(map[int]func(string)),
 		subsPort:        make(map[int]func(int)),
 		subsMaxRetries:  make(map[int]func(int32)),
@@ -116,6 +218,7 @@ func NewConfigLayerBroker(cfg *Config) *ConfigLayerBroker {
 		subsRate:        make(map[int]func(float64)),
 		subsEnabled:     make(map[int]func(bool)),
 		subsDescription: make(map[int]func(*string)),
+		subsLogLevel:    make(map[int]func(string)),
 		subsHosts:       make(map[int]func([]string)),
 		subsTags:        make(map[int]func([]Tag)),
 		subsLabels:      make(map[int]func(map[string]string)),
@@ -124,7 +227,11 @@ func NewConfigLayerBroker(cfg *Config) *ConfigLayerBroker {
 		subsCreatedAt:   make(map[int]func(time.Time)),
 		subsUpdatedAt:   make(map[int]func(*time.Time)),
 	}
+	for _, opt := range opts {
+		opt(b)
+	}
 	b.config.Store(cfg.Copy())
+	b.record(b.config.Load())
 	return b
 }
 
@@ -134,15 +241,102 @@ func (b *ConfigLayerBroker) Get() *Config {
 	return b.config.Load().Copy()
 }
 
-// Layer returns a new layer for applying partial changes.
+// Ranks order the layers of a broker: a layer is always above every layer of a lower
+// rank, and above the earlier layers of its own rank. Each group is a rank of its
+// own, below the layers created by Layer.
+const (
+	configRankLayer = 3 + iota
+	configRankTop
+)
+
+// Layer returns a new layer for applying partial changes. It takes priority
+// over the layers of every group.
 func (b *ConfigLayerBroker) Layer() *ConfigLayer {
+	return b.newLayer(configRankLayer)
+}
+
+// TopLayer returns a new layer that takes priority over every layer created by Layer,
+// including those created after it, for overrides that must always win. Top layers
+// are ordered among themselves like other layers, the most recent one winning.
+func (b *ConfigLayerBroker) TopLayer() *ConfigLayer {
+	return b.newLayer(configRankTop)
+}
+
+// ConfigLayerGroup is a group of broker layers with a fixed priority relative to
+// the other groups. Layers within a group are ordered like other layers, the most
+// recent one winning.
+type ConfigLayerGroup int
+
+// Layer groups, lowest priority first.
+const (
+	ConfigGroupDefaults ConfigLayerGroup = iota
+	ConfigGroupFile
+	ConfigGroupEnv
+)
+
+// String returns the name of the group.
+func (g ConfigLayerGroup) String() string {
+	switch g {
+	case ConfigGroupDefaults:
+		return "defaults"
+	case ConfigGroupFile:
+		return "file"
+	case ConfigGroupEnv:
+		return "env"
+	}
+	return "ConfigLayerGroup(" + strconv.Itoa(int(g)) + ")"
+}
+
+// GroupLayer returns a new layer in group. It takes priority over the layers of lower
+// groups and the earlier layers of its own group, whenever they were created, and is
+// overridden by the layers of higher groups and those created by Layer and TopLayer.
+func (b *ConfigLayerBroker) GroupLayer(group ConfigLayerGroup) *ConfigLayer {
+	if group < 0 || group >= 3 {
+		panic("unknown layer group " + group.String())
+	}
+	return b.newLayer(int(group))
+}
+
+// newLayer returns a new layer of the given rank.
+func (b *ConfigLayerBroker) newLayer(rank int) *ConfigLayer {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	l := &ConfigLayer{broker: b}
-	b.layers = append(b.layers, l)
+	l := &ConfigLayer{broker: b, rank: rank}
+	b.created++
+	l.name = "layer " + strconv.Itoa(b.created)
+	b.layers = configInsertLayer(b.layers, l)
 	return l
 }
 
+// configInsertLayer inserts l into layers above every layer of the same or a
+// lower rank.
+func configInsertLayer(layers []*ConfigLayer, l *ConfigLayer) []*ConfigLayer {
+	i := len(layers)
+	for i > 0 && layers[i-1].rank > l.rank {
+		i--
+	}
+	return slices.Insert(layers, i, l)
+}
+
+// Subscribe subscribes to changes anywhere in the configuration. The callback is
+// invoked immediately with the current configuration, and with the new configuration
+// whenever a change alters it. The configuration passed to callback is shared and
+// must not be modified. Returns an unsubscribe function.
+func (b *ConfigLayerBroker) Subscribe(callback func(*Config)) func() {
+	b.mu.Lock()
+	id := b.nextSubID
+	b.nextSubID++
+	b.subscribers[id] = callback
+	cfg := b.config.Load()
+	b.mu.Unlock()
+	callback(cfg)
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers, id)
+	}
+}
+
 // SubscribeName subscribes to changes on Name.
 // The callback is invoked immediately if the value is non-zero, and on future changes.
 // Returns an unsubscribe function.
@@ -283,6 +477,26 @@ func (b *ConfigLayerBroker) SubscribeDescription(callback func(*string)) func()
 	}
 }
 
+// SubscribeLogLevel subscribes to changes on LogLevel.
+// The callback is invoked immediately if the value is non-zero, and on future changes.
+// Returns an unsubscribe function.
+func (b *ConfigLayerBroker) SubscribeLogLevel(callback func(string)) func() {
+	b.mu.Lock()
+	id := b.nextSubID
+	b.nextSubID++
+	b.subsLogLevel[id] = callback
+	v := b.config.Load().LogLevel
+	b.mu.Unlock()
+	if v != "" {
+		callback(v)
+	}
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subsLogLevel, id)
+	}
+}
+
 // SubscribeHosts subscribes to changes on Hosts.
 // The callback is invoked immediately if the value is non-zero, and on future changes.
 // Returns an unsubscribe function.
@@ -425,90 +639,168 @@ func (b *ConfigLayerBroker) SubscribeUpdatedAt(callback func(*time.Time)) func()
 
 // ConfigLayer applies partial updates to the LayerBroker.
 type ConfigLayer struct {
-	broker  *ConfigLayerBroker
-	partial *ConfigPartial
+	broker   *ConfigLayerBroker
+	partial  *ConfigPartial
+	rank     int     // See configRankLayer
+	snapshot *Config // Config the layer replaces all lower layers with, set by Rollback
+	name     string  // See Named
+	actor    string  // See As
 }
 
 // Set applies the partial and notifies subscribers for changed fields.
 // Uses copy-on-write: copies the config, applies changes, then atomically swaps.
-func (l *ConfigLayer) Set(p *ConfigPartial) {
+// If the broker's validator rejects the result, the layer is left as it was and a
+// *ConfigValidationError is returned.
+func (l *ConfigLayer) Set(p *ConfigPartial) error {
 	if p == nil {
-		return
+		return nil
 	}
 	l.broker.mu.Lock()
 	defer l.broker.mu.Unlock()
-	if l.partial == nil {
-		l.partial = &ConfigPartial{}
+	prev := l.partial
+	l.partial = &ConfigPartial{}
+	if prev != nil {
+		*l.partial = *prev
 	}
 	l.mergePartial(p)
-	newCfg := l.broker.recompute()
-	oldCfg := l.broker.config.Load()
+	if err := l.broker.publish(l); err != nil {
+		l.partial = prev
+		return err
+	}
+	return nil
+}
+
+// Replace discards everything previously set on the layer and applies p in its
+// place, notifying subscribers for changed fields. A nil p clears the layer. If the
+// broker's validator rejects the result, the layer keeps its previous contents.
+func (l *ConfigLayer) Replace(p *ConfigPartial) error {
+	l.broker.mu.Lock()
+	defer l.broker.mu.Unlock()
+	prev := l.partial
+	l.partial = p
+	if err := l.broker.publish(l); err != nil {
+		l.partial = prev
+		return err
+	}
+	return nil
+}
+
+// Remove detaches the layer from the broker, so its values no longer contribute to
+// the config, and notifies subscribers for fields that change as a result. Calling
+// Set on a removed layer has no effect on the config. If the broker's validator
+// rejects the config without the layer, the layer stays attached.
+func (l *ConfigLayer) Remove() error {
+	l.broker.mu.Lock()
+	defer l.broker.mu.Unlock()
+	i := slices.Index(l.broker.layers, l)
+	if i < 0 {
+		return nil
+	}
+	l.broker.layers = slices.Delete(l.broker.layers, i, i+1)
+	if err := l.broker.publish(l); err != nil {
+		l.broker.layers = slices.Insert(l.broker.layers, i, l)
+		return err
+	}
+	return nil
+}
+
+// publish recomputes the config, notifies subscribers for changed fields and stores
+// the result. If the config changed and the validator rejects it, nothing is stored
+// and the error is reported against layer. The caller must hold b.mu.
+func (b *ConfigLayerBroker) publish(layer *ConfigLayer) error {
+	newCfg := b.recompute(b.layers)
+	oldCfg := b.config.Load()
+	if b.validate != nil && !oldCfg.Equal(newCfg) {
+		if err := b.validate(*newCfg); err != nil {
+			return &ConfigValidationError{Layer: layer.name, Err: err}
+		}
+	}
 	if old, new := oldCfg.Name, newCfg.Name; !configEqualName(old, new) {
-		for _, cb := range l.broker.subsName {
+		for _, cb := range b.subsName {
 			cb(new)
 		}
 	}
 	if old, new := oldCfg.Port, newCfg.Port; !configEqualPort(old, new) {
-		for _, cb := range l.broker.subsPort {
+		for _, cb := range b.subsPort {
 			cb(new)
 		}
 	}
 	if old, new := oldCfg.MaxRetries, newCfg.MaxRetries; !configEqualMaxRetries(old, new) {
-		for _, cb := range l.broker.subsMaxRetries {
+		for _, cb := range b.subsMaxRetries {
 			cb(new)
 		}
 	}
 	if old, new := oldCfg.Timeout, newCfg.Timeout; !configEqualTimeout(old, new) {
-		for _, cb := range l.broker.subsTimeout {
+		for _, cb := range b.subsTimeout {
 			cb(new)
 		}
 	}
 	if old, new := oldCfg.Rate, newCfg.Rate; !configEqualRate(old, new) {
-		for _, cb := range l.broker.subsRate {
+		for _, cb := range b.subsRate {
 			cb(new)
 		}
 	}
 	if old, new := oldCfg.Enabled, newCfg.Enabled; !configEqualEnabled(old, new) {
-		for _, cb := range l.broker.subsEnabled {
+		for _, cb := range b.subsEnabled {
 			cb(new)
 		}
 	}
 	if old, new := oldCfg.Description, newCfg.Description; !configEqualDescription(old, new) {
-		for _, cb := range l.broker.subsDescription {
+		for _, cb := range b.subsDescription {
+			cb(new)
+		}
+	}
+	if old, new := oldCfg.LogLevel, newCfg.LogLevel; !configEqualLogLevel(old, new) {
+		for _, cb := range b.subsLogLevel {
 			cb(new)
 		}
 	}
 	if old, new := oldCfg.Hosts, newCfg.Hosts; !configEqualHosts(old, new) {
-		for _, cb := range l.broker.subsHosts {
+		for _, cb := range b.subsHosts {
 			cb(new)
 		}
 	}
 	if old, new := oldCfg.Tags, newCfg.Tags; !configEqualTags(old, new) {
-		for _, cb := range l.broker.subsTags {
+		for _, cb := range b.subsTags {
 			cb(new)
 		}
 	}
 	if old, new := oldCfg.Labels, newCfg.Labels; !configEqualLabels(old, new) {
-		for _, cb := range l.broker.subsLabels {
+		for _, cb := range b.subsLabels {
 			cb(new)
 		}
 	}
 	if old, new := oldCfg.Metadata, newCfg.Metadata; !configEqualMetadata(old, new) {
-		for _, cb := range l.broker.subsMetadata {
+		for _, cb := range b.subsMetadata {
 			cb(new)
 		}
 	}
 	if old, new := oldCfg.CreatedAt, newCfg.CreatedAt; !configEqualCreatedAt(old, new) {
-		for _, cb := range l.broker.subsCreatedAt {
+		for _, cb := range b.subsCreatedAt {
 			cb(new)
 		}
 	}
 	if old, new := oldCfg.UpdatedAt, newCfg.UpdatedAt; !configEqualUpdatedAt(old, new) {
-		for _, cb := range l.broker.subsUpdatedAt {
+		for _, cb := range b.subsUpdatedAt {
 			cb(new)
 		}
 	}
-	l.broker.config.Store(newCfg)
+	b.config.Store(newCfg)
+	if !oldCfg.Equal(newCfg) {
+		b.record(newCfg)
+		for _, cb := range b.subscribers {
+			cb(newCfg)
+		}
+	}
+	if b.audit != nil {
+		b.audit.Audit(ConfigAuditEntry{
+			Layer:   layer.name,
+			Actor:   layer.actor,
+			Time:    time.Now(),
+			Changes: configAuditChanges(oldCfg, newCfg),
+		})
+	}
+	return nil
 }
 func configEqualName(a, b string) bool {
 	return a == b
@@ -534,6 +826,9 @@ func configEqualDescription(a, b *string) bool {
 	}
 	return a == nil || *a == *b
 }
+func configEqualLogLevel(a, b string) bool {
+	return a == b
+}
 func configEqualHosts(a, b []string) bool {
 	if len(a) != len(b) {
 		return false
@@ -611,6 +906,9 @@ func (l *ConfigLayer) mergePartial(p *ConfigPartial) {
 	if p.Description != nil {
 		l.partial.Description = p.Description
 	}
+	if p.LogLevel != nil {
+		l.partial.LogLevel = p.LogLevel
+	}
 	if p.Hosts != nil {
 		l.partial.Hosts = p.Hosts
 	}
@@ -634,10 +932,157 @@ func (l *ConfigLayer) mergePartial(p *ConfigPartial) {
 	}
 }
 
-// recompute rebuilds the config from base and all layer partials.
-func (b *ConfigLayerBroker) recompute() *Config {
-	cfg := b.base.Copy()
+// Named sets the name the layer is reported under, by validation errors and
+// Explain, and returns the layer. Layers are called "layer 1", "layer 2", ... in
+// order of creation until they are named.
+func (l *ConfigLayer) Named(name string) *ConfigLayer {
+	l.broker.mu.Lock()
+	defer l.broker.mu.Unlock()
+	l.name = name
+	return l
+}
+
+// Name returns the name of the layer (see Named).
+func (l *ConfigLayer) Name() string {
+	l.broker.mu.Lock()
+	defer l.broker.mu.Unlock()
+	return l.name
+}
+
+// As sets who the following changes of the layer are audited as, such as the user
+// of an admin API, and returns the layer.
+func (l *ConfigLayer) As(actor string) *ConfigLayer {
+	l.broker.mu.Lock()
+	defer l.broker.mu.Unlock()
+	l.actor = actor
+	return l
+}
+
+// Explain returns the name of the layer providing the current value of each field set
+// by a layer, keyed by the dotted path of the field as in ApplySparse ("Database.Host").
+// Fields that are missing have the value of the base config. A nested struct that a
+// layer cleared or replaced as a whole is reported under its own path, and slices and
+// maps under the last layer that set them.
+func (b *ConfigLayerBroker) Explain() map[string]string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	sources := make(map[string]string)
 	for _, layer := range b.layers {
+		if layer.snapshot != nil {
+			clear(sources)
+			sources["Name"] = layer.name
+			sources["Port"] = layer.name
+			sources["MaxRetries"] = layer.name
+			sources["Timeout"] = layer.name
+			sources["Rate"] = layer.name
+			sources["Enabled"] = layer.name
+			sources["Description"] = layer.name
+			sources["LogLevel"] = layer.name
+			sources["Hosts"] = layer.name
+			sources["Tags"] = layer.name
+			sources["Labels"] = layer.name
+			sources["Metadata"] = layer.name
+			sources["Database"] = layer.name
+			sources["CreatedAt"] = layer.name
+			sources["UpdatedAt"] = layer.name
+		}
+		if layer.partial != nil {
+			configExplainConfig(sources, "", layer.partial, layer.name)
+		}
+	}
+	return sources
+}
+
+// configExplainConfig records layer as the source of the fields p sets.
+func configExplainConfig(sources map[string]string, prefix string, p *ConfigPartial, layer string) {
+	if p.Name != nil {
+		configExplainSet(sources, prefix+"Name", layer)
+	}
+	if p.Port != nil {
+		configExplainSet(sources, prefix+"Port", layer)
+	}
+	if p.MaxRetries != nil {
+		configExplainSet(sources, prefix+"MaxRetries", layer)
+	}
+	if p.Timeout != nil {
+		configExplainSet(sources, prefix+"Timeout", layer)
+	}
+	if p.Rate != nil {
+		configExplainSet(sources, prefix+"Rate", layer)
+	}
+	if p.Enabled != nil {
+		configExplainSet(sources, prefix+"Enabled", layer)
+	}
+	if p.Description != nil {
+		configExplainSet(sources, prefix+"Description", layer)
+	}
+	if p.LogLevel != nil {
+		configExplainSet(sources, prefix+"LogLevel", layer)
+	}
+	if p.Hosts != nil {
+		configExplainSet(sources, prefix+"Hosts", layer)
+	}
+	if p.Tags != nil {
+		configExplainSet(sources, prefix+"Tags", layer)
+	}
+	if p.Labels != nil {
+		configExplainSet(sources, prefix+"Labels", layer)
+	}
+	if p.Metadata != nil {
+		configExplainSet(sources, prefix+"Metadata", layer)
+	}
+	if p.Database != nil {
+		configExplainDatabaseConfig(sources, prefix+"Database.", p.Database, layer)
+	}
+	if p.CreatedAt != nil {
+		configExplainSet(sources, prefix+"CreatedAt", layer)
+	}
+	if p.UpdatedAt != nil {
+		configExplainSet(sources, prefix+"UpdatedAt", layer)
+	}
+}
+
+// configExplainDatabaseConfig records layer as the source of the fields p sets.
+func configExplainDatabaseConfig(sources map[string]string, prefix string, p *DatabaseConfigPartial, layer string) {
+	if p.Driver != nil {
+		configExplainSet(sources, prefix+"Driver", layer)
+	}
+	if p.Host != nil {
+		configExplainSet(sources, prefix+"Host", layer)
+	}
+	if p.Port != nil {
+		configExplainSet(sources, prefix+"Port", layer)
+	}
+	if p.Username != nil {
+		configExplainSet(sources, prefix+"Username", layer)
+	}
+	if p.Password != nil {
+		configExplainSet(sources, prefix+"Password", layer)
+	}
+	if p.SSLMode != nil {
+		configExplainSet(sources, prefix+"SSLMode", layer)
+	}
+}
+
+// configExplainSet records layer as the source of path, which replaces the
+// sources of any fields nested in it.
+func configExplainSet(sources map[string]string, path, layer string) {
+	for p := range sources {
+		if strings.HasPrefix(p, path+".") {
+			delete(sources, p)
+		}
+	}
+	sources[path] = layer
+}
+
+// recompute rebuilds the config from base and the partials of layers.
+func (b *ConfigLayerBroker) recompute(layers []*ConfigLayer) *Config {
+	cfg := b.base.Copy()
+	for _, layer := range layers {
+		if layer.snapshot != nil {
+			// cfg isn't published yet, so its slices and maps can be reused
+			layer.snapshot.CopyInto(cfg)
+		}
 		if layer.partial != nil {
 			cfg.ApplyPartial(layer.partial)
 		}
@@ -645,6 +1090,168 @@ func (b *ConfigLayerBroker) recompute() *Config {
 	return cfg
 }
 
+// ConfigFieldChange is a top-level field of Config whose value a change alters.
+type ConfigFieldChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+// PreviewLayer returns the config that setting p on the layer called name would
+// produce, and the fields that would change, without applying anything. If several
+// layers have the name the highest one is previewed, and if none has it, a new layer
+// created by Layer is. When the broker's validator rejects the previewed config, its
+// *ConfigValidationError is returned along with the preview, so that a confirm
+// step can show what would be rejected.
+func (b *ConfigLayerBroker) PreviewLayer(name string, p *ConfigPartial) (*Config, []ConfigFieldChange, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	preview := &ConfigLayer{broker: b, partial: &ConfigPartial{}, rank: configRankLayer, name: name}
+	layers := slices.Clone(b.layers)
+	i := len(layers) - 1
+	for i >= 0 && layers[i].name != name {
+		i--
+	}
+	if i >= 0 {
+		if layers[i].partial != nil {
+			*preview.partial = *layers[i].partial
+		}
+		preview.snapshot = layers[i].snapshot
+		layers[i] = preview
+	} else {
+		layers = configInsertLayer(layers, preview)
+	}
+	if p != nil {
+		preview.mergePartial(p)
+	}
+	oldCfg := b.config.Load()
+	newCfg := b.recompute(layers)
+	changes := configChanges(oldCfg, newCfg)
+	if b.validate != nil && !oldCfg.Equal(newCfg) {
+		if err := b.validate(*newCfg); err != nil {
+			return newCfg, changes, &ConfigValidationError{Layer: name, Err: err}
+		}
+	}
+	return newCfg, changes, nil
+}
+
+// configChanges returns the top-level fields whose values differ between
+// old and new.
+func configChanges(old, new *Config) []ConfigFieldChange {
+	var changes []ConfigFieldChange
+	if !configEqualName(old.Name, new.Name) {
+		changes = append(changes, ConfigFieldChange{Field: "Name", Old: old.Name, New: new.Name})
+	}
+	if !configEqualPort(old.Port, new.Port) {
+		changes = append(changes, ConfigFieldChange{Field: "Port", Old: old.Port, New: new.Port})
+	}
+	if !configEqualMaxRetries(old.MaxRetries, new.MaxRetries) {
+		changes = append(changes, ConfigFieldChange{Field: "MaxRetries", Old: old.MaxRetries, New: new.MaxRetries})
+	}
+	if !configEqualTimeout(old.Timeout, new.Timeout) {
+		changes = append(changes, ConfigFieldChange{Field: "Timeout", Old: old.Timeout, New: new.Timeout})
+	}
+	if !configEqualRate(old.Rate, new.Rate) {
+		changes = append(changes, ConfigFieldChange{Field: "Rate", Old: old.Rate, New: new.Rate})
+	}
+	if !configEqualEnabled(old.Enabled, new.Enabled) {
+		changes = append(changes, ConfigFieldChange{Field: "Enabled", Old: old.Enabled, New: new.Enabled})
+	}
+	if !configEqualDescription(old.Description, new.Description) {
+		changes = append(changes, ConfigFieldChange{Field: "Description", Old: old.Description, New: new.Description})
+	}
+	if !configEqualLogLevel(old.LogLevel, new.LogLevel) {
+		changes = append(changes, ConfigFieldChange{Field: "LogLevel", Old: old.LogLevel, New: new.LogLevel})
+	}
+	if !configEqualHosts(old.Hosts, new.Hosts) {
+		changes = append(changes, ConfigFieldChange{Field: "Hosts", Old: old.Hosts, New: new.Hosts})
+	}
+	if !configEqualTags(old.Tags, new.Tags) {
+		changes = append(changes, ConfigFieldChange{Field: "Tags", Old: old.Tags, New: new.Tags})
+	}
+	if !configEqualLabels(old.Labels, new.Labels) {
+		changes = append(changes, ConfigFieldChange{Field: "Labels", Old: old.Labels, New: new.Labels})
+	}
+	if !configEqualMetadata(old.Metadata, new.Metadata) {
+		changes = append(changes, ConfigFieldChange{Field: "Metadata", Old: old.Metadata, New: new.Metadata})
+	}
+	if !old.Database.Equal(new.Database) {
+		changes = append(changes, ConfigFieldChange{Field: "Database", Old: old.Database, New: new.Database})
+	}
+	if !configEqualCreatedAt(old.CreatedAt, new.CreatedAt) {
+		changes = append(changes, ConfigFieldChange{Field: "CreatedAt", Old: old.CreatedAt, New: new.CreatedAt})
+	}
+	if !configEqualUpdatedAt(old.UpdatedAt, new.UpdatedAt) {
+		changes = append(changes, ConfigFieldChange{Field: "UpdatedAt", Old: old.UpdatedAt, New: new.UpdatedAt})
+	}
+	return changes
+}
+
+// configAuditRedacted replaces the values of fields holding secrets in audit entries.
+const configAuditRedacted = "[REDACTED]"
+
+// configAuditChanges returns the changes between old and new reported to
+// the audit sink, with the values of fields holding secrets redacted.
+func configAuditChanges(old, new *Config) []ConfigFieldChange {
+	changes := configChanges(old, new)
+	for i, change := range changes {
+		switch change.Field {
+		case "Database":
+			changes[i].Old, changes[i].New = configAuditRedacted, configAuditRedacted
+		}
+	}
+	return changes
+}
+
+// ConfigSnapshot is a merged configuration recorded in the history of a
+// ConfigLayerBroker.
+type ConfigSnapshot struct {
+	Config *Config
+	Time   time.Time // When the config took effect
+}
+
+// record adds cfg to the history, dropping the oldest snapshot once it holds
+// 10. The caller must hold b.mu.
+func (b *ConfigLayerBroker) record(cfg *Config) {
+	b.history[b.snapshots%len(b.history)] = ConfigSnapshot{Config: cfg, Time: time.Now()}
+	b.snapshots++
+}
+
+// History returns the last 10 merged configurations, newest first, so that
+// History()[0] is the current config and History()[n] is the one Rollback(n) restores.
+func (b *ConfigLayerBroker) History() []ConfigSnapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := min(b.snapshots, len(b.history))
+	history := make([]ConfigSnapshot, n)
+	for i := range history {
+		s := b.history[(b.snapshots-1-i)%len(b.history)]
+		history[i] = ConfigSnapshot{Config: s.Config.Copy(), Time: s.Time}
+	}
+	return history
+}
+
+// Rollback restores the config from n changes ago (see History) by adding a top layer
+// that replaces every layer below it with that snapshot. Removing the returned layer
+// undoes the rollback. Layers created with TopLayer after it still apply on top. The
+// layer is not added if the broker's validator rejects the restored config.
+func (b *ConfigLayerBroker) Rollback(n int) (*ConfigLayer, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if kept := min(b.snapshots, len(b.history)); n < 1 || n >= kept {
+		return nil, fmt.Errorf("cannot roll back %d changes: history holds %d previous configs", n, kept-1)
+	}
+	l := &ConfigLayer{broker: b, rank: configRankTop, snapshot: b.history[(b.snapshots-1-n)%len(b.history)].Config}
+	b.created++
+	l.name = "rollback " + strconv.Itoa(n)
+	b.layers = configInsertLayer(b.layers, l)
+	if err := b.publish(l); err != nil {
+		b.layers = slices.DeleteFunc(b.layers, func(layer *ConfigLayer) bool { return layer == l })
+		return nil, err
+	}
+	return l, nil
+}
+
 // ConfigLayerBrokerState represents the serializable state of the broker.
 type ConfigLayerBrokerState struct {
 	Base   *Config          `json:"base"`
@@ -0,0 +1,288 @@
+// Code generated by sudo-gen layerbroker. DO NOT EDIT.
+// Generated by sudo-gen (devel): layerbroker -tests -json -merge-patch -json-patch -http -sighup -provenance -audit -history=10 -groups=defaults,file,env -redact -constant-time-secrets -bench -examples
+
+package basic
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+)
+
+// ConfigHTTPHandler exposes a ConfigLayerBroker as a runtime configuration
+// admin API:
+//
+//	GET    /config                 merged configuration as JSON
+//	GET    /config/stream          Server-Sent Events stream of the merged configuration
+//	GET    /layers                 named layers in priority order, lowest first
+//	PUT    /layers/{name}          apply a ConfigPartial to the named layer, creating it on first use
+//	POST   /layers/{name}/preview  the result of a PUT with the same body, without applying it
+//	DELETE /layers/{name}          remove the named layer
+//	GET    /explain                name of the layer providing each field, as returned by Explain
+//
+// Layers created through the handler are appended above all existing layers. Layers
+// created directly with Layer() are not listed. A PUT or DELETE that the broker's
+// validator rejects fails with 422 Unprocessable Entity and leaves the layer as it was.
+// A preview the validator rejects is still returned, with the error set.
+//
+// Fields tagged sudo:"secret" are masked in every response, including the config
+// stream and the changes of a preview. They can still be set with PUT.
+type ConfigHTTPHandler struct {
+	broker *ConfigLayerBroker
+	mux    *http.ServeMux
+	mu     sync.Mutex // protects layers and names
+	layers map[string]*ConfigLayer
+	names  []string
+}
+
+// ConfigHTTPLayer is a named layer as listed by GET /layers.
+type ConfigHTTPLayer struct {
+	Name    string         `json:"name"`
+	Partial *ConfigPartial `json:"partial"`
+}
+
+// ConfigHTTPPreview is the response of POST /layers/{name}/preview.
+type ConfigHTTPPreview struct {
+	Config  *Config             `json:"config"`
+	Changes []ConfigFieldChange `json:"changes"`
+	Error   string              `json:"error,omitempty"`
+}
+
+// NewConfigHTTPHandler returns an http.Handler serving the admin API for broker.
+func NewConfigHTTPHandler(broker *ConfigLayerBroker) *ConfigHTTPHandler {
+	h := &ConfigHTTPHandler{
+		broker: broker,
+		mux:    http.NewServeMux(),
+		layers: make(map[string]*ConfigLayer),
+	}
+	h.mux.HandleFunc("GET /config", h.getConfig)
+	h.mux.HandleFunc("GET /config/stream", h.streamConfig)
+	h.mux.HandleFunc("GET /layers", h.listLayers)
+	h.mux.HandleFunc("PUT /layers/{name}", h.putLayer)
+	h.mux.HandleFunc("POST /layers/{name}/preview", h.previewLayer)
+	h.mux.HandleFunc("DELETE /layers/{name}", h.deleteLayer)
+	h.mux.HandleFunc("GET /explain", h.explain)
+	return h
+}
+
+// ServeHTTP implements http.Handler.
+func (h *ConfigHTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *ConfigHTTPHandler) getConfig(w http.ResponseWriter, r *http.Request) {
+	configWriteJSON(w, configMaskSecrets(h.broker.Get(), ""))
+}
+
+// streamConfig sends the merged configuration as a "config" event when the client
+// connects and again after every change. Slow clients skip to the latest snapshot.
+func (h *ConfigHTTPHandler) streamConfig(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	updates := make(chan *Config, 1)
+	unsub := h.broker.Subscribe(func(cfg *Config) {
+		for {
+			select {
+			case updates <- cfg:
+				return
+			default:
+			}
+			select {
+			case <-updates:
+			default:
+			}
+		}
+	})
+	defer unsub()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case cfg := <-updates:
+			data, err := json.Marshal(configMaskSecrets(cfg, ""))
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: config\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func (h *ConfigHTTPHandler) listLayers(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.broker.mu.Lock()
+	defer h.broker.mu.Unlock()
+	layers := make([]ConfigHTTPLayer, 0, len(h.names))
+	for _, name := range h.names {
+		partial := h.layers[name].partial
+		if partial == nil {
+			partial = &ConfigPartial{}
+		}
+		layers = append(layers, ConfigHTTPLayer{Name: name, Partial: partial})
+	}
+	configWriteJSON(w, configMaskSecrets(layers, "", "partial"))
+}
+
+func (h *ConfigHTTPHandler) putLayer(w http.ResponseWriter, r *http.Request) {
+	var p ConfigPartial
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		http.Error(w, "decoding partial: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	name := r.PathValue("name")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	layer, ok := h.layers[name]
+	if !ok {
+		layer = h.broker.Layer().Named(name)
+	}
+	if err := layer.Set(&p); err != nil {
+		if !ok {
+			layer.Remove()
+		}
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if !ok {
+		h.layers[name] = layer
+		h.names = append(h.names, name)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ConfigHTTPHandler) previewLayer(w http.ResponseWriter, r *http.Request) {
+	var p ConfigPartial
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		http.Error(w, "decoding partial: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	cfg, changes, err := h.broker.PreviewLayer(r.PathValue("name"), &p)
+	preview := ConfigHTTPPreview{Config: cfg, Changes: changes}
+	if preview.Changes == nil {
+		preview.Changes = []ConfigFieldChange{}
+	}
+	if err != nil {
+		preview.Error = err.Error()
+	}
+	for i, change := range preview.Changes {
+		preview.Changes[i].Old = configMaskSecrets(change.Old, change.Field)
+		preview.Changes[i].New = configMaskSecrets(change.New, change.Field)
+	}
+	configWriteJSON(w, configMaskSecrets(preview, "", "config"))
+}
+
+func (h *ConfigHTTPHandler) deleteLayer(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	layer, ok := h.layers[name]
+	if !ok {
+		http.Error(w, "layer "+name+" not found", http.StatusNotFound)
+		return
+	}
+	if err := layer.Remove(); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	delete(h.layers, name)
+	h.names = slices.DeleteFunc(h.names, func(n string) bool { return n == name })
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ConfigHTTPHandler) explain(w http.ResponseWriter, r *http.Request) {
+	configWriteJSON(w, h.broker.Explain())
+}
+
+func configWriteJSON(w http.ResponseWriter, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, "encoding response: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// configSecretPaths locates the fields tagged sudo:"secret" in the JSON
+// encoding of a Config or its partial. A "*" key matches every key of a map, and
+// arrays are walked element by element.
+var configSecretPaths = []struct {
+	field string   // Top-level field holding the secret
+	keys  []string // Object keys from the root
+}{
+	{"Database", []string{"database", "password"}},
+}
+
+// configSecretMask replaces the values of secret fields in responses.
+const configSecretMask = "********"
+
+// configMaskSecrets returns v, encoded as JSON and decoded again, with the
+// secrets of the config found at prefix replaced by configSecretMask. If field
+// is set, v holds the value of that top-level field rather than a whole config.
+func configMaskSecrets(v any, field string, prefix ...string) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v // Writing v fails in the same way
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var masked any
+	if err := dec.Decode(&masked); err != nil {
+		return v
+	}
+	for _, secret := range configSecretPaths {
+		keys := secret.keys
+		if field != "" {
+			if secret.field != field {
+				continue
+			}
+			keys = keys[1:]
+		}
+		masked = configMaskPath(masked, slices.Concat(prefix, keys))
+	}
+	return masked
+}
+
+// configMaskPath masks the values at keys below v, which is decoded JSON.
+// Null and zero values are left as they are, so that unset secrets can be told from
+// set ones.
+func configMaskPath(v any, keys []string) any {
+	if len(keys) == 0 {
+		switch v {
+		case nil, "", false, json.Number("0"):
+			return v
+		}
+		return configSecretMask
+	}
+	switch v := v.(type) {
+	case []any:
+		for i := range v {
+			v[i] = configMaskPath(v[i], keys)
+		}
+	case map[string]any:
+		for key := range v {
+			if keys[0] == "*" || keys[0] == key {
+				v[key] = configMaskPath(v[key], keys[1:])
+			}
+		}
+	}
+	return v
+}
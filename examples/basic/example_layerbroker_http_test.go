@@ -0,0 +1,206 @@
+// Code generated by sudo-gen layerbroker. DO NOT EDIT.
+// Generated by sudo-gen (devel): layerbroker -tests -json -merge-patch -json-patch -http -sighup -provenance -audit -history=10 -groups=defaults,file,env -redact -constant-time-secrets -bench -examples
+
+package basic
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func configServeAdmin(t *testing.T, h http.Handler, method, path string, body []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, path, bytes.NewReader(body)))
+	return rec
+}
+
+func TestConfigHTTPHandlerGetConfig(t *testing.T) {
+	h := NewConfigHTTPHandler(NewConfigLayerBroker(nil))
+	rec := configServeAdmin(t, h, http.MethodGet, "/config", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var cfg Config
+	if err := json.Unmarshal(rec.Body.Bytes(), &cfg); err != nil {
+		t.Fatalf("response is not a Config: %v", err)
+	}
+}
+
+func TestConfigHTTPHandlerLayerLifecycle(t *testing.T) {
+	broker := NewConfigLayerBroker(nil)
+	h := NewConfigHTTPHandler(broker)
+	body, err := json.Marshal(&ConfigPartial{Name: sudogenPtr("from-http")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec := configServeAdmin(t, h, http.MethodPut, "/layers/admin", body); rec.Code != http.StatusNoContent {
+		t.Fatalf("PUT: expected 204, got %d: %s", rec.Code, rec.Body)
+	}
+	if got := broker.Get().Name; got != "from-http" {
+		t.Errorf("expected Name=from-http, got %s", got)
+	}
+	rec := configServeAdmin(t, h, http.MethodGet, "/layers", nil)
+	var layers []ConfigHTTPLayer
+	if err := json.Unmarshal(rec.Body.Bytes(), &layers); err != nil {
+		t.Fatalf("GET /layers: %v", err)
+	}
+	if len(layers) != 1 || layers[0].Name != "admin" {
+		t.Fatalf("expected layer admin, got %+v", layers)
+	}
+	rec = configServeAdmin(t, h, http.MethodGet, "/explain", nil)
+	var sources map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &sources); err != nil {
+		t.Fatalf("GET /explain: %v", err)
+	}
+	if sources["Name"] != "admin" {
+		t.Errorf("expected Name to come from layer admin, got %v", sources)
+	}
+	if rec := configServeAdmin(t, h, http.MethodDelete, "/layers/admin", nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("DELETE: expected 204, got %d", rec.Code)
+	}
+	if got := broker.Get().Name; got != "" {
+		t.Errorf("expected Name to be reset after DELETE, got %s", got)
+	}
+	if rec := configServeAdmin(t, h, http.MethodDelete, "/layers/admin", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("second DELETE: expected 404, got %d", rec.Code)
+	}
+}
+
+func TestConfigHTTPHandlerStreamConfig(t *testing.T) {
+	h := NewConfigHTTPHandler(NewConfigLayerBroker(nil))
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/config/stream", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected text/event-stream, got %q", ct)
+	}
+	events := bufio.NewReader(resp.Body)
+	if _, err := configReadEvent(events); err != nil {
+		t.Fatalf("reading initial snapshot: %v", err)
+	}
+	body, err := json.Marshal(&ConfigPartial{Name: sudogenPtr("streamed")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	configServeAdmin(t, h, http.MethodPut, "/layers/stream", body)
+	cfg, err := configReadEvent(events)
+	if err != nil {
+		t.Fatalf("reading update: %v", err)
+	}
+	if cfg.Name != "streamed" {
+		t.Errorf("expected Name=streamed, got %s", cfg.Name)
+	}
+}
+
+// configReadEvent reads one Server-Sent Event and decodes its data.
+func configReadEvent(r *bufio.Reader) (*Config, error) {
+	var data string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" && data != "" {
+			break
+		}
+		if v, ok := strings.CutPrefix(line, "data: "); ok {
+			data = v
+		}
+	}
+	var cfg Config
+	if err := json.Unmarshal([]byte(data), &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func TestConfigHTTPHandlerPutInvalidPartial(t *testing.T) {
+	h := NewConfigHTTPHandler(NewConfigLayerBroker(nil))
+	rec := configServeAdmin(t, h, http.MethodPut, "/layers/bad", []byte("{not json"))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rec.Code)
+	}
+}
+
+func TestConfigHTTPHandlerPreviewLayer(t *testing.T) {
+	broker := NewConfigLayerBroker(nil)
+	h := NewConfigHTTPHandler(broker)
+	body, err := json.Marshal(&ConfigPartial{Name: sudogenPtr("previewed")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := configServeAdmin(t, h, http.MethodPost, "/layers/admin/preview", body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var preview ConfigHTTPPreview
+	if err := json.Unmarshal(rec.Body.Bytes(), &preview); err != nil {
+		t.Fatal(err)
+	}
+	if preview.Config.Name != "previewed" || len(preview.Changes) != 1 || preview.Error != "" {
+		t.Errorf("expected a preview changing Name, got %+v", preview)
+	}
+	if got := broker.Get().Name; got != "" {
+		t.Errorf("expected the preview not to apply, got Name=%s", got)
+	}
+	if rec := configServeAdmin(t, h, http.MethodDelete, "/layers/admin", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected the preview not to create the layer, got %d", rec.Code)
+	}
+}
+
+func TestConfigHTTPHandlerPutRejected(t *testing.T) {
+	broker := NewConfigLayerBroker(nil, WithConfigValidator(func(cfg Config) error {
+		return errors.New("rejected")
+	}))
+	h := NewConfigHTTPHandler(broker)
+	body, err := json.Marshal(&ConfigPartial{Name: sudogenPtr("from-http")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec := configServeAdmin(t, h, http.MethodPut, "/layers/admin", body); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("PUT: expected 422, got %d: %s", rec.Code, rec.Body)
+	}
+	if got := broker.Get().Name; got != "" {
+		t.Errorf("expected the rejected layer not to apply, got Name=%s", got)
+	}
+	if rec := configServeAdmin(t, h, http.MethodDelete, "/layers/admin", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected the rejected layer not to be created, got %d", rec.Code)
+	}
+}
+
+func TestConfigHTTPHandlerMasksSecrets(t *testing.T) {
+	h := NewConfigHTTPHandler(NewConfigLayerBroker(nil))
+	if rec := configServeAdmin(t, h, http.MethodPut, "/layers/admin", []byte("{\"database\":{\"password\":\"hunter2\"}}")); rec.Code != http.StatusNoContent {
+		t.Fatalf("PUT: expected 204, got %d: %s", rec.Code, rec.Body)
+	}
+	for _, req := range []struct{ method, path, body string }{
+		{http.MethodGet, "/config", ""},
+		{http.MethodGet, "/layers", ""},
+		{http.MethodPost, "/layers/admin/preview", "{\"database\":{\"password\":\"hunter3\"}}"},
+	} {
+		rec := configServeAdmin(t, h, req.method, req.path, []byte(req.body))
+		if body := rec.Body.String(); strings.Contains(body, "hunter") || !strings.Contains(body, configSecretMask) {
+			t.Errorf("%s %s: expected Database to be masked, got %s", req.method, req.path, body)
+		}
+	}
+}
@@ -1,17 +1,18 @@
 // Code generated by sudo-gen layerbroker. DO NOT EDIT.
+// Generated by sudo-gen (devel): layerbroker -tests -json -merge-patch -json-patch -http -sighup -provenance -audit -history=10 -groups=defaults,file,env -redact -constant-time-secrets -bench -examples
 
 package basic
 
 import (
 	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
 
-func configPtr[T any](v T) *T {
-	return &v
-}
-
 func TestConfigLayerBrokerSubscriptionOrder(t *testing.T) {
 	broker := NewConfigLayerBroker(&Config{Name: "initial", Port: 8080})
 	layer1 := broker.Layer()
@@ -31,7 +32,7 @@ func TestConfigLayerBrokerSubscriptionOrder(t *testing.T) {
 	if len(intUpdates) != 1 || intUpdates[0] != 8080 {
 		t.Fatalf("expected initial Port callback, got %v", intUpdates)
 	}
-	layer1.Set(&ConfigPartial{Name: configPtr("updated")})
+	layer1.Set(&ConfigPartial{Name: sudogenPtr("updated")})
 	if len(stringUpdates) != 2 || stringUpdates[1] != "updated" {
 		t.Fatalf("expected Name update, got %v", stringUpdates)
 	}
@@ -39,7 +40,7 @@ func TestConfigLayerBrokerSubscriptionOrder(t *testing.T) {
 		t.Fatalf("Port subscriber should not have been called, got %v", intUpdates)
 	}
 	layer2 := broker.Layer()
-	layer2.Set(&ConfigPartial{Port: configPtr(9090)})
+	layer2.Set(&ConfigPartial{Port: sudogenPtr(9090)})
 	if len(intUpdates) != 2 || intUpdates[1] != 9090 {
 		t.Fatalf("expected Port update, got %v", intUpdates)
 	}
@@ -59,8 +60,8 @@ func TestConfigLayerBrokerLowerLayerOverridden(t *testing.T) {
 	broker := NewConfigLayerBroker(nil)
 	layer1 := broker.Layer()
 	layer2 := broker.Layer()
-	layer1.Set(&ConfigPartial{Name: configPtr("one")})
-	layer2.Set(&ConfigPartial{Name: configPtr("two"), Port: configPtr(8080)})
+	layer1.Set(&ConfigPartial{Name: sudogenPtr("one")})
+	layer2.Set(&ConfigPartial{Name: sudogenPtr("two"), Port: sudogenPtr(8080)})
 	var updates []string
 	unsub := broker.SubscribeName(func(v string) {
 		updates = append(updates, v)
@@ -69,7 +70,7 @@ func TestConfigLayerBrokerLowerLayerOverridden(t *testing.T) {
 	if len(updates) != 1 || updates[0] != "two" {
 		t.Fatalf("expected initial callback with 'two', got %v", updates)
 	}
-	layer1.Set(&ConfigPartial{Name: configPtr("three")})
+	layer1.Set(&ConfigPartial{Name: sudogenPtr("three")})
 	if len(updates) != 1 {
 		t.Fatalf("expected no update when lower layer is overridden, got %v", updates)
 	}
@@ -86,9 +87,9 @@ func TestConfigLayerBrokerMultipleLayersPriority(t *testing.T) {
 	layer3 := broker.Layer()
 
 	// Set same field in all layers - last layer should win
-	layer1.Set(&ConfigPartial{Name: configPtr("layer1")})
-	layer2.Set(&ConfigPartial{Name: configPtr("layer2")})
-	layer3.Set(&ConfigPartial{Name: configPtr("layer3")})
+	layer1.Set(&ConfigPartial{Name: sudogenPtr("layer1")})
+	layer2.Set(&ConfigPartial{Name: sudogenPtr("layer2")})
+	layer3.Set(&ConfigPartial{Name: sudogenPtr("layer3")})
 
 	cfg := broker.Get()
 	if cfg.Name != "layer3" {
@@ -96,13 +97,32 @@ func TestConfigLayerBrokerMultipleLayersPriority(t *testing.T) {
 	}
 
 	// Update layer2, but layer3 still wins
-	layer2.Set(&ConfigPartial{Name: configPtr("layer2-updated")})
+	layer2.Set(&ConfigPartial{Name: sudogenPtr("layer2-updated")})
 	cfg = broker.Get()
 	if cfg.Name != "layer3" {
 		t.Errorf("expected Name=layer3 (higher layer should still win), got %s", cfg.Name)
 	}
 }
 
+func TestConfigLayerBrokerTopLayer(t *testing.T) {
+	broker := NewConfigLayerBroker(nil)
+	top := broker.TopLayer()
+	top.Set(&ConfigPartial{Name: sudogenPtr("top")})
+	layer := broker.Layer()
+	layer.Set(&ConfigPartial{Name: sudogenPtr("layer")})
+	if got := broker.Get().Name; got != "top" {
+		t.Errorf("expected Name=top (top layer should win over later layers), got %s", got)
+	}
+	top.Remove()
+	if got := broker.Get().Name; got != "layer" {
+		t.Errorf("expected Name=layer after removing the top layer, got %s", got)
+	}
+	broker.Layer().Set(&ConfigPartial{Name: sudogenPtr("newest")})
+	if got := broker.Get().Name; got != "newest" {
+		t.Errorf("expected Name=newest, got %s", got)
+	}
+}
+
 func TestConfigLayerBrokerMultipleSubscribers(t *testing.T) {
 	broker := NewConfigLayerBroker(&Config{Name: "initial"})
 	var updates1, updates2 []string
@@ -121,7 +141,7 @@ func TestConfigLayerBrokerMultipleSubscribers(t *testing.T) {
 		t.Fatalf("expected both subscribers to get initial value")
 	}
 
-	broker.Layer().Set(&ConfigPartial{Name: configPtr("updated")})
+	broker.Layer().Set(&ConfigPartial{Name: sudogenPtr("updated")})
 
 	if len(updates1) != 2 || updates1[1] != "updated" {
 		t.Errorf("expected subscriber1 to get update, got %v", updates1)
@@ -131,6 +151,81 @@ func TestConfigLayerBrokerMultipleSubscribers(t *testing.T) {
 	}
 }
 
+func TestConfigLayerBrokerExplain(t *testing.T) {
+	broker := NewConfigLayerBroker(&Config{Name: "base"})
+	if got := broker.Explain(); len(got) != 0 {
+		t.Errorf("expected no layer sources, got %v", got)
+	}
+	file := broker.Layer().Named("file")
+	file.Set(&ConfigPartial{Name: sudogenPtr("file")})
+	env := broker.Layer()
+	env.Set(&ConfigPartial{Name: sudogenPtr("env")})
+	if got := broker.Explain()["Name"]; got != env.Name() || got == "" {
+		t.Errorf("expected Name from the unnamed layer %q, got %q", env.Name(), got)
+	}
+	env.Remove()
+	if got := broker.Explain()["Name"]; got != "file" {
+		t.Errorf("expected Name from layer file, got %q", got)
+	}
+	file.Remove()
+	if got := broker.Explain(); len(got) != 0 {
+		t.Errorf("expected no layer sources after removing all layers, got %v", got)
+	}
+}
+
+func TestConfigLayerBrokerRollback(t *testing.T) {
+	broker := NewConfigLayerBroker(&Config{Name: "v0"})
+	layer := broker.Layer()
+	layer.Set(&ConfigPartial{Name: sudogenPtr("v1")})
+	layer.Set(&ConfigPartial{Name: sudogenPtr("v1")}) // unchanged, not recorded
+	layer.Set(&ConfigPartial{Name: sudogenPtr("v2")})
+	history := broker.History()
+	if len(history) != min(3, 10) || history[0].Config.Name != "v2" {
+		t.Fatalf("expected history v2, v1, v0, got %+v", history)
+	}
+	rollback, err := broker.Rollback(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := broker.Get().Name; got != "v1" {
+		t.Errorf("expected Name=v1 after rolling back, got %s", got)
+	}
+	layer.Set(&ConfigPartial{Name: sudogenPtr("v3")})
+	if got := broker.Get().Name; got != "v1" {
+		t.Errorf("expected the rollback to hide later changes to lower layers, got %s", got)
+	}
+	rollback.Remove()
+	if got := broker.Get().Name; got != "v3" {
+		t.Errorf("expected Name=v3 after removing the rollback, got %s", got)
+	}
+	if _, err := broker.Rollback(0); err == nil {
+		t.Error("expected an error rolling back to the current config")
+	}
+	if _, err := broker.Rollback(10); err == nil {
+		t.Error("expected an error rolling back beyond the history")
+	}
+}
+
+func TestConfigLayerBrokerHistoryLimit(t *testing.T) {
+	broker := NewConfigLayerBroker(nil)
+	layer := broker.Layer()
+	for i := range 10 + 2 {
+		layer.Set(&ConfigPartial{Name: sudogenPtr(strconv.Itoa(i))})
+	}
+	history := broker.History()
+	if len(history) != 10 {
+		t.Fatalf("expected 10 snapshots, got %d", len(history))
+	}
+	if got, want := history[0].Config.Name, strconv.Itoa(10+1); got != want {
+		t.Errorf("expected newest snapshot %s, got %s", want, got)
+	}
+	for i := 1; i < len(history); i++ {
+		if history[i].Time.After(history[i-1].Time) {
+			t.Errorf("expected snapshots newest first, got %v after %v", history[i].Time, history[i-1].Time)
+		}
+	}
+}
+
 func TestConfigLayerBrokerUnsubscribe(t *testing.T) {
 	broker := NewConfigLayerBroker(&Config{Name: "test"})
 	var updates []string
@@ -140,12 +235,12 @@ func TestConfigLayerBrokerUnsubscribe(t *testing.T) {
 	if len(updates) != 1 {
 		t.Fatalf("expected 1 update, got %d", len(updates))
 	}
-	broker.Layer().Set(&ConfigPartial{Name: configPtr("changed")})
+	broker.Layer().Set(&ConfigPartial{Name: sudogenPtr("changed")})
 	if len(updates) != 2 {
 		t.Fatalf("expected 2 updates, got %d", len(updates))
 	}
 	unsub()
-	broker.Layer().Set(&ConfigPartial{Name: configPtr("ignored")})
+	broker.Layer().Set(&ConfigPartial{Name: sudogenPtr("ignored")})
 	if len(updates) != 2 {
 		t.Fatalf("expected 2 updates after unsubscribe, got %d", len(updates))
 	}
@@ -154,6 +249,184 @@ func TestConfigLayerBrokerUnsubscribe(t *testing.T) {
 	}
 }
 
+func TestConfigLayerBrokerRemoveLayer(t *testing.T) {
+	broker := NewConfigLayerBroker(&Config{Name: "base"})
+	layer := broker.Layer()
+	layer.Set(&ConfigPartial{Name: sudogenPtr("layer")})
+	var updates []string
+	unsub := broker.SubscribeName(func(v string) {
+		updates = append(updates, v)
+	})
+	defer unsub()
+	layer.Remove()
+	if len(updates) != 2 || updates[1] != "base" {
+		t.Fatalf("expected update back to base, got %v", updates)
+	}
+	layer.Set(&ConfigPartial{Name: sudogenPtr("ignored")})
+	if got := broker.Get().Name; got != "base" {
+		t.Errorf("removed layer should not apply, got Name=%s", got)
+	}
+}
+
+func TestConfigLayerBrokerSubscribe(t *testing.T) {
+	broker := NewConfigLayerBroker(nil)
+	var updates []*Config
+	unsub := broker.Subscribe(func(cfg *Config) {
+		updates = append(updates, cfg)
+	})
+	defer unsub()
+	if len(updates) != 1 {
+		t.Fatalf("expected initial callback, got %d", len(updates))
+	}
+	layer := broker.Layer()
+	layer.Set(&ConfigPartial{Name: sudogenPtr("changed")})
+	if len(updates) != 2 || updates[1].Name != "changed" {
+		t.Fatalf("expected update with Name=changed, got %d updates", len(updates))
+	}
+	layer.Set(&ConfigPartial{Name: sudogenPtr("changed")})
+	if len(updates) != 2 {
+		t.Fatalf("expected no update when nothing changed, got %d updates", len(updates))
+	}
+}
+
+func TestConfigLayerBrokerReplaceLayer(t *testing.T) {
+	broker := NewConfigLayerBroker(&Config{Name: "base"})
+	layer := broker.Layer()
+	layer.Set(&ConfigPartial{Name: sudogenPtr("first")})
+	layer.Replace(&ConfigPartial{})
+	if got := broker.Get().Name; got != "base" {
+		t.Errorf("expected Replace to drop earlier values, got Name=%s", got)
+	}
+	layer.Replace(&ConfigPartial{Name: sudogenPtr("second")})
+	if got := broker.Get().Name; got != "second" {
+		t.Errorf("expected Name=second, got %s", got)
+	}
+	layer.Replace(nil)
+	if got := broker.Get().Name; got != "base" {
+		t.Errorf("expected nil Replace to clear the layer, got Name=%s", got)
+	}
+}
+
+func TestConfigLayerBrokerValidator(t *testing.T) {
+	// The initial config is not validated, so the base can hold a rejected value
+	broker := NewConfigLayerBroker(&Config{Name: "invalid"}, WithConfigValidator(func(cfg Config) error {
+		if cfg.Name == "invalid" {
+			return errors.New("Name is invalid")
+		}
+		return nil
+	}))
+	layer := broker.Layer().Named("override")
+	if err := layer.Set(&ConfigPartial{Name: sudogenPtr("valid")}); err != nil {
+		t.Fatal(err)
+	}
+	var updates int
+	unsub := broker.Subscribe(func(*Config) {
+		updates++
+	})
+	defer unsub()
+	err := layer.Set(&ConfigPartial{Name: sudogenPtr("invalid")})
+	var verr *ConfigValidationError
+	if !errors.Is(err, ErrConfigValidationFailed) || !errors.As(err, &verr) || verr.Layer != "override" {
+		t.Fatalf("expected a validation error for layer override, got %v", err)
+	}
+	if got := broker.Get().Name; got != "valid" {
+		t.Errorf("expected the rejected change to keep Name=valid, got %s", got)
+	}
+	if got := layer.partial.Name; got == nil || *got != "valid" {
+		t.Errorf("expected the rejected change to leave the layer as it was, got %v", got)
+	}
+	if err := layer.Replace(&ConfigPartial{Name: sudogenPtr("invalid")}); !errors.Is(err, ErrConfigValidationFailed) {
+		t.Errorf("expected Replace to be rejected, got %v", err)
+	}
+	if err := layer.Remove(); !errors.Is(err, ErrConfigValidationFailed) {
+		t.Errorf("expected Remove to be rejected, got %v", err)
+	}
+	if updates != 1 {
+		t.Errorf("expected no notifications for rejected changes, got %d", updates-1)
+	}
+	if err := layer.Set(&ConfigPartial{Name: sudogenPtr("still attached")}); err != nil {
+		t.Fatal(err)
+	}
+	if got := broker.Get().Name; got != "still attached" {
+		t.Errorf("expected the layer to stay attached, got Name=%s", got)
+	}
+}
+
+func TestConfigLayerBrokerAuditSink(t *testing.T) {
+	var entries []ConfigAuditEntry
+	broker := NewConfigLayerBroker(nil, WithConfigAuditSink(ConfigAuditFunc(func(entry ConfigAuditEntry) {
+		entries = append(entries, entry)
+	})))
+	layer := broker.Layer().Named("admin").As("alice")
+	if err := layer.Set(&ConfigPartial{Name: sudogenPtr("audited")}); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected one audit entry, got %d", len(entries))
+	}
+	entry := entries[0]
+	if entry.Layer != "admin" || entry.Actor != "alice" || entry.Time.IsZero() {
+		t.Errorf("expected an entry for layer admin by alice, got %+v", entry)
+	}
+	if len(entry.Changes) != 1 || entry.Changes[0].Field != "Name" || entry.Changes[0].New != "audited" {
+		t.Errorf("expected the change of Name, got %+v", entry.Changes)
+	}
+	if err := layer.Set(&ConfigPartial{Name: sudogenPtr("audited")}); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || len(entries[1].Changes) != 0 {
+		t.Errorf("expected an entry without changes for a change keeping the config, got %+v", entries)
+	}
+}
+
+func TestConfigLayerBrokerLayerGroups(t *testing.T) {
+	broker := NewConfigLayerBroker(nil)
+	broker.GroupLayer(ConfigGroupFile).Set(&ConfigPartial{Name: sudogenPtr("high")})
+	broker.GroupLayer(ConfigGroupDefaults).Set(&ConfigPartial{Name: sudogenPtr("low")})
+	if got := broker.Get().Name; got != "high" {
+		t.Errorf("expected the higher group to win over a later layer of a lower group, got Name=%s", got)
+	}
+	layer := broker.Layer()
+	layer.Set(&ConfigPartial{Name: sudogenPtr("layer")})
+	if got := broker.Get().Name; got != "layer" {
+		t.Errorf("expected Layer to win over every group, got Name=%s", got)
+	}
+	layer.Remove()
+	broker.GroupLayer(ConfigGroupFile).Set(&ConfigPartial{Name: sudogenPtr("newer")})
+	if got := broker.Get().Name; got != "newer" {
+		t.Errorf("expected the most recent layer of a group to win, got Name=%s", got)
+	}
+	if got := ConfigGroupDefaults.String(); got != "defaults" {
+		t.Errorf("expected group name defaults, got %s", got)
+	}
+}
+
+func TestConfigLayerBrokerPreviewLayer(t *testing.T) {
+	broker := NewConfigLayerBroker(&Config{Name: "base"})
+	layer := broker.Layer().Named("file")
+	layer.Set(&ConfigPartial{Name: sudogenPtr("file")})
+	cfg, changes, err := broker.PreviewLayer("file", &ConfigPartial{Name: sudogenPtr("preview")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Name != "preview" {
+		t.Errorf("expected previewed Name=preview, got %s", cfg.Name)
+	}
+	if len(changes) != 1 || changes[0].Field != "Name" || changes[0].Old != "file" || changes[0].New != "preview" {
+		t.Errorf("expected Name to change from file to preview, got %+v", changes)
+	}
+	if got := broker.Get().Name; got != "file" {
+		t.Errorf("expected the preview not to apply, got Name=%s", got)
+	}
+	if got := layer.partial.Name; got == nil || *got != "file" {
+		t.Errorf("expected the preview to leave the layer as it was, got %v", got)
+	}
+	// A name no layer has previews a new layer
+	if _, changes, err := broker.PreviewLayer("env", &ConfigPartial{Name: sudogenPtr("file")}); err != nil || len(changes) != 0 {
+		t.Errorf("expected no changes from a new layer repeating the current value, got %+v, %v", changes, err)
+	}
+}
+
 func TestConfigLayerBrokerSubscribeToEmptyField(t *testing.T) {
 	broker := NewConfigLayerBroker(nil)
 	var callCount int
@@ -168,7 +441,7 @@ func TestConfigLayerBrokerSubscribeToEmptyField(t *testing.T) {
 	}
 
 	// Should be called when field is set
-	broker.Layer().Set(&ConfigPartial{Name: configPtr("test")})
+	broker.Layer().Set(&ConfigPartial{Name: sudogenPtr("test")})
 	if callCount != 1 {
 		t.Errorf("expected 1 call after setting field, got %d", callCount)
 	}
@@ -185,12 +458,12 @@ func TestConfigLayerBrokerNoChangeNoNotify(t *testing.T) {
 		t.Fatalf("expected 1 update, got %d", len(updates))
 	}
 	// Setting to same value should NOT trigger callback
-	broker.Layer().Set(&ConfigPartial{Port: configPtr(42)})
+	broker.Layer().Set(&ConfigPartial{Port: sudogenPtr(42)})
 	if len(updates) != 1 {
 		t.Fatalf("expected 1 update (no change), got %d", len(updates))
 	}
 	// Setting to different value should trigger callback
-	broker.Layer().Set(&ConfigPartial{Port: configPtr(100)})
+	broker.Layer().Set(&ConfigPartial{Port: sudogenPtr(100)})
 	if len(updates) != 2 || updates[1] != 100 {
 		t.Fatalf("expected 2 updates with 100, got %v", updates)
 	}
@@ -205,7 +478,7 @@ func TestConfigLayerBrokerZeroValueUpdate(t *testing.T) {
 	defer unsub()
 
 	// Setting to zero value should trigger callback
-	broker.Layer().Set(&ConfigPartial{Port: configPtr(0)})
+	broker.Layer().Set(&ConfigPartial{Port: sudogenPtr(0)})
 	if len(updates) != 2 || updates[1] != 0 {
 		t.Errorf("expected zero value update, got %v", updates)
 	}
@@ -246,11 +519,86 @@ func TestConfigLayerBrokerConcurrentLayerCreation(t *testing.T) {
 	}
 }
 
+// TestConfigLayerBrokerConcurrentWriters races layer writers and removals against
+// readers and subscribers, and is meant to be run with go test -race. Every write sets
+// Name and Port together, so a config where they disagree is a torn read.
+func TestConfigLayerBrokerConcurrentWriters(t *testing.T) {
+	torn := func(cfg *Config) bool {
+		return cfg.Name != strconv.Itoa(cfg.Port)
+	}
+	broker := NewConfigLayerBroker(&Config{Name: "0", Port: 0})
+	var last atomic.Pointer[Config]
+	unsub := broker.Subscribe(func(cfg *Config) {
+		if torn(cfg) {
+			t.Errorf("subscriber received a torn config: Name=%q Port=%d", cfg.Name, cfg.Port)
+		}
+		last.Store(cfg)
+	})
+	defer unsub()
+
+	const writers, iterations = 8, 100
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	for range 4 {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if cfg := broker.Get(); torn(cfg) {
+					t.Errorf("Get returned a torn config: Name=%q Port=%d", cfg.Name, cfg.Port)
+					return
+				}
+				broker.SubscribeName(func(string) {})()
+			}
+		}()
+	}
+	var wg sync.WaitGroup
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			layer := broker.Layer()
+			for i := range iterations {
+				v := w*iterations + i + 1
+				if err := layer.Set(&ConfigPartial{Name: sudogenPtr(strconv.Itoa(v)), Port: sudogenPtr(v)}); err != nil {
+					t.Error(err)
+					return
+				}
+				if i%10 == 9 {
+					if err := layer.Remove(); err != nil {
+						t.Error(err)
+						return
+					}
+					layer = broker.Layer()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := broker.TopLayer().Set(&ConfigPartial{Name: sudogenPtr("-1"), Port: sudogenPtr(-1)}); err != nil {
+		t.Fatal(err)
+	}
+	close(stop)
+	readers.Wait()
+	if got := last.Load(); got == nil || got.Name != "-1" || got.Port != -1 {
+		t.Errorf("expected the last notification to carry the final config, got %+v", got)
+	}
+	if got := broker.Get(); got.Name != "-1" || got.Port != -1 {
+		t.Errorf("expected the final config to stay in place, got Name=%q Port=%d", got.Name, got.Port)
+	}
+}
+
 func TestConfigLayerBrokerBaseConfigPreserved(t *testing.T) {
 
 	broker := NewConfigLayerBroker(&Config{Name: "base"})
 	layer := broker.Layer()
-	layer.Set(&ConfigPartial{Name: configPtr("layer")})
+	layer.Set(&ConfigPartial{Name: sudogenPtr("layer")})
 
 	cfg := broker.Get()
 	if cfg.Name != "layer" {
@@ -276,7 +624,7 @@ func TestConfigLayerBrokerSubscribeMaxRetries(t *testing.T) {
 	if len(updates) != 1 || updates[0] != 42 {
 		t.Fatalf("expected initial callback with 42, got %v", updates)
 	}
-	broker.Layer().Set(&ConfigPartial{MaxRetries: configPtr(int32(100))})
+	broker.Layer().Set(&ConfigPartial{MaxRetries: sudogenPtr(int32(100))})
 	if len(updates) != 2 || updates[1] != 100 {
 		t.Fatalf("expected update callback with 100, got %v", updates)
 	}
@@ -292,7 +640,7 @@ func TestConfigLayerBrokerSubscribeTimeout(t *testing.T) {
 	if len(updates) != 1 || updates[0] != 42 {
 		t.Fatalf("expected initial callback with 42, got %v", updates)
 	}
-	broker.Layer().Set(&ConfigPartial{Timeout: configPtr(int64(100))})
+	broker.Layer().Set(&ConfigPartial{Timeout: sudogenPtr(int64(100))})
 	if len(updates) != 2 || updates[1] != 100 {
 		t.Fatalf("expected update callback with 100, got %v", updates)
 	}
@@ -308,7 +656,7 @@ func TestConfigLayerBrokerSubscribeRate(t *testing.T) {
 	if len(updates) != 1 || updates[0] != 3.14 {
 		t.Fatalf("expected initial callback with 3.14, got %v", updates)
 	}
-	broker.Layer().Set(&ConfigPartial{Rate: configPtr(2.71)})
+	broker.Layer().Set(&ConfigPartial{Rate: sudogenPtr(2.71)})
 	if len(updates) != 2 || updates[1] != 2.71 {
 		t.Fatalf("expected update callback with 2.71, got %v", updates)
 	}
@@ -324,7 +672,7 @@ func TestConfigLayerBrokerSubscribeEnabled(t *testing.T) {
 	if len(updates) != 1 || !updates[0] {
 		t.Fatalf("expected initial callback with true, got %v", updates)
 	}
-	broker.Layer().Set(&ConfigPartial{Enabled: configPtr(false)})
+	broker.Layer().Set(&ConfigPartial{Enabled: sudogenPtr(false)})
 	if len(updates) != 2 || updates[1] {
 		t.Fatalf("expected update callback with false, got %v", updates)
 	}
@@ -341,7 +689,7 @@ func TestConfigLayerBrokerSubscribeDescriptionPointer(t *testing.T) {
 	if len(updates) != 1 || updates[0] == nil || *updates[0] != "initial" {
 		t.Fatalf("expected initial callback with 'initial', got %v", updates)
 	}
-	broker.Layer().Set(&ConfigPartial{Description: configPtr("updated")})
+	broker.Layer().Set(&ConfigPartial{Description: sudogenPtr("updated")})
 	if len(updates) != 2 || updates[1] == nil || *updates[1] != "updated" {
 		t.Fatalf("expected update callback with 'updated', got %v", updates)
 	}
@@ -454,8 +802,7 @@ func TestConfigLayerBrokerSubscribeUpdatedAtTimePointer(t *testing.T) {
 
 func TestConfigLayerBrokerMarshalJSON(t *testing.T) {
 	broker := NewConfigLayerBroker(nil)
-	layer := broker.Layer()
-	layer.Set(&ConfigPartial{Name: configPtr("test")})
+	broker.Layer().Set(&ConfigPartial{Name: sudogenPtr("test")})
 	data, err := json.Marshal(broker)
 	if err != nil {
 		t.Fatalf("MarshalJSON failed: %v", err)
@@ -495,12 +842,13 @@ func TestConfigLayerBrokerSetAllFieldTypes(t *testing.T) {
 	layer := broker.Layer()
 	// Test setting all field types to exercise mergePartial
 	partial := &ConfigPartial{}
-	partial.Name = configPtr("test")
-	partial.Port = configPtr(42)
-	partial.MaxRetries = configPtr(int32(42))
-	partial.Timeout = configPtr(int64(42))
-	partial.Rate = configPtr(3.14)
-	partial.Enabled = configPtr(true)
+	partial.Name = sudogenPtr("test")
+	partial.Port = sudogenPtr(42)
+	partial.MaxRetries = sudogenPtr(int32(42))
+	partial.Timeout = sudogenPtr(int64(42))
+	partial.Rate = sudogenPtr(3.14)
+	partial.Enabled = sudogenPtr(true)
+	partial.LogLevel = sudogenPtr("test")
 
 	layer.Set(partial)
 	cfg := broker.Get()
@@ -529,7 +877,7 @@ func TestConfigLayerBrokerSetPointerFields(t *testing.T) {
 	broker := NewConfigLayerBroker(nil)
 	layer := broker.Layer()
 	partial := &ConfigPartial{}
-	partial.Description = configPtr("test")
+	partial.Description = sudogenPtr("test")
 
 	layer.Set(partial)
 	cfg := broker.Get()
@@ -1,7 +1,17 @@
 // Code generated by sudo-gen merge. DO NOT EDIT.
+// Generated by sudo-gen (devel): layerbroker -tests -json -merge-patch -json-patch -http -sighup -provenance -audit -history=10 -groups=defaults,file,env -redact -constant-time-secrets -bench -examples
 
 package basic
 
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
 func (c *Config) ApplyPartial(p *ConfigPartial) {
 	if c == nil || p == nil {
 		return
@@ -28,6 +38,9 @@ func (c *Config) ApplyPartial(p *ConfigPartial) {
 		v := *p.Description
 		c.Description = &v
 	}
+	if p.LogLevel != nil {
+		c.LogLevel = *p.LogLevel
+	}
 	if p.Hosts != nil {
 		c.Hosts = make([]string, len(p.Hosts))
 		copy(c.Hosts, p.Hosts)
@@ -67,6 +80,250 @@ func (c *Config) ApplyPartial(p *ConfigPartial) {
 	}
 }
 
+// ApplySparse applies each entry to c in order without materializing nested partials.
+// Entries applied before a failing entry remain applied.
+func (c *Config) ApplySparse(entries ...ConfigSparseEntry) error {
+	if c == nil {
+		return nil
+	}
+	for _, e := range entries {
+		if err := c.applySparse(e.Path, e.Value); err != nil {
+			return fmt.Errorf("sparse path %q: %w", e.Path, err)
+		}
+	}
+	return nil
+}
+
+func (c *Config) applySparse(path string, value any) error {
+	name, rest, _ := strings.Cut(path, ".")
+	switch name {
+	case "Name":
+		if rest != "" {
+			return fmt.Errorf("Name has no fields")
+		}
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("Name: expected string, got %T", value)
+		}
+		c.Name = v
+	case "Port":
+		if rest != "" {
+			return fmt.Errorf("Port has no fields")
+		}
+		v, ok := value.(int)
+		if !ok {
+			return fmt.Errorf("Port: expected int, got %T", value)
+		}
+		c.Port = v
+	case "MaxRetries":
+		if rest != "" {
+			return fmt.Errorf("MaxRetries has no fields")
+		}
+		v, ok := value.(int32)
+		if !ok {
+			return fmt.Errorf("MaxRetries: expected int32, got %T", value)
+		}
+		c.MaxRetries = v
+	case "Timeout":
+		if rest != "" {
+			return fmt.Errorf("Timeout has no fields")
+		}
+		v, ok := value.(int64)
+		if !ok {
+			return fmt.Errorf("Timeout: expected int64, got %T", value)
+		}
+		c.Timeout = v
+	case "Rate":
+		if rest != "" {
+			return fmt.Errorf("Rate has no fields")
+		}
+		v, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("Rate: expected float64, got %T", value)
+		}
+		c.Rate = v
+	case "Enabled":
+		if rest != "" {
+			return fmt.Errorf("Enabled has no fields")
+		}
+		v, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("Enabled: expected bool, got %T", value)
+		}
+		c.Enabled = v
+	case "Description":
+		if rest != "" {
+			return fmt.Errorf("Description has no fields")
+		}
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("Description: expected string, got %T", value)
+		}
+		c.Description = &v
+	case "LogLevel":
+		if rest != "" {
+			return fmt.Errorf("LogLevel has no fields")
+		}
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("LogLevel: expected string, got %T", value)
+		}
+		c.LogLevel = v
+	case "Hosts":
+		if rest != "" {
+			return fmt.Errorf("Hosts has no fields")
+		}
+		v, ok := value.([]string)
+		if !ok {
+			return fmt.Errorf("Hosts: expected []string, got %T", value)
+		}
+		c.Hosts = make([]string, len(v))
+		copy(c.Hosts, v)
+	case "Tags":
+		if rest != "" {
+			return fmt.Errorf("Tags has no fields")
+		}
+		v, ok := value.([]Tag)
+		if !ok {
+			return fmt.Errorf("Tags: expected []Tag, got %T", value)
+		}
+		c.Tags = make([]Tag, len(v))
+		copy(c.Tags, v)
+	case "Labels":
+		if rest != "" {
+			return fmt.Errorf("Labels has no fields")
+		}
+		v, ok := value.(map[string]string)
+		if !ok {
+			return fmt.Errorf("Labels: expected map[string]string, got %T", value)
+		}
+		if c.Labels == nil {
+			c.Labels = make(map[string]string, len(v))
+		}
+		for k, mv := range v {
+			c.Labels[k] = mv
+		}
+	case "Metadata":
+		if rest != "" {
+			return fmt.Errorf("Metadata has no fields")
+		}
+		v, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("Metadata: expected map[string]any, got %T", value)
+		}
+		if c.Metadata == nil {
+			c.Metadata = make(map[string]any, len(v))
+		}
+		for k, mv := range v {
+			c.Metadata[k] = mv
+		}
+	case "Database":
+		if rest == "" {
+			return fmt.Errorf("Database is a struct, not a field")
+		}
+		if c.Database == nil {
+			c.Database = &DatabaseConfig{}
+		}
+		return c.Database.applySparse(rest, value)
+	case "CreatedAt":
+		if rest != "" {
+			return fmt.Errorf("CreatedAt has no fields")
+		}
+		v, ok := value.(time.Time)
+		if !ok {
+			return fmt.Errorf("CreatedAt: expected time.Time, got %T", value)
+		}
+		c.CreatedAt = v
+	case "UpdatedAt":
+		if rest != "" {
+			return fmt.Errorf("UpdatedAt has no fields")
+		}
+		v, ok := value.(time.Time)
+		if !ok {
+			return fmt.Errorf("UpdatedAt: expected time.Time, got %T", value)
+		}
+		c.UpdatedAt = &v
+	default:
+		return fmt.Errorf("unknown field %q", name)
+	}
+	return nil
+}
+
+// ToPartial returns a partial setting every field of c, so that a snapshot of c
+// can be applied as a layer. Nil pointers, slices and maps are left unset, and the
+// others are copied shallowly.
+func (c Config) ToPartial() ConfigPartial {
+	return c.toPartial(false)
+}
+
+// NonZeroPartial returns a partial setting the fields of c that don't hold their zero
+// value, so that applying it leaves the fields c doesn't set untouched. Nested structs
+// that are zero as a whole are left unset too.
+func (c Config) NonZeroPartial() ConfigPartial {
+	return c.toPartial(true)
+}
+
+func (c Config) toPartial(skipZero bool) ConfigPartial {
+	var p ConfigPartial
+	if !skipZero || c.Name != "" {
+		v := c.Name
+		p.Name = &v
+	}
+	if !skipZero || c.Port != 0 {
+		v := c.Port
+		p.Port = &v
+	}
+	if !skipZero || c.MaxRetries != 0 {
+		v := c.MaxRetries
+		p.MaxRetries = &v
+	}
+	if !skipZero || c.Timeout != 0 {
+		v := c.Timeout
+		p.Timeout = &v
+	}
+	if !skipZero || c.Rate != 0 {
+		v := c.Rate
+		p.Rate = &v
+	}
+	if !skipZero || c.Enabled {
+		v := c.Enabled
+		p.Enabled = &v
+	}
+	if c.Description != nil {
+		v := *c.Description
+		p.Description = &v
+	}
+	if !skipZero || c.LogLevel != "" {
+		v := c.LogLevel
+		p.LogLevel = &v
+	}
+	if c.Hosts != nil {
+		p.Hosts = slices.Clone(c.Hosts)
+	}
+	if c.Tags != nil {
+		p.Tags = slices.Clone(c.Tags)
+	}
+	if c.Labels != nil {
+		p.Labels = maps.Clone(c.Labels)
+	}
+	if c.Metadata != nil {
+		p.Metadata = maps.Clone(c.Metadata)
+	}
+	if c.Database != nil {
+		n := c.Database.toPartial(skipZero)
+		p.Database = &n
+	}
+	if !skipZero || !c.CreatedAt.IsZero() {
+		v := c.CreatedAt
+		p.CreatedAt = &v
+	}
+	if c.UpdatedAt != nil {
+		v := *c.UpdatedAt
+		p.UpdatedAt = &v
+	}
+	return p
+}
+
 func (c *Tag) ApplyPartial(p *TagPartial) {
 	if c == nil || p == nil {
 		return
@@ -79,10 +336,67 @@ func (c *Tag) ApplyPartial(p *TagPartial) {
 	}
 }
 
+func (c *Tag) applySparse(path string, value any) error {
+	name, rest, _ := strings.Cut(path, ".")
+	switch name {
+	case "Key":
+		if rest != "" {
+			return fmt.Errorf("Key has no fields")
+		}
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("Key: expected string, got %T", value)
+		}
+		c.Key = v
+	case "Value":
+		if rest != "" {
+			return fmt.Errorf("Value has no fields")
+		}
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("Value: expected string, got %T", value)
+		}
+		c.Value = v
+	default:
+		return fmt.Errorf("unknown field %q", name)
+	}
+	return nil
+}
+
+// ToPartial returns a partial setting every field of c, so that a snapshot of c
+// can be applied as a layer. Nil pointers, slices and maps are left unset, and the
+// others are copied shallowly.
+func (c Tag) ToPartial() TagPartial {
+	return c.toPartial(false)
+}
+
+// NonZeroPartial returns a partial setting the fields of c that don't hold their zero
+// value, so that applying it leaves the fields c doesn't set untouched. Nested structs
+// that are zero as a whole are left unset too.
+func (c Tag) NonZeroPartial() TagPartial {
+	return c.toPartial(true)
+}
+
+func (c Tag) toPartial(skipZero bool) TagPartial {
+	var p TagPartial
+	if !skipZero || c.Key != "" {
+		v := c.Key
+		p.Key = &v
+	}
+	if !skipZero || c.Value != "" {
+		v := c.Value
+		p.Value = &v
+	}
+	return p
+}
+
 func (c *DatabaseConfig) ApplyPartial(p *DatabaseConfigPartial) {
 	if c == nil || p == nil {
 		return
 	}
+	if p.Driver != nil {
+		c.Driver = *p.Driver
+	}
 	if p.Host != nil {
 		c.Host = *p.Host
 	}
@@ -99,3 +413,109 @@ func (c *DatabaseConfig) ApplyPartial(p *DatabaseConfigPartial) {
 		c.SSLMode = *p.SSLMode
 	}
 }
+
+func (c *DatabaseConfig) applySparse(path string, value any) error {
+	name, rest, _ := strings.Cut(path, ".")
+	switch name {
+	case "Driver":
+		if rest != "" {
+			return fmt.Errorf("Driver has no fields")
+		}
+		v, ok := value.(Driver)
+		if !ok {
+			return fmt.Errorf("Driver: expected Driver, got %T", value)
+		}
+		c.Driver = v
+	case "Host":
+		if rest != "" {
+			return fmt.Errorf("Host has no fields")
+		}
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("Host: expected string, got %T", value)
+		}
+		c.Host = v
+	case "Port":
+		if rest != "" {
+			return fmt.Errorf("Port has no fields")
+		}
+		v, ok := value.(int)
+		if !ok {
+			return fmt.Errorf("Port: expected int, got %T", value)
+		}
+		c.Port = v
+	case "Username":
+		if rest != "" {
+			return fmt.Errorf("Username has no fields")
+		}
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("Username: expected string, got %T", value)
+		}
+		c.Username = v
+	case "Password":
+		if rest != "" {
+			return fmt.Errorf("Password has no fields")
+		}
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("Password: expected string, got %T", value)
+		}
+		c.Password = v
+	case "SSLMode":
+		if rest != "" {
+			return fmt.Errorf("SSLMode has no fields")
+		}
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("SSLMode: expected string, got %T", value)
+		}
+		c.SSLMode = v
+	default:
+		return fmt.Errorf("unknown field %q", name)
+	}
+	return nil
+}
+
+// ToPartial returns a partial setting every field of c, so that a snapshot of c
+// can be applied as a layer. Nil pointers, slices and maps are left unset, and the
+// others are copied shallowly.
+func (c DatabaseConfig) ToPartial() DatabaseConfigPartial {
+	return c.toPartial(false)
+}
+
+// NonZeroPartial returns a partial setting the fields of c that don't hold their zero
+// value, so that applying it leaves the fields c doesn't set untouched. Nested structs
+// that are zero as a whole are left unset too.
+func (c DatabaseConfig) NonZeroPartial() DatabaseConfigPartial {
+	return c.toPartial(true)
+}
+
+func (c DatabaseConfig) toPartial(skipZero bool) DatabaseConfigPartial {
+	var p DatabaseConfigPartial
+	if !skipZero || !reflect.ValueOf(c.Driver).IsZero() {
+		v := c.Driver
+		p.Driver = &v
+	}
+	if !skipZero || c.Host != "" {
+		v := c.Host
+		p.Host = &v
+	}
+	if !skipZero || c.Port != 0 {
+		v := c.Port
+		p.Port = &v
+	}
+	if !skipZero || c.Username != "" {
+		v := c.Username
+		p.Username = &v
+	}
+	if !skipZero || c.Password != "" {
+		v := c.Password
+		p.Password = &v
+	}
+	if !skipZero || c.SSLMode != "" {
+		v := c.SSLMode
+		p.SSLMode = &v
+	}
+	return p
+}
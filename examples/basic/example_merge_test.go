@@ -1,15 +1,13 @@
 // Code generated by sudo-gen merge. DO NOT EDIT.
+// Generated by sudo-gen (devel): layerbroker -tests -json -merge-patch -json-patch -http -sighup -provenance -audit -history=10 -groups=defaults,file,env -redact -constant-time-secrets -bench -examples
 
 package basic
 
 import (
+	"encoding/json"
 	"testing"
 )
 
-func mergePtr[T any](v T) *T {
-	return &v
-}
-
 func TestConfigApplyPartialNil(t *testing.T) {
 	var c *Config
 	c.ApplyPartial(nil) // should not panic
@@ -26,7 +24,7 @@ func TestConfigApplyPartialEmpty(t *testing.T) {
 
 func TestConfigApplyPartial_Name(t *testing.T) {
 	c := &Config{}
-	p := &ConfigPartial{Name: mergePtr("test")}
+	p := &ConfigPartial{Name: sudogenPtr("test")}
 	c.ApplyPartial(p)
 	if c.Name != "test" {
 		t.Errorf("expected Name=test, got %s", c.Name)
@@ -35,7 +33,7 @@ func TestConfigApplyPartial_Name(t *testing.T) {
 
 func TestConfigApplyPartial_NameOverwrite(t *testing.T) {
 	c := &Config{Name: "original"}
-	p := &ConfigPartial{Name: mergePtr("updated")}
+	p := &ConfigPartial{Name: sudogenPtr("updated")}
 	c.ApplyPartial(p)
 	if c.Name != "updated" {
 		t.Errorf("expected Name=updated, got %s", c.Name)
@@ -44,7 +42,7 @@ func TestConfigApplyPartial_NameOverwrite(t *testing.T) {
 
 func TestConfigApplyPartial_Port(t *testing.T) {
 	c := &Config{}
-	p := &ConfigPartial{Port: mergePtr(42)}
+	p := &ConfigPartial{Port: sudogenPtr(42)}
 	c.ApplyPartial(p)
 	if c.Port != 42 {
 		t.Errorf("expected Port=42, got %d", c.Port)
@@ -53,7 +51,7 @@ func TestConfigApplyPartial_Port(t *testing.T) {
 
 func TestConfigApplyPartial_PortOverwrite(t *testing.T) {
 	c := &Config{Port: 100}
-	p := &ConfigPartial{Port: mergePtr(42)}
+	p := &ConfigPartial{Port: sudogenPtr(42)}
 	c.ApplyPartial(p)
 	if c.Port != 42 {
 		t.Errorf("expected Port=42, got %d", c.Port)
@@ -62,7 +60,7 @@ func TestConfigApplyPartial_PortOverwrite(t *testing.T) {
 
 func TestConfigApplyPartial_PortZeroValue(t *testing.T) {
 	c := &Config{Port: 100}
-	p := &ConfigPartial{Port: mergePtr(0)}
+	p := &ConfigPartial{Port: sudogenPtr(0)}
 	c.ApplyPartial(p)
 	if c.Port != 0 {
 		t.Errorf("expected Port=0 (zero value should be applied), got %d", c.Port)
@@ -71,7 +69,7 @@ func TestConfigApplyPartial_PortZeroValue(t *testing.T) {
 
 func TestConfigApplyPartial_MaxRetries(t *testing.T) {
 	c := &Config{}
-	p := &ConfigPartial{MaxRetries: mergePtr(int32(42))}
+	p := &ConfigPartial{MaxRetries: sudogenPtr(int32(42))}
 	c.ApplyPartial(p)
 	if c.MaxRetries != 42 {
 		t.Errorf("expected MaxRetries=42, got %v", c.MaxRetries)
@@ -80,7 +78,7 @@ func TestConfigApplyPartial_MaxRetries(t *testing.T) {
 
 func TestConfigApplyPartial_Timeout(t *testing.T) {
 	c := &Config{}
-	p := &ConfigPartial{Timeout: mergePtr(int64(42))}
+	p := &ConfigPartial{Timeout: sudogenPtr(int64(42))}
 	c.ApplyPartial(p)
 	if c.Timeout != 42 {
 		t.Errorf("expected Timeout=42, got %v", c.Timeout)
@@ -89,7 +87,7 @@ func TestConfigApplyPartial_Timeout(t *testing.T) {
 
 func TestConfigApplyPartial_Rate(t *testing.T) {
 	c := &Config{}
-	p := &ConfigPartial{Rate: mergePtr(float64(42))}
+	p := &ConfigPartial{Rate: sudogenPtr(float64(42))}
 	c.ApplyPartial(p)
 	if c.Rate != 42 {
 		t.Errorf("expected Rate=42, got %v", c.Rate)
@@ -98,7 +96,7 @@ func TestConfigApplyPartial_Rate(t *testing.T) {
 
 func TestConfigApplyPartial_Enabled(t *testing.T) {
 	c := &Config{}
-	p := &ConfigPartial{Enabled: mergePtr(true)}
+	p := &ConfigPartial{Enabled: sudogenPtr(true)}
 	c.ApplyPartial(p)
 	if !c.Enabled {
 		t.Errorf("expected Enabled=true, got %v", c.Enabled)
@@ -107,13 +105,31 @@ func TestConfigApplyPartial_Enabled(t *testing.T) {
 
 func TestConfigApplyPartial_EnabledFalse(t *testing.T) {
 	c := &Config{Enabled: true}
-	p := &ConfigPartial{Enabled: mergePtr(false)}
+	p := &ConfigPartial{Enabled: sudogenPtr(false)}
 	c.ApplyPartial(p)
 	if c.Enabled {
 		t.Errorf("expected Enabled=false, got %v", c.Enabled)
 	}
 }
 
+func TestConfigApplyPartial_LogLevel(t *testing.T) {
+	c := &Config{}
+	p := &ConfigPartial{LogLevel: sudogenPtr("test")}
+	c.ApplyPartial(p)
+	if c.LogLevel != "test" {
+		t.Errorf("expected LogLevel=test, got %s", c.LogLevel)
+	}
+}
+
+func TestConfigApplyPartial_LogLevelOverwrite(t *testing.T) {
+	c := &Config{LogLevel: "original"}
+	p := &ConfigPartial{LogLevel: sudogenPtr("updated")}
+	c.ApplyPartial(p)
+	if c.LogLevel != "updated" {
+		t.Errorf("expected LogLevel=updated, got %s", c.LogLevel)
+	}
+}
+
 func TestConfigApplyPartial_HostsSlice(t *testing.T) {
 	c := &Config{}
 	newSlice := []string{}
@@ -243,12 +259,16 @@ func TestConfigApplyPartial_DatabaseNestedStruct(t *testing.T) {
 }
 
 func TestConfigApplyPartial_DatabaseNestedStructExisting(t *testing.T) {
-	c := &Config{Database: &DatabaseConfig{}}
+	existing := &DatabaseConfig{}
+	c := &Config{Database: existing}
 	p := &ConfigPartial{Database: &DatabaseConfigPartial{}}
 	c.ApplyPartial(p)
 	if c.Database == nil {
 		t.Error("expected nested struct to remain set")
 	}
+	if c.Database != existing {
+		t.Error("expected nested struct to be merged in place")
+	}
 }
 
 func TestTagApplyPartialNil(t *testing.T) {
@@ -267,7 +287,7 @@ func TestTagApplyPartialEmpty(t *testing.T) {
 
 func TestTagApplyPartial_Key(t *testing.T) {
 	c := &Tag{}
-	p := &TagPartial{Key: mergePtr("test")}
+	p := &TagPartial{Key: sudogenPtr("test")}
 	c.ApplyPartial(p)
 	if c.Key != "test" {
 		t.Errorf("expected Key=test, got %s", c.Key)
@@ -276,7 +296,7 @@ func TestTagApplyPartial_Key(t *testing.T) {
 
 func TestTagApplyPartial_KeyOverwrite(t *testing.T) {
 	c := &Tag{Key: "original"}
-	p := &TagPartial{Key: mergePtr("updated")}
+	p := &TagPartial{Key: sudogenPtr("updated")}
 	c.ApplyPartial(p)
 	if c.Key != "updated" {
 		t.Errorf("expected Key=updated, got %s", c.Key)
@@ -285,7 +305,7 @@ func TestTagApplyPartial_KeyOverwrite(t *testing.T) {
 
 func TestTagApplyPartial_Value(t *testing.T) {
 	c := &Tag{}
-	p := &TagPartial{Value: mergePtr("test")}
+	p := &TagPartial{Value: sudogenPtr("test")}
 	c.ApplyPartial(p)
 	if c.Value != "test" {
 		t.Errorf("expected Value=test, got %s", c.Value)
@@ -294,7 +314,7 @@ func TestTagApplyPartial_Value(t *testing.T) {
 
 func TestTagApplyPartial_ValueOverwrite(t *testing.T) {
 	c := &Tag{Value: "original"}
-	p := &TagPartial{Value: mergePtr("updated")}
+	p := &TagPartial{Value: sudogenPtr("updated")}
 	c.ApplyPartial(p)
 	if c.Value != "updated" {
 		t.Errorf("expected Value=updated, got %s", c.Value)
@@ -317,7 +337,7 @@ func TestDatabaseConfigApplyPartialEmpty(t *testing.T) {
 
 func TestDatabaseConfigApplyPartial_Host(t *testing.T) {
 	c := &DatabaseConfig{}
-	p := &DatabaseConfigPartial{Host: mergePtr("test")}
+	p := &DatabaseConfigPartial{Host: sudogenPtr("test")}
 	c.ApplyPartial(p)
 	if c.Host != "test" {
 		t.Errorf("expected Host=test, got %s", c.Host)
@@ -326,7 +346,7 @@ func TestDatabaseConfigApplyPartial_Host(t *testing.T) {
 
 func TestDatabaseConfigApplyPartial_HostOverwrite(t *testing.T) {
 	c := &DatabaseConfig{Host: "original"}
-	p := &DatabaseConfigPartial{Host: mergePtr("updated")}
+	p := &DatabaseConfigPartial{Host: sudogenPtr("updated")}
 	c.ApplyPartial(p)
 	if c.Host != "updated" {
 		t.Errorf("expected Host=updated, got %s", c.Host)
@@ -335,7 +355,7 @@ func TestDatabaseConfigApplyPartial_HostOverwrite(t *testing.T) {
 
 func TestDatabaseConfigApplyPartial_Port(t *testing.T) {
 	c := &DatabaseConfig{}
-	p := &DatabaseConfigPartial{Port: mergePtr(42)}
+	p := &DatabaseConfigPartial{Port: sudogenPtr(42)}
 	c.ApplyPartial(p)
 	if c.Port != 42 {
 		t.Errorf("expected Port=42, got %d", c.Port)
@@ -344,7 +364,7 @@ func TestDatabaseConfigApplyPartial_Port(t *testing.T) {
 
 func TestDatabaseConfigApplyPartial_PortOverwrite(t *testing.T) {
 	c := &DatabaseConfig{Port: 100}
-	p := &DatabaseConfigPartial{Port: mergePtr(42)}
+	p := &DatabaseConfigPartial{Port: sudogenPtr(42)}
 	c.ApplyPartial(p)
 	if c.Port != 42 {
 		t.Errorf("expected Port=42, got %d", c.Port)
@@ -353,7 +373,7 @@ func TestDatabaseConfigApplyPartial_PortOverwrite(t *testing.T) {
 
 func TestDatabaseConfigApplyPartial_PortZeroValue(t *testing.T) {
 	c := &DatabaseConfig{Port: 100}
-	p := &DatabaseConfigPartial{Port: mergePtr(0)}
+	p := &DatabaseConfigPartial{Port: sudogenPtr(0)}
 	c.ApplyPartial(p)
 	if c.Port != 0 {
 		t.Errorf("expected Port=0 (zero value should be applied), got %d", c.Port)
@@ -362,7 +382,7 @@ func TestDatabaseConfigApplyPartial_PortZeroValue(t *testing.T) {
 
 func TestDatabaseConfigApplyPartial_Username(t *testing.T) {
 	c := &DatabaseConfig{}
-	p := &DatabaseConfigPartial{Username: mergePtr("test")}
+	p := &DatabaseConfigPartial{Username: sudogenPtr("test")}
 	c.ApplyPartial(p)
 	if c.Username != "test" {
 		t.Errorf("expected Username=test, got %s", c.Username)
@@ -371,7 +391,7 @@ func TestDatabaseConfigApplyPartial_Username(t *testing.T) {
 
 func TestDatabaseConfigApplyPartial_UsernameOverwrite(t *testing.T) {
 	c := &DatabaseConfig{Username: "original"}
-	p := &DatabaseConfigPartial{Username: mergePtr("updated")}
+	p := &DatabaseConfigPartial{Username: sudogenPtr("updated")}
 	c.ApplyPartial(p)
 	if c.Username != "updated" {
 		t.Errorf("expected Username=updated, got %s", c.Username)
@@ -380,7 +400,7 @@ func TestDatabaseConfigApplyPartial_UsernameOverwrite(t *testing.T) {
 
 func TestDatabaseConfigApplyPartial_Password(t *testing.T) {
 	c := &DatabaseConfig{}
-	p := &DatabaseConfigPartial{Password: mergePtr("test")}
+	p := &DatabaseConfigPartial{Password: sudogenPtr("test")}
 	c.ApplyPartial(p)
 	if c.Password != "test" {
 		t.Errorf("expected Password=test, got %s", c.Password)
@@ -389,7 +409,7 @@ func TestDatabaseConfigApplyPartial_Password(t *testing.T) {
 
 func TestDatabaseConfigApplyPartial_PasswordOverwrite(t *testing.T) {
 	c := &DatabaseConfig{Password: "original"}
-	p := &DatabaseConfigPartial{Password: mergePtr("updated")}
+	p := &DatabaseConfigPartial{Password: sudogenPtr("updated")}
 	c.ApplyPartial(p)
 	if c.Password != "updated" {
 		t.Errorf("expected Password=updated, got %s", c.Password)
@@ -398,7 +418,7 @@ func TestDatabaseConfigApplyPartial_PasswordOverwrite(t *testing.T) {
 
 func TestDatabaseConfigApplyPartial_SSLMode(t *testing.T) {
 	c := &DatabaseConfig{}
-	p := &DatabaseConfigPartial{SSLMode: mergePtr("test")}
+	p := &DatabaseConfigPartial{SSLMode: sudogenPtr("test")}
 	c.ApplyPartial(p)
 	if c.SSLMode != "test" {
 		t.Errorf("expected SSLMode=test, got %s", c.SSLMode)
@@ -407,9 +427,199 @@ func TestDatabaseConfigApplyPartial_SSLMode(t *testing.T) {
 
 func TestDatabaseConfigApplyPartial_SSLModeOverwrite(t *testing.T) {
 	c := &DatabaseConfig{SSLMode: "original"}
-	p := &DatabaseConfigPartial{SSLMode: mergePtr("updated")}
+	p := &DatabaseConfigPartial{SSLMode: sudogenPtr("updated")}
 	c.ApplyPartial(p)
 	if c.SSLMode != "updated" {
 		t.Errorf("expected SSLMode=updated, got %s", c.SSLMode)
 	}
 }
+
+func TestConfigApplySparseUnknownPath(t *testing.T) {
+	c := &Config{}
+	if err := c.ApplySparse(ConfigSparseEntry{Path: "DoesNotExist", Value: 1}); err == nil {
+		t.Error("expected error for unknown path")
+	}
+}
+
+func TestConfigApplySparse_Name(t *testing.T) {
+	c := &Config{Name: "original"}
+	if err := c.ApplySparse(ConfigSparseEntry{Path: "Name", Value: "updated"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Name != "updated" {
+		t.Errorf("expected Name=updated, got %s", c.Name)
+	}
+}
+
+func TestConfigApplySparse_NameWrongType(t *testing.T) {
+	c := &Config{Name: "original"}
+	if err := c.ApplySparse(ConfigSparseEntry{Path: "Name", Value: 42}); err == nil {
+		t.Error("expected error for mismatched value type")
+	}
+	if c.Name != "original" {
+		t.Errorf("expected Name to be unchanged, got %s", c.Name)
+	}
+}
+
+func TestConfigApplySparse_LogLevel(t *testing.T) {
+	c := &Config{LogLevel: "original"}
+	if err := c.ApplySparse(ConfigSparseEntry{Path: "LogLevel", Value: "updated"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.LogLevel != "updated" {
+		t.Errorf("expected LogLevel=updated, got %s", c.LogLevel)
+	}
+}
+
+func TestConfigApplySparse_LogLevelWrongType(t *testing.T) {
+	c := &Config{LogLevel: "original"}
+	if err := c.ApplySparse(ConfigSparseEntry{Path: "LogLevel", Value: 42}); err == nil {
+		t.Error("expected error for mismatched value type")
+	}
+	if c.LogLevel != "original" {
+		t.Errorf("expected LogLevel to be unchanged, got %s", c.LogLevel)
+	}
+}
+
+func TestConfigPartialMerge_Name(t *testing.T) {
+	base := ConfigPartial{Name: sudogenPtr("base")}
+	override := ConfigPartial{Name: sudogenPtr("override")}
+	if got := base.Merge(override); got.Name == nil || *got.Name != "override" {
+		t.Errorf("expected the override to win, got %v", got.Name)
+	}
+	if got := base.Merge(ConfigPartial{}); got.Name == nil || *got.Name != "base" {
+		t.Errorf("expected an unset field to keep the base, got %v", got.Name)
+	}
+	if got := (ConfigPartial{}).Overlay(base, override, ConfigPartial{}); got.Name == nil || *got.Name != "override" {
+		t.Errorf("expected the last layer setting Name to win, got %v", got.Name)
+	}
+	if *base.Name != "base" {
+		t.Errorf("Merge modified its receiver: %s", *base.Name)
+	}
+}
+
+func TestConfigPartialIsEmpty(t *testing.T) {
+	var p *ConfigPartial
+	if !p.IsEmpty() {
+		t.Error("expected a nil partial to be empty")
+	}
+	if !(&ConfigPartial{}).IsEmpty() {
+		t.Error("expected a zero partial to be empty")
+	}
+	if (&ConfigPartial{Name: sudogenPtr("set")}).IsEmpty() {
+		t.Error("expected a partial setting Name not to be empty")
+	}
+}
+
+func TestConfigPartialPrune_Database(t *testing.T) {
+	p := &ConfigPartial{Database: &DatabaseConfigPartial{}}
+	if !p.IsEmpty() {
+		t.Error("expected a partial with an empty nested partial to be empty")
+	}
+	p.Prune()
+	if p.Database != nil {
+		t.Errorf("expected the empty nested partial to be pruned, got %v", p.Database)
+	}
+}
+
+func TestConfigPartialMarshalJSONSparse(t *testing.T) {
+	// Keys are looked up in the encoding, since fields without omitempty are
+	// written as null when unset.
+	fields := func(p ConfigPartial) map[string]json.RawMessage {
+		t.Helper()
+		data, err := json.Marshal(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			t.Fatal(err)
+		}
+		return fields
+	}
+	if got := string(fields(ConfigPartial{Hosts: []string{}})["hosts"]); got != "[]" {
+		t.Errorf("expected Hosts set to an empty value to be written, got %s", got)
+	}
+	if got := string(fields(ConfigPartial{Tags: []Tag{}})["tags"]); got != "[]" {
+		t.Errorf("expected Tags set to an empty value to be written, got %s", got)
+	}
+	if got := string(fields(ConfigPartial{Labels: map[string]string{}})["labels"]); got != "{}" {
+		t.Errorf("expected Labels set to an empty value to be written, got %s", got)
+	}
+	if got := string(fields(ConfigPartial{Metadata: map[string]any{}})["metadata"]); got != "{}" {
+		t.Errorf("expected Metadata set to an empty value to be written, got %s", got)
+	}
+	if got, ok := fields(ConfigPartial{Database: &DatabaseConfigPartial{}})["database"]; ok {
+		t.Errorf("expected the empty nested partial Database to be left out, got %s", got)
+	}
+}
+
+func TestConfigToPartial(t *testing.T) {
+	var c Config
+	c.Name = "snapshot"
+	p := c.ToPartial()
+	var got Config
+	got.ApplyPartial(&p)
+	if got.Name != "snapshot" {
+		t.Errorf("expected the partial to restore Name, got %q", got.Name)
+	}
+	if p := c.NonZeroPartial(); p.Name == nil || *p.Name != "snapshot" {
+		t.Errorf("expected the non-zero partial to set Name, got %v", p.Name)
+	}
+	if p := (Config{}).NonZeroPartial(); !p.IsEmpty() {
+		t.Errorf("expected the non-zero partial of a zero Config to be empty, got %+v", p)
+	}
+}
+
+func TestConfigJSONMergePatch(t *testing.T) {
+	var c Config
+	if err := c.ApplyJSONMergePatch([]byte("{\"name\":\"patched\"}")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Name != "patched" {
+		t.Errorf("expected the patch to set Name, got %q", c.Name)
+	}
+	base := c
+	base.Name = "base"
+	patch, err := c.ToJSONMergePatch(base)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(patch) != "{\"name\":\"patched\"}" {
+		t.Errorf("expected a patch of the differing field, got %s", patch)
+	}
+	if err := base.ApplyJSONMergePatch(patch); err != nil || base.Name != "patched" {
+		t.Errorf("expected the patch to turn base into c, got %q (%v)", base.Name, err)
+	}
+	if patch, err := c.ToJSONMergePatch(c); err != nil || string(patch) != "{}" {
+		t.Errorf("expected an empty patch between equal values, got %s (%v)", patch, err)
+	}
+	if err := c.ApplyJSONMergePatch([]byte("{\"name\":null}")); err != nil || c.Name != "" {
+		t.Errorf("expected null to reset Name, got %q (%v)", c.Name, err)
+	}
+	if err := c.ApplyJSONMergePatch([]byte("[]")); err == nil {
+		t.Error("expected an error for a patch that isn't an object")
+	}
+}
+
+func TestDiffConfigAsJSONPatch(t *testing.T) {
+	var from, to Config
+	to.Name = "patched"
+	ops, err := DiffConfigAsJSONPatch(from, to)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ops) != 1 || ops[0].Op != "add" || ops[0].Path != "/name" || string(ops[0].Value) != "\"patched\"" {
+		t.Errorf("expected a single operation setting Name, got %+v", ops)
+	}
+	ops, err = DiffConfigAsJSONPatch(to, from)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ops) != 1 || ops[0].Op != "remove" || ops[0].Path != "/name" {
+		t.Errorf("expected a single operation resetting Name, got %+v", ops)
+	}
+	if ops, err := DiffConfigAsJSONPatch(to, to); err != nil || len(ops) != 0 {
+		t.Errorf("expected no operations between equal values, got %+v (%v)", ops, err)
+	}
+}
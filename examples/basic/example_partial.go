@@ -1,26 +1,215 @@
 // Code generated by sudo-gen merge. DO NOT EDIT.
+// Generated by sudo-gen (devel): layerbroker -tests -json -merge-patch -json-patch -http -sighup -provenance -audit -history=10 -groups=defaults,file,env -redact -constant-time-secrets -bench -examples
 
 package basic
 
 import (
+	"encoding/json"
+	"maps"
 	"time"
 )
 
 type ConfigPartial struct {
-	Name        *string                `json:"name,omitempty"`
-	Port        *int                   `json:"port,omitempty"`
-	MaxRetries  *int32                 `json:"max_retries,omitempty"`
-	Timeout     *int64                 `json:"timeout,omitempty"`
-	Rate        *float64               `json:"rate,omitempty"`
-	Enabled     *bool                  `json:"enabled,omitempty"`
-	Description *string                `json:"description,omitempty"`
-	Hosts       []string               `json:"hosts,omitempty"`
-	Tags        []Tag                  `json:"tags,omitempty"`
-	Labels      map[string]string      `json:"labels,omitempty"`
-	Metadata    map[string]any         `json:"metadata,omitempty"`
-	Database    *DatabaseConfigPartial `json:"database,omitempty"`
-	CreatedAt   *time.Time             `json:"created_at,omitempty"`
-	UpdatedAt   *time.Time             `json:"updated_at,omitempty"`
+	// Basic types
+	Name        *string  `json:"name,omitempty" env:"APP_NAME"`                                     // Service name in logs. Default: "app"
+	Port        *int     `json:"port,omitempty" default:"8080" env:"PORT" sudo:"flag=service.port"` // Port to listen on
+	MaxRetries  *int32   `json:"max_retries,omitempty" default:"3" env:"MAX_RETRIES"`
+	Timeout     *int64   `json:"timeout,omitempty"`
+	Rate        *float64 `json:"rate,omitempty" default:"0.5" sudo:"epsilon=1e-9"`
+	Enabled     *bool    `json:"enabled,omitempty" sudo:"flag=service.enabled"`
+	Description *string  `json:"description,omitempty"`
+	LogLevel    *string  `json:"log_level,omitempty" default:"info" env:"LOG_LEVEL" sudo:"enum=debug|info|warn|error"`
+
+	// Slice types
+	Hosts []string `json:"hosts,omitempty" default:"localhost" env:"HOSTS"` // Comma-separated
+	Tags  []Tag    `json:"tags,omitempty"`
+
+	// Map types
+	Labels   map[string]string `json:"labels,omitempty"`
+	Metadata map[string]any    `json:"metadata,omitempty"`
+
+	// Nested struct
+	Database *DatabaseConfigPartial `json:"database,omitempty" envPrefix:"DB_"`
+
+	// Time
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// Merge returns p with the fields other sets applied over it, as applying p and then
+// other with ApplyPartial would: maps are merged key by key and nested partials field
+// by field. The result shares unmerged values with p and other, which must not be
+// modified while it is in use.
+func (p ConfigPartial) Merge(other ConfigPartial) ConfigPartial {
+	if other.Name != nil {
+		p.Name = other.Name
+	}
+	if other.Port != nil {
+		p.Port = other.Port
+	}
+	if other.MaxRetries != nil {
+		p.MaxRetries = other.MaxRetries
+	}
+	if other.Timeout != nil {
+		p.Timeout = other.Timeout
+	}
+	if other.Rate != nil {
+		p.Rate = other.Rate
+	}
+	if other.Enabled != nil {
+		p.Enabled = other.Enabled
+	}
+	if other.Description != nil {
+		p.Description = other.Description
+	}
+	if other.LogLevel != nil {
+		p.LogLevel = other.LogLevel
+	}
+	if other.Hosts != nil {
+		p.Hosts = other.Hosts
+	}
+	if other.Tags != nil {
+		p.Tags = other.Tags
+	}
+	if other.Labels != nil {
+		m := make(map[string]string, len(p.Labels)+len(other.Labels))
+		maps.Copy(m, p.Labels)
+		maps.Copy(m, other.Labels)
+		p.Labels = m
+	}
+	if other.Metadata != nil {
+		m := make(map[string]any, len(p.Metadata)+len(other.Metadata))
+		maps.Copy(m, p.Metadata)
+		maps.Copy(m, other.Metadata)
+		p.Metadata = m
+	}
+	if other.Database != nil {
+		if p.Database == nil {
+			p.Database = other.Database
+		} else {
+			v := p.Database.Merge(*other.Database)
+			p.Database = &v
+		}
+	}
+	if other.CreatedAt != nil {
+		p.CreatedAt = other.CreatedAt
+	}
+	if other.UpdatedAt != nil {
+		p.UpdatedAt = other.UpdatedAt
+	}
+	return p
+}
+
+// IsEmpty reports whether p sets no field. Nested
+// partials that are empty themselves count as unset (see Prune).
+func (p *ConfigPartial) IsEmpty() bool {
+	if p == nil {
+		return true
+	}
+	if p.Name != nil {
+		return false
+	}
+	if p.Port != nil {
+		return false
+	}
+	if p.MaxRetries != nil {
+		return false
+	}
+	if p.Timeout != nil {
+		return false
+	}
+	if p.Rate != nil {
+		return false
+	}
+	if p.Enabled != nil {
+		return false
+	}
+	if p.Description != nil {
+		return false
+	}
+	if p.LogLevel != nil {
+		return false
+	}
+	if p.Hosts != nil {
+		return false
+	}
+	if p.Tags != nil {
+		return false
+	}
+	if p.Labels != nil {
+		return false
+	}
+	if p.Metadata != nil {
+		return false
+	}
+	if !p.Database.IsEmpty() {
+		return false
+	}
+	if p.CreatedAt != nil {
+		return false
+	}
+	if p.UpdatedAt != nil {
+		return false
+	}
+	return true
+}
+
+// Prune sets the nested partials of p that are empty to nil, at every depth, so that
+// p encodes without empty objects. Applying a pruned partial no longer allocates the
+// structs of nil pointer fields that those partials would have left zero. Partials of
+// fields replaced as a whole are kept, since applying them resets the field.
+func (p *ConfigPartial) Prune() {
+	if p == nil {
+		return
+	}
+	p.Database.Prune()
+	if p.Database.IsEmpty() {
+		p.Database = nil
+	}
+}
+
+// MarshalJSON encodes p, leaving out the fields it doesn't set.
+// Empty nested partials count as unset, as after Prune, while slices and maps set
+// to empty values are written.
+func (p ConfigPartial) MarshalJSON() ([]byte, error) {
+	type partial ConfigPartial
+	aux := struct {
+		*partial
+	}{
+		partial: (*partial)(&p),
+	}
+	data, err := json.Marshal(aux)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if p.Hosts == nil {
+		delete(fields, "hosts")
+	} else if len(p.Hosts) == 0 {
+		fields["hosts"] = json.RawMessage("[]")
+	}
+	if p.Tags == nil {
+		delete(fields, "tags")
+	} else if len(p.Tags) == 0 {
+		fields["tags"] = json.RawMessage("[]")
+	}
+	if p.Labels == nil {
+		delete(fields, "labels")
+	} else if len(p.Labels) == 0 {
+		fields["labels"] = json.RawMessage("{}")
+	}
+	if p.Metadata == nil {
+		delete(fields, "metadata")
+	} else if len(p.Metadata) == 0 {
+		fields["metadata"] = json.RawMessage("{}")
+	}
+	if p.Database.IsEmpty() {
+		delete(fields, "database")
+	}
+	return json.Marshal(fields)
 }
 
 type TagPartial struct {
@@ -28,10 +217,131 @@ type TagPartial struct {
 	Value *string `json:"value,omitempty"`
 }
 
+// Merge returns p with the fields other sets applied over it, as applying p and then
+// other with ApplyPartial would: maps are merged key by key and nested partials field
+// by field. The result shares unmerged values with p and other, which must not be
+// modified while it is in use.
+func (p TagPartial) Merge(other TagPartial) TagPartial {
+	if other.Key != nil {
+		p.Key = other.Key
+	}
+	if other.Value != nil {
+		p.Value = other.Value
+	}
+	return p
+}
+
+// IsEmpty reports whether p sets no field. Nested
+// partials that are empty themselves count as unset (see Prune).
+func (p *TagPartial) IsEmpty() bool {
+	if p == nil {
+		return true
+	}
+	if p.Key != nil {
+		return false
+	}
+	if p.Value != nil {
+		return false
+	}
+	return true
+}
+
+// Prune sets the nested partials of p that are empty to nil, at every depth, so that
+// p encodes without empty objects. Applying a pruned partial no longer allocates the
+// structs of nil pointer fields that those partials would have left zero. Partials of
+// fields replaced as a whole are kept, since applying them resets the field.
+func (p *TagPartial) Prune() {
+	if p == nil {
+		return
+	}
+}
+
 type DatabaseConfigPartial struct {
-	Host     *string `json:"host,omitempty"`
-	Port     *int    `json:"port,omitempty"`
+	Driver   *Driver `json:"driver,omitempty" sudo:"flag=database.driver"`
+	Host     *string `json:"host,omitempty" default:"localhost" env:"HOST" sudo:"flag=database.host"`
+	Port     *int    `json:"port,omitempty" default:"5432" env:"PORT"`
 	Username *string `json:"username,omitempty"`
-	Password *string `json:"password,omitempty"`
-	SSLMode  *string `json:"ssl_mode,omitempty"`
+	Password *string `json:"password,omitempty" env:"PASSWORD,required" sudo:"secret"`
+	SSLMode  *string `json:"ssl_mode,omitempty" default:"disable" sudo:"enum=disable|require|verify-full"`
+}
+
+// Merge returns p with the fields other sets applied over it, as applying p and then
+// other with ApplyPartial would: maps are merged key by key and nested partials field
+// by field. The result shares unmerged values with p and other, which must not be
+// modified while it is in use.
+func (p DatabaseConfigPartial) Merge(other DatabaseConfigPartial) DatabaseConfigPartial {
+	if other.Driver != nil {
+		p.Driver = other.Driver
+	}
+	if other.Host != nil {
+		p.Host = other.Host
+	}
+	if other.Port != nil {
+		p.Port = other.Port
+	}
+	if other.Username != nil {
+		p.Username = other.Username
+	}
+	if other.Password != nil {
+		p.Password = other.Password
+	}
+	if other.SSLMode != nil {
+		p.SSLMode = other.SSLMode
+	}
+	return p
+}
+
+// IsEmpty reports whether p sets no field. Nested
+// partials that are empty themselves count as unset (see Prune).
+func (p *DatabaseConfigPartial) IsEmpty() bool {
+	if p == nil {
+		return true
+	}
+	if p.Driver != nil {
+		return false
+	}
+	if p.Host != nil {
+		return false
+	}
+	if p.Port != nil {
+		return false
+	}
+	if p.Username != nil {
+		return false
+	}
+	if p.Password != nil {
+		return false
+	}
+	if p.SSLMode != nil {
+		return false
+	}
+	return true
+}
+
+// Prune sets the nested partials of p that are empty to nil, at every depth, so that
+// p encodes without empty objects. Applying a pruned partial no longer allocates the
+// structs of nil pointer fields that those partials would have left zero. Partials of
+// fields replaced as a whole are kept, since applying them resets the field.
+func (p *DatabaseConfigPartial) Prune() {
+	if p == nil {
+		return
+	}
+}
+
+// ConfigSparseEntry sets a single leaf field of Config addressed by a dotted
+// path of Go field names (e.g. "Database.Host"). Value must have the field's
+// type, or the pointed-to type for pointer fields.
+type ConfigSparseEntry struct {
+	Path  string
+	Value any
+}
+
+// Overlay returns p with layers merged over it in order, the last one winning, to
+// compose the partials of a defaults, file and environment pipeline before applying
+// them to a Config: defaults.Overlay(file, env). See Merge.
+func (p ConfigPartial) Overlay(layers ...ConfigPartial) ConfigPartial {
+	for _, layer := range layers {
+		p = p.Merge(layer)
+	}
+	return p
 }
@@ -0,0 +1,22 @@
+package composite
+
+// Node is a vertex of a graph whose edges can share nodes and form cycles, which Copy
+// duplicates with the same edges between the copies of the nodes.
+//
+//go:generate go run github.com/bobcob7/sudo-gen copy -graph -tests
+type Node struct {
+	Name     string            `json:"name"`
+	Next     *Node             `json:"next"`
+	Edges    []*Node           `json:"edges"`
+	ByName   map[string]*Node  `json:"byName"`
+	Weight   *float64          `json:"weight"`
+	Position Point             `json:"position"`
+	Path     []Point           `json:"path"`
+	Labels   map[string]string `json:"labels"`
+}
+
+// Point places a Node in a layout, relative to its anchor.
+type Point struct {
+	X, Y   float64
+	Anchor *Node `json:"anchor"`
+}
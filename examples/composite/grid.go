@@ -0,0 +1,35 @@
+package composite
+
+// Grid is a layout of cells. Equal skips its nested slices and maps, as -skip-nested
+// allows, and compares Rows through the Equal method of Row.
+//
+//go:generate go run github.com/bobcob7/sudo-gen equals -skip-nested -tests
+type Grid struct {
+	Name    string              `json:"name"`
+	Cells   [][]Cell            `json:"cells"`
+	ByLayer map[string][]Cell   `json:"byLayer"`
+	Styles  []map[string]string `json:"styles"`
+	Rows    []Row               `json:"rows"`
+}
+
+// Cell is a cell of a Grid.
+type Cell struct {
+	Value  string  `json:"value"`
+	Weight float64 `json:"weight"`
+}
+
+// Row is a row of cells, compared cell by cell.
+type Row []Cell
+
+// Equal reports whether r and other hold equal cells in the same order.
+func (r *Row) Equal(other *Row) bool {
+	if len(*r) != len(*other) {
+		return false
+	}
+	for i := range *r {
+		if (*r)[i] != (*other)[i] {
+			return false
+		}
+	}
+	return true
+}
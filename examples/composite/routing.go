@@ -0,0 +1,27 @@
+package composite
+
+// Routing holds values nested several levels deep, which Copy duplicates level by
+// level so that no slice, map or pointer is shared with the copy.
+//
+//go:generate go run github.com/bobcob7/sudo-gen copy -tests -redact -encrypt
+type Routing struct {
+	Tags      []map[string]Tag          `json:"tags"`
+	Databases map[string][]*Database    `json:"databases"`
+	Fallbacks *[]Tag                    `json:"fallbacks"`
+	Weights   map[string]map[string]int `json:"weights"`
+	Hops      [][]string                `json:"hops"`
+	Timeouts  []*int                    `json:"timeouts"`
+	Primary   map[string]*Database      `json:"primary"`
+}
+
+// Tag labels a route.
+type Tag struct {
+	Key    string   `json:"key"`
+	Values []string `json:"values"`
+}
+
+// Database is a backend a route reads from.
+type Database struct {
+	Host     string `json:"host"`
+	Password string `json:"password" sudo:"secret"`
+}
@@ -0,0 +1,25 @@
+package convert
+
+// Config is the domain representation of InputConfig.
+type Config struct {
+	Name     string         `json:"name"`
+	Port     int64          `json:"port"`
+	Hosts    []string       `json:"hosts"`
+	Tags     []Tag          `json:"tags"`
+	Limits   map[string]int `json:"limits"`
+	Database DatabaseConfig `json:"database"`
+	Verbose  bool           `json:"verbose"`
+	Debug    bool           `json:"debug"`
+}
+
+// Tag is a key-value tag.
+type Tag struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// DatabaseConfig represents database connection settings.
+type DatabaseConfig struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
@@ -0,0 +1,26 @@
+package convert
+
+// InputConfig is the wire format of Config, as decoded from a request body.
+//
+//go:generate go run github.com/bobcob7/sudo-gen convert -to=Config -bidirectional -tests
+type InputConfig struct {
+	Name     *string           `json:"name"`
+	Port     *int              `json:"port"`
+	Hosts    []string          `json:"hosts"`
+	Tags     []*InputTag       `json:"tags"`
+	Limits   map[string]*int32 `json:"limits"`
+	Database *InputDatabase    `json:"database"`
+	Verbose  bool              `json:"verbose"`
+}
+
+// InputTag is the wire format of Tag.
+type InputTag struct {
+	Key   *string `json:"key"`
+	Value *string `json:"value"`
+}
+
+// InputDatabase is the wire format of DatabaseConfig.
+type InputDatabase struct {
+	Host       *string `json:"host"`
+	PortNumber *int    `json:"port"`
+}
@@ -0,0 +1,30 @@
+// Package integrations generates the code that imports third-party packages:
+// fsnotify and OpenTelemetry for the broker, TOML, HCL and mapstructure for the
+// loader, fx and wire providers, the cobra command, and the etcd and Consul
+// adapters. It is a module of its own, so that building and testing it checks
+// the generated code against the real APIs without sudo-gen depending on them.
+package integrations
+
+import "time"
+
+// Config is the config of a service whose layers come from files, a KV store
+// and the command line.
+//
+//go:generate go run github.com/bobcob7/sudo-gen layerbroker -tests -watch -otel
+//go:generate go run github.com/bobcob7/sudo-gen loader -tests -formats=json,yaml,toml,hcl -mapstructure
+//go:generate go run github.com/bobcob7/sudo-gen providers -tests -frameworks=fx,wire
+//go:generate go run github.com/bobcob7/sudo-gen cli -tests
+//go:generate go run github.com/bobcob7/sudo-gen integrations -tests -sources=etcd,consul -otel
+type Config struct {
+	Name    string        `json:"name,omitempty"`
+	Port    int           `json:"port,omitempty"`
+	Timeout time.Duration `json:"timeout,omitempty"`
+	Tags    []string      `json:"tags,omitempty"`
+	DB      Database      `json:"db,omitempty"`
+}
+
+// Database is the database the service connects to.
+type Database struct {
+	Host string `json:"host,omitempty"`
+	User string `json:"user,omitempty"`
+}
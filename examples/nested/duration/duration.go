@@ -1,29 +1,6 @@
 package duration
 
-import (
-	"encoding/json"
-	"time"
-)
-
-type Duration time.Duration
-
-func (d *Duration) UnmarshalJSON(b []byte) error {
-	var s string
-	if err := json.Unmarshal(b, &s); err != nil {
-		return err
-	}
-	duration, err := time.ParseDuration(s)
-	if err != nil {
-		return err
-	}
-	*d = Duration(duration)
-	return nil
-}
-
-func (d Duration) MarshalJSON() ([]byte, error) {
-	s := time.Duration(d).String()
-	return json.Marshal(s)
-}
+import "time"
 
 type Timestamp struct {
 	Minutes int `json:"minutes,omitempty"`
@@ -38,3 +15,9 @@ func (t *Timestamp) ToDuration() time.Duration {
 	total += time.Duration(t.Days) * 24 * time.Hour
 	return total
 }
+
+// Duration is a time.Duration that flags set and print in its string form, like
+// "1m30s".
+//
+//go:generate go run github.com/bobcob7/sudo-gen flagvalue -type=Duration -tests
+type Duration time.Duration
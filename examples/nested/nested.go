@@ -1,6 +1,10 @@
 package nested
 
-import "github.com/bobcob7/sudo-gen/examples/nested/duration"
+import (
+	"time"
+
+	"github.com/bobcob7/sudo-gen/examples/nested/duration"
+)
 
 type Job struct {
 	Title    string              `json:"title,omitempty"`
@@ -11,12 +15,12 @@ type Job struct {
 }
 
 type Home struct {
-	Address     string `json:"address,omitempty"`
-	City        string `json:"city,omitempty"`
-	ZipCode     string `json:"zip_code,omitempty"`
-	Age         duration.Duration
-	Coords      Coordinates  `json:"coords,omitempty"`
-	Destination *Coordinates `json:"destination,omitempty"`
+	Address     string        `json:"address,omitempty"`
+	City        string        `json:"city,omitempty"`
+	ZipCode     string        `json:"zip_code,omitempty"`
+	Age         time.Duration `json:"age,omitempty"`
+	Coords      Coordinates   `json:"coords,omitempty"`
+	Destination *Coordinates  `json:"destination,omitempty" sudo:"merge=replace"`
 }
 
 type Coordinates struct {
@@ -1,17 +1,23 @@
 package nested
 
 import (
+	"net/url"
 	"time"
 
 	"github.com/bobcob7/sudo-gen/examples/nested/duration"
 )
 
-//go:generate go run ../../../sudo-gen layerbroker -tests -json
+//go:generate go run github.com/bobcob7/sudo-gen layerbroker -tests -json -external=partial -clear -migrate-deprecated -merge-patch -json-patch -explain-diff -with -bench -utc
+//go:generate go run github.com/bobcob7/sudo-gen loader -tests -formats=json,yaml -extends -expand-env -secret-refs
+//go:generate go run github.com/bobcob7/sudo-gen providers -tests
 type Config struct {
 	Name      string             `json:"name,omitempty"`
 	Jobs      []Job              `json:"jobs,omitempty"`
+	City      string             `json:"city,omitempty" sudo:"deprecated=use Home.City"`
 	Home      Home               `json:"home,omitempty"`
-	OtherHome *Home              `json:"home,omitempty"`
+	OtherHome *Home              `json:"other_home,omitempty"`
 	CreatedAt time.Time          `json:"created_at,omitempty"`
 	Limit     duration.Timestamp `json:"limit,omitempty"`
+	Avatar    []byte             `json:"avatar,omitempty"`
+	Website   *url.URL           `json:"website,omitempty"`
 }
@@ -1,7 +1,17 @@
 // Code generated by sudo-gen copy. DO NOT EDIT.
+// Generated by sudo-gen (devel): layerbroker -tests -json -external=partial -clear -migrate-deprecated -merge-patch -json-patch -explain-diff -with -bench -utc
 
 package nested
 
+import (
+	"bytes"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/bobcob7/sudo-gen/examples/nested/duration"
+)
+
 // Copy creates a deep copy of the Config.
 func (c *Config) Copy() *Config {
 	if c == nil {
@@ -15,12 +25,18 @@ func (c *Config) Copy() *Config {
 			dst.Jobs[i] = *c.Jobs[i].Copy()
 		}
 	}
+	dst.City = c.City
 	dst.Home = *c.Home.Copy()
 	if c.OtherHome != nil {
 		dst.OtherHome = c.OtherHome.Copy()
 	}
 	dst.CreatedAt = c.CreatedAt
 	dst.Limit = c.Limit
+	dst.Avatar = bytes.Clone(c.Avatar)
+	if c.Website != nil {
+		v := *c.Website
+		dst.Website = &v
+	}
 	return dst
 }
 
@@ -67,3 +83,279 @@ func (c *Home) Copy() *Home {
 	}
 	return dst
 }
+
+// CopyInto deep copies c into dst, reusing the slices, maps and nested structs
+// dst already holds where possible instead of allocating new ones. dst must not share
+// memory with values still in use elsewhere. If c is nil, dst is reset to the zero value.
+func (c *Config) CopyInto(dst *Config) {
+	if c == nil {
+		*dst = Config{}
+		return
+	}
+	dst.Name = c.Name
+	if c.Jobs == nil {
+		dst.Jobs = nil
+	} else {
+		if dst.Jobs == nil {
+			dst.Jobs = make([]Job, 0, len(c.Jobs))
+		}
+		dst.Jobs = slices.Grow(dst.Jobs[:0], len(c.Jobs))[:len(c.Jobs)]
+		for i := range c.Jobs {
+			c.Jobs[i].CopyInto(&dst.Jobs[i])
+		}
+	}
+	dst.City = c.City
+	c.Home.CopyInto(&dst.Home)
+	if c.OtherHome == nil {
+		dst.OtherHome = nil
+	} else {
+		if dst.OtherHome == nil {
+			dst.OtherHome = new(Home)
+		}
+		c.OtherHome.CopyInto(dst.OtherHome)
+	}
+	dst.CreatedAt = c.CreatedAt
+	dst.Limit = c.Limit
+	if c.Avatar == nil {
+		dst.Avatar = nil
+	} else {
+		if dst.Avatar == nil {
+			dst.Avatar = make([]byte, 0, len(c.Avatar))
+		}
+		dst.Avatar = append(slices.Grow(dst.Avatar[:0], len(c.Avatar)), c.Avatar...)
+	}
+	if c.Website == nil {
+		dst.Website = nil
+	} else if dst.Website == nil {
+		v := *c.Website
+		dst.Website = &v
+	} else {
+		*dst.Website = *c.Website
+	}
+}
+
+// CopyInto deep copies c into dst, reusing the slices, maps and nested structs
+// dst already holds where possible instead of allocating new ones. dst must not share
+// memory with values still in use elsewhere. If c is nil, dst is reset to the zero value.
+func (c *Job) CopyInto(dst *Job) {
+	if c == nil {
+		*dst = Job{}
+		return
+	}
+	dst.Title = c.Title
+	dst.Company = c.Company
+	dst.Location = c.Location
+	if c.Tenure == nil {
+		dst.Tenure = nil
+	} else if dst.Tenure == nil {
+		v := *c.Tenure
+		dst.Tenure = &v
+	} else {
+		*dst.Tenure = *c.Tenure
+	}
+	if c.Coords == nil {
+		dst.Coords = nil
+	} else {
+		if dst.Coords == nil {
+			dst.Coords = new(Coordinates)
+		}
+		c.Coords.CopyInto(dst.Coords)
+	}
+}
+
+// CopyInto deep copies c into dst, reusing the slices, maps and nested structs
+// dst already holds where possible instead of allocating new ones. dst must not share
+// memory with values still in use elsewhere. If c is nil, dst is reset to the zero value.
+func (c *Coordinates) CopyInto(dst *Coordinates) {
+	if c == nil {
+		*dst = Coordinates{}
+		return
+	}
+	dst.Latitude = c.Latitude
+	dst.Longitude = c.Longitude
+}
+
+// CopyInto deep copies c into dst, reusing the slices, maps and nested structs
+// dst already holds where possible instead of allocating new ones. dst must not share
+// memory with values still in use elsewhere. If c is nil, dst is reset to the zero value.
+func (c *Home) CopyInto(dst *Home) {
+	if c == nil {
+		*dst = Home{}
+		return
+	}
+	dst.Address = c.Address
+	dst.City = c.City
+	dst.ZipCode = c.ZipCode
+	dst.Age = c.Age
+	c.Coords.CopyInto(&dst.Coords)
+	if c.Destination == nil {
+		dst.Destination = nil
+	} else {
+		if dst.Destination == nil {
+			dst.Destination = new(Coordinates)
+		}
+		c.Destination.CopyInto(dst.Destination)
+	}
+}
+
+// With returns a deep copy of the Config with opts applied to it, in order. A nil c
+// is copied as the zero value.
+func (c *Config) With(opts ...func(*Config)) Config {
+	dst := c.Copy()
+	if dst == nil {
+		dst = &Config{}
+	}
+	for _, opt := range opts {
+		opt(dst)
+	}
+	return *dst
+}
+
+// WithName returns a deep copy of the Config with Name set to v.
+func (c *Config) WithName(v string) Config {
+	return c.With(func(dst *Config) { dst.Name = v })
+}
+
+// WithJobs returns a deep copy of the Config with Jobs set to v.
+func (c *Config) WithJobs(v []Job) Config {
+	return c.With(func(dst *Config) { dst.Jobs = v })
+}
+
+// WithCity returns a deep copy of the Config with City set to v.
+func (c *Config) WithCity(v string) Config {
+	return c.With(func(dst *Config) { dst.City = v })
+}
+
+// WithHome returns a deep copy of the Config with Home set to v.
+func (c *Config) WithHome(v Home) Config {
+	return c.With(func(dst *Config) { dst.Home = v })
+}
+
+// WithOtherHome returns a deep copy of the Config with OtherHome set to v.
+func (c *Config) WithOtherHome(v *Home) Config {
+	return c.With(func(dst *Config) { dst.OtherHome = v })
+}
+
+// WithCreatedAt returns a deep copy of the Config with CreatedAt set to v.
+func (c *Config) WithCreatedAt(v time.Time) Config {
+	return c.With(func(dst *Config) { dst.CreatedAt = v })
+}
+
+// WithLimit returns a deep copy of the Config with Limit set to v.
+func (c *Config) WithLimit(v duration.Timestamp) Config {
+	return c.With(func(dst *Config) { dst.Limit = v })
+}
+
+// WithAvatar returns a deep copy of the Config with Avatar set to v.
+func (c *Config) WithAvatar(v []byte) Config {
+	return c.With(func(dst *Config) { dst.Avatar = v })
+}
+
+// WithWebsite returns a deep copy of the Config with Website set to v.
+func (c *Config) WithWebsite(v *url.URL) Config {
+	return c.With(func(dst *Config) { dst.Website = v })
+}
+
+// With returns a deep copy of the Job with opts applied to it, in order. A nil c
+// is copied as the zero value.
+func (c *Job) With(opts ...func(*Job)) Job {
+	dst := c.Copy()
+	if dst == nil {
+		dst = &Job{}
+	}
+	for _, opt := range opts {
+		opt(dst)
+	}
+	return *dst
+}
+
+// WithTitle returns a deep copy of the Job with Title set to v.
+func (c *Job) WithTitle(v string) Job {
+	return c.With(func(dst *Job) { dst.Title = v })
+}
+
+// WithCompany returns a deep copy of the Job with Company set to v.
+func (c *Job) WithCompany(v string) Job {
+	return c.With(func(dst *Job) { dst.Company = v })
+}
+
+// WithLocation returns a deep copy of the Job with Location set to v.
+func (c *Job) WithLocation(v string) Job {
+	return c.With(func(dst *Job) { dst.Location = v })
+}
+
+// WithTenure returns a deep copy of the Job with Tenure set to v.
+func (c *Job) WithTenure(v *duration.Timestamp) Job {
+	return c.With(func(dst *Job) { dst.Tenure = v })
+}
+
+// WithCoords returns a deep copy of the Job with Coords set to v.
+func (c *Job) WithCoords(v *Coordinates) Job {
+	return c.With(func(dst *Job) { dst.Coords = v })
+}
+
+// With returns a deep copy of the Coordinates with opts applied to it, in order. A nil c
+// is copied as the zero value.
+func (c *Coordinates) With(opts ...func(*Coordinates)) Coordinates {
+	dst := c.Copy()
+	if dst == nil {
+		dst = &Coordinates{}
+	}
+	for _, opt := range opts {
+		opt(dst)
+	}
+	return *dst
+}
+
+// WithLatitude returns a deep copy of the Coordinates with Latitude set to v.
+func (c *Coordinates) WithLatitude(v float64) Coordinates {
+	return c.With(func(dst *Coordinates) { dst.Latitude = v })
+}
+
+// WithLongitude returns a deep copy of the Coordinates with Longitude set to v.
+func (c *Coordinates) WithLongitude(v float64) Coordinates {
+	return c.With(func(dst *Coordinates) { dst.Longitude = v })
+}
+
+// With returns a deep copy of the Home with opts applied to it, in order. A nil c
+// is copied as the zero value.
+func (c *Home) With(opts ...func(*Home)) Home {
+	dst := c.Copy()
+	if dst == nil {
+		dst = &Home{}
+	}
+	for _, opt := range opts {
+		opt(dst)
+	}
+	return *dst
+}
+
+// WithAddress returns a deep copy of the Home with Address set to v.
+func (c *Home) WithAddress(v string) Home {
+	return c.With(func(dst *Home) { dst.Address = v })
+}
+
+// WithCity returns a deep copy of the Home with City set to v.
+func (c *Home) WithCity(v string) Home {
+	return c.With(func(dst *Home) { dst.City = v })
+}
+
+// WithZipCode returns a deep copy of the Home with ZipCode set to v.
+func (c *Home) WithZipCode(v string) Home {
+	return c.With(func(dst *Home) { dst.ZipCode = v })
+}
+
+// WithAge returns a deep copy of the Home with Age set to v.
+func (c *Home) WithAge(v time.Duration) Home {
+	return c.With(func(dst *Home) { dst.Age = v })
+}
+
+// WithCoords returns a deep copy of the Home with Coords set to v.
+func (c *Home) WithCoords(v Coordinates) Home {
+	return c.With(func(dst *Home) { dst.Coords = v })
+}
+
+// WithDestination returns a deep copy of the Home with Destination set to v.
+func (c *Home) WithDestination(v *Coordinates) Home {
+	return c.With(func(dst *Home) { dst.Destination = v })
+}
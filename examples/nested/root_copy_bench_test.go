@@ -0,0 +1,89 @@
+// Code generated by sudo-gen copy. DO NOT EDIT.
+// Generated by sudo-gen (devel): layerbroker -tests -json -external=partial -clear -migrate-deprecated -merge-patch -json-patch -explain-diff -with -bench -utc
+
+package nested
+
+import (
+	"testing"
+	"time"
+)
+
+// configCopyBenchValue returns a Config with every field set, for benchmarks.
+func configCopyBenchValue() *Config {
+	return &Config{
+		Name: "value",
+		Jobs: []Job{{
+			Title:    "value",
+			Company:  "value",
+			Location: "value",
+			Coords: &Coordinates{
+				Latitude:  1.5,
+				Longitude: 1.5,
+			},
+		}, {
+			Title:    "value",
+			Company:  "value",
+			Location: "value",
+			Coords: &Coordinates{
+				Latitude:  1.5,
+				Longitude: 1.5,
+			},
+		}, {
+			Title:    "value",
+			Company:  "value",
+			Location: "value",
+			Coords: &Coordinates{
+				Latitude:  1.5,
+				Longitude: 1.5,
+			},
+		}},
+		City: "value",
+		Home: Home{
+			Address: "value",
+			City:    "value",
+			ZipCode: "value",
+			Age:     time.Second,
+			Coords: Coordinates{
+				Latitude:  1.5,
+				Longitude: 1.5,
+			},
+			Destination: &Coordinates{
+				Latitude:  1.5,
+				Longitude: 1.5,
+			},
+		},
+		OtherHome: &Home{
+			Address: "value",
+			City:    "value",
+			ZipCode: "value",
+			Age:     time.Second,
+			Coords: Coordinates{
+				Latitude:  1.5,
+				Longitude: 1.5,
+			},
+			Destination: &Coordinates{
+				Latitude:  1.5,
+				Longitude: 1.5,
+			},
+		},
+		CreatedAt: time.Unix(1700000000, 0),
+		Avatar:    []byte{42, 42, 42},
+	}
+}
+
+func BenchmarkConfigCopy(b *testing.B) {
+	c := configCopyBenchValue()
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = c.Copy()
+	}
+}
+
+func BenchmarkConfigCopyInto(b *testing.B) {
+	c := configCopyBenchValue()
+	var dst Config
+	b.ReportAllocs()
+	for b.Loop() {
+		c.CopyInto(&dst)
+	}
+}
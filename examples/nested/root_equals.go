@@ -1,7 +1,16 @@
 // Code generated by sudo-gen equals. DO NOT EDIT.
+// Generated by sudo-gen (devel): layerbroker -tests -json -external=partial -clear -migrate-deprecated -merge-patch -json-patch -explain-diff -with -bench -utc
 
 package nested
 
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 // Equal returns true if c and other have the same values.
 func (c *Config) Equal(other *Config) bool {
 	if c == other {
@@ -21,6 +30,9 @@ func (c *Config) Equal(other *Config) bool {
 			return false
 		}
 	}
+	if c.City != other.City {
+		return false
+	}
 	if !c.Home.Equal(&other.Home) {
 		return false
 	}
@@ -33,9 +45,77 @@ func (c *Config) Equal(other *Config) bool {
 	if c.Limit != other.Limit {
 		return false
 	}
+	if !bytes.Equal(c.Avatar, other.Avatar) {
+		return false
+	}
+	if (c.Website == nil) != (other.Website == nil) || c.Website != nil && !(c.Website.String() == other.Website.String()) {
+		return false
+	}
 	return true
 }
 
+// Diff returns the dotted paths of the fields that differ between c and other, such
+// as "Database.Host", "Tags[2]" or `Labels["env"]`, in field order. Slices of
+// different lengths are reported as a whole. Diff returns nil when c.Equal(other),
+// and [""] when only one of them is nil.
+func (c *Config) Diff(other *Config) []string {
+	var paths []string
+	c.diff(other, "", func(path string, a, b any) {
+		paths = append(paths, path)
+	})
+	return paths
+}
+
+// ExplainNotEqual describes each difference between c and other on a line of its own,
+// such as Database.Host: "a" != "b", for test failure messages. It returns "" when
+// c.Equal(other).
+func (c *Config) ExplainNotEqual(other *Config) string {
+	var lines []string
+	c.diff(other, "", func(path string, a, b any) {
+		lines = append(lines, fmt.Sprintf("%s: %#v != %#v", cmp.Or(path, "Config"), a, b))
+	})
+	return strings.Join(lines, "\n")
+}
+
+// diff calls report with the path under prefix and both values of each field that
+// differs between c and other.
+func (c *Config) diff(other *Config, prefix string, report func(path string, a, b any)) {
+	if c == other {
+		return
+	}
+	if c == nil || other == nil {
+		report(strings.TrimSuffix(prefix, "."), c, other)
+		return
+	}
+	if c.Name != other.Name {
+		report(prefix+"Name", c.Name, other.Name)
+	}
+	if len(c.Jobs) != len(other.Jobs) {
+		report(prefix+"Jobs", c.Jobs, other.Jobs)
+	} else {
+		for i := range c.Jobs {
+			c.Jobs[i].diff(&other.Jobs[i], prefix+"Jobs["+strconv.Itoa(i)+"].", report)
+		}
+	}
+	if c.City != other.City {
+		report(prefix+"City", c.City, other.City)
+	}
+	c.Home.diff(&other.Home, prefix+"Home.", report)
+	c.OtherHome.diff(other.OtherHome, prefix+"OtherHome.", report)
+	if !c.CreatedAt.Equal(other.CreatedAt) {
+		report(prefix+"CreatedAt", c.CreatedAt, other.CreatedAt)
+	}
+	if c.Limit != other.Limit {
+		report(prefix+"Limit", c.Limit, other.Limit)
+	}
+	if !bytes.Equal(c.Avatar, other.Avatar) {
+		report(prefix+"Avatar", c.Avatar, other.Avatar)
+	}
+	if (c.Website == nil) != (other.Website == nil) || c.Website != nil && !(c.Website.String() == other.Website.String()) {
+		report(prefix+"Website", c.Website, other.Website)
+	}
+}
+
 // Equal returns true if c and other have the same values.
 func (c *Job) Equal(other *Job) bool {
 	if c == other {
@@ -65,6 +145,61 @@ func (c *Job) Equal(other *Job) bool {
 	return true
 }
 
+// Diff returns the dotted paths of the fields that differ between c and other, such
+// as "Database.Host", "Tags[2]" or `Labels["env"]`, in field order. Slices of
+// different lengths are reported as a whole. Diff returns nil when c.Equal(other),
+// and [""] when only one of them is nil.
+func (c *Job) Diff(other *Job) []string {
+	var paths []string
+	c.diff(other, "", func(path string, a, b any) {
+		paths = append(paths, path)
+	})
+	return paths
+}
+
+// ExplainNotEqual describes each difference between c and other on a line of its own,
+// such as Database.Host: "a" != "b", for test failure messages. It returns "" when
+// c.Equal(other).
+func (c *Job) ExplainNotEqual(other *Job) string {
+	var lines []string
+	c.diff(other, "", func(path string, a, b any) {
+		lines = append(lines, fmt.Sprintf("%s: %#v != %#v", cmp.Or(path, "Job"), a, b))
+	})
+	return strings.Join(lines, "\n")
+}
+
+// diff calls report with the path under prefix and both values of each field that
+// differs between c and other.
+func (c *Job) diff(other *Job, prefix string, report func(path string, a, b any)) {
+	if c == other {
+		return
+	}
+	if c == nil || other == nil {
+		report(strings.TrimSuffix(prefix, "."), c, other)
+		return
+	}
+	if c.Title != other.Title {
+		report(prefix+"Title", c.Title, other.Title)
+	}
+	if c.Company != other.Company {
+		report(prefix+"Company", c.Company, other.Company)
+	}
+	if c.Location != other.Location {
+		report(prefix+"Location", c.Location, other.Location)
+	}
+	if a, b := c.Tenure, other.Tenure; (a == nil) != (b == nil) || a != nil && *a != *b {
+		var av, bv any
+		if a != nil {
+			av = *a
+		}
+		if b != nil {
+			bv = *b
+		}
+		report(prefix+"Tenure", av, bv)
+	}
+	c.Coords.diff(other.Coords, prefix+"Coords.", report)
+}
+
 // Equal returns true if c and other have the same values.
 func (c *Coordinates) Equal(other *Coordinates) bool {
 	if c == other {
@@ -82,6 +217,47 @@ func (c *Coordinates) Equal(other *Coordinates) bool {
 	return true
 }
 
+// Diff returns the dotted paths of the fields that differ between c and other, such
+// as "Database.Host", "Tags[2]" or `Labels["env"]`, in field order. Slices of
+// different lengths are reported as a whole. Diff returns nil when c.Equal(other),
+// and [""] when only one of them is nil.
+func (c *Coordinates) Diff(other *Coordinates) []string {
+	var paths []string
+	c.diff(other, "", func(path string, a, b any) {
+		paths = append(paths, path)
+	})
+	return paths
+}
+
+// ExplainNotEqual describes each difference between c and other on a line of its own,
+// such as Database.Host: "a" != "b", for test failure messages. It returns "" when
+// c.Equal(other).
+func (c *Coordinates) ExplainNotEqual(other *Coordinates) string {
+	var lines []string
+	c.diff(other, "", func(path string, a, b any) {
+		lines = append(lines, fmt.Sprintf("%s: %#v != %#v", cmp.Or(path, "Coordinates"), a, b))
+	})
+	return strings.Join(lines, "\n")
+}
+
+// diff calls report with the path under prefix and both values of each field that
+// differs between c and other.
+func (c *Coordinates) diff(other *Coordinates, prefix string, report func(path string, a, b any)) {
+	if c == other {
+		return
+	}
+	if c == nil || other == nil {
+		report(strings.TrimSuffix(prefix, "."), c, other)
+		return
+	}
+	if c.Latitude != other.Latitude {
+		report(prefix+"Latitude", c.Latitude, other.Latitude)
+	}
+	if c.Longitude != other.Longitude {
+		report(prefix+"Longitude", c.Longitude, other.Longitude)
+	}
+}
+
 // Equal returns true if c and other have the same values.
 func (c *Home) Equal(other *Home) bool {
 	if c == other {
@@ -110,3 +286,52 @@ func (c *Home) Equal(other *Home) bool {
 	}
 	return true
 }
+
+// Diff returns the dotted paths of the fields that differ between c and other, such
+// as "Database.Host", "Tags[2]" or `Labels["env"]`, in field order. Slices of
+// different lengths are reported as a whole. Diff returns nil when c.Equal(other),
+// and [""] when only one of them is nil.
+func (c *Home) Diff(other *Home) []string {
+	var paths []string
+	c.diff(other, "", func(path string, a, b any) {
+		paths = append(paths, path)
+	})
+	return paths
+}
+
+// ExplainNotEqual describes each difference between c and other on a line of its own,
+// such as Database.Host: "a" != "b", for test failure messages. It returns "" when
+// c.Equal(other).
+func (c *Home) ExplainNotEqual(other *Home) string {
+	var lines []string
+	c.diff(other, "", func(path string, a, b any) {
+		lines = append(lines, fmt.Sprintf("%s: %#v != %#v", cmp.Or(path, "Home"), a, b))
+	})
+	return strings.Join(lines, "\n")
+}
+
+// diff calls report with the path under prefix and both values of each field that
+// differs between c and other.
+func (c *Home) diff(other *Home, prefix string, report func(path string, a, b any)) {
+	if c == other {
+		return
+	}
+	if c == nil || other == nil {
+		report(strings.TrimSuffix(prefix, "."), c, other)
+		return
+	}
+	if c.Address != other.Address {
+		report(prefix+"Address", c.Address, other.Address)
+	}
+	if c.City != other.City {
+		report(prefix+"City", c.City, other.City)
+	}
+	if c.ZipCode != other.ZipCode {
+		report(prefix+"ZipCode", c.ZipCode, other.ZipCode)
+	}
+	if c.Age != other.Age {
+		report(prefix+"Age", c.Age, other.Age)
+	}
+	c.Coords.diff(&other.Coords, prefix+"Coords.", report)
+	c.Destination.diff(other.Destination, prefix+"Destination.", report)
+}
@@ -0,0 +1,85 @@
+// Code generated by sudo-gen equals. DO NOT EDIT.
+// Generated by sudo-gen (devel): layerbroker -tests -json -external=partial -clear -migrate-deprecated -merge-patch -json-patch -explain-diff -with -bench -utc
+
+package nested
+
+import (
+	"testing"
+	"time"
+)
+
+// configEqualBenchValue returns a Config with every field set, for
+// benchmarks.
+func configEqualBenchValue() *Config {
+	return &Config{
+		Name: "value",
+		Jobs: []Job{{
+			Title:    "value",
+			Company:  "value",
+			Location: "value",
+			Coords: &Coordinates{
+				Latitude:  1.5,
+				Longitude: 1.5,
+			},
+		}, {
+			Title:    "value",
+			Company:  "value",
+			Location: "value",
+			Coords: &Coordinates{
+				Latitude:  1.5,
+				Longitude: 1.5,
+			},
+		}, {
+			Title:    "value",
+			Company:  "value",
+			Location: "value",
+			Coords: &Coordinates{
+				Latitude:  1.5,
+				Longitude: 1.5,
+			},
+		}},
+		City: "value",
+		Home: Home{
+			Address: "value",
+			City:    "value",
+			ZipCode: "value",
+			Age:     time.Second,
+			Coords: Coordinates{
+				Latitude:  1.5,
+				Longitude: 1.5,
+			},
+			Destination: &Coordinates{
+				Latitude:  1.5,
+				Longitude: 1.5,
+			},
+		},
+		OtherHome: &Home{
+			Address: "value",
+			City:    "value",
+			ZipCode: "value",
+			Age:     time.Second,
+			Coords: Coordinates{
+				Latitude:  1.5,
+				Longitude: 1.5,
+			},
+			Destination: &Coordinates{
+				Latitude:  1.5,
+				Longitude: 1.5,
+			},
+		},
+		CreatedAt: time.Unix(1700000000, 0),
+		Avatar:    []byte{42, 42, 42},
+	}
+}
+
+// BenchmarkConfigEqual compares two equal values, so every field is compared.
+func BenchmarkConfigEqual(b *testing.B) {
+	x, y := configEqualBenchValue(), configEqualBenchValue()
+	if !x.Equal(y) {
+		b.Fatal("expected the values to be equal")
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = x.Equal(y)
+	}
+}
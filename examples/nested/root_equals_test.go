@@ -1,4 +1,5 @@
 // Code generated by sudo-gen equals. DO NOT EDIT.
+// Generated by sudo-gen (devel): layerbroker -tests -json -external=partial -clear -migrate-deprecated -merge-patch -json-patch -explain-diff -with -bench -utc
 
 package nested
 
@@ -39,6 +40,47 @@ func TestConfigEqualEmptyStructs(t *testing.T) {
 	}
 }
 
+func TestConfigEqualAvatarBytes(t *testing.T) {
+	a := &Config{Avatar: []byte("abc")}
+	b := &Config{Avatar: []byte("abc")}
+	if !a.Equal(b) {
+		t.Error("same bytes should be equal")
+	}
+	b.Avatar[0] = 'x'
+	if a.Equal(b) {
+		t.Error("different bytes should not be equal")
+	}
+	a.Avatar, b.Avatar = nil, []byte{}
+	if !a.Equal(b) {
+		t.Error("nil and empty bytes should be equal")
+	}
+}
+
+func TestConfigDiffEqual(t *testing.T) {
+	a := &Config{}
+	if diff := a.Diff(&Config{}); diff != nil {
+		t.Errorf("expected no differences, got %v", diff)
+	}
+	if got := a.ExplainNotEqual(&Config{}); got != "" {
+		t.Errorf("expected no explanation, got %q", got)
+	}
+	var b *Config
+	if diff := a.Diff(b); len(diff) != 1 || diff[0] != "" {
+		t.Errorf("expected the whole value to differ from nil, got %q", diff)
+	}
+}
+
+func TestConfigDiffName(t *testing.T) {
+	a := &Config{Name: "a"}
+	b := &Config{Name: "b"}
+	if diff := a.Diff(b); len(diff) != 1 || diff[0] != "Name" {
+		t.Errorf("expected Name to differ, got %q", diff)
+	}
+	if got, want := a.ExplainNotEqual(b), `Name: "a" != "b"`; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
 func TestJobEqualBothNil(t *testing.T) {
 	var a, b *Job
 	if !a.Equal(b) {
@@ -72,6 +114,31 @@ func TestJobEqualEmptyStructs(t *testing.T) {
 	}
 }
 
+func TestJobDiffEqual(t *testing.T) {
+	a := &Job{}
+	if diff := a.Diff(&Job{}); diff != nil {
+		t.Errorf("expected no differences, got %v", diff)
+	}
+	if got := a.ExplainNotEqual(&Job{}); got != "" {
+		t.Errorf("expected no explanation, got %q", got)
+	}
+	var b *Job
+	if diff := a.Diff(b); len(diff) != 1 || diff[0] != "" {
+		t.Errorf("expected the whole value to differ from nil, got %q", diff)
+	}
+}
+
+func TestJobDiffTitle(t *testing.T) {
+	a := &Job{Title: "a"}
+	b := &Job{Title: "b"}
+	if diff := a.Diff(b); len(diff) != 1 || diff[0] != "Title" {
+		t.Errorf("expected Title to differ, got %q", diff)
+	}
+	if got, want := a.ExplainNotEqual(b), `Title: "a" != "b"`; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
 func TestCoordinatesEqualBothNil(t *testing.T) {
 	var a, b *Coordinates
 	if !a.Equal(b) {
@@ -105,6 +172,20 @@ func TestCoordinatesEqualEmptyStructs(t *testing.T) {
 	}
 }
 
+func TestCoordinatesDiffEqual(t *testing.T) {
+	a := &Coordinates{}
+	if diff := a.Diff(&Coordinates{}); diff != nil {
+		t.Errorf("expected no differences, got %v", diff)
+	}
+	if got := a.ExplainNotEqual(&Coordinates{}); got != "" {
+		t.Errorf("expected no explanation, got %q", got)
+	}
+	var b *Coordinates
+	if diff := a.Diff(b); len(diff) != 1 || diff[0] != "" {
+		t.Errorf("expected the whole value to differ from nil, got %q", diff)
+	}
+}
+
 func TestHomeEqualBothNil(t *testing.T) {
 	var a, b *Home
 	if !a.Equal(b) {
@@ -137,3 +218,28 @@ func TestHomeEqualEmptyStructs(t *testing.T) {
 		t.Error("two empty structs should be equal")
 	}
 }
+
+func TestHomeDiffEqual(t *testing.T) {
+	a := &Home{}
+	if diff := a.Diff(&Home{}); diff != nil {
+		t.Errorf("expected no differences, got %v", diff)
+	}
+	if got := a.ExplainNotEqual(&Home{}); got != "" {
+		t.Errorf("expected no explanation, got %q", got)
+	}
+	var b *Home
+	if diff := a.Diff(b); len(diff) != 1 || diff[0] != "" {
+		t.Errorf("expected the whole value to differ from nil, got %q", diff)
+	}
+}
+
+func TestHomeDiffAddress(t *testing.T) {
+	a := &Home{Address: "a"}
+	b := &Home{Address: "b"}
+	if diff := a.Diff(b); len(diff) != 1 || diff[0] != "Address" {
+		t.Errorf("expected Address to differ, got %q", diff)
+	}
+	if got, want := a.ExplainNotEqual(b), `Address: "a" != "b"`; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
@@ -1,4 +1,5 @@
 // Code generated by sudo-gen layerbroker. DO NOT EDIT.
+// Generated by sudo-gen (devel): layerbroker -tests -json -external=partial -clear -migrate-deprecated -merge-patch -json-patch -explain-diff -with -bench -utc
 
 // ConfigLayerBroker Overview
 //
@@ -54,7 +55,24 @@
 //	defer unsub() // Clean up when done
 //
 // Subscribers are only notified when the value actually changes. Setting the same
-// value again does not trigger a notification.
+// value again does not trigger a notification. Subscribe observes the whole config
+// instead of a single field.
+//
+// # Validation
+//
+// Pass WithConfigValidator to check every config a layer change would produce.
+// A change that fails validation is undone, the current config stays in place, and
+// the layer method returns a *ConfigValidationError naming the layer:
+//
+//	broker := NewConfigLayerBroker(nil, WithConfigValidator(func(cfg Config) error {
+//	    if cfg.Name == "" {
+//	        return errors.New("name is required")
+//	    }
+//	    return nil
+//	}))
+//	if err := broker.Layer().Named("env").Set(envPartial); errors.Is(err, ErrConfigValidationFailed) {
+//	    log.Println("rejected:", err)
+//	}
 //
 // # Thread Safety
 //
@@ -68,15 +86,21 @@
 //
 // This generated code requires the following to also be generated:
 //   - ConfigPartial (from: sudo-gen merge)
-//   - Config.Copy() (from: sudo-gen copy)
+//   - Config.Copy() and CopyInto() (from: sudo-gen copy)
 package nested
 
 import (
+	"bytes"
 	"encoding/json"
-	"github.com/bobcob7/sudo-gen/examples/nested/duration"
+	"errors"
+	"net/url"
+	"slices"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/bobcob7/sudo-gen/examples/nested/duration"
 )
 
 // ConfigLayerBroker provides thread-safe access to Config with ordered layer updates and subscriptions.
@@ -86,28 +110,78 @@ type ConfigLayerBroker struct {
 	mu            sync.Mutex // protects subscribers, layers, and serializes writes
 	nextSubID     int
 	layers        []*ConfigLayer
+	created       int                // Number of layers ever created, for default layer names
+	validate      func(Config) error // Set by WithConfigValidator
+	subscribers   map[int]func(*Config)
 	subsName      map[int]func(string)
 	subsJobs      map[int]func([]Job)
+	subsCity      map[int]func(string)
 	subsHome      map[int]func(Home)
 	subsOtherHome map[int]func(*Home)
 	subsCreatedAt map[int]func(time.Time)
 	subsLimit     map[int]func(duration.Timestamp)
+	subsAvatar    map[int]func([]byte)
+	subsWebsite   map[int]func(*url.URL)
+}
+
+// ErrConfigValidationFailed is matched by every error returned for a layer change
+// that the validator of a ConfigLayerBroker rejected.
+var ErrConfigValidationFailed = errors.New("config validation failed")
+
+// ConfigValidationError reports a layer change rejected by the validator of a
+// ConfigLayerBroker. It matches ErrConfigValidationFailed with errors.Is and
+// unwraps to the error returned by the validator.
+type ConfigValidationError struct {
+	Layer string // Name of the layer whose change was rejected
+	Err   error
+}
+
+func (e *ConfigValidationError) Error() string {
+	return "layer " + strconv.Quote(e.Layer) + ": " + ErrConfigValidationFailed.Error() + ": " + e.Err.Error()
+}
+
+func (e *ConfigValidationError) Is(target error) bool {
+	return target == ErrConfigValidationFailed
+}
+
+func (e *ConfigValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ConfigLayerBrokerOption configures a broker created by NewConfigLayerBroker.
+type ConfigLayerBrokerOption func(*ConfigLayerBroker)
+
+// WithConfigValidator makes the broker check every config a layer change
+// produces with validate. A change that fails validation is undone, keeping the
+// current config, and the layer method returns a *ConfigValidationError. Changes
+// that leave the config as it is are not validated, and neither is the initial config.
+func WithConfigValidator(validate func(Config) error) ConfigLayerBrokerOption {
+	return func(b *ConfigLayerBroker) {
+		b.validate = validate
+	}
 }
 
 // NewConfigLayerBroker creates a new LayerBroker wrapping the given config.
 // If cfg is nil, an empty config is used.
-func NewConfigLayerBroker(cfg *Config) *ConfigLayerBroker {
+func NewConfigLayerBroker(cfg *Config, opts ...ConfigLayerBrokerOption) *ConfigLayerBroker {
 	if cfg == nil {
 		cfg = &Config{}
 	}
 	b := &ConfigLayerBroker{
 		base:          cfg.Copy(),
+		subscribers:   make(map[int]func(*Config)),
 		subsName:      make(map[int]func(string)),
 		subsJobs:      make(map[int]func([]Job)),
+		subsCity:      make(map[int]func(string)),
 		subsHome:      make(map[int]func(Home)),
 		subsOtherHome: make(map[int]func(*Home)),
 		subsCreatedAt: make(map[int]func(time.Time)),
 		subsLimit:     make(map[int]func(duration.Timestamp)),
+		subsAvatar:    make(map[int]func([]byte)),
+		subsWebsite:   make(map[int]func(*url.URL)),
+	}
+	for _, opt := range opts {
+		opt(b)
 	}
 	b.config.Store(cfg.Copy())
 	return b
@@ -119,15 +193,65 @@ func (b *ConfigLayerBroker) Get() *Config {
 	return b.config.Load().Copy()
 }
 
+// Ranks order the layers of a broker: a layer is always above every layer of a lower
+// rank, and above the earlier layers of its own rank.
+const (
+	configRankLayer = iota
+	configRankTop
+)
+
 // Layer returns a new layer for applying partial changes.
 func (b *ConfigLayerBroker) Layer() *ConfigLayer {
+	return b.newLayer(configRankLayer)
+}
+
+// TopLayer returns a new layer that takes priority over every layer created by Layer,
+// including those created after it, for overrides that must always win. Top layers
+// are ordered among themselves like other layers, the most recent one winning.
+func (b *ConfigLayerBroker) TopLayer() *ConfigLayer {
+	return b.newLayer(configRankTop)
+}
+
+// newLayer returns a new layer of the given rank.
+func (b *ConfigLayerBroker) newLayer(rank int) *ConfigLayer {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	l := &ConfigLayer{broker: b}
-	b.layers = append(b.layers, l)
+	l := &ConfigLayer{broker: b, rank: rank}
+	b.created++
+	l.name = "layer " + strconv.Itoa(b.created)
+	b.layers = configInsertLayer(b.layers, l)
 	return l
 }
 
+// configInsertLayer inserts l into layers above every layer of the same or a
+// lower rank.
+func configInsertLayer(layers []*ConfigLayer, l *ConfigLayer) []*ConfigLayer {
+	i := len(layers)
+	for i > 0 && layers[i-1].rank > l.rank {
+		i--
+	}
+	return slices.Insert(layers, i, l)
+}
+
+// Subscribe subscribes to changes anywhere in the configuration. The callback is
+// invoked immediately with the current configuration, and with the new configuration
+// whenever a change alters it. The configuration passed to callback is shared and
+// must not be modified. Returns an unsubscribe function.
+func (b *ConfigLayerBroker) Subscribe(callback func(*Config)) func() {
+	b.mu.Lock()
+	id := b.nextSubID
+	b.nextSubID++
+	b.subscribers[id] = callback
+	cfg := b.config.Load()
+	b.mu.Unlock()
+	callback(cfg)
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers, id)
+	}
+}
+
 // SubscribeName subscribes to changes on Name.
 // The callback is invoked immediately if the value is non-zero, and on future changes.
 // Returns an unsubscribe function.
@@ -168,6 +292,26 @@ func (b *ConfigLayerBroker) SubscribeJobs(callback func([]Job)) func() {
 	}
 }
 
+// SubscribeCity subscribes to changes on City.
+// The callback is invoked immediately if the value is non-zero, and on future changes.
+// Returns an unsubscribe function.
+func (b *ConfigLayerBroker) SubscribeCity(callback func(string)) func() {
+	b.mu.Lock()
+	id := b.nextSubID
+	b.nextSubID++
+	b.subsCity[id] = callback
+	v := b.config.Load().City
+	b.mu.Unlock()
+	if v != "" {
+		callback(v)
+	}
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subsCity, id)
+	}
+}
+
 // SubscribeHome subscribes to changes on Home.
 // The callback is invoked immediately if the value is non-zero, and on future changes.
 // Returns an unsubscribe function.
@@ -244,52 +388,169 @@ func (b *ConfigLayerBroker) SubscribeLimit(callback func(duration.Timestamp)) fu
 	}
 }
 
+// SubscribeAvatar subscribes to changes on Avatar.
+// The callback is invoked immediately if the value is non-zero, and on future changes.
+// Returns an unsubscribe function.
+func (b *ConfigLayerBroker) SubscribeAvatar(callback func([]byte)) func() {
+	b.mu.Lock()
+	id := b.nextSubID
+	b.nextSubID++
+	b.subsAvatar[id] = callback
+	v := b.config.Load().Avatar
+	b.mu.Unlock()
+	if v != nil {
+		callback(v)
+	}
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subsAvatar, id)
+	}
+}
+
+// SubscribeWebsite subscribes to changes on Website.
+// The callback is invoked immediately if the value is non-zero, and on future changes.
+// Returns an unsubscribe function.
+func (b *ConfigLayerBroker) SubscribeWebsite(callback func(*url.URL)) func() {
+	b.mu.Lock()
+	id := b.nextSubID
+	b.nextSubID++
+	b.subsWebsite[id] = callback
+	v := b.config.Load().Website
+	b.mu.Unlock()
+	if v != nil {
+		callback(v)
+	}
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subsWebsite, id)
+	}
+}
+
 // ConfigLayer applies partial updates to the LayerBroker.
 type ConfigLayer struct {
 	broker  *ConfigLayerBroker
 	partial *ConfigPartial
+	rank    int    // See configRankLayer
+	name    string // See Named
 }
 
 // Set applies the partial and notifies subscribers for changed fields.
 // Uses copy-on-write: copies the config, applies changes, then atomically swaps.
-func (l *ConfigLayer) Set(p *ConfigPartial) {
+// If the broker's validator rejects the result, the layer is left as it was and a
+// *ConfigValidationError is returned.
+func (l *ConfigLayer) Set(p *ConfigPartial) error {
 	if p == nil {
-		return
+		return nil
 	}
 	l.broker.mu.Lock()
 	defer l.broker.mu.Unlock()
-	if l.partial == nil {
-		l.partial = &ConfigPartial{}
+	prev := l.partial
+	l.partial = &ConfigPartial{}
+	if prev != nil {
+		*l.partial = *prev
 	}
 	l.mergePartial(p)
-	newCfg := l.broker.recompute()
-	oldCfg := l.broker.config.Load()
+	if err := l.broker.publish(l); err != nil {
+		l.partial = prev
+		return err
+	}
+	return nil
+}
+
+// Replace discards everything previously set on the layer and applies p in its
+// place, notifying subscribers for changed fields. A nil p clears the layer. If the
+// broker's validator rejects the result, the layer keeps its previous contents.
+func (l *ConfigLayer) Replace(p *ConfigPartial) error {
+	l.broker.mu.Lock()
+	defer l.broker.mu.Unlock()
+	prev := l.partial
+	l.partial = p
+	if err := l.broker.publish(l); err != nil {
+		l.partial = prev
+		return err
+	}
+	return nil
+}
+
+// Remove detaches the layer from the broker, so its values no longer contribute to
+// the config, and notifies subscribers for fields that change as a result. Calling
+// Set on a removed layer has no effect on the config. If the broker's validator
+// rejects the config without the layer, the layer stays attached.
+func (l *ConfigLayer) Remove() error {
+	l.broker.mu.Lock()
+	defer l.broker.mu.Unlock()
+	i := slices.Index(l.broker.layers, l)
+	if i < 0 {
+		return nil
+	}
+	l.broker.layers = slices.Delete(l.broker.layers, i, i+1)
+	if err := l.broker.publish(l); err != nil {
+		l.broker.layers = slices.Insert(l.broker.layers, i, l)
+		return err
+	}
+	return nil
+}
+
+// publish recomputes the config, notifies subscribers for changed fields and stores
+// the result. If the config changed and the validator rejects it, nothing is stored
+// and the error is reported against layer. The caller must hold b.mu.
+func (b *ConfigLayerBroker) publish(layer *ConfigLayer) error {
+	newCfg := b.recompute(b.layers)
+	oldCfg := b.config.Load()
+	if b.validate != nil && !oldCfg.Equal(newCfg) {
+		if err := b.validate(*newCfg); err != nil {
+			return &ConfigValidationError{Layer: layer.name, Err: err}
+		}
+	}
 	if old, new := oldCfg.Name, newCfg.Name; !configEqualName(old, new) {
-		for _, cb := range l.broker.subsName {
+		for _, cb := range b.subsName {
 			cb(new)
 		}
 	}
 	if old, new := oldCfg.Jobs, newCfg.Jobs; !configEqualJobs(old, new) {
-		for _, cb := range l.broker.subsJobs {
+		for _, cb := range b.subsJobs {
+			cb(new)
+		}
+	}
+	if old, new := oldCfg.City, newCfg.City; !configEqualCity(old, new) {
+		for _, cb := range b.subsCity {
 			cb(new)
 		}
 	}
 	if old, new := oldCfg.Home, newCfg.Home; !configEqualHome(old, new) {
-		for _, cb := range l.broker.subsHome {
+		for _, cb := range b.subsHome {
 			cb(new)
 		}
 	}
 	if old, new := oldCfg.CreatedAt, newCfg.CreatedAt; !configEqualCreatedAt(old, new) {
-		for _, cb := range l.broker.subsCreatedAt {
+		for _, cb := range b.subsCreatedAt {
 			cb(new)
 		}
 	}
 	if old, new := oldCfg.Limit, newCfg.Limit; !configEqualLimit(old, new) {
-		for _, cb := range l.broker.subsLimit {
+		for _, cb := range b.subsLimit {
+			cb(new)
+		}
+	}
+	if old, new := oldCfg.Avatar, newCfg.Avatar; !configEqualAvatar(old, new) {
+		for _, cb := range b.subsAvatar {
 			cb(new)
 		}
 	}
-	l.broker.config.Store(newCfg)
+	if old, new := oldCfg.Website, newCfg.Website; !configEqualWebsite(old, new) {
+		for _, cb := range b.subsWebsite {
+			cb(new)
+		}
+	}
+	b.config.Store(newCfg)
+	if !oldCfg.Equal(newCfg) {
+		for _, cb := range b.subscribers {
+			cb(newCfg)
+		}
+	}
+	return nil
 }
 func configEqualName(a, b string) bool {
 	return a == b
@@ -305,6 +566,9 @@ func configEqualJobs(a, b []Job) bool {
 	}
 	return true
 }
+func configEqualCity(a, b string) bool {
+	return a == b
+}
 func configEqualHome(a, b Home) bool {
 	return a.Equal(&b)
 }
@@ -314,15 +578,31 @@ func configEqualCreatedAt(a, b time.Time) bool {
 func configEqualLimit(a, b duration.Timestamp) bool {
 	return a == b
 }
+func configEqualAvatar(a, b []byte) bool {
+	return bytes.Equal(a, b)
+}
+func configEqualWebsite(a, b *url.URL) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.String() == b.String()
+}
 
 // mergePartial merges the given partial into the layer's accumulated partial.
 func (l *ConfigLayer) mergePartial(p *ConfigPartial) {
+	// Clears go first, so that values set by p take precedence as in ApplyPartial
+	for _, name := range p.Clear {
+		l.partial.ClearField(name)
+	}
 	if p.Name != nil {
 		l.partial.Name = p.Name
 	}
 	if p.Jobs != nil {
 		l.partial.Jobs = p.Jobs
 	}
+	if p.City != nil {
+		l.partial.City = p.City
+	}
 	if p.Home != nil {
 		l.partial.Home = p.Home
 	}
@@ -335,12 +615,34 @@ func (l *ConfigLayer) mergePartial(p *ConfigPartial) {
 	if p.Limit != nil {
 		l.partial.Limit = p.Limit
 	}
+	if p.Avatar != nil {
+		l.partial.Avatar = p.Avatar
+	}
+	if p.Website != nil {
+		l.partial.Website = p.Website
+	}
+}
+
+// Named sets the name the layer is reported under, by validation errors, and returns the layer. Layers are called "layer 1", "layer 2", ... in
+// order of creation until they are named.
+func (l *ConfigLayer) Named(name string) *ConfigLayer {
+	l.broker.mu.Lock()
+	defer l.broker.mu.Unlock()
+	l.name = name
+	return l
 }
 
-// recompute rebuilds the config from base and all layer partials.
-func (b *ConfigLayerBroker) recompute() *Config {
+// Name returns the name of the layer (see Named).
+func (l *ConfigLayer) Name() string {
+	l.broker.mu.Lock()
+	defer l.broker.mu.Unlock()
+	return l.name
+}
+
+// recompute rebuilds the config from base and the partials of layers.
+func (b *ConfigLayerBroker) recompute(layers []*ConfigLayer) *Config {
 	cfg := b.base.Copy()
-	for _, layer := range b.layers {
+	for _, layer := range layers {
 		if layer.partial != nil {
 			cfg.ApplyPartial(layer.partial)
 		}
@@ -348,6 +650,84 @@ func (b *ConfigLayerBroker) recompute() *Config {
 	return cfg
 }
 
+// ConfigFieldChange is a top-level field of Config whose value a change alters.
+type ConfigFieldChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+// PreviewLayer returns the config that setting p on the layer called name would
+// produce, and the fields that would change, without applying anything. If several
+// layers have the name the highest one is previewed, and if none has it, a new layer
+// created by Layer is. When the broker's validator rejects the previewed config, its
+// *ConfigValidationError is returned along with the preview, so that a confirm
+// step can show what would be rejected.
+func (b *ConfigLayerBroker) PreviewLayer(name string, p *ConfigPartial) (*Config, []ConfigFieldChange, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	preview := &ConfigLayer{broker: b, partial: &ConfigPartial{}, rank: configRankLayer, name: name}
+	layers := slices.Clone(b.layers)
+	i := len(layers) - 1
+	for i >= 0 && layers[i].name != name {
+		i--
+	}
+	if i >= 0 {
+		if layers[i].partial != nil {
+			*preview.partial = *layers[i].partial
+		}
+		layers[i] = preview
+	} else {
+		layers = configInsertLayer(layers, preview)
+	}
+	if p != nil {
+		preview.mergePartial(p)
+	}
+	oldCfg := b.config.Load()
+	newCfg := b.recompute(layers)
+	changes := configChanges(oldCfg, newCfg)
+	if b.validate != nil && !oldCfg.Equal(newCfg) {
+		if err := b.validate(*newCfg); err != nil {
+			return newCfg, changes, &ConfigValidationError{Layer: name, Err: err}
+		}
+	}
+	return newCfg, changes, nil
+}
+
+// configChanges returns the top-level fields whose values differ between
+// old and new.
+func configChanges(old, new *Config) []ConfigFieldChange {
+	var changes []ConfigFieldChange
+	if !configEqualName(old.Name, new.Name) {
+		changes = append(changes, ConfigFieldChange{Field: "Name", Old: old.Name, New: new.Name})
+	}
+	if !configEqualJobs(old.Jobs, new.Jobs) {
+		changes = append(changes, ConfigFieldChange{Field: "Jobs", Old: old.Jobs, New: new.Jobs})
+	}
+	if !configEqualCity(old.City, new.City) {
+		changes = append(changes, ConfigFieldChange{Field: "City", Old: old.City, New: new.City})
+	}
+	if !configEqualHome(old.Home, new.Home) {
+		changes = append(changes, ConfigFieldChange{Field: "Home", Old: old.Home, New: new.Home})
+	}
+	if !old.OtherHome.Equal(new.OtherHome) {
+		changes = append(changes, ConfigFieldChange{Field: "OtherHome", Old: old.OtherHome, New: new.OtherHome})
+	}
+	if !configEqualCreatedAt(old.CreatedAt, new.CreatedAt) {
+		changes = append(changes, ConfigFieldChange{Field: "CreatedAt", Old: old.CreatedAt, New: new.CreatedAt})
+	}
+	if !configEqualLimit(old.Limit, new.Limit) {
+		changes = append(changes, ConfigFieldChange{Field: "Limit", Old: old.Limit, New: new.Limit})
+	}
+	if !configEqualAvatar(old.Avatar, new.Avatar) {
+		changes = append(changes, ConfigFieldChange{Field: "Avatar", Old: old.Avatar, New: new.Avatar})
+	}
+	if !configEqualWebsite(old.Website, new.Website) {
+		changes = append(changes, ConfigFieldChange{Field: "Website", Old: old.Website, New: new.Website})
+	}
+	return changes
+}
+
 // ConfigLayerBrokerState represents the serializable state of the broker.
 type ConfigLayerBrokerState struct {
 	Base   *Config          `json:"base"`
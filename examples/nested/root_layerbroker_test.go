@@ -1,15 +1,38 @@
 // Code generated by sudo-gen layerbroker. DO NOT EDIT.
+// Generated by sudo-gen (devel): layerbroker -tests -json -external=partial -clear -migrate-deprecated -merge-patch -json-patch -explain-diff -with -bench -utc
 
 package nested
 
 import (
 	"encoding/json"
+	"errors"
 	"testing"
 	"time"
 )
 
-func configPtr[T any](v T) *T {
-	return &v
+func TestConfigLayerBrokerClearField(t *testing.T) {
+	broker := NewConfigLayerBroker(&Config{Name: "base"})
+	lower := broker.Layer()
+	lower.Set(&ConfigPartial{Name: sudogenPtr("lower")})
+	layer := broker.Layer()
+	p := &ConfigPartial{}
+	p.ClearField("Name")
+	layer.Set(p)
+	if got := broker.Get().Name; got != "" {
+		t.Errorf("expected Name to be cleared over lower layers, got %s", got)
+	}
+	layer.Set(&ConfigPartial{Name: sudogenPtr("set")})
+	if got := broker.Get().Name; got != "set" {
+		t.Errorf("expected a later Set to override the clear, got %s", got)
+	}
+	layer.Set(p)
+	if got := broker.Get().Name; got != "" {
+		t.Errorf("expected a later clear to override the value, got %s", got)
+	}
+	layer.Remove()
+	if got := broker.Get().Name; got != "lower" {
+		t.Errorf("expected Name=lower after removing the clearing layer, got %s", got)
+	}
 }
 
 func TestConfigLayerBrokerUnsubscribe(t *testing.T) {
@@ -21,12 +44,12 @@ func TestConfigLayerBrokerUnsubscribe(t *testing.T) {
 	if len(updates) != 1 {
 		t.Fatalf("expected 1 update, got %d", len(updates))
 	}
-	broker.Layer().Set(&ConfigPartial{Name: configPtr("changed")})
+	broker.Layer().Set(&ConfigPartial{Name: sudogenPtr("changed")})
 	if len(updates) != 2 {
 		t.Fatalf("expected 2 updates, got %d", len(updates))
 	}
 	unsub()
-	broker.Layer().Set(&ConfigPartial{Name: configPtr("ignored")})
+	broker.Layer().Set(&ConfigPartial{Name: sudogenPtr("ignored")})
 	if len(updates) != 2 {
 		t.Fatalf("expected 2 updates after unsubscribe, got %d", len(updates))
 	}
@@ -35,6 +58,135 @@ func TestConfigLayerBrokerUnsubscribe(t *testing.T) {
 	}
 }
 
+func TestConfigLayerBrokerRemoveLayer(t *testing.T) {
+	broker := NewConfigLayerBroker(&Config{Name: "base"})
+	layer := broker.Layer()
+	layer.Set(&ConfigPartial{Name: sudogenPtr("layer")})
+	var updates []string
+	unsub := broker.SubscribeName(func(v string) {
+		updates = append(updates, v)
+	})
+	defer unsub()
+	layer.Remove()
+	if len(updates) != 2 || updates[1] != "base" {
+		t.Fatalf("expected update back to base, got %v", updates)
+	}
+	layer.Set(&ConfigPartial{Name: sudogenPtr("ignored")})
+	if got := broker.Get().Name; got != "base" {
+		t.Errorf("removed layer should not apply, got Name=%s", got)
+	}
+}
+
+func TestConfigLayerBrokerSubscribe(t *testing.T) {
+	broker := NewConfigLayerBroker(nil)
+	var updates []*Config
+	unsub := broker.Subscribe(func(cfg *Config) {
+		updates = append(updates, cfg)
+	})
+	defer unsub()
+	if len(updates) != 1 {
+		t.Fatalf("expected initial callback, got %d", len(updates))
+	}
+	layer := broker.Layer()
+	layer.Set(&ConfigPartial{Name: sudogenPtr("changed")})
+	if len(updates) != 2 || updates[1].Name != "changed" {
+		t.Fatalf("expected update with Name=changed, got %d updates", len(updates))
+	}
+	layer.Set(&ConfigPartial{Name: sudogenPtr("changed")})
+	if len(updates) != 2 {
+		t.Fatalf("expected no update when nothing changed, got %d updates", len(updates))
+	}
+}
+
+func TestConfigLayerBrokerReplaceLayer(t *testing.T) {
+	broker := NewConfigLayerBroker(&Config{Name: "base"})
+	layer := broker.Layer()
+	layer.Set(&ConfigPartial{Name: sudogenPtr("first")})
+	layer.Replace(&ConfigPartial{})
+	if got := broker.Get().Name; got != "base" {
+		t.Errorf("expected Replace to drop earlier values, got Name=%s", got)
+	}
+	layer.Replace(&ConfigPartial{Name: sudogenPtr("second")})
+	if got := broker.Get().Name; got != "second" {
+		t.Errorf("expected Name=second, got %s", got)
+	}
+	layer.Replace(nil)
+	if got := broker.Get().Name; got != "base" {
+		t.Errorf("expected nil Replace to clear the layer, got Name=%s", got)
+	}
+}
+
+func TestConfigLayerBrokerValidator(t *testing.T) {
+	// The initial config is not validated, so the base can hold a rejected value
+	broker := NewConfigLayerBroker(&Config{Name: "invalid"}, WithConfigValidator(func(cfg Config) error {
+		if cfg.Name == "invalid" {
+			return errors.New("Name is invalid")
+		}
+		return nil
+	}))
+	layer := broker.Layer().Named("override")
+	if err := layer.Set(&ConfigPartial{Name: sudogenPtr("valid")}); err != nil {
+		t.Fatal(err)
+	}
+	var updates int
+	unsub := broker.Subscribe(func(*Config) {
+		updates++
+	})
+	defer unsub()
+	err := layer.Set(&ConfigPartial{Name: sudogenPtr("invalid")})
+	var verr *ConfigValidationError
+	if !errors.Is(err, ErrConfigValidationFailed) || !errors.As(err, &verr) || verr.Layer != "override" {
+		t.Fatalf("expected a validation error for layer override, got %v", err)
+	}
+	if got := broker.Get().Name; got != "valid" {
+		t.Errorf("expected the rejected change to keep Name=valid, got %s", got)
+	}
+	if got := layer.partial.Name; got == nil || *got != "valid" {
+		t.Errorf("expected the rejected change to leave the layer as it was, got %v", got)
+	}
+	if err := layer.Replace(&ConfigPartial{Name: sudogenPtr("invalid")}); !errors.Is(err, ErrConfigValidationFailed) {
+		t.Errorf("expected Replace to be rejected, got %v", err)
+	}
+	if err := layer.Remove(); !errors.Is(err, ErrConfigValidationFailed) {
+		t.Errorf("expected Remove to be rejected, got %v", err)
+	}
+	if updates != 1 {
+		t.Errorf("expected no notifications for rejected changes, got %d", updates-1)
+	}
+	if err := layer.Set(&ConfigPartial{Name: sudogenPtr("still attached")}); err != nil {
+		t.Fatal(err)
+	}
+	if got := broker.Get().Name; got != "still attached" {
+		t.Errorf("expected the layer to stay attached, got Name=%s", got)
+	}
+}
+
+func TestConfigLayerBrokerPreviewLayer(t *testing.T) {
+	broker := NewConfigLayerBroker(&Config{Name: "base"})
+	layer := broker.Layer().Named("file")
+	layer.Set(&ConfigPartial{Name: sudogenPtr("file")})
+	cfg, changes, err := broker.PreviewLayer("file", &ConfigPartial{Name: sudogenPtr("preview")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Name != "preview" {
+		t.Errorf("expected previewed Name=preview, got %s", cfg.Name)
+	}
+	if len(changes) != 1 || changes[0].Field != "Name" || changes[0].Old != "file" || changes[0].New != "preview" {
+		t.Errorf("expected Name to change from file to preview, got %+v", changes)
+	}
+	if got := broker.Get().Name; got != "file" {
+		t.Errorf("expected the preview not to apply, got Name=%s", got)
+	}
+	if got := layer.partial.Name; got == nil || *got != "file" {
+		t.Errorf("expected the preview to leave the layer as it was, got %v", got)
+	}
+	// A name no layer has previews a new layer
+	if _, changes, err := broker.PreviewLayer("env", &ConfigPartial{Name: sudogenPtr("file")}); err != nil || len(changes) != 0 {
+		t.Errorf("expected no changes from a new layer repeating the current value, got %+v, %v", changes, err)
+	}
+}
+
 func TestConfigLayerBrokerSubscribeToEmptyField(t *testing.T) {
 	broker := NewConfigLayerBroker(nil)
 	var callCount int
@@ -49,7 +201,7 @@ func TestConfigLayerBrokerSubscribeToEmptyField(t *testing.T) {
 	}
 
 	// Should be called when field is set
-	broker.Layer().Set(&ConfigPartial{Name: configPtr("test")})
+	broker.Layer().Set(&ConfigPartial{Name: sudogenPtr("test")})
 	if callCount != 1 {
 		t.Errorf("expected 1 call after setting field, got %d", callCount)
 	}
@@ -94,7 +246,7 @@ func TestConfigLayerBrokerBaseConfigPreserved(t *testing.T) {
 
 	broker := NewConfigLayerBroker(&Config{Name: "base"})
 	layer := broker.Layer()
-	layer.Set(&ConfigPartial{Name: configPtr("layer")})
+	layer.Set(&ConfigPartial{Name: sudogenPtr("layer")})
 
 	cfg := broker.Get()
 	if cfg.Name != "layer" {
@@ -128,6 +280,24 @@ func TestConfigLayerBrokerSubscribeJobsSlice(t *testing.T) {
 	}
 }
 
+func TestConfigLayerBrokerSubscribeAvatarSlice(t *testing.T) {
+	broker := NewConfigLayerBroker(&Config{Avatar: []byte{}})
+	var callCount int
+	unsub := broker.SubscribeAvatar(func(v []byte) {
+		callCount++
+	})
+	defer unsub()
+	// Empty slice is non-nil, so should get initial callback
+	if callCount != 1 {
+		t.Fatalf("expected 1 initial callback, got %d", callCount)
+	}
+	// Set a new slice
+	broker.Layer().Set(&ConfigPartial{Avatar: make([]byte, 3)})
+	if callCount != 2 {
+		t.Fatalf("expected 2 callbacks after update, got %d", callCount)
+	}
+}
+
 func TestConfigLayerBrokerSubscribeOtherHomeStruct(t *testing.T) {
 	broker := NewConfigLayerBroker(&Config{OtherHome: &Home{}})
 	var callCount int
@@ -160,8 +330,7 @@ func TestConfigLayerBrokerSubscribeCreatedAtTime(t *testing.T) {
 
 func TestConfigLayerBrokerMarshalJSON(t *testing.T) {
 	broker := NewConfigLayerBroker(nil)
-	layer := broker.Layer()
-	layer.Set(&ConfigPartial{Name: configPtr("test")})
+	broker.Layer().Set(&ConfigPartial{Name: sudogenPtr("test")})
 	data, err := json.Marshal(broker)
 	if err != nil {
 		t.Fatalf("MarshalJSON failed: %v", err)
@@ -201,7 +370,8 @@ func TestConfigLayerBrokerSetAllFieldTypes(t *testing.T) {
 	layer := broker.Layer()
 	// Test setting all field types to exercise mergePartial
 	partial := &ConfigPartial{}
-	partial.Name = configPtr("test")
+	partial.Name = sudogenPtr("test")
+	partial.City = sudogenPtr("test")
 
 	layer.Set(partial)
 	cfg := broker.Get()
@@ -215,6 +385,7 @@ func TestConfigLayerBrokerSetSliceAndMapFields(t *testing.T) {
 	layer := broker.Layer()
 	partial := &ConfigPartial{}
 	partial.Jobs = make([]Job, 1)
+	partial.Avatar = make([]byte, 1)
 
 	layer.Set(partial)
 	cfg := broker.Get()
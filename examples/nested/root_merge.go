@@ -1,8 +1,16 @@
 // Code generated by sudo-gen merge. DO NOT EDIT.
+// Generated by sudo-gen (devel): layerbroker -tests -json -external=partial -clear -migrate-deprecated -merge-patch -json-patch -explain-diff -with -bench -utc
 
 package nested
 
 import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
 	"github.com/bobcob7/sudo-gen/examples/nested/duration"
 )
 
@@ -10,6 +18,34 @@ func (c *Config) ApplyPartial(p *ConfigPartial) {
 	if c == nil || p == nil {
 		return
 	}
+	p = p.MigrateDeprecated()
+	for _, name := range p.Clear {
+		switch name {
+		case "Name":
+			var zero string
+			c.Name = zero
+		case "Jobs":
+			c.Jobs = nil
+		case "City":
+			var zero string
+			c.City = zero
+		case "Home":
+			var zero Home
+			c.Home = zero
+		case "OtherHome":
+			c.OtherHome = nil
+		case "CreatedAt":
+			var zero time.Time
+			c.CreatedAt = zero
+		case "Limit":
+			var zero duration.Timestamp
+			c.Limit = zero
+		case "Avatar":
+			c.Avatar = nil
+		case "Website":
+			c.Website = nil
+		}
+	}
 	if p.Name != nil {
 		c.Name = *p.Name
 	}
@@ -17,6 +53,9 @@ func (c *Config) ApplyPartial(p *ConfigPartial) {
 		c.Jobs = make([]Job, len(p.Jobs))
 		copy(c.Jobs, p.Jobs)
 	}
+	if p.City != nil {
+		c.City = *p.City
+	}
 	if p.Home != nil {
 		c.Home.ApplyPartial(p.Home)
 	}
@@ -27,17 +66,187 @@ func (c *Config) ApplyPartial(p *ConfigPartial) {
 		c.OtherHome.ApplyPartial(p.OtherHome)
 	}
 	if p.CreatedAt != nil {
-		c.CreatedAt = *p.CreatedAt
+		c.CreatedAt = p.CreatedAt.UTC()
 	}
 	if p.Limit != nil {
 		applyDurationTimestampPartial(&c.Limit, p.Limit)
 	}
+	if p.Avatar != nil {
+		c.Avatar = bytes.Clone(p.Avatar)
+	}
+	if p.Website != nil {
+		v := *p.Website
+		c.Website = &v
+	}
+}
+
+// ApplySparse applies each entry to c in order without materializing nested partials.
+// Entries applied before a failing entry remain applied.
+func (c *Config) ApplySparse(entries ...ConfigSparseEntry) error {
+	if c == nil {
+		return nil
+	}
+	for _, e := range entries {
+		if err := c.applySparse(e.Path, e.Value); err != nil {
+			return fmt.Errorf("sparse path %q: %w", e.Path, err)
+		}
+	}
+	return nil
+}
+
+func (c *Config) applySparse(path string, value any) error {
+	name, rest, _ := strings.Cut(path, ".")
+	switch name {
+	case "Name":
+		if rest != "" {
+			return fmt.Errorf("Name has no fields")
+		}
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("Name: expected string, got %T", value)
+		}
+		c.Name = v
+	case "Jobs":
+		if rest != "" {
+			return fmt.Errorf("Jobs has no fields")
+		}
+		v, ok := value.([]Job)
+		if !ok {
+			return fmt.Errorf("Jobs: expected []Job, got %T", value)
+		}
+		c.Jobs = make([]Job, len(v))
+		copy(c.Jobs, v)
+	case "City":
+		if rest != "" {
+			return fmt.Errorf("City has no fields")
+		}
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("City: expected string, got %T", value)
+		}
+		c.City = v
+	case "Home":
+		if rest == "" {
+			return fmt.Errorf("Home is a struct, not a field")
+		}
+		return c.Home.applySparse(rest, value)
+	case "OtherHome":
+		if rest == "" {
+			return fmt.Errorf("OtherHome is a struct, not a field")
+		}
+		if c.OtherHome == nil {
+			c.OtherHome = &Home{}
+		}
+		return c.OtherHome.applySparse(rest, value)
+	case "CreatedAt":
+		if rest != "" {
+			return fmt.Errorf("CreatedAt has no fields")
+		}
+		v, ok := value.(time.Time)
+		if !ok {
+			return fmt.Errorf("CreatedAt: expected time.Time, got %T", value)
+		}
+		v = v.UTC()
+		c.CreatedAt = v
+	case "Limit":
+		if rest == "" {
+			return fmt.Errorf("Limit is a struct, not a field")
+		}
+		return applySparseDurationTimestampPartial(&c.Limit, rest, value)
+	case "Avatar":
+		if rest != "" {
+			return fmt.Errorf("Avatar has no fields")
+		}
+		v, ok := value.([]byte)
+		if !ok {
+			return fmt.Errorf("Avatar: expected []byte, got %T", value)
+		}
+		c.Avatar = bytes.Clone(v)
+	case "Website":
+		if rest != "" {
+			return fmt.Errorf("Website has no fields")
+		}
+		v, ok := value.(url.URL)
+		if !ok {
+			return fmt.Errorf("Website: expected url.URL, got %T", value)
+		}
+		c.Website = &v
+	default:
+		return fmt.Errorf("unknown field %q", name)
+	}
+	return nil
+}
+
+// ToPartial returns a partial setting every field of c, so that a snapshot of c
+// can be applied as a layer. Nil pointers, slices and maps are left unset, and the
+// others are copied shallowly.
+func (c Config) ToPartial() ConfigPartial {
+	return c.toPartial(false)
+}
+
+// NonZeroPartial returns a partial setting the fields of c that don't hold their zero
+// value, so that applying it leaves the fields c doesn't set untouched. Nested structs
+// that are zero as a whole are left unset too.
+func (c Config) NonZeroPartial() ConfigPartial {
+	return c.toPartial(true)
+}
+
+func (c Config) toPartial(skipZero bool) ConfigPartial {
+	var p ConfigPartial
+	if !skipZero || c.Name != "" {
+		v := c.Name
+		p.Name = &v
+	}
+	if c.Jobs != nil {
+		p.Jobs = slices.Clone(c.Jobs)
+	}
+	if !skipZero || c.City != "" {
+		v := c.City
+		p.City = &v
+	}
+	if n := c.Home.toPartial(skipZero); !skipZero || !n.IsEmpty() {
+		p.Home = &n
+	}
+	if c.OtherHome != nil {
+		n := c.OtherHome.toPartial(skipZero)
+		p.OtherHome = &n
+	}
+	if !skipZero || !c.CreatedAt.IsZero() {
+		v := c.CreatedAt
+		p.CreatedAt = &v
+	}
+	if n := toDurationTimestampPartial(&c.Limit, skipZero); !skipZero || !n.IsEmpty() {
+		p.Limit = &n
+	}
+	p.Avatar = bytes.Clone(c.Avatar)
+	if c.Website != nil {
+		v := *c.Website
+		p.Website = &v
+	}
+	return p
 }
 
 func (c *Job) ApplyPartial(p *JobPartial) {
 	if c == nil || p == nil {
 		return
 	}
+	for _, name := range p.Clear {
+		switch name {
+		case "Title":
+			var zero string
+			c.Title = zero
+		case "Company":
+			var zero string
+			c.Company = zero
+		case "Location":
+			var zero string
+			c.Location = zero
+		case "Tenure":
+			c.Tenure = nil
+		case "Coords":
+			c.Coords = nil
+		}
+	}
 	if p.Title != nil {
 		c.Title = *p.Title
 	}
@@ -61,6 +270,97 @@ func (c *Job) ApplyPartial(p *JobPartial) {
 	}
 }
 
+func (c *Job) applySparse(path string, value any) error {
+	name, rest, _ := strings.Cut(path, ".")
+	switch name {
+	case "Title":
+		if rest != "" {
+			return fmt.Errorf("Title has no fields")
+		}
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("Title: expected string, got %T", value)
+		}
+		c.Title = v
+	case "Company":
+		if rest != "" {
+			return fmt.Errorf("Company has no fields")
+		}
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("Company: expected string, got %T", value)
+		}
+		c.Company = v
+	case "Location":
+		if rest != "" {
+			return fmt.Errorf("Location has no fields")
+		}
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("Location: expected string, got %T", value)
+		}
+		c.Location = v
+	case "Tenure":
+		if rest == "" {
+			return fmt.Errorf("Tenure is a struct, not a field")
+		}
+		if c.Tenure == nil {
+			c.Tenure = &duration.Timestamp{}
+		}
+		return applySparseDurationTimestampPartial(c.Tenure, rest, value)
+	case "Coords":
+		if rest == "" {
+			return fmt.Errorf("Coords is a struct, not a field")
+		}
+		if c.Coords == nil {
+			c.Coords = &Coordinates{}
+		}
+		return c.Coords.applySparse(rest, value)
+	default:
+		return fmt.Errorf("unknown field %q", name)
+	}
+	return nil
+}
+
+// ToPartial returns a partial setting every field of c, so that a snapshot of c
+// can be applied as a layer. Nil pointers, slices and maps are left unset, and the
+// others are copied shallowly.
+func (c Job) ToPartial() JobPartial {
+	return c.toPartial(false)
+}
+
+// NonZeroPartial returns a partial setting the fields of c that don't hold their zero
+// value, so that applying it leaves the fields c doesn't set untouched. Nested structs
+// that are zero as a whole are left unset too.
+func (c Job) NonZeroPartial() JobPartial {
+	return c.toPartial(true)
+}
+
+func (c Job) toPartial(skipZero bool) JobPartial {
+	var p JobPartial
+	if !skipZero || c.Title != "" {
+		v := c.Title
+		p.Title = &v
+	}
+	if !skipZero || c.Company != "" {
+		v := c.Company
+		p.Company = &v
+	}
+	if !skipZero || c.Location != "" {
+		v := c.Location
+		p.Location = &v
+	}
+	if c.Tenure != nil {
+		n := toDurationTimestampPartial(c.Tenure, skipZero)
+		p.Tenure = &n
+	}
+	if c.Coords != nil {
+		n := c.Coords.toPartial(skipZero)
+		p.Coords = &n
+	}
+	return p
+}
+
 // applyDurationTimestampPartial applies a partial update to a duration.Timestamp.
 func applyDurationTimestampPartial(c *duration.Timestamp, p *DurationTimestampPartial) {
 	if c == nil || p == nil {
@@ -77,10 +377,76 @@ func applyDurationTimestampPartial(c *duration.Timestamp, p *DurationTimestampPa
 	}
 }
 
+// applySparseDurationTimestampPartial applies a single sparse update to a duration.Timestamp.
+func applySparseDurationTimestampPartial(c *duration.Timestamp, path string, value any) error {
+	name, rest, _ := strings.Cut(path, ".")
+	switch name {
+	case "Minutes":
+		if rest != "" {
+			return fmt.Errorf("Minutes has no fields")
+		}
+		v, ok := value.(int)
+		if !ok {
+			return fmt.Errorf("Minutes: expected int, got %T", value)
+		}
+		c.Minutes = v
+	case "Hours":
+		if rest != "" {
+			return fmt.Errorf("Hours has no fields")
+		}
+		v, ok := value.(int)
+		if !ok {
+			return fmt.Errorf("Hours: expected int, got %T", value)
+		}
+		c.Hours = v
+	case "Days":
+		if rest != "" {
+			return fmt.Errorf("Days has no fields")
+		}
+		v, ok := value.(int)
+		if !ok {
+			return fmt.Errorf("Days: expected int, got %T", value)
+		}
+		c.Days = v
+	default:
+		return fmt.Errorf("unknown field %q", name)
+	}
+	return nil
+}
+
+// toDurationTimestampPartial returns a partial setting the fields of c, or only
+// those not holding their zero value if skipZero is set.
+func toDurationTimestampPartial(c *duration.Timestamp, skipZero bool) DurationTimestampPartial {
+	var p DurationTimestampPartial
+	if !skipZero || c.Minutes != 0 {
+		v := c.Minutes
+		p.Minutes = &v
+	}
+	if !skipZero || c.Hours != 0 {
+		v := c.Hours
+		p.Hours = &v
+	}
+	if !skipZero || c.Days != 0 {
+		v := c.Days
+		p.Days = &v
+	}
+	return p
+}
+
 func (c *Coordinates) ApplyPartial(p *CoordinatesPartial) {
 	if c == nil || p == nil {
 		return
 	}
+	for _, name := range p.Clear {
+		switch name {
+		case "Latitude":
+			var zero float64
+			c.Latitude = zero
+		case "Longitude":
+			var zero float64
+			c.Longitude = zero
+		}
+	}
 	if p.Latitude != nil {
 		c.Latitude = *p.Latitude
 	}
@@ -89,10 +455,85 @@ func (c *Coordinates) ApplyPartial(p *CoordinatesPartial) {
 	}
 }
 
+func (c *Coordinates) applySparse(path string, value any) error {
+	name, rest, _ := strings.Cut(path, ".")
+	switch name {
+	case "Latitude":
+		if rest != "" {
+			return fmt.Errorf("Latitude has no fields")
+		}
+		v, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("Latitude: expected float64, got %T", value)
+		}
+		c.Latitude = v
+	case "Longitude":
+		if rest != "" {
+			return fmt.Errorf("Longitude has no fields")
+		}
+		v, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("Longitude: expected float64, got %T", value)
+		}
+		c.Longitude = v
+	default:
+		return fmt.Errorf("unknown field %q", name)
+	}
+	return nil
+}
+
+// ToPartial returns a partial setting every field of c, so that a snapshot of c
+// can be applied as a layer. Nil pointers, slices and maps are left unset, and the
+// others are copied shallowly.
+func (c Coordinates) ToPartial() CoordinatesPartial {
+	return c.toPartial(false)
+}
+
+// NonZeroPartial returns a partial setting the fields of c that don't hold their zero
+// value, so that applying it leaves the fields c doesn't set untouched. Nested structs
+// that are zero as a whole are left unset too.
+func (c Coordinates) NonZeroPartial() CoordinatesPartial {
+	return c.toPartial(true)
+}
+
+func (c Coordinates) toPartial(skipZero bool) CoordinatesPartial {
+	var p CoordinatesPartial
+	if !skipZero || c.Latitude != 0 {
+		v := c.Latitude
+		p.Latitude = &v
+	}
+	if !skipZero || c.Longitude != 0 {
+		v := c.Longitude
+		p.Longitude = &v
+	}
+	return p
+}
+
 func (c *Home) ApplyPartial(p *HomePartial) {
 	if c == nil || p == nil {
 		return
 	}
+	for _, name := range p.Clear {
+		switch name {
+		case "Address":
+			var zero string
+			c.Address = zero
+		case "City":
+			var zero string
+			c.City = zero
+		case "ZipCode":
+			var zero string
+			c.ZipCode = zero
+		case "Age":
+			var zero time.Duration
+			c.Age = zero
+		case "Coords":
+			var zero Coordinates
+			c.Coords = zero
+		case "Destination":
+			c.Destination = nil
+		}
+	}
 	if p.Address != nil {
 		c.Address = *p.Address
 	}
@@ -109,9 +550,148 @@ func (c *Home) ApplyPartial(p *HomePartial) {
 		c.Coords.ApplyPartial(p.Coords)
 	}
 	if p.Destination != nil {
+		// Replaced as a whole rather than merged field by field
+		c.Destination = &Coordinates{}
+		c.Destination.ApplyPartial(p.Destination)
+	}
+}
+
+func (c *Home) applySparse(path string, value any) error {
+	name, rest, _ := strings.Cut(path, ".")
+	switch name {
+	case "Address":
+		if rest != "" {
+			return fmt.Errorf("Address has no fields")
+		}
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("Address: expected string, got %T", value)
+		}
+		c.Address = v
+	case "City":
+		if rest != "" {
+			return fmt.Errorf("City has no fields")
+		}
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("City: expected string, got %T", value)
+		}
+		c.City = v
+	case "ZipCode":
+		if rest != "" {
+			return fmt.Errorf("ZipCode has no fields")
+		}
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("ZipCode: expected string, got %T", value)
+		}
+		c.ZipCode = v
+	case "Age":
+		if rest != "" {
+			return fmt.Errorf("Age has no fields")
+		}
+		v, ok := value.(time.Duration)
+		if !ok {
+			return fmt.Errorf("Age: expected time.Duration, got %T", value)
+		}
+		c.Age = v
+	case "Coords":
+		if rest == "" {
+			return fmt.Errorf("Coords is a struct, not a field")
+		}
+		return c.Coords.applySparse(rest, value)
+	case "Destination":
+		if rest == "" {
+			return fmt.Errorf("Destination is a struct, not a field")
+		}
 		if c.Destination == nil {
 			c.Destination = &Coordinates{}
 		}
-		c.Destination.ApplyPartial(p.Destination)
+		return c.Destination.applySparse(rest, value)
+	default:
+		return fmt.Errorf("unknown field %q", name)
+	}
+	return nil
+}
+
+// ToPartial returns a partial setting every field of c, so that a snapshot of c
+// can be applied as a layer. Nil pointers, slices and maps are left unset, and the
+// others are copied shallowly.
+func (c Home) ToPartial() HomePartial {
+	return c.toPartial(false)
+}
+
+// NonZeroPartial returns a partial setting the fields of c that don't hold their zero
+// value, so that applying it leaves the fields c doesn't set untouched. Nested structs
+// that are zero as a whole are left unset too.
+func (c Home) NonZeroPartial() HomePartial {
+	return c.toPartial(true)
+}
+
+func (c Home) toPartial(skipZero bool) HomePartial {
+	var p HomePartial
+	if !skipZero || c.Address != "" {
+		v := c.Address
+		p.Address = &v
+	}
+	if !skipZero || c.City != "" {
+		v := c.City
+		p.City = &v
+	}
+	if !skipZero || c.ZipCode != "" {
+		v := c.ZipCode
+		p.ZipCode = &v
+	}
+	if !skipZero || c.Age != 0 {
+		v := c.Age
+		p.Age = &v
+	}
+	if n := c.Coords.toPartial(skipZero); !skipZero || !n.IsEmpty() {
+		p.Coords = &n
+	}
+	if c.Destination != nil {
+		n := c.Destination.toPartial(skipZero)
+		p.Destination = &n
+	}
+	return p
+}
+
+// ConfigDeprecation is a field tagged sudo:"deprecated" that a partial sets.
+type ConfigDeprecation struct {
+	Path    string // Dotted path of Go field names, e.g. "Database.URL"
+	Message string // Message of the deprecated tag option
+}
+
+// Deprecations returns the deprecated fields p sets, at any depth outside slices
+// and maps, so loaders can warn about them.
+func (p *ConfigPartial) Deprecations() []ConfigDeprecation {
+	if p == nil {
+		return nil
+	}
+	var deprecations []ConfigDeprecation
+	if p.City != nil {
+		deprecations = append(deprecations, ConfigDeprecation{Path: "City", Message: "use Home.City"})
+	}
+	return deprecations
+}
+
+// MigrateDeprecated returns a copy of p that also sets each replacement named by a
+// deprecated field it sets, unless p sets the replacement too. Nested partials are
+// copied before they change, so p is left unchanged.
+// ApplyPartial migrates each partial before applying it.
+func (p *ConfigPartial) MigrateDeprecated() *ConfigPartial {
+	if p == nil {
+		return nil
+	}
+	q := *p
+	if q.City != nil && (q.Home == nil || q.Home.City == nil) {
+		if q.Home == nil {
+			q.Home = &HomePartial{}
+		} else {
+			v := *q.Home
+			q.Home = &v
+		}
+		q.Home.City = q.City
 	}
+	return &q
 }
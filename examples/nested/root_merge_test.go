@@ -1,15 +1,15 @@
 // Code generated by sudo-gen merge. DO NOT EDIT.
+// Generated by sudo-gen (devel): layerbroker -tests -json -external=partial -clear -migrate-deprecated -merge-patch -json-patch -explain-diff -with -bench -utc
 
 package nested
 
 import (
+	"encoding/json"
+	"slices"
 	"testing"
+	"time"
 )
 
-func mergePtr[T any](v T) *T {
-	return &v
-}
-
 func TestConfigApplyPartialNil(t *testing.T) {
 	var c *Config
 	c.ApplyPartial(nil) // should not panic
@@ -24,9 +24,18 @@ func TestConfigApplyPartialEmpty(t *testing.T) {
 	c.ApplyPartial(p) // should not panic or change anything
 }
 
+func TestConfigApplyPartial_CreatedAtUTC(t *testing.T) {
+	local := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("UTC+2", 2*60*60))
+	c := &Config{}
+	c.ApplyPartial(&ConfigPartial{CreatedAt: &local})
+	if c.CreatedAt.Location() != time.UTC || !c.CreatedAt.Equal(local) {
+		t.Errorf("expected CreatedAt in UTC, got %v", c.CreatedAt)
+	}
+}
+
 func TestConfigApplyPartial_Name(t *testing.T) {
 	c := &Config{}
-	p := &ConfigPartial{Name: mergePtr("test")}
+	p := &ConfigPartial{Name: sudogenPtr("test")}
 	c.ApplyPartial(p)
 	if c.Name != "test" {
 		t.Errorf("expected Name=test, got %s", c.Name)
@@ -35,13 +44,31 @@ func TestConfigApplyPartial_Name(t *testing.T) {
 
 func TestConfigApplyPartial_NameOverwrite(t *testing.T) {
 	c := &Config{Name: "original"}
-	p := &ConfigPartial{Name: mergePtr("updated")}
+	p := &ConfigPartial{Name: sudogenPtr("updated")}
 	c.ApplyPartial(p)
 	if c.Name != "updated" {
 		t.Errorf("expected Name=updated, got %s", c.Name)
 	}
 }
 
+func TestConfigApplyPartial_City(t *testing.T) {
+	c := &Config{}
+	p := &ConfigPartial{City: sudogenPtr("test")}
+	c.ApplyPartial(p)
+	if c.City != "test" {
+		t.Errorf("expected City=test, got %s", c.City)
+	}
+}
+
+func TestConfigApplyPartial_CityOverwrite(t *testing.T) {
+	c := &Config{City: "original"}
+	p := &ConfigPartial{City: sudogenPtr("updated")}
+	c.ApplyPartial(p)
+	if c.City != "updated" {
+		t.Errorf("expected City=updated, got %s", c.City)
+	}
+}
+
 func TestConfigApplyPartial_JobsSlice(t *testing.T) {
 	c := &Config{}
 	newSlice := []Job{}
@@ -62,6 +89,40 @@ func TestConfigApplyPartial_JobsSliceReplace(t *testing.T) {
 	}
 }
 
+func TestConfigApplyPartial_AvatarSlice(t *testing.T) {
+	c := &Config{}
+	newSlice := []byte{}
+	p := &ConfigPartial{Avatar: newSlice}
+	c.ApplyPartial(p)
+	if c.Avatar == nil {
+		t.Error("expected slice to be set")
+	}
+}
+
+func TestConfigApplyPartial_AvatarSliceReplace(t *testing.T) {
+	c := &Config{Avatar: make([]byte, 2)}
+	newSlice := make([]byte, 3)
+	p := &ConfigPartial{Avatar: newSlice}
+	c.ApplyPartial(p)
+	if len(c.Avatar) != 3 {
+		t.Errorf("expected slice length 3, got %d", len(c.Avatar))
+	}
+}
+
+func TestConfigApplyPartial_AvatarBytes(t *testing.T) {
+	c := &Config{Avatar: []byte("abc")}
+	p := &ConfigPartial{Avatar: []byte("xy")}
+	c.ApplyPartial(p)
+	p.Avatar[0] = 'z'
+	if string(c.Avatar) != "xy" {
+		t.Errorf("expected the partial's bytes to replace the field, got %q", c.Avatar)
+	}
+	c.ApplyPartial(&ConfigPartial{Avatar: []byte{}})
+	if c.Avatar == nil || len(c.Avatar) != 0 {
+		t.Errorf("expected empty bytes to replace the field, got %#v", c.Avatar)
+	}
+}
+
 func TestConfigApplyPartial_OtherHomeNestedStruct(t *testing.T) {
 	c := &Config{}
 	p := &ConfigPartial{OtherHome: &HomePartial{}}
@@ -72,12 +133,16 @@ func TestConfigApplyPartial_OtherHomeNestedStruct(t *testing.T) {
 }
 
 func TestConfigApplyPartial_OtherHomeNestedStructExisting(t *testing.T) {
-	c := &Config{OtherHome: &Home{}}
+	existing := &Home{}
+	c := &Config{OtherHome: existing}
 	p := &ConfigPartial{OtherHome: &HomePartial{}}
 	c.ApplyPartial(p)
 	if c.OtherHome == nil {
 		t.Error("expected nested struct to remain set")
 	}
+	if c.OtherHome != existing {
+		t.Error("expected nested struct to be merged in place")
+	}
 }
 
 func TestJobApplyPartialNil(t *testing.T) {
@@ -96,7 +161,7 @@ func TestJobApplyPartialEmpty(t *testing.T) {
 
 func TestJobApplyPartial_Title(t *testing.T) {
 	c := &Job{}
-	p := &JobPartial{Title: mergePtr("test")}
+	p := &JobPartial{Title: sudogenPtr("test")}
 	c.ApplyPartial(p)
 	if c.Title != "test" {
 		t.Errorf("expected Title=test, got %s", c.Title)
@@ -105,7 +170,7 @@ func TestJobApplyPartial_Title(t *testing.T) {
 
 func TestJobApplyPartial_TitleOverwrite(t *testing.T) {
 	c := &Job{Title: "original"}
-	p := &JobPartial{Title: mergePtr("updated")}
+	p := &JobPartial{Title: sudogenPtr("updated")}
 	c.ApplyPartial(p)
 	if c.Title != "updated" {
 		t.Errorf("expected Title=updated, got %s", c.Title)
@@ -114,7 +179,7 @@ func TestJobApplyPartial_TitleOverwrite(t *testing.T) {
 
 func TestJobApplyPartial_Company(t *testing.T) {
 	c := &Job{}
-	p := &JobPartial{Company: mergePtr("test")}
+	p := &JobPartial{Company: sudogenPtr("test")}
 	c.ApplyPartial(p)
 	if c.Company != "test" {
 		t.Errorf("expected Company=test, got %s", c.Company)
@@ -123,7 +188,7 @@ func TestJobApplyPartial_Company(t *testing.T) {
 
 func TestJobApplyPartial_CompanyOverwrite(t *testing.T) {
 	c := &Job{Company: "original"}
-	p := &JobPartial{Company: mergePtr("updated")}
+	p := &JobPartial{Company: sudogenPtr("updated")}
 	c.ApplyPartial(p)
 	if c.Company != "updated" {
 		t.Errorf("expected Company=updated, got %s", c.Company)
@@ -132,7 +197,7 @@ func TestJobApplyPartial_CompanyOverwrite(t *testing.T) {
 
 func TestJobApplyPartial_Location(t *testing.T) {
 	c := &Job{}
-	p := &JobPartial{Location: mergePtr("test")}
+	p := &JobPartial{Location: sudogenPtr("test")}
 	c.ApplyPartial(p)
 	if c.Location != "test" {
 		t.Errorf("expected Location=test, got %s", c.Location)
@@ -141,7 +206,7 @@ func TestJobApplyPartial_Location(t *testing.T) {
 
 func TestJobApplyPartial_LocationOverwrite(t *testing.T) {
 	c := &Job{Location: "original"}
-	p := &JobPartial{Location: mergePtr("updated")}
+	p := &JobPartial{Location: sudogenPtr("updated")}
 	c.ApplyPartial(p)
 	if c.Location != "updated" {
 		t.Errorf("expected Location=updated, got %s", c.Location)
@@ -158,12 +223,16 @@ func TestJobApplyPartial_CoordsNestedStruct(t *testing.T) {
 }
 
 func TestJobApplyPartial_CoordsNestedStructExisting(t *testing.T) {
-	c := &Job{Coords: &Coordinates{}}
+	existing := &Coordinates{}
+	c := &Job{Coords: existing}
 	p := &JobPartial{Coords: &CoordinatesPartial{}}
 	c.ApplyPartial(p)
 	if c.Coords == nil {
 		t.Error("expected nested struct to remain set")
 	}
+	if c.Coords != existing {
+		t.Error("expected nested struct to be merged in place")
+	}
 }
 
 func TestCoordinatesApplyPartialNil(t *testing.T) {
@@ -182,7 +251,7 @@ func TestCoordinatesApplyPartialEmpty(t *testing.T) {
 
 func TestCoordinatesApplyPartial_Latitude(t *testing.T) {
 	c := &Coordinates{}
-	p := &CoordinatesPartial{Latitude: mergePtr(float64(42))}
+	p := &CoordinatesPartial{Latitude: sudogenPtr(float64(42))}
 	c.ApplyPartial(p)
 	if c.Latitude != 42 {
 		t.Errorf("expected Latitude=42, got %v", c.Latitude)
@@ -191,7 +260,7 @@ func TestCoordinatesApplyPartial_Latitude(t *testing.T) {
 
 func TestCoordinatesApplyPartial_Longitude(t *testing.T) {
 	c := &Coordinates{}
-	p := &CoordinatesPartial{Longitude: mergePtr(float64(42))}
+	p := &CoordinatesPartial{Longitude: sudogenPtr(float64(42))}
 	c.ApplyPartial(p)
 	if c.Longitude != 42 {
 		t.Errorf("expected Longitude=42, got %v", c.Longitude)
@@ -214,7 +283,7 @@ func TestHomeApplyPartialEmpty(t *testing.T) {
 
 func TestHomeApplyPartial_Address(t *testing.T) {
 	c := &Home{}
-	p := &HomePartial{Address: mergePtr("test")}
+	p := &HomePartial{Address: sudogenPtr("test")}
 	c.ApplyPartial(p)
 	if c.Address != "test" {
 		t.Errorf("expected Address=test, got %s", c.Address)
@@ -223,7 +292,7 @@ func TestHomeApplyPartial_Address(t *testing.T) {
 
 func TestHomeApplyPartial_AddressOverwrite(t *testing.T) {
 	c := &Home{Address: "original"}
-	p := &HomePartial{Address: mergePtr("updated")}
+	p := &HomePartial{Address: sudogenPtr("updated")}
 	c.ApplyPartial(p)
 	if c.Address != "updated" {
 		t.Errorf("expected Address=updated, got %s", c.Address)
@@ -232,7 +301,7 @@ func TestHomeApplyPartial_AddressOverwrite(t *testing.T) {
 
 func TestHomeApplyPartial_City(t *testing.T) {
 	c := &Home{}
-	p := &HomePartial{City: mergePtr("test")}
+	p := &HomePartial{City: sudogenPtr("test")}
 	c.ApplyPartial(p)
 	if c.City != "test" {
 		t.Errorf("expected City=test, got %s", c.City)
@@ -241,7 +310,7 @@ func TestHomeApplyPartial_City(t *testing.T) {
 
 func TestHomeApplyPartial_CityOverwrite(t *testing.T) {
 	c := &Home{City: "original"}
-	p := &HomePartial{City: mergePtr("updated")}
+	p := &HomePartial{City: sudogenPtr("updated")}
 	c.ApplyPartial(p)
 	if c.City != "updated" {
 		t.Errorf("expected City=updated, got %s", c.City)
@@ -250,7 +319,7 @@ func TestHomeApplyPartial_CityOverwrite(t *testing.T) {
 
 func TestHomeApplyPartial_ZipCode(t *testing.T) {
 	c := &Home{}
-	p := &HomePartial{ZipCode: mergePtr("test")}
+	p := &HomePartial{ZipCode: sudogenPtr("test")}
 	c.ApplyPartial(p)
 	if c.ZipCode != "test" {
 		t.Errorf("expected ZipCode=test, got %s", c.ZipCode)
@@ -259,7 +328,7 @@ func TestHomeApplyPartial_ZipCode(t *testing.T) {
 
 func TestHomeApplyPartial_ZipCodeOverwrite(t *testing.T) {
 	c := &Home{ZipCode: "original"}
-	p := &HomePartial{ZipCode: mergePtr("updated")}
+	p := &HomePartial{ZipCode: sudogenPtr("updated")}
 	c.ApplyPartial(p)
 	if c.ZipCode != "updated" {
 		t.Errorf("expected ZipCode=updated, got %s", c.ZipCode)
@@ -276,10 +345,396 @@ func TestHomeApplyPartial_DestinationNestedStruct(t *testing.T) {
 }
 
 func TestHomeApplyPartial_DestinationNestedStructExisting(t *testing.T) {
-	c := &Home{Destination: &Coordinates{}}
+	existing := &Coordinates{}
+	c := &Home{Destination: existing}
 	p := &HomePartial{Destination: &CoordinatesPartial{}}
 	c.ApplyPartial(p)
 	if c.Destination == nil {
 		t.Error("expected nested struct to remain set")
 	}
+	if c.Destination == existing {
+		t.Error("expected nested struct to be replaced (merge=replace)")
+	}
+}
+
+func TestConfigApplySparseUnknownPath(t *testing.T) {
+	c := &Config{}
+	if err := c.ApplySparse(ConfigSparseEntry{Path: "DoesNotExist", Value: 1}); err == nil {
+		t.Error("expected error for unknown path")
+	}
+}
+
+func TestConfigApplySparse_Name(t *testing.T) {
+	c := &Config{Name: "original"}
+	if err := c.ApplySparse(ConfigSparseEntry{Path: "Name", Value: "updated"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Name != "updated" {
+		t.Errorf("expected Name=updated, got %s", c.Name)
+	}
+}
+
+func TestConfigApplySparse_NameWrongType(t *testing.T) {
+	c := &Config{Name: "original"}
+	if err := c.ApplySparse(ConfigSparseEntry{Path: "Name", Value: 42}); err == nil {
+		t.Error("expected error for mismatched value type")
+	}
+	if c.Name != "original" {
+		t.Errorf("expected Name to be unchanged, got %s", c.Name)
+	}
+}
+
+func TestConfigApplyPartial_NameClear(t *testing.T) {
+	c := &Config{Name: "original"}
+	p := &ConfigPartial{}
+	if !p.ClearField("Name") {
+		t.Fatal("expected Name to be clearable")
+	}
+	c.ApplyPartial(p)
+	if c.Name != "" {
+		t.Errorf("expected Name to be cleared, got %s", c.Name)
+	}
+	p.Name = sudogenPtr("updated")
+	c.ApplyPartial(p)
+	if c.Name != "updated" {
+		t.Errorf("expected a set value to take precedence over a clear, got %s", c.Name)
+	}
+}
+
+func TestConfigApplySparse_City(t *testing.T) {
+	c := &Config{City: "original"}
+	if err := c.ApplySparse(ConfigSparseEntry{Path: "City", Value: "updated"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.City != "updated" {
+		t.Errorf("expected City=updated, got %s", c.City)
+	}
+}
+
+func TestConfigApplySparse_CityWrongType(t *testing.T) {
+	c := &Config{City: "original"}
+	if err := c.ApplySparse(ConfigSparseEntry{Path: "City", Value: 42}); err == nil {
+		t.Error("expected error for mismatched value type")
+	}
+	if c.City != "original" {
+		t.Errorf("expected City to be unchanged, got %s", c.City)
+	}
+}
+
+func TestConfigApplyPartial_CityClear(t *testing.T) {
+	c := &Config{City: "original"}
+	p := &ConfigPartial{}
+	if !p.ClearField("City") {
+		t.Fatal("expected City to be clearable")
+	}
+	c.ApplyPartial(p)
+	if c.City != "" {
+		t.Errorf("expected City to be cleared, got %s", c.City)
+	}
+	p.City = sudogenPtr("updated")
+	c.ApplyPartial(p)
+	if c.City != "updated" {
+		t.Errorf("expected a set value to take precedence over a clear, got %s", c.City)
+	}
+}
+
+func TestConfigPartialMerge_Name(t *testing.T) {
+	base := ConfigPartial{Name: sudogenPtr("base")}
+	override := ConfigPartial{Name: sudogenPtr("override")}
+	if got := base.Merge(override); got.Name == nil || *got.Name != "override" {
+		t.Errorf("expected the override to win, got %v", got.Name)
+	}
+	if got := base.Merge(ConfigPartial{}); got.Name == nil || *got.Name != "base" {
+		t.Errorf("expected an unset field to keep the base, got %v", got.Name)
+	}
+	if got := (ConfigPartial{}).Overlay(base, override, ConfigPartial{}); got.Name == nil || *got.Name != "override" {
+		t.Errorf("expected the last layer setting Name to win, got %v", got.Name)
+	}
+	var cleared ConfigPartial
+	cleared.ClearField("Name")
+	if got := base.Merge(cleared); got.Name != nil || !slices.Contains(got.Clear, "Name") {
+		t.Errorf("expected the clear to drop the base value, got %v, %v", got.Name, got.Clear)
+	}
+	if len(base.Clear) != 0 {
+		t.Errorf("Merge modified its receiver: %v", base.Clear)
+	}
+	if *base.Name != "base" {
+		t.Errorf("Merge modified its receiver: %s", *base.Name)
+	}
+}
+
+func TestConfigPartialIsEmpty(t *testing.T) {
+	var p *ConfigPartial
+	if !p.IsEmpty() {
+		t.Error("expected a nil partial to be empty")
+	}
+	if !(&ConfigPartial{}).IsEmpty() {
+		t.Error("expected a zero partial to be empty")
+	}
+	if (&ConfigPartial{Name: sudogenPtr("set")}).IsEmpty() {
+		t.Error("expected a partial setting Name not to be empty")
+	}
+}
+
+func TestConfigPartialPrune_Home(t *testing.T) {
+	p := &ConfigPartial{Home: &HomePartial{}}
+	if !p.IsEmpty() {
+		t.Error("expected a partial with an empty nested partial to be empty")
+	}
+	p.Prune()
+	if p.Home != nil {
+		t.Errorf("expected the empty nested partial to be pruned, got %v", p.Home)
+	}
+}
+
+func TestConfigPartialMarshalJSONSparse(t *testing.T) {
+	// Keys are looked up in the encoding, since fields without omitempty are
+	// written as null when unset.
+	fields := func(p ConfigPartial) map[string]json.RawMessage {
+		t.Helper()
+		data, err := json.Marshal(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			t.Fatal(err)
+		}
+		return fields
+	}
+	if got := string(fields(ConfigPartial{Jobs: []Job{}})["jobs"]); got != "[]" {
+		t.Errorf("expected Jobs set to an empty value to be written, got %s", got)
+	}
+	if got, ok := fields(ConfigPartial{Home: &HomePartial{}})["home"]; ok {
+		t.Errorf("expected the empty nested partial Home to be left out, got %s", got)
+	}
+	if got, ok := fields(ConfigPartial{OtherHome: &HomePartial{}})["other_home"]; ok {
+		t.Errorf("expected the empty nested partial OtherHome to be left out, got %s", got)
+	}
+	if got := string(fields(ConfigPartial{Avatar: []byte{}})["avatar"]); got != "\"\"" {
+		t.Errorf("expected Avatar set to an empty value to be written, got %s", got)
+	}
+}
+
+func TestConfigToPartial(t *testing.T) {
+	var c Config
+	c.Name = "snapshot"
+	p := c.ToPartial()
+	var got Config
+	got.ApplyPartial(&p)
+	if got.Name != "snapshot" {
+		t.Errorf("expected the partial to restore Name, got %q", got.Name)
+	}
+	if p := c.NonZeroPartial(); p.Name == nil || *p.Name != "snapshot" {
+		t.Errorf("expected the non-zero partial to set Name, got %v", p.Name)
+	}
+	if p := (Config{}).NonZeroPartial(); !p.IsEmpty() {
+		t.Errorf("expected the non-zero partial of a zero Config to be empty, got %+v", p)
+	}
+}
+
+func TestConfigJSONMergePatch(t *testing.T) {
+	var c Config
+	if err := c.ApplyJSONMergePatch([]byte("{\"name\":\"patched\"}")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Name != "patched" {
+		t.Errorf("expected the patch to set Name, got %q", c.Name)
+	}
+	base := c
+	base.Name = "base"
+	patch, err := c.ToJSONMergePatch(base)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(patch) != "{\"name\":\"patched\"}" {
+		t.Errorf("expected a patch of the differing field, got %s", patch)
+	}
+	if err := base.ApplyJSONMergePatch(patch); err != nil || base.Name != "patched" {
+		t.Errorf("expected the patch to turn base into c, got %q (%v)", base.Name, err)
+	}
+	if patch, err := c.ToJSONMergePatch(c); err != nil || string(patch) != "{}" {
+		t.Errorf("expected an empty patch between equal values, got %s (%v)", patch, err)
+	}
+	if err := c.ApplyJSONMergePatch([]byte("{\"name\":null}")); err != nil || c.Name != "" {
+		t.Errorf("expected null to reset Name, got %q (%v)", c.Name, err)
+	}
+	if err := c.ApplyJSONMergePatch([]byte("[]")); err == nil {
+		t.Error("expected an error for a patch that isn't an object")
+	}
+}
+
+func TestDiffConfigAsJSONPatch(t *testing.T) {
+	var from, to Config
+	to.Name = "patched"
+	ops, err := DiffConfigAsJSONPatch(from, to)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ops) != 1 || ops[0].Op != "add" || ops[0].Path != "/name" || string(ops[0].Value) != "\"patched\"" {
+		t.Errorf("expected a single operation setting Name, got %+v", ops)
+	}
+	ops, err = DiffConfigAsJSONPatch(to, from)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ops) != 1 || ops[0].Op != "remove" || ops[0].Path != "/name" {
+		t.Errorf("expected a single operation resetting Name, got %+v", ops)
+	}
+	if ops, err := DiffConfigAsJSONPatch(to, to); err != nil || len(ops) != 0 {
+		t.Errorf("expected no operations between equal values, got %+v (%v)", ops, err)
+	}
+}
+
+func TestConfigPartialJSONNull_Name(t *testing.T) {
+	var p ConfigPartial
+	if err := json.Unmarshal([]byte("{\"name\":null}"), &p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Contains(p.Clear, "Name") {
+		t.Fatalf("expected null to clear Name, got %v", p.Clear)
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("unexpected marshal error: %v", err)
+	}
+	if string(data) != "{\"name\":null}" {
+		t.Errorf("expected the clear to be written as null, got %s", data)
+	}
+	if data, err := json.Marshal(ConfigPartial{}); err != nil || string(data) != "{}" {
+		t.Errorf("expected unset fields to be left out, got %s (%v)", data, err)
+	}
+}
+
+func TestJobPartialJSONNull_Title(t *testing.T) {
+	var p JobPartial
+	if err := json.Unmarshal([]byte("{\"title\":null}"), &p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Contains(p.Clear, "Title") {
+		t.Fatalf("expected null to clear Title, got %v", p.Clear)
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("unexpected marshal error: %v", err)
+	}
+	if string(data) != "{\"title\":null}" {
+		t.Errorf("expected the clear to be written as null, got %s", data)
+	}
+	if data, err := json.Marshal(JobPartial{}); err != nil || string(data) != "{}" {
+		t.Errorf("expected unset fields to be left out, got %s (%v)", data, err)
+	}
+}
+
+func TestCoordinatesPartialJSONNull_Latitude(t *testing.T) {
+	var p CoordinatesPartial
+	if err := json.Unmarshal([]byte("{\"latitude\":null}"), &p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Contains(p.Clear, "Latitude") {
+		t.Fatalf("expected null to clear Latitude, got %v", p.Clear)
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("unexpected marshal error: %v", err)
+	}
+	if string(data) != "{\"latitude\":null}" {
+		t.Errorf("expected the clear to be written as null, got %s", data)
+	}
+	if data, err := json.Marshal(CoordinatesPartial{}); err != nil || string(data) != "{}" {
+		t.Errorf("expected unset fields to be left out, got %s (%v)", data, err)
+	}
+}
+
+func TestHomePartialJSONNull_Address(t *testing.T) {
+	var p HomePartial
+	if err := json.Unmarshal([]byte("{\"address\":null}"), &p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Contains(p.Clear, "Address") {
+		t.Fatalf("expected null to clear Address, got %v", p.Clear)
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("unexpected marshal error: %v", err)
+	}
+	if string(data) != "{\"address\":null}" {
+		t.Errorf("expected the clear to be written as null, got %s", data)
+	}
+	if data, err := json.Marshal(HomePartial{}); err != nil || string(data) != "{}" {
+		t.Errorf("expected unset fields to be left out, got %s (%v)", data, err)
+	}
+}
+
+func TestConfigPartialJSONURL_Website(t *testing.T) {
+	var p ConfigPartial
+	if err := json.Unmarshal([]byte("{\"website\":\"https://example.com/a?b=c\"}"), &p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Website == nil || p.Website.Host != "example.com" || p.Website.RawQuery != "b=c" {
+		t.Fatalf("expected Website=https://example.com/a?b=c, got %v", p.Website)
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("unexpected marshal error: %v", err)
+	}
+	var roundTrip ConfigPartial
+	if err := json.Unmarshal(data, &roundTrip); err != nil {
+		t.Fatalf("unexpected error decoding %s: %v", data, err)
+	}
+	if roundTrip.Website == nil || roundTrip.Website.String() != "https://example.com/a?b=c" {
+		t.Errorf("expected round-tripped Website=https://example.com/a?b=c, got %s", data)
+	}
+	if err := json.Unmarshal([]byte("{\"website\":\"://\"}"), &p); err == nil {
+		t.Error("expected error for invalid URL")
+	}
+}
+
+func TestHomePartialJSONDuration_Age(t *testing.T) {
+	var p HomePartial
+	if err := json.Unmarshal([]byte("{\"age\":\"1h30m\"}"), &p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Age == nil || *p.Age != 90*time.Minute {
+		t.Fatalf("expected Age=1h30m, got %v", p.Age)
+	}
+	if err := json.Unmarshal([]byte("{\"age\":1000000000}"), &p); err != nil {
+		t.Fatalf("unexpected error for nanoseconds: %v", err)
+	}
+	if *p.Age != time.Second {
+		t.Errorf("expected Age=1s, got %v", *p.Age)
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("unexpected marshal error: %v", err)
+	}
+	var roundTrip HomePartial
+	if err := json.Unmarshal(data, &roundTrip); err != nil {
+		t.Fatalf("unexpected error decoding %s: %v", data, err)
+	}
+	if roundTrip.Age == nil || *roundTrip.Age != time.Second {
+		t.Errorf("expected round-tripped Age=1s, got %s", data)
+	}
+	if err := json.Unmarshal([]byte("{\"age\":\"soon\"}"), &p); err == nil {
+		t.Error("expected error for invalid duration")
+	}
+}
+
+func TestConfigPartialDeprecations_City(t *testing.T) {
+	p := &ConfigPartial{}
+	if d := p.Deprecations(); len(d) != 0 {
+		t.Fatalf("expected no deprecations for an empty partial, got %v", d)
+	}
+	p.City = new(string)
+	d := p.Deprecations()
+	if len(d) != 1 || d[0].Path != "City" || d[0].Message != "use Home.City" {
+		t.Errorf("expected the deprecation of City, got %v", d)
+	}
+	migrated := p.MigrateDeprecated()
+	if migrated.Home == nil || migrated.Home.City == nil {
+		t.Fatal("expected MigrateDeprecated to set Home.City")
+	}
+	if p.Home != nil && p.Home.City != nil {
+		t.Error("expected MigrateDeprecated to leave p unchanged")
+	}
 }
@@ -1,19 +1,316 @@
 // Code generated by sudo-gen merge. DO NOT EDIT.
+// Generated by sudo-gen (devel): layerbroker -tests -json -external=partial -clear -migrate-deprecated -merge-patch -json-patch -explain-diff -with -bench -utc
 
 package nested
 
 import (
-	"github.com/bobcob7/sudo-gen/examples/nested/duration"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
 	"time"
 )
 
 type ConfigPartial struct {
 	Name      *string                   `json:"name,omitempty"`
 	Jobs      []Job                     `json:"jobs,omitempty"`
+	City      *string                   `json:"city,omitempty" sudo:"deprecated=use Home.City"`
 	Home      *HomePartial              `json:"home,omitempty"`
-	OtherHome *HomePartial              `json:"home,omitempty"`
+	OtherHome *HomePartial              `json:"other_home,omitempty"`
 	CreatedAt *time.Time                `json:"created_at,omitempty"`
 	Limit     *DurationTimestampPartial `json:"limit,omitempty"`
+	Avatar    []byte                    `json:"avatar,omitempty"`
+	Website   *url.URL                  `json:"website,omitempty"`
+
+	// Clear names the fields ApplyPartial resets to their zero value before applying
+	// the fields set above. Fields are added with ClearField.
+	Clear []string `json:"-"`
+}
+
+// ClearField marks the named field to be reset to its zero value, discarding any
+// value p sets for it. It reports false if ConfigPartial has no such field.
+func (p *ConfigPartial) ClearField(name string) bool {
+	switch name {
+	case "Name":
+		p.Name = nil
+	case "Jobs":
+		p.Jobs = nil
+	case "City":
+		p.City = nil
+	case "Home":
+		p.Home = nil
+	case "OtherHome":
+		p.OtherHome = nil
+	case "CreatedAt":
+		p.CreatedAt = nil
+	case "Limit":
+		p.Limit = nil
+	case "Avatar":
+		p.Avatar = nil
+	case "Website":
+		p.Website = nil
+	default:
+		return false
+	}
+	if !slices.Contains(p.Clear, name) {
+		p.Clear = append(p.Clear, name)
+	}
+	return true
+}
+
+// Merge returns p with the fields other sets applied over it, as applying p and then
+// other with ApplyPartial would: maps are merged key by key and nested partials field
+// by field. The result shares unmerged values with p and other, which must not be
+// modified while it is in use. Fields other clears drop the values p sets for them.
+func (p ConfigPartial) Merge(other ConfigPartial) ConfigPartial {
+	p.Clear = slices.Clone(p.Clear)
+	for _, name := range other.Clear {
+		p.ClearField(name)
+	}
+	if other.Name != nil {
+		p.Name = other.Name
+	}
+	if other.Jobs != nil {
+		p.Jobs = other.Jobs
+	}
+	if other.City != nil {
+		p.City = other.City
+	}
+	if other.Home != nil {
+		if p.Home == nil {
+			p.Home = other.Home
+		} else {
+			v := p.Home.Merge(*other.Home)
+			p.Home = &v
+		}
+	}
+	if other.OtherHome != nil {
+		if p.OtherHome == nil {
+			p.OtherHome = other.OtherHome
+		} else {
+			v := p.OtherHome.Merge(*other.OtherHome)
+			p.OtherHome = &v
+		}
+	}
+	if other.CreatedAt != nil {
+		p.CreatedAt = other.CreatedAt
+	}
+	if other.Limit != nil {
+		if p.Limit == nil {
+			p.Limit = other.Limit
+		} else {
+			v := p.Limit.Merge(*other.Limit)
+			p.Limit = &v
+		}
+	}
+	if other.Avatar != nil {
+		p.Avatar = other.Avatar
+	}
+	if other.Website != nil {
+		p.Website = other.Website
+	}
+	return p
+}
+
+// IsEmpty reports whether p neither sets nor clears any field. Nested
+// partials that are empty themselves count as unset (see Prune).
+func (p *ConfigPartial) IsEmpty() bool {
+	if p == nil {
+		return true
+	}
+	if len(p.Clear) > 0 {
+		return false
+	}
+	if p.Name != nil {
+		return false
+	}
+	if p.Jobs != nil {
+		return false
+	}
+	if p.City != nil {
+		return false
+	}
+	if !p.Home.IsEmpty() {
+		return false
+	}
+	if !p.OtherHome.IsEmpty() {
+		return false
+	}
+	if p.CreatedAt != nil {
+		return false
+	}
+	if !p.Limit.IsEmpty() {
+		return false
+	}
+	if p.Avatar != nil {
+		return false
+	}
+	if p.Website != nil {
+		return false
+	}
+	return true
+}
+
+// Prune sets the nested partials of p that are empty to nil, at every depth, so that
+// p encodes without empty objects. Applying a pruned partial no longer allocates the
+// structs of nil pointer fields that those partials would have left zero. Partials of
+// fields replaced as a whole are kept, since applying them resets the field.
+func (p *ConfigPartial) Prune() {
+	if p == nil {
+		return
+	}
+	p.Home.Prune()
+	if p.Home.IsEmpty() {
+		p.Home = nil
+	}
+	p.OtherHome.Prune()
+	if p.OtherHome.IsEmpty() {
+		p.OtherHome = nil
+	}
+	p.Limit.Prune()
+	if p.Limit.IsEmpty() {
+		p.Limit = nil
+	}
+}
+
+// UnmarshalJSON decodes p, accepting URL strings for url.URL fields.
+// A null value clears the field (see ClearField).
+func (p *ConfigPartial) UnmarshalJSON(data []byte) error {
+	type partial ConfigPartial
+	aux := struct {
+		*partial
+		Website *configPartialURL `json:"website"`
+	}{partial: (*partial)(p)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if aux.Website != nil {
+		p.Website = (*url.URL)(aux.Website)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if string(fields["name"]) == "null" {
+		p.ClearField("Name")
+	}
+	if string(fields["jobs"]) == "null" {
+		p.ClearField("Jobs")
+	}
+	if string(fields["city"]) == "null" {
+		p.ClearField("City")
+	}
+	if string(fields["home"]) == "null" {
+		p.ClearField("Home")
+	}
+	if string(fields["other_home"]) == "null" {
+		p.ClearField("OtherHome")
+	}
+	if string(fields["created_at"]) == "null" {
+		p.ClearField("CreatedAt")
+	}
+	if string(fields["limit"]) == "null" {
+		p.ClearField("Limit")
+	}
+	if string(fields["avatar"]) == "null" {
+		p.ClearField("Avatar")
+	}
+	if string(fields["website"]) == "null" {
+		p.ClearField("Website")
+	}
+	return nil
+}
+
+// MarshalJSON encodes p, writing url.URL fields as URL strings.
+// Cleared fields are written as null, and fields that are neither set nor cleared
+// are left out.
+// Empty nested partials count as unset, as after Prune, while slices and maps set
+// to empty values are written.
+func (p ConfigPartial) MarshalJSON() ([]byte, error) {
+	type partial ConfigPartial
+	aux := struct {
+		*partial
+		Website *configPartialURL `json:"website,omitempty"`
+	}{
+		partial: (*partial)(&p),
+		Website: (*configPartialURL)(p.Website),
+	}
+	data, err := json.Marshal(aux)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if p.Name == nil {
+		if slices.Contains(p.Clear, "Name") {
+			fields["name"] = json.RawMessage("null")
+		} else {
+			delete(fields, "name")
+		}
+	}
+	if p.Jobs == nil {
+		if slices.Contains(p.Clear, "Jobs") {
+			fields["jobs"] = json.RawMessage("null")
+		} else {
+			delete(fields, "jobs")
+		}
+	} else if len(p.Jobs) == 0 {
+		fields["jobs"] = json.RawMessage("[]")
+	}
+	if p.City == nil {
+		if slices.Contains(p.Clear, "City") {
+			fields["city"] = json.RawMessage("null")
+		} else {
+			delete(fields, "city")
+		}
+	}
+	if p.Home.IsEmpty() {
+		if slices.Contains(p.Clear, "Home") {
+			fields["home"] = json.RawMessage("null")
+		} else {
+			delete(fields, "home")
+		}
+	}
+	if p.OtherHome.IsEmpty() {
+		if slices.Contains(p.Clear, "OtherHome") {
+			fields["other_home"] = json.RawMessage("null")
+		} else {
+			delete(fields, "other_home")
+		}
+	}
+	if p.CreatedAt == nil {
+		if slices.Contains(p.Clear, "CreatedAt") {
+			fields["created_at"] = json.RawMessage("null")
+		} else {
+			delete(fields, "created_at")
+		}
+	}
+	if p.Limit.IsEmpty() {
+		if slices.Contains(p.Clear, "Limit") {
+			fields["limit"] = json.RawMessage("null")
+		} else {
+			delete(fields, "limit")
+		}
+	}
+	if p.Avatar == nil {
+		if slices.Contains(p.Clear, "Avatar") {
+			fields["avatar"] = json.RawMessage("null")
+		} else {
+			delete(fields, "avatar")
+		}
+	} else if len(p.Avatar) == 0 {
+		fields["avatar"] = json.RawMessage("\"\"")
+	}
+	if p.Website == nil {
+		if slices.Contains(p.Clear, "Website") {
+			fields["website"] = json.RawMessage("null")
+		} else {
+			delete(fields, "website")
+		}
+	}
+	return json.Marshal(fields)
 }
 
 type JobPartial struct {
@@ -22,6 +319,202 @@ type JobPartial struct {
 	Location *string                   `json:"location,omitempty"`
 	Tenure   *DurationTimestampPartial `json:"tenure,omitempty"`
 	Coords   *CoordinatesPartial       `json:"coords,omitempty"`
+
+	// Clear names the fields ApplyPartial resets to their zero value before applying
+	// the fields set above. Fields are added with ClearField.
+	Clear []string `json:"-"`
+}
+
+// ClearField marks the named field to be reset to its zero value, discarding any
+// value p sets for it. It reports false if JobPartial has no such field.
+func (p *JobPartial) ClearField(name string) bool {
+	switch name {
+	case "Title":
+		p.Title = nil
+	case "Company":
+		p.Company = nil
+	case "Location":
+		p.Location = nil
+	case "Tenure":
+		p.Tenure = nil
+	case "Coords":
+		p.Coords = nil
+	default:
+		return false
+	}
+	if !slices.Contains(p.Clear, name) {
+		p.Clear = append(p.Clear, name)
+	}
+	return true
+}
+
+// Merge returns p with the fields other sets applied over it, as applying p and then
+// other with ApplyPartial would: maps are merged key by key and nested partials field
+// by field. The result shares unmerged values with p and other, which must not be
+// modified while it is in use. Fields other clears drop the values p sets for them.
+func (p JobPartial) Merge(other JobPartial) JobPartial {
+	p.Clear = slices.Clone(p.Clear)
+	for _, name := range other.Clear {
+		p.ClearField(name)
+	}
+	if other.Title != nil {
+		p.Title = other.Title
+	}
+	if other.Company != nil {
+		p.Company = other.Company
+	}
+	if other.Location != nil {
+		p.Location = other.Location
+	}
+	if other.Tenure != nil {
+		if p.Tenure == nil {
+			p.Tenure = other.Tenure
+		} else {
+			v := p.Tenure.Merge(*other.Tenure)
+			p.Tenure = &v
+		}
+	}
+	if other.Coords != nil {
+		if p.Coords == nil {
+			p.Coords = other.Coords
+		} else {
+			v := p.Coords.Merge(*other.Coords)
+			p.Coords = &v
+		}
+	}
+	return p
+}
+
+// IsEmpty reports whether p neither sets nor clears any field. Nested
+// partials that are empty themselves count as unset (see Prune).
+func (p *JobPartial) IsEmpty() bool {
+	if p == nil {
+		return true
+	}
+	if len(p.Clear) > 0 {
+		return false
+	}
+	if p.Title != nil {
+		return false
+	}
+	if p.Company != nil {
+		return false
+	}
+	if p.Location != nil {
+		return false
+	}
+	if !p.Tenure.IsEmpty() {
+		return false
+	}
+	if !p.Coords.IsEmpty() {
+		return false
+	}
+	return true
+}
+
+// Prune sets the nested partials of p that are empty to nil, at every depth, so that
+// p encodes without empty objects. Applying a pruned partial no longer allocates the
+// structs of nil pointer fields that those partials would have left zero. Partials of
+// fields replaced as a whole are kept, since applying them resets the field.
+func (p *JobPartial) Prune() {
+	if p == nil {
+		return
+	}
+	p.Tenure.Prune()
+	if p.Tenure.IsEmpty() {
+		p.Tenure = nil
+	}
+	p.Coords.Prune()
+	if p.Coords.IsEmpty() {
+		p.Coords = nil
+	}
+}
+
+// UnmarshalJSON decodes p. A null value clears the field (see ClearField).
+func (p *JobPartial) UnmarshalJSON(data []byte) error {
+	type partial JobPartial
+	aux := struct {
+		*partial
+	}{partial: (*partial)(p)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if string(fields["title"]) == "null" {
+		p.ClearField("Title")
+	}
+	if string(fields["company"]) == "null" {
+		p.ClearField("Company")
+	}
+	if string(fields["location"]) == "null" {
+		p.ClearField("Location")
+	}
+	if string(fields["tenure"]) == "null" {
+		p.ClearField("Tenure")
+	}
+	if string(fields["coords"]) == "null" {
+		p.ClearField("Coords")
+	}
+	return nil
+}
+
+// MarshalJSON encodes p, writing cleared fields as null and leaving out
+// fields that are neither set nor cleared.
+// Empty nested partials count as unset, as after Prune.
+func (p JobPartial) MarshalJSON() ([]byte, error) {
+	type partial JobPartial
+	aux := struct {
+		*partial
+	}{
+		partial: (*partial)(&p),
+	}
+	data, err := json.Marshal(aux)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if p.Title == nil {
+		if slices.Contains(p.Clear, "Title") {
+			fields["title"] = json.RawMessage("null")
+		} else {
+			delete(fields, "title")
+		}
+	}
+	if p.Company == nil {
+		if slices.Contains(p.Clear, "Company") {
+			fields["company"] = json.RawMessage("null")
+		} else {
+			delete(fields, "company")
+		}
+	}
+	if p.Location == nil {
+		if slices.Contains(p.Clear, "Location") {
+			fields["location"] = json.RawMessage("null")
+		} else {
+			delete(fields, "location")
+		}
+	}
+	if p.Tenure.IsEmpty() {
+		if slices.Contains(p.Clear, "Tenure") {
+			fields["tenure"] = json.RawMessage("null")
+		} else {
+			delete(fields, "tenure")
+		}
+	}
+	if p.Coords.IsEmpty() {
+		if slices.Contains(p.Clear, "Coords") {
+			fields["coords"] = json.RawMessage("null")
+		} else {
+			delete(fields, "coords")
+		}
+	}
+	return json.Marshal(fields)
 }
 
 type DurationTimestampPartial struct {
@@ -30,16 +523,466 @@ type DurationTimestampPartial struct {
 	Days    *int `json:"days,omitempty"`
 }
 
+// Merge returns p with the fields other sets applied over it, as applying p and then
+// other with ApplyPartial would: maps are merged key by key and nested partials field
+// by field. The result shares unmerged values with p and other, which must not be
+// modified while it is in use.
+func (p DurationTimestampPartial) Merge(other DurationTimestampPartial) DurationTimestampPartial {
+	if other.Minutes != nil {
+		p.Minutes = other.Minutes
+	}
+	if other.Hours != nil {
+		p.Hours = other.Hours
+	}
+	if other.Days != nil {
+		p.Days = other.Days
+	}
+	return p
+}
+
+// IsEmpty reports whether p sets no field. Nested
+// partials that are empty themselves count as unset (see Prune).
+func (p *DurationTimestampPartial) IsEmpty() bool {
+	if p == nil {
+		return true
+	}
+	if p.Minutes != nil {
+		return false
+	}
+	if p.Hours != nil {
+		return false
+	}
+	if p.Days != nil {
+		return false
+	}
+	return true
+}
+
+// Prune sets the nested partials of p that are empty to nil, at every depth, so that
+// p encodes without empty objects. Applying a pruned partial no longer allocates the
+// structs of nil pointer fields that those partials would have left zero. Partials of
+// fields replaced as a whole are kept, since applying them resets the field.
+func (p *DurationTimestampPartial) Prune() {
+	if p == nil {
+		return
+	}
+}
+
 type CoordinatesPartial struct {
 	Latitude  *float64 `json:"latitude,omitempty"`
 	Longitude *float64 `json:"longitude,omitempty"`
+
+	// Clear names the fields ApplyPartial resets to their zero value before applying
+	// the fields set above. Fields are added with ClearField.
+	Clear []string `json:"-"`
+}
+
+// ClearField marks the named field to be reset to its zero value, discarding any
+// value p sets for it. It reports false if CoordinatesPartial has no such field.
+func (p *CoordinatesPartial) ClearField(name string) bool {
+	switch name {
+	case "Latitude":
+		p.Latitude = nil
+	case "Longitude":
+		p.Longitude = nil
+	default:
+		return false
+	}
+	if !slices.Contains(p.Clear, name) {
+		p.Clear = append(p.Clear, name)
+	}
+	return true
+}
+
+// Merge returns p with the fields other sets applied over it, as applying p and then
+// other with ApplyPartial would: maps are merged key by key and nested partials field
+// by field. The result shares unmerged values with p and other, which must not be
+// modified while it is in use. Fields other clears drop the values p sets for them.
+func (p CoordinatesPartial) Merge(other CoordinatesPartial) CoordinatesPartial {
+	p.Clear = slices.Clone(p.Clear)
+	for _, name := range other.Clear {
+		p.ClearField(name)
+	}
+	if other.Latitude != nil {
+		p.Latitude = other.Latitude
+	}
+	if other.Longitude != nil {
+		p.Longitude = other.Longitude
+	}
+	return p
+}
+
+// IsEmpty reports whether p neither sets nor clears any field. Nested
+// partials that are empty themselves count as unset (see Prune).
+func (p *CoordinatesPartial) IsEmpty() bool {
+	if p == nil {
+		return true
+	}
+	if len(p.Clear) > 0 {
+		return false
+	}
+	if p.Latitude != nil {
+		return false
+	}
+	if p.Longitude != nil {
+		return false
+	}
+	return true
+}
+
+// Prune sets the nested partials of p that are empty to nil, at every depth, so that
+// p encodes without empty objects. Applying a pruned partial no longer allocates the
+// structs of nil pointer fields that those partials would have left zero. Partials of
+// fields replaced as a whole are kept, since applying them resets the field.
+func (p *CoordinatesPartial) Prune() {
+	if p == nil {
+		return
+	}
+}
+
+// UnmarshalJSON decodes p. A null value clears the field (see ClearField).
+func (p *CoordinatesPartial) UnmarshalJSON(data []byte) error {
+	type partial CoordinatesPartial
+	aux := struct {
+		*partial
+	}{partial: (*partial)(p)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if string(fields["latitude"]) == "null" {
+		p.ClearField("Latitude")
+	}
+	if string(fields["longitude"]) == "null" {
+		p.ClearField("Longitude")
+	}
+	return nil
+}
+
+// MarshalJSON encodes p, writing cleared fields as null and leaving out
+// fields that are neither set nor cleared.
+func (p CoordinatesPartial) MarshalJSON() ([]byte, error) {
+	type partial CoordinatesPartial
+	aux := struct {
+		*partial
+	}{
+		partial: (*partial)(&p),
+	}
+	data, err := json.Marshal(aux)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if p.Latitude == nil {
+		if slices.Contains(p.Clear, "Latitude") {
+			fields["latitude"] = json.RawMessage("null")
+		} else {
+			delete(fields, "latitude")
+		}
+	}
+	if p.Longitude == nil {
+		if slices.Contains(p.Clear, "Longitude") {
+			fields["longitude"] = json.RawMessage("null")
+		} else {
+			delete(fields, "longitude")
+		}
+	}
+	return json.Marshal(fields)
 }
 
 type HomePartial struct {
-	Address     *string `json:"address,omitempty"`
-	City        *string `json:"city,omitempty"`
-	ZipCode     *string `json:"zip_code,omitempty"`
-	Age         *duration.Duration
+	Address     *string             `json:"address,omitempty"`
+	City        *string             `json:"city,omitempty"`
+	ZipCode     *string             `json:"zip_code,omitempty"`
+	Age         *time.Duration      `json:"age,omitempty"`
 	Coords      *CoordinatesPartial `json:"coords,omitempty"`
-	Destination *CoordinatesPartial `json:"destination,omitempty"`
+	Destination *CoordinatesPartial `json:"destination,omitempty" sudo:"merge=replace"`
+
+	// Clear names the fields ApplyPartial resets to their zero value before applying
+	// the fields set above. Fields are added with ClearField.
+	Clear []string `json:"-"`
+}
+
+// ClearField marks the named field to be reset to its zero value, discarding any
+// value p sets for it. It reports false if HomePartial has no such field.
+func (p *HomePartial) ClearField(name string) bool {
+	switch name {
+	case "Address":
+		p.Address = nil
+	case "City":
+		p.City = nil
+	case "ZipCode":
+		p.ZipCode = nil
+	case "Age":
+		p.Age = nil
+	case "Coords":
+		p.Coords = nil
+	case "Destination":
+		p.Destination = nil
+	default:
+		return false
+	}
+	if !slices.Contains(p.Clear, name) {
+		p.Clear = append(p.Clear, name)
+	}
+	return true
+}
+
+// Merge returns p with the fields other sets applied over it, as applying p and then
+// other with ApplyPartial would: maps are merged key by key and nested partials field
+// by field. The result shares unmerged values with p and other, which must not be
+// modified while it is in use. Fields other clears drop the values p sets for them.
+func (p HomePartial) Merge(other HomePartial) HomePartial {
+	p.Clear = slices.Clone(p.Clear)
+	for _, name := range other.Clear {
+		p.ClearField(name)
+	}
+	if other.Address != nil {
+		p.Address = other.Address
+	}
+	if other.City != nil {
+		p.City = other.City
+	}
+	if other.ZipCode != nil {
+		p.ZipCode = other.ZipCode
+	}
+	if other.Age != nil {
+		p.Age = other.Age
+	}
+	if other.Coords != nil {
+		if p.Coords == nil {
+			p.Coords = other.Coords
+		} else {
+			v := p.Coords.Merge(*other.Coords)
+			p.Coords = &v
+		}
+	}
+	if other.Destination != nil {
+		p.Destination = other.Destination
+	}
+	return p
+}
+
+// IsEmpty reports whether p neither sets nor clears any field. Nested
+// partials that are empty themselves count as unset (see Prune).
+func (p *HomePartial) IsEmpty() bool {
+	if p == nil {
+		return true
+	}
+	if len(p.Clear) > 0 {
+		return false
+	}
+	if p.Address != nil {
+		return false
+	}
+	if p.City != nil {
+		return false
+	}
+	if p.ZipCode != nil {
+		return false
+	}
+	if p.Age != nil {
+		return false
+	}
+	if !p.Coords.IsEmpty() {
+		return false
+	}
+	if p.Destination != nil {
+		return false
+	}
+	return true
+}
+
+// Prune sets the nested partials of p that are empty to nil, at every depth, so that
+// p encodes without empty objects. Applying a pruned partial no longer allocates the
+// structs of nil pointer fields that those partials would have left zero. Partials of
+// fields replaced as a whole are kept, since applying them resets the field.
+func (p *HomePartial) Prune() {
+	if p == nil {
+		return
+	}
+	p.Coords.Prune()
+	if p.Coords.IsEmpty() {
+		p.Coords = nil
+	}
+	p.Destination.Prune()
+}
+
+// UnmarshalJSON decodes p, accepting duration strings such as "1h30m" for time.Duration fields.
+// A null value clears the field (see ClearField).
+func (p *HomePartial) UnmarshalJSON(data []byte) error {
+	type partial HomePartial
+	aux := struct {
+		*partial
+		Age *configPartialDuration `json:"age"`
+	}{partial: (*partial)(p)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if aux.Age != nil {
+		p.Age = (*time.Duration)(aux.Age)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if string(fields["address"]) == "null" {
+		p.ClearField("Address")
+	}
+	if string(fields["city"]) == "null" {
+		p.ClearField("City")
+	}
+	if string(fields["zip_code"]) == "null" {
+		p.ClearField("ZipCode")
+	}
+	if string(fields["age"]) == "null" {
+		p.ClearField("Age")
+	}
+	if string(fields["coords"]) == "null" {
+		p.ClearField("Coords")
+	}
+	if string(fields["destination"]) == "null" {
+		p.ClearField("Destination")
+	}
+	return nil
+}
+
+// MarshalJSON encodes p, writing time.Duration fields as duration strings.
+// Cleared fields are written as null, and fields that are neither set nor cleared
+// are left out.
+// Empty nested partials count as unset, as after Prune.
+func (p HomePartial) MarshalJSON() ([]byte, error) {
+	type partial HomePartial
+	aux := struct {
+		*partial
+		Age *configPartialDuration `json:"age,omitempty"`
+	}{
+		partial: (*partial)(&p),
+		Age:     (*configPartialDuration)(p.Age),
+	}
+	data, err := json.Marshal(aux)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if p.Address == nil {
+		if slices.Contains(p.Clear, "Address") {
+			fields["address"] = json.RawMessage("null")
+		} else {
+			delete(fields, "address")
+		}
+	}
+	if p.City == nil {
+		if slices.Contains(p.Clear, "City") {
+			fields["city"] = json.RawMessage("null")
+		} else {
+			delete(fields, "city")
+		}
+	}
+	if p.ZipCode == nil {
+		if slices.Contains(p.Clear, "ZipCode") {
+			fields["zip_code"] = json.RawMessage("null")
+		} else {
+			delete(fields, "zip_code")
+		}
+	}
+	if p.Age == nil {
+		if slices.Contains(p.Clear, "Age") {
+			fields["age"] = json.RawMessage("null")
+		} else {
+			delete(fields, "age")
+		}
+	}
+	if p.Coords.IsEmpty() {
+		if slices.Contains(p.Clear, "Coords") {
+			fields["coords"] = json.RawMessage("null")
+		} else {
+			delete(fields, "coords")
+		}
+	}
+	if p.Destination == nil {
+		if slices.Contains(p.Clear, "Destination") {
+			fields["destination"] = json.RawMessage("null")
+		} else {
+			delete(fields, "destination")
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// ConfigSparseEntry sets a single leaf field of Config addressed by a dotted
+// path of Go field names (e.g. "Database.Host"). Value must have the field's
+// type, or the pointed-to type for pointer fields.
+type ConfigSparseEntry struct {
+	Path  string
+	Value any
+}
+
+// Overlay returns p with layers merged over it in order, the last one winning, to
+// compose the partials of a defaults, file and environment pipeline before applying
+// them to a Config: defaults.Overlay(file, env). See Merge.
+func (p ConfigPartial) Overlay(layers ...ConfigPartial) ConfigPartial {
+	for _, layer := range layers {
+		p = p.Merge(layer)
+	}
+	return p
+}
+
+// configPartialDuration is a time.Duration that encodes as a duration string and
+// decodes from either a duration string such as "1h30m" or integer nanoseconds.
+type configPartialDuration time.Duration
+
+// MarshalJSON encodes d as a duration string.
+func (d configPartialDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON decodes d from a duration string or integer nanoseconds.
+func (d *configPartialDuration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		var n int64
+		if err := json.Unmarshal(data, &n); err != nil {
+			return fmt.Errorf("duration must be a string such as \"1h30m\" or integer nanoseconds: %w", err)
+		}
+		*d = configPartialDuration(n)
+		return nil
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = configPartialDuration(v)
+	return nil
+}
+
+// configPartialURL is a url.URL that encodes as a URL string and decodes from one.
+type configPartialURL url.URL
+
+// MarshalJSON encodes u as a URL string.
+func (u *configPartialURL) MarshalJSON() ([]byte, error) {
+	return json.Marshal((*url.URL)(u).String())
+}
+
+// UnmarshalJSON decodes u from a URL string, parsed with url.Parse.
+func (u *configPartialURL) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("URL must be a string such as \"https://example.com\": %w", err)
+	}
+	v, err := url.Parse(s)
+	if err != nil {
+		return err
+	}
+	*u = configPartialURL(*v)
+	return nil
 }
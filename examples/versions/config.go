@@ -0,0 +1,29 @@
+package versions
+
+// ConfigV1 is the first format of Config documents.
+type ConfigV1 struct {
+	Version string `json:"version" sudo:"version"`
+	Name    string `json:"name"`
+	Port    int    `json:"port"`
+}
+
+// ConfigV2 moves the port of ConfigV1 into Listen, next to the address to bind.
+//
+//go:generate go run github.com/bobcob7/sudo-gen versions -tests
+type ConfigV2 struct {
+	Version string   `json:"version" sudo:"version"`
+	Name    string   `json:"name"`
+	Listen  Listener `json:"listen"`
+}
+
+// Listener is the address a server listens on.
+type Listener struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
+
+// convertConfigV1ToV2Fields converts the fields of ConfigV1 that changed in ConfigV2.
+func convertConfigV1ToV2Fields(in *ConfigV1, out *ConfigV2) error {
+	out.Listen.Port = in.Port
+	return nil
+}
@@ -1,3 +1,5 @@
 module github.com/bobcob7/sudo-gen
 
 go 1.25.5
+
+require gopkg.in/yaml.v3 v3.0.1
@@ -0,0 +1,282 @@
+// Package canonical implements the canonical JSON code generation subtool.
+package canonical
+
+import (
+	"cmp"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/bobcob7/sudo-gen/internal/codegen"
+)
+
+// Subtool implements the canonical JSON code generator.
+type Subtool struct{}
+
+// Name returns the subtool name.
+func (s *Subtool) Name() string { return "canonical" }
+
+// Description returns the subtool description.
+func (s *Subtool) Description() string {
+	return "Generate MarshalCanonical methods producing byte-stable JSON"
+}
+
+// Doc describes the struct tags the subtool honors and the files it generates.
+func (s *Subtool) Doc() codegen.SubtoolDoc {
+	return codegen.SubtoolDoc{
+		Tags: []codegen.DocEntry{
+			{Name: `json:"name"`, Description: `Key of the field in the canonical encoding; json:"-" leaves it out`},
+			codegen.ExcludeTagDoc(s.Name()),
+		},
+		Files: []codegen.DocEntry{
+			{Name: "{source}_canonical.go", Description: "MarshalCanonical method encoding the struct as canonical JSON"},
+		},
+	}
+}
+
+// Run executes the canonical JSON code generation.
+func (s *Subtool) Run(cfg codegen.GeneratorConfig) error {
+	info, err := cfg.Index.ParseStruct(cfg.SourceDir, cfg.SourceFile, cfg.Source, cfg.TypeName)
+	if err != nil {
+		return fmt.Errorf("parsing struct: %w", err)
+	}
+	selection := codegen.NewFieldSelection(cfg, s.Name())
+	selection.Apply(info)
+	values := codegen.NewValueTypes(cfg.Index, cfg.SourceDir, cfg.ValueTypes)
+	nested, err := cfg.Index.FindNestedStructs(cfg.SourceDir, cfg.Source, info, values)
+	if err != nil {
+		return fmt.Errorf("finding nested structs: %w", err)
+	}
+	// External package structs can't have methods added to them, so they are encoded
+	// with encoding/json
+	allStructs := []*codegen.StructInfo{info}
+	local := map[string]bool{info.Name: true}
+	for _, st := range nested {
+		if st.Package == "" {
+			selection.Apply(st)
+			allStructs = append(allStructs, st)
+			local[st.Name] = true
+		}
+	}
+	cfg, err = codegen.CheckUnsupported(cfg, s.Name(), codegen.UnsupportedFields(allStructs))
+	if err != nil {
+		return err
+	}
+	data := templateData{
+		Package:  cfg.OutputPkg,
+		TypeName: info.Name,
+	}
+	for _, st := range allStructs {
+		data.Structs = append(data.Structs, structData{Name: st.Name, Fields: jsonFields(st.Fields)})
+	}
+	baseName := strings.TrimSuffix(cfg.SourceFile, ".go")
+	gen := codegen.NewTemplateGenerator(cfg, templateFuncs(local))
+	if err := gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_canonical.go"), canonicalTemplate, data); err != nil {
+		return err
+	}
+	if cfg.GenerateTest {
+		return gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_canonical_test.go"), canonicalTestTemplate, data)
+	}
+	return nil
+}
+
+type templateData struct {
+	Package  string
+	TypeName string // Root type, which names the encoder shared by all structs
+	Structs  []structData
+}
+
+type structData struct {
+	Name   string
+	Fields []jsonField // Sorted by key
+}
+
+// jsonField is a struct field with the object key it is encoded under.
+type jsonField struct {
+	codegen.FieldInfo
+	Key    string
+	Prefix string // JSON text written before the value: the opening brace or a comma, then the key
+}
+
+// jsonFields returns the fields encoding/json would marshal, sorted by key.
+// Fields tagged json:"-" are skipped, and omitempty is ignored so that every value
+// of a type has the same keys.
+func jsonFields(fields []codegen.FieldInfo) []jsonField {
+	var out []jsonField
+	for _, f := range fields {
+		name, _, _ := strings.Cut(f.StructTag().Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		out = append(out, jsonField{FieldInfo: f, Key: name})
+	}
+	slices.SortFunc(out, func(a, b jsonField) int { return cmp.Compare(a.Key, b.Key) })
+	for i := range out {
+		out[i].Prefix = "," + quote(out[i].Key) + ":"
+		if i == 0 {
+			out[i].Prefix = "{" + out[i].Prefix[1:]
+		}
+	}
+	return out
+}
+
+// quote returns s as a JSON string literal, escaped as the generated encoder
+// escapes strings.
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20:
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func templateFuncs(local map[string]bool) template.FuncMap {
+	return template.FuncMap{
+		"lower": strings.ToLower,
+		"encodeStmt": func(typ, expr string) string {
+			w := &stmtWriter{local: local}
+			w.write(typ, expr)
+			return strings.TrimSuffix(w.b.String(), "\n")
+		},
+	}
+}
+
+// stmtWriter builds the statements appending a value to the generated encoder.
+type stmtWriter struct {
+	local map[string]bool // Struct types with a generated canonical method
+	depth int             // Nesting of loops, for unique variable names
+	b     strings.Builder
+}
+
+// write adds the statements encoding expr, of Go type typ. Nil pointers encode as
+// null, and nil slices and maps as empty arrays and objects so that they compare
+// equal to empty ones. Types without a known encoding are marshalled with
+// encoding/json, which sorts map keys.
+func (w *stmtWriter) write(typ, expr string) {
+	switch typ {
+	case "string":
+		w.line("w.string(%s)", expr)
+		return
+	case "bool":
+		w.line("w.bool(%s)", expr)
+		return
+	case "int", "int8", "int16", "int32", "int64", "rune", "time.Duration":
+		w.line("w.int(int64(%s))", expr)
+		return
+	case "uint", "uint8", "uint16", "uint32", "uint64", "uintptr", "byte":
+		w.line("w.uint(uint64(%s))", expr)
+		return
+	case "float32":
+		w.line("w.float(float64(%s), 32)", expr)
+		return
+	case "float64":
+		w.line("w.float(%s, 64)", expr)
+		return
+	case "time.Time":
+		w.line("w.time(%s)", expr)
+		return
+	case "[]byte":
+		w.line("w.bytes(%s)", expr)
+		return
+	}
+	if w.local[strings.TrimPrefix(typ, "*")] {
+		// Generated canonical methods handle nil receivers themselves
+		w.line("%s.canonical(w)", expr)
+		return
+	}
+	if elem, ok := strings.CutPrefix(typ, "*"); ok {
+		w.line("if %s == nil {", expr)
+		w.line("w.null()")
+		w.line("} else {")
+		w.write(elem, "*"+expr)
+		w.line("}")
+		return
+	}
+	if elem, ok := strings.CutPrefix(typ, "[]"); ok {
+		i := w.variable("i")
+		w.line("w.b = append(w.b, '[')")
+		w.line("for %s := range %s {", i, expr)
+		w.depth++
+		w.line("if %s > 0 {", i)
+		w.line("w.b = append(w.b, ',')")
+		w.line("}")
+		w.write(elem, expr+"["+i+"]")
+		w.depth--
+		w.line("}")
+		w.line("w.b = append(w.b, ']')")
+		return
+	}
+	if key, val, ok := splitMapType(typ); ok && key == "string" {
+		i, k, v := w.variable("i"), w.variable("k"), w.variable("v")
+		w.line("w.b = append(w.b, '{')")
+		w.line("for %s, %s := range slices.Sorted(maps.Keys(%s)) {", i, k, expr)
+		w.depth++
+		w.line("if %s > 0 {", i)
+		w.line("w.b = append(w.b, ',')")
+		w.line("}")
+		w.line("w.string(%s)", k)
+		w.line("w.b = append(w.b, ':')")
+		// Map values aren't addressable, so they are copied for struct canonical methods
+		w.line("%s := %s[%s]", v, expr, k)
+		w.write(val, v)
+		w.depth--
+		w.line("}")
+		w.line("w.b = append(w.b, '}')")
+		return
+	}
+	w.line("w.value(%s)", expr)
+}
+
+func (w *stmtWriter) line(format string, args ...any) {
+	fmt.Fprintf(&w.b, format+"\n", args...)
+}
+
+// variable returns a loop variable name unique to the current nesting.
+func (w *stmtWriter) variable(name string) string {
+	if w.depth == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s%d", name, w.depth)
+}
+
+// splitMapType splits map[K]V into K and V.
+func splitMapType(typ string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(typ, "map[")
+	if !ok {
+		return "", "", false
+	}
+	depth := 1
+	for i, r := range rest {
+		switch r {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return rest[:i], rest[i+1:], true
+			}
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,201 @@
+package canonical
+
+const canonicalTemplate = `// Code generated by sudo-gen canonical. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+{{range .Structs}}
+// {{method "MarshalCanonical"}} returns the canonical JSON encoding of c, for signing and for
+// comparing values across services. The output is byte-stable: object keys are
+// sorted, every field is written regardless of omitempty, floats use the shortest
+// representation that round-trips, and times are RFC 3339 in UTC.
+func (c *{{.Name}}) {{method "MarshalCanonical"}}() []byte {
+	w := &{{lower $.TypeName}}CanonicalEncoder{}
+	c.canonical(w)
+	return w.b
+}
+
+// canonical appends the canonical JSON encoding of c to w.
+func (c *{{.Name}}) canonical(w *{{lower $.TypeName}}CanonicalEncoder) {
+	if c == nil {
+		w.null()
+		return
+	}
+{{- range .Fields}}
+	w.b = append(w.b, {{printf "%q" .Prefix}}...)
+	{{encodeStmt .Type (print "c." .Name)}}
+{{- end}}
+{{- if .Fields}}
+	w.b = append(w.b, '}')
+{{- else}}
+	w.b = append(w.b, "{}"...)
+{{- end}}
+}
+{{end}}
+// {{lower .TypeName}}CanonicalEncoder appends values to a buffer as canonical JSON.
+type {{lower .TypeName}}CanonicalEncoder struct {
+	b []byte
+}
+
+func (w *{{lower .TypeName}}CanonicalEncoder) null() {
+	w.b = append(w.b, "null"...)
+}
+
+func (w *{{lower .TypeName}}CanonicalEncoder) bool(v bool) {
+	w.b = strconv.AppendBool(w.b, v)
+}
+
+func (w *{{lower .TypeName}}CanonicalEncoder) int(v int64) {
+	w.b = strconv.AppendInt(w.b, v, 10)
+}
+
+func (w *{{lower .TypeName}}CanonicalEncoder) uint(v uint64) {
+	w.b = strconv.AppendUint(w.b, v, 10)
+}
+
+// float writes f as encoding/json does, except that -0 is written as 0. NaN and
+// infinities have no JSON representation and are written as null.
+func (w *{{lower .TypeName}}CanonicalEncoder) float(f float64, bits int) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		w.null()
+		return
+	}
+	if f == 0 {
+		f = 0
+	}
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	w.b = strconv.AppendFloat(w.b, f, format, -1, bits)
+	if format == 'e' {
+		// Shorten e-09 to e-9
+		if n := len(w.b); n >= 4 && w.b[n-4] == 'e' && w.b[n-3] == '-' && w.b[n-2] == '0' {
+			w.b[n-2] = w.b[n-1]
+			w.b = w.b[:n-1]
+		}
+	}
+}
+
+// string writes s as a JSON string, escaping only quotes, backslashes and control
+// characters. Invalid UTF-8 is replaced with U+FFFD.
+func (w *{{lower .TypeName}}CanonicalEncoder) string(s string) {
+	const hex = "0123456789abcdef"
+	w.b = append(w.b, '"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			w.b = append(w.b, '\\', byte(r))
+		case r == '\n':
+			w.b = append(w.b, '\\', 'n')
+		case r == '\r':
+			w.b = append(w.b, '\\', 'r')
+		case r == '\t':
+			w.b = append(w.b, '\\', 't')
+		case r < 0x20:
+			w.b = append(w.b, '\\', 'u', '0', '0', hex[r>>4], hex[r&0xf])
+		default:
+			w.b = utf8.AppendRune(w.b, r)
+		}
+	}
+	w.b = append(w.b, '"')
+}
+
+// time writes t as an RFC 3339 string in UTC, so that times that are Equal encode
+// the same.
+func (w *{{lower .TypeName}}CanonicalEncoder) time(t time.Time) {
+	w.string(t.UTC().Format(time.RFC3339Nano))
+}
+
+func (w *{{lower .TypeName}}CanonicalEncoder) bytes(v []byte) {
+	w.string(base64.StdEncoding.EncodeToString(v))
+}
+
+// value writes a value of a type without a known encoding with encoding/json, which
+// sorts map keys. Values encoding/json can't marshal are written as null.
+func (w *{{lower .TypeName}}CanonicalEncoder) value(v any) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		w.null()
+		return
+	}
+	w.b = append(w.b, bytes.TrimSuffix(buf.Bytes(), []byte("\n"))...)
+}
+`
+
+const canonicalTestTemplate = `// Code generated by sudo-gen canonical. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"encoding/json"
+	"testing"
+)
+{{range .Structs}}
+func Test{{.Name}}MarshalCanonicalEmpty(t *testing.T) {
+	got := (&{{.Name}}{}).{{method "MarshalCanonical"}}()
+	if !json.Valid(got) {
+		t.Fatalf("invalid JSON: %s", got)
+	}
+	if string(got) != string((&{{.Name}}{}).{{method "MarshalCanonical"}}()) {
+		t.Error("two empty structs should encode the same")
+	}
+	var nilValue *{{.Name}}
+	if got := string(nilValue.{{method "MarshalCanonical"}}()); got != "null" {
+		t.Errorf("nil encoded as %s, want null", got)
+	}
+}
+{{- $struct := .}}
+{{- range .Fields}}
+{{- if eq .Type "string"}}
+
+func Test{{$struct.Name}}MarshalCanonical{{.Name}}(t *testing.T) {
+	got := (&{{$struct.Name}}{ {{.Name}}: "a\"b\n"}).{{method "MarshalCanonical"}}()
+	var decoded {{$struct.Name}}
+	if err := json.Unmarshal(got, &decoded); err != nil {
+		t.Fatalf("unmarshalling %s: %v", got, err)
+	}
+	if decoded.{{.Name}} != "a\"b\n" {
+		t.Errorf("{{.Name}} decoded as %q", decoded.{{.Name}})
+	}
+	if string(got) == string((&{{$struct.Name}}{ {{.Name}}: "b"}).{{method "MarshalCanonical"}}()) {
+		t.Error("expected a different encoding after changing {{.Name}}")
+	}
+}
+{{- break}}
+{{- end}}
+{{- end}}
+{{- range .Fields}}
+{{- if eq .Type "map[string]string"}}
+
+func Test{{$struct.Name}}MarshalCanonical{{.Name}}Order(t *testing.T) {
+	a := &{{$struct.Name}}{ {{.Name}}: map[string]string{}}
+	b := &{{$struct.Name}}{ {{.Name}}: map[string]string{}}
+	for i := range 20 {
+		a.{{.Name}}[string(rune('a'+i))] = "value"
+		b.{{.Name}}[string(rune('a'+19-i))] = "value"
+	}
+	if string(a.{{method "MarshalCanonical"}}()) != string(b.{{method "MarshalCanonical"}}()) {
+		t.Error("maps with the same entries should encode the same")
+	}
+}
+{{- break}}
+{{- end}}
+{{- end}}
+{{end}}
+`
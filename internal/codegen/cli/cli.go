@@ -0,0 +1,124 @@
+// Package cli implements the cli code generation subtool.
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/bobcob7/sudo-gen/internal/codegen"
+)
+
+// Subtool implements the CLI command code generator.
+type Subtool struct{}
+
+// Name returns the subtool name.
+func (s *Subtool) Name() string { return "cli" }
+
+// Description returns the subtool description.
+func (s *Subtool) Description() string {
+	return "Generate cobra config dump, validate and explain commands for the layer broker"
+}
+
+// Doc describes the struct tags the subtool honors and the files it generates.
+func (s *Subtool) Doc() codegen.SubtoolDoc {
+	return codegen.SubtoolDoc{
+		Files: []codegen.DocEntry{
+			{Name: "{source}_cli.go", Description: "New{Type}Command, a cobra command with the dump, validate and explain subcommands"},
+		},
+	}
+}
+
+// DefineFlags defines the flags of the cli subcommand.
+func (s *Subtool) DefineFlags(fs *flag.FlagSet, cfg *codegen.GeneratorConfig) {
+	fs.StringVar(&cfg.ValidateMethod, "validate", "", "Method of the type returning an error that config validate calls (default: Validate, if the type declares it)")
+}
+
+// Run executes the cli code generation. The generated commands build on the
+// layerbroker and loader output for the same type; explain is generated when the
+// broker has the Explain method of layerbroker -provenance, and dump prints the
+// Redacted copy of copy -redact when the type holds secrets, which fails if the
+// type doesn't declare Redacted yet.
+func (s *Subtool) Run(cfg codegen.GeneratorConfig) error {
+	info, err := cfg.Index.ParseStruct(cfg.SourceDir, cfg.SourceFile, cfg.Source, cfg.TypeName)
+	if err != nil {
+		return fmt.Errorf("parsing struct: %w", err)
+	}
+	nested, err := cfg.Index.FindNestedStructs(cfg.SourceDir, cfg.Source, info, codegen.NewValueTypes(cfg.Index, cfg.SourceDir, cfg.ValueTypes))
+	if err != nil {
+		return fmt.Errorf("finding nested structs: %w", err)
+	}
+	methods := cfg.Index.Methods(cfg.SourceDir, info.Name)
+	_, explain := cfg.Index.Methods(cfg.SourceDir, info.Name+"LayerBroker")["Explain"]
+	data := templateData{
+		Package:  cfg.OutputPkg,
+		TypeName: info.Name,
+		Validate: cfg.ValidateMethod,
+		Explain:  explain,
+		Redact:   holdsSecrets(append([]*codegen.StructInfo{info}, nested...)),
+	}
+	if _, ok := methods[cfg.Method("Redacted")]; data.Redact && !ok {
+		return fmt.Errorf("%s holds sudo:\"secret\" fields, which config dump redacts with %s; generate it with sudo-gen copy -redact", info.Name, cfg.Method("Redacted"))
+	}
+	if data.Validate == "" {
+		if _, ok := methods["Validate"]; ok {
+			data.Validate = "Validate"
+		}
+	}
+	baseName := strings.TrimSuffix(cfg.SourceFile, ".go")
+	gen := codegen.NewTemplateGenerator(cfg, templateFuncs())
+	if err := gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_cli.go"), cliTemplate, data); err != nil {
+		return err
+	}
+	if cfg.GenerateTest {
+		return gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_cli_test.go"), cliTestTemplate, data)
+	}
+	return nil
+}
+
+type templateData struct {
+	Package  string
+	TypeName string
+	Validate string // Method config validate checks the merged config with, if any
+	Explain  bool   // The broker has Explain, so the explain command is generated
+	Redact   bool   // The type holds secrets, so dump prints the Redacted copy
+}
+
+// holdsSecrets reports whether any of the local structs has a field tagged sudo:"secret".
+func holdsSecrets(structs []*codegen.StructInfo) bool {
+	for _, st := range structs {
+		if st.Package != "" {
+			continue
+		}
+		for _, f := range st.Fields {
+			if f.Secret {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"capitalize": capitalize,
+		"ident":      ident,
+		"lower":      strings.ToLower,
+	}
+}
+
+func capitalize(name string) string {
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// ident builds a generated function name such as NewConfigCommand, keeping it
+// unexported (newConfigCommand) when the type is unexported.
+func ident(verb, typeName, suffix string) string {
+	if ast.IsExported(typeName) {
+		return capitalize(verb) + typeName + suffix
+	}
+	return verb + capitalize(typeName) + suffix
+}
@@ -0,0 +1,191 @@
+package cli
+
+const cliTemplate = `// Code generated by sudo-gen cli. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"encoding/json"
+	"fmt"
+{{- if .Explain}}
+	"slices"
+	"strings"
+{{- end}}
+
+	"github.com/spf13/cobra"
+)
+
+// {{ident "new" .TypeName "Command"}} returns a "config" command for the operators of a service, with
+// the subcommands:
+//
+//	config dump                 print the merged {{.TypeName}} as JSON{{if .Redact}}, with secrets redacted{{end}}
+//	config validate [file...]   check files strictly and the merged {{.TypeName}}
+{{- if .Explain}}
+//	config explain [path...]    print the layer that set each field
+{{- end}}
+//
+// newBroker builds the broker the service runs with, for instance with the
+// Provide{{.TypeName}}LayerBroker provider and the service's file and environment:
+//
+//	root.AddCommand({{ident "new" .TypeName "Command"}}(func() (*{{.TypeName}}LayerBroker, error) {
+//		return Provide{{.TypeName}}LayerBroker({{.TypeName}}File(path), loadEnv)
+//	}))
+func {{ident "new" .TypeName "Command"}}(newBroker func() (*{{.TypeName}}LayerBroker, error)) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect and check the configuration",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "dump",
+		Short: "Print the merged configuration as JSON",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			broker, err := newBroker()
+			if err != nil {
+				return err
+			}
+{{- if .Redact}}
+			data, err := json.MarshalIndent(broker.Get().{{method "Redacted"}}(), "", "  ")
+{{- else}}
+			data, err := json.MarshalIndent(broker.Get(), "", "  ")
+{{- end}}
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintln(cmd.OutOrStdout(), string(data))
+			return err
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "validate [file...]",
+		Short: "Check config files strictly, then the merged configuration",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, path := range args {
+				if _, err := {{ident "load" (partial .TypeName) "File"}}(path, true); err != nil {
+					return err
+				}
+			}
+			broker, err := newBroker()
+			if err != nil {
+				return err
+			}
+{{- if .Validate}}
+			if err := broker.Get().{{.Validate}}(); err != nil {
+				return err
+			}
+{{- else}}
+			_ = broker
+{{- end}}
+			_, err = fmt.Fprintln(cmd.OutOrStdout(), "configuration is valid")
+			return err
+		},
+	})
+{{- if .Explain}}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "explain [path...]",
+		Short: "Print the layer that set each field, for the fields under the given paths",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			broker, err := newBroker()
+			if err != nil {
+				return err
+			}
+			sources := broker.Explain()
+			paths := make([]string, 0, len(sources))
+			for path := range sources {
+				if len(args) == 0 || slices.ContainsFunc(args, func(prefix string) bool {
+					return path == prefix || strings.HasPrefix(path, prefix+".")
+				}) {
+					paths = append(paths, path)
+				}
+			}
+			slices.Sort(paths)
+			for _, path := range paths {
+				if _, err := fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s\n", path, sources[path]); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+{{- end}}
+	return cmd
+}
+`
+
+const cliTestTemplate = `// Code generated by sudo-gen cli. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func run{{capitalize .TypeName}}Command(t *testing.T, newBroker func() (*{{.TypeName}}LayerBroker, error), args ...string) (string, error) {
+	t.Helper()
+	cmd := {{ident "new" .TypeName "Command"}}(newBroker)
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs(args)
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func Test{{capitalize .TypeName}}CommandDump(t *testing.T) {
+	newBroker := func() (*{{.TypeName}}LayerBroker, error) {
+		return {{ident "new" .TypeName "LayerBroker"}}(&{{.TypeName}}{}), nil
+	}
+	out, err := run{{capitalize .TypeName}}Command(t, newBroker, "dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c {{.TypeName}}
+	if err := json.Unmarshal([]byte(out), &c); err != nil {
+		t.Errorf("dump printed %q, not a {{.TypeName}}: %v", out, err)
+	}
+}
+
+func Test{{capitalize .TypeName}}CommandValidate(t *testing.T) {
+	newBroker := func() (*{{.TypeName}}LayerBroker, error) {
+		return {{ident "new" .TypeName "LayerBroker"}}(&{{.TypeName}}{}), nil
+	}
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.json")
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(valid, []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(invalid, []byte(` + "`" + `{"sudoGenUnknownField": 1}` + "`" + `), 0o600); err != nil {
+		t.Fatal(err)
+	}
+{{- if not .Validate}}
+	if _, err := run{{capitalize .TypeName}}Command(t, newBroker, "validate", valid); err != nil {
+		t.Errorf("validate %s: %v", valid, err)
+	}
+{{- end}}
+	if _, err := run{{capitalize .TypeName}}Command(t, newBroker, "validate", valid, invalid); err == nil {
+		t.Errorf("validate %s: expected an error for an unknown field", invalid)
+	}
+	errBroker := errors.New("broken broker")
+	failing := func() (*{{.TypeName}}LayerBroker, error) { return nil, errBroker }
+	if _, err := run{{capitalize .TypeName}}Command(t, failing, "validate"); !errors.Is(err, errBroker) {
+		t.Errorf("err = %v, want %v", err, errBroker)
+	}
+}
+{{- if .Explain}}
+
+func Test{{capitalize .TypeName}}CommandExplain(t *testing.T) {
+	newBroker := func() (*{{.TypeName}}LayerBroker, error) {
+		return {{ident "new" .TypeName "LayerBroker"}}(&{{.TypeName}}{}), nil
+	}
+	if _, err := run{{capitalize .TypeName}}Command(t, newBroker, "explain"); err != nil {
+		t.Fatal(err)
+	}
+}
+{{- end}}
+`
@@ -0,0 +1,129 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CloneRecipe is how copy and equals handle a type whose values share memory when
+// assigned, such as *big.Int, or can't be compared with ==, such as *regexp.Regexp.
+// Clone and Equal are Go expressions in which $x, $a and $b stand for the values and
+// $pkg for the name the package of the type is imported as. Either may be empty, to
+// handle the type like any other for that generator.
+type CloneRecipe struct {
+	Clone string // Expression returning a copy of $x sharing no memory with it
+	Equal string // Condition that $a and $b are equal
+	// Nilable types are pointers or slices: nil values are assigned and compared
+	// without calling Clone or Equal, which may assume non-nil values.
+	Nilable bool
+}
+
+// builtinCloneRecipes are the CloneRecipes of standard library types, by type
+// qualified with its import path.
+var builtinCloneRecipes = map[string]CloneRecipe{
+	"*math/big.Int":   {Clone: "new($pkg.Int).Set($x)", Equal: "$a.Cmp($b) == 0", Nilable: true},
+	"*math/big.Rat":   {Clone: "new($pkg.Rat).Set($x)", Equal: "$a.Cmp($b) == 0", Nilable: true},
+	"*math/big.Float": {Clone: "new($pkg.Float).Copy($x)", Equal: "$a.Cmp($b) == 0", Nilable: true},
+	"net.IP":          {Clone: "slices.Clone($x)", Equal: "$a.Equal($b)", Nilable: true},
+	// URLs are copied like other pointers; User is immutable, so it may be shared
+	"*net/url.URL": {Equal: "$a.String() == $b.String()", Nilable: true},
+	// A Regexp is immutable and safe for concurrent use, so copies share it
+	"*regexp.Regexp": {Clone: "$x", Equal: "$a.String() == $b.String()", Nilable: true},
+}
+
+// CloneTypes looks up the CloneRecipes of field types: those of the standard library
+// and those given with -clone-funcs and -equal-funcs. A nil *CloneTypes knows the
+// standard library types only.
+type CloneTypes struct {
+	recipes map[string]CloneRecipe // By type as written in flags, or qualified with its import path
+}
+
+// NewCloneTypes returns the CloneTypes with the functions of cloneFuncs and
+// equalFuncs. Each entry is Type=Func, where Type is written like the entries of
+// -value-types, with a * for pointers, and Func is a function of the package the
+// code is generated into: func(T) T for cloneFuncs and func(a, b T) bool for
+// equalFuncs. Functions given for pointer types are not called with nil pointers.
+func NewCloneTypes(cloneFuncs, equalFuncs []string) (*CloneTypes, error) {
+	c := &CloneTypes{recipes: make(map[string]CloneRecipe)}
+	for _, entry := range cloneFuncs {
+		typ, fn, err := splitFuncEntry(entry)
+		if err != nil {
+			return nil, fmt.Errorf("-clone-funcs: %w", err)
+		}
+		r := c.recipes[typ]
+		r.Clone, r.Nilable = fn+"($x)", strings.HasPrefix(typ, "*")
+		c.recipes[typ] = r
+	}
+	for _, entry := range equalFuncs {
+		typ, fn, err := splitFuncEntry(entry)
+		if err != nil {
+			return nil, fmt.Errorf("-equal-funcs: %w", err)
+		}
+		r := c.recipes[typ]
+		r.Equal, r.Nilable = fn+"($a, $b)", strings.HasPrefix(typ, "*")
+		c.recipes[typ] = r
+	}
+	return c, nil
+}
+
+func splitFuncEntry(entry string) (typ, fn string, err error) {
+	typ, fn, ok := strings.Cut(entry, "=")
+	typ, fn = strings.TrimSpace(typ), strings.TrimSpace(fn)
+	if !ok || strings.TrimPrefix(typ, "*") == "" || fn == "" {
+		return "", "", fmt.Errorf("%q is not of the form Type=Func", entry)
+	}
+	return typ, fn, nil
+}
+
+// Lookup returns the CloneRecipe of the type typ, such as "*big.Int", as written in a
+// file with the given imports, or nil if it has none. $pkg is resolved in the
+// returned recipe.
+func (c *CloneTypes) Lookup(imports []ImportInfo, typ string) *CloneRecipe {
+	ptr, name := "", typ
+	if strings.HasPrefix(name, "*") {
+		ptr, name = "*", name[1:]
+	}
+	if strings.ContainsAny(name, "[]*") {
+		return nil
+	}
+	var recipes map[string]CloneRecipe
+	if c != nil {
+		recipes = c.recipes
+	}
+	pkg, typeName, qualified := strings.Cut(name, ".")
+	if !qualified {
+		if r, ok := recipes[typ]; ok {
+			return &r
+		}
+		return nil
+	}
+	r, ok := recipes[typ]
+	if path := importPathFor(imports, pkg); !ok && path != "" {
+		if r, ok = recipes[ptr+path+"."+typeName]; !ok {
+			r, ok = builtinCloneRecipes[ptr+path+"."+typeName]
+		}
+	}
+	if !ok {
+		return nil
+	}
+	r.Clone = strings.ReplaceAll(r.Clone, "$pkg", pkg)
+	r.Equal = strings.ReplaceAll(r.Equal, "$pkg", pkg)
+	return &r
+}
+
+// Apply sets the Recipe of the fields of info that have one.
+func (c *CloneTypes) Apply(info *StructInfo) {
+	for i := range info.Fields {
+		info.Fields[i].Recipe = c.Lookup(info.Imports, info.Fields[i].Type)
+	}
+}
+
+// CloneOf returns the expression cloning x.
+func (r *CloneRecipe) CloneOf(x string) string {
+	return strings.ReplaceAll(r.Clone, "$x", x)
+}
+
+// EqualOf returns the condition that a and b are equal.
+func (r *CloneRecipe) EqualOf(a, b string) string {
+	return strings.NewReplacer("$a", a, "$b", b).Replace(r.Equal)
+}
@@ -0,0 +1,109 @@
+// Package context implements the context code generation subtool.
+package context
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/bobcob7/sudo-gen/internal/codegen"
+)
+
+// Subtool implements the context code generator.
+type Subtool struct{}
+
+// Name returns the subtool name.
+func (s *Subtool) Name() string { return "context" }
+
+// Description returns the subtool description.
+func (s *Subtool) Description() string {
+	return "Generate helpers carrying a config and per-request overrides in a context"
+}
+
+// Doc describes the struct tags the subtool honors and the files it generates.
+func (s *Subtool) Doc() codegen.SubtoolDoc {
+	return codegen.SubtoolDoc{
+		Files: []codegen.DocEntry{
+			{Name: "{source}_context.go", Description: "New{Type}Context, {Type}FromContext and With{Type}Overrides"},
+			{Name: "{source}_context_tenant.go", Description: "{Type}TenantMiddleware (with -tenant)"},
+		},
+	}
+}
+
+// DefineFlags defines the flags of the context subcommand.
+func (s *Subtool) DefineFlags(fs *flag.FlagSet, cfg *codegen.GeneratorConfig) {
+	fs.BoolVar(&cfg.GenerateTenant, "tenant", false, "Also generate HTTP middleware resolving per-tenant overrides into the request context")
+}
+
+// Run executes the context code generation. The generated helpers build on the
+// merge and copy output for the same type.
+func (s *Subtool) Run(cfg codegen.GeneratorConfig) error {
+	info, err := cfg.Index.ParseStruct(cfg.SourceDir, cfg.SourceFile, cfg.Source, cfg.TypeName)
+	if err != nil {
+		return fmt.Errorf("parsing struct: %w", err)
+	}
+	data := templateData{
+		Package:     cfg.OutputPkg,
+		TypeName:    info.Name,
+		StringField: firstStringField(info),
+	}
+	baseName := strings.TrimSuffix(cfg.SourceFile, ".go")
+	gen := codegen.NewTemplateGenerator(cfg, templateFuncs())
+	if err := gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_context.go"), contextTemplate, data); err != nil {
+		return err
+	}
+	if cfg.GenerateTest {
+		if err := gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_context_test.go"), contextTestTemplate, data); err != nil {
+			return err
+		}
+	}
+	if !cfg.GenerateTenant {
+		return nil
+	}
+	if err := gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_context_tenant.go"), tenantTemplate, data); err != nil {
+		return err
+	}
+	if cfg.GenerateTest {
+		return gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_context_tenant_test.go"), tenantTestTemplate, data)
+	}
+	return nil
+}
+
+type templateData struct {
+	Package     string
+	TypeName    string
+	StringField string // First plain string field, used by generated tests
+}
+
+// firstStringField returns the first plain string field of info, used by test examples.
+func firstStringField(info *codegen.StructInfo) string {
+	for _, f := range info.Fields {
+		if f.TypeName == "string" && !f.IsPointer && !f.IsSlice && !f.IsMap {
+			return f.Name
+		}
+	}
+	return ""
+}
+
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"lower": strings.ToLower,
+		"ident": ident,
+	}
+}
+
+func capitalize(name string) string {
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// ident builds a generated function name such as NewConfigContext, keeping it
+// unexported (newConfigContext) when the type is unexported.
+func ident(verb, typeName, suffix string) string {
+	if ast.IsExported(typeName) {
+		return capitalize(verb) + typeName + suffix
+	}
+	return verb + capitalize(typeName) + suffix
+}
@@ -0,0 +1,277 @@
+package context
+
+const contextTemplate = `// Code generated by sudo-gen context. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+	"slices"
+)
+
+// {{lower .TypeName}}ContextKey is the context key of {{lower .TypeName}}ContextValue. Being
+// unexported, it can't collide with keys of other packages.
+type {{lower .TypeName}}ContextKey struct{}
+
+// {{lower .TypeName}}ContextValue is the {{.TypeName}} carried by a context: a base config with
+// the overrides of WithOverrides applied over it, lowest first.
+type {{lower .TypeName}}ContextValue struct {
+	base      *{{.TypeName}}
+	overrides []*{{partial .TypeName}}
+	merged    *{{.TypeName}} // base with overrides applied
+}
+
+// {{ident "new" .TypeName "Context"}} returns a copy of ctx carrying cfg, typically the
+// current snapshot of a broker injected by middleware. Overrides already in ctx are
+// applied over cfg, so the order in which middleware runs doesn't matter. cfg must
+// not be modified afterwards.
+func {{ident "new" .TypeName "Context"}}(ctx context.Context, cfg *{{.TypeName}}) context.Context {
+	v := &{{lower .TypeName}}ContextValue{base: cfg}
+	if parent, ok := ctx.Value({{lower .TypeName}}ContextKey{}).(*{{lower .TypeName}}ContextValue); ok {
+		v.overrides = parent.overrides
+	}
+	v.merge()
+	return context.WithValue(ctx, {{lower .TypeName}}ContextKey{}, v)
+}
+
+// {{ident "with" .TypeName "Overrides"}} returns a copy of ctx whose config has p applied
+// over the config of ctx, for request-scoped settings such as a tenant's limits.
+// Overrides stack: the most recent one wins. A nil p returns ctx unchanged. p must
+// not be modified afterwards.
+func {{ident "with" .TypeName "Overrides"}}(ctx context.Context, p *{{partial .TypeName}}) context.Context {
+	if p == nil {
+		return ctx
+	}
+	v := &{{lower .TypeName}}ContextValue{}
+	if parent, ok := ctx.Value({{lower .TypeName}}ContextKey{}).(*{{lower .TypeName}}ContextValue); ok {
+		v.base = parent.base
+		v.overrides = slices.Clip(parent.overrides)
+	}
+	v.overrides = append(v.overrides, p)
+	v.merge()
+	return context.WithValue(ctx, {{lower .TypeName}}ContextKey{}, v)
+}
+
+// {{.TypeName}}FromContext returns the config carried by ctx, with its overrides
+// applied, and whether ctx carries one. Overrides without a base config apply over
+// an empty {{.TypeName}}. The config is shared by every caller and must not be modified;
+// {{method "Copy"}} it first.
+func {{.TypeName}}FromContext(ctx context.Context) (*{{.TypeName}}, bool) {
+	v, ok := ctx.Value({{lower .TypeName}}ContextKey{}).(*{{lower .TypeName}}ContextValue)
+	if !ok {
+		return nil, false
+	}
+	return v.merged, true
+}
+
+// merge computes v.merged from v.base and v.overrides, copying the base only if
+// there is something to apply.
+func (v *{{lower .TypeName}}ContextValue) merge() {
+	v.merged = v.base
+	if len(v.overrides) == 0 && v.merged != nil {
+		return
+	}
+	if v.merged == nil {
+		v.merged = &{{.TypeName}}{}
+	} else {
+		v.merged = v.merged.{{method "Copy"}}()
+	}
+	for _, p := range v.overrides {
+		v.merged.{{method "ApplyPartial"}}(p)
+	}
+}
+`
+
+const contextTestTemplate = `// Code generated by sudo-gen context. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+	"testing"
+)
+
+func Test{{.TypeName}}FromContextEmpty(t *testing.T) {
+	if cfg, ok := {{.TypeName}}FromContext(context.Background()); ok || cfg != nil {
+		t.Errorf("expected no config, got %v, %v", cfg, ok)
+	}
+}
+
+func Test{{.TypeName}}ContextRoundTrip(t *testing.T) {
+	cfg := &{{.TypeName}}{}
+	ctx := {{ident "new" .TypeName "Context"}}(context.Background(), cfg)
+	got, ok := {{.TypeName}}FromContext(ctx)
+	if !ok || got != cfg {
+		t.Errorf("expected the config passed to {{ident "new" .TypeName "Context"}}, got %v, %v", got, ok)
+	}
+	if ctx := {{ident "with" .TypeName "Overrides"}}(ctx, nil); ctx.Value({{lower .TypeName}}ContextKey{}) == nil {
+		t.Error("nil overrides should keep the config")
+	}
+}
+{{- if .StringField}}
+
+func Test{{.TypeName}}ContextOverrides(t *testing.T) {
+	base := &{{.TypeName}}{ {{.StringField}}: "base"}
+	ctx := {{ident "new" .TypeName "Context"}}(context.Background(), base)
+	first := "first"
+	ctx = {{ident "with" .TypeName "Overrides"}}(ctx, &{{partial .TypeName}}{ {{.StringField}}: &first})
+	child := "child"
+	childCtx := {{ident "with" .TypeName "Overrides"}}(ctx, &{{partial .TypeName}}{ {{.StringField}}: &child})
+	if cfg, _ := {{.TypeName}}FromContext(ctx); cfg.{{.StringField}} != "first" {
+		t.Errorf("expected first, got %q", cfg.{{.StringField}})
+	}
+	if cfg, _ := {{.TypeName}}FromContext(childCtx); cfg.{{.StringField}} != "child" {
+		t.Errorf("expected the most recent override to win, got %q", cfg.{{.StringField}})
+	}
+	if base.{{.StringField}} != "base" {
+		t.Errorf("overrides modified the base config: %q", base.{{.StringField}})
+	}
+}
+
+func Test{{.TypeName}}ContextOverridesBeforeBase(t *testing.T) {
+	override := "override"
+	ctx := {{ident "with" .TypeName "Overrides"}}(context.Background(), &{{partial .TypeName}}{ {{.StringField}}: &override})
+	if cfg, ok := {{.TypeName}}FromContext(ctx); !ok || cfg.{{.StringField}} != "override" {
+		t.Errorf("expected overrides over an empty config, got %v, %v", cfg, ok)
+	}
+	ctx = {{ident "new" .TypeName "Context"}}(ctx, &{{.TypeName}}{ {{.StringField}}: "base"})
+	if cfg, _ := {{.TypeName}}FromContext(ctx); cfg.{{.StringField}} != "override" {
+		t.Errorf("expected earlier overrides to apply over the new base, got %q", cfg.{{.StringField}})
+	}
+}
+{{- end}}
+`
+
+const tenantTemplate = `// Code generated by sudo-gen context. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"net/http"
+)
+
+// {{.TypeName}}Source provides snapshots of the current config. {{.TypeName}}LayerBroker and
+// {{.TypeName}}Manager implement it.
+type {{.TypeName}}Source interface {
+	Get() *{{.TypeName}}
+}
+
+// {{.TypeName}}TenantResolver returns the overrides of the tenant a request is made for, or
+// nil if the tenant has none.
+type {{.TypeName}}TenantResolver interface {
+	ResolveTenant(r *http.Request) (*{{partial .TypeName}}, error)
+}
+
+// {{.TypeName}}TenantResolverFunc adapts a function to a {{.TypeName}}TenantResolver.
+type {{.TypeName}}TenantResolverFunc func(r *http.Request) (*{{partial .TypeName}}, error)
+
+// ResolveTenant calls f(r).
+func (f {{.TypeName}}TenantResolverFunc) ResolveTenant(r *http.Request) (*{{partial .TypeName}}, error) {
+	return f(r)
+}
+
+// {{.TypeName}}TenantMiddleware returns middleware that puts the config of each request's
+// tenant in its context: a snapshot of source with the tenant's overrides from
+// resolver layered over it (see {{ident "with" .TypeName "Overrides"}}). Handlers read it with
+// {{.TypeName}}FromContext. If resolver fails, onError is called instead of the next
+// handler; a nil onError responds with 500 Internal Server Error.
+func {{.TypeName}}TenantMiddleware(source {{.TypeName}}Source, resolver {{.TypeName}}TenantResolver, onError func(http.ResponseWriter, *http.Request, error)) func(http.Handler) http.Handler {
+	if onError == nil {
+		onError = func(w http.ResponseWriter, _ *http.Request, _ error) {
+			http.Error(w, "resolving tenant config", http.StatusInternalServerError)
+		}
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, err := resolver.ResolveTenant(r)
+			if err != nil {
+				onError(w, r, err)
+				return
+			}
+			ctx := {{ident "new" .TypeName "Context"}}(r.Context(), source.Get())
+			ctx = {{ident "with" .TypeName "Overrides"}}(ctx, p)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+`
+
+const tenantTestTemplate = `// Code generated by sudo-gen context. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// {{lower .TypeName}}StaticSource is a {{.TypeName}}Source always returning the same config.
+type {{lower .TypeName}}StaticSource struct {
+	cfg *{{.TypeName}}
+}
+
+func (s {{lower .TypeName}}StaticSource) Get() *{{.TypeName}} {
+	return s.cfg.{{method "Copy"}}()
+}
+{{- if .StringField}}
+
+func Test{{.TypeName}}TenantMiddleware(t *testing.T) {
+	source := {{lower .TypeName}}StaticSource{cfg: &{{.TypeName}}{ {{.StringField}}: "base"}}
+	resolver := {{.TypeName}}TenantResolverFunc(func(r *http.Request) (*{{partial .TypeName}}, error) {
+		tenant := r.Header.Get("X-Tenant")
+		if tenant == "" {
+			return nil, nil
+		}
+		return &{{partial .TypeName}}{ {{.StringField}}: &tenant}, nil
+	})
+	var got string
+	handler := {{.TypeName}}TenantMiddleware(source, resolver, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg, ok := {{.TypeName}}FromContext(r.Context())
+		if !ok {
+			t.Fatal("no config in the request context")
+		}
+		got = cfg.{{.StringField}}
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Tenant", "acme")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	if got != "acme" {
+		t.Errorf("expected the tenant override, got %q", got)
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if got != "base" {
+		t.Errorf("expected the base config without a tenant, got %q", got)
+	}
+	if source.cfg.{{.StringField}} != "base" {
+		t.Errorf("tenant overrides modified the source config: %q", source.cfg.{{.StringField}})
+	}
+}
+{{- end}}
+
+func Test{{.TypeName}}TenantMiddlewareError(t *testing.T) {
+	source := {{lower .TypeName}}StaticSource{cfg: &{{.TypeName}}{}}
+	resolver := {{.TypeName}}TenantResolverFunc(func(*http.Request) (*{{partial .TypeName}}, error) {
+		return nil, errors.New("unknown tenant")
+	})
+	handler := {{.TypeName}}TenantMiddleware(source, resolver, nil)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		t.Error("next handler called after the resolver failed")
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", w.Code)
+	}
+	var reported error
+	handler = {{.TypeName}}TenantMiddleware(source, resolver, func(w http.ResponseWriter, _ *http.Request, err error) {
+		reported = err
+		w.WriteHeader(http.StatusNotFound)
+	})(http.NotFoundHandler())
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if reported == nil || w.Code != http.StatusNotFound {
+		t.Errorf("expected onError to handle the failure, got %v, %d", reported, w.Code)
+	}
+}
+`
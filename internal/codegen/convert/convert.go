@@ -0,0 +1,422 @@
+// Package convert implements the convert code generation subtool.
+package convert
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/bobcob7/sudo-gen/internal/codegen"
+)
+
+// Subtool implements the convert code generator.
+type Subtool struct{}
+
+// Name returns the subtool name.
+func (s *Subtool) Name() string { return "convert" }
+
+// Description returns the subtool description.
+func (s *Subtool) Description() string {
+	return "Generate a function converting one struct (or protobuf message) into another"
+}
+
+// Doc describes the struct tags the subtool honors and the files it generates.
+func (s *Subtool) Doc() codegen.SubtoolDoc {
+	return codegen.SubtoolDoc{
+		Tags: []codegen.DocEntry{
+			{Name: `json:"name"`, Description: "Matches fields of the two types whose names differ"},
+			codegen.ExcludeTagDoc(s.Name()),
+		},
+		Files: []codegen.DocEntry{
+			{Name: "{source}_convert.go", Description: "Convert{From}To{To} and helpers for nested struct pairs (plus Convert{To}To{From} with -bidirectional)"},
+			{Name: "{source}_proto.go", Description: "{Type}FromProto and {Type}PartialFromProto (with -proto)"},
+		},
+	}
+}
+
+// DefineFlags defines the flags of the convert subcommand.
+func (s *Subtool) DefineFlags(fs *flag.FlagSet, cfg *codegen.GeneratorConfig) {
+	fs.StringVar(&cfg.TypeName, "from", "", "Source type (alias for -type)")
+	fs.StringVar(&cfg.ConvertTo, "to", "", "Target type")
+	fs.BoolVar(&cfg.ConvertBidirectional, "bidirectional", false, "Also generate the reverse conversion and a round-trip test")
+	fs.StringVar(&cfg.ProtoFile, "proto", "", "protoc-gen-go file (e.g. pb/config.pb.go) whose messages are converted into -type")
+}
+
+// Run executes the convert code generation.
+func (s *Subtool) Run(cfg codegen.GeneratorConfig) error {
+	if cfg.ProtoFile != "" {
+		return s.runProto(cfg)
+	}
+	if cfg.ConvertTo == "" {
+		return errors.New("convert requires -to=TargetType or -proto=file.pb.go")
+	}
+	c := &converter{
+		cfg:       cfg,
+		selection: codegen.NewFieldSelection(cfg, s.Name()),
+		structs:   make(map[string]*codegen.StructInfo),
+		seen:      make(map[string]bool),
+	}
+	c.funcFor(cfg.TypeName, cfg.ConvertTo)
+	if cfg.ConvertBidirectional {
+		c.funcFor(cfg.ConvertTo, cfg.TypeName)
+	}
+	for i := 0; i < len(c.pending); i++ {
+		fn, err := c.buildFunc(c.pending[i].from, c.pending[i].to)
+		if err != nil {
+			return err
+		}
+		c.funcs = append(c.funcs, fn)
+	}
+	structs := make([]*codegen.StructInfo, 0, len(c.structs))
+	for _, name := range slices.Sorted(maps.Keys(c.structs)) {
+		structs = append(structs, c.structs[name])
+	}
+	cfg, err := codegen.CheckUnsupported(cfg, s.Name(), codegen.UnsupportedFields(structs))
+	if err != nil {
+		return err
+	}
+	data := templateData{
+		Package: cfg.OutputPkg,
+		Funcs:   c.funcs,
+	}
+	if cfg.ConvertBidirectional {
+		rt, err := c.roundTrip(cfg.TypeName, cfg.ConvertTo)
+		if err != nil {
+			return err
+		}
+		data.RoundTrip = rt
+	}
+	return generateConvertFile(cfg, data)
+}
+
+// Conversion is a generated function converting a struct into another.
+type Conversion struct {
+	Name     string
+	Unmapped []string // Fields of the target with no counterpart in the source
+	Dropped  []string // Fields of the source with no counterpart in the target
+}
+
+// GenerateConversions generates a function converting each pair of a source and a
+// target type into the file at path, along with the functions for the pairs of nested
+// structs they reach. Other generators use it for the conversions they build on.
+func GenerateConversions(cfg codegen.GeneratorConfig, path string, pairs ...[2]string) ([]Conversion, error) {
+	c := &converter{
+		cfg:       cfg,
+		selection: codegen.NewFieldSelection(cfg, "convert"),
+		structs:   make(map[string]*codegen.StructInfo),
+		seen:      make(map[string]bool),
+	}
+	for _, p := range pairs {
+		c.funcFor(p[0], p[1])
+	}
+	for i := 0; i < len(c.pending); i++ {
+		fn, err := c.buildFunc(c.pending[i].from, c.pending[i].to)
+		if err != nil {
+			return nil, err
+		}
+		c.funcs = append(c.funcs, fn)
+	}
+	conversions := make([]Conversion, len(pairs))
+	for i, fn := range c.funcs[:len(pairs)] {
+		conversions[i] = Conversion{Name: fn.Name, Unmapped: fn.Unmapped, Dropped: fn.Dropped}
+	}
+	gen := codegen.NewTemplateGenerator(cfg, templateFuncs())
+	return conversions, gen.GenerateFile(path, convertTemplate, templateData{Package: cfg.OutputPkg, Funcs: c.funcs})
+}
+
+func generateConvertFile(cfg codegen.GeneratorConfig, data templateData) error {
+	baseName := strings.TrimSuffix(cfg.SourceFile, ".go")
+	outputFile := filepath.Join(cfg.OutputDir, baseName+"_convert.go")
+	gen := codegen.NewTemplateGenerator(cfg, templateFuncs())
+	if err := gen.GenerateFile(outputFile, convertTemplate, data); err != nil {
+		return err
+	}
+	if cfg.GenerateTest {
+		if data.RoundTrip != nil {
+			if err := codegen.WriteHelpers(cfg, codegen.HelperPtr); err != nil {
+				return err
+			}
+		}
+		testFile := filepath.Join(cfg.OutputDir, baseName+"_convert_test.go")
+		return gen.GenerateFile(testFile, convertTestTemplate, data)
+	}
+	return nil
+}
+
+type templateData struct {
+	Package   string
+	Funcs     []convertFunc
+	RoundTrip *roundTrip // Set with -bidirectional
+}
+
+// convertFunc is a generated function converting From into To.
+type convertFunc struct {
+	Name     string
+	From     string
+	To       string
+	Fields   []fieldMapping
+	Unmapped []string // Fields of To with no counterpart in From
+	Dropped  []string // Fields of From with no counterpart in To
+}
+
+// fieldMapping assigns one field of To from a field of From.
+type fieldMapping struct {
+	From   codegen.FieldInfo
+	To     codegen.FieldInfo
+	Assign string // Statements performing the assignment from src to dst
+}
+
+type pair struct {
+	from string
+	to   string
+}
+
+type converter struct {
+	cfg       codegen.GeneratorConfig
+	selection *codegen.FieldSelection
+	structs   map[string]*codegen.StructInfo
+	seen      map[string]bool
+	pending   []pair
+	funcs     []convertFunc
+}
+
+// funcFor returns the name of the function converting from into to,
+// scheduling it for generation if it hasn't been seen yet.
+func (c *converter) funcFor(from, to string) string {
+	key := from + "->" + to
+	if !c.seen[key] {
+		c.seen[key] = true
+		c.pending = append(c.pending, pair{from: from, to: to})
+	}
+	return funcName(from, to)
+}
+
+func funcName(from, to string) string {
+	if ast.IsExported(from) && ast.IsExported(to) {
+		return "Convert" + from + "To" + to
+	}
+	return "convert" + capitalize(from) + "To" + capitalize(to)
+}
+
+func capitalize(name string) string {
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// lookup parses the named struct from the source or its package.
+func (c *converter) lookup(name string) (*codegen.StructInfo, error) {
+	if info, ok := c.structs[name]; ok {
+		return info, nil
+	}
+	info, err := c.cfg.Index.ParseStruct(c.cfg.SourceDir, c.cfg.SourceFile, c.cfg.Source, name)
+	if err != nil {
+		info, err = c.cfg.Index.FindStructInPackage(c.cfg.SourceDir, name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	c.selection.Apply(info)
+	c.structs[name] = info
+	return info, nil
+}
+
+func (c *converter) buildFunc(from, to string) (convertFunc, error) {
+	src, err := c.lookup(from)
+	if err != nil {
+		return convertFunc{}, fmt.Errorf("parsing source struct: %w", err)
+	}
+	dst, err := c.lookup(to)
+	if err != nil {
+		return convertFunc{}, fmt.Errorf("parsing target struct: %w", err)
+	}
+	fn := convertFunc{Name: funcName(from, to), From: from, To: to}
+	for _, df := range dst.Fields {
+		sf, ok := matchField(src.Fields, df)
+		if !ok {
+			fn.Unmapped = append(fn.Unmapped, df.Name)
+			continue
+		}
+		assign, err := c.assign(df.TypeExpr, sf.TypeExpr, "dst."+df.Name, "src."+sf.Name, 0)
+		if err != nil {
+			return convertFunc{}, fmt.Errorf("%s.%s to %s.%s: %w", from, sf.Name, to, df.Name, err)
+		}
+		fn.Fields = append(fn.Fields, fieldMapping{From: sf, To: df, Assign: assign})
+	}
+	for _, sf := range src.Fields {
+		if !slices.ContainsFunc(fn.Fields, func(m fieldMapping) bool { return m.From.Name == sf.Name }) {
+			fn.Dropped = append(fn.Dropped, sf.Name)
+		}
+	}
+	return fn, nil
+}
+
+// matchField finds the source field for target, first by Go name and then by json key.
+func matchField(fields []codegen.FieldInfo, target codegen.FieldInfo) (codegen.FieldInfo, bool) {
+	for _, f := range fields {
+		if f.Name == target.Name {
+			return f, true
+		}
+	}
+	key := jsonKey(target)
+	for _, f := range fields {
+		if jsonKey(f) == key {
+			return f, true
+		}
+	}
+	return codegen.FieldInfo{}, false
+}
+
+func jsonKey(f codegen.FieldInfo) string {
+	name, _, _ := strings.Cut(f.StructTag().Get("json"), ",")
+	if name == "" || name == "-" {
+		return f.Name
+	}
+	return name
+}
+
+// assign returns statements that assign srcExpr, of type src, to dstExpr, of type dst.
+func (c *converter) assign(dst, src ast.Expr, dstExpr, srcExpr string, depth int) (string, error) {
+	dstType, srcType := types.ExprString(dst), types.ExprString(src)
+	if dstType == srcType {
+		return fmt.Sprintf("%s = %s", dstExpr, srcExpr), nil
+	}
+	dstPtr, dstIsPtr := dst.(*ast.StarExpr)
+	srcPtr, srcIsPtr := src.(*ast.StarExpr)
+	switch {
+	case dstIsPtr && srcIsPtr:
+		if c.isStructPair(dstPtr.X, srcPtr.X) {
+			fn := c.funcFor(types.ExprString(srcPtr.X), types.ExprString(dstPtr.X))
+			return fmt.Sprintf("%s = %s(%s)", dstExpr, fn, srcExpr), nil
+		}
+		v := varName("v", depth)
+		inner, err := c.assign(dstPtr.X, srcPtr.X, v, "*"+srcExpr, depth+1)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("if %s != nil {\nvar %s %s\n%s\n%s = &%s\n}", srcExpr, v, types.ExprString(dstPtr.X), inner, dstExpr, v), nil
+	case srcIsPtr:
+		if c.isStructPair(dst, srcPtr.X) {
+			fn := c.funcFor(types.ExprString(srcPtr.X), dstType)
+			return fmt.Sprintf("if %s != nil {\n%s = *%s(%s)\n}", srcExpr, dstExpr, fn, srcExpr), nil
+		}
+		inner, err := c.assign(dst, srcPtr.X, dstExpr, "*"+srcExpr, depth+1)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("if %s != nil {\n%s\n}", srcExpr, inner), nil
+	case dstIsPtr:
+		if c.isStructPair(dstPtr.X, src) {
+			fn := c.funcFor(srcType, types.ExprString(dstPtr.X))
+			return fmt.Sprintf("%s = %s(&%s)", dstExpr, fn, srcExpr), nil
+		}
+		v := varName("v", depth)
+		inner, err := c.assign(dstPtr.X, src, v, srcExpr, depth+1)
+		if err != nil {
+			return "", err
+		}
+		if value, ok := strings.CutPrefix(inner, v+" = "); ok && !strings.Contains(value, "\n") {
+			return fmt.Sprintf("{\n%s := %s\n%s = &%s\n}", v, value, dstExpr, v), nil
+		}
+		return fmt.Sprintf("{\nvar %s %s\n%s\n%s = &%s\n}", v, types.ExprString(dstPtr.X), inner, dstExpr, v), nil
+	}
+	if c.isStructPair(dst, src) {
+		fn := c.funcFor(srcType, dstType)
+		return fmt.Sprintf("%s = *%s(&%s)", dstExpr, fn, srcExpr), nil
+	}
+	dstArr, dstIsArr := dst.(*ast.ArrayType)
+	srcArr, srcIsArr := src.(*ast.ArrayType)
+	if dstIsArr && srcIsArr && dstArr.Len == nil && srcArr.Len == nil {
+		i := varName("i", depth)
+		inner, err := c.assign(dstArr.Elt, srcArr.Elt, dstExpr+"["+i+"]", srcExpr+"["+i+"]", depth+1)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("if %s != nil {\n%s = make(%s, len(%s))\nfor %s := range %s {\n%s\n}\n}",
+			srcExpr, dstExpr, dstType, srcExpr, i, srcExpr, inner), nil
+	}
+	dstMap, dstIsMap := dst.(*ast.MapType)
+	srcMap, srcIsMap := src.(*ast.MapType)
+	if dstIsMap && srcIsMap {
+		if types.ExprString(dstMap.Key) != types.ExprString(srcMap.Key) {
+			return "", fmt.Errorf("map key types differ: %s and %s", srcType, dstType)
+		}
+		k, v, dv := varName("k", depth), varName("v", depth), varName("dv", depth)
+		inner, err := c.assign(dstMap.Value, srcMap.Value, dv, v, depth+1)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("if %s != nil {\n%s = make(%s, len(%s))\nfor %s, %s := range %s {\nvar %s %s\n%s\n%s[%s] = %s\n}\n}",
+			srcExpr, dstExpr, dstType, srcExpr, k, v, srcExpr, dv, types.ExprString(dstMap.Value), inner, dstExpr, k, dv), nil
+	}
+	if c.isConvertible(dst, src) {
+		return fmt.Sprintf("%s = %s(%s)", dstExpr, dstType, srcExpr), nil
+	}
+	return "", fmt.Errorf("cannot convert %s to %s", srcType, dstType)
+}
+
+// isStructPair reports whether dst and src are both local struct types.
+func (c *converter) isStructPair(dst, src ast.Expr) bool {
+	return c.isLocalStruct(dst) && c.isLocalStruct(src)
+}
+
+func (c *converter) isLocalStruct(expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	if !ok || isBasicType(ident.Name) {
+		return false
+	}
+	_, err := c.lookup(ident.Name)
+	return err == nil
+}
+
+// isConvertible reports whether a Go conversion between dst and src is expected to
+// compile: both are basic numeric types, or neither is a struct and at least one is a local named
+// non-struct type (e.g. type Level int).
+func (c *converter) isConvertible(dst, src ast.Expr) bool {
+	dstIdent, ok1 := dst.(*ast.Ident)
+	srcIdent, ok2 := src.(*ast.Ident)
+	if !ok1 || !ok2 {
+		return false
+	}
+	if isBasicType(dstIdent.Name) && isBasicType(srcIdent.Name) {
+		return isNumeric(dstIdent.Name) && isNumeric(srcIdent.Name)
+	}
+	return !c.isLocalStruct(dst) && !c.isLocalStruct(src)
+}
+
+func varName(prefix string, depth int) string {
+	if depth == 0 {
+		return prefix
+	}
+	return fmt.Sprintf("%s%d", prefix, depth)
+}
+
+func isBasicType(name string) bool {
+	switch name {
+	case "bool", "string", "any", "error":
+		return true
+	}
+	return isNumeric(name)
+}
+
+func isNumeric(name string) bool {
+	switch name {
+	case "int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64", "uintptr",
+		"float32", "float64", "complex64", "complex128", "byte", "rune":
+		return true
+	}
+	return false
+}
+
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"join":       strings.Join,
+		"capitalize": capitalize,
+	}
+}
@@ -0,0 +1,577 @@
+package convert
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/bobcob7/sudo-gen/internal/codegen"
+)
+
+// Import paths of the protobuf well-known types that map onto plain Go values.
+const (
+	wrappersPath  = "google.golang.org/protobuf/types/known/wrapperspb"
+	timestampPath = "google.golang.org/protobuf/types/known/timestamppb"
+	durationPath  = "google.golang.org/protobuf/types/known/durationpb"
+)
+
+// wrapperValues maps wrapperspb messages to the type returned by their GetValue method.
+var wrapperValues = map[string]string{
+	"DoubleValue": "float64",
+	"FloatValue":  "float32",
+	"Int64Value":  "int64",
+	"UInt64Value": "uint64",
+	"Int32Value":  "int32",
+	"UInt32Value": "uint32",
+	"BoolValue":   "bool",
+	"StringValue": "string",
+	"BytesValue":  "[]byte",
+}
+
+// runProto generates conversions from the protoc-gen-go messages in cfg.ProtoFile
+// into cfg.TypeName and, when the merge generator's partial exists, its partial.
+func (s *Subtool) runProto(cfg codegen.GeneratorConfig) error {
+	if cfg.ConvertTo != "" || cfg.ConvertBidirectional {
+		return errors.New("-proto cannot be combined with -to or -bidirectional")
+	}
+	protoPath := cfg.ProtoFile
+	if !filepath.IsAbs(protoPath) {
+		protoPath = filepath.Join(cfg.SourceDir, protoPath)
+	}
+	pf, err := parseProtoFile(protoPath)
+	if err != nil {
+		return err
+	}
+	p := &protoConverter{
+		c: &converter{
+			cfg:       cfg,
+			selection: codegen.NewFieldSelection(cfg, s.Name()),
+			structs:   make(map[string]*codegen.StructInfo),
+		},
+		proto:   pf,
+		sources: make(map[string]string),
+	}
+	if _, err := cfg.Index.FindStructInPackage(cfg.SourceDir, cfg.Partial(cfg.TypeName)); err == nil {
+		p.partial = true
+	}
+	if _, err := p.funcFor(cfg.TypeName, cfg.TypeName); err != nil {
+		return err
+	}
+	for i := 0; i < len(p.pending); i++ {
+		fn, err := p.buildFunc(p.pending[i].from, p.pending[i].to)
+		if err != nil {
+			return err
+		}
+		p.funcs = append(p.funcs, fn)
+	}
+	data := protoTemplateData{
+		Package:     cfg.OutputPkg,
+		ProtoPkg:    pf.pkg,
+		ProtoImport: pf.path,
+		Partial:     p.partial,
+		Funcs:       p.funcs,
+	}
+	if path.Base(pf.path) != pf.pkg {
+		data.ProtoAlias = pf.pkg
+	}
+	baseName := strings.TrimSuffix(cfg.SourceFile, ".go")
+	gen := codegen.NewTemplateGenerator(cfg, templateFuncs())
+	if err := gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_proto.go"), protoTemplate, data); err != nil {
+		return err
+	}
+	if cfg.GenerateTest {
+		return gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_proto_test.go"), protoTestTemplate, data)
+	}
+	return nil
+}
+
+type protoTemplateData struct {
+	Package     string
+	ProtoPkg    string
+	ProtoImport string
+	ProtoAlias  string // Set when the proto package name differs from its import path
+	Partial     bool   // Generate {Type}PartialFromProto functions
+	Funcs       []protoFunc
+}
+
+// protoFunc converts the proto message Message into Type and its partial.
+type protoFunc struct {
+	Name        string
+	PartialName string
+	Message     string
+	Type        string
+	Fields      []protoMapping
+	Unmapped    []string // Fields of Type with no counterpart in Message
+}
+
+// protoMapping holds the statements assigning one field from a message.
+type protoMapping struct {
+	Full    string // Assignment into the config struct
+	Partial string // Assignment into the partial, set only when the field is present
+}
+
+// protoFile indexes the declarations of a protoc-gen-go output file.
+type protoFile struct {
+	pkg      string
+	path     string
+	imports  map[string]string // Package name -> import path
+	messages map[string]*ast.StructType
+	enums    map[string]bool
+	oneofs   map[string][]oneofCase // Oneof interface name -> its wrapper types
+}
+
+// oneofCase is a generated oneof wrapper such as Config_Postgres{Postgres *PostgresConfig}.
+type oneofCase struct {
+	wrapper string
+	field   *ast.Field
+}
+
+func parseProtoFile(filename string) (*protoFile, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), filename, nil, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("parsing proto file: %w", err)
+	}
+	importPath, err := codegen.ImportPathForDir(filepath.Dir(filename))
+	if err != nil {
+		return nil, fmt.Errorf("resolving proto package: %w", err)
+	}
+	pf := &protoFile{
+		pkg:      f.Name.Name,
+		path:     importPath,
+		imports:  make(map[string]string),
+		messages: make(map[string]*ast.StructType),
+		enums:    make(map[string]bool),
+		oneofs:   make(map[string][]oneofCase),
+	}
+	for _, imp := range f.Imports {
+		p := strings.Trim(imp.Path.Value, `"`)
+		name := path.Base(p)
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		pf.imports[name] = p
+	}
+	wrappers := make(map[string][]string)
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				ts, ok := spec.(*ast.TypeSpec)
+				if !ok {
+					continue
+				}
+				switch t := ts.Type.(type) {
+				case *ast.StructType:
+					pf.messages[ts.Name.Name] = t
+				case *ast.Ident:
+					if t.Name == "int32" {
+						pf.enums[ts.Name.Name] = true
+					}
+				}
+			}
+		case *ast.FuncDecl:
+			// Oneof wrappers implement an unexported isMessage_Field marker method.
+			if d.Recv == nil || len(d.Recv.List) != 1 || !strings.HasPrefix(d.Name.Name, "is") || !strings.Contains(d.Name.Name, "_") {
+				continue
+			}
+			if star, ok := d.Recv.List[0].Type.(*ast.StarExpr); ok {
+				if ident, ok := star.X.(*ast.Ident); ok {
+					wrappers[d.Name.Name] = append(wrappers[d.Name.Name], ident.Name)
+				}
+			}
+		}
+	}
+	for iface, names := range wrappers {
+		for _, name := range names {
+			st, ok := pf.messages[name]
+			if !ok || len(st.Fields.List) != 1 {
+				continue
+			}
+			pf.oneofs[iface] = append(pf.oneofs[iface], oneofCase{wrapper: name, field: st.Fields.List[0]})
+		}
+	}
+	return pf, nil
+}
+
+// protoField is a value readable from a message: a regular field or a oneof case.
+type protoField struct {
+	Name   string
+	Keys   []string // json= and name= entries of the protobuf tag, and the json tag
+	Type   ast.Expr
+	Access string // Expression reading the value
+	Guard  string // Condition that must hold before Access is valid (oneof cases)
+}
+
+// fields returns the readable values of message msg, expanding oneofs into their cases.
+func (pf *protoFile) fields(msg string) []protoField {
+	var fields []protoField
+	for _, field := range pf.messages[msg].Fields.List {
+		tag := fieldTag(field)
+		for _, name := range field.Names {
+			if !name.IsExported() {
+				continue
+			}
+			if _, ok := tag.Lookup("protobuf_oneof"); !ok {
+				fields = append(fields, protoField{Name: name.Name, Keys: protoKeys(tag), Type: field.Type, Access: "m." + name.Name})
+				continue
+			}
+			iface, _ := field.Type.(*ast.Ident)
+			if iface == nil {
+				continue
+			}
+			for _, c := range pf.oneofs[iface.Name] {
+				if len(c.field.Names) != 1 {
+					continue
+				}
+				caseName := c.field.Names[0].Name
+				fields = append(fields, protoField{
+					Name:   caseName,
+					Keys:   protoKeys(fieldTag(c.field)),
+					Type:   c.field.Type,
+					Access: "x." + caseName,
+					Guard:  fmt.Sprintf("x, ok := m.%s.(*%s.%s); ok", name.Name, pf.pkg, c.wrapper),
+				})
+			}
+		}
+	}
+	return fields
+}
+
+func fieldTag(field *ast.Field) reflect.StructTag {
+	if field.Tag == nil {
+		return ""
+	}
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return ""
+	}
+	return reflect.StructTag(tag)
+}
+
+func protoKeys(tag reflect.StructTag) []string {
+	var keys []string
+	for _, entry := range strings.Split(tag.Get("protobuf"), ",") {
+		if key, ok := strings.CutPrefix(entry, "json="); ok {
+			keys = append(keys, key)
+		} else if key, ok := strings.CutPrefix(entry, "name="); ok {
+			keys = append(keys, key)
+		}
+	}
+	if name, _, _ := strings.Cut(tag.Get("json"), ","); name != "" && name != "-" {
+		keys = append(keys, name)
+	}
+	return keys
+}
+
+// matchProtoField finds the message value for target, first by Go name and then by
+// the proto field names.
+func matchProtoField(fields []protoField, target codegen.FieldInfo) (protoField, bool) {
+	for _, f := range fields {
+		if f.Name == target.Name {
+			return f, true
+		}
+	}
+	key := jsonKey(target)
+	for _, f := range fields {
+		for _, k := range f.Keys {
+			if k == key {
+				return f, true
+			}
+		}
+	}
+	return protoField{}, false
+}
+
+type protoConverter struct {
+	c       *converter
+	proto   *protoFile
+	partial bool
+	sources map[string]string // Config type -> message it is converted from
+	pending []pair
+	funcs   []protoFunc
+}
+
+// funcFor returns the function converting message msg into typ, scheduling it for
+// generation if it hasn't been seen yet.
+func (p *protoConverter) funcFor(msg, typ string) (string, error) {
+	if seen, ok := p.sources[typ]; ok {
+		if seen != msg {
+			return "", fmt.Errorf("%s is converted from both %s and %s", typ, seen, msg)
+		}
+		return typ + "FromProto", nil
+	}
+	if _, ok := p.proto.messages[msg]; !ok {
+		return "", fmt.Errorf("message %s not found in %s", msg, p.proto.path)
+	}
+	p.sources[typ] = msg
+	p.pending = append(p.pending, pair{from: msg, to: typ})
+	return typ + "FromProto", nil
+}
+
+func (p *protoConverter) buildFunc(msg, typ string) (protoFunc, error) {
+	dst, err := p.c.lookup(typ)
+	if err != nil {
+		return protoFunc{}, fmt.Errorf("parsing target struct: %w", err)
+	}
+	fn := protoFunc{Name: typ + "FromProto", PartialName: p.c.cfg.Partial(typ) + "FromProto", Message: msg, Type: typ}
+	src := p.proto.fields(msg)
+	for _, df := range dst.Fields {
+		sf, ok := matchProtoField(src, df)
+		if !ok {
+			fn.Unmapped = append(fn.Unmapped, df.Name)
+			continue
+		}
+		full, err := p.assign(df.TypeExpr, sf.Type, "dst."+df.Name, sf.Access, 0)
+		if err != nil {
+			return protoFunc{}, fmt.Errorf("%s.%s to %s.%s: %w", msg, sf.Name, typ, df.Name, err)
+		}
+		m := protoMapping{Full: guarded(sf.Guard, full)}
+		if p.partial {
+			partial, err := p.assignPartial(df, sf)
+			if err != nil {
+				return protoFunc{}, fmt.Errorf("%s.%s to %s.%s: %w", msg, sf.Name, p.c.cfg.Partial(typ), df.Name, err)
+			}
+			m.Partial = guarded(sf.Guard, partial)
+		}
+		fn.Fields = append(fn.Fields, m)
+	}
+	return fn, nil
+}
+
+// assignPartial returns statements setting the partial's field df from sf only when
+// sf is present. Scalars without explicit presence count as present when non-zero;
+// oneof cases are present when selected.
+func (p *protoConverter) assignPartial(df codegen.FieldInfo, sf protoField) (string, error) {
+	dst := df.TypeExpr
+	if star, ok := dst.(*ast.StarExpr); ok {
+		dst = star.X
+	}
+	dstExpr := "dst." + df.Name
+	if typ, ok := p.localStruct(dst); ok {
+		msg, ok := p.message(sf.Type)
+		if !ok {
+			return "", fmt.Errorf("cannot convert %s to %s", types.ExprString(sf.Type), typ)
+		}
+		if _, err := p.funcFor(msg, typ); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s = %sFromProto(%s)", dstExpr, p.c.cfg.Partial(typ), sf.Access), nil
+	}
+	switch dst.(type) {
+	case *ast.ArrayType, *ast.MapType:
+		return p.assign(df.TypeExpr, sf.Type, dstExpr, sf.Access, 0)
+	}
+	set, err := p.assign(&ast.StarExpr{X: dst}, sf.Type, dstExpr, sf.Access, 0)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := sf.Type.(*ast.StarExpr); ok || sf.Guard != "" {
+		return set, nil
+	}
+	return guarded(isSet(sf.Type, sf.Access), set), nil
+}
+
+// isSet returns the condition under which a proto3 scalar without presence is set.
+func isSet(typ ast.Expr, expr string) string {
+	if ident, ok := typ.(*ast.Ident); ok {
+		switch {
+		case ident.Name == "bool":
+			return expr
+		case ident.Name == "string":
+			return expr + ` != ""`
+		}
+		return expr + " != 0"
+	}
+	return expr + " != nil"
+}
+
+// assign returns statements that assign srcExpr, of proto type src, to dstExpr, of
+// config type dst.
+func (p *protoConverter) assign(dst, src ast.Expr, dstExpr, srcExpr string, depth int) (string, error) {
+	dstPtr, dstIsPtr := dst.(*ast.StarExpr)
+	if srcPtr, ok := src.(*ast.StarExpr); ok {
+		if value, typ, ok := p.wellKnown(srcPtr.X, srcExpr); ok {
+			inner, err := p.assign(dst, typ, dstExpr, value, depth)
+			if err != nil {
+				return "", err
+			}
+			return guarded(srcExpr+" != nil", inner), nil
+		}
+		if msg, ok := p.message(srcPtr.X); ok {
+			target := dst
+			if dstIsPtr {
+				target = dstPtr.X
+			}
+			typ, ok := p.localStruct(target)
+			if !ok {
+				return "", fmt.Errorf("cannot convert message %s to %s", msg, types.ExprString(dst))
+			}
+			fn, err := p.funcFor(msg, typ)
+			if err != nil {
+				return "", err
+			}
+			if dstIsPtr {
+				return fmt.Sprintf("%s = %s(%s)", dstExpr, fn, srcExpr), nil
+			}
+			return guarded(srcExpr+" != nil", fmt.Sprintf("%s = *%s(%s)", dstExpr, fn, srcExpr)), nil
+		}
+		// proto3 optional scalars and enums
+		inner, err := p.assign(dst, srcPtr.X, dstExpr, "*"+srcExpr, depth)
+		if err != nil {
+			return "", err
+		}
+		return guarded(srcExpr+" != nil", inner), nil
+	}
+	if dstIsPtr {
+		v := varName("v", depth)
+		inner, err := p.assign(dstPtr.X, src, v, srcExpr, depth+1)
+		if err != nil {
+			return "", err
+		}
+		value, ok := strings.CutPrefix(inner, v+" = ")
+		if !ok || strings.Contains(value, "\n") {
+			return "", fmt.Errorf("cannot convert %s to %s", types.ExprString(src), types.ExprString(dst))
+		}
+		return fmt.Sprintf("{\n%s := %s\n%s = &%s\n}", v, value, dstExpr, v), nil
+	}
+	dstType, srcType := types.ExprString(dst), types.ExprString(src)
+	switch s := src.(type) {
+	case *ast.Ident:
+		d, ok := dst.(*ast.Ident)
+		if !ok || p.c.isLocalStruct(d) {
+			break
+		}
+		switch {
+		case p.proto.enums[s.Name] && d.Name == "string":
+			return fmt.Sprintf("%s = %s.String()", dstExpr, srcExpr), nil
+		case p.proto.enums[s.Name]:
+			return fmt.Sprintf("%s = %s(%s)", dstExpr, dstType, srcExpr), nil
+		case s.Name == d.Name && isBasicType(s.Name):
+			return fmt.Sprintf("%s = %s", dstExpr, srcExpr), nil
+		case isNumeric(s.Name) && isNumeric(d.Name), isBasicType(s.Name) && !isBasicType(d.Name):
+			return fmt.Sprintf("%s = %s(%s)", dstExpr, dstType, srcExpr), nil
+		}
+	case *ast.SelectorExpr:
+		if dstType == srcType {
+			return fmt.Sprintf("%s = %s", dstExpr, srcExpr), nil
+		}
+	case *ast.ArrayType:
+		d, ok := dst.(*ast.ArrayType)
+		if !ok || d.Len != nil || s.Len != nil {
+			break
+		}
+		if dstType == srcType && isBuiltin(s) {
+			return fmt.Sprintf("%s = %s", dstExpr, srcExpr), nil
+		}
+		i := varName("i", depth)
+		inner, err := p.assign(d.Elt, s.Elt, dstExpr+"["+i+"]", srcExpr+"["+i+"]", depth+1)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("if %s != nil {\n%s = make(%s, len(%s))\nfor %s := range %s {\n%s\n}\n}",
+			srcExpr, dstExpr, dstType, srcExpr, i, srcExpr, inner), nil
+	case *ast.MapType:
+		d, ok := dst.(*ast.MapType)
+		if !ok {
+			break
+		}
+		if !isBuiltin(s.Key) || types.ExprString(s.Key) != types.ExprString(d.Key) {
+			return "", fmt.Errorf("map key types differ: %s and %s", srcType, dstType)
+		}
+		if dstType == srcType && isBuiltin(s) {
+			return fmt.Sprintf("%s = %s", dstExpr, srcExpr), nil
+		}
+		k, v, dv := varName("k", depth), varName("v", depth), varName("dv", depth)
+		inner, err := p.assign(d.Value, s.Value, dv, v, depth+1)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("if %s != nil {\n%s = make(%s, len(%s))\nfor %s, %s := range %s {\nvar %s %s\n%s\n%s[%s] = %s\n}\n}",
+			srcExpr, dstExpr, dstType, srcExpr, k, v, srcExpr, dv, types.ExprString(d.Value), inner, dstExpr, k, dv), nil
+	}
+	return "", fmt.Errorf("cannot convert %s to %s", srcType, dstType)
+}
+
+// wellKnown returns the Go value held by a wrapperspb, timestamppb or durationpb
+// message read by expr, and its type.
+func (p *protoConverter) wellKnown(expr ast.Expr, acc string) (string, ast.Expr, bool) {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return "", nil, false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return "", nil, false
+	}
+	switch p.proto.imports[pkg.Name] {
+	case wrappersPath:
+		if typ, ok := wrapperValues[sel.Sel.Name]; ok {
+			valueType, err := parser.ParseExpr(typ)
+			return acc + ".GetValue()", valueType, err == nil
+		}
+	case timestampPath:
+		if sel.Sel.Name == "Timestamp" {
+			return acc + ".AsTime()", &ast.SelectorExpr{X: ast.NewIdent("time"), Sel: ast.NewIdent("Time")}, true
+		}
+	case durationPath:
+		if sel.Sel.Name == "Duration" {
+			return acc + ".AsDuration()", &ast.SelectorExpr{X: ast.NewIdent("time"), Sel: ast.NewIdent("Duration")}, true
+		}
+	}
+	return "", nil, false
+}
+
+// message reports the name of the message that expr, a message pointer, refers to.
+func (p *protoConverter) message(expr ast.Expr) (string, bool) {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	ident, ok := expr.(*ast.Ident)
+	if !ok || p.proto.messages[ident.Name] == nil {
+		return "", false
+	}
+	return ident.Name, true
+}
+
+func (p *protoConverter) localStruct(expr ast.Expr) (string, bool) {
+	ident, ok := expr.(*ast.Ident)
+	if !ok || !p.c.isLocalStruct(ident) {
+		return "", false
+	}
+	return ident.Name, true
+}
+
+// isBuiltin reports whether expr only refers to predeclared types, so that the same
+// type expression means the same type in the proto and config packages.
+func isBuiltin(expr ast.Expr) bool {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return isBasicType(t.Name)
+	case *ast.ArrayType:
+		return isBuiltin(t.Elt)
+	case *ast.MapType:
+		return isBuiltin(t.Key) && isBuiltin(t.Value)
+	case *ast.StarExpr:
+		return isBuiltin(t.X)
+	}
+	return false
+}
+
+// guarded wraps stmt in an if statement on cond, merging a bare block.
+func guarded(cond, stmt string) string {
+	if cond == "" {
+		return stmt
+	}
+	if body, ok := strings.CutPrefix(stmt, "{\n"); ok && strings.HasSuffix(body, "\n}") {
+		stmt = strings.TrimSuffix(body, "\n}")
+	}
+	return fmt.Sprintf("if %s {\n%s\n}", cond, stmt)
+}
@@ -0,0 +1,135 @@
+package convert
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"strings"
+)
+
+// roundTrip describes the generated test that converts a sample value to the target
+// type and back again.
+type roundTrip struct {
+	From    string
+	To      string
+	Forward string
+	Reverse string
+	Sample  string // Composite literal of From with every round-tripping field set
+}
+
+// maxSampleDepth bounds sample generation for self-referencing types.
+const maxSampleDepth = 3
+
+func (c *converter) roundTrip(from, to string) (*roundTrip, error) {
+	sample, ok := c.sampleStruct(from, to, 0)
+	if !ok {
+		return nil, fmt.Errorf("cannot build a round-trip sample for %s", from)
+	}
+	return &roundTrip{
+		From:    from,
+		To:      to,
+		Forward: funcName(from, to),
+		Reverse: funcName(to, from),
+		Sample:  sample,
+	}, nil
+}
+
+func (c *converter) findFunc(from, to string) *convertFunc {
+	name := funcName(from, to)
+	for i := range c.funcs {
+		if c.funcs[i].Name == name {
+			return &c.funcs[i]
+		}
+	}
+	return nil
+}
+
+// sampleStruct returns a composite literal of from that sets every field surviving a
+// conversion to to and back.
+func (c *converter) sampleStruct(from, to string, depth int) (string, bool) {
+	forward, reverse := c.findFunc(from, to), c.findFunc(to, from)
+	if forward == nil || reverse == nil || depth > maxSampleDepth {
+		return "", false
+	}
+	var fields []string
+	for _, m := range forward.Fields {
+		if !mapsBack(reverse, m) {
+			continue
+		}
+		if lit, ok := c.sample(m.From.TypeExpr, m.To.TypeExpr, depth); ok {
+			fields = append(fields, m.From.Name+": "+lit)
+		}
+	}
+	if len(fields) == 0 {
+		return from + "{}", true
+	}
+	return from + "{\n" + strings.Join(fields, ",\n") + ",\n}", true
+}
+
+// mapsBack reports whether reverse assigns the target of m back to its source.
+func mapsBack(reverse *convertFunc, m fieldMapping) bool {
+	for _, r := range reverse.Fields {
+		if r.From.Name == m.To.Name && r.To.Name == m.From.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// sample returns a non-zero literal of type expr whose conversion to counterpart
+// and back is lossless.
+func (c *converter) sample(expr, counterpart ast.Expr, depth int) (string, bool) {
+	other := counterpart
+	if star, ok := other.(*ast.StarExpr); ok {
+		other = star.X
+	}
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		if c.isStructPair(t.X, other) {
+			lit, ok := c.sampleStruct(types.ExprString(t.X), types.ExprString(other), depth+1)
+			return "&" + lit, ok
+		}
+		lit, ok := c.sample(t.X, other, depth)
+		if !ok {
+			return "", false
+		}
+		return fmt.Sprintf("sudogenPtr[%s](%s)", types.ExprString(t.X), lit), true
+	case *ast.ArrayType:
+		arr, ok := other.(*ast.ArrayType)
+		if !ok || t.Len != nil {
+			return "", false
+		}
+		lit, ok := c.sample(t.Elt, arr.Elt, depth)
+		if !ok {
+			return "", false
+		}
+		return types.ExprString(t) + "{" + lit + "}", true
+	case *ast.MapType:
+		m, ok := other.(*ast.MapType)
+		if !ok {
+			return "", false
+		}
+		key, ok := c.sample(t.Key, m.Key, depth)
+		if !ok {
+			return "", false
+		}
+		lit, ok := c.sample(t.Value, m.Value, depth)
+		if !ok {
+			return "", false
+		}
+		return types.ExprString(t) + "{" + key + ": " + lit + "}", true
+	case *ast.Ident:
+		if c.isStructPair(t, other) {
+			return c.sampleStruct(t.Name, types.ExprString(other), depth+1)
+		}
+		switch {
+		case t.Name == "string":
+			return `"sample"`, true
+		case t.Name == "bool":
+			return "true", true
+		case isNumeric(t.Name):
+			return "7", true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,164 @@
+package convert
+
+const convertTemplate = `// Code generated by sudo-gen convert. DO NOT EDIT.
+
+package {{.Package}}
+{{range .Funcs}}
+// {{.Name}} converts src into a new {{.To}}, matching fields by name or json tag.
+{{- if .Unmapped}}
+// Fields of {{.To}} with no counterpart in {{.From}} are left zero: {{join .Unmapped ", "}}.
+{{- end}}
+func {{.Name}}(src *{{.From}}) *{{.To}} {
+	if src == nil {
+		return nil
+	}
+	dst := &{{.To}}{}
+{{- range .Fields}}
+	{{.Assign}}
+{{- end}}
+	return dst
+}
+{{end}}
+`
+
+const convertTestTemplate = `// Code generated by sudo-gen convert. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- if .RoundTrip}}
+	"reflect"
+{{- end}}
+	"testing"
+)
+{{- with .RoundTrip}}
+
+func Test{{capitalize .Forward}}RoundTrip(t *testing.T) {
+	src := &{{.Sample}}
+	got := {{.Reverse}}({{.Forward}}(src))
+	if !reflect.DeepEqual(src, got) {
+		t.Errorf("round trip through {{.To}} changed the value:\n got %+v\nwant %+v", got, src)
+	}
+}
+{{- end}}
+{{range .Funcs}}
+func Test{{capitalize .Name}}Nil(t *testing.T) {
+	if got := {{.Name}}(nil); got != nil {
+		t.Errorf("expected nil, got %v", got)
+	}
+}
+
+func Test{{capitalize .Name}}Empty(t *testing.T) {
+	if got := {{.Name}}(&{{.From}}{}); got == nil {
+		t.Fatal("expected non-nil result")
+	}
+}
+{{- $fn := .}}
+{{- range .Fields}}
+{{- if and (eq .From.Type "string") (eq .To.Type "string")}}
+
+func Test{{capitalize $fn.Name}}_{{.To.Name}}(t *testing.T) {
+	got := {{$fn.Name}}(&{{$fn.From}}{ {{- .From.Name}}: "value"})
+	if got.{{.To.Name}} != "value" {
+		t.Errorf("expected {{.To.Name}}=value, got %q", got.{{.To.Name}})
+	}
+}
+{{- else if and (eq .From.Type "*string") (eq .To.Type "string")}}
+
+func Test{{capitalize $fn.Name}}_{{.To.Name}}(t *testing.T) {
+	v := "value"
+	got := {{$fn.Name}}(&{{$fn.From}}{ {{- .From.Name}}: &v})
+	if got.{{.To.Name}} != "value" {
+		t.Errorf("expected {{.To.Name}}=value, got %q", got.{{.To.Name}})
+	}
+}
+{{- end}}
+{{- end}}
+{{end}}
+`
+
+const protoTemplate = `// Code generated by sudo-gen convert. DO NOT EDIT.
+
+package {{.Package}}
+
+import {{with .ProtoAlias}}{{.}} {{end}}"{{.ProtoImport}}"
+{{- $pkg := .ProtoPkg}}
+{{- $partial := .Partial}}
+{{range .Funcs}}
+// {{.Name}} converts the protobuf message m into a new {{.Type}}, matching fields by
+// name or proto field name. Wrapper and well-known types are unwrapped.
+{{- if .Unmapped}}
+// Fields of {{.Type}} with no counterpart in {{$pkg}}.{{.Message}} are left zero: {{join .Unmapped ", "}}.
+{{- end}}
+func {{.Name}}(m *{{$pkg}}.{{.Message}}) *{{.Type}} {
+	if m == nil {
+		return nil
+	}
+	dst := &{{.Type}}{}
+{{- range .Fields}}
+	{{.Full}}
+{{- end}}
+	return dst
+}
+{{- if $partial}}
+
+// {{.PartialName}} converts the protobuf message m into a {{partial .Type}} holding
+// only the fields present in m, ready to be applied as a configuration layer. Scalar
+// fields without explicit presence are treated as unset when they hold their zero value.
+func {{.PartialName}}(m *{{$pkg}}.{{.Message}}) *{{partial .Type}} {
+	if m == nil {
+		return nil
+	}
+	dst := &{{partial .Type}}{}
+{{- range .Fields}}
+	{{.Partial}}
+{{- end}}
+	return dst
+}
+{{- end}}
+{{end}}
+`
+
+const protoTestTemplate = `// Code generated by sudo-gen convert. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- if .Partial}}
+	"reflect"
+{{- end}}
+	"testing"
+
+	{{with .ProtoAlias}}{{.}} {{end}}"{{.ProtoImport}}"
+)
+{{- $pkg := .ProtoPkg}}
+{{- $partial := .Partial}}
+{{range .Funcs}}
+func Test{{capitalize .Name}}Nil(t *testing.T) {
+	if got := {{.Name}}(nil); got != nil {
+		t.Errorf("expected nil, got %v", got)
+	}
+}
+
+func Test{{capitalize .Name}}Empty(t *testing.T) {
+	if got := {{.Name}}(&{{$pkg}}.{{.Message}}{}); got == nil {
+		t.Fatal("expected non-nil result")
+	}
+}
+{{- if $partial}}
+
+func Test{{capitalize .PartialName}}Nil(t *testing.T) {
+	if got := {{.PartialName}}(nil); got != nil {
+		t.Errorf("expected nil, got %v", got)
+	}
+}
+
+func Test{{capitalize .PartialName}}Empty(t *testing.T) {
+	got := {{.PartialName}}(&{{$pkg}}.{{.Message}}{})
+	if want := (&{{partial .Type}}{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected an empty partial, got %+v", got)
+	}
+}
+{{- end}}
+{{end}}
+`
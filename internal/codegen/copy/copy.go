@@ -2,11 +2,14 @@
 package copy
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"go/ast"
-	"go/parser"
-	"go/token"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
 	"strings"
 	"text/template"
 
@@ -16,6 +19,7 @@ import (
 // Subtool implements the copy code generator.
 type Subtool struct {
 	MethodName string
+	Graph      bool // Preserve shared pointers and cycles (-graph)
 }
 
 // Name returns the subtool name.
@@ -26,29 +30,95 @@ func (s *Subtool) Description() string {
 	return "Generate deep copy methods for structs"
 }
 
+// Doc describes the struct tags the subtool honors and the files it generates.
+func (s *Subtool) Doc() codegen.SubtoolDoc {
+	return codegen.SubtoolDoc{
+		Tags: []codegen.DocEntry{
+			{Name: `sudo:"secret"`, Description: "Cleared by Redacted (with -redact), encrypted by EncryptSecrets (with -encrypt)"},
+			codegen.ExcludeTagDoc(s.Name()),
+		},
+		Files: []codegen.DocEntry{
+			{Name: "{source}_copy.go", Description: "Deep copy method for the struct"},
+			{Name: "{type}_copy_bench_test.go", Description: "Benchmark{Type}Copy and Benchmark{Type}CopyInto (with -bench)"},
+			{Name: "{source}_copy_example_test.go", Description: "Example{Type}_Copy, shown by godoc (with -examples)"},
+		},
+	}
+}
+
+// DefineFlags defines the flags of the copy subcommand.
+func (s *Subtool) DefineFlags(fs *flag.FlagSet, cfg *codegen.GeneratorConfig) {
+	fs.StringVar(&s.MethodName, "method", "Copy", "Name of the generated copy method")
+	fs.BoolVar(&cfg.IncludeUnexported, "include-unexported", false, "Also copy unexported fields (requires generating into the source package)")
+	fs.BoolVar(&cfg.GenerateBench, "bench", false, "Generate Benchmark{Type}Copy and Benchmark{Type}CopyInto in a _bench_test.go file")
+	fs.BoolVar(&cfg.GenerateExamples, "examples", false, "Generate Example{Type}_Copy, a godoc example of the copy method, in an _example_test.go file")
+	fs.BoolVar(&s.Graph, "graph", false, "Copy pointers shared within the struct once and cycles as cycles, for data structures that aren't trees")
+	AddFlags(fs, cfg)
+}
+
+// AddFlags defines the flags shaping the copy output, for the subcommands that run
+// the copy generator too.
+func AddFlags(fs *flag.FlagSet, cfg *codegen.GeneratorConfig) {
+	fs.BoolVar(&cfg.RedactSecrets, "redact", false, `Also generate Redacted, returning a copy with fields tagged sudo:"secret" cleared`)
+	fs.BoolVar(&cfg.GenerateWith, "with", false, "Also generate With and With{Field} helpers returning modified copies")
+	fs.BoolVar(&cfg.EncryptSecrets, "encrypt", false, `Also generate EncryptSecrets and DecryptSecrets, returning a copy with fields tagged sudo:"secret" transformed by a {Type}Keyring`)
+	fs.Var((*codegen.ListFlag)(&cfg.CloneFuncs), "clone-funcs", "Comma-separated Type=Func functions cloning values of types with pointer semantics (e.g. *money.Amount=cloneAmount)")
+}
+
 // Run executes the copy code generation.
 func (s *Subtool) Run(cfg codegen.GeneratorConfig) error {
 	methodName := s.MethodName
 	if methodName == "" {
 		methodName = "Copy"
 	}
+	clones, err := codegen.NewCloneTypes(cfg.CloneFuncs, cfg.EqualFuncs)
+	if err != nil {
+		return err
+	}
 	g := &generator{
 		cfg:        cfg,
-		methodName: methodName,
-		fset:       token.NewFileSet(),
+		methodName: cfg.Method(methodName),
 		imports:    make(map[string]string),
 		processed:  make(map[string]bool),
+		values:     codegen.NewValueTypes(cfg.Index, cfg.SourceDir, cfg.ValueTypes),
+		clones:     clones,
+		selection:  codegen.NewFieldSelection(cfg, s.Name()),
+	}
+	if s.Graph {
+		if cfg.RedactSecrets || cfg.EncryptSecrets {
+			return errors.New("-graph can't be combined with -redact or -encrypt, which would transform the secrets of a cyclic struct forever")
+		}
+		g.graph = unexport(g.methodName) + "Graph"
+	}
+	if cfg.CrossPackage() {
+		if cfg.GenerateWith || cfg.RedactSecrets || cfg.EncryptSecrets || cfg.GenerateBench || cfg.GenerateExamples || s.Graph {
+			return errors.New("-with, -redact, -encrypt, -bench, -examples and -graph generate methods, so they require generating into the source package")
+		}
+		source, qualifier, err := cfg.SourceImport()
+		if err != nil {
+			return err
+		}
+		g.source, g.qualifier = source, qualifier
 	}
 	return g.run()
 }
 
 type generator struct {
-	cfg        codegen.GeneratorConfig
-	methodName string
-	pkg        *ast.Package
-	fset       *token.FileSet
-	imports    map[string]string
-	processed  map[string]bool
+	cfg         codegen.GeneratorConfig
+	methodName  string
+	pkg         *codegen.Package
+	imports     map[string]string
+	processed   map[string]bool
+	values      *codegen.ValueTypes
+	clones      *codegen.CloneTypes
+	selection   *codegen.FieldSelection
+	unsupported []codegen.UnsupportedField // Fields skipped by analyzeFields
+	uncryptable []string                   // Secret fields of types that -encrypt can't encrypt
+	helpers     []*typeNode                // Helpers copying the levels of composite fields
+	source      codegen.ImportInfo         // Import of the source package, when generating into another one
+	qualifier   string                     // Qualifier of the source types in another package, like "config."; copy functions replace methods
+	unreachable []string                   // Unexported types and fields, which functions in another package can't copy
+	graph       string                     // Unexported method copying with the visited map of -graph, like copyGraph; empty without it
+	typeParams  []codegen.TypeParam        // Type parameters of the root type, if it is generic
 }
 
 func (g *generator) run() error {
@@ -59,19 +129,26 @@ func (g *generator) run() error {
 }
 
 func (g *generator) parsePackage() error {
-	pkgs, err := parser.ParseDir(g.fset, g.cfg.SourceDir, nil, parser.ParseComments)
+	if g.cfg.Source != nil {
+		return g.parseSource()
+	}
+	pkg, err := g.cfg.Index.Package(g.cfg.SourceDir)
 	if err != nil {
 		return fmt.Errorf("parsing directory: %w", err)
 	}
-	for name, pkg := range pkgs {
-		if !strings.HasSuffix(name, "_test") {
-			g.pkg = pkg
-			break
-		}
-	}
-	if g.pkg == nil {
-		return fmt.Errorf("no non-test package found in %s", g.cfg.SourceDir)
+	g.pkg = pkg
+	return nil
+}
+
+// parseSource parses the in-memory source in place of the source file. Other files
+// in the source directory that belong to the same package are included so nested
+// types declared elsewhere are still found.
+func (g *generator) parseSource() error {
+	pkg, err := g.cfg.Index.Overlay(g.cfg.SourceDir, g.cfg.SourceFile, g.cfg.Source)
+	if err != nil {
+		return fmt.Errorf("parsing source: %w", err)
 	}
+	g.pkg = pkg
 	return nil
 }
 
@@ -84,31 +161,60 @@ func (g *generator) generateForType(typeName string) error {
 	if err != nil {
 		return fmt.Errorf("building template data: %w", err)
 	}
+	if err := g.checkReachable(typeName); err != nil {
+		return err
+	}
+	if err := g.checkGeneric(); err != nil {
+		return err
+	}
+	if len(g.uncryptable) > 0 {
+		return fmt.Errorf("-encrypt encrypts secret fields of type string, *string and []byte, not %s", strings.Join(g.uncryptable, ", "))
+	}
+	g.cfg, err = codegen.CheckUnsupported(g.cfg, "copy", g.unsupported)
+	if err != nil {
+		return err
+	}
 	return g.writeOutput(typeName, data)
 }
 
+// checkReachable returns an error if the copy functions generated into another package
+// would need unexported types or fields of the source package.
+func (g *generator) checkReachable(typeName string) error {
+	if g.qualifier == "" {
+		return nil
+	}
+	if !ast.IsExported(typeName) {
+		g.unreachable = append(g.unreachable, "type "+typeName)
+	}
+	if len(g.unreachable) == 0 {
+		return nil
+	}
+	slices.Sort(g.unreachable)
+	return fmt.Errorf("package %s can't copy the unexported %s of package %s: export them, exclude the fields with %s:\"-copy\" tags, or generate into package %s",
+		g.cfg.OutputPkg, strings.Join(slices.Compact(g.unreachable), ", "), g.cfg.SourcePkg, codegen.FieldTagKey, g.cfg.SourcePkg)
+}
+
+// checkGeneric returns an error for the flags that don't support a generic root type.
+func (g *generator) checkGeneric() error {
+	if len(g.typeParams) == 0 {
+		return nil
+	}
+	if g.cfg.GenerateWith || g.cfg.RedactSecrets || g.cfg.EncryptSecrets || g.cfg.GenerateTest || g.cfg.GenerateBench || g.cfg.GenerateExamples || g.graph != "" || g.qualifier != "" {
+		return fmt.Errorf("%s is generic, which -with, -redact, -encrypt, -tests, -bench, -examples, -graph and generating into another package don't support", g.cfg.TypeName)
+	}
+	return nil
+}
+
 func (g *generator) findStruct(typeName string) (*ast.StructType, error) {
-	var structType *ast.StructType
-	for _, file := range g.pkg.Files {
-		ast.Inspect(file, func(n ast.Node) bool {
-			ts, ok := n.(*ast.TypeSpec)
-			if !ok || ts.Name.Name != typeName {
-				return true
-			}
-			if st, ok := ts.Type.(*ast.StructType); ok {
-				structType = st
-				g.collectFileImports(file)
-			}
-			return false
-		})
-		if structType != nil {
-			break
-		}
+	st, err := g.pkg.Struct(typeName)
+	if err != nil {
+		return nil, err
 	}
-	if structType == nil {
-		return nil, fmt.Errorf("type %s not found or is not a struct", typeName)
+	if typeName == g.cfg.TypeName {
+		g.typeParams = st.TypeParams
 	}
-	return structType, nil
+	g.collectFileImports(g.pkg.Files[st.File])
+	return st.Type, nil
 }
 
 func (g *generator) collectFileImports(file *ast.File) {
@@ -124,44 +230,171 @@ func (g *generator) collectFileImports(file *ast.File) {
 
 func (g *generator) buildTemplateData(typeName string, st *ast.StructType) (templateData, error) {
 	g.processed[typeName] = true
-	fields := g.analyzeFields(st)
-	imports := g.collectRequiredImports(fields)
+	fields := g.analyzeFields(typeName, st)
+	imports := g.collectRequiredImports(fields, g.cfg.GenerateWith)
 	nestedTypes, err := g.collectNestedTypes(fields)
 	if err != nil {
 		return templateData{}, err
 	}
+	// Nested types are written to the same file, so their imports are needed too.
+	// With{Field} helpers take every exported field type as a parameter
+	for i, nested := range nestedTypes {
+		nestedTypes[i].Root = typeName
+		for _, imp := range nested.Imports {
+			if !slices.Contains(imports, imp) {
+				imports = append(imports, imp)
+			}
+		}
+	}
+	if g.qualifier != "" && !slices.Contains(imports, g.source) {
+		imports = append(imports, g.source)
+	}
+	if g.cfg.EncryptSecrets {
+		for _, path := range []string{"encoding/base64", "fmt"} {
+			if !slices.ContainsFunc(imports, func(imp codegen.ImportInfo) bool { return imp.Path == path }) {
+				imports = append(imports, codegen.ImportInfo{Path: path})
+			}
+		}
+	}
 	return templateData{
-		Package:     g.pkg.Name,
+		Package:     g.cfg.OutputPkg,
+		Source:      g.source,
+		Qualifier:   g.qualifier,
 		TypeName:    typeName,
+		TypeArgs:    g.typeArgs(typeName),
+		Root:        typeName,
 		MethodName:  g.methodName,
+		Redact:      g.cfg.RedactSecrets,
+		Encrypt:     g.cfg.EncryptSecrets,
+		With:        g.cfg.GenerateWith,
+		Bench:       g.cfg.GenerateBench,
 		Fields:      fields,
 		Imports:     imports,
+		TestImports: g.collectRequiredImports(fields, false),
 		NestedTypes: nestedTypes,
+		Helpers:     g.helpers,
+		Graph:       g.graph,
 	}, nil
 }
 
-func (g *generator) analyzeFields(st *ast.StructType) []fieldInfo {
+// typeArgs returns the type arguments of typeName in the receivers of its methods,
+// like "[T]" for the generic root type Box[T any].
+func (g *generator) typeArgs(typeName string) string {
+	if typeName != g.cfg.TypeName {
+		return ""
+	}
+	return codegen.TypeArgList(g.typeParams)
+}
+
+func (g *generator) analyzeFields(typeName string, st *ast.StructType) []fieldInfo {
 	fields := make([]fieldInfo, 0, len(st.Fields.List))
 	for _, field := range st.Fields.List {
+		var tag reflect.StructTag
+		if field.Tag != nil {
+			if unquoted, err := strconv.Unquote(field.Tag.Value); err == nil {
+				tag = reflect.StructTag(unquoted)
+			}
+		}
 		if len(field.Names) == 0 {
+			name := strings.TrimPrefix(exprToString(field.Type), "*")
+			if g.selection.Includes(typeName, name, tag) {
+				g.skip(typeName, name, field, "embedded fields are not supported; name the field")
+			}
 			continue
 		}
 		for _, name := range field.Names {
-			if !ast.IsExported(name.Name) {
+			if g.qualifier != "" && !ast.IsExported(name.Name) && g.selection.Includes(typeName, name.Name, tag) {
+				g.unreachable = append(g.unreachable, "field "+typeName+"."+name.Name)
+				continue
+			}
+			if (!ast.IsExported(name.Name) && !g.cfg.IncludeUnexported) || !g.selection.Includes(typeName, name.Name, tag) {
+				continue
+			}
+			if reason := codegen.UnsupportedType(field.Type); reason != "" {
+				g.skip(typeName, name.Name, field, reason)
+				continue
+			}
+			if p, ok := g.heldTypeParam(typeName, field.Type); ok {
+				// Copied by the Copy method of the type argument, if it has one
+				fi := fieldInfo{Name: name.Name, Type: exprToString(field.Type), TypeExpr: field.Type, TypeParam: &p}
+				switch field.Type.(type) {
+				case *ast.StarExpr:
+					fi.IsPointer = true
+				case *ast.ArrayType:
+					fi.IsSlice = true
+				case *ast.MapType:
+					fi.IsMap = true
+				}
+				fields = append(fields, fi)
+				continue
+			}
+			if typeName == g.cfg.TypeName && codegen.MentionsTypeParam(field.Type, g.typeParams) {
+				g.skip(typeName, name.Name, field, codegen.TypeParamReason)
 				continue
 			}
 			fi := fieldInfo{
 				Name:     name.Name,
 				Type:     exprToString(field.Type),
 				TypeExpr: field.Type,
+				Secret:   codegen.HasTagFlag(tag, codegen.SecretOption),
 			}
 			g.analyzeType(field.Type, &fi)
+			if g.cfg.EncryptSecrets && fi.Secret && fi.Type != "string" && fi.Type != "*string" && fi.Type != "[]byte" {
+				g.uncryptable = append(g.uncryptable, fmt.Sprintf("%s.%s (%s)", typeName, name.Name, fi.Type))
+			}
+			if fi.Recipe = g.clones.Lookup(g.importInfos(), fi.Type); fi.Recipe != nil && fi.Recipe.Clone != "" {
+				// Cloned by the recipe rather than field by field or by helpers
+				fi.IsStruct = false
+				fi.StructTypeName = ""
+				fi.NeedsDeep = false
+				fields = append(fields, fi)
+				continue
+			}
+			if g.isValueType(field.Type) {
+				// Opaque values are assigned rather than copied field by field
+				fi.IsStruct = false
+				fi.StructTypeName = ""
+				fi.SliceElemIsPtr = false
+				fi.NeedsDeep = false
+			}
+			if n := g.node(field.Type); n.nested() {
+				fi.Node, fi.Deep, fi.NeedsDeep = n, n.deepTest(), true
+				g.register(n)
+			} else if g.qualifier != "" || g.graph != "" {
+				// Copy functions, and copies threading the visited map of -graph, copy
+				// every field through its node
+				fi.Node = n
+				g.register(n)
+			}
 			fields = append(fields, fi)
 		}
 	}
 	return fields
 }
 
+// heldTypeParam returns the type parameter of the root type a field of typeName holds
+// (see codegen.HeldTypeParam).
+func (g *generator) heldTypeParam(typeName string, expr ast.Expr) (codegen.TypeParam, bool) {
+	if typeName != g.cfg.TypeName {
+		return codegen.TypeParam{}, false
+	}
+	return codegen.HeldTypeParam(expr, g.typeParams)
+}
+
+// skip records a field that can't be copied, to be reported by codegen.CheckUnsupported.
+func (g *generator) skip(typeName, name string, field *ast.Field, reason string) {
+	u := codegen.UnsupportedField{
+		Struct: typeName,
+		Field:  codegen.FieldInfo{Name: name, Type: exprToString(field.Type)},
+		Pos:    g.pkg.Fset.Position(field.Pos()).String(),
+		Reason: reason,
+	}
+	if field.Tag != nil {
+		u.Field.Tag = field.Tag.Value
+	}
+	g.unsupported = append(g.unsupported, u)
+}
+
 func (g *generator) analyzeType(expr ast.Expr, fi *fieldInfo) {
 	switch t := expr.(type) {
 	case *ast.StarExpr:
@@ -176,6 +409,7 @@ func (g *generator) analyzeType(expr ast.Expr, fi *fieldInfo) {
 	case *ast.ArrayType:
 		fi.IsSlice = true
 		fi.ElemType = exprToString(t.Elt)
+		fi.IsBytes = t.Len == nil && (fi.ElemType == "byte" || fi.ElemType == "uint8")
 		switch elt := t.Elt.(type) {
 		case *ast.Ident:
 			if !isBasicType(elt.Name) {
@@ -233,35 +467,77 @@ func (g *generator) analyzeType(expr ast.Expr, fi *fieldInfo) {
 	}
 }
 
+// isValueType reports whether the named type in expr, or its slice or map element,
+// has value semantics.
+func (g *generator) isValueType(expr ast.Expr) bool {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return g.isValueType(t.X)
+	case *ast.ArrayType:
+		return g.isValueType(t.Elt)
+	case *ast.MapType:
+		return g.isValueType(t.Value)
+	case *ast.Ident:
+		return !isBasicType(t.Name) && g.values.IsValue(g.cfg.SourceDir, nil, "", t.Name)
+	case *ast.SelectorExpr:
+		pkg, ok := t.X.(*ast.Ident)
+		if !ok {
+			return false
+		}
+		return g.values.IsValue(g.cfg.SourceDir, g.importInfos(), pkg.Name, t.Sel.Name)
+	}
+	return false
+}
+
+// importInfos returns the imports of the source files.
+func (g *generator) importInfos() []codegen.ImportInfo {
+	imports := make([]codegen.ImportInfo, 0, len(g.imports))
+	for path, alias := range g.imports {
+		imports = append(imports, codegen.ImportInfo{Path: path, Alias: alias})
+	}
+	return imports
+}
+
 func (g *generator) collectNestedTypes(fields []fieldInfo) ([]templateData, error) {
 	var nested []templateData
 	seen := make(map[string]bool)
 	for _, f := range fields {
-		if f.StructTypeName == "" || seen[f.StructTypeName] || g.processed[f.StructTypeName] {
-			continue
+		names := []string{f.StructTypeName}
+		if f.Node != nil {
+			names = f.Node.structs()
 		}
-		seen[f.StructTypeName] = true
-		st, err := g.findStruct(f.StructTypeName)
-		if err != nil {
-			continue
-		}
-		data, err := g.buildTemplateData(f.StructTypeName, st)
-		if err != nil {
-			return nil, err
+		for _, name := range names {
+			if name == "" || seen[name] || g.processed[name] {
+				continue
+			}
+			seen[name] = true
+			st, err := g.findStruct(name)
+			if errors.Is(err, codegen.ErrAmbiguousType) {
+				return nil, err
+			}
+			if err != nil {
+				continue
+			}
+			data, err := g.buildTemplateData(name, st)
+			if err != nil {
+				return nil, err
+			}
+			data.IsNestedType = true
+			nested = append(nested, data)
+			// Flatten: also add nested types from this type
+			nested = append(nested, data.NestedTypes...)
+			data.NestedTypes = nil // Clear to avoid duplication in template
 		}
-		data.IsNestedType = true
-		nested = append(nested, data)
-		// Flatten: also add nested types from this type
-		nested = append(nested, data.NestedTypes...)
-		data.NestedTypes = nil // Clear to avoid duplication in template
 	}
 	return nested, nil
 }
 
-func (g *generator) collectRequiredImports(fields []fieldInfo) []codegen.ImportInfo {
+// collectRequiredImports returns the imports of the slice and map types of fields,
+// and of all exported field types if exported is set.
+func (g *generator) collectRequiredImports(fields []fieldInfo, exported bool) []codegen.ImportInfo {
 	needed := make(map[string]string)
 	for _, f := range fields {
-		if f.IsSlice || f.IsMap {
+		if f.IsSlice || f.IsMap || f.Node != nil || exported && ast.IsExported(f.Name) {
 			g.collectImportsFromType(f.TypeExpr, needed)
 		}
 	}
@@ -308,25 +584,100 @@ func (g *generator) collectImportsFromType(expr ast.Expr, needed map[string]stri
 func (g *generator) writeOutput(typeName string, data templateData) error {
 	baseName := strings.TrimSuffix(g.cfg.SourceFile, ".go")
 	outputFile := filepath.Join(g.cfg.OutputDir, baseName+"_copy.go")
-	gen := codegen.NewTemplateGenerator(templateFuncs())
-	if err := gen.GenerateFile(outputFile, copyTemplate, data); err != nil {
+	gen := codegen.NewTemplateGenerator(g.cfg, templateFuncs())
+	tmpl, testTmpl := copyTemplate, copyTestTemplate
+	if g.qualifier != "" {
+		tmpl, testTmpl = copyFuncsTemplate, copyFuncsTestTemplate
+	}
+	if err := gen.GenerateFile(outputFile, tmpl+copyHelpersTemplate, data); err != nil {
 		return err
 	}
+	if needsDeepCopyAny(data) {
+		if err := codegen.WriteHelpers(g.cfg, codegen.HelperDeepCopyAny); err != nil {
+			return err
+		}
+	}
+	if len(g.typeParams) > 0 {
+		// Declares Copier, which constraints of the type parameters may refer to
+		if err := codegen.WriteHelpers(g.cfg, codegen.HelperCopyValue); err != nil {
+			return err
+		}
+	}
 	if g.cfg.GenerateTest {
 		testFile := filepath.Join(g.cfg.OutputDir, baseName+"_copy_test.go")
-		return gen.GenerateFile(testFile, copyTestTemplate, data)
+		if err := gen.GenerateFile(testFile, testTmpl, data); err != nil {
+			return err
+		}
+	}
+	if g.cfg.GenerateBench || g.cfg.GenerateExamples {
+		sample, err := codegen.SampleLiteral(g.cfg)
+		if err != nil {
+			return err
+		}
+		data.Sample = sample
+	}
+	if g.cfg.GenerateBench {
+		benchFile := filepath.Join(g.cfg.OutputDir, baseName+"_copy_bench_test.go")
+		if err := gen.GenerateFile(benchFile, copyBenchTemplate, data); err != nil {
+			return err
+		}
+	}
+	if g.cfg.GenerateExamples {
+		if !ast.IsExported(typeName) {
+			return fmt.Errorf("-examples documents exported types, and %s is unexported", typeName)
+		}
+		data.ExampleField = exampleField(data.Fields)
+		exampleFile := filepath.Join(g.cfg.OutputDir, baseName+"_copy_example_test.go")
+		return gen.GenerateFile(exampleFile, copyExampleTemplate, data)
 	}
 	return nil
 }
 
+// exampleField returns the first string field, which the example changes in the copy
+// to show that the original keeps its value, or "" if there is none.
+func exampleField(fields []fieldInfo) string {
+	for _, f := range fields {
+		if f.Type == "string" && ast.IsExported(f.Name) {
+			return f.Name
+		}
+	}
+	return ""
+}
+
+// needsDeepCopyAny reports whether a map field of the types in data holds decoded
+// values, copied by codegen.HelperDeepCopyAny.
+func needsDeepCopyAny(data templateData) bool {
+	for _, t := range append([]templateData{data}, data.NestedTypes...) {
+		for _, f := range t.Fields {
+			if f.IsMap && f.NeedsDeep && f.StructTypeName == "" && f.Node == nil || f.Node != nil && f.Node.copiesAny() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 type templateData struct {
 	Package      string
 	TypeName     string
+	TypeArgs     string             // Type arguments of a generic TypeName in receivers, like "[T]"
+	Qualifier    string             // Qualifier of TypeName in another package; functions replace methods
+	Source       codegen.ImportInfo // Import of the package of TypeName, with Qualifier
+	Root         string             // Type the file is generated for, which names shared helpers
 	MethodName   string
+	Redact       bool   // Also generate Redacted
+	Encrypt      bool   // Also generate EncryptSecrets and DecryptSecrets
+	With         bool   // Also generate With and With{Field}
+	Bench        bool   // Benchmarks go in their own file instead of the test file
+	Sample       string // Literal of a populated TypeName, for benchmarks and examples
+	ExampleField string // String field the example changes in the copy, if any
 	Fields       []fieldInfo
 	Imports      []codegen.ImportInfo
+	TestImports  []codegen.ImportInfo // Imports of the slice and map types used by tests
 	NestedTypes  []templateData
 	IsNestedType bool
+	Helpers      []*typeNode // Helpers of the composite fields of every type in the file
+	Graph        string      // Unexported method copying with the visited map of -graph; empty without it
 }
 
 type fieldInfo struct {
@@ -335,6 +686,7 @@ type fieldInfo struct {
 	TypeExpr       ast.Expr
 	IsPointer      bool
 	IsSlice        bool
+	IsBytes        bool // A []byte, copied with bytes.Clone
 	IsMap          bool
 	IsStruct       bool
 	ElemType       string
@@ -343,11 +695,22 @@ type fieldInfo struct {
 	NeedsDeep      bool
 	StructTypeName string
 	SliceElemIsPtr bool
+	Secret         bool                 // Tagged sudo:"secret", cleared by Redacted and encrypted by EncryptSecrets
+	Recipe         *codegen.CloneRecipe // Clone of a type with pointer semantics, like *big.Int
+	Node           *typeNode            // Set for composite types copied by helpers, like []map[string]Tag
+	Deep           *deepTest            // Test of the copy of every level of Node
+	TypeParam      *codegen.TypeParam   // Type parameter of the root type the field holds, like T in []T
 }
 
 func templateFuncs() template.FuncMap {
 	return template.FuncMap{
-		"lower": strings.ToLower,
+		"lower":     strings.ToLower,
+		"hasPrefix": strings.HasPrefix,
+		"exported":  ast.IsExported,
+		"copyValue": func(p *codegen.TypeParam, x string) string { return codegen.CopyValue(*p, x) },
+		"hasSecret": func(fields []fieldInfo) bool {
+			return slices.ContainsFunc(fields, func(f fieldInfo) bool { return f.Secret })
+		},
 	}
 }
 
@@ -0,0 +1,360 @@
+package copy
+
+import (
+	"go/ast"
+	"go/types"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/bobcob7/sudo-gen/internal/codegen"
+)
+
+// typeNode is a field type broken down level by level, so that composite types like
+// []map[string]Tag and map[string][]*DatabaseConfig are deep copied at every level
+// by a generated helper function per level.
+type typeNode struct {
+	Expr   string // Go type, e.g. []map[string]Tag
+	Kind   string // value (assigned), any, struct, structPtr, slice, map or pointer
+	Key    string // Key type of a map
+	Elem   *typeNode
+	Struct string // Struct type with a copy method, for struct and structPtr
+	Helper string // Function copying a slice, map or pointer
+	Redact string // Function clearing the secrets of the structs a slice, map or pointer holds
+	Crypt  string // Function encrypting or decrypting the secrets of the structs a slice, map or pointer holds
+	method string // Copy method of structs
+	funcs  bool   // Structs are copied by functions named method+Struct (see generator.qualifier)
+	graph  string // Method of structs threading the visited map of -graph; helpers take it too
+}
+
+// node returns the typeNode of expr. Slices, maps and pointers are named helpers, which
+// register adds to the file when a field needs them.
+func (g *generator) node(expr ast.Expr) *typeNode {
+	n := &typeNode{Expr: g.typeString(expr), Kind: "value", method: g.methodName, funcs: g.qualifier != "", graph: g.graph}
+	switch t := expr.(type) {
+	case *ast.ArrayType:
+		if t.Len != nil {
+			return n
+		}
+		n.Kind, n.Elem = "slice", g.node(t.Elt)
+	case *ast.MapType:
+		n.Kind, n.Key, n.Elem = "map", g.typeString(t.Key), g.node(t.Value)
+	case *ast.StarExpr:
+		if name := g.structName(t.X); name != "" {
+			n.Kind, n.Struct = "structPtr", name
+			return n
+		}
+		n.Kind, n.Elem = "pointer", g.node(t.X)
+	case *ast.Ident:
+		if name := g.structName(t); name != "" {
+			n.Kind, n.Struct = "struct", name
+		} else if t.Name == "any" {
+			n.Kind = "any"
+		}
+		return n
+	case *ast.InterfaceType:
+		if t.Methods == nil || len(t.Methods.List) == 0 {
+			n.Kind = "any"
+		}
+		return n
+	default:
+		return n
+	}
+	root := capitalize(g.cfg.TypeName)
+	n.Helper = "copy" + root + n.mangle()
+	if g.cfg.RedactSecrets && n.holdsStructs() {
+		n.Redact = "redact" + root + n.mangle()
+	}
+	if g.cfg.EncryptSecrets && n.holdsStructs() {
+		n.Crypt = "crypt" + root + n.mangle()
+	}
+	return n
+}
+
+// typeString returns expr as written in the generated file. Generating into another
+// package, the types of the source package are qualified, and those that aren't
+// exported are recorded as unreachable.
+func (g *generator) typeString(expr ast.Expr) string {
+	if g.qualifier == "" {
+		return types.ExprString(expr)
+	}
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if types.Universe.Lookup(t.Name) != nil {
+			return t.Name
+		}
+		if !ast.IsExported(t.Name) {
+			g.unreachable = append(g.unreachable, "type "+t.Name)
+		}
+		return g.qualifier + t.Name
+	case *ast.StarExpr:
+		return "*" + g.typeString(t.X)
+	case *ast.ArrayType:
+		if t.Len != nil {
+			return "[" + types.ExprString(t.Len) + "]" + g.typeString(t.Elt)
+		}
+		return "[]" + g.typeString(t.Elt)
+	case *ast.MapType:
+		return "map[" + g.typeString(t.Key) + "]" + g.typeString(t.Value)
+	}
+	return types.ExprString(expr)
+}
+
+// register adds the helpers of n and of its levels to the file, once per type.
+func (g *generator) register(n *typeNode) {
+	if n.Helper == "" || slices.ContainsFunc(g.helpers, func(h *typeNode) bool { return h.Expr == n.Expr }) {
+		return
+	}
+	g.helpers = append(g.helpers, n)
+	g.register(n.Elem)
+}
+
+// structName returns the name of the struct type of the package in expr, which gets a
+// copy method, or "" if expr is another type.
+func (g *generator) structName(expr ast.Expr) string {
+	ident, ok := expr.(*ast.Ident)
+	if !ok || isBasicType(ident.Name) || g.isValueType(ident) {
+		return ""
+	}
+	if _, err := g.pkg.Struct(ident.Name); err != nil {
+		return ""
+	}
+	return ident.Name
+}
+
+// nested reports whether a field of the type needs helpers: a slice or map whose
+// elements are slices, maps or pointers to anything but structs, a map of pointers to
+// structs, or a pointer to a slice or map. The copy methods handle other types inline.
+func (n *typeNode) nested() bool {
+	switch n.Kind {
+	case "slice":
+		return n.Elem.Helper != ""
+	case "map":
+		return n.Elem.Helper != "" || n.Elem.Kind == "structPtr"
+	case "pointer":
+		return n.Elem.Helper != "" && n.Elem.Kind != "pointer"
+	}
+	return false
+}
+
+func (n *typeNode) holdsStructs() bool {
+	switch n.Kind {
+	case "struct", "structPtr":
+		return true
+	case "slice", "map", "pointer":
+		return n.Elem.holdsStructs()
+	}
+	return false
+}
+
+// copiesAny reports whether a level of n holds decoded values, copied by
+// codegen.HelperDeepCopyAny.
+func (n *typeNode) copiesAny() bool {
+	return n.Kind == "any" || n.Elem != nil && n.Elem.copiesAny()
+}
+
+// structs returns the struct types at the levels of n.
+func (n *typeNode) structs() []string {
+	if n.Struct != "" {
+		return []string{n.Struct}
+	}
+	if n.Elem != nil {
+		return n.Elem.structs()
+	}
+	return nil
+}
+
+// mangle returns a name for the type usable in identifiers, e.g. SliceOfMapOfStringToTag.
+func (n *typeNode) mangle() string {
+	switch n.Kind {
+	case "slice":
+		return "SliceOf" + n.Elem.mangle()
+	case "map":
+		return "MapOf" + identifier(n.Key) + "To" + n.Elem.mangle()
+	case "pointer":
+		return "PtrTo" + n.Elem.mangle()
+	case "structPtr":
+		return "PtrTo" + identifier(n.Struct)
+	case "struct":
+		return identifier(n.Struct)
+	}
+	return identifier(n.Expr)
+}
+
+// identifier turns a type expression like time.Time or [4]byte into TimeTime or
+// Array4Byte.
+func identifier(expr string) string {
+	var b strings.Builder
+	if strings.HasPrefix(expr, "[") {
+		b.WriteString("Array")
+	}
+	for _, part := range strings.FieldsFunc(expr, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		b.WriteString(capitalize(part))
+	}
+	return b.String()
+}
+
+// Graph returns the method of structs threading the visited map of -graph, or "".
+func (n *typeNode) Graph() string {
+	return n.graph
+}
+
+// CopyOf returns an expression deep copying v, a value of the type. With -graph, it
+// records the copies of pointers in visited.
+func (n *typeNode) CopyOf(v string) string {
+	switch {
+	case n.Helper != "" && n.graph != "":
+		return n.Helper + "(" + v + ", visited)"
+	case n.Helper != "":
+		return n.Helper + "(" + v + ")"
+	case n.graph != "" && n.Kind == "struct":
+		return "*" + v + "." + n.graph + "(visited)"
+	case n.graph != "" && n.Kind == "structPtr":
+		return v + "." + n.graph + "(visited)"
+	case n.Kind == "any":
+		return codegen.HelperDeepCopyAny + "(" + v + ")"
+	case n.funcs && n.Kind == "struct":
+		return "*" + n.method + n.Struct + "(&" + v + ")"
+	case n.funcs && n.Kind == "structPtr":
+		return n.method + n.Struct + "(" + v + ")"
+	case n.Kind == "struct":
+		return "*" + v + "." + n.method + "()"
+	case n.Kind == "structPtr":
+		return v + "." + n.method + "()"
+	}
+	return v
+}
+
+// RedactOf returns a statement clearing the secrets of the structs in v, a value of
+// the type, in place.
+func (n *typeNode) RedactOf(v string) string {
+	if n.Redact != "" {
+		return n.Redact + "(" + v + ")"
+	}
+	return v + ".redactSecrets()"
+}
+
+// CryptOf returns an expression transforming the secrets of the structs in v, a value
+// of the type, in place with crypt, and evaluating to an error.
+func (n *typeNode) CryptOf(v string) string {
+	if n.Crypt != "" {
+		return n.Crypt + "(" + v + ", crypt)"
+	}
+	return v + ".cryptSecrets(crypt)"
+}
+
+// deepTest is a generated test checking that a field is copied at every level.
+type deepTest struct {
+	Literal  string // Value of the field with one element at every level
+	Inner    string // Format of the innermost slice or map of the field, given the field
+	Slice    bool   // The innermost is a slice; otherwise a map
+	AddKey   string // Key added to an innermost map
+	AddValue string
+}
+
+// deepTest returns the test of a field of the type, or nil if the type has map keys
+// the test can't build.
+func (n *typeNode) deepTest() *deepTest {
+	lit, inner, ok := n.sample(false)
+	if !ok || inner == nil {
+		return nil
+	}
+	t := &deepTest{Literal: lit, Inner: inner.path, Slice: inner.node.Kind == "slice"}
+	if !t.Slice {
+		t.AddKey, _ = sampleKey(inner.node.Key, 1)
+		t.AddValue, _, _ = inner.node.Elem.sample(false)
+	}
+	return t
+}
+
+// innermost is the innermost slice or map of a sample.
+type innermost struct {
+	node *typeNode
+	path string // Format of the expression reaching it from the sample (%s)
+}
+
+// sample returns a literal of the type holding one element at every level, and the
+// innermost slice or map in it. The type of an element literal is elided, as the
+// composite literal holding it allows.
+func (n *typeNode) sample(elem bool) (string, *innermost, bool) {
+	typ := n.Expr
+	if elem {
+		typ = ""
+	}
+	switch n.Kind {
+	case "struct":
+		return typ + "{}", nil, true
+	case "structPtr":
+		if elem {
+			return "{}", nil, true
+		}
+		return "&" + n.Struct + "{}", nil, true
+	case "value", "any":
+		return zeroLiteral(n.Expr), nil, true
+	case "pointer":
+		if n.Elem.Kind != "slice" && n.Elem.Kind != "map" {
+			return "new(" + n.Elem.Expr + ")", nil, true
+		}
+		lit, inner, ok := n.Elem.sample(false)
+		if !ok {
+			return "", nil, false
+		}
+		return "&" + lit, &innermost{node: inner.node, path: strings.Replace(inner.path, "%s", "(*%s)", 1)}, true
+	}
+	elemLit, inner, ok := n.Elem.sample(true)
+	if !ok {
+		return "", nil, false
+	}
+	index := "[0]"
+	lit := typ + "{" + elemLit + "}"
+	if n.Kind == "map" {
+		key, ok := sampleKey(n.Key, 0)
+		if !ok {
+			return "", nil, false
+		}
+		index = "[" + key + "]"
+		lit = typ + "{" + key + ": " + elemLit + "}"
+	}
+	if inner == nil {
+		return lit, &innermost{node: n, path: "%s"}, true
+	}
+	return lit, &innermost{node: inner.node, path: strings.Replace(inner.path, "%s", "%s"+index, 1)}, true
+}
+
+// sampleKey returns the i-th of two distinct map keys of a basic type.
+func sampleKey(typ string, i int) (string, bool) {
+	switch {
+	case typ == "string":
+		return `"` + string(rune('a'+i)) + `"`, true
+	case isBasicType(typ) && strings.Contains(typ, "int"):
+		return string(rune('1' + i)), true
+	}
+	return "", false
+}
+
+func zeroLiteral(typ string) string {
+	switch {
+	case typ == "string":
+		return `""`
+	case typ == "bool":
+		return "false"
+	case isBasicType(typ) && typ != "any":
+		return "0"
+	}
+	return "*new(" + typ + ")"
+}
+
+func capitalize(name string) string {
+	if name == "" {
+		return name
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// unexport lowercases the first letter of name, so that Copy becomes copy.
+func unexport(name string) string {
+	if name == "" {
+		return name
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
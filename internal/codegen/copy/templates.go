@@ -10,14 +10,30 @@ import (
 {{end}})
 
 {{end -}}
+{{if .Graph -}}
+{{template "graph" .}}
+{{- template "helpers" .}}
+{{- range .NestedTypes}}
+{{template "graph" .}}
+{{- end}}
+{{- else -}}
 // {{.MethodName}} creates a deep copy of the {{.TypeName}}.
-func (c *{{.TypeName}}) {{.MethodName}}() *{{.TypeName}} {
+{{- if .TypeArgs}} Values of a type parameter are copied with their
+// Copy method if they have one, and assigned otherwise.
+{{- end}}
+func (c *{{.TypeName}}{{.TypeArgs}}) {{.MethodName}}() *{{.TypeName}}{{.TypeArgs}} {
 	if c == nil {
 		return nil
 	}
-	dst := &{{.TypeName}}{}
+	dst := &{{.TypeName}}{{.TypeArgs}}{}
 {{- range .Fields}}
-{{- if .IsPointer}}
+{{- if .TypeParam}}
+{{- template "copyTypeParam" .}}
+{{- else if and .Recipe .Recipe.Clone}}
+{{- template "cloneRecipe" .}}
+{{- else if .Node}}
+	dst.{{.Name}} = {{.Node.CopyOf (print "c." .Name)}}
+{{- else if .IsPointer}}
 {{- if .StructTypeName}}
 	if c.{{.Name}} != nil {
 		dst.{{.Name}} = c.{{.Name}}.{{$.MethodName}}()
@@ -28,6 +44,8 @@ func (c *{{.TypeName}}) {{.MethodName}}() *{{.TypeName}} {
 		dst.{{.Name}} = &v
 	}
 {{- end}}
+{{- else if .IsBytes}}
+	dst.{{.Name}} = bytes.Clone(c.{{.Name}})
 {{- else if .IsSlice}}
 {{- if .NeedsDeep}}
 {{- if .SliceElemIsPtr}}
@@ -69,7 +87,7 @@ func (c *{{.TypeName}}) {{.MethodName}}() *{{.TypeName}} {
 	if c.{{.Name}} != nil {
 		dst.{{.Name}} = make({{.Type}}, len(c.{{.Name}}))
 		for k, v := range c.{{.Name}} {
-			dst.{{.Name}}[k] = deepCopy{{$.TypeName}}Any(v)
+			dst.{{.Name}}[k] = sudogenDeepCopyAny(v)
 		}
 	}
 {{- end}}
@@ -91,37 +109,7 @@ func (c *{{.TypeName}}) {{.MethodName}}() *{{.TypeName}} {
 {{- end}}
 	return dst
 }
-{{range .Fields}}{{if and .IsMap .NeedsDeep (not .StructTypeName)}}
-func deepCopy{{$.TypeName}}Any(v any) any {
-	if v == nil {
-		return nil
-	}
-	switch val := v.(type) {
-	case map[string]any:
-		m := make(map[string]any, len(val))
-		for k, v := range val {
-			m[k] = deepCopy{{$.TypeName}}Any(v)
-		}
-		return m
-	case []any:
-		s := make([]any, len(val))
-		for i, v := range val {
-			s[i] = deepCopy{{$.TypeName}}Any(v)
-		}
-		return s
-	case []string:
-		s := make([]string, len(val))
-		copy(s, val)
-		return s
-	case []int:
-		s := make([]int, len(val))
-		copy(s, val)
-		return s
-	default:
-		return val
-	}
-}
-{{break}}{{end}}{{end}}
+{{- template "helpers" .}}
 {{- range .NestedTypes}}
 
 func (c *{{.TypeName}}) {{.MethodName}}() *{{.TypeName}} {
@@ -130,7 +118,11 @@ func (c *{{.TypeName}}) {{.MethodName}}() *{{.TypeName}} {
 	}
 	dst := &{{.TypeName}}{}
 {{- range .Fields}}
-{{- if .IsPointer}}
+{{- if and .Recipe .Recipe.Clone}}
+{{- template "cloneRecipe" .}}
+{{- else if .Node}}
+	dst.{{.Name}} = {{.Node.CopyOf (print "c." .Name)}}
+{{- else if .IsPointer}}
 {{- if .StructTypeName}}
 	if c.{{.Name}} != nil {
 		dst.{{.Name}} = c.{{.Name}}.{{$.MethodName}}()
@@ -141,6 +133,8 @@ func (c *{{.TypeName}}) {{.MethodName}}() *{{.TypeName}} {
 		dst.{{.Name}} = &v
 	}
 {{- end}}
+{{- else if .IsBytes}}
+	dst.{{.Name}} = bytes.Clone(c.{{.Name}})
 {{- else if .IsSlice}}
 {{- if .NeedsDeep}}
 {{- if .SliceElemIsPtr}}
@@ -182,7 +176,7 @@ func (c *{{.TypeName}}) {{.MethodName}}() *{{.TypeName}} {
 	if c.{{.Name}} != nil {
 		dst.{{.Name}} = make({{.Type}}, len(c.{{.Name}}))
 		for k, v := range c.{{.Name}} {
-			dst.{{.Name}}[k] = deepCopy{{$.TypeName}}Any(v)
+			dst.{{.Name}}[k] = sudogenDeepCopyAny(v)
 		}
 	}
 {{- end}}
@@ -205,6 +199,545 @@ func (c *{{.TypeName}}) {{.MethodName}}() *{{.TypeName}} {
 	return dst
 }
 {{- end}}
+{{template "copyInto" .}}
+{{- range .NestedTypes}}
+{{template "copyInto" .}}
+{{- end}}
+{{- end}}
+{{- if .Redact}}
+{{template "redact" .}}
+{{- range .NestedTypes}}
+{{template "redact" .}}
+{{- end}}
+{{- end}}
+{{- if .Encrypt}}
+{{template "keyring" .}}
+{{template "crypt" .}}
+{{- range .NestedTypes}}
+{{template "crypt" .}}
+{{- end}}
+{{- end}}
+{{- if .With}}
+{{template "with" .}}
+{{- range .NestedTypes}}
+{{template "with" .}}
+{{- end}}
+{{- end}}
+{{- define "graph"}}
+// {{.MethodName}} creates a deep copy of the {{.TypeName}}. Pointers shared within it are copied
+// once and shared within the copy, and cycles are copied as cycles.
+func (c *{{.TypeName}}) {{.MethodName}}() *{{.TypeName}} {
+	return c.{{.Graph}}(make(map[any]any))
+}
+
+// {{.Graph}} deep copies c, recording the copy of each pointer it reaches in visited
+// so that a pointer reached again is given the same copy.
+func (c *{{.TypeName}}) {{.Graph}}(visited map[any]any) *{{.TypeName}} {
+	if c == nil {
+		return nil
+	}
+	if dst, ok := visited[c]; ok {
+		return dst.(*{{.TypeName}})
+	}
+	dst := &{{.TypeName}}{}
+	c.{{.Graph}}Fields(dst, visited)
+	return dst
+}
+
+// {{.Graph}}Fields deep copies the fields of c into dst, recording dst as the copy of c
+// in visited first so that the cycles through c lead back to dst.
+func (c *{{.TypeName}}) {{.Graph}}Fields(dst *{{.TypeName}}, visited map[any]any) {
+	visited[c] = dst
+{{- range .Fields}}
+{{- if and .Recipe .Recipe.Clone}}
+{{- if .Recipe.Nilable}}
+	if c.{{.Name}} == nil {
+		dst.{{.Name}} = nil
+	} else {
+		dst.{{.Name}} = {{.Recipe.CloneOf (print "c." .Name)}}
+	}
+{{- else}}
+	dst.{{.Name}} = {{.Recipe.CloneOf (print "c." .Name)}}
+{{- end}}
+{{- else if eq .Node.Kind "struct"}}
+	c.{{.Name}}.{{$.Graph}}Fields(&dst.{{.Name}}, visited)
+{{- else}}
+	dst.{{.Name}} = {{.Node.CopyOf (print "c." .Name)}}
+{{- end}}
+{{- end}}
+}
+
+// {{.MethodName}}Into deep copies c into dst as {{.MethodName}} does, so that the cycles through c
+// lead back to dst. The slices, maps and pointers dst already holds are replaced rather
+// than reused. If c is nil, dst is reset to the zero value.
+func (c *{{.TypeName}}) {{.MethodName}}Into(dst *{{.TypeName}}) {
+	if c == nil {
+		*dst = {{.TypeName}}{}
+		return
+	}
+	c.{{.Graph}}Fields(dst, make(map[any]any))
+}
+{{- end}}
+{{- define "copyInto"}}
+// {{.MethodName}}Into deep copies c into dst, reusing the slices, maps and nested structs
+// dst already holds where possible instead of allocating new ones. dst must not share
+// memory with values still in use elsewhere. If c is nil, dst is reset to the zero value.
+func (c *{{.TypeName}}{{.TypeArgs}}) {{.MethodName}}Into(dst *{{.TypeName}}{{.TypeArgs}}) {
+	if c == nil {
+		*dst = {{.TypeName}}{{.TypeArgs}}{}
+		return
+	}
+{{- range .Fields}}
+{{- if .TypeParam}}
+{{- template "copyTypeParam" .}}
+{{- else if and .Recipe .Recipe.Clone}}
+{{- if .Recipe.Nilable}}
+	if c.{{.Name}} == nil {
+		dst.{{.Name}} = nil
+	} else {
+		dst.{{.Name}} = {{.Recipe.CloneOf (print "c." .Name)}}
+	}
+{{- else}}
+	dst.{{.Name}} = {{.Recipe.CloneOf (print "c." .Name)}}
+{{- end}}
+{{- else if .Node}}
+	dst.{{.Name}} = {{.Node.CopyOf (print "c." .Name)}}
+{{- else if .IsPointer}}
+	if c.{{.Name}} == nil {
+		dst.{{.Name}} = nil
+{{- if .StructTypeName}}
+	} else {
+		if dst.{{.Name}} == nil {
+			dst.{{.Name}} = new({{.StructTypeName}})
+		}
+		c.{{.Name}}.{{$.MethodName}}Into(dst.{{.Name}})
+	}
+{{- else}}
+	} else if dst.{{.Name}} == nil {
+		v := *c.{{.Name}}
+		dst.{{.Name}} = &v
+	} else {
+		*dst.{{.Name}} = *c.{{.Name}}
+	}
+{{- end}}
+{{- else if .IsSlice}}
+	if c.{{.Name}} == nil {
+		dst.{{.Name}} = nil
+	} else {
+		if dst.{{.Name}} == nil {
+			dst.{{.Name}} = make({{.Type}}, 0, len(c.{{.Name}}))
+		}
+{{- if and .NeedsDeep .StructTypeName}}
+		dst.{{.Name}} = slices.Grow(dst.{{.Name}}[:0], len(c.{{.Name}}))[:len(c.{{.Name}})]
+{{- if .SliceElemIsPtr}}
+		for i, v := range c.{{.Name}} {
+			if v == nil {
+				dst.{{.Name}}[i] = nil
+				continue
+			}
+			if dst.{{.Name}}[i] == nil {
+				dst.{{.Name}}[i] = new({{.StructTypeName}})
+			}
+			v.{{$.MethodName}}Into(dst.{{.Name}}[i])
+		}
+{{- else}}
+		for i := range c.{{.Name}} {
+			c.{{.Name}}[i].{{$.MethodName}}Into(&dst.{{.Name}}[i])
+		}
+{{- end}}
+{{- else}}
+		dst.{{.Name}} = append(slices.Grow(dst.{{.Name}}[:0], len(c.{{.Name}})), c.{{.Name}}...)
+{{- end}}
+	}
+{{- else if .IsMap}}
+	if c.{{.Name}} == nil {
+		dst.{{.Name}} = nil
+	} else {
+		if dst.{{.Name}} == nil {
+			dst.{{.Name}} = make({{.Type}}, len(c.{{.Name}}))
+		} else {
+			clear(dst.{{.Name}})
+		}
+{{- if and .NeedsDeep .StructTypeName (not (eq .ValueType "any"))}}
+		for k, v := range c.{{.Name}} {
+			dst.{{.Name}}[k] = *v.{{$.MethodName}}()
+		}
+{{- else if .NeedsDeep}}
+		for k, v := range c.{{.Name}} {
+			dst.{{.Name}}[k] = sudogenDeepCopyAny(v)
+		}
+{{- else}}
+		maps.Copy(dst.{{.Name}}, c.{{.Name}})
+{{- end}}
+	}
+{{- else if and .IsStruct .StructTypeName}}
+	c.{{.Name}}.{{$.MethodName}}Into(&dst.{{.Name}})
+{{- else}}
+	dst.{{.Name}} = c.{{.Name}}
+{{- end}}
+{{- end}}
+}
+{{- end}}
+{{- define "with"}}
+// {{method "With"}} returns a deep copy of the {{.TypeName}} with opts applied to it, in order. A nil c
+// is copied as the zero value.
+func (c *{{.TypeName}}) {{method "With"}}(opts ...func(*{{.TypeName}})) {{.TypeName}} {
+	dst := c.{{.MethodName}}()
+	if dst == nil {
+		dst = &{{.TypeName}}{}
+	}
+	for _, opt := range opts {
+		opt(dst)
+	}
+	return *dst
+}
+{{- $struct := .}}
+{{- range .Fields}}
+{{- if exported .Name}}
+
+// {{method "With"}}{{.Name}} returns a deep copy of the {{$struct.TypeName}} with {{.Name}} set to v.
+func (c *{{$struct.TypeName}}) {{method "With"}}{{.Name}}(v {{.Type}}) {{$struct.TypeName}} {
+	return c.{{method "With"}}(func(dst *{{$struct.TypeName}}) { dst.{{.Name}} = v })
+}
+{{- end}}
+{{- end}}
+{{- end}}
+{{- define "redact"}}
+// {{method "Redacted"}} returns a deep copy of the {{.TypeName}} with the fields tagged
+// sudo:"secret" cleared, including those of nested structs, for logging and display.
+func (c *{{.TypeName}}) {{method "Redacted"}}() *{{.TypeName}} {
+	dst := c.{{.MethodName}}()
+	dst.redactSecrets()
+	return dst
+}
+
+// redactSecrets clears the fields of c tagged sudo:"secret" in place.
+func (c *{{.TypeName}}) redactSecrets() {
+	if c == nil {
+		return
+	}
+{{- if hasSecret .Fields}}
+	var zero {{.TypeName}}
+{{- end}}
+{{- range .Fields}}
+{{- if .Secret}}
+	c.{{.Name}} = zero.{{.Name}}
+{{- else if and .Node .Node.Redact}}
+	{{.Node.RedactOf (print "c." .Name)}}
+{{- else if not .StructTypeName}}
+{{- else if .IsPointer}}
+	c.{{.Name}}.redactSecrets()
+{{- else if .IsSlice}}
+	for i := range c.{{.Name}} {
+		c.{{.Name}}[i].redactSecrets()
+	}
+{{- else if .IsMap}}
+	for k, v := range c.{{.Name}} {
+		v.redactSecrets()
+{{- if not (hasPrefix .ValueType "*")}}
+		c.{{.Name}}[k] = v
+{{- end}}
+	}
+{{- else if .IsStruct}}
+	c.{{.Name}}.redactSecrets()
+{{- end}}
+{{- end}}
+}
+{{- end}}
+{{- define "keyring"}}
+// {{.TypeName}}Keyring encrypts and decrypts the fields of a {{.TypeName}} tagged sudo:"secret",
+// such as with an AEAD cipher or a key management service.
+type {{.TypeName}}Keyring interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// {{method "EncryptSecrets"}} returns a deep copy of the {{.TypeName}} with the fields tagged
+// sudo:"secret" encrypted by keyring, including those of nested structs, so that it can
+// be persisted safely. The fields hold the ciphertext in base64, and empty fields are
+// left empty.
+func (c *{{.TypeName}}) {{method "EncryptSecrets"}}(keyring {{.TypeName}}Keyring) (*{{.TypeName}}, error) {
+	dst := c.{{.MethodName}}()
+	err := dst.cryptSecrets(func(s string) (string, error) {
+		ciphertext, err := keyring.Encrypt([]byte(s))
+		if err != nil {
+			return "", err
+		}
+		return base64.StdEncoding.EncodeToString(ciphertext), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encrypting secrets: %w", err)
+	}
+	return dst, nil
+}
+
+// {{method "DecryptSecrets"}} returns a deep copy of the {{.TypeName}} with the fields encrypted by
+// {{method "EncryptSecrets"}} decrypted by keyring.
+func (c *{{.TypeName}}) {{method "DecryptSecrets"}}(keyring {{.TypeName}}Keyring) (*{{.TypeName}}, error) {
+	dst := c.{{.MethodName}}()
+	err := dst.cryptSecrets(func(s string) (string, error) {
+		ciphertext, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return "", err
+		}
+		plaintext, err := keyring.Decrypt(ciphertext)
+		if err != nil {
+			return "", err
+		}
+		return string(plaintext), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("decrypting secrets: %w", err)
+	}
+	return dst, nil
+}
+{{- end}}
+{{- define "crypt"}}
+// cryptSecrets replaces the non-empty fields of c tagged sudo:"secret" with what crypt
+// returns for them, in place.
+func (c *{{.TypeName}}) cryptSecrets(crypt func(string) (string, error)) error {
+	if c == nil {
+		return nil
+	}
+{{- range .Fields}}
+{{- if and .Secret (eq .Type "string")}}
+	if c.{{.Name}} != "" {
+		s, err := crypt(c.{{.Name}})
+		if err != nil {
+			return fmt.Errorf("{{.Name}}: %w", err)
+		}
+		c.{{.Name}} = s
+	}
+{{- else if and .Secret (eq .Type "*string")}}
+	if c.{{.Name}} != nil && *c.{{.Name}} != "" {
+		s, err := crypt(*c.{{.Name}})
+		if err != nil {
+			return fmt.Errorf("{{.Name}}: %w", err)
+		}
+		c.{{.Name}} = &s
+	}
+{{- else if .Secret}}
+	if len(c.{{.Name}}) > 0 {
+		s, err := crypt(string(c.{{.Name}}))
+		if err != nil {
+			return fmt.Errorf("{{.Name}}: %w", err)
+		}
+		c.{{.Name}} = []byte(s)
+	}
+{{- else if and .Node .Node.Crypt}}
+	if err := {{.Node.CryptOf (print "c." .Name)}}; err != nil {
+		return fmt.Errorf("{{.Name}}.%w", err)
+	}
+{{- else if not .StructTypeName}}
+{{- else if .IsPointer}}
+	if err := c.{{.Name}}.cryptSecrets(crypt); err != nil {
+		return fmt.Errorf("{{.Name}}.%w", err)
+	}
+{{- else if .IsSlice}}
+	for i := range c.{{.Name}} {
+		if err := c.{{.Name}}[i].cryptSecrets(crypt); err != nil {
+			return fmt.Errorf("{{.Name}}.%w", err)
+		}
+	}
+{{- else if .IsMap}}
+	for k, v := range c.{{.Name}} {
+		if err := v.cryptSecrets(crypt); err != nil {
+			return fmt.Errorf("{{.Name}}.%w", err)
+		}
+{{- if not (hasPrefix .ValueType "*")}}
+		c.{{.Name}}[k] = v
+{{- end}}
+	}
+{{- else if .IsStruct}}
+	if err := c.{{.Name}}.cryptSecrets(crypt); err != nil {
+		return fmt.Errorf("{{.Name}}.%w", err)
+	}
+{{- end}}
+{{- end}}
+	return nil
+}
+{{- end}}
+`
+
+const copyFuncsTemplate = `// Code generated by sudo-gen copy. DO NOT EDIT.
+
+package {{.Package}}
+
+{{if .Imports -}}
+import (
+{{range .Imports}}	{{if .Alias}}{{.Alias}} {{end}}"{{.Path}}"
+{{end}})
+
+{{end -}}
+{{template "copyFunc" .}}
+{{- range .NestedTypes}}
+
+{{template "copyFunc" .}}
+{{- end}}
+{{- template "helpers" .}}
+{{- define "copyFunc"}}
+// {{.MethodName}}{{.TypeName}} creates a deep copy of c.
+func {{.MethodName}}{{.TypeName}}(c *{{.Qualifier}}{{.TypeName}}) *{{.Qualifier}}{{.TypeName}} {
+	if c == nil {
+		return nil
+	}
+	dst := &{{.Qualifier}}{{.TypeName}}{}
+{{- range .Fields}}
+{{- if and .Recipe .Recipe.Clone}}
+{{- template "cloneRecipe" .}}
+{{- else}}
+	dst.{{.Name}} = {{.Node.CopyOf (print "c." .Name)}}
+{{- end}}
+{{- end}}
+	return dst
+}
+{{- end}}
+`
+
+// copyHelpersTemplate declares the helpers copying the levels of composite fields
+// and the copy of fields with a codegen.CloneRecipe, shared by copyTemplate and
+// copyFuncsTemplate.
+const copyHelpersTemplate = `
+{{- define "copyTypeParam"}}
+{{- if .IsPointer}}
+	if c.{{.Name}} == nil {
+		dst.{{.Name}} = nil
+	} else {
+		v := {{copyValue .TypeParam (print "*c." .Name)}}
+		dst.{{.Name}} = &v
+	}
+{{- else if .IsSlice}}
+	if c.{{.Name}} == nil {
+		dst.{{.Name}} = nil
+	} else {
+		dst.{{.Name}} = make({{.Type}}, len(c.{{.Name}}))
+		for i, v := range c.{{.Name}} {
+			dst.{{.Name}}[i] = {{copyValue .TypeParam "v"}}
+		}
+	}
+{{- else if .IsMap}}
+	if c.{{.Name}} == nil {
+		dst.{{.Name}} = nil
+	} else {
+		dst.{{.Name}} = make({{.Type}}, len(c.{{.Name}}))
+		for k, v := range c.{{.Name}} {
+			dst.{{.Name}}[k] = {{copyValue .TypeParam "v"}}
+		}
+	}
+{{- else}}
+	dst.{{.Name}} = {{copyValue .TypeParam (print "c." .Name)}}
+{{- end}}
+{{- end}}
+{{- define "cloneRecipe"}}
+{{- if .Recipe.Nilable}}
+	if c.{{.Name}} != nil {
+		dst.{{.Name}} = {{.Recipe.CloneOf (print "c." .Name)}}
+	}
+{{- else}}
+	dst.{{.Name}} = {{.Recipe.CloneOf (print "c." .Name)}}
+{{- end}}
+{{- end}}
+{{- define "helpers"}}
+{{- range .Helpers}}
+
+// {{.Helper}} deep copies a {{.Expr}}.
+func {{.Helper}}(src {{.Expr}}{{if .Graph}}, visited map[any]any{{end}}) {{.Expr}} {
+	if src == nil {
+		return nil
+	}
+{{- if and (eq .Kind "pointer") .Graph}}
+	if dst, ok := visited[src]; ok {
+		return dst.({{.Expr}})
+	}
+	dst := new({{.Elem.Expr}})
+	visited[src] = dst
+	*dst = {{.Elem.CopyOf "*src"}}
+	return dst
+{{- else if eq .Kind "pointer"}}
+	v := {{.Elem.CopyOf "*src"}}
+	return &v
+{{- else}}
+	dst := make({{.Expr}}, len(src))
+{{- if and (eq .Kind "slice") (eq .Elem.Kind "value")}}
+	copy(dst, src)
+{{- else if and (eq .Kind "slice") .Graph (eq .Elem.Kind "struct")}}
+	for i := range src {
+		src[i].{{.Graph}}Fields(&dst[i], visited)
+	}
+{{- else if eq .Kind "slice"}}
+	for i := range src {
+		dst[i] = {{.Elem.CopyOf "src[i]"}}
+	}
+{{- else if eq .Elem.Kind "value"}}
+	maps.Copy(dst, src)
+{{- else}}
+	for k, v := range src {
+		dst[k] = {{.Elem.CopyOf "v"}}
+	}
+{{- end}}
+	return dst
+{{- end}}
+}
+{{- if .Redact}}
+
+// {{.Redact}} clears the secrets of the structs in a {{.Expr}} in place.
+func {{.Redact}}(v {{.Expr}}) {
+{{- if eq .Kind "pointer"}}
+	if v != nil {
+		{{.Elem.RedactOf "*v"}}
+	}
+{{- else if eq .Kind "slice"}}
+	for i := range v {
+		{{.Elem.RedactOf "v[i]"}}
+	}
+{{- else if eq .Elem.Kind "struct"}}
+	for k, e := range v {
+		e.redactSecrets()
+		v[k] = e
+	}
+{{- else}}
+	for _, e := range v {
+		{{.Elem.RedactOf "e"}}
+	}
+{{- end}}
+}
+{{- end}}
+{{- if .Crypt}}
+
+// {{.Crypt}} transforms the secrets of the structs in a {{.Expr}} in place.
+func {{.Crypt}}(v {{.Expr}}, crypt func(string) (string, error)) error {
+{{- if eq .Kind "pointer"}}
+	if v == nil {
+		return nil
+	}
+	return {{.Elem.CryptOf "*v"}}
+{{- else if eq .Kind "slice"}}
+	for i := range v {
+		if err := {{.Elem.CryptOf "v[i]"}}; err != nil {
+			return err
+		}
+	}
+	return nil
+{{- else if eq .Elem.Kind "struct"}}
+	for k, e := range v {
+		if err := e.cryptSecrets(crypt); err != nil {
+			return err
+		}
+		v[k] = e
+	}
+	return nil
+{{- else}}
+	for _, e := range v {
+		if err := {{.Elem.CryptOf "e"}}; err != nil {
+			return err
+		}
+	}
+	return nil
+{{- end}}
+}
+{{- end}}
+{{- end}}
+{{- end}}
 `
 
 const copyTestTemplate = `// Code generated by sudo-gen copy. DO NOT EDIT.
@@ -213,6 +746,9 @@ package {{.Package}}
 
 import (
 	"testing"
+{{- range .TestImports}}{{if ne .Path "maps"}}
+	{{if .Alias}}{{.Alias}} {{end}}"{{.Path}}"
+{{- end}}{{end}}
 )
 
 func Test{{.TypeName}}{{.MethodName}}Nil(t *testing.T) {
@@ -285,6 +821,23 @@ func Test{{$.TypeName}}{{$.MethodName}}_{{.Name}}SliceIndependence(t *testing.T)
 		t.Error("modifications to original slice should not affect copy")
 	}
 }
+{{- if .IsBytes}}
+
+func Test{{$.TypeName}}{{$.MethodName}}_{{.Name}}Bytes(t *testing.T) {
+	c := &{{$.TypeName}}{
+		{{.Name}}: {{.Type}}("abc"),
+	}
+	got := c.{{$.MethodName}}()
+	c.{{.Name}}[0] = 'x'
+	if string(got.{{.Name}}) != "abc" {
+		t.Errorf("expected %q, got %q", "abc", got.{{.Name}})
+	}
+	c.{{.Name}} = {{.Type}}{}
+	if got := c.{{$.MethodName}}(); got.{{.Name}} == nil || len(got.{{.Name}}) != 0 {
+		t.Errorf("empty bytes should stay empty and non-nil after copy, got %#v", got.{{.Name}})
+	}
+}
+{{- end}}
 {{end}}{{end}}
 {{range .Fields}}{{if .IsMap}}
 func Test{{$.TypeName}}{{$.MethodName}}_{{.Name}}Map(t *testing.T) {
@@ -317,6 +870,25 @@ func Test{{$.TypeName}}{{$.MethodName}}_{{.Name}}MapIndependence(t *testing.T) {
 	// Maps are copied by value, so they should be different instances
 }
 {{end}}{{end}}
+{{- range $f := .Fields}}
+{{- with .Deep}}
+
+func Test{{$.TypeName}}{{$.MethodName}}_{{$f.Name}}Deep(t *testing.T) {
+	c := &{{$.TypeName}}{ {{$f.Name}}: {{.Literal}}}
+	got := c.{{$.MethodName}}()
+{{- if .Slice}}
+	if &{{printf .Inner (print "got." $f.Name)}}[0] == &{{printf .Inner (print "c." $f.Name)}}[0] {
+		t.Error("{{$f.Name}} should be copied at every level")
+	}
+{{- else}}
+	{{printf .Inner (print "c." $f.Name)}}[{{.AddKey}}] = {{.AddValue}}
+	if len({{printf .Inner (print "got." $f.Name)}}) != 1 {
+		t.Error("{{$f.Name}} should be copied at every level")
+	}
+{{- end}}
+}
+{{- end}}
+{{- end}}
 {{range .Fields}}{{if and .IsPointer (not .StructTypeName)}}
 func Test{{$.TypeName}}{{$.MethodName}}_{{.Name}}PointerNil(t *testing.T) {
 	c := &{{$.TypeName}}{}
@@ -564,4 +1136,333 @@ func Test{{.TypeName}}{{.MethodName}}Empty(t *testing.T) {
 	}
 }
 {{end}}
+{{- if .Graph}}
+{{- range .Fields}}
+{{- if and .IsPointer (eq .StructTypeName $.TypeName)}}
+
+func Test{{$.TypeName}}{{$.MethodName}}_{{.Name}}Cycle(t *testing.T) {
+	c := &{{$.TypeName}}{}
+	c.{{.Name}} = c
+	got := c.{{$.MethodName}}()
+	if got == c || got.{{.Name}} != got {
+		t.Error("a cycle through {{.Name}} should be copied as a cycle through the copy")
+	}
+	var dst {{$.TypeName}}
+	c.{{$.MethodName}}Into(&dst)
+	if dst.{{.Name}} != &dst {
+		t.Error("{{$.MethodName}}Into should copy a cycle through {{.Name}} as a cycle through dst")
+	}
+}
+{{- end}}
+{{- if .SliceElemIsPtr}}
+
+func Test{{$.TypeName}}{{$.MethodName}}_{{.Name}}Shared(t *testing.T) {
+	shared := &{{.StructTypeName}}{}
+	c := &{{$.TypeName}}{ {{.Name}}: {{.Type}}{shared, shared}}
+	got := c.{{$.MethodName}}()
+	if got.{{.Name}}[0] == shared {
+		t.Fatal("{{.Name}} should be deep copied")
+	}
+	if got.{{.Name}}[0] != got.{{.Name}}[1] {
+		t.Error("a pointer shared within {{.Name}} should be shared within the copy")
+	}
+}
+{{- end}}
+{{- end}}
+{{- end}}
+{{- range .Fields}}
+{{- if and .IsSlice (not .StructTypeName) (not .Node)}}
+
+func Test{{$.TypeName}}{{$.MethodName}}IntoReuse(t *testing.T) {
+	dst := &{{$.TypeName}}{ {{.Name}}: make({{.Type}}, 0, 16)}
+	c := &{{$.TypeName}}{ {{.Name}}: make({{.Type}}, 2)}
+	c.{{$.MethodName}}Into(dst)
+	if len(dst.{{.Name}}) != 2 || cap(dst.{{.Name}}) != 16 {
+		t.Errorf("expected {{.Name}} to be copied into the existing slice, got len %d cap %d", len(dst.{{.Name}}), cap(dst.{{.Name}}))
+	}
+	if &dst.{{.Name}}[0] == &c.{{.Name}}[0] {
+		t.Error("{{.Name}} should not share the source's backing array")
+	}
+	(&{{$.TypeName}}{}).{{$.MethodName}}Into(dst)
+	if dst.{{.Name}} != nil {
+		t.Error("nil {{.Name}} should be copied as nil")
+	}
+}
+{{- break}}
+{{- end}}
+{{- end}}
+{{- if not .Bench}}
+
+// {{lower .TypeName}}{{.MethodName}}BenchValue returns a {{.TypeName}} with its slices, maps and
+// nested structs allocated, for benchmarks.
+func {{lower .TypeName}}{{.MethodName}}BenchValue() *{{.TypeName}} {
+	return &{{.TypeName}}{
+{{- range .Fields}}
+{{- if or .IsSlice .IsMap}}
+		{{.Name}}: make({{.Type}}, 8),
+{{- else if and .IsPointer .StructTypeName}}
+		{{.Name}}: &{{.StructTypeName}}{},
+{{- end}}
+{{- end}}
+	}
+}
+
+func Benchmark{{.TypeName}}{{.MethodName}}(b *testing.B) {
+	c := {{lower .TypeName}}{{.MethodName}}BenchValue()
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = c.{{.MethodName}}()
+	}
+}
+
+func Benchmark{{.TypeName}}{{.MethodName}}Into(b *testing.B) {
+	c := {{lower .TypeName}}{{.MethodName}}BenchValue()
+	var dst {{.TypeName}}
+	b.ReportAllocs()
+	for b.Loop() {
+		c.{{.MethodName}}Into(&dst)
+	}
+}
+{{- end}}
+{{- if .Redact}}
+{{- template "redactTest" .}}
+{{- range .NestedTypes}}
+{{- template "redactTest" .}}
+{{- end}}
+{{- end}}
+{{- if .Encrypt}}
+{{- template "encryptTest" .}}
+{{- template "cryptTest" .}}
+{{- range .NestedTypes}}
+{{- template "cryptTest" .}}
+{{- end}}
+{{- end}}
+{{- if .With}}
+{{- $struct := .}}
+{{- range .Fields}}
+{{- if and (exported .Name) (eq .Type "string")}}
+
+func Test{{$struct.TypeName}}With{{.Name}}(t *testing.T) {
+	c := &{{$struct.TypeName}}{ {{.Name}}: "original"}
+	got := c.With{{.Name}}("changed")
+	if got.{{.Name}} != "changed" {
+		t.Errorf("{{.Name}} = %q, want changed", got.{{.Name}})
+	}
+	if c.{{.Name}} != "original" {
+		t.Error("With{{.Name}} should not modify the original")
+	}
+	got = c.{{method "With"}}(func(dst *{{$struct.TypeName}}) { dst.{{.Name}} += "!" })
+	if got.{{.Name}} != "original!" {
+		t.Errorf("{{.Name}} = %q after With, want original!", got.{{.Name}})
+	}
+}
+{{- break}}
+{{- end}}
+{{- end}}
+{{- end}}
+{{- define "redactTest"}}
+{{- $struct := .}}
+{{- range .Fields}}
+{{- if and .Secret (eq .Type "string")}}
+
+func Test{{$struct.TypeName}}Redacted(t *testing.T) {
+	c := &{{$struct.TypeName}}{ {{.Name}}: "secret"}
+	got := c.{{method "Redacted"}}()
+	if got.{{.Name}} != "" {
+		t.Errorf("{{.Name}} = %q after Redacted, want empty", got.{{.Name}})
+	}
+	if c.{{.Name}} != "secret" {
+		t.Error("Redacted should not modify the original")
+	}
+}
+{{- break}}
+{{- end}}
+{{- end}}
+{{- end}}
+{{- define "encryptTest"}}
+
+// {{lower .TypeName}}TestKeyring seals secrets with a prefix, to check that they are
+// transformed without a real cipher.
+type {{lower .TypeName}}TestKeyring struct{}
+
+func ({{lower .TypeName}}TestKeyring) Encrypt(plaintext []byte) ([]byte, error) {
+	return append([]byte("sealed:"), plaintext...), nil
+}
+
+func ({{lower .TypeName}}TestKeyring) Decrypt(ciphertext []byte) ([]byte, error) {
+	plaintext, ok := bytes.CutPrefix(ciphertext, []byte("sealed:"))
+	if !ok {
+		return nil, errors.New("not sealed")
+	}
+	return plaintext, nil
+}
+
+func Test{{.TypeName}}EncryptSecrets(t *testing.T) {
+	c := &{{.TypeName}}{}
+	encrypted, err := c.{{method "EncryptSecrets"}}({{lower .TypeName}}TestKeyring{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := encrypted.{{method "DecryptSecrets"}}({{lower .TypeName}}TestKeyring{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decrypted == c || decrypted == nil {
+		t.Error("DecryptSecrets should return a new copy")
+	}
+{{- $struct := .}}
+{{- range .Fields}}
+{{- if and .Secret (eq .Type "string")}}
+	c.{{.Name}} = "secret"
+	if encrypted, err = c.{{method "EncryptSecrets"}}({{lower $struct.TypeName}}TestKeyring{}); err != nil {
+		t.Fatal(err)
+	}
+	if encrypted.{{.Name}} == "secret" || c.{{.Name}} != "secret" {
+		t.Errorf("{{.Name}} = %q after EncryptSecrets, want it encrypted in the copy only", encrypted.{{.Name}})
+	}
+	if decrypted, err = encrypted.{{method "DecryptSecrets"}}({{lower $struct.TypeName}}TestKeyring{}); err != nil {
+		t.Fatal(err)
+	}
+	if decrypted.{{.Name}} != "secret" {
+		t.Errorf("{{.Name}} = %q after DecryptSecrets, want secret", decrypted.{{.Name}})
+	}
+	if _, err := c.{{method "DecryptSecrets"}}({{lower $struct.TypeName}}TestKeyring{}); err == nil {
+		t.Error("expected an error decrypting a secret that isn't encrypted")
+	}
+{{- break}}
+{{- end}}
+{{- end}}
+}
+{{- end}}
+{{- define "cryptTest"}}
+{{- $struct := .}}
+{{- range .Fields}}
+{{- if and .Secret (eq .Type "string")}}
+
+func Test{{$struct.TypeName}}CryptSecrets(t *testing.T) {
+	upper := func(s string) (string, error) { return strings.ToUpper(s), nil }
+	c := &{{$struct.TypeName}}{ {{.Name}}: "secret"}
+	if err := c.cryptSecrets(upper); err != nil {
+		t.Fatal(err)
+	}
+	if c.{{.Name}} != "SECRET" {
+		t.Errorf("{{.Name}} = %q after cryptSecrets, want SECRET", c.{{.Name}})
+	}
+	errCrypt := errors.New("crypt failed")
+	failing := func(string) (string, error) { return "", errCrypt }
+	if err := c.cryptSecrets(failing); !errors.Is(err, errCrypt) {
+		t.Errorf("err = %v, want %v", err, errCrypt)
+	}
+	if err := (&{{$struct.TypeName}}{}).cryptSecrets(failing); err != nil {
+		t.Errorf("empty secrets should be left alone, got %v", err)
+	}
+}
+{{- break}}
+{{- end}}
+{{- end}}
+{{- end}}
+`
+
+const copyBenchTemplate = `// Code generated by sudo-gen copy. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"testing"
+)
+
+// {{lower .TypeName}}{{.MethodName}}BenchValue returns a {{.TypeName}} with every field set, for benchmarks.
+func {{lower .TypeName}}{{.MethodName}}BenchValue() *{{.TypeName}} {
+	return &{{.Sample}}
+}
+
+func Benchmark{{.TypeName}}{{.MethodName}}(b *testing.B) {
+	c := {{lower .TypeName}}{{.MethodName}}BenchValue()
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = c.{{.MethodName}}()
+	}
+}
+
+func Benchmark{{.TypeName}}{{.MethodName}}Into(b *testing.B) {
+	c := {{lower .TypeName}}{{.MethodName}}BenchValue()
+	var dst {{.TypeName}}
+	b.ReportAllocs()
+	for b.Loop() {
+		c.{{.MethodName}}Into(&dst)
+	}
+}
+`
+
+const copyExampleTemplate = `// Code generated by sudo-gen copy. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"fmt"
+)
+
+func Example{{.TypeName}}_{{.MethodName}}() {
+	c := &{{.Sample}}
+	copied := c.{{.MethodName}}()
+{{- with .ExampleField}}
+	copied.{{.}} = "changed"
+	fmt.Println(c.{{.}}, copied.{{.}})
+	// Output: value changed
+{{- else}}
+	fmt.Println(copied != c)
+	// Output: true
+{{- end}}
+}
+`
+
+const copyFuncsTestTemplate = `// Code generated by sudo-gen copy. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"testing"
+
+	{{if .Source.Alias}}{{.Source.Alias}} {{end}}"{{.Source.Path}}"
+)
+{{template "funcTests" .}}
+{{- range .NestedTypes}}
+{{template "funcTests" .}}
+{{- end}}
+{{- define "funcTests"}}
+func Test{{.MethodName}}{{.TypeName}}Nil(t *testing.T) {
+	if got := {{.MethodName}}{{.TypeName}}(nil); got != nil {
+		t.Errorf("expected nil, got %v", got)
+	}
+}
+
+func Test{{.MethodName}}{{.TypeName}}Empty(t *testing.T) {
+	c := &{{.Qualifier}}{{.TypeName}}{}
+	got := {{.MethodName}}{{.TypeName}}(c)
+	if got == nil {
+		t.Fatal("expected non-nil copy")
+	}
+	if got == c {
+		t.Error("copy should be a different pointer")
+	}
+}
+{{- $struct := .}}
+{{- range .Fields}}
+{{- if eq .Node.Kind "slice"}}
+
+func Test{{$struct.MethodName}}{{$struct.TypeName}}_{{.Name}}Slice(t *testing.T) {
+	c := &{{$struct.Qualifier}}{{$struct.TypeName}}{
+		{{.Name}}: make({{.Node.Expr}}, 2),
+	}
+	got := {{$struct.MethodName}}{{$struct.TypeName}}(c)
+	if len(got.{{.Name}}) != len(c.{{.Name}}) {
+		t.Fatalf("expected len %d, got %d", len(c.{{.Name}}), len(got.{{.Name}}))
+	}
+	if &got.{{.Name}}[0] == &c.{{.Name}}[0] {
+		t.Error("slice should be a deep copy, not share backing array")
+	}
+}
+{{- end}}
+{{- end}}
+{{- end}}
 `
@@ -0,0 +1,362 @@
+// Package defaults implements the defaults code generation subtool.
+package defaults
+
+import (
+	"fmt"
+	"go/ast"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/bobcob7/sudo-gen/internal/codegen"
+)
+
+// Subtool implements the defaults code generator.
+type Subtool struct{}
+
+// Name returns the subtool name.
+func (s *Subtool) Name() string { return "defaults" }
+
+// Description returns the subtool description.
+func (s *Subtool) Description() string {
+	return "Generate SetDefaults methods and a defaulted constructor from default tags"
+}
+
+// Doc describes the struct tags the subtool honors and the files it generates.
+func (s *Subtool) Doc() codegen.SubtoolDoc {
+	return codegen.SubtoolDoc{
+		Tags: []codegen.DocEntry{
+			{Name: `default:"value"`, Description: "Value SetDefaults assigns to the field when it is zero"},
+			codegen.ExcludeTagDoc(s.Name()),
+		},
+		Files: []codegen.DocEntry{
+			{Name: "{source}_defaults.go", Description: "SetDefaults methods and Default{Type} constructor"},
+		},
+	}
+}
+
+// Run executes the defaults code generation.
+func (s *Subtool) Run(cfg codegen.GeneratorConfig) error {
+	info, err := cfg.Index.ParseStruct(cfg.SourceDir, cfg.SourceFile, cfg.Source, cfg.TypeName)
+	if err != nil {
+		return fmt.Errorf("parsing struct: %w", err)
+	}
+	selection := codegen.NewFieldSelection(cfg, s.Name())
+	selection.Apply(info)
+	values := codegen.NewValueTypes(cfg.Index, cfg.SourceDir, cfg.ValueTypes)
+	nested, err := cfg.Index.FindNestedStructs(cfg.SourceDir, cfg.Source, info, values)
+	if err != nil {
+		return fmt.Errorf("finding nested structs: %w", err)
+	}
+	for _, st := range nested {
+		selection.Apply(st)
+	}
+	// External package structs can't have methods added to them
+	allStructs := []*codegen.StructInfo{info}
+	for _, st := range nested {
+		if st.Package == "" {
+			allStructs = append(allStructs, st)
+		}
+	}
+	cfg, err = codegen.CheckUnsupported(cfg, s.Name(), codegen.UnsupportedFields(allStructs))
+	if err != nil {
+		return err
+	}
+	data, err := buildTemplateData(cfg, allStructs)
+	if err != nil {
+		return err
+	}
+	return generateDefaultsFile(cfg, data)
+}
+
+func generateDefaultsFile(cfg codegen.GeneratorConfig, data templateData) error {
+	baseName := strings.TrimSuffix(cfg.SourceFile, ".go")
+	outputFile := filepath.Join(cfg.OutputDir, baseName+"_defaults.go")
+	gen := codegen.NewTemplateGenerator(cfg, templateFuncs())
+	if err := gen.GenerateFile(outputFile, defaultsTemplate, data); err != nil {
+		return err
+	}
+	if cfg.GenerateTest {
+		testFile := filepath.Join(cfg.OutputDir, baseName+"_defaults_test.go")
+		return gen.GenerateFile(testFile, defaultsTestTemplate, data)
+	}
+	return nil
+}
+
+type templateData struct {
+	Package     string
+	TypeName    string
+	Constructor string
+	Broker      string // Layer broker constructor the defaults are meant to seed
+	TestName    string // Exported form of TypeName for test function names
+	Structs     []structData
+	Imports     []codegen.ImportInfo
+}
+
+type structData struct {
+	Name   string
+	Fields []fieldData
+}
+
+type fieldData struct {
+	codegen.FieldInfo
+	Literal   string // Go expression for the declared default
+	Zero      string // Condition that is true when the field is unset
+	Nested    bool   // Field is a local struct (or pointer to one) with defaults of its own
+	NestedPtr bool   // Nested is a pointer that must be allocated before defaulting
+	Elements  bool   // Field is a slice of local structs with defaults
+}
+
+func buildTemplateData(cfg codegen.GeneratorConfig, structs []*codegen.StructInfo) (templateData, error) {
+	hasDefaults := findStructsWithDefaults(structs)
+	imports := make(map[string]codegen.ImportInfo)
+	data := templateData{
+		Package:     cfg.OutputPkg,
+		TypeName:    structs[0].Name,
+		Constructor: constructorName(structs[0].Name),
+		Broker:      brokerConstructorName(structs[0].Name),
+		TestName:    capitalize(structs[0].Name),
+	}
+	for i, st := range structs {
+		if i > 0 && !hasDefaults[st.Name] {
+			continue
+		}
+		sd := structData{Name: st.Name}
+		for _, f := range st.Fields {
+			fd, err := buildFieldData(f, hasDefaults)
+			if err != nil {
+				return templateData{}, fmt.Errorf("%s.%s: %w", st.Name, f.Name, err)
+			}
+			if fd.Literal == "" && !fd.Nested && !fd.Elements {
+				continue
+			}
+			if fd.Literal != "" && f.TypePkg != "" {
+				addImport(imports, st.Imports, f.TypePkg)
+			}
+			sd.Fields = append(sd.Fields, fd)
+		}
+		data.Structs = append(data.Structs, sd)
+	}
+	for _, imp := range imports {
+		data.Imports = append(data.Imports, imp)
+	}
+	sort.Slice(data.Imports, func(i, j int) bool { return data.Imports[i].Path < data.Imports[j].Path })
+	return data, nil
+}
+
+func buildFieldData(f codegen.FieldInfo, hasDefaults map[string]bool) (fieldData, error) {
+	fd := fieldData{FieldInfo: f}
+	if f.Default != "" {
+		lit, err := defaultLiteral(f)
+		if err != nil {
+			return fd, err
+		}
+		fd.Literal = lit
+		fd.Zero = zeroCondition(f)
+		return fd, nil
+	}
+	if f.TypePkg != "" || f.StructTypeName == "" || !hasDefaults[f.StructTypeName] || f.IsMap {
+		return fd, nil
+	}
+	if f.IsSlice {
+		fd.Elements = true
+		return fd, nil
+	}
+	fd.Nested = true
+	fd.NestedPtr = f.IsPointer
+	return fd, nil
+}
+
+// findStructsWithDefaults reports which structs declare defaults directly or through nested structs.
+func findStructsWithDefaults(structs []*codegen.StructInfo) map[string]bool {
+	result := make(map[string]bool)
+	for changed := true; changed; {
+		changed = false
+		for _, st := range structs {
+			if result[st.Name] {
+				continue
+			}
+			for _, f := range st.Fields {
+				if f.Default != "" || (f.TypePkg == "" && f.StructTypeName != "" && result[f.StructTypeName]) {
+					result[st.Name] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	return result
+}
+
+func addImport(imports map[string]codegen.ImportInfo, available []codegen.ImportInfo, pkg string) {
+	for _, imp := range available {
+		name := imp.Alias
+		if name == "" {
+			name = filepath.Base(imp.Path)
+		}
+		if name == pkg {
+			imports[imp.Path] = imp
+			return
+		}
+	}
+}
+
+func constructorName(typeName string) string {
+	if ast.IsExported(typeName) {
+		return "Default" + typeName
+	}
+	return "default" + capitalize(typeName)
+}
+
+func brokerConstructorName(typeName string) string {
+	if ast.IsExported(typeName) {
+		return "New" + typeName + "LayerBroker"
+	}
+	return "new" + capitalize(typeName) + "LayerBroker"
+}
+
+func capitalize(name string) string {
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func zeroCondition(f codegen.FieldInfo) string {
+	switch {
+	case f.IsPointer, f.IsSlice, f.IsMap:
+		return "c." + f.Name + " == nil"
+	case f.TypeName == "string" && f.TypePkg == "":
+		return `c.` + f.Name + ` == ""`
+	case f.TypeName == "bool" && f.TypePkg == "":
+		return "!c." + f.Name
+	default:
+		return "c." + f.Name + " == 0"
+	}
+}
+
+// defaultLiteral converts the declared default of f into a Go expression of the field's type.
+func defaultLiteral(f codegen.FieldInfo) (string, error) {
+	if (f.IsSlice || f.IsMap) && f.Type != f.TypeName {
+		return "", fmt.Errorf("defaults are not supported for type %s", f.Type)
+	}
+	switch {
+	case f.IsSlice:
+		elems := make([]string, 0)
+		for _, item := range strings.Split(f.Default, ",") {
+			lit, err := scalarLiteral(f.SliceType, strings.TrimSpace(item))
+			if err != nil {
+				return "", err
+			}
+			elems = append(elems, lit)
+		}
+		return f.TypeName + "{" + strings.Join(elems, ", ") + "}", nil
+	case f.IsMap:
+		entries := make([]string, 0)
+		for _, item := range strings.Split(f.Default, ",") {
+			k, v, ok := strings.Cut(item, ":")
+			if !ok {
+				return "", fmt.Errorf("map default entry %q is not key:value", item)
+			}
+			keyLit, err := scalarLiteral(f.MapKeyType, strings.TrimSpace(k))
+			if err != nil {
+				return "", err
+			}
+			valLit, err := scalarLiteral(f.MapValType, strings.TrimSpace(v))
+			if err != nil {
+				return "", err
+			}
+			entries = append(entries, keyLit+": "+valLit)
+		}
+		return f.TypeName + "{" + strings.Join(entries, ", ") + "}", nil
+	}
+	typeName := f.TypeName
+	if f.TypePkg != "" {
+		typeName = f.TypePkg + "." + f.TypeName
+	}
+	return scalarLiteral(typeName, f.Default)
+}
+
+// scalarLiteral converts value into a typed Go literal for typeName.
+func scalarLiteral(typeName, value string) (string, error) {
+	switch typeName {
+	case "string":
+		return strconv.Quote(value), nil
+	case "bool":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid bool default %q", value)
+		}
+		return strconv.FormatBool(b), nil
+	case "int":
+		n, err := strconv.ParseInt(value, 0, 0)
+		if err != nil {
+			return "", fmt.Errorf("invalid int default %q", value)
+		}
+		return strconv.FormatInt(n, 10), nil
+	case "int8", "int16", "int32", "int64", "rune":
+		n, err := strconv.ParseInt(value, 0, bitSize(typeName))
+		if err != nil {
+			return "", fmt.Errorf("invalid %s default %q", typeName, value)
+		}
+		return typeName + "(" + strconv.FormatInt(n, 10) + ")", nil
+	case "uint", "uint8", "uint16", "uint32", "uint64", "uintptr", "byte":
+		n, err := strconv.ParseUint(value, 0, bitSize(typeName))
+		if err != nil {
+			return "", fmt.Errorf("invalid %s default %q", typeName, value)
+		}
+		return typeName + "(" + strconv.FormatUint(n, 10) + ")", nil
+	case "float32", "float64":
+		n, err := strconv.ParseFloat(value, bitSize(typeName))
+		if err != nil {
+			return "", fmt.Errorf("invalid %s default %q", typeName, value)
+		}
+		return typeName + "(" + strconv.FormatFloat(n, 'g', -1, bitSize(typeName)) + ")", nil
+	}
+	if pkg, name, ok := strings.Cut(typeName, "."); ok && name == "Duration" {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid duration default %q", value)
+		}
+		return durationLiteral(pkg, d), nil
+	}
+	return "", fmt.Errorf("defaults are not supported for type %s", typeName)
+}
+
+func bitSize(typeName string) int {
+	switch typeName {
+	case "int8", "uint8", "byte":
+		return 8
+	case "int16", "uint16":
+		return 16
+	case "int32", "uint32", "rune", "float32":
+		return 32
+	case "int", "uint", "uintptr":
+		return 0
+	}
+	return 64
+}
+
+// durationLiteral renders d as a multiple of the largest time unit that divides it evenly.
+func durationLiteral(pkg string, d time.Duration) string {
+	units := []struct {
+		name string
+		d    time.Duration
+	}{
+		{"Hour", time.Hour},
+		{"Minute", time.Minute},
+		{"Second", time.Second},
+		{"Millisecond", time.Millisecond},
+		{"Microsecond", time.Microsecond},
+	}
+	for _, u := range units {
+		if d%u.d == 0 {
+			return fmt.Sprintf("%d * %s.%s", d/u.d, pkg, u.name)
+		}
+	}
+	return fmt.Sprintf("%s.Duration(%d)", pkg, int64(d))
+}
+
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{}
+}
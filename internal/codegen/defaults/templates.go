@@ -0,0 +1,107 @@
+package defaults
+
+const defaultsTemplate = `// Code generated by sudo-gen defaults. DO NOT EDIT.
+
+package {{.Package}}
+{{if .Imports}}
+import (
+{{- range .Imports}}
+	{{if .Alias}}{{.Alias}} {{end}}"{{.Path}}"
+{{- end}}
+)
+{{end}}
+// {{.Constructor}} returns a {{.TypeName}} with every declared default applied.
+// It is intended as the base layer of a layer broker, e.g. {{.Broker}}({{.Constructor}}()).
+func {{.Constructor}}() *{{.TypeName}} {
+	c := &{{.TypeName}}{}
+	c.{{method "SetDefaults"}}()
+	return c
+}
+{{range .Structs}}
+// {{method "SetDefaults"}} sets every zero-valued field of c that declares a default.
+// Fields that already hold a value are left untouched.
+func (c *{{.Name}}) {{method "SetDefaults"}}() {
+	if c == nil {
+		return
+	}
+{{- range .Fields}}
+{{- if .Literal}}
+{{- if and .IsPointer (not .IsSlice) (not .IsMap)}}
+	if c.{{.Name}} == nil {
+		v := {{.Literal}}
+		c.{{.Name}} = &v
+	}
+{{- else}}
+	if {{.Zero}} {
+		c.{{.Name}} = {{.Literal}}
+	}
+{{- end}}
+{{- else if .Elements}}
+	for i := range c.{{.Name}} {
+		c.{{.Name}}[i].{{method "SetDefaults"}}()
+	}
+{{- else if .NestedPtr}}
+	if c.{{.Name}} == nil {
+		c.{{.Name}} = &{{.StructTypeName}}{}
+	}
+	c.{{.Name}}.{{method "SetDefaults"}}()
+{{- else if .Nested}}
+	c.{{.Name}}.{{method "SetDefaults"}}()
+{{- end}}
+{{- end}}
+}
+{{end}}
+`
+
+const defaultsTestTemplate = `// Code generated by sudo-gen defaults. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDefault{{.TestName}}(t *testing.T) {
+	c := {{.Constructor}}()
+	if c == nil {
+		t.Fatal("{{.Constructor}}() returned nil")
+	}
+{{- with index .Structs 0}}
+{{- range .Fields}}
+{{- if and .Literal (not .TypePkg) (not .IsPointer) (not .IsSlice) (not .IsMap)}}
+	if c.{{.Name}} != {{.Literal}} {
+		t.Errorf("{{.Name}} = %v, want %v", c.{{.Name}}, {{.Literal}})
+	}
+{{- end}}
+{{- end}}
+{{- end}}
+}
+
+func Test{{.TestName}}SetDefaultsIdempotent(t *testing.T) {
+	c := {{.Constructor}}()
+	c.{{method "SetDefaults"}}()
+	if !reflect.DeepEqual(c, {{.Constructor}}()) {
+		t.Error("SetDefaults changed an already defaulted value")
+	}
+}
+
+func Test{{.TestName}}SetDefaultsNil(t *testing.T) {
+	var c *{{.TypeName}}
+	c.{{method "SetDefaults"}}() // Should not panic
+}
+{{- with index .Structs 0}}
+{{- range .Fields}}
+{{- if and .Literal (eq .Type "string")}}
+
+func Test{{$.TestName}}SetDefaultsKeeps{{.Name}}(t *testing.T) {
+	c := &{{$.TypeName}}{ {{- .Name}}: "custom"}
+	c.{{method "SetDefaults"}}()
+	if c.{{.Name}} != "custom" {
+		t.Errorf("{{.Name}} = %q, want %q", c.{{.Name}}, "custom")
+	}
+}
+{{- end}}
+{{- end}}
+{{- end}}
+`
@@ -0,0 +1,101 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DeprecatedOption is the sudo tag option marking a field as deprecated, with a
+// message for the people still setting it, as in `sudo:"deprecated=use Database.DSN"`.
+// A message of the form "use <path>", where path is a dotted path of Go field names
+// from the root type, names the field that replaces it.
+const DeprecatedOption = "deprecated"
+
+// Deprecation is a deprecated field reachable from a root type through nested
+// structs, outside slices and maps.
+type Deprecation struct {
+	Path        []FieldInfo // Fields from the root to the deprecated field
+	Message     string
+	Replacement []FieldInfo // Fields from the root to the replacement, if the message names one
+}
+
+// Deprecations returns the deprecated fields of root and of the structs of local it
+// holds, with the replacements their messages name. It reports an error for a
+// replacement that names no field, or a field of a different type.
+func Deprecations(root *StructInfo, local map[string]*StructInfo) ([]Deprecation, error) {
+	var result []Deprecation
+	var walk func(st *StructInfo, path []FieldInfo, visiting map[string]bool) error
+	walk = func(st *StructInfo, path []FieldInfo, visiting map[string]bool) error {
+		visiting[st.Name] = true
+		defer delete(visiting, st.Name)
+		for _, f := range st.Fields {
+			fieldPath := append(path[:len(path):len(path)], f)
+			if message, ok := f.TagOption(DeprecatedOption); ok {
+				d := Deprecation{Path: fieldPath, Message: message}
+				target, ok := strings.CutPrefix(message, "use ")
+				if ok && target != "" && !strings.ContainsAny(target, " \t") {
+					replacement, err := resolvePath(root, local, target)
+					if err != nil {
+						return fmt.Errorf("%s.%s: %s: %w", st.Name, f.Name, message, err)
+					}
+					if last := replacement[len(replacement)-1]; strings.TrimPrefix(last.Type, "*") != strings.TrimPrefix(f.Type, "*") {
+						return fmt.Errorf("%s.%s: replacement %s has type %s, not %s", st.Name, f.Name, target, last.Type, f.Type)
+					}
+					d.Replacement = replacement
+				}
+				result = append(result, d)
+			}
+			if nested := localNested(f, local); nested != nil && !visiting[nested.Name] {
+				if err := walk(nested, fieldPath, visiting); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := walk(root, nil, make(map[string]bool)); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// resolvePath returns the fields along a dotted path of Go field names from root.
+func resolvePath(root *StructInfo, local map[string]*StructInfo, path string) ([]FieldInfo, error) {
+	var fields []FieldInfo
+	st := root
+	for name := range strings.SplitSeq(path, ".") {
+		if st == nil {
+			return nil, fmt.Errorf("%s is not a struct", JoinFieldPath(fields))
+		}
+		var found *FieldInfo
+		for i := range st.Fields {
+			if st.Fields[i].Name == name {
+				found = &st.Fields[i]
+				break
+			}
+		}
+		if found == nil {
+			return nil, fmt.Errorf("%s has no field %s", st.Name, name)
+		}
+		fields = append(fields, *found)
+		st = localNested(*found, local)
+	}
+	return fields, nil
+}
+
+// localNested returns the struct of local that f holds directly or through a pointer.
+func localNested(f FieldInfo, local map[string]*StructInfo) *StructInfo {
+	if f.TypePkg != "" || f.IsSlice || f.IsMap {
+		return nil
+	}
+	return local[f.TypeName]
+}
+
+// JoinFieldPath returns the dotted Go field names of path, e.g. "Database.URL".
+func JoinFieldPath(path []FieldInfo) string {
+	names := make([]string, len(path))
+	for i, f := range path {
+		names[i] = f.Name
+	}
+	return strings.Join(names, ".")
+}
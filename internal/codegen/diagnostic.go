@@ -0,0 +1,72 @@
+package codegen
+
+import (
+	"errors"
+	"go/ast"
+	"go/scanner"
+	"go/token"
+	"path/filepath"
+)
+
+// Diagnostic is a machine-readable description of a generation failure.
+type Diagnostic struct {
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Message string `json:"message"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+// Diagnostics converts err into one or more diagnostics for the source described by cfg.
+// Syntax errors keep their original positions; other errors are attributed to the
+// declaration of cfg.TypeName when it can be located.
+func Diagnostics(err error, cfg GeneratorConfig) []Diagnostic {
+	var list scanner.ErrorList
+	if errors.As(err, &list) && len(list) > 0 {
+		diags := make([]Diagnostic, 0, len(list))
+		for _, e := range list {
+			diags = append(diags, Diagnostic{
+				File:    e.Pos.Filename,
+				Line:    e.Pos.Line,
+				Column:  e.Pos.Column,
+				Type:    cfg.TypeName,
+				Message: e.Msg,
+			})
+		}
+		return diags
+	}
+	d := Diagnostic{Type: cfg.TypeName, Message: err.Error()}
+	if cfg.SourceFile != "" {
+		d.File = filepath.Join(cfg.SourceDir, cfg.SourceFile)
+		if pos, ok := typePosition(d.File, cfg.Source, cfg.TypeName); ok {
+			d.Line = pos.Line
+			d.Column = pos.Column
+		}
+	}
+	return []Diagnostic{d}
+}
+
+// typePosition returns the position of the declaration of typeName in the given file.
+func typePosition(path string, src []byte, typeName string) (token.Position, bool) {
+	if typeName == "" {
+		return token.Position{}, false
+	}
+	fset := token.NewFileSet()
+	f, err := parseSourceFile(fset, path, src)
+	if err != nil {
+		return token.Position{}, false
+	}
+	for _, decl := range f.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			if typeSpec, ok := spec.(*ast.TypeSpec); ok && typeSpec.Name.Name == typeName {
+				return fset.Position(typeSpec.Pos()), true
+			}
+		}
+	}
+	return token.Position{}, false
+}
@@ -0,0 +1,209 @@
+package codegen
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+type diffOp struct {
+	kind byte // ' ', '-', or '+'
+	line string
+}
+
+// UnifiedDiff returns a unified diff turning oldContent into newContent.
+// It returns an empty string when the contents are identical.
+func UnifiedDiff(oldName, newName string, oldContent, newContent []byte) string {
+	if string(oldContent) == string(newContent) {
+		return ""
+	}
+	ops := diffLines(splitLines(string(oldContent)), splitLines(string(newContent)))
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+	for start := 0; start < len(ops); {
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		end := hunkEnd(ops, start)
+		from := max(start-diffContext, 0)
+		to := min(end+diffContext, len(ops))
+		writeHunk(&b, ops, from, to)
+		start = to
+	}
+	return b.String()
+}
+
+// hunkEnd returns the index just past the last change belonging to the hunk
+// starting at start. Changes separated by fewer than 2*diffContext unchanged
+// lines are merged into a single hunk.
+func hunkEnd(ops []diffOp, start int) int {
+	end := start
+	for i := start; i < len(ops); i++ {
+		if ops[i].kind != ' ' {
+			end = i + 1
+			continue
+		}
+		if i-end >= 2*diffContext {
+			break
+		}
+	}
+	return end
+}
+
+func writeHunk(b *strings.Builder, ops []diffOp, from, to int) {
+	oldLine, newLine := 1, 1
+	for _, op := range ops[:from] {
+		if op.kind != '+' {
+			oldLine++
+		}
+		if op.kind != '-' {
+			newLine++
+		}
+	}
+	var oldCount, newCount int
+	for _, op := range ops[from:to] {
+		if op.kind != '+' {
+			oldCount++
+		}
+		if op.kind != '-' {
+			newCount++
+		}
+	}
+	if oldCount == 0 {
+		oldLine--
+	}
+	if newCount == 0 {
+		newLine--
+	}
+	fmt.Fprintf(b, "@@ -%d,%d +%d,%d @@\n", oldLine, oldCount, newLine, newCount)
+	for _, op := range ops[from:to] {
+		b.WriteByte(op.kind)
+		b.WriteString(op.line)
+		b.WriteByte('\n')
+	}
+}
+
+// diffLines computes a shortest line-level edit script with Myers' O(ND) algorithm,
+// in space linear in the number of lines: each part is split at the middle snake of
+// its edit script, after its common prefix and suffix are set aside. Within each
+// change, removed lines come before added ones.
+func diffLines(a, b []string) []diffOp {
+	d := &differ{a: a, b: b, ops: make([]diffOp, 0, len(a)+len(b))}
+	d.diff(0, len(a), 0, len(b))
+	for start := 0; start < len(d.ops); {
+		end := start
+		for end < len(d.ops) && d.ops[end].kind != ' ' {
+			end++
+		}
+		slices.SortStableFunc(d.ops[start:end], func(x, y diffOp) int {
+			return cmp.Compare(y.kind, x.kind) // '-' before '+'
+		})
+		start = end + 1
+	}
+	return d.ops
+}
+
+// differ accumulates the edit script turning a into b.
+type differ struct {
+	a, b []string
+	ops  []diffOp
+}
+
+// diff appends the edit script turning a[a0:a1] into b[b0:b1].
+func (d *differ) diff(a0, a1, b0, b1 int) {
+	for a0 < a1 && b0 < b1 && d.a[a0] == d.b[b0] {
+		d.ops = append(d.ops, diffOp{' ', d.a[a0]})
+		a0++
+		b0++
+	}
+	suffix := a1
+	for a1 > a0 && b1 > b0 && d.a[a1-1] == d.b[b1-1] {
+		a1--
+		b1--
+	}
+	switch {
+	case a0 == a1:
+		for _, line := range d.b[b0:b1] {
+			d.ops = append(d.ops, diffOp{'+', line})
+		}
+	case b0 == b1:
+		for _, line := range d.a[a0:a1] {
+			d.ops = append(d.ops, diffOp{'-', line})
+		}
+	default:
+		// Without a common prefix or suffix, the script has at least two edits, so
+		// both sides of the middle snake hold fewer
+		x, y, u, v := d.middleSnake(a0, a1, b0, b1)
+		d.diff(a0, x, b0, y)
+		for _, line := range d.a[x:u] {
+			d.ops = append(d.ops, diffOp{' ', line})
+		}
+		d.diff(u, a1, v, b1)
+	}
+	for _, line := range d.a[a1:suffix] {
+		d.ops = append(d.ops, diffOp{' ', line})
+	}
+}
+
+// middleSnake returns the start (x, y) and end (u, v) of the snake in the middle of
+// a shortest edit script turning a[a0:a1] into b[b0:b1], found by searching from both
+// ends until the paths overlap.
+func (d *differ) middleSnake(a0, a1, b0, b1 int) (x, y, u, v int) {
+	n, m := a1-a0, b1-b0
+	delta := n - m
+	limit := (n + m + 1) / 2
+	offset := limit + 1
+	// forward[k] is the furthest x reached on diagonal k = x-y from the start, and
+	// backward[k] that on diagonal k from the end, counting from the end
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+	for depth := 0; depth <= limit; depth++ {
+		for k := -depth; k <= depth; k += 2 {
+			x := forward[offset+k-1] + 1
+			if k == -depth || (k != depth && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && d.a[a0+x] == d.b[b0+y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+			if c := delta - k; delta%2 != 0 && c >= -(depth-1) && c <= depth-1 && x+backward[offset+c] >= n {
+				return a0 + startX, b0 + startY, a0 + x, b0 + y
+			}
+		}
+		for k := -depth; k <= depth; k += 2 {
+			x := backward[offset+k-1] + 1
+			if k == -depth || (k != depth && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && d.a[a1-1-x] == d.b[b1-1-y] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+			if c := delta - k; delta%2 == 0 && c >= -depth && c <= depth && x+forward[offset+c] >= n {
+				return a1 - x, b1 - y, a1 - startX, b1 - startY
+			}
+		}
+	}
+	panic("unreachable: the searches from both ends always meet")
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
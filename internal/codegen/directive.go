@@ -0,0 +1,109 @@
+package codegen
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ModulePath is the module path sudo-gen is published at.
+const ModulePath = "github.com/bobcob7/sudo-gen"
+
+// Invocation is how go:generate directives run sudo-gen in a project.
+type Invocation string
+
+const (
+	InvokeBinary Invocation = "binary" // sudo-gen, installed on $PATH with go install
+	InvokeTool   Invocation = "tool"   // go tool sudo-gen, with a tool directive in go.mod (go get -tool)
+	InvokeRun    Invocation = "run"    // go run of the module at a pinned version, without installing it
+)
+
+// ParseInvocation parses the name of an invocation. An empty name is InvokeBinary.
+func ParseInvocation(name string) (Invocation, error) {
+	switch inv := Invocation(name); inv {
+	case "":
+		return InvokeBinary, nil
+	case InvokeBinary, InvokeTool, InvokeRun:
+		return inv, nil
+	default:
+		return "", fmt.Errorf("unknown invocation %q (want binary, tool or run)", name)
+	}
+}
+
+// Command returns the command running sudo-gen at version. Only InvokeRun uses the
+// version; development builds, which can't be fetched, run the latest release.
+func (inv Invocation) Command(version string) string {
+	switch inv {
+	case InvokeTool:
+		return "go tool sudo-gen"
+	case InvokeRun:
+		if version == "" || version == "(devel)" || strings.Contains(version, "+") {
+			version = "latest"
+		}
+		return "go run " + ModulePath + "@" + version
+	default:
+		return "sudo-gen"
+	}
+}
+
+// ParseDirective returns the subcommand and arguments of a go:generate comment
+// running sudo-gen, whichever way it is invoked. Like go generate, it only accepts
+// lines starting with //go:generate and unquotes arguments written as Go string
+// literals.
+func ParseDirective(comment string) (subcommand string, args []string, ok bool) {
+	if !strings.HasPrefix(comment, "//go:generate ") {
+		return "", nil, false
+	}
+	fields := splitDirective(comment)
+	for i, field := range fields[1:] {
+		name, _, _ := strings.Cut(field, "@")
+		if path.Base(name) == "sudo-gen" && i+2 < len(fields) {
+			return fields[i+2], fields[i+3:], true
+		}
+	}
+	return "", nil, false
+}
+
+// splitDirective splits a go:generate line into words at spaces, except inside
+// double-quoted strings, which are unquoted.
+func splitDirective(line string) []string {
+	var words []string
+	for line = strings.TrimSpace(line); line != ""; line = strings.TrimLeft(line, " \t") {
+		end := strings.IndexAny(line, " \t")
+		if line[0] == '"' {
+			if quoted, err := strconv.QuotedPrefix(line); err == nil {
+				if word, err := strconv.Unquote(quoted); err == nil {
+					words = append(words, word)
+					line = line[len(quoted):]
+					continue
+				}
+			}
+		}
+		if end < 0 {
+			end = len(line)
+		}
+		words = append(words, line[:end])
+		line = line[end:]
+	}
+	return words
+}
+
+// CommandLine returns a subcommand and its arguments as a single line, quoting
+// arguments that contain spaces or quotes, as stamped into generated files.
+func CommandLine(subcommand string, args []string) string {
+	parts := []string{subcommand}
+	for _, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'\\") {
+			arg = strconv.Quote(arg)
+		}
+		parts = append(parts, arg)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Directive returns the go:generate line running sudo-gen with args, e.g.
+// "//go:generate go tool sudo-gen copy -tests".
+func Directive(inv Invocation, version string, args ...string) string {
+	return "//go:generate " + strings.Join(append([]string{inv.Command(version)}, args...), " ")
+}
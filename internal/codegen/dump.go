@@ -0,0 +1,220 @@
+package codegen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// TemplateDump is what ModeDumpData writes for each generated file: the data its
+// template is executed with, and the types and functions the template can use. The
+// shape of TemplateDump is stable; the fields of the template data follow the
+// generators and change with the version of sudo-gen.
+type TemplateDump struct {
+	File     string                `json:"file"`     // File the template generates
+	Template string                `json:"template"` // Name of its override in a -templates directory
+	DataType string                `json:"dataType"` // Type of Data, e.g. copy.templateData
+	Data     any                   `json:"data"`
+	Types    map[string]DumpedType `json:"types"` // Struct types of sudo-gen reachable from Data, by name
+	Funcs    map[string]string     `json:"funcs"` // Template functions and their signatures, besides the text/template builtins
+}
+
+// DumpedType describes a struct type of template data.
+type DumpedType struct {
+	Fields  []DumpedMember `json:"fields"`
+	Methods []DumpedMember `json:"methods,omitempty"`
+}
+
+// DumpedMember is a field of a DumpedType and its type, or a method and its signature.
+type DumpedMember struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// astNodeType is the interface of go/ast nodes, which are dumped as source.
+var astNodeType = reflect.TypeFor[ast.Node]()
+
+// dumpData writes the TemplateDump of outputFile to stdout.
+func (g *TemplateGenerator) dumpData(outputFile string, data any) error {
+	funcs := make(map[string]string)
+	for name, fn := range g.funcs() {
+		funcs[name] = reflect.TypeOf(fn).String()
+	}
+	dump := TemplateDump{
+		File:     outputFile,
+		Template: TemplateName(g.SourceFile, outputFile),
+		DataType: reflect.TypeOf(data).String(),
+		Data:     dumpValue(reflect.ValueOf(data), make(map[uintptr]bool)),
+		Types:    make(map[string]DumpedType),
+		Funcs:    funcs,
+	}
+	collectTypes(reflect.TypeOf(data), dump.Types)
+	out, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding template data of %s: %w", outputFile, err)
+	}
+	if _, err := os.Stdout.Write(append(out, '\n')); err != nil {
+		return fmt.Errorf("writing to stdout: %w", err)
+	}
+	return nil
+}
+
+// dumpedObject is a struct value, encoded as a JSON object with its fields in
+// declaration order.
+type dumpedObject []dumpedField
+
+type dumpedField struct {
+	name  string
+	value any
+}
+
+func (o dumpedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(f.name)
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// dumpValue returns v as a value encoding/json can marshal. Exported fields of
+// structs are kept, as templates can only reach those; go/ast nodes are printed as
+// source, and functions and channels are dropped. Pointers on the path from the
+// root are tracked in seen, so cycles end in "(cycle)".
+func dumpValue(v reflect.Value, seen map[uintptr]bool) any {
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Type().Implements(astNodeType) && v.Kind() != reflect.Struct {
+		if v.IsNil() {
+			return nil
+		}
+		if expr, ok := v.Interface().(ast.Expr); ok {
+			return types.ExprString(expr)
+		}
+		return fmt.Sprintf("%T", v.Interface())
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Kind() == reflect.Pointer {
+			if seen[v.Pointer()] {
+				return "(cycle)"
+			}
+			seen[v.Pointer()] = true
+			defer delete(seen, v.Pointer())
+		}
+		return dumpValue(v.Elem(), seen)
+	case reflect.Struct:
+		obj := dumpedObject{}
+		for i := range v.NumField() {
+			f := v.Type().Field(i)
+			if !f.IsExported() || isOpaqueKind(f.Type.Kind()) {
+				continue
+			}
+			obj = append(obj, dumpedField{f.Name, dumpValue(v.Field(i), seen)})
+		}
+		return obj
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		list := make([]any, v.Len())
+		for i := range list {
+			list[i] = dumpValue(v.Index(i), seen)
+		}
+		return list
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		m := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			m[fmt.Sprint(iter.Key().Interface())] = dumpValue(iter.Value(), seen)
+		}
+		return m
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return nil
+	}
+	return v.Interface()
+}
+
+// isOpaqueKind reports whether values of kind k are left out of dumps.
+func isOpaqueKind(k reflect.Kind) bool {
+	return k == reflect.Func || k == reflect.Chan || k == reflect.UnsafePointer
+}
+
+// collectTypes adds the struct types of sudo-gen reachable from t to found, with
+// their exported fields and methods.
+func collectTypes(t reflect.Type, found map[string]DumpedType) {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || !strings.HasPrefix(t.PkgPath(), ModulePath) {
+		return
+	}
+	if _, ok := found[t.String()]; ok {
+		return
+	}
+	var dumped DumpedType
+	found[t.String()] = dumped
+	var fieldTypes []reflect.Type
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() || isOpaqueKind(f.Type.Kind()) {
+			continue
+		}
+		dumped.Fields = append(dumped.Fields, DumpedMember{Name: f.Name, Type: f.Type.String()})
+		fieldTypes = append(fieldTypes, f.Type)
+	}
+	// Templates call the methods of pointer receivers on addressable values too
+	ptr := reflect.PointerTo(t)
+	for i := range ptr.NumMethod() {
+		m := ptr.Method(i)
+		dumped.Methods = append(dumped.Methods, DumpedMember{Name: m.Name, Type: methodSignature(m.Type)})
+	}
+	found[t.String()] = dumped
+	for _, ft := range fieldTypes {
+		collectTypes(ft, found)
+	}
+}
+
+// methodSignature returns the signature of a method without its receiver, such as
+// "func(string) string".
+func methodSignature(t reflect.Type) string {
+	in := make([]string, 0, t.NumIn()-1)
+	for i := 1; i < t.NumIn(); i++ {
+		in = append(in, t.In(i).String())
+	}
+	out := make([]string, t.NumOut())
+	for i := range out {
+		out[i] = t.Out(i).String()
+	}
+	sig := "func(" + strings.Join(in, ", ") + ")"
+	switch len(out) {
+	case 0:
+		return sig
+	case 1:
+		return sig + " " + out[0]
+	}
+	return sig + " (" + strings.Join(out, ", ") + ")"
+}
@@ -0,0 +1,325 @@
+// Package enum implements the enum code generation subtool.
+package enum
+
+import (
+	"fmt"
+	"go/ast"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/bobcob7/sudo-gen/internal/codegen"
+)
+
+// Subtool implements the enum code generator.
+type Subtool struct{}
+
+// Name returns the subtool name.
+func (s *Subtool) Name() string { return "enum" }
+
+// Description returns the subtool description.
+func (s *Subtool) Description() string {
+	return "Generate Parse, IsValid and ValidateEnums for fields restricted to a set of string values"
+}
+
+// Doc describes the struct tags the subtool honors and the files it generates.
+func (s *Subtool) Doc() codegen.SubtoolDoc {
+	return codegen.SubtoolDoc{
+		Tags: []codegen.DocEntry{
+			{Name: `sudo:"enum=a|b"`, Description: "Values the field is restricted to, besides constants of its type"},
+			codegen.ExcludeTagDoc(s.Name()),
+		},
+		Files: []codegen.DocEntry{
+			{Name: "{source}_enum.go", Description: "Parse{Enum}, IsValid and {Enum}Values per enum, and ValidateEnums on the type and its partial"},
+		},
+	}
+}
+
+// Run executes the enum code generation. The generated ValidateEnums method of the
+// partial builds on the merge output for the same type.
+func (s *Subtool) Run(cfg codegen.GeneratorConfig) error {
+	info, err := cfg.Index.ParseStruct(cfg.SourceDir, cfg.SourceFile, cfg.Source, cfg.TypeName)
+	if err != nil {
+		return fmt.Errorf("parsing struct: %w", err)
+	}
+	selection := codegen.NewFieldSelection(cfg, s.Name())
+	selection.Apply(info)
+	values := codegen.NewValueTypes(cfg.Index, cfg.SourceDir, cfg.ValueTypes)
+	values.Apply(info)
+	nested, err := cfg.Index.FindNestedStructs(cfg.SourceDir, cfg.Source, info, values)
+	if err != nil {
+		return fmt.Errorf("finding nested structs: %w", err)
+	}
+	structs := []*codegen.StructInfo{info}
+	local := make(map[string]*codegen.StructInfo)
+	for _, st := range nested {
+		if st.Package == "" {
+			selection.Apply(st)
+			values.Apply(st)
+			structs = append(structs, st)
+			local[st.Name] = st
+		}
+	}
+	cfg, err = codegen.CheckUnsupported(cfg, s.Name(), codegen.UnsupportedFields(structs))
+	if err != nil {
+		return err
+	}
+	b := &builder{cfg: cfg, root: info.Name, local: local, types: make(map[string]*enumType), hasEnums: make(map[string]bool)}
+	for _, st := range structs {
+		if err := b.collect(st); err != nil {
+			return err
+		}
+	}
+	if !b.contains(info, nil) {
+		return fmt.Errorf("%s has no fields with enum values (tag them sudo:\"enum=a|b\" or declare constants of their type)", info.Name)
+	}
+	data := templateData{
+		Package:  cfg.OutputPkg,
+		TypeName: info.Name,
+		Invalid:  b.invalid(),
+		Fields:   b.fields,
+	}
+	for _, name := range b.typeOrder {
+		data.Types = append(data.Types, *b.types[name])
+	}
+	for _, st := range structs {
+		if b.contains(st, nil) {
+			data.Structs = append(data.Structs, b.structChecks(st))
+		}
+	}
+	baseName := strings.TrimSuffix(cfg.SourceFile, ".go")
+	gen := codegen.NewTemplateGenerator(cfg, templateFuncs())
+	if err := gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_enum.go"), enumTemplate, data); err != nil {
+		return err
+	}
+	if cfg.GenerateTest {
+		return gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_enum_test.go"), enumTestTemplate, data)
+	}
+	return nil
+}
+
+type templateData struct {
+	Package  string
+	TypeName string
+	Invalid  string // Function returning the error for an invalid value
+	Types    []enumType
+	Fields   []enumField
+	Structs  []structCheck // Structs holding enums, the root first
+}
+
+// InvalidCheck returns a check of an enum value of the root, which generated tests
+// set to an invalid value.
+func (d templateData) InvalidCheck() *fieldCheck {
+	for i, c := range d.Structs[0].Checks {
+		if c.Kind == "value" {
+			return &d.Structs[0].Checks[i]
+		}
+	}
+	return nil
+}
+
+// enumType is a type defined over string with a set of values.
+type enumType struct {
+	Name   string
+	Values []string
+	Exprs  []string // Go expressions of Values: the constants, or conversions of literals
+}
+
+// enumField is a string field with a set of values, which gets functions of its own.
+type enumField struct {
+	Name   string // e.g. ConfigLogLevel, the struct and field names
+	Values []string
+}
+
+// structCheck is a struct whose fields are checked for values outside their sets.
+type structCheck struct {
+	Name          string
+	Func          string // Validator of the struct
+	PartialFunc   string // Validator of the partial of the struct
+	Checks        []fieldCheck
+	PartialChecks []fieldCheck
+}
+
+// fieldCheck checks a field holding enum values, or structs that do.
+type fieldCheck struct {
+	Field   string
+	Kind    string // value, pointer or slice of enum values; struct, structPointer, structSlice or structPointerSlice
+	Type    string // Type of the enum values
+	Valid   string // Function or method expression reporting whether an enum value is valid
+	Values  string // The valid values, for error messages
+	Func    string // Validator of a nested struct
+	Invalid string // Function returning the error for an invalid value
+}
+
+// builder collects the enums of a config and the checks of its structs.
+type builder struct {
+	cfg       codegen.GeneratorConfig
+	root      string
+	local     map[string]*codegen.StructInfo
+	types     map[string]*enumType
+	typeOrder []string
+	fields    []enumField
+	enums     map[string]codegen.Enum // By struct and field name
+	hasEnums  map[string]bool         // Whether a struct holds enums, by name
+}
+
+// collect records the enums of the fields of st.
+func (b *builder) collect(st *codegen.StructInfo) error {
+	if b.enums == nil {
+		b.enums = make(map[string]codegen.Enum)
+	}
+	for _, f := range st.Fields {
+		e, ok := b.cfg.Index.EnumOf(b.cfg.SourceDir, f)
+		if !ok {
+			if _, tagged := f.TagOption(codegen.EnumOption); tagged {
+				return fmt.Errorf("%s.%s: enum values need a string field, a pointer to one or a slice of them", st.Name, f.Name)
+			}
+			continue
+		}
+		b.enums[st.Name+"."+f.Name] = e
+		if !e.Defined() {
+			b.fields = append(b.fields, enumField{Name: st.Name + f.Name, Values: e.Values})
+			continue
+		}
+		exprs := e.Consts
+		if len(exprs) == 0 {
+			for _, v := range e.Values {
+				exprs = append(exprs, e.Type+"("+strconv.Quote(v)+")")
+			}
+		}
+		if existing, ok := b.types[e.Type]; ok {
+			if strings.Join(existing.Values, "|") != strings.Join(e.Values, "|") {
+				return fmt.Errorf("%s.%s: enum values of %s differ from the ones of another field", st.Name, f.Name, e.Type)
+			}
+			continue
+		}
+		b.types[e.Type] = &enumType{Name: e.Type, Values: e.Values, Exprs: exprs}
+		b.typeOrder = append(b.typeOrder, e.Type)
+	}
+	return nil
+}
+
+// contains reports whether st holds enum values, directly or in nested structs.
+func (b *builder) contains(st *codegen.StructInfo, visiting map[string]bool) bool {
+	if has, ok := b.hasEnums[st.Name]; ok {
+		return has
+	}
+	if visiting == nil {
+		visiting = make(map[string]bool)
+	}
+	if visiting[st.Name] {
+		return false
+	}
+	visiting[st.Name] = true
+	has := false
+	for _, f := range st.Fields {
+		if _, ok := b.enums[st.Name+"."+f.Name]; ok {
+			has = true
+		} else if nested := b.nested(f); nested != nil && b.contains(nested, visiting) {
+			has = true
+		}
+	}
+	b.hasEnums[st.Name] = has
+	return has
+}
+
+// nested returns the struct of the package that f holds, unless it is in a map.
+func (b *builder) nested(f codegen.FieldInfo) *codegen.StructInfo {
+	if f.TypePkg != "" || f.IsMap || f.IsValue {
+		return nil
+	}
+	return b.local[f.StructTypeName]
+}
+
+// structChecks builds the checks of the fields of st and of its partial.
+func (b *builder) structChecks(st *codegen.StructInfo) structCheck {
+	sc := structCheck{Name: st.Name, Func: b.validator(st.Name, false), PartialFunc: b.validator(st.Name, true)}
+	for _, f := range st.Fields {
+		if e, ok := b.enums[st.Name+"."+f.Name]; ok {
+			c := fieldCheck{Field: f.Name, Type: e.Type, Values: strings.Join(e.Values, ", "), Invalid: b.invalid()}
+			if e.Defined() {
+				c.Valid = e.Type + ".IsValid"
+			} else {
+				c.Valid = ident("isValid", st.Name+f.Name, "")
+			}
+			switch {
+			case f.IsSlice:
+				c.Kind = "slice"
+			case f.IsPointer:
+				c.Kind = "pointer"
+			default:
+				c.Kind = "value"
+			}
+			sc.Checks = append(sc.Checks, c)
+			partial := c
+			if !f.IsSlice {
+				partial.Kind = "pointer"
+			}
+			sc.PartialChecks = append(sc.PartialChecks, partial)
+			continue
+		}
+		nested := b.nested(f)
+		if nested == nil || !b.contains(nested, nil) {
+			continue
+		}
+		c := fieldCheck{Field: f.Name, Func: b.validator(nested.Name, false)}
+		switch {
+		case f.IsSlice && f.SliceElemIsPtr:
+			c.Kind = "structPointerSlice"
+		case f.IsSlice:
+			c.Kind = "structSlice"
+		case f.IsPointer:
+			c.Kind = "structPointer"
+		default:
+			c.Kind = "struct"
+		}
+		sc.Checks = append(sc.Checks, c)
+		// Partials hold nested structs as partials, and slices of them as whole values
+		partial := c
+		if !f.IsSlice {
+			partial.Kind, partial.Func = "structPointer", b.validator(nested.Name, true)
+		}
+		sc.PartialChecks = append(sc.PartialChecks, partial)
+	}
+	return sc
+}
+
+// validator returns the name of the function validating a struct of the config, or
+// its partial if partial is set.
+func (b *builder) validator(name string, partial bool) string {
+	suffix := ""
+	if partial {
+		suffix = b.cfg.Partial("")
+	}
+	if name == b.root {
+		return "validate" + capitalize(b.root) + suffix + "Enums"
+	}
+	return "validate" + capitalize(b.root) + capitalize(name) + suffix + "Enums"
+}
+
+// invalid returns the name of the function building the error for an invalid value,
+// which stays unexported as generated code alone calls it.
+func (b *builder) invalid() string {
+	return "invalid" + capitalize(b.root) + "Enum"
+}
+
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"ident": ident,
+		"join":  strings.Join,
+	}
+}
+
+func capitalize(name string) string {
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// ident builds a generated name such as ParseLogLevel, keeping it unexported
+// (parseLogLevel) when the type is unexported.
+func ident(verb, typeName, suffix string) string {
+	if ast.IsExported(typeName) {
+		return capitalize(verb) + typeName + suffix
+	}
+	return verb + capitalize(typeName) + suffix
+}
@@ -0,0 +1,213 @@
+package enum
+
+const enumTemplate = `// Code generated by sudo-gen enum. DO NOT EDIT.
+
+// {{.TypeName}}.ValidateEnums reports the first field holding a value outside its enum
+// values, so a config can be checked when it is loaded:
+//
+//	broker := {{ident "new" .TypeName "LayerBroker"}}(nil, {{ident "with" .TypeName "Validator"}}(func(c {{.TypeName}}) error {
+//	    return c.{{method "ValidateEnums"}}()
+//	}))
+//
+// Empty values are taken as unset and pass. {{partial .TypeName}}.ValidateEnums checks the
+// fields a partial sets.
+//
+// # Dependencies
+//
+// This generated code requires the following to also be generated:
+//   - {{partial .TypeName}} (from: sudo-gen merge)
+package {{.Package}}
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// {{ident "err" .TypeName "InvalidEnum"}} is returned for a value outside the enum values of its field.
+var {{ident "err" .TypeName "InvalidEnum"}} = errors.New("invalid {{.TypeName}} enum value")
+{{- range .Types}}
+
+// {{.Name}}Values returns the values a {{.Name}} may hold.
+func {{.Name}}Values() []{{.Name}} {
+	return []{{.Name}}{ {{- join .Exprs ", " -}} }
+}
+
+// {{ident "parse" .Name ""}} returns s as a {{.Name}}, or an error wrapping {{ident "err" $.TypeName "InvalidEnum"}} if it
+// is not one of {{join .Values ", "}}.
+func {{ident "parse" .Name ""}}(s string) ({{.Name}}, error) {
+	if v := {{.Name}}(s); v.IsValid() {
+		return v, nil
+	}
+	return "", fmt.Errorf("%w: %q, want one of %s", {{ident "err" $.TypeName "InvalidEnum"}}, s, {{printf "%q" (join .Values ", ")}})
+}
+
+// IsValid reports whether v is one of {{join .Values ", "}}.
+func (v {{.Name}}) IsValid() bool {
+	return slices.Contains({{.Name}}Values(), v)
+}
+{{- end}}
+{{- range .Fields}}
+
+// {{.Name}}Values returns the values the field may hold.
+func {{.Name}}Values() []string {
+	return []string{ {{- range $i, $v := .Values}}{{if $i}}, {{end}}{{printf "%q" $v}}{{end -}} }
+}
+
+// {{ident "parse" .Name ""}} returns s, or an error wrapping {{ident "err" $.TypeName "InvalidEnum"}} if it is not one of
+// {{join .Values ", "}}.
+func {{ident "parse" .Name ""}}(s string) (string, error) {
+	if {{ident "isValid" .Name ""}}(s) {
+		return s, nil
+	}
+	return "", fmt.Errorf("%w: %q, want one of %s", {{ident "err" $.TypeName "InvalidEnum"}}, s, {{printf "%q" (join .Values ", ")}})
+}
+
+// {{ident "isValid" .Name ""}} reports whether s is one of {{join .Values ", "}}.
+func {{ident "isValid" .Name ""}}(s string) bool {
+	return slices.Contains({{.Name}}Values(), s)
+}
+{{- end}}
+
+// {{method "ValidateEnums"}} returns an error wrapping {{ident "err" .TypeName "InvalidEnum"}} for the first field of c,
+// at any depth, that holds a value outside its enum values.
+func (c *{{.TypeName}}) {{method "ValidateEnums"}}() error {
+	return {{(index .Structs 0).Func}}(c, "")
+}
+
+// {{method "ValidateEnums"}} returns an error wrapping {{ident "err" .TypeName "InvalidEnum"}} for the first field p
+// sets, at any depth, to a value outside its enum values.
+func (p *{{partial .TypeName}}) {{method "ValidateEnums"}}() error {
+	return {{(index .Structs 0).PartialFunc}}(p, "")
+}
+{{- range .Structs}}
+
+func {{.Func}}(s *{{.Name}}, path string) error {
+{{- range .Checks}}
+{{- template "check" .}}
+{{- end}}
+	return nil
+}
+
+func {{.PartialFunc}}(s *{{partial .Name}}, path string) error {
+{{- range .PartialChecks}}
+{{- template "check" .}}
+{{- end}}
+	return nil
+}
+{{- end}}
+
+// {{.Invalid}} returns the error for a value outside the enum values of a field.
+func {{.Invalid}}(path, value, values string) error {
+	return fmt.Errorf("%w: %s is %s, want one of %s", {{ident "err" .TypeName "InvalidEnum"}}, path, strconv.Quote(value), values)
+}
+{{- define "check"}}
+{{- if eq .Kind "value"}}
+	if v := s.{{.Field}}; v != "" && !{{.Valid}}(v) {
+		return {{.Invalid}}(path+"{{.Field}}", string(v), {{printf "%q" .Values}})
+	}
+{{- else if eq .Kind "pointer"}}
+	if v := s.{{.Field}}; v != nil && *v != "" && !{{.Valid}}(*v) {
+		return {{.Invalid}}(path+"{{.Field}}", string(*v), {{printf "%q" .Values}})
+	}
+{{- else if eq .Kind "slice"}}
+	for i, v := range s.{{.Field}} {
+		if v != "" && !{{.Valid}}(v) {
+			return {{.Invalid}}(path+"{{.Field}}["+strconv.Itoa(i)+"]", string(v), {{printf "%q" .Values}})
+		}
+	}
+{{- else if eq .Kind "struct"}}
+	if err := {{.Func}}(&s.{{.Field}}, path+"{{.Field}}."); err != nil {
+		return err
+	}
+{{- else if eq .Kind "structPointer"}}
+	if s.{{.Field}} != nil {
+		if err := {{.Func}}(s.{{.Field}}, path+"{{.Field}}."); err != nil {
+			return err
+		}
+	}
+{{- else if eq .Kind "structSlice"}}
+	for i := range s.{{.Field}} {
+		if err := {{.Func}}(&s.{{.Field}}[i], path+"{{.Field}}["+strconv.Itoa(i)+"]."); err != nil {
+			return err
+		}
+	}
+{{- else if eq .Kind "structPointerSlice"}}
+	for i, elem := range s.{{.Field}} {
+		if elem == nil {
+			continue
+		}
+		if err := {{.Func}}(elem, path+"{{.Field}}["+strconv.Itoa(i)+"]."); err != nil {
+			return err
+		}
+	}
+{{- end}}
+{{- end}}
+`
+
+const enumTestTemplate = `// Code generated by sudo-gen enum. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"errors"
+	"testing"
+)
+{{- range .Types}}
+
+func Test{{ident "parse" .Name ""}}(t *testing.T) {
+	for _, want := range {{.Name}}Values() {
+		got, err := {{ident "parse" .Name ""}}(string(want))
+		if err != nil || got != want {
+			t.Errorf("{{ident "parse" .Name ""}}(%q) = %q, %v", want, got, err)
+		}
+	}
+	if _, err := {{ident "parse" .Name ""}}("no-such-value"); !errors.Is(err, {{ident "err" $.TypeName "InvalidEnum"}}) {
+		t.Errorf("expected {{ident "err" $.TypeName "InvalidEnum"}}, got %v", err)
+	}
+	if {{.Name}}("no-such-value").IsValid() {
+		t.Error("IsValid reported true for an invalid value")
+	}
+}
+{{- end}}
+{{- range .Fields}}
+
+func Test{{ident "parse" .Name ""}}(t *testing.T) {
+	for _, want := range {{.Name}}Values() {
+		got, err := {{ident "parse" .Name ""}}(want)
+		if err != nil || got != want {
+			t.Errorf("{{ident "parse" .Name ""}}(%q) = %q, %v", want, got, err)
+		}
+	}
+	if _, err := {{ident "parse" .Name ""}}("no-such-value"); !errors.Is(err, {{ident "err" $.TypeName "InvalidEnum"}}) {
+		t.Errorf("expected {{ident "err" $.TypeName "InvalidEnum"}}, got %v", err)
+	}
+	if {{ident "isValid" .Name ""}}("no-such-value") {
+		t.Error("{{ident "isValid" .Name ""}} reported true for an invalid value")
+	}
+}
+{{- end}}
+
+func Test{{.TypeName}}ValidateEnums(t *testing.T) {
+	var c {{.TypeName}}
+	if err := c.{{method "ValidateEnums"}}(); err != nil {
+		t.Errorf("zero {{.TypeName}}: %v", err)
+	}
+	var p {{partial .TypeName}}
+	if err := p.{{method "ValidateEnums"}}(); err != nil {
+		t.Errorf("empty {{partial .TypeName}}: %v", err)
+	}
+{{- with .InvalidCheck}}
+	c.{{.Field}} = "no-such-value"
+	if err := c.{{method "ValidateEnums"}}(); !errors.Is(err, {{ident "err" $.TypeName "InvalidEnum"}}) {
+		t.Errorf("{{.Field}}: expected {{ident "err" $.TypeName "InvalidEnum"}}, got %v", err)
+	}
+	invalid := {{.Type}}("no-such-value")
+	p.{{.Field}} = &invalid
+	if err := p.{{method "ValidateEnums"}}(); !errors.Is(err, {{ident "err" $.TypeName "InvalidEnum"}}) {
+		t.Errorf("partial {{.Field}}: expected {{ident "err" $.TypeName "InvalidEnum"}}, got %v", err)
+	}
+{{- end}}
+}
+`
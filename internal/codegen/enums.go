@@ -0,0 +1,77 @@
+package codegen
+
+import (
+	"cmp"
+	"go/constant"
+	"go/types"
+	"slices"
+	"strings"
+)
+
+// EnumOption is the sudo tag option listing the values a string field may hold,
+// separated by |, as in `sudo:"enum=debug|info|warn|error"`.
+const EnumOption = "enum"
+
+// Enum is the set of values a field may hold.
+type Enum struct {
+	Type   string   // Type of the values: string, or a type of the package defined over string
+	Values []string // In declaration order
+	Consts []string // Constants declaring Values, when they come from constants rather than a tag
+}
+
+// Defined reports whether the values have a type of their own, rather than string.
+func (e Enum) Defined() bool {
+	return e.Type != "string"
+}
+
+// EnumOf returns the values the field f of a struct declared in dir may hold: the
+// values of its enum tag option, or else the string constants declared in dir of its
+// defined type. Fields of string type, of pointers to it, and of slices of it can be
+// enums; it reports false for other fields and for fields without values.
+func (x *PackageIndex) EnumOf(dir string, f FieldInfo) (Enum, bool) {
+	if f.IsMap || f.TypePkg != "" {
+		return Enum{}, false
+	}
+	typeName := f.TypeName
+	if f.IsSlice {
+		typeName = f.SliceType
+	}
+	var named *types.TypeName
+	if typeName != "string" {
+		pkg := x.Types(dir)
+		if pkg == nil {
+			return Enum{}, false
+		}
+		obj, ok := pkg.Scope().Lookup(typeName).(*types.TypeName)
+		if !ok {
+			return Enum{}, false
+		}
+		if basic, ok := obj.Type().Underlying().(*types.Basic); !ok || basic.Kind() != types.String {
+			return Enum{}, false
+		}
+		named = obj
+	}
+	if values, ok := f.TagOption(EnumOption); ok {
+		return Enum{Type: typeName, Values: strings.Split(values, "|")}, true
+	}
+	if named == nil {
+		return Enum{}, false
+	}
+	var consts []*types.Const
+	scope := named.Pkg().Scope()
+	for _, name := range scope.Names() {
+		if c, ok := scope.Lookup(name).(*types.Const); ok && types.Identical(c.Type(), named.Type()) && c.Val().Kind() == constant.String {
+			consts = append(consts, c)
+		}
+	}
+	if len(consts) == 0 {
+		return Enum{}, false
+	}
+	slices.SortFunc(consts, func(a, b *types.Const) int { return cmp.Compare(a.Pos(), b.Pos()) })
+	e := Enum{Type: typeName}
+	for _, c := range consts {
+		e.Values = append(e.Values, constant.StringVal(c.Val()))
+		e.Consts = append(e.Consts, c.Name())
+	}
+	return e, true
+}
@@ -0,0 +1,191 @@
+// Package envdoc implements the envdoc code generation subtool.
+package envdoc
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/bobcob7/sudo-gen/internal/codegen"
+)
+
+// Subtool implements the envdoc code generator.
+type Subtool struct{}
+
+// Name returns the subtool name.
+func (s *Subtool) Name() string { return "envdoc" }
+
+// Description returns the subtool description.
+func (s *Subtool) Description() string {
+	return "Generate a Markdown table of the environment variables bound with env tags"
+}
+
+// Doc describes the struct tags the subtool honors and the files it generates.
+func (s *Subtool) Doc() codegen.SubtoolDoc {
+	return codegen.SubtoolDoc{
+		Tags: []codegen.DocEntry{
+			{Name: `env:"NAME"`, Description: `Environment variable documented for the field; env:"NAME,required" marks it required`},
+			{Name: `envPrefix:"PREFIX_"`, Description: "Prefix of the variables of a nested struct field"},
+			{Name: `default:"value"`, Description: "Default listed for the variable"},
+		},
+		Files: []codegen.DocEntry{
+			{Name: "{source}_env.md", Description: "Markdown table of the env-tagged fields' variables"},
+			{Name: "{source}_envdoc.go", Description: "{Type}EnvDoc constant holding the same table"},
+		},
+	}
+}
+
+// Run executes the envdoc code generation.
+func (s *Subtool) Run(cfg codegen.GeneratorConfig) error {
+	info, err := cfg.Index.ParseStruct(cfg.SourceDir, cfg.SourceFile, cfg.Source, cfg.TypeName)
+	if err != nil {
+		return fmt.Errorf("parsing struct: %w", err)
+	}
+	values := codegen.NewValueTypes(cfg.Index, cfg.SourceDir, cfg.ValueTypes)
+	values.Apply(info)
+	nested, err := cfg.Index.FindNestedStructs(cfg.SourceDir, cfg.Source, info, values)
+	if err != nil {
+		return fmt.Errorf("finding nested structs: %w", err)
+	}
+	local := make(map[string]*codegen.StructInfo)
+	for _, st := range nested {
+		if st.Package == "" {
+			values.Apply(st)
+			local[st.Name] = st
+		}
+	}
+	c := &collector{local: local, seen: map[string]bool{info.Name: true}}
+	if err := c.collect(info, ""); err != nil {
+		return err
+	}
+	if len(c.vars) == 0 {
+		return fmt.Errorf("%s has no fields tagged %s:\"NAME\"", info.Name, EnvTagKey)
+	}
+	baseName := strings.TrimSuffix(cfg.SourceFile, ".go")
+	data := templateData{
+		Package:  cfg.OutputPkg,
+		TypeName: info.Name,
+		Table:    markdownTable(c.vars),
+		DocFile:  baseName + "_env.md",
+	}
+	gen := codegen.NewTemplateGenerator(cfg, templateFuncs())
+	if err := gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_envdoc.go"), envDocTemplate, data); err != nil {
+		return err
+	}
+	if err := gen.GenerateText(filepath.Join(cfg.OutputDir, data.DocFile), markdownTemplate, data); err != nil {
+		return err
+	}
+	if cfg.GenerateTest {
+		return gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_envdoc_test.go"), envDocTestTemplate, data)
+	}
+	return nil
+}
+
+// EnvTagKey is the struct tag key naming the environment variable of a field, as in
+// `env:"PORT"` or `env:"DB_PASSWORD,required"`. A struct field's `envPrefix:"DB_"`
+// tag prefixes the variables of its fields.
+const EnvTagKey = "env"
+
+type templateData struct {
+	Package  string
+	TypeName string
+	Table    string // Markdown table of the variables
+	DocFile  string // Name of the generated Markdown file
+}
+
+// envVar is an environment variable bound to a config field.
+type envVar struct {
+	Name        string
+	Type        string
+	Default     string
+	Required    bool
+	Description string
+}
+
+type collector struct {
+	local map[string]*codegen.StructInfo
+	seen  map[string]bool // Struct types on the current path, to stop at recursive types
+	vars  []envVar
+}
+
+// collect adds the variables of the env-tagged fields of st and the local structs
+// nested in it, prefixing their names with prefix.
+func (c *collector) collect(st *codegen.StructInfo, prefix string) error {
+	for _, f := range st.Fields {
+		tag := f.StructTag()
+		if value, ok := tag.Lookup(EnvTagKey); ok {
+			name, options, _ := strings.Cut(value, ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			v := envVar{
+				Name:        prefix + name,
+				Type:        f.Type,
+				Default:     f.Default,
+				Required:    strings.Contains(","+options+",", ",required,"),
+				Description: f.Doc,
+			}
+			for _, existing := range c.vars {
+				if existing.Name == v.Name {
+					return fmt.Errorf("%s.%s: environment variable %s is bound to another field", st.Name, f.Name, v.Name)
+				}
+			}
+			c.vars = append(c.vars, v)
+			continue
+		}
+		nested, ok := c.local[f.StructTypeName]
+		if !ok || f.TypePkg != "" || f.IsSlice || f.IsMap || f.IsValue || c.seen[nested.Name] {
+			continue
+		}
+		c.seen[nested.Name] = true
+		err := c.collect(nested, prefix+tag.Get("envPrefix"))
+		delete(c.seen, nested.Name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// markdownTable renders vars as a Markdown table, one variable per row.
+func markdownTable(vars []envVar) string {
+	var b strings.Builder
+	b.WriteString("| Variable | Type | Default | Required | Description |\n")
+	b.WriteString("|----------|------|---------|----------|-------------|\n")
+	for _, v := range vars {
+		def, required := "", ""
+		if v.Default != "" {
+			def = "`" + escapeCell(v.Default) + "`"
+		}
+		if v.Required {
+			required = "yes"
+		}
+		fmt.Fprintf(&b, "| `%s` | `%s` | %s | %s | %s |\n", v.Name, escapeCell(v.Type), def, required, escapeCell(v.Description))
+	}
+	return b.String()
+}
+
+// escapeCell escapes the characters of s that would end a table cell.
+func escapeCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"goLines": goLines,
+	}
+}
+
+// goLines renders s as a Go string expression concatenating one quoted line per
+// line of s, for a readable constant.
+func goLines(s string) string {
+	lines := strings.SplitAfter(strings.TrimSuffix(s, "\n"), "\n")
+	lines[len(lines)-1] += "\n"
+	quoted := make([]string, len(lines))
+	for i, line := range lines {
+		quoted[i] = strconv.Quote(line)
+	}
+	return strings.Join(quoted, " +\n\t")
+}
@@ -0,0 +1,37 @@
+package envdoc
+
+const envDocTemplate = `// Code generated by sudo-gen envdoc. DO NOT EDIT.
+
+package {{.Package}}
+
+// {{.TypeName}}EnvDoc documents the environment variables bound to {{.TypeName}} as a Markdown
+// table, for help output and admin pages. {{.DocFile}} holds the same table.
+const {{.TypeName}}EnvDoc = {{goLines .Table}}
+`
+
+const markdownTemplate = `<!-- Code generated by sudo-gen envdoc. DO NOT EDIT. -->
+
+# {{.TypeName}} environment variables
+
+{{.Table}}`
+
+const envDocTestTemplate = `// Code generated by sudo-gen envdoc. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test{{.TypeName}}EnvDocInSync(t *testing.T) {
+	md, err := os.ReadFile("{{.DocFile}}")
+	if err != nil {
+		t.Skipf("reading {{.DocFile}}: %v", err)
+	}
+	if !strings.Contains(string(md), {{.TypeName}}EnvDoc) {
+		t.Error("{{.DocFile}} does not contain {{.TypeName}}EnvDoc; run go generate")
+	}
+}
+`
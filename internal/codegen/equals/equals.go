@@ -2,8 +2,15 @@
 package equals
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"go/ast"
+	"go/types"
+	"math"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"text/template"
 
@@ -23,20 +30,69 @@ func (s *Subtool) Description() string {
 	return "Generate type-safe equality comparison methods for structs"
 }
 
+// Doc describes the struct tags the subtool honors and the files it generates.
+func (s *Subtool) Doc() codegen.SubtoolDoc {
+	return codegen.SubtoolDoc{
+		Tags: []codegen.DocEntry{
+			{Name: `sudo:"secret"`, Description: "Compared in constant time (with -constant-time-secrets)"},
+			{Name: `sudo:"epsilon=1e-9"`, Description: "Floats compared within the tolerance, with NaN equal to NaN"},
+			codegen.ExcludeTagDoc(s.Name()),
+		},
+		Files: []codegen.DocEntry{
+			{Name: "{source}_equals.go", Description: "Type-safe Equal method for the struct (plus Diff and ExplainNotEqual with -explain-diff)"},
+			{Name: "{source}_equals_bench_test.go", Description: "Benchmark{Type}Equal (with -bench)"},
+		},
+	}
+}
+
+// DefineFlags defines the flags of the equals subcommand.
+func (s *Subtool) DefineFlags(fs *flag.FlagSet, cfg *codegen.GeneratorConfig) {
+	fs.StringVar(&s.MethodName, "method", "Equal", "Name of the generated equality method")
+	fs.BoolVar(&cfg.IncludeUnexported, "include-unexported", false, "Also compare unexported fields (requires generating into the source package)")
+	fs.BoolVar(&cfg.GenerateBench, "bench", false, "Generate Benchmark{Type}Equal in a _bench_test.go file")
+	fs.BoolVar(&cfg.UTCTimes, "utc", false, "Compare the time.Time values of slices and maps with Equal too, as merge -utc stores them in UTC")
+	AddFlags(fs, cfg)
+}
+
+// AddFlags defines the flags shaping the equals output, for the subcommands that run
+// the equals generator too.
+func AddFlags(fs *flag.FlagSet, cfg *codegen.GeneratorConfig) {
+	fs.BoolVar(&cfg.GenerateExplainDiff, "explain-diff", false, "Also generate Diff and ExplainNotEqual, reporting the paths of differing fields")
+	fs.BoolVar(&cfg.ConstantTimeSecrets, "constant-time-secrets", false, `Compare string and []byte fields tagged sudo:"secret" in constant time`)
+	fs.StringVar(&cfg.FloatEpsilon, "float-epsilon", "", `Compare float fields within this tolerance, with NaN equal to NaN (sudo:"epsilon=..." tags override it)`)
+	fs.BoolVar(&cfg.SkipNested, "skip-nested", false, "Skip fields holding slices or maps of slices or maps, which Equal can't compare, with a warning instead of failing")
+	fs.Var((*codegen.ListFlag)(&cfg.EqualFuncs), "equal-funcs", "Comma-separated Type=Func functions comparing values of types (e.g. *money.Amount=amountsEqual)")
+}
+
+// EpsilonOption is the sudo tag option setting the tolerance within which the floats
+// of a field compare equal, as in sudo:"epsilon=1e-9".
+const EpsilonOption = "epsilon"
+
 // Run executes the equals code generation.
 func (s *Subtool) Run(cfg codegen.GeneratorConfig) error {
 	methodName := s.MethodName
 	if methodName == "" {
 		methodName = "Equal"
 	}
-	info, err := codegen.ParseStruct(cfg.SourceDir, cfg.SourceFile, cfg.TypeName)
+	parse := cfg.Index.ParseStruct
+	if cfg.IncludeUnexported || cfg.CrossPackage() {
+		// Generating into another package, unexported fields are parsed to report them
+		parse = cfg.Index.ParseStructUnexported
+	}
+	info, err := parse(cfg.SourceDir, cfg.SourceFile, cfg.Source, cfg.TypeName)
 	if err != nil {
 		return fmt.Errorf("parsing struct: %w", err)
 	}
-	nested, err := codegen.FindNestedStructs(cfg.SourceDir, cfg.SourceFile, info)
+	selection := codegen.NewFieldSelection(cfg, s.Name())
+	selection.Apply(info)
+	values := codegen.NewValueTypes(cfg.Index, cfg.SourceDir, cfg.ValueTypes)
+	nested, err := cfg.Index.FindNestedStructs(cfg.SourceDir, cfg.Source, info, values)
 	if err != nil {
 		return fmt.Errorf("finding nested structs: %w", err)
 	}
+	for _, st := range nested {
+		selection.Apply(st)
+	}
 	// Filter out external package structs - we can't add methods to them
 	allStructs := []*codegen.StructInfo{info}
 	for _, st := range nested {
@@ -44,40 +100,311 @@ func (s *Subtool) Run(cfg codegen.GeneratorConfig) error {
 			allStructs = append(allStructs, st)
 		}
 	}
-	return generateEqualsFile(cfg, allStructs, methodName)
+	if err := checkEpsilons(allStructs, cfg.FloatEpsilon); err != nil {
+		return err
+	}
+	if !cfg.SkipNested {
+		if err := checkNested(allStructs); err != nil {
+			return err
+		}
+	}
+	codegen.SkipFields(allStructs, nestedContainerReason)
+	cfg, err = codegen.CheckUnsupported(cfg, s.Name(), codegen.UnsupportedFields(allStructs))
+	if err != nil {
+		return err
+	}
+	clones, err := codegen.NewCloneTypes(cfg.CloneFuncs, cfg.EqualFuncs)
+	if err != nil {
+		return err
+	}
+	for _, st := range allStructs {
+		clones.Apply(st)
+	}
+	data := templateData{
+		Package:      cfg.OutputPkg,
+		Structs:      allStructs,
+		MethodName:   cfg.Method(methodName),
+		ExplainDiff:  cfg.GenerateExplainDiff,
+		ConstantTime: cfg.ConstantTimeSecrets,
+		FloatEpsilon: cfg.FloatEpsilon,
+		UTC:          cfg.UTCTimes,
+		Style:        cfg.Project.Style,
+	}
+	if cfg.CrossPackage() {
+		if data.Source, data.Qualifier, err = crossPackage(cfg, allStructs); err != nil {
+			return err
+		}
+	}
+	return generateEqualsFile(cfg, data)
+}
+
+// nestedContainerReason returns why a field whose slice elements or map values are
+// slices or maps, such as [][]T, map[K][]T or []map[K]V, isn't compared: the
+// generated loops compare elements with == or their Equal method, which slices and
+// maps have neither of.
+func nestedContainerReason(f codegen.FieldInfo) string {
+	var elem ast.Expr
+	switch t := f.TypeExpr.(type) {
+	case *ast.ArrayType:
+		elem = t.Elt
+	case *ast.MapType:
+		elem = t.Value
+	default:
+		return ""
+	}
+	if star, ok := elem.(*ast.StarExpr); ok {
+		elem = star.X
+	}
+	switch elem.(type) {
+	case *ast.ArrayType, *ast.MapType:
+		return "equals doesn't compare slices or maps of slices or maps; declare a named element type with an Equal method"
+	}
+	return ""
+}
+
+// checkNested reports the fields of structs that nestedContainerReason skips, since
+// an Equal leaving them out would report values differing in them as equal.
+func checkNested(structs []*codegen.StructInfo) error {
+	var fields []string
+	for _, st := range structs {
+		for _, f := range st.Fields {
+			if nestedContainerReason(f) != "" {
+				fields = append(fields, fmt.Sprintf("%s: %s.%s", f.Pos, st.Name, f.Name))
+			}
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return errors.New("equals can't compare slices or maps of slices or maps; declare a named element type with an Equal method, exclude the fields with sudo-gen:\"-equals\" tags, or skip them with -skip-nested:\n\t" + strings.Join(fields, "\n\t"))
+}
+
+// checkEpsilons validates the tolerance of -float-epsilon and those of the fields
+// of structs tagged with EpsilonOption. Tagged fields of named types, like a Rate
+// defined over float64, are compared as floats rather than by Equal methods.
+func checkEpsilons(structs []*codegen.StructInfo, flag string) error {
+	if flag != "" {
+		if err := checkEpsilon(flag); err != nil {
+			return fmt.Errorf("-float-epsilon: %w", err)
+		}
+	}
+	for _, st := range structs {
+		for i, f := range st.Fields {
+			value, ok := f.TagOption(EpsilonOption)
+			if !ok {
+				continue
+			}
+			if err := checkEpsilon(value); err != nil {
+				return fmt.Errorf("field %s.%s: %w", st.Name, f.Name, err)
+			}
+			name := floatElem(f.TypeExpr)
+			isStruct := slices.ContainsFunc(structs, func(s *codegen.StructInfo) bool { return s.Name == name })
+			if !isFloat(name) && (name == "" || types.Universe.Lookup(name) != nil || isStruct) {
+				return fmt.Errorf("field %s.%s: %s:\"%s=...\" needs floats, not %s", st.Name, f.Name, codegen.OptionTagKey, EpsilonOption, f.Type)
+			}
+			st.Fields[i].IsStruct = false
+			st.Fields[i].StructTypeName = ""
+		}
+	}
+	return nil
+}
+
+// checkEpsilon returns an error if value isn't a tolerance: a non-negative number.
+func checkEpsilon(value string) error {
+	epsilon, err := strconv.ParseFloat(value, 64)
+	if err != nil || epsilon < 0 || math.IsInf(epsilon, 0) {
+		return fmt.Errorf("tolerance %q is not a non-negative number", value)
+	}
+	return nil
+}
+
+// floatElem returns the name of the type of the values a field of type expr compares:
+// its own, or that of the value it points to, its slice elements or its map values.
+// It returns "" for other types.
+func floatElem(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		expr = t.X
+	case *ast.ArrayType:
+		if t.Len != nil {
+			return ""
+		}
+		expr = t.Elt
+	case *ast.MapType:
+		expr = t.Value
+	}
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+func isFloat(name string) bool {
+	return name == "float32" || name == "float64"
+}
+
+// crossPackage returns the import and qualifier of the source package for functions
+// comparing structs from another package, or an error if they would need unexported
+// types or fields.
+func crossPackage(cfg codegen.GeneratorConfig, structs []*codegen.StructInfo) (codegen.ImportInfo, string, error) {
+	if cfg.GenerateExplainDiff || cfg.GenerateBench {
+		return codegen.ImportInfo{}, "", errors.New("-explain-diff and -bench generate methods, so they require generating into the source package")
+	}
+	var unreachable []string
+	for _, st := range structs {
+		if !ast.IsExported(st.Name) {
+			unreachable = append(unreachable, "type "+st.Name)
+		}
+		for _, f := range st.Fields {
+			if !ast.IsExported(f.Name) {
+				unreachable = append(unreachable, "field "+st.Name+"."+f.Name)
+			}
+		}
+	}
+	if len(unreachable) > 0 {
+		return codegen.ImportInfo{}, "", fmt.Errorf("package %s can't compare the unexported %s of package %s: export them, exclude the fields with %s:\"-equals\" tags, or generate into package %s",
+			cfg.OutputPkg, strings.Join(unreachable, ", "), cfg.SourcePkg, codegen.FieldTagKey, cfg.SourcePkg)
+	}
+	return cfg.SourceImport()
 }
 
-func generateEqualsFile(cfg codegen.GeneratorConfig, structs []*codegen.StructInfo, methodName string) error {
+func generateEqualsFile(cfg codegen.GeneratorConfig, data templateData) error {
+	structs := data.Structs
 	baseName := strings.TrimSuffix(cfg.SourceFile, ".go")
 	outputFile := filepath.Join(cfg.OutputDir, baseName+"_equals.go")
-	data := templateData{
-		Package:    cfg.OutputPkg,
-		Structs:    structs,
-		MethodName: methodName,
-	}
-	gen := codegen.NewTemplateGenerator(templateFuncs())
+	gen := codegen.NewTemplateGenerator(cfg, templateFuncs())
 	if err := gen.GenerateFile(outputFile, equalsTemplate, data); err != nil {
 		return err
 	}
+	if needsEqualAny(structs) {
+		if err := codegen.WriteHelpers(cfg, codegen.HelperEqualAny); err != nil {
+			return err
+		}
+	}
+	if data.comparesFloats() {
+		if err := codegen.WriteHelpers(cfg, codegen.HelperFloatEqual); err != nil {
+			return err
+		}
+	}
 	if cfg.GenerateTest {
 		testFile := filepath.Join(cfg.OutputDir, baseName+"_equals_test.go")
-		return gen.GenerateFile(testFile, equalsTestTemplate, data)
+		testTmpl := equalsTestTemplate
+		if data.Qualifier != "" {
+			testTmpl = equalsFuncsTestTemplate
+		}
+		if err := gen.GenerateFile(testFile, testTmpl, data); err != nil {
+			return err
+		}
+	}
+	if cfg.GenerateBench {
+		data.Sample = codegen.NewSampler(structs).Literal(structs[0].Name)
+		benchFile := filepath.Join(cfg.OutputDir, baseName+"_equals_bench_test.go")
+		return gen.GenerateFile(benchFile, equalsBenchTemplate, data)
 	}
 	return nil
 }
 
+// needsEqualAny reports whether a field of structs holds decoded values, compared
+// by codegen.HelperEqualAny.
+func needsEqualAny(structs []*codegen.StructInfo) bool {
+	for _, st := range structs {
+		for _, f := range st.Fields {
+			if f.TypeName == "map[string]any" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 type templateData struct {
-	Package    string
-	Structs    []*codegen.StructInfo
-	MethodName string
+	Package      string
+	Structs      []*codegen.StructInfo
+	MethodName   string
+	ExplainDiff  bool               // Also generate Diff and ExplainNotEqual
+	ConstantTime bool               // Compare secret string and []byte fields with crypto/subtle
+	FloatEpsilon string             // Tolerance of float comparisons from -float-epsilon; "" compares exactly
+	UTC          bool               // Compare the time.Time elements of slices and maps with Equal
+	Sample       string             // Literal of a populated root struct, for benchmarks
+	Qualifier    string             // Qualifier of the structs in another package, like "config."; functions replace methods
+	Source       codegen.ImportInfo // Import of the package of the structs, with Qualifier
+	Style        codegen.Style      // Value receivers of Equal methods, with Style.ValueEqual
+}
+
+// ValueReceiver reports whether the Equal methods take value receivers.
+func (d templateData) ValueReceiver() bool {
+	return d.Qualifier == "" && d.Style.ValueEqual()
+}
+
+// Call returns the comparison of recv and arg, a pointer to a typ: a call of the method,
+// or of the function replacing it in another package. recv is addressed for the
+// function if it is a struct rather than a pointer, and checked for nil before calling
+// a value receiver method if it is a pointer.
+func (d templateData) Call(typ, recv, arg string, addr bool) string {
+	if d.Qualifier == "" {
+		if addr {
+			return recv + "." + d.MethodName + "(" + arg + ")"
+		}
+		return d.Style.EqualCall(d.MethodName, recv, arg)
+	}
+	if addr {
+		recv = "&" + recv
+	}
+	return d.MethodName + typ + "(" + recv + ", " + arg + ")"
+}
+
+// Epsilon returns the tolerance within which the floats of f compare equal, from its
+// sudo:"epsilon=..." tag or -float-epsilon, or "" to compare them exactly.
+func (d templateData) Epsilon(f codegen.FieldInfo) string {
+	if value, ok := f.TagOption(EpsilonOption); ok {
+		return value
+	}
+	if d.FloatEpsilon != "" && isFloat(floatElem(f.TypeExpr)) {
+		return d.FloatEpsilon
+	}
+	return ""
+}
+
+// NotEqual returns the condition that a and b, values of f or of its elements, differ:
+// a != b, a comparison by codegen.HelperFloatEqual for floats with a tolerance, or by
+// time.Time.Equal for times with -utc.
+func (d templateData) NotEqual(f codegen.FieldInfo, a, b string) string {
+	if d.UTC && f.HoldsTime() {
+		return "!" + a + ".Equal(" + b + ")"
+	}
+	if epsilon := d.Epsilon(f); epsilon != "" {
+		return "!" + codegen.HelperFloatEqual + "(" + a + ", " + b + ", " + epsilon + ")"
+	}
+	return a + " != " + b
+}
+
+// comparesFloats reports whether a field of d.Structs is compared by
+// codegen.HelperFloatEqual.
+func (d templateData) comparesFloats() bool {
+	for _, st := range d.Structs {
+		for _, f := range st.Fields {
+			if d.Epsilon(f) != "" {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 func templateFuncs() template.FuncMap {
 	return template.FuncMap{
 		"isLocalStruct": isLocalStruct,
+		"lower":         strings.ToLower,
+		"isBytesLike":   isBytesLike,
 	}
 }
 
 func isLocalStruct(f codegen.FieldInfo) bool {
 	return f.IsStruct && f.TypePkg == "" && !f.IsSlice && !f.IsMap
 }
+
+// isBytesLike reports whether f is a string or []byte, which can be compared in
+// constant time.
+func isBytesLike(f codegen.FieldInfo) bool {
+	return f.Type == "string" || f.Type == "[]byte"
+}
@@ -3,23 +3,58 @@ package equals
 const equalsTemplate = `// Code generated by sudo-gen equals. DO NOT EDIT.
 
 package {{.Package}}
+{{- if .ExplainDiff}}
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+{{- end}}
+{{- if .Qualifier}}
+
+import (
+	{{if .Source.Alias}}{{.Source.Alias}} {{end}}"{{.Source.Path}}"
+)
+{{- end}}
 
 {{range .Structs}}
+{{if $.Qualifier -}}
+// {{$.MethodName}}{{.Name}} returns true if c and other have the same values.
+func {{$.MethodName}}{{.Name}}(c, other *{{$.Qualifier}}{{.Name}}) bool {
+{{- else -}}
 // {{$.MethodName}} returns true if c and other have the same values.
-func (c *{{.Name}}) {{$.MethodName}}(other *{{.Name}}) bool {
+func (c {{if not $.ValueReceiver}}*{{end}}{{.Name}}) {{$.MethodName}}(other *{{.Name}}) bool {
+{{- end}}
+{{- if $.ValueReceiver}}
+	if other == nil {
+		return false
+	}
+{{- else}}
 	if c == other {
 		return true
 	}
 	if c == nil || other == nil {
 		return false
 	}
+{{- end}}
 {{- range .Fields}}
-{{- if .IsPointer}}
+{{- if and $.ConstantTime .Secret (isBytesLike .)}}
+	if subtle.ConstantTimeCompare([]byte(c.{{.Name}}), []byte(other.{{.Name}})) != 1 {
+		return false
+	}
+{{- else if and .Recipe .Recipe.Equal}}
+	if {{template "recipeNotEqual" .}} {
+		return false
+	}
+{{- else if .IsPointer}}
 {{- if isLocalStruct .}}
-	if !c.{{.Name}}.{{$.MethodName}}(other.{{.Name}}) {
+	if !{{$.Call .TypeName (print "c." .Name) (print "other." .Name) false}} {
 		return false
 	}
-{{- else if eq .TypePkg "time"}}
+{{- else if and (eq .TypePkg "time") (eq .TypeName "Time")}}
 	if (c.{{.Name}} == nil) != (other.{{.Name}} == nil) {
 		return false
 	}
@@ -30,21 +65,25 @@ func (c *{{.Name}}) {{$.MethodName}}(other *{{.Name}}) bool {
 	if (c.{{.Name}} == nil) != (other.{{.Name}} == nil) {
 		return false
 	}
-	if c.{{.Name}} != nil && *c.{{.Name}} != *other.{{.Name}} {
+	if c.{{.Name}} != nil && {{$.NotEqual . (print "*c." .Name) (print "*other." .Name)}} {
 		return false
 	}
 {{- end}}
+{{- else if .IsBytes}}
+	if !bytes.Equal(c.{{.Name}}, other.{{.Name}}) {
+		return false
+	}
 {{- else if .IsSlice}}
 	if len(c.{{.Name}}) != len(other.{{.Name}}) {
 		return false
 	}
 	for i := range c.{{.Name}} {
 {{- if and .StructTypeName (eq .TypePkg "")}}
-		if !c.{{.Name}}[i].{{$.MethodName}}(&other.{{.Name}}[i]) {
+		if !{{$.Call .StructTypeName (print "c." .Name "[i]") (print "&other." .Name "[i]") true}} {
 			return false
 		}
 {{- else}}
-		if c.{{.Name}}[i] != other.{{.Name}}[i] {
+		if {{$.NotEqual . (print "c." .Name "[i]") (print "other." .Name "[i]")}} {
 			return false
 		}
 {{- end}}
@@ -59,114 +98,182 @@ func (c *{{.Name}}) {{$.MethodName}}(other *{{.Name}}) bool {
 			return false
 		}
 {{- if eq .TypeName "map[string]any"}}
-		if !equalAny(v, ov) {
+		if !sudogenEqualAny(v, ov) {
 			return false
 		}
 {{- else}}
-		if v != ov {
+		if {{$.NotEqual . "v" "ov"}} {
 			return false
 		}
 {{- end}}
 	}
 {{- else if isLocalStruct .}}
-	if !c.{{.Name}}.{{$.MethodName}}(&other.{{.Name}}) {
+	if !{{$.Call .TypeName (print "c." .Name) (print "&other." .Name) true}} {
 		return false
 	}
-{{- else if eq .TypePkg "time"}}
+{{- else if and (eq .TypePkg "time") (eq .TypeName "Time")}}
 	if !c.{{.Name}}.Equal(other.{{.Name}}) {
 		return false
 	}
 {{- else}}
-	if c.{{.Name}} != other.{{.Name}} {
+	if {{$.NotEqual . (print "c." .Name) (print "other." .Name)}} {
 		return false
 	}
 {{- end}}
 {{- end}}
 	return true
 }
-{{end}}
-{{- $needsEqualAny := false}}
-{{- range .Structs}}
-{{- range .Fields}}
-{{- if eq .TypeName "map[string]any"}}
-{{- $needsEqualAny = true}}
-{{- end}}
-{{- end}}
-{{- end}}
-{{- if $needsEqualAny}}
+{{- if $.ExplainDiff}}
 
-func equalAny(a, b any) bool {
-	if a == nil && b == nil {
-		return true
+// {{method "Diff"}} returns the dotted paths of the fields that differ between c and other, such
+// as "Database.Host", "Tags[2]" or ` + "`" + `Labels["env"]` + "`" + `, in field order. Slices of
+// different lengths are reported as a whole. Diff returns nil when c.{{$.MethodName}}(other),
+// and [""] when only one of them is nil.
+func (c *{{.Name}}) {{method "Diff"}}(other *{{.Name}}) []string {
+	var paths []string
+	c.diff(other, "", func(path string, a, b any) {
+		paths = append(paths, path)
+	})
+	return paths
+}
+
+// {{method "ExplainNotEqual"}} describes each difference between c and other on a line of its own,
+// such as Database.Host: "a" != "b", for test failure messages. It returns "" when
+// c.{{$.MethodName}}(other).
+func (c *{{.Name}}) {{method "ExplainNotEqual"}}(other *{{.Name}}) string {
+	var lines []string
+	c.diff(other, "", func(path string, a, b any) {
+		lines = append(lines, fmt.Sprintf("%s: %#v != %#v", cmp.Or(path, "{{.Name}}"), a, b))
+	})
+	return strings.Join(lines, "\n")
+}
+
+// diff calls report with the path under prefix and both values of each field that
+// differs between c and other.
+func (c *{{.Name}}) diff(other *{{.Name}}, prefix string, report func(path string, a, b any)) {
+	if c == other {
+		return
 	}
-	if a == nil || b == nil {
-		return false
+	if c == nil || other == nil {
+		report(strings.TrimSuffix(prefix, "."), c, other)
+		return
 	}
-	switch av := a.(type) {
-	case map[string]any:
-		bv, ok := b.(map[string]any)
-		if !ok || len(av) != len(bv) {
-			return false
+{{- range .Fields}}
+{{- if .Secret}}
+	{
+		// Secret values are reported as redactedSecret
+		report := func(path string, a, b any) {
+			report(path, redactedSecret{}, redactedSecret{})
 		}
-		for k, v := range av {
-			if ov, ok := bv[k]; !ok || !equalAny(v, ov) {
-				return false
-			}
+{{- end}}
+{{- if and .Recipe .Recipe.Equal}}
+	if {{template "recipeNotEqual" .}} {
+		report(prefix+"{{.Name}}", c.{{.Name}}, other.{{.Name}})
+	}
+{{- else if .IsPointer}}
+{{- if isLocalStruct .}}
+	c.{{.Name}}.diff(other.{{.Name}}, prefix+"{{.Name}}.", report)
+{{- else}}
+	if a, b := c.{{.Name}}, other.{{.Name}}; (a == nil) != (b == nil) || a != nil && {{if and (eq .TypePkg "time") (eq .TypeName "Time")}}!a.Equal(*b){{else}}{{$.NotEqual . "*a" "*b"}}{{end}} {
+		var av, bv any
+		if a != nil {
+			av = *a
 		}
-		return true
-	case []any:
-		bv, ok := b.([]any)
-		if !ok || len(av) != len(bv) {
-			return false
+		if b != nil {
+			bv = *b
 		}
-		for i := range av {
-			if !equalAny(av[i], bv[i]) {
-				return false
+		report(prefix+"{{.Name}}", av, bv)
+	}
+{{- end}}
+{{- else if .IsBytes}}
+	if !bytes.Equal(c.{{.Name}}, other.{{.Name}}) {
+		report(prefix+"{{.Name}}", c.{{.Name}}, other.{{.Name}})
+	}
+{{- else if .IsSlice}}
+	if len(c.{{.Name}}) != len(other.{{.Name}}) {
+		report(prefix+"{{.Name}}", c.{{.Name}}, other.{{.Name}})
+	} else {
+		for i := range c.{{.Name}} {
+{{- if and .StructTypeName (eq .TypePkg "")}}
+			c.{{.Name}}[i].diff(&other.{{.Name}}[i], prefix+"{{.Name}}["+strconv.Itoa(i)+"].", report)
+{{- else}}
+			if {{$.NotEqual . (print "c." .Name "[i]") (print "other." .Name "[i]")}} {
+				report(prefix+"{{.Name}}["+strconv.Itoa(i)+"]", c.{{.Name}}[i], other.{{.Name}}[i])
 			}
+{{- end}}
 		}
-		return true
-	case []string:
-		bv, ok := b.([]string)
-		if !ok || len(av) != len(bv) {
-			return false
-		}
-		for i := range av {
-			if av[i] != bv[i] {
-				return false
+	}
+{{- else if .IsMap}}
+	{
+		var keys []{{.MapKeyType}}
+		for k, v := range c.{{.Name}} {
+			if ov, ok := other.{{.Name}}[k]; !ok || {{if eq .TypeName "map[string]any"}}!sudogenEqualAny(v, ov){{else}}{{$.NotEqual . "v" "ov"}}{{end}} {
+				keys = append(keys, k)
 			}
 		}
-		return true
-	case []int:
-		bv, ok := b.([]int)
-		if !ok || len(av) != len(bv) {
-			return false
+		for k := range other.{{.Name}} {
+			if _, ok := c.{{.Name}}[k]; !ok {
+				keys = append(keys, k)
+			}
 		}
-		for i := range av {
-			if av[i] != bv[i] {
-				return false
+		slices.SortFunc(keys, func(a, b {{.MapKeyType}}) int {
+			return cmp.Compare(fmt.Sprintf("%#v", a), fmt.Sprintf("%#v", b))
+		})
+		for _, k := range keys {
+			var a, b any
+			if v, ok := c.{{.Name}}[k]; ok {
+				a = v
+			}
+			if v, ok := other.{{.Name}}[k]; ok {
+				b = v
 			}
+			report(fmt.Sprintf("%s{{.Name}}[%#v]", prefix, k), a, b)
 		}
-		return true
-	case string:
-		bv, ok := b.(string)
-		return ok && av == bv
-	case int:
-		bv, ok := b.(int)
-		return ok && av == bv
-	case int64:
-		bv, ok := b.(int64)
-		return ok && av == bv
-	case float64:
-		bv, ok := b.(float64)
-		return ok && av == bv
-	case bool:
-		bv, ok := b.(bool)
-		return ok && av == bv
-	default:
-		return a == b
 	}
+{{- else if isLocalStruct .}}
+	c.{{.Name}}.diff(&other.{{.Name}}, prefix+"{{.Name}}.", report)
+{{- else if and (eq .TypePkg "time") (eq .TypeName "Time")}}
+	if !c.{{.Name}}.Equal(other.{{.Name}}) {
+		report(prefix+"{{.Name}}", c.{{.Name}}, other.{{.Name}})
+	}
+{{- else}}
+	if {{$.NotEqual . (print "c." .Name) (print "other." .Name)}} {
+		report(prefix+"{{.Name}}", c.{{.Name}}, other.{{.Name}})
+	}
+{{- end}}
+{{- if .Secret}}
+	}
+{{- end}}
+{{- end}}
 }
 {{- end}}
+{{end}}
+{{- $needsRedacted := false}}
+{{- if .ExplainDiff}}
+{{- range .Structs}}
+{{- range .Fields}}
+{{- if .Secret}}
+{{- $needsRedacted = true}}
+{{- end}}
+{{- end}}
+{{- end}}
+{{- end}}
+{{- if $needsRedacted}}
+
+// redactedSecret stands in for the values of fields tagged sudo:"secret" in
+// ExplainNotEqual, so that credentials don't end up in test logs.
+type redactedSecret struct{}
+
+// GoString implements fmt.GoStringer.
+func (redactedSecret) GoString() string { return "<redacted>" }
+{{- end}}
+{{- define "recipeNotEqual"}}
+{{- if .Recipe.Nilable -}}
+(c.{{.Name}} == nil) != (other.{{.Name}} == nil) || c.{{.Name}} != nil && !({{.Recipe.EqualOf (print "c." .Name) (print "other." .Name)}})
+{{- else -}}
+!({{.Recipe.EqualOf (print "c." .Name) (print "other." .Name)}})
+{{- end}}
+{{- end}}
 `
 
 const equalsTestTemplate = `// Code generated by sudo-gen equals. DO NOT EDIT.
@@ -177,22 +284,25 @@ import (
 	"testing"
 )
 {{range .Structs}}
+{{- if not $.ValueReceiver}}
 func Test{{.Name}}{{$.MethodName}}BothNil(t *testing.T) {
 	var a, b *{{.Name}}
 	if !a.{{$.MethodName}}(b) {
 		t.Error("two nil pointers should be equal")
 	}
 }
-
+{{end}}
 func Test{{.Name}}{{$.MethodName}}OneNil(t *testing.T) {
 	a := &{{.Name}}{}
 	var b *{{.Name}}
 	if a.{{$.MethodName}}(b) {
 		t.Error("non-nil should not equal nil")
 	}
+{{- if not $.ValueReceiver}}
 	if b.{{$.MethodName}}(a) {
 		t.Error("nil should not equal non-nil")
 	}
+{{- end}}
 }
 
 func Test{{.Name}}{{$.MethodName}}SamePointer(t *testing.T) {
@@ -209,5 +319,178 @@ func Test{{.Name}}{{$.MethodName}}EmptyStructs(t *testing.T) {
 		t.Error("two empty structs should be equal")
 	}
 }
+{{- $s := .}}
+{{- range .Fields}}
+{{- if and .IsBytes (not .IsPointer)}}
+
+func Test{{$s.Name}}{{$.MethodName}}{{.Name}}Bytes(t *testing.T) {
+	a := &{{$s.Name}}{ {{.Name}}: {{.Type}}("abc")}
+	b := &{{$s.Name}}{ {{.Name}}: {{.Type}}("abc")}
+	if !a.{{$.MethodName}}(b) {
+		t.Error("same bytes should be equal")
+	}
+	b.{{.Name}}[0] = 'x'
+	if a.{{$.MethodName}}(b) {
+		t.Error("different bytes should not be equal")
+	}
+	a.{{.Name}}, b.{{.Name}} = nil, {{.Type}}{}
+	if !a.{{$.MethodName}}(b) {
+		t.Error("nil and empty bytes should be equal")
+	}
+}
+{{- end}}
+{{- end}}
+{{- if $.ExplainDiff}}
+
+func Test{{.Name}}DiffEqual(t *testing.T) {
+	a := &{{.Name}}{}
+	if diff := a.{{method "Diff"}}(&{{.Name}}{}); diff != nil {
+		t.Errorf("expected no differences, got %v", diff)
+	}
+	if got := a.{{method "ExplainNotEqual"}}(&{{.Name}}{}); got != "" {
+		t.Errorf("expected no explanation, got %q", got)
+	}
+	var b *{{.Name}}
+	if diff := a.{{method "Diff"}}(b); len(diff) != 1 || diff[0] != "" {
+		t.Errorf("expected the whole value to differ from nil, got %q", diff)
+	}
+}
+{{- $struct := .}}
+{{- range .Fields}}
+{{- if and (eq .TypeName "string") (eq .TypePkg "") (not .IsPointer) (not .IsSlice) (not .IsMap) (not .Secret)}}
+
+func Test{{$struct.Name}}Diff{{.Name}}(t *testing.T) {
+	a := &{{$struct.Name}}{ {{.Name}}: "a"}
+	b := &{{$struct.Name}}{ {{.Name}}: "b"}
+	if diff := a.{{method "Diff"}}(b); len(diff) != 1 || diff[0] != "{{.Name}}" {
+		t.Errorf("expected {{.Name}} to differ, got %q", diff)
+	}
+	if got, want := a.{{method "ExplainNotEqual"}}(b), ` + "`" + `{{.Name}}: "a" != "b"` + "`" + `; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+{{- break}}
+{{- end}}
+{{- end}}
+{{- range .Fields}}
+{{- if and .Secret (eq .Type "string")}}
+
+func Test{{$struct.Name}}Diff{{.Name}}Redacted(t *testing.T) {
+	a := &{{$struct.Name}}{ {{.Name}}: "a"}
+	b := &{{$struct.Name}}{ {{.Name}}: "b"}
+	if got, want := a.{{method "ExplainNotEqual"}}(b), "{{.Name}}: <redacted> != <redacted>"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+{{- break}}
+{{- end}}
+{{- end}}
+{{- end}}
+{{- $struct := .}}
+{{- range .Fields}}
+{{- if and $.UTC .IsSlice .HoldsTime}}
+
+func Test{{$struct.Name}}{{$.MethodName}}{{.Name}}UTC(t *testing.T) {
+	local := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("UTC+2", 2*60*60))
+	a := &{{$struct.Name}}{ {{.Name}}: {{.Type}}{local}}
+	if !a.{{$.MethodName}}(&{{$struct.Name}}{ {{.Name}}: {{.Type}}{local.UTC()}}) {
+		t.Error("{{.Name}} holding the same instants in different zones should compare equal")
+	}
+}
+{{- break}}
+{{- end}}
+{{- end}}
+{{- range .Fields}}
+{{- if and ($.Epsilon .) (not .IsPointer) (not .IsSlice) (not .IsMap)}}
+
+func Test{{$struct.Name}}{{$.MethodName}}{{.Name}}Epsilon(t *testing.T) {
+	a := &{{$struct.Name}}{ {{.Name}}: 1}
+	if !a.{{$.MethodName}}(&{{$struct.Name}}{ {{.Name}}: 1 + {{$.Epsilon .}}/2}) {
+		t.Error("{{.Name}} within {{$.Epsilon .}} should compare equal")
+	}
+	if a.{{$.MethodName}}(&{{$struct.Name}}{ {{.Name}}: 2 + 2*{{$.Epsilon .}}}) {
+		t.Error("{{.Name}} farther apart than {{$.Epsilon .}} should not compare equal")
+	}
+	a.{{.Name}} = {{.Type}}(math.NaN())
+	if !a.{{$.MethodName}}(&{{$struct.Name}}{ {{.Name}}: {{.Type}}(math.NaN())}) {
+		t.Error("NaN {{.Name}} should compare equal")
+	}
+}
+{{- end}}
+{{- end}}
+{{- range .Fields}}
+{{- if and .Secret (eq .Type "string")}}
+
+func Test{{$struct.Name}}{{$.MethodName}}Secret{{.Name}}(t *testing.T) {
+	a := &{{$struct.Name}}{ {{.Name}}: "secret"}
+	if !a.{{$.MethodName}}(&{{$struct.Name}}{ {{.Name}}: "secret"}) {
+		t.Error("equal secrets should compare equal")
+	}
+	if a.{{$.MethodName}}(&{{$struct.Name}}{ {{.Name}}: "secreT"}) {
+		t.Error("different secrets should not compare equal")
+	}
+}
+{{- break}}
+{{- end}}
+{{- end}}
+{{end}}
+`
+
+const equalsBenchTemplate = `// Code generated by sudo-gen equals. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"testing"
+)
+{{- with index .Structs 0}}
+
+// {{lower .Name}}{{$.MethodName}}BenchValue returns a {{.Name}} with every field set, for
+// benchmarks.
+func {{lower .Name}}{{$.MethodName}}BenchValue() *{{.Name}} {
+	return &{{$.Sample}}
+}
+
+// Benchmark{{.Name}}{{$.MethodName}} compares two equal values, so every field is compared.
+func Benchmark{{.Name}}{{$.MethodName}}(b *testing.B) {
+	x, y := {{lower .Name}}{{$.MethodName}}BenchValue(), {{lower .Name}}{{$.MethodName}}BenchValue()
+	if !x.{{$.MethodName}}(y) {
+		b.Fatal("expected the values to be equal")
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = x.{{$.MethodName}}(y)
+	}
+}
+{{- end}}
+`
+
+const equalsFuncsTestTemplate = `// Code generated by sudo-gen equals. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"testing"
+
+	{{if .Source.Alias}}{{.Source.Alias}} {{end}}"{{.Source.Path}}"
+)
+{{range .Structs}}
+func Test{{$.MethodName}}{{.Name}}Nil(t *testing.T) {
+	if !{{$.MethodName}}{{.Name}}(nil, nil) {
+		t.Error("two nil pointers should be equal")
+	}
+	if {{$.MethodName}}{{.Name}}(&{{$.Qualifier}}{{.Name}}{}, nil) {
+		t.Error("non-nil should not equal nil")
+	}
+	if {{$.MethodName}}{{.Name}}(nil, &{{$.Qualifier}}{{.Name}}{}) {
+		t.Error("nil should not equal non-nil")
+	}
+}
+
+func Test{{$.MethodName}}{{.Name}}EmptyStructs(t *testing.T) {
+	if !{{$.MethodName}}{{.Name}}(&{{$.Qualifier}}{{.Name}}{}, &{{$.Qualifier}}{{.Name}}{}) {
+		t.Error("two empty structs should be equal")
+	}
+}
 {{end}}
 `
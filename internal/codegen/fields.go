@@ -0,0 +1,82 @@
+package codegen
+
+import (
+	"reflect"
+	"strings"
+)
+
+// FieldTagKey is the struct tag key that excludes fields from generators. The
+// value is a comma-separated list of "-" (exclude from every generator) or
+// "-<generator>" entries, e.g. `sudo-gen:"-merge,-equals"`.
+const FieldTagKey = "sudo-gen"
+
+// FieldSelection decides which struct fields a generator operates on, combining
+// the -fields and -exclude-fields flags with sudo-gen struct tags.
+type FieldSelection struct {
+	generator string
+	root      string
+	include   map[string]map[string]bool // Type name -> allowed field names
+	exclude   map[string]map[string]bool // Type name -> excluded field names
+}
+
+// NewFieldSelection returns the field selection for the named generator. Entries in
+// cfg.Fields and cfg.ExcludeFields are either a field of the root type ("Port") or
+// a type-qualified field of a nested type ("DatabaseConfig.Password").
+func NewFieldSelection(cfg GeneratorConfig, generator string) *FieldSelection {
+	return &FieldSelection{
+		generator: generator,
+		root:      cfg.TypeName,
+		include:   groupFields(cfg.TypeName, cfg.Fields),
+		exclude:   groupFields(cfg.TypeName, cfg.ExcludeFields),
+	}
+}
+
+func groupFields(root string, names []string) map[string]map[string]bool {
+	groups := make(map[string]map[string]bool)
+	for _, name := range names {
+		typeName, field, ok := strings.Cut(name, ".")
+		if !ok {
+			typeName, field = root, name
+		}
+		if groups[typeName] == nil {
+			groups[typeName] = make(map[string]bool)
+		}
+		groups[typeName][field] = true
+	}
+	return groups
+}
+
+// Includes reports whether the field of typeName with the given name and tag is selected.
+func (s *FieldSelection) Includes(typeName, field string, tag reflect.StructTag) bool {
+	if include, ok := s.include[typeName]; ok && !include[field] {
+		return false
+	}
+	if s.exclude[typeName][field] {
+		return false
+	}
+	for _, entry := range strings.Split(tag.Get(FieldTagKey), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "-" || entry == "-"+s.generator {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply removes the fields of info that are not selected, supported or not.
+func (s *FieldSelection) Apply(info *StructInfo) {
+	fields := info.Fields[:0]
+	for _, f := range info.Fields {
+		if s.Includes(info.Name, f.Name, f.StructTag()) {
+			fields = append(fields, f)
+		}
+	}
+	info.Fields = fields
+	unsupported := info.Unsupported[:0]
+	for _, u := range info.Unsupported {
+		if s.Includes(info.Name, u.Field.Name, u.Field.StructTag()) {
+			unsupported = append(unsupported, u)
+		}
+	}
+	info.Unsupported = unsupported
+}
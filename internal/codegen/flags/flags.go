@@ -0,0 +1,261 @@
+// Package flags implements the flags code generation subtool.
+package flags
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/bobcob7/sudo-gen/internal/codegen"
+)
+
+// Subtool implements the flags code generator.
+type Subtool struct{}
+
+// Name returns the subtool name.
+func (s *Subtool) Name() string { return "flags" }
+
+// Description returns the subtool description.
+func (s *Subtool) Description() string {
+	return "Generate a feature-flag overlay for fields tagged sudo:\"flag=key\""
+}
+
+// Doc describes the struct tags the subtool honors and the files it generates.
+func (s *Subtool) Doc() codegen.SubtoolDoc {
+	return codegen.SubtoolDoc{
+		Tags: []codegen.DocEntry{
+			{Name: `sudo:"flag=key"`, Description: "Feature flag overriding the field"},
+			codegen.ExcludeTagDoc(s.Name()),
+		},
+		Files: []codegen.DocEntry{
+			{Name: "{source}_flags.go", Description: "{Type}FlagOverrides and Apply{Type}Flags top-layer overlay"},
+		},
+	}
+}
+
+// Run executes the flags code generation. The generated overlay builds on the
+// layerbroker output for the same type.
+func (s *Subtool) Run(cfg codegen.GeneratorConfig) error {
+	info, err := cfg.Index.ParseStruct(cfg.SourceDir, cfg.SourceFile, cfg.Source, cfg.TypeName)
+	if err != nil {
+		return fmt.Errorf("parsing struct: %w", err)
+	}
+	// Overrides are delivered as partials, so only fields the merge generator keeps apply
+	selection := codegen.NewFieldSelection(cfg, "merge")
+	selection.Apply(info)
+	values := codegen.NewValueTypes(cfg.Index, cfg.SourceDir, cfg.ValueTypes)
+	values.Apply(info)
+	nested, err := cfg.Index.FindNestedStructs(cfg.SourceDir, cfg.Source, info, values)
+	if err != nil {
+		return fmt.Errorf("finding nested structs: %w", err)
+	}
+	local := make(map[string]*codegen.StructInfo)
+	for _, st := range nested {
+		if st.Package == "" {
+			selection.Apply(st)
+			values.Apply(st)
+			local[st.Name] = st
+		}
+	}
+	c := &collector{index: cfg.Index, dir: cfg.SourceDir, partial: cfg.Partial, local: local, seen: map[string]bool{info.Name: true}}
+	if err := c.collect(info, nil, ""); err != nil {
+		return err
+	}
+	if len(c.flags) == 0 {
+		return fmt.Errorf("%s has no fields tagged %s:\"flag=<key>\"", info.Name, codegen.OptionTagKey)
+	}
+	data := templateData{
+		Package:  cfg.OutputPkg,
+		TypeName: info.Name,
+		Flags:    c.flags,
+		Imports:  c.imports,
+	}
+	for _, f := range c.flags {
+		data.NeedsFmt = data.NeedsFmt || f.Duration || f.Set
+	}
+	baseName := strings.TrimSuffix(cfg.SourceFile, ".go")
+	gen := codegen.NewTemplateGenerator(cfg, templateFuncs())
+	if err := gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_flags.go"), flagsTemplate, data); err != nil {
+		return err
+	}
+	if cfg.GenerateTest {
+		return gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_flags_test.go"), flagsTestTemplate, data)
+	}
+	return nil
+}
+
+type templateData struct {
+	Package  string
+	TypeName string
+	Flags    []flagField
+	NeedsFmt bool
+	Imports  []codegen.ImportInfo // Packages of the types of Set flags
+}
+
+// flagField is a config field overridden by a feature flag.
+type flagField struct {
+	Name     string       // Field of the overrides struct, e.g. DatabasePort
+	Key      string       // Flag key
+	Type     string       // Go type of the field, without pointer
+	Target   string       // Partial field assigned, e.g. p.Database.Port
+	Parents  []partialRef // Nested partials allocated before Target is assigned
+	Method   string       // Provider method looking the flag up
+	Convert  bool         // The provider value must be converted to Type
+	Duration bool         // Parsed from a string flag with time.ParseDuration
+	Set      bool         // Parsed from a string flag with the Set method of Type
+	Value    string       // Provider value used by generated tests; empty leaves the flag out of them
+	Other    string       // A different provider value used by generated tests
+}
+
+// partialRef is a nested partial on the way to a flag field.
+type partialRef struct {
+	Expr string // e.g. p.Database
+	Type string // e.g. DatabaseConfigPartial
+}
+
+type collector struct {
+	index   *codegen.PackageIndex
+	dir     string
+	partial func(name string) string // Names the partial types of structs (see codegen.GeneratorConfig.Partial)
+	local   map[string]*codegen.StructInfo
+	seen    map[string]bool // Struct types on the current path, to stop at recursive types
+	flags   []flagField
+	imports []codegen.ImportInfo
+}
+
+// collect adds the flag fields of st, whose partial is reached through parents.
+func (c *collector) collect(st *codegen.StructInfo, parents []partialRef, prefix string) error {
+	expr := "p"
+	if len(parents) > 0 {
+		expr = parents[len(parents)-1].Expr
+	}
+	for _, f := range st.Fields {
+		if key, ok := flagKey(f); ok {
+			ff, err := c.newFlagField(st, f, key)
+			if err != nil {
+				return fmt.Errorf("%s.%s: %w", st.Name, f.Name, err)
+			}
+			ff.Name = prefix + f.Name
+			ff.Target = expr + "." + f.Name
+			ff.Parents = parents
+			for _, existing := range c.flags {
+				if existing.Name == ff.Name {
+					return fmt.Errorf("%s.%s: override field %s is generated twice", st.Name, f.Name, ff.Name)
+				}
+			}
+			c.flags = append(c.flags, ff)
+			continue
+		}
+		nested, ok := c.local[f.StructTypeName]
+		if !ok || f.TypePkg != "" || f.IsSlice || f.IsMap || f.IsValue || c.seen[nested.Name] {
+			continue
+		}
+		c.seen[nested.Name] = true
+		ref := partialRef{Expr: expr + "." + f.Name, Type: c.partial(nested.Name)}
+		err := c.collect(nested, append(parents[:len(parents):len(parents)], ref), prefix+f.Name)
+		delete(c.seen, nested.Name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flagKey returns the flag key from a `sudo:"flag=key"` tag on f.
+func flagKey(f codegen.FieldInfo) (string, bool) {
+	key, ok := f.TagOption("flag")
+	return key, ok && key != ""
+}
+
+// newFlagField describes how the flag for f, a field of st, is looked up. Fields of
+// types with a Set(string) error method, like the flag.Value types that sudo-gen
+// flagvalue generates, are parsed from string flags.
+func (c *collector) newFlagField(st *codegen.StructInfo, f codegen.FieldInfo, key string) (flagField, error) {
+	ff, err := newFlagField(f, key)
+	if err == nil || f.IsSlice || f.IsMap {
+		return ff, err
+	}
+	dir := codegen.TypeDir(c.dir, st.Imports, f.TypePkg)
+	if dir == "" || c.index.Methods(dir, f.TypeName)["Set"] == "" {
+		return ff, err
+	}
+	ff = flagField{Key: key, Type: strings.TrimPrefix(f.Type, "*"), Method: "StringFlag", Set: true}
+	for _, imp := range codegen.CollectRequiredImports([]codegen.FieldInfo{f}, st.Imports) {
+		if !slices.Contains(c.imports, imp) {
+			c.imports = append(c.imports, imp)
+		}
+	}
+	if scalar, ok := c.index.ScalarType(dir, f.TypeName); ok {
+		value, other := scalar.Samples()
+		ff.Value, ff.Other = strconv.Quote(value), strconv.Quote(other)
+	}
+	return ff, nil
+}
+
+// newFlagField describes how the flag for f is looked up.
+func newFlagField(f codegen.FieldInfo, key string) (flagField, error) {
+	ff := flagField{Key: key, Type: f.TypeName}
+	if f.IsSlice || f.IsMap || f.IsStruct && !isDuration(f) {
+		return ff, errUnsupported(f)
+	}
+	switch {
+	case isDuration(f):
+		ff.Type = f.TypePkg + "." + f.TypeName
+		ff.Method, ff.Duration = "StringFlag", true
+		ff.Value, ff.Other = `"90s"`, `"5s"`
+		return ff, nil
+	case f.TypePkg != "":
+		return ff, errUnsupported(f)
+	}
+	switch f.TypeName {
+	case "bool":
+		ff.Method, ff.Value, ff.Other = "BoolFlag", "true", "false"
+	case "string":
+		ff.Method, ff.Value, ff.Other = "StringFlag", `"flag"`, `"layer"`
+	case "float64":
+		ff.Method, ff.Value, ff.Other = "FloatFlag", "1.5", "2.5"
+	case "float32":
+		ff.Method, ff.Value, ff.Other, ff.Convert = "FloatFlag", "1.5", "2.5", true
+	case "int64":
+		ff.Method, ff.Value, ff.Other = "IntFlag", "int64(42)", "int64(7)"
+	case "int", "int8", "int16", "int32", "uint", "uint8", "uint16", "uint32", "uint64":
+		ff.Method, ff.Value, ff.Other, ff.Convert = "IntFlag", "int64(42)", "int64(7)", true
+	default:
+		return ff, errUnsupported(f)
+	}
+	return ff, nil
+}
+
+func isDuration(f codegen.FieldInfo) bool {
+	return f.TypePkg == "time" && f.TypeName == "Duration" && !f.IsSlice && !f.IsMap
+}
+
+func errUnsupported(f codegen.FieldInfo) error {
+	return errors.New("flags can only override bool, string, numeric and time.Duration fields and fields of types with a Set(string) error method, not " + f.Type)
+}
+
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"lower":      strings.ToLower,
+		"capitalize": capitalize,
+		"ident":      ident,
+	}
+}
+
+func capitalize(name string) string {
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// ident builds a generated function name such as ApplyConfigFlags, keeping it
+// unexported (applyConfigFlags) when the type is unexported.
+func ident(verb, typeName, suffix string) string {
+	if ast.IsExported(typeName) {
+		return capitalize(verb) + typeName + suffix
+	}
+	return verb + capitalize(typeName) + suffix
+}
@@ -0,0 +1,220 @@
+package flags
+
+const flagsTemplate = `// Code generated by sudo-gen flags. DO NOT EDIT.
+
+package {{.Package}}
+
+{{- if .NeedsFmt}}
+
+import (
+	"fmt"
+	"time"
+{{- if .Imports}}
+{{range .Imports}}
+	{{if .Alias}}{{.Alias}} {{end}}"{{.Path}}"
+{{- end}}
+{{- end}}
+)
+{{- end}}
+
+// {{.TypeName}}FlagProvider looks up feature flag values by key, in the style of
+// LaunchDarkly or OpenFeature clients. Each method reports false when the flag has no
+// value for the current context, leaving the field it overrides unchanged.
+type {{.TypeName}}FlagProvider interface {
+	BoolFlag(key string) (bool, bool)
+	IntFlag(key string) (int64, bool)
+	FloatFlag(key string) (float64, bool)
+	StringFlag(key string) (string, bool)
+}
+
+// {{.TypeName}}FlagOverrides holds the {{.TypeName}} fields overridden by feature flags.
+// A nil field has no flag value.
+type {{.TypeName}}FlagOverrides struct {
+{{- range .Flags}}
+	{{.Name}} *{{.Type}} // {{.Key}}
+{{- end}}
+}
+
+// {{ident "lookup" .TypeName "Flags"}} reads every flag bound to a {{.TypeName}} field from provider.
+func {{ident "lookup" .TypeName "Flags"}}(provider {{.TypeName}}FlagProvider) (*{{.TypeName}}FlagOverrides, error) {
+	o := &{{.TypeName}}FlagOverrides{}
+{{- range .Flags}}
+	if v, ok := provider.{{.Method}}("{{.Key}}"); ok {
+{{- if .Duration}}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("flag %q: %w", "{{.Key}}", err)
+		}
+		o.{{.Name}} = &d
+{{- else if .Set}}
+		var value {{.Type}}
+		if err := value.Set(v); err != nil {
+			return nil, fmt.Errorf("flag %q: %w", "{{.Key}}", err)
+		}
+		o.{{.Name}} = &value
+{{- else if .Convert}}
+		value := {{.Type}}(v)
+		o.{{.Name}} = &value
+{{- else}}
+		o.{{.Name}} = &v
+{{- end}}
+	}
+{{- end}}
+	return o, nil
+}
+
+// Partial returns the overrides as a partial holding only the flags that have values.
+func (o *{{.TypeName}}FlagOverrides) Partial() *{{partial .TypeName}} {
+	p := &{{partial .TypeName}}{}
+{{- range .Flags}}
+	if o.{{.Name}} != nil {
+{{- range .Parents}}
+		if {{.Expr}} == nil {
+			{{.Expr}} = &{{.Type}}{}
+		}
+{{- end}}
+		{{.Target}} = o.{{.Name}}
+	}
+{{- end}}
+	return p
+}
+
+// {{.TypeName}}FlagLayer keeps a top broker layer in sync with a flag provider.
+type {{.TypeName}}FlagLayer struct {
+	// Layer holds the overrides. It is a top layer, so the flags take priority over
+	// every other layer of the broker.
+	Layer    *{{.TypeName}}Layer
+	provider {{.TypeName}}FlagProvider
+}
+
+// {{ident "apply" .TypeName "Flags"}} overlays the flags from provider on broker as a new top layer.
+// Call Refresh on the result whenever the provider reports a flag change.
+func {{ident "apply" .TypeName "Flags"}}(broker *{{.TypeName}}LayerBroker, provider {{.TypeName}}FlagProvider) (*{{.TypeName}}FlagLayer, error) {
+	o, err := {{ident "lookup" .TypeName "Flags"}}(provider)
+	if err != nil {
+		return nil, err
+	}
+	l := &{{.TypeName}}FlagLayer{Layer: broker.TopLayer(), provider: provider}
+	if err := l.Layer.Replace(o.Partial()); err != nil {
+		l.Layer.Remove()
+		return nil, err
+	}
+	return l, nil
+}
+
+// Refresh re-reads the flags and replaces the layer with them, so flags that no longer
+// have a value stop overriding their fields. The layer is unchanged if a flag is invalid
+// or the broker's validator rejects the result.
+func (l *{{.TypeName}}FlagLayer) Refresh() error {
+	o, err := {{ident "lookup" .TypeName "Flags"}}(l.provider)
+	if err != nil {
+		return err
+	}
+	return l.Layer.Replace(o.Partial())
+}
+`
+
+const flagsTestTemplate = `// Code generated by sudo-gen flags. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"testing"
+)
+
+// {{lower .TypeName}}TestFlags is a {{.TypeName}}FlagProvider backed by a map of flag values.
+type {{lower .TypeName}}TestFlags map[string]any
+
+func (f {{lower .TypeName}}TestFlags) BoolFlag(key string) (bool, bool) {
+	v, ok := f[key].(bool)
+	return v, ok
+}
+
+func (f {{lower .TypeName}}TestFlags) IntFlag(key string) (int64, bool) {
+	v, ok := f[key].(int64)
+	return v, ok
+}
+
+func (f {{lower .TypeName}}TestFlags) FloatFlag(key string) (float64, bool) {
+	v, ok := f[key].(float64)
+	return v, ok
+}
+
+func (f {{lower .TypeName}}TestFlags) StringFlag(key string) (string, bool) {
+	v, ok := f[key].(string)
+	return v, ok
+}
+
+// {{lower .TypeName}}FlagConfig returns the config holding just the given overrides.
+func {{lower .TypeName}}FlagConfig(t *testing.T, flags {{lower .TypeName}}TestFlags) *{{.TypeName}} {
+	t.Helper()
+	o, err := {{ident "lookup" .TypeName "Flags"}}(flags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &{{.TypeName}}{}
+	c.{{method "ApplyPartial"}}(o.Partial())
+	return c
+}
+
+func Test{{capitalize (ident "lookup" .TypeName "Flags")}}Unset(t *testing.T) {
+	o, err := {{ident "lookup" .TypeName "Flags"}}({{lower .TypeName}}TestFlags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *o != ({{.TypeName}}FlagOverrides{}) {
+		t.Errorf("expected no overrides, got %+v", o)
+	}
+}
+
+func Test{{capitalize (ident "apply" .TypeName "Flags")}}(t *testing.T) {
+	flags := {{lower .TypeName}}TestFlags{
+{{- range .Flags}}
+{{- if .Value}}
+		"{{.Key}}": {{.Value}},
+{{- end}}
+{{- end}}
+	}
+	broker := {{ident "new" .TypeName "LayerBroker"}}(nil)
+	l, err := {{ident "apply" .TypeName "Flags"}}(broker, flags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A layer created after the flag layer must not override it
+	other := {{lower .TypeName}}TestFlags{
+{{- range .Flags}}
+{{- if .Value}}
+		"{{.Key}}": {{.Other}},
+{{- end}}
+{{- end}}
+	}
+	o, err := {{ident "lookup" .TypeName "Flags"}}(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker.Layer().Set(o.Partial())
+	if want := {{lower .TypeName}}FlagConfig(t, flags); !broker.Get().{{method "Equal"}}(want) {
+		t.Errorf("expected the flags to take priority, got %+v, want %+v", broker.Get(), want)
+	}
+	for key, value := range other {
+		flags[key] = value
+	}
+	if err := l.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if want := {{lower .TypeName}}FlagConfig(t, other); !broker.Get().{{method "Equal"}}(want) {
+		t.Errorf("expected refreshed flags, got %+v, want %+v", broker.Get(), want)
+	}
+}
+{{- range .Flags}}
+{{- if .Duration}}
+
+func Test{{capitalize (ident "lookup" $.TypeName "Flags")}}InvalidDuration(t *testing.T) {
+	if _, err := {{ident "lookup" $.TypeName "Flags"}}({{lower $.TypeName}}TestFlags{"{{.Key}}": "soon"}); err == nil {
+		t.Error("expected an error for an invalid duration flag")
+	}
+}
+{{- break}}
+{{- end}}
+{{- end}}
+`
@@ -0,0 +1,202 @@
+// Package flagvalue implements the flagvalue code generation subtool.
+package flagvalue
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/bobcob7/sudo-gen/internal/codegen"
+)
+
+// Subtool implements the flagvalue code generator.
+type Subtool struct{}
+
+// Name returns the subtool name.
+func (s *Subtool) Name() string { return "flagvalue" }
+
+// Description returns the subtool description.
+func (s *Subtool) Description() string {
+	return "Generate String, Set and Type methods making scalar types flag.Value and pflag.Value"
+}
+
+// Doc describes the struct tags the subtool honors and the files it generates.
+func (s *Subtool) Doc() codegen.SubtoolDoc {
+	return codegen.SubtoolDoc{
+		Tags: []codegen.DocEntry{
+			codegen.ExcludeTagDoc(s.Name()),
+		},
+		Files: []codegen.DocEntry{
+			{Name: "{source}_flagvalue.go", Description: "String, Set and Type on the type, or on the scalar types of the struct's fields"},
+		},
+	}
+}
+
+// Run executes the flagvalue code generation. The type is either a scalar type
+// itself, like type Port uint16, or a struct whose fields of scalar types declared in
+// the package get the methods.
+func (s *Subtool) Run(cfg codegen.GeneratorConfig) error {
+	if cfg.CrossPackage() {
+		return errors.New("flagvalue declares methods, so it must generate into the source package")
+	}
+	baseName := strings.TrimSuffix(cfg.SourceFile, ".go")
+	outputFile := filepath.Join(cfg.OutputDir, baseName+"_flagvalue.go")
+	var scalars []codegen.ScalarType
+	if st, ok := cfg.Index.ScalarType(cfg.SourceDir, cfg.TypeName); ok {
+		scalars = append(scalars, st)
+	} else {
+		found, err := fieldScalars(cfg, s.Name())
+		if err != nil {
+			return err
+		}
+		scalars = found
+	}
+	data := templateData{Package: cfg.OutputPkg}
+	for _, st := range scalars {
+		t := newScalarType(st)
+		for method, file := range cfg.Index.Methods(cfg.SourceDir, st.Name) {
+			if !sameFile(file, outputFile) {
+				t.declared(method)
+			}
+		}
+		if t.String || t.Set || t.Type {
+			data.Types = append(data.Types, t)
+		}
+	}
+	if len(data.Types) == 0 {
+		return fmt.Errorf("every scalar type of %s already declares String, Set and Type", cfg.TypeName)
+	}
+	gen := codegen.NewTemplateGenerator(cfg, templateFuncs())
+	if err := gen.GenerateFile(outputFile, flagValueTemplate, data); err != nil {
+		return err
+	}
+	if cfg.GenerateTest {
+		return gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_flagvalue_test.go"), flagValueTestTemplate, data)
+	}
+	return nil
+}
+
+// fieldScalars returns the scalar types declared in the package of the fields of
+// the struct cfg.TypeName and of the structs nested in it, in field order.
+func fieldScalars(cfg codegen.GeneratorConfig, generator string) ([]codegen.ScalarType, error) {
+	info, err := cfg.Index.ParseStruct(cfg.SourceDir, cfg.SourceFile, cfg.Source, cfg.TypeName)
+	if err != nil {
+		return nil, fmt.Errorf("%s is neither a type defined over a basic type or time.Duration nor a struct: %w", cfg.TypeName, err)
+	}
+	selection := codegen.NewFieldSelection(cfg, generator)
+	selection.Apply(info)
+	nested, err := cfg.Index.FindNestedStructs(cfg.SourceDir, cfg.Source, info, nil)
+	if err != nil {
+		return nil, fmt.Errorf("finding nested structs: %w", err)
+	}
+	structs := []*codegen.StructInfo{info}
+	for _, st := range nested {
+		if st.Package == "" {
+			selection.Apply(st)
+			structs = append(structs, st)
+		}
+	}
+	var scalars []codegen.ScalarType
+	seen := make(map[string]bool)
+	for _, st := range structs {
+		for _, f := range st.Fields {
+			// Methods can only be declared on types of the package
+			if f.TypePkg != "" || f.IsSlice || f.IsMap || seen[f.TypeName] {
+				continue
+			}
+			if scalar, ok := cfg.Index.ScalarType(cfg.SourceDir, f.TypeName); ok {
+				seen[f.TypeName] = true
+				scalars = append(scalars, scalar)
+			}
+		}
+	}
+	if len(scalars) == 0 {
+		return nil, fmt.Errorf("%s has no fields of types defined over a basic type or time.Duration in its package", info.Name)
+	}
+	return scalars, nil
+}
+
+func sameFile(a, b string) bool {
+	a, errA := filepath.Abs(a)
+	b, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && a == b
+}
+
+type templateData struct {
+	Package string
+	Types   []scalarType
+}
+
+// scalarType is a scalar type and the methods it gets.
+type scalarType struct {
+	codegen.ScalarType
+	String, Set, Type bool   // Whether to generate the method
+	Format            string // Expression formatting v as a string
+	Parse             string // Expression parsing s into the kind, with an error
+	Sample, Other     string // Values for generated tests
+	Invalid           string // A string Set rejects; empty if it accepts any
+}
+
+func newScalarType(st codegen.ScalarType) scalarType {
+	t := scalarType{ScalarType: st, String: true, Set: true, Type: true}
+	t.Sample, t.Other = st.Samples()
+	switch kind := st.Kind; kind {
+	case "string":
+		t.Format = "string(v)"
+	case "bool":
+		t.Format = "strconv.FormatBool(bool(v))"
+		t.Parse = "strconv.ParseBool(s)"
+		t.Invalid = "maybe"
+	case "float32", "float64":
+		t.Format = "strconv.FormatFloat(float64(v), 'g', -1, " + strings.TrimPrefix(kind, "float") + ")"
+		t.Parse = "strconv.ParseFloat(s, " + strings.TrimPrefix(kind, "float") + ")"
+		t.Invalid = "one"
+	case "duration":
+		t.Format = "time.Duration(v).String()"
+		t.Parse = "time.ParseDuration(s)"
+		t.Invalid = "soon"
+	case "int", "int8", "int16", "int32", "int64":
+		t.Format = "strconv.FormatInt(int64(v), 10)"
+		t.Parse = "strconv.ParseInt(s, 0, " + bitSize(kind, "int") + ")"
+		t.Invalid = "forty-two"
+	default:
+		t.Format = "strconv.FormatUint(uint64(v), 10)"
+		t.Parse = "strconv.ParseUint(s, 0, " + bitSize(kind, "uint") + ")"
+		t.Invalid = "forty-two"
+	}
+	return t
+}
+
+// declared records that the type already declares method.
+func (t *scalarType) declared(method string) {
+	switch method {
+	case "String":
+		t.String = false
+	case "Set":
+		t.Set = false
+	case "Type":
+		t.Type = false
+	}
+}
+
+// bitSize returns the bit size argument of strconv for an integer kind.
+func bitSize(kind, prefix string) string {
+	if kind == prefix {
+		return "strconv.IntSize"
+	}
+	return strings.TrimPrefix(kind, prefix)
+}
+
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"capitalize": capitalize,
+		"quote":      strconv.Quote,
+	}
+}
+
+func capitalize(name string) string {
+	return strings.ToUpper(name[:1]) + name[1:]
+}
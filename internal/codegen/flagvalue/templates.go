@@ -0,0 +1,77 @@
+package flagvalue
+
+const flagValueTemplate = `// Code generated by sudo-gen flagvalue. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"strconv"
+	"time"
+)
+{{- range .Types}}
+{{- if .String}}
+
+// String returns the {{.Name}} in the form Set parses.
+func (v {{.Name}}) String() string {
+	return {{.Format}}
+}
+{{- end}}
+{{- if .Set}}
+
+// Set parses s into the {{.Name}}, so that a *{{.Name}} is a flag.Value.
+func (v *{{.Name}}) Set(s string) error {
+{{- if eq .Kind "string"}}
+	*v = {{.Name}}(s)
+{{- else}}
+	parsed, err := {{.Parse}}
+	if err != nil {
+		return err
+	}
+	*v = {{.Name}}(parsed)
+{{- end}}
+	return nil
+}
+{{- end}}
+{{- if .Type}}
+
+// Type returns the name of the kind of value, so that a *{{.Name}} is a pflag.Value.
+func (v *{{.Name}}) Type() string {
+	return "{{.Kind}}"
+}
+{{- end}}
+{{- end}}
+`
+
+const flagValueTestTemplate = `// Code generated by sudo-gen flagvalue. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"flag"
+	"testing"
+)
+{{- range $t := .Types}}
+
+var _ flag.Value = (*{{.Name}})(nil)
+
+func Test{{capitalize .Name}}FlagValue(t *testing.T) {
+	for _, s := range []string{ {{- quote .Sample}}, {{quote .Other -}} } {
+		var v {{.Name}}
+		if err := v.Set(s); err != nil {
+			t.Fatalf("Set(%q): %v", s, err)
+		}
+{{- if .String}}
+		if got := v.String(); got != s {
+			t.Errorf("Set(%q).String() = %q", s, got)
+		}
+{{- end}}
+	}
+{{- if .Set}}{{with .Invalid}}
+	var v {{$t.Name}}
+	if err := v.Set({{quote .}}); err == nil {
+		t.Errorf("expected an error for %q", {{quote .}})
+	}
+{{- end}}{{end}}
+}
+{{- end}}
+`
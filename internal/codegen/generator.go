@@ -2,47 +2,271 @@ package codegen
 
 import (
 	"bytes"
+	"errors"
+	"flag"
 	"fmt"
 	"go/format"
+	"io/fs"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"text/template"
 )
 
 // TemplateGenerator handles template-based code generation.
 type TemplateGenerator struct {
-	FuncMap template.FuncMap
+	FuncMap       template.FuncMap
+	Mode          OutputMode
+	Capture       func(path string, content []byte) error
+	Banner        Banner
+	Force         bool       // Rewrite files whose content is unchanged
+	TODOs         []string   // Comments added below the package clause of MainFile
+	MainFile      string     // File the TODOs go in; empty for every non-test file
+	MethodPrefix  string     // Prefix of generated method names, for the method template function
+	PartialSuffix string     // Suffix of partial type names, for the partial template function
+	TemplatesDir  string     // Directory of templates overriding the embedded ones (see TemplateName)
+	Style         Style      // Code style the output is rewritten in
+	Implements    Implements // Interface assertions added to one of the generated files
+	SourceFile    string     // File the output is generated from, naming the templates of TemplatesDir
 }
 
-// NewTemplateGenerator creates a new TemplateGenerator with optional custom functions.
-func NewTemplateGenerator(customFuncs template.FuncMap) *TemplateGenerator {
-	return &TemplateGenerator{FuncMap: customFuncs}
+// NewTemplateGenerator creates a new TemplateGenerator for cfg with optional custom functions.
+func NewTemplateGenerator(cfg GeneratorConfig, customFuncs template.FuncMap) *TemplateGenerator {
+	return &TemplateGenerator{FuncMap: customFuncs, Mode: cfg.Mode, Capture: cfg.Capture, Banner: cfg.Banner, Force: cfg.Force, TODOs: cfg.TODOs, MainFile: cfg.MainFile, MethodPrefix: cfg.MethodPrefix, PartialSuffix: cfg.Project.PartialSuffix, TemplatesDir: cfg.TemplatesDir, Style: cfg.Project.Style, Implements: cfg.Implements, SourceFile: cfg.SourceFile}
+}
+
+// funcs returns the custom functions along with those every template can use.
+func (g *TemplateGenerator) funcs() template.FuncMap {
+	funcs := template.FuncMap{
+		"method":  func(name string) string { return g.MethodPrefix + name },
+		"partial": func(name string) string { return partialName(name, g.PartialSuffix) },
+		"equal":   func(a, b string) string { return g.Style.EqualCall(g.MethodPrefix+"Equal", a, b) },
+	}
+	maps.Copy(funcs, g.FuncMap)
+	return funcs
 }
 
 // GenerateFile executes a template and writes the formatted output to a file.
 func (g *TemplateGenerator) GenerateFile(outputFile, tmplText string, data any) error {
-	tmpl, err := template.New("gen").Funcs(g.FuncMap).Parse(tmplText)
+	if g.Mode == ModeDumpData {
+		return g.dumpData(outputFile, data)
+	}
+	tmpl, err := g.parse(outputFile, tmplText, data)
 	if err != nil {
-		return fmt.Errorf("parsing template: %w", err)
+		return err
 	}
 	var buf bytes.Buffer
 	if err := tmpl.Execute(&buf, data); err != nil {
 		return fmt.Errorf("executing template: %w", err)
 	}
-	formatted, err := format.Source(buf.Bytes())
+	src, err := g.Banner.apply(buf.Bytes(), outputFile)
+	if err != nil {
+		return fmt.Errorf("adding banner to %s: %w", outputFile, err)
+	}
+	if outputFile == g.MainFile || g.MainFile == "" && !strings.HasSuffix(outputFile, "_test.go") {
+		src = addTODOs(src, g.TODOs)
+	}
+	src = g.Implements.apply(src, outputFile)
+	if src, err = g.Style.apply(src); err != nil {
+		return fmt.Errorf("generating %s: %w", outputFile, err)
+	}
+	src = fixImports(src)
+	formatted, err := format.Source(src)
 	if err != nil {
-		_ = os.WriteFile(outputFile+".unformatted", buf.Bytes(), 0644)
+		if g.Mode != ModeWrite {
+			return fmt.Errorf("formatting generated code for %s: %w", outputFile, err)
+		}
+		_ = writeFileAtomic(outputFile+".unformatted", src)
 		return fmt.Errorf("formatting generated code: %w (wrote unformatted to %s.unformatted)", err, outputFile)
 	}
-	if err := os.WriteFile(outputFile, formatted, 0644); err != nil {
-		return fmt.Errorf("writing file: %w", err)
+	return g.emit(outputFile, formatted)
+}
+
+// GenerateText executes a template for a file that isn't Go source, such as
+// Markdown documentation, and writes the output as it is.
+func (g *TemplateGenerator) GenerateText(outputFile, tmplText string, data any) error {
+	if g.Mode == ModeDumpData {
+		return g.dumpData(outputFile, data)
+	}
+	tmpl, err := g.parse(outputFile, tmplText, data)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return fmt.Errorf("executing template: %w", err)
+	}
+	return g.emit(outputFile, buf.Bytes())
+}
+
+// addTODOs adds a TODO comment for each of todos below the package clause of src.
+func addTODOs(src []byte, todos []string) []byte {
+	if len(todos) == 0 {
+		return src
+	}
+	var comments bytes.Buffer
+	comments.WriteString("\n")
+	for _, todo := range todos {
+		fmt.Fprintf(&comments, "// TODO(sudo-gen): %s\n", todo)
+	}
+	for i := 0; i < len(src); {
+		end := bytes.IndexByte(src[i:], '\n')
+		if end < 0 {
+			break
+		}
+		end += i + 1
+		if bytes.HasPrefix(src[i:], []byte("package ")) {
+			return slices.Concat(src[:end], comments.Bytes(), src[end:])
+		}
+		i = end
+	}
+	return src
+}
+
+// emit writes, lists, or diffs the formatted output according to the generator mode.
+func (g *TemplateGenerator) emit(outputFile string, content []byte) error {
+	switch g.Mode {
+	case ModeDryRun:
+		fmt.Printf("Would generate: %s\n", outputFile)
+		return nil
+	case ModeCapture:
+		return g.Capture(outputFile, content)
+	case ModeDiff:
+		existing, err := os.ReadFile(outputFile)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("reading existing file: %w", err)
+		}
+		oldName := outputFile
+		if existing == nil {
+			oldName = os.DevNull
+		}
+		fmt.Print(UnifiedDiff(oldName, outputFile, existing, content))
+		return nil
+	}
+	written, err := WriteFile(outputFile, content, g.Force)
+	if err != nil {
+		return err
+	}
+	if !written {
+		fmt.Printf("Up to date: %s\n", outputFile)
+		return nil
 	}
 	fmt.Printf("Generated: %s\n", outputFile)
 	return nil
 }
 
+// WriteFile writes the generated content to path as ModeWrite does, reporting whether
+// it wrote it. Files already holding content are left alone unless force is set.
+func WriteFile(path string, content []byte, force bool) (bool, error) {
+	// Output of an earlier run that failed to format is stale once formatting succeeds
+	if err := os.Remove(path + ".unformatted"); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, fmt.Errorf("removing stale unformatted output: %w", err)
+	}
+	// Leave unchanged files alone so their mtimes don't trigger rebuilds and reloads
+	if !force {
+		if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, content) {
+			return false, nil
+		}
+	}
+	if err := writeFileAtomic(path, content); err != nil {
+		return false, fmt.Errorf("writing file: %w", err)
+	}
+	return true, nil
+}
+
+// writeFileAtomic writes content to a temporary file in the directory of path and
+// renames it over path, so that an interrupted run never leaves a partially
+// written file behind.
+func writeFileAtomic(path string, content []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // Fails harmlessly once renamed
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
 // Subtool defines the interface for code generation subtools.
 type Subtool interface {
 	Name() string
 	Description() string
 	Run(cfg GeneratorConfig) error
 }
+
+// FlagDefiner is implemented by subtools with flags of their own. DefineFlags defines
+// them on the flag set of the subcommand, storing their values in cfg or the subtool,
+// so flags of other subtools are rejected instead of silently ignored.
+type FlagDefiner interface {
+	DefineFlags(fs *flag.FlagSet, cfg *GeneratorConfig)
+}
+
+// Documenter is implemented by subtools describing the struct tags they honor and the
+// files they generate, for sudo-gen help <subcommand>.
+type Documenter interface {
+	Doc() SubtoolDoc
+}
+
+// SubtoolDoc describes what a subtool reads and writes besides its flags.
+type SubtoolDoc struct {
+	Tags  []DocEntry // Struct tags honored, e.g. sudo:"secret"
+	Files []DocEntry // Generated files, e.g. {source}_copy.go
+}
+
+// DocEntry is a struct tag or generated file of a SubtoolDoc with what it does.
+type DocEntry struct {
+	Name        string
+	Description string
+}
+
+// MainFile returns the main generated file of cfg's run: the first Go file doc lists
+// other than tests, in cfg.OutputDir, or "" if it lists none.
+func (doc SubtoolDoc) MainFile(cfg GeneratorConfig) string {
+	for _, f := range doc.Files {
+		if strings.HasSuffix(f.Name, ".go") && !strings.HasSuffix(f.Name, "_test.go") {
+			return filepath.Join(cfg.OutputDir, strings.ReplaceAll(f.Name, "{source}", strings.TrimSuffix(cfg.SourceFile, ".go")))
+		}
+	}
+	return ""
+}
+
+// ExcludeTagDoc documents the sudo-gen:"-<generator>" tag honored by the generators
+// that select fields with a FieldSelection.
+func ExcludeTagDoc(generator string) DocEntry {
+	return DocEntry{`sudo-gen:"-` + generator + `"`, `Skips the field in this generator; sudo-gen:"-" skips it in every generator`}
+}
+
+// ListFlag is a flag.Value holding a comma-separated list, such as -tags=json,yaml.
+// Entries are trimmed and empty ones dropped. Convert a *[]string to define one:
+//
+//	fs.Var((*codegen.ListFlag)(&cfg.Tags), "tags", "...")
+type ListFlag []string
+
+func (l *ListFlag) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+// Set replaces the list with the entries of s.
+func (l *ListFlag) Set(s string) error {
+	*l = nil
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
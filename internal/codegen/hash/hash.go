@@ -0,0 +1,240 @@
+// Package hash implements the hash code generation subtool.
+package hash
+
+import (
+	"fmt"
+	"go/ast"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/bobcob7/sudo-gen/internal/codegen"
+)
+
+// Subtool implements the hash code generator.
+type Subtool struct{}
+
+// Name returns the subtool name.
+func (s *Subtool) Name() string { return "hash" }
+
+// Description returns the subtool description.
+func (s *Subtool) Description() string {
+	return "Generate deterministic Hash methods fingerprinting struct values"
+}
+
+// Doc describes the struct tags the subtool honors and the files it generates.
+func (s *Subtool) Doc() codegen.SubtoolDoc {
+	return codegen.SubtoolDoc{
+		Tags: []codegen.DocEntry{
+			codegen.ExcludeTagDoc(s.Name()),
+		},
+		Files: []codegen.DocEntry{
+			{Name: "{source}_hash.go", Description: "Hash method fingerprinting the struct"},
+		},
+	}
+}
+
+// Run executes the hash code generation.
+func (s *Subtool) Run(cfg codegen.GeneratorConfig) error {
+	info, err := cfg.Index.ParseStruct(cfg.SourceDir, cfg.SourceFile, cfg.Source, cfg.TypeName)
+	if err != nil {
+		return fmt.Errorf("parsing struct: %w", err)
+	}
+	selection := codegen.NewFieldSelection(cfg, s.Name())
+	selection.Apply(info)
+	values := codegen.NewValueTypes(cfg.Index, cfg.SourceDir, cfg.ValueTypes)
+	nested, err := cfg.Index.FindNestedStructs(cfg.SourceDir, cfg.Source, info, values)
+	if err != nil {
+		return fmt.Errorf("finding nested structs: %w", err)
+	}
+	// External package structs can't have methods added to them, so they are hashed
+	// as opaque values
+	allStructs := []*codegen.StructInfo{info}
+	local := map[string]bool{info.Name: true}
+	for _, st := range nested {
+		if st.Package == "" {
+			selection.Apply(st)
+			allStructs = append(allStructs, st)
+			local[st.Name] = true
+		}
+	}
+	codegen.SkipFields(allStructs, pointerKeyReason)
+	cfg, err = codegen.CheckUnsupported(cfg, s.Name(), codegen.UnsupportedFields(allStructs))
+	if err != nil {
+		return err
+	}
+	baseName := strings.TrimSuffix(cfg.SourceFile, ".go")
+	data := templateData{
+		Package:  cfg.OutputPkg,
+		TypeName: info.Name,
+		Structs:  allStructs,
+	}
+	gen := codegen.NewTemplateGenerator(cfg, templateFuncs(local, info.Name))
+	if err := gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_hash.go"), hashTemplate, data); err != nil {
+		return err
+	}
+	if cfg.GenerateTest {
+		return gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_hash_test.go"), hashTestTemplate, data)
+	}
+	return nil
+}
+
+type templateData struct {
+	Package  string
+	TypeName string // Root type, which names the hasher shared by all structs
+	Structs  []*codegen.StructInfo
+}
+
+// pointerKeyReason returns why a field holding a map keyed by pointers isn't hashed:
+// pointers are told apart by their addresses, which change between processes.
+func pointerKeyReason(f codegen.FieldInfo) string {
+	pointerKeys := false
+	ast.Inspect(f.TypeExpr, func(n ast.Node) bool {
+		if m, ok := n.(*ast.MapType); ok {
+			_, star := m.Key.(*ast.StarExpr)
+			pointerKeys = pointerKeys || star
+		}
+		return !pointerKeys
+	})
+	if pointerKeys {
+		return "hash doesn't hash maps keyed by pointers, which differ between processes; key the map by value"
+	}
+	return ""
+}
+
+func templateFuncs(local map[string]bool, typeName string) template.FuncMap {
+	return template.FuncMap{
+		"lower": strings.ToLower,
+		"hashStmt": func(typ, expr string) string {
+			w := &stmtWriter{local: local, encode: strings.ToLower(typeName) + "JSON"}
+			w.write(typ, expr)
+			return strings.TrimSuffix(w.b.String(), "\n")
+		},
+	}
+}
+
+// stmtWriter builds the statements feeding a value to the generated hasher.
+type stmtWriter struct {
+	local  map[string]bool // Struct types with a generated hash method
+	encode string          // Function encoding values without a known encoding
+	depth  int             // Nesting of loops, for unique variable names
+	b      strings.Builder
+}
+
+// write adds the statements hashing expr, of Go type typ. Pointers, slices and maps
+// are hashed element by element, with a nil marker or length first so that values
+// of different shapes don't run together. Types without a known encoding are
+// hashed through their JSON encoding, which sorts map keys.
+func (w *stmtWriter) write(typ, expr string) {
+	switch typ {
+	case "string":
+		w.line("w.string(%s)", expr)
+		return
+	case "bool":
+		w.line("w.bool(%s)", expr)
+		return
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "uintptr", "byte", "rune", "time.Duration":
+		w.line("w.uint(uint64(%s))", expr)
+		return
+	case "float32", "float64":
+		w.line("w.float(float64(%s))", expr)
+		return
+	case "time.Time":
+		w.line("w.time(%s)", expr)
+		return
+	}
+	if w.local[strings.TrimPrefix(typ, "*")] {
+		// Generated hash methods handle nil receivers themselves
+		w.line("%s.hash(w)", expr)
+		return
+	}
+	if elem, ok := strings.CutPrefix(typ, "*"); ok {
+		w.line("if %s == nil {", expr)
+		w.line("w.bool(false)")
+		w.line("} else {")
+		w.line("w.bool(true)")
+		w.write(elem, "*"+expr)
+		w.line("}")
+		return
+	}
+	if elem, ok := strings.CutPrefix(typ, "[]"); ok {
+		i := w.variable("i")
+		w.line("w.uint(uint64(len(%s)))", expr)
+		w.line("for %s := range %s {", i, expr)
+		w.depth++
+		w.write(elem, expr+"["+i+"]")
+		w.depth--
+		w.line("}")
+		return
+	}
+	if key, val, ok := splitMapType(typ); ok {
+		k, v := w.variable("k"), w.variable("v")
+		w.line("w.uint(uint64(len(%s)))", expr)
+		keys := fmt.Sprintf("slices.Sorted(maps.Keys(%s))", expr)
+		if !isOrdered(key) {
+			// Keys without an order are sorted by their JSON encoding, in a block of
+			// their own so that several maps can be hashed in one function
+			keys = w.variable("keys")
+			w.line("{")
+			w.line("%s := slices.Collect(maps.Keys(%s))", keys, expr)
+			w.line("slices.SortFunc(%s, func(a, b %s) int {", keys, key)
+			w.line("return cmp.Compare(%s(a), %s(b))", w.encode, w.encode)
+			w.line("})")
+		}
+		w.line("for _, %s := range %s {", k, keys)
+		w.depth++
+		w.write(key, k)
+		// Map values aren't addressable, so they are copied for struct hash methods
+		w.line("%s := %s[%s]", v, expr, k)
+		w.write(val, v)
+		w.depth--
+		w.line("}")
+		if !isOrdered(key) {
+			w.line("}")
+		}
+		return
+	}
+	w.line("w.value(%s)", expr)
+}
+
+func (w *stmtWriter) line(format string, args ...any) {
+	fmt.Fprintf(&w.b, format+"\n", args...)
+}
+
+// variable returns a loop variable name unique to the current nesting.
+func (w *stmtWriter) variable(name string) string {
+	if w.depth == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s%d", name, w.depth)
+}
+
+// splitMapType splits map[K]V into K and V.
+func splitMapType(typ string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(typ, "map[")
+	if !ok {
+		return "", "", false
+	}
+	depth := 1
+	for i, r := range rest {
+		switch r {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return rest[:i], rest[i+1:], true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// isOrdered reports whether a map key type can be sorted with slices.Sorted.
+func isOrdered(typ string) bool {
+	switch typ {
+	case "string", "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "uintptr", "byte", "rune", "float32", "float64":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,310 @@
+package codegen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Helpers shared by the files generated into a package. Generators and types that
+// need the same one would otherwise declare it twice, so they are declared once in
+// HelpersFile, or HelpersTestFile for those only generated tests use.
+const (
+	HelperPtr         = "sudogenPtr"         // Returns a pointer to a copy of a value
+	HelperEqualAny    = "sudogenEqualAny"    // Compares decoded JSON-like values
+	HelperDeepCopyAny = "sudogenDeepCopyAny" // Deep copies decoded JSON-like values
+	HelperFloatEqual  = "sudogenFloatEqual"  // Compares floats within a tolerance
+	HelperCopyValue   = "sudogenCopyValue"   // Copies the value of a type parameter, declaring Copier
+)
+
+// HelpersFile and HelpersTestFile are the files of an output directory declaring the
+// shared helpers. The zz_ prefix sorts them after the files using them.
+const (
+	HelpersFile     = "zz_sudogen_helpers.go"
+	HelpersTestFile = "zz_sudogen_helpers_test.go"
+)
+
+// helper is the declaration of a shared helper.
+type helper struct {
+	test   bool     // Only generated tests use it
+	types  []string // Types declared alongside it
+	source string
+}
+
+var helpers = map[string]helper{
+	HelperPtr: {test: true, source: `
+// sudogenPtr returns a pointer to a copy of v.
+func sudogenPtr[T any](v T) *T {
+	return &v
+}
+`},
+	HelperEqualAny: {source: `
+// sudogenEqualAny reports whether a and b, values decoded from JSON or a similar
+// format, are deeply equal.
+func sudogenEqualAny(a, b any) bool {
+	if a == nil && b == nil {
+		return true
+	}
+	if a == nil || b == nil {
+		return false
+	}
+	switch av := a.(type) {
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, v := range av {
+			if ov, ok := bv[k]; !ok || !sudogenEqualAny(v, ov) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		bv, ok := b.([]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !sudogenEqualAny(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	case []string:
+		bv, ok := b.([]string)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if av[i] != bv[i] {
+				return false
+			}
+		}
+		return true
+	case []int:
+		bv, ok := b.([]int)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if av[i] != bv[i] {
+				return false
+			}
+		}
+		return true
+	case string:
+		bv, ok := b.(string)
+		return ok && av == bv
+	case int:
+		bv, ok := b.(int)
+		return ok && av == bv
+	case int64:
+		bv, ok := b.(int64)
+		return ok && av == bv
+	case float64:
+		bv, ok := b.(float64)
+		return ok && av == bv
+	case bool:
+		bv, ok := b.(bool)
+		return ok && av == bv
+	default:
+		return a == b
+	}
+}
+`},
+	HelperDeepCopyAny: {source: `
+// sudogenDeepCopyAny deep copies v, a value decoded from JSON or a similar format.
+func sudogenDeepCopyAny(v any) any {
+	if v == nil {
+		return nil
+	}
+	switch val := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(val))
+		for k, v := range val {
+			m[k] = sudogenDeepCopyAny(v)
+		}
+		return m
+	case []any:
+		s := make([]any, len(val))
+		for i, v := range val {
+			s[i] = sudogenDeepCopyAny(v)
+		}
+		return s
+	case []string:
+		s := make([]string, len(val))
+		copy(s, val)
+		return s
+	case []int:
+		s := make([]int, len(val))
+		copy(s, val)
+		return s
+	default:
+		return val
+	}
+}
+`},
+	HelperCopyValue: {types: []string{"Copier"}, source: `
+// Copier is implemented by types with a Copy method returning a deep copy, such as
+// those sudo-gen copy generates for. Generic structs copy the values of a type
+// parameter constrained by Copier[T] with their Copy method.
+type Copier[T any] interface {
+	Copy() T
+}
+
+// sudogenCopyValue returns a copy of v, a value of a type parameter: a deep copy if
+// v or a pointer to it is a Copier, and v itself otherwise.
+func sudogenCopyValue[T any](v T) T {
+	if c, ok := any(v).(Copier[T]); ok {
+		return c.Copy()
+	}
+	if c, ok := any(&v).(Copier[*T]); ok {
+		if p := c.Copy(); p != nil {
+			return *p
+		}
+	}
+	return v
+}
+`},
+	HelperFloatEqual: {source: `
+// sudogenFloatEqual reports whether a and b are within epsilon of each other. NaN
+// equals NaN, so that a value holding one still equals itself.
+func sudogenFloatEqual[F ~float32 | ~float64](a, b F, epsilon float64) bool {
+	if math.IsNaN(float64(a)) || math.IsNaN(float64(b)) {
+		return math.IsNaN(float64(a)) && math.IsNaN(float64(b))
+	}
+	return a == b || math.Abs(float64(a)-float64(b)) <= epsilon
+}
+`},
+}
+
+const helpersTemplate = `// Code generated by sudo-gen helpers. DO NOT EDIT.
+
+package {{.Package}}
+{{range .Sources}}{{.}}{{end}}`
+
+// required records the helpers each output directory needs in this run, so that
+// generators writing into the same package see each other's helpers even when the
+// files aren't written to disk.
+var required = struct {
+	sync.Mutex
+	dirs map[string]map[string]bool
+}{dirs: make(map[string]map[string]bool)}
+
+// WriteHelpers declares the named helpers in the helpers files of cfg.OutputDir.
+// Helpers already declared there are kept, since files generated by other runs may
+// use them; deleting the files and regenerating drops the unused ones.
+func WriteHelpers(cfg GeneratorConfig, names ...string) error {
+	dir, err := filepath.Abs(cfg.OutputDir)
+	if err != nil {
+		return fmt.Errorf("resolving output directory: %w", err)
+	}
+	required.Lock()
+	needed := required.dirs[dir]
+	if needed == nil {
+		needed = make(map[string]bool)
+		required.dirs[dir] = needed
+	}
+	for _, name := range names {
+		if _, ok := helpers[name]; !ok {
+			required.Unlock()
+			return fmt.Errorf("unknown helper %q", name)
+		}
+		needed[name] = true
+	}
+	all := maps.Clone(needed)
+	required.Unlock()
+	if err := checkHelperTypes(cfg, all); err != nil {
+		return err
+	}
+	// Helpers are generated code, so a run's own TODOs don't belong in their files,
+	// and every subcommand writes them, so its command line doesn't either
+	cfg.TODOs = nil
+	cfg.Banner.Stamp.Command = ""
+	gen := NewTemplateGenerator(cfg, nil)
+	for _, test := range []bool{false, true} {
+		file := filepath.Join(cfg.OutputDir, HelpersFile)
+		if test {
+			file = filepath.Join(cfg.OutputDir, HelpersTestFile)
+		}
+		var sources []string
+		for _, name := range sortedHelpers(all, declaredHelpers(file)) {
+			if h := helpers[name]; h.test == test {
+				sources = append(sources, h.source)
+			}
+		}
+		if len(sources) == 0 {
+			continue
+		}
+		data := struct {
+			Package string
+			Sources []string
+		}{cfg.OutputPkg, sources}
+		if err := gen.GenerateFile(file, helpersTemplate, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkHelperTypes reports types declared by the needed helpers that the output
+// package already declares in another file than HelpersFile, such as a hand-written
+// Copier. Output packages that can't be parsed aren't checked.
+func checkHelperTypes(cfg GeneratorConfig, needed map[string]bool) error {
+	var pkg *Package
+	var err error
+	if cfg.Source != nil && filepath.Clean(cfg.OutputDir) == filepath.Clean(cfg.SourceDir) {
+		pkg, err = cfg.Index.Overlay(cfg.SourceDir, cfg.SourceFile, cfg.Source)
+	} else {
+		pkg, err = cfg.Index.Package(cfg.OutputDir)
+	}
+	if err != nil {
+		return nil
+	}
+	var conflicts []string
+	for _, name := range slices.Sorted(maps.Keys(needed)) {
+		for _, typ := range helpers[name].types {
+			if pos, ok := pkg.TypeDecl(typ, filepath.Join(cfg.OutputDir, HelpersFile)); ok {
+				conflicts = append(conflicts, fmt.Sprintf("type %s of helper %s is already declared at %s", typ, name, pos))
+			}
+		}
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s; rename the declared types, since sudo-gen declares them in %s", strings.Join(conflicts, "; "), HelpersFile)
+}
+
+// declaredHelpers returns the shared helpers file declares, if it exists.
+func declaredHelpers(file string) []string {
+	f, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, decl := range f.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil {
+			if _, ok := helpers[fn.Name.Name]; ok {
+				names = append(names, fn.Name.Name)
+			}
+		}
+	}
+	return names
+}
+
+// sortedHelpers returns the names in needed and declared, sorted and without duplicates.
+func sortedHelpers(needed map[string]bool, declared []string) []string {
+	names := declared
+	for name := range needed {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
@@ -0,0 +1,138 @@
+package codegen
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Implements holds the interfaces given with -implements, which a generated file
+// asserts the config type implements, so that regenerating code that no longer
+// satisfies one of them fails to compile:
+//
+//	var (
+//		_ io.Closer = (*Config)(nil)
+//	)
+type Implements struct {
+	Type       string      // Config type asserted to implement the interfaces
+	File       string      // Generated file holding the assertions
+	Interfaces []Interface // Empty without -implements
+}
+
+// Interface is an interface named by -implements.
+type Interface struct {
+	Import ImportInfo // Package declaring the interface; empty for the source package
+	Name   string     // Interface as written in generated code, e.g. "io.Closer"
+}
+
+// NewImplements resolves the interfaces of entries, asserted in file for the type of
+// cfg. Each entry is an interface of the source package, such as Snapshotter, one of
+// a package imported by the source file or of the standard library, such as
+// io.Closer, or one qualified with its import path, such as
+// example.com/myapp.Snapshotter.
+func NewImplements(cfg GeneratorConfig, file string, entries []string) (Implements, error) {
+	impl := Implements{Type: cfg.TypeName, File: file}
+	if len(entries) == 0 {
+		return impl, nil
+	}
+	if cfg.CrossPackage() {
+		return Implements{}, fmt.Errorf("-implements requires generating into package %s", cfg.SourcePkg)
+	}
+	f, _, err := cfg.Index.File(filepath.Join(cfg.SourceDir, cfg.SourceFile), cfg.Source)
+	if err != nil {
+		return Implements{}, fmt.Errorf("parsing file: %w", err)
+	}
+	imports := collectImports(f)
+	for _, entry := range entries {
+		iface, err := resolveInterface(entry, imports, cfg.SourceFile)
+		if err != nil {
+			return Implements{}, fmt.Errorf("-implements: %w", err)
+		}
+		impl.Interfaces = append(impl.Interfaces, iface)
+	}
+	return impl, nil
+}
+
+// resolveInterface returns the Interface named by entry in a file with imports.
+func resolveInterface(entry string, imports []ImportInfo, file string) (Interface, error) {
+	dot := strings.LastIndex(entry, ".")
+	if dot < 0 {
+		if !token.IsIdentifier(entry) {
+			return Interface{}, fmt.Errorf("%q is not an interface name", entry)
+		}
+		return Interface{Name: entry}, nil
+	}
+	pkg, name := entry[:dot], entry[dot+1:]
+	if !token.IsIdentifier(name) {
+		return Interface{}, fmt.Errorf("%q is not an interface name", entry)
+	}
+	if strings.Contains(pkg, "/") {
+		imp := ImportInfo{Path: pkg}
+		for _, existing := range imports {
+			if existing.Path == pkg {
+				imp = existing
+			}
+		}
+		return Interface{Import: imp, Name: importName(imp) + "." + name}, nil
+	}
+	if path := importPathFor(imports, pkg); path != "" {
+		i := slices.IndexFunc(imports, func(imp ImportInfo) bool { return imp.Path == path })
+		return Interface{Import: imports[i], Name: entry}, nil
+	}
+	if path, ok := stdlibPackages[pkg]; ok {
+		return Interface{Import: ImportInfo{Path: path}, Name: entry}, nil
+	}
+	return Interface{}, fmt.Errorf("package %s of %s is not imported by %s; write its import path, as in example.com/%s.%s", pkg, entry, file, pkg, name)
+}
+
+// importName returns the name imp is referred to by.
+func importName(imp ImportInfo) string {
+	if imp.Alias != "" {
+		return imp.Alias
+	}
+	return assumedPackageName(imp.Path)
+}
+
+// apply appends the assertions of impl to src, the contents of the generated file
+// outputFile, if it is impl.File, importing the packages of the interfaces.
+func (impl Implements) apply(src []byte, outputFile string) []byte {
+	if len(impl.Interfaces) == 0 || outputFile != impl.File {
+		return src
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n// %s implements these interfaces, so that generating methods that no longer\n", impl.Type)
+	b.WriteString("// satisfy one of them fails to compile.\nvar (\n")
+	var imports []ImportInfo
+	for _, iface := range impl.Interfaces {
+		fmt.Fprintf(&b, "\t_ %s = (*%s)(nil)\n", iface.Name, impl.Type)
+		if iface.Import.Path != "" {
+			imports = append(imports, iface.Import)
+		}
+	}
+	b.WriteString(")\n")
+	return addImports(append(src, b.String()...), imports)
+}
+
+// addImports adds the imports to src that it doesn't have yet. src is returned
+// unchanged if it doesn't parse, leaving the syntax error for the formatter to report.
+func addImports(src []byte, imports []ImportInfo) []byte {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ImportsOnly)
+	if err != nil {
+		return src
+	}
+	existing := collectImports(f)
+	all := slices.Clone(existing)
+	for _, imp := range imports {
+		if !slices.Contains(all, imp) {
+			all = append(all, imp)
+		}
+	}
+	if len(all) == len(existing) {
+		return src
+	}
+	return replaceImports(fset, f, src, all)
+}
@@ -0,0 +1,295 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// stdlibPackages maps package names to standard library import paths, for adding
+// imports that templates use without declaring and removing those they don't use. Names shared by several packages
+// (rand, template, ...) are left out, since the right one can't be guessed.
+var stdlibPackages = map[string]string{
+	"atomic":   "sync/atomic",
+	"base64":   "encoding/base64",
+	"big":      "math/big",
+	"binary":   "encoding/binary",
+	"bufio":    "bufio",
+	"bytes":    "bytes",
+	"cmp":      "cmp",
+	"context":  "context",
+	"errors":   "errors",
+	"filepath": "path/filepath",
+	"flag":     "flag",
+	"fmt":      "fmt",
+	"fnv":      "hash/fnv",
+	"fs":       "io/fs",
+	"hash":     "hash",
+	"hex":      "encoding/hex",
+	"http":     "net/http",
+	"httptest": "net/http/httptest",
+	"io":       "io",
+	"iter":     "iter",
+	"json":     "encoding/json",
+	"log":      "log",
+	"maps":     "maps",
+	"math":     "math",
+	"net":      "net",
+	"netip":    "net/netip",
+	"os":       "os",
+	"reflect":  "reflect",
+	"regexp":   "regexp",
+	"sha256":   "crypto/sha256",
+	"signal":   "os/signal",
+	"slices":   "slices",
+	"slog":     "log/slog",
+	"sort":     "sort",
+	"strconv":  "strconv",
+	"strings":  "strings",
+	"subtle":   "crypto/subtle",
+	"sync":     "sync",
+	"syscall":  "syscall",
+	"testing":  "testing",
+	"time":     "time",
+	"unicode":  "unicode",
+	"url":      "net/url",
+	"utf8":     "unicode/utf8",
+}
+
+// fixImports rewrites the imports of src like goimports, without loading any
+// packages: imports that are never referenced are removed, and references to
+// standard library packages that aren't imported are added. Only aliased imports and
+// the standard library packages of stdlibPackages are removed, since the name of any
+// other package can't be known without loading it: module paths without a dot, like
+// mycorp/config, look like the standard library's. src is returned unchanged if it
+// doesn't parse, leaving the syntax error for the formatter to report.
+func fixImports(src []byte) []byte {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.SkipObjectResolution)
+	if err != nil {
+		return src
+	}
+	used := packageRefs(f)
+	var imports []ImportInfo
+	imported := make(map[string]bool)
+	changed := false
+	for _, spec := range f.Imports {
+		imp := ImportInfo{Path: strings.Trim(spec.Path.Value, `"`)}
+		if spec.Name != nil {
+			imp.Alias = spec.Name.Name
+		}
+		name, known := imp.Alias, imp.Alias != ""
+		if name == "" {
+			name, known = stdlibName(imp.Path)
+		}
+		if !known {
+			name = assumedPackageName(imp.Path)
+		}
+		if known && name != "_" && name != "." && !used[name] {
+			changed = true
+			continue
+		}
+		imported[name] = true
+		imports = append(imports, imp)
+	}
+	for name := range used {
+		if importPath, ok := stdlibPackages[name]; ok && !imported[name] {
+			imports = append(imports, ImportInfo{Path: importPath})
+			imported[name] = true
+			changed = true
+		}
+	}
+	if !changed {
+		return src
+	}
+	return replaceImports(fset, f, src, imports)
+}
+
+// packageRefs returns the names used as the package of a selector expression (the
+// fmt of fmt.Sprintf). A name declared by f, or by the declaration holding the
+// selector, as the url of url.Host after url := ..., is a value rather than a package.
+// Scopes within a declaration aren't told apart, so a name declared anywhere in a
+// function is never a package in it.
+func packageRefs(f *ast.File) map[string]bool {
+	fileNames := make(map[string]bool)
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv == nil {
+				fileNames[decl.Name.Name] = true
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				for _, name := range specNames(spec) {
+					fileNames[name.Name] = true
+				}
+			}
+		}
+	}
+	used := make(map[string]bool)
+	for _, decl := range f.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			continue
+		}
+		local := declaredNames(decl)
+		ast.Inspect(decl, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if ident, ok := sel.X.(*ast.Ident); ok && !fileNames[ident.Name] && !local[ident.Name] {
+					used[ident.Name] = true
+				}
+			}
+			return true
+		})
+	}
+	return used
+}
+
+// declaredNames returns the names of the parameters, results, variables, constants
+// and types declared within decl.
+func declaredNames(decl ast.Decl) map[string]bool {
+	names := make(map[string]bool)
+	addFields := func(fields *ast.FieldList) {
+		if fields == nil {
+			return
+		}
+		for _, field := range fields.List {
+			for _, name := range field.Names {
+				names[name.Name] = true
+			}
+		}
+	}
+	if fn, ok := decl.(*ast.FuncDecl); ok {
+		addFields(fn.Recv)
+	}
+	ast.Inspect(decl, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncType:
+			addFields(n.TypeParams)
+			addFields(n.Params)
+			addFields(n.Results)
+		case *ast.AssignStmt:
+			if n.Tok == token.DEFINE {
+				for _, lhs := range n.Lhs {
+					if ident, ok := lhs.(*ast.Ident); ok {
+						names[ident.Name] = true
+					}
+				}
+			}
+		case *ast.RangeStmt:
+			if n.Tok == token.DEFINE {
+				for _, expr := range []ast.Expr{n.Key, n.Value} {
+					if ident, ok := expr.(*ast.Ident); ok {
+						names[ident.Name] = true
+					}
+				}
+			}
+		case *ast.DeclStmt:
+			for _, spec := range n.Decl.(*ast.GenDecl).Specs {
+				for _, name := range specNames(spec) {
+					names[name.Name] = true
+				}
+			}
+		}
+		return true
+	})
+	return names
+}
+
+// specNames returns the names a value or type spec declares.
+func specNames(spec ast.Spec) []*ast.Ident {
+	switch spec := spec.(type) {
+	case *ast.ValueSpec:
+		return spec.Names
+	case *ast.TypeSpec:
+		return []*ast.Ident{spec.Name}
+	}
+	return nil
+}
+
+// replaceImports replaces the import declarations of f with a single declaration of
+// imports, placed where the first one was or after the package clause.
+func replaceImports(fset *token.FileSet, f *ast.File, src []byte, imports []ImportInfo) []byte {
+	start, end := fset.Position(f.Name.End()).Offset, -1
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		if end < 0 {
+			start = fset.Position(gen.Pos()).Offset
+		}
+		end = fset.Position(gen.End()).Offset
+	}
+	// Standard library imports come first, as goimports groups them
+	sort.Slice(imports, func(i, j int) bool {
+		if isStdlib(imports[i].Path) != isStdlib(imports[j].Path) {
+			return isStdlib(imports[i].Path)
+		}
+		return imports[i].Path < imports[j].Path
+	})
+	var block bytes.Buffer
+	if len(imports) > 0 {
+		if end < 0 {
+			block.WriteString("\n\n")
+		}
+		block.WriteString("import (\n")
+		for i, imp := range imports {
+			if i > 0 && isStdlib(imp.Path) != isStdlib(imports[i-1].Path) {
+				block.WriteString("\n")
+			}
+			if imp.Alias != "" {
+				fmt.Fprintf(&block, "\t%s %s\n", imp.Alias, strconv.Quote(imp.Path))
+			} else {
+				fmt.Fprintf(&block, "\t%s\n", strconv.Quote(imp.Path))
+			}
+		}
+		block.WriteString(")")
+	}
+	if end < 0 {
+		end = start
+	}
+	var out bytes.Buffer
+	out.Write(src[:start])
+	out.Write(block.Bytes())
+	out.Write(src[end:])
+	return out.Bytes()
+}
+
+// stdlibName returns the name of the standard library package at importPath if
+// stdlibPackages lists it.
+func stdlibName(importPath string) (string, bool) {
+	for name, p := range stdlibPackages {
+		if p == importPath {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// isStdlib reports whether importPath looks like a standard library path, with no
+// dot in its first element, for grouping imports as goimports does.
+func isStdlib(importPath string) bool {
+	first, _, _ := strings.Cut(importPath, "/")
+	return !strings.Contains(first, ".")
+}
+
+// assumedPackageName guesses the name of the package at importPath the way goimports
+// does: its last element, skipping a major version suffix and dropping a "go-" prefix
+// and anything after a dot or dash (gopkg.in/yaml.v3 is yaml).
+func assumedPackageName(importPath string) string {
+	base := path.Base(importPath)
+	if len(base) > 1 && base[0] == 'v' && strings.Trim(base[1:], "0123456789") == "" && path.Dir(importPath) != "." {
+		base = path.Base(path.Dir(importPath))
+	}
+	base = strings.TrimPrefix(base, "go-")
+	if i := strings.IndexAny(base, ".-"); i >= 0 {
+		base = base[:i]
+	}
+	return base
+}
@@ -0,0 +1,70 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFixImports(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string // Import paths of the result
+	}{
+		{
+			name: "adds used stdlib packages",
+			src:  "package p\n\nfunc f() string { return fmt.Sprint(strings.ToUpper(\"x\")) }\n",
+			want: []string{"fmt", "strings"},
+		},
+		{
+			name: "removes unused stdlib packages",
+			src:  "package p\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\nfunc f() { fmt.Println() }\n",
+			want: []string{"fmt"},
+		},
+		{
+			name: "keeps packages it can't name",
+			src:  "package p\n\nimport \"example.com/config\"\n\nfunc f() {}\n",
+			want: []string{"example.com/config"},
+		},
+		{
+			name: "local variable is not a package",
+			src:  "package p\n\nimport \"net/url\"\n\nfunc f(s string) string { url := parse(s); return url.Host }\n",
+			want: nil,
+		},
+		{
+			name: "parameter is not a package",
+			src:  "package p\n\nfunc f(json T) string { return json.Name }\n",
+			want: nil,
+		},
+		{
+			name: "range variable is not a package",
+			src:  "package p\n\nfunc f(xs []T) { for _, sort := range xs { sort.Run() } }\n",
+			want: nil,
+		},
+		{
+			name: "file-level variable is not a package",
+			src:  "package p\n\nvar log = newLogger()\n\nfunc f() { log.Print() }\n",
+			want: nil,
+		},
+		{
+			name: "name declared in another function is still a package",
+			src:  "package p\n\nfunc f(s string) string { url := parse(s); return url.Host }\n\nfunc g() { url.Parse(\"x\") }\n",
+			want: []string{"net/url"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := string(fixImports([]byte(tt.src)))
+			var got []string
+			for _, line := range strings.Split(out, "\n") {
+				line = strings.TrimSpace(strings.TrimPrefix(line, "import "))
+				if strings.HasPrefix(line, `"`) {
+					got = append(got, strings.Trim(line, `"`))
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("imports = %q, want %q in:\n%s", got, tt.want, out)
+			}
+		})
+	}
+}
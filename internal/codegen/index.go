@@ -0,0 +1,300 @@
+package codegen
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// PackageIndex caches what generators read from source packages: parsed files,
+// the structs declared in each package directory, and type-checked packages for
+// method sets. main builds one per invocation and passes it in GeneratorConfig.Index,
+// so that subtools run together (layerbroker runs merge, copy and equals) parse
+// each package once. A nil *PackageIndex is valid and parses on every call.
+type PackageIndex struct {
+	mu       sync.Mutex
+	fset     *token.FileSet
+	files    map[string]*indexedFile    // By cleaned path
+	packages map[string]*indexedPackage // By cleaned directory
+	checked  map[string]*types.Package  // By cleaned directory; nil if checking failed
+}
+
+type indexedFile struct {
+	file *ast.File
+	err  error
+}
+
+type indexedPackage struct {
+	pkg *Package
+	err error
+}
+
+// ErrAmbiguousType is returned for a struct type declared more than once in a package.
+var ErrAmbiguousType = errors.New("ambiguous type")
+
+// Package is the parsed non-test package in a directory.
+type Package struct {
+	Name    string
+	Fset    *token.FileSet
+	Files   map[string]*ast.File // By path
+	structs map[string][]IndexedStruct
+	overlay string // Path of the file parsed from memory by Overlay, whose declarations win
+}
+
+// IndexedStruct is a struct type declaration found by a PackageIndex.
+type IndexedStruct struct {
+	Type       *ast.StructType
+	File       string // Path of the declaring file
+	Package    string // Name of the declaring package
+	Imports    []ImportInfo
+	Pos        token.Position
+	TypeParams []TypeParam // Type parameters of a generic struct
+}
+
+// NewPackageIndex returns an empty PackageIndex.
+func NewPackageIndex() *PackageIndex {
+	return &PackageIndex{
+		fset:     token.NewFileSet(),
+		files:    make(map[string]*indexedFile),
+		packages: make(map[string]*indexedPackage),
+		checked:  make(map[string]*types.Package),
+	}
+}
+
+// File parses the Go file at path. If src is non-nil it is parsed in place of the
+// file contents and the result isn't cached, since it may differ from the file on
+// disk.
+func (x *PackageIndex) File(path string, src []byte) (*ast.File, *token.FileSet, error) {
+	if x == nil {
+		x = NewPackageIndex()
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if src != nil {
+		f, err := parser.ParseFile(x.fset, path, src, parser.ParseComments)
+		return f, x.fset, err
+	}
+	f, err := x.file(path)
+	return f, x.fset, err
+}
+
+// file parses the file at path once. x.mu must be held.
+func (x *PackageIndex) file(path string) (*ast.File, error) {
+	key := filepath.Clean(path)
+	if entry, ok := x.files[key]; ok {
+		return entry.file, entry.err
+	}
+	f, err := parser.ParseFile(x.fset, path, nil, parser.ParseComments)
+	x.files[key] = &indexedFile{file: f, err: err}
+	return f, err
+}
+
+// Package parses the non-test .go files in dir that match the build context, so
+// that of files like config_linux.go and config_windows.go only one is used. Files
+// of other packages, such as snippets kept in the directory, are skipped: the
+// package is the one most files belong to.
+func (x *PackageIndex) Package(dir string) (*Package, error) {
+	if x == nil {
+		x = NewPackageIndex()
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	key := filepath.Clean(dir)
+	if entry, ok := x.packages[key]; ok {
+		return entry.pkg, entry.err
+	}
+	pkg, err := x.parsePackage(dir, "", nil)
+	x.packages[key] = &indexedPackage{pkg: pkg, err: err}
+	return pkg, err
+}
+
+// Overlay is like Package, but src is parsed in place of the contents of filename
+// and decides the package. Structs declared in src take precedence over ones of
+// the same name in other files, which may be the file src was read from. The
+// result isn't cached.
+func (x *PackageIndex) Overlay(dir, filename string, src []byte) (*Package, error) {
+	if x == nil {
+		x = NewPackageIndex()
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.parsePackage(dir, filename, src)
+}
+
+// parsePackage parses the package in dir, with src in place of filename if non-nil.
+// x.mu must be held.
+func (x *PackageIndex) parsePackage(dir, filename string, src []byte) (*Package, error) {
+	byPackage := make(map[string]map[string]*ast.File)
+	add := func(path string, f *ast.File) {
+		if byPackage[f.Name.Name] == nil {
+			byPackage[f.Name.Name] = make(map[string]*ast.File)
+		}
+		byPackage[f.Name.Name][path] = f
+	}
+	name := ""
+	if src != nil {
+		f, err := parser.ParseFile(x.fset, filepath.Join(dir, filename), src, parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", filename, err)
+		}
+		add(filepath.Join(dir, filename), f)
+		name = f.Name.Name
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil && src == nil {
+		return nil, fmt.Errorf("reading directory: %w", err)
+	}
+	for _, entry := range entries {
+		base := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(base, ".go") || strings.HasSuffix(base, "_test.go") {
+			continue
+		}
+		if src != nil && base == filename {
+			continue
+		}
+		if match, err := build.Default.MatchFile(dir, base); err == nil && !match {
+			continue
+		}
+		path := filepath.Join(dir, base)
+		f, err := x.file(path)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", base, err)
+		}
+		add(path, f)
+	}
+	if len(byPackage) == 0 {
+		return nil, fmt.Errorf("no Go files in %s", dir)
+	}
+	if name == "" {
+		names := slices.Sorted(maps.Keys(byPackage))
+		name = names[0]
+		for _, n := range names[1:] {
+			if len(byPackage[n]) > len(byPackage[name]) {
+				name = n
+			}
+		}
+	}
+	pkg := &Package{Name: name, Fset: x.fset, Files: byPackage[name], structs: make(map[string][]IndexedStruct)}
+	if src != nil {
+		pkg.overlay = filepath.Join(dir, filename)
+	}
+	for _, path := range slices.Sorted(maps.Keys(pkg.Files)) {
+		f := pkg.Files[path]
+		imports := collectImports(f)
+		for _, decl := range f.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				typeSpec, ok := spec.(*ast.TypeSpec)
+				if !ok {
+					continue
+				}
+				structType, ok := typeSpec.Type.(*ast.StructType)
+				if !ok {
+					continue
+				}
+				pkg.structs[typeSpec.Name.Name] = append(pkg.structs[typeSpec.Name.Name], IndexedStruct{
+					Type:       structType,
+					File:       path,
+					Package:    name,
+					Imports:    imports,
+					Pos:        x.fset.Position(typeSpec.Pos()),
+					TypeParams: TypeParamsOf(typeSpec.TypeParams),
+				})
+			}
+		}
+	}
+	return pkg, nil
+}
+
+// TypeDecl returns the position of the package-level type declared as name, of any
+// kind, outside the file at path skip, and false if there is none.
+func (p *Package) TypeDecl(name, skip string) (token.Position, bool) {
+	for _, path := range slices.Sorted(maps.Keys(p.Files)) {
+		if path == filepath.Clean(skip) {
+			continue
+		}
+		for _, decl := range p.Files[path].Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				if typeSpec, ok := spec.(*ast.TypeSpec); ok && typeSpec.Name.Name == name {
+					return p.Fset.Position(typeSpec.Pos()), true
+				}
+			}
+		}
+	}
+	return token.Position{}, false
+}
+
+// Struct returns the declaration of the named struct type at package scope. A
+// name declared more than once, for instance in files whose build constraints
+// overlap, is an error listing the declarations.
+func (p *Package) Struct(name string) (IndexedStruct, error) {
+	decls := p.structs[name]
+	switch len(decls) {
+	case 0:
+		return IndexedStruct{}, fmt.Errorf("type %s not found in package %s", name, p.Name)
+	case 1:
+		return decls[0], nil
+	}
+	for _, d := range decls {
+		if d.File == p.overlay {
+			return d, nil
+		}
+	}
+	positions := make([]string, len(decls))
+	for i, d := range decls {
+		positions[i] = d.Pos.String()
+	}
+	return IndexedStruct{}, fmt.Errorf("%w: %s is declared more than once in package %s: %s", ErrAmbiguousType, name, p.Name, strings.Join(positions, " and "))
+}
+
+// Types type-checks the package in dir, returning nil if it can't be parsed.
+// Imports are not loaded: only the methods declared on the package's own types
+// are needed, so unresolved references are tolerated.
+func (x *PackageIndex) Types(dir string) *types.Package {
+	if x == nil {
+		x = NewPackageIndex()
+	}
+	key := filepath.Clean(dir)
+	x.mu.Lock()
+	if pkg, ok := x.checked[key]; ok {
+		x.mu.Unlock()
+		return pkg
+	}
+	x.mu.Unlock()
+	parsed, err := x.Package(dir)
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if err != nil {
+		x.checked[key] = nil
+		return nil
+	}
+	files := make([]*ast.File, 0, len(parsed.Files))
+	for _, path := range slices.Sorted(maps.Keys(parsed.Files)) {
+		files = append(files, parsed.Files[path])
+	}
+	conf := types.Config{
+		Importer:    emptyImporter{},
+		Error:       func(error) {},
+		FakeImportC: true,
+	}
+	pkg, _ := conf.Check(dir, x.fset, files, nil)
+	x.checked[key] = pkg
+	return pkg
+}
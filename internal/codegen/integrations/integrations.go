@@ -0,0 +1,128 @@
+// Package integrations implements the integrations code generation subtool.
+package integrations
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/bobcob7/sudo-gen/internal/codegen"
+)
+
+// Subtool implements the integrations code generator.
+type Subtool struct{}
+
+// Name returns the subtool name.
+func (s *Subtool) Name() string { return "integrations" }
+
+// Description returns the subtool description.
+func (s *Subtool) Description() string {
+	return "Generate adapters binding etcd or Consul KV prefixes to broker layers"
+}
+
+// Doc describes the struct tags the subtool honors and the files it generates.
+func (s *Subtool) Doc() codegen.SubtoolDoc {
+	return codegen.SubtoolDoc{
+		Tags: []codegen.DocEntry{
+			{Name: `json:"name"`, Description: "Path segment of the field in KV keys"},
+		},
+		Files: []codegen.DocEntry{
+			{Name: "{source}_kv.go", Description: "Decode{Type}KV and the reconnecting {Type}KVWatcher"},
+			{Name: "{source}_etcd.go", Description: "Watch{Type}EtcdLayer (with -sources=etcd)"},
+			{Name: "{source}_consul.go", Description: "Watch{Type}ConsulLayer (with -sources=consul)"},
+		},
+	}
+}
+
+// sources maps each supported -sources entry to its template.
+var sources = map[string]string{
+	"etcd":   etcdTemplate,
+	"consul": consulTemplate,
+}
+
+// DefineFlags defines the flags of the integrations subcommand.
+func (s *Subtool) DefineFlags(fs *flag.FlagSet, cfg *codegen.GeneratorConfig) {
+	fs.Var((*codegen.ListFlag)(&cfg.IntegrationSources), "sources", "Comma-separated KV stores to generate adapters for (etcd, consul)")
+	fs.BoolVar(&cfg.GenerateTracing, "otel", false, "Trace KV layer loads with the broker's tracer (see layerbroker -otel)")
+}
+
+// Run executes the integrations code generation. The generated adapters build on the
+// layerbroker output for the same type.
+func (s *Subtool) Run(cfg codegen.GeneratorConfig) error {
+	if len(cfg.IntegrationSources) == 0 {
+		return errors.New("integrations requires -sources=etcd,consul (one or more)")
+	}
+	for _, source := range cfg.IntegrationSources {
+		if _, ok := sources[source]; !ok {
+			return fmt.Errorf("unknown integration source %q (supported: etcd, consul)", source)
+		}
+	}
+	info, err := cfg.Index.ParseStruct(cfg.SourceDir, cfg.SourceFile, cfg.Source, cfg.TypeName)
+	if err != nil {
+		return fmt.Errorf("parsing struct: %w", err)
+	}
+	data := templateData{
+		Package:     cfg.OutputPkg,
+		TypeName:    info.Name,
+		StringField: firstStringField(info),
+		Tracing:     cfg.GenerateTracing,
+	}
+	baseName := strings.TrimSuffix(cfg.SourceFile, ".go")
+	gen := codegen.NewTemplateGenerator(cfg, templateFuncs())
+	if err := gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_kv.go"), kvTemplate, data); err != nil {
+		return err
+	}
+	if cfg.GenerateTest {
+		if err := gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_kv_test.go"), kvTestTemplate, data); err != nil {
+			return err
+		}
+	}
+	for _, source := range cfg.IntegrationSources {
+		if err := gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_"+source+".go"), sources[source], data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type templateData struct {
+	Package     string
+	TypeName    string
+	StringField string // First plain string field, used by generated tests
+	Tracing     bool   // Trace loads with the broker's tracer (see layerbroker -otel)
+}
+
+// firstStringField returns the first plain string field of info, used by test examples.
+func firstStringField(info *codegen.StructInfo) string {
+	for _, f := range info.Fields {
+		if f.TypeName == "string" && !f.IsPointer && !f.IsSlice && !f.IsMap {
+			return f.Name
+		}
+	}
+	return ""
+}
+
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"lower":      strings.ToLower,
+		"capitalize": capitalize,
+		"ident":      ident,
+	}
+}
+
+func capitalize(name string) string {
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// ident builds a generated function name such as DecodeConfigKV, keeping it
+// unexported (decodeConfigKV) when the type is unexported.
+func ident(verb, typeName, suffix string) string {
+	if ast.IsExported(typeName) {
+		return capitalize(verb) + typeName + suffix
+	}
+	return verb + capitalize(typeName) + suffix
+}
@@ -0,0 +1,362 @@
+package integrations
+
+const kvTemplate = `// Code generated by sudo-gen integrations. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"time"
+{{- if .Tracing}}
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+{{- end}}
+)
+
+// {{ident "decode" .TypeName "KV"}} decodes the keys under prefix into a {{partial .TypeName}}. Each key
+// below the prefix is a path of json field names ("prefix/database/host") and each
+// value is JSON; values that are not valid JSON are used as plain strings.
+func {{ident "decode" .TypeName "KV"}}(prefix string, kvs map[string][]byte) (*{{partial .TypeName}}, error) {
+	keys := make([]string, 0, len(kvs))
+	for key := range kvs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	root := make(map[string]any)
+	for _, key := range keys {
+		rel := strings.Trim(strings.TrimPrefix(key, prefix), "/")
+		if rel == "" {
+			continue
+		}
+		var value any
+		if err := json.Unmarshal(kvs[key], &value); err != nil {
+			value = string(kvs[key])
+		}
+		segments := strings.Split(rel, "/")
+		node := root
+		for _, segment := range segments[:len(segments)-1] {
+			child, ok := node[segment].(map[string]any)
+			if !ok {
+				child = make(map[string]any)
+				node[segment] = child
+			}
+			node = child
+		}
+		node[segments[len(segments)-1]] = value
+	}
+	data, err := json.Marshal(root)
+	if err != nil {
+		return nil, fmt.Errorf("encoding %s: %w", prefix, err)
+	}
+	var p {{partial .TypeName}}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", prefix, err)
+	}
+	return &p, nil
+}
+
+// {{.TypeName}}KVWatcher keeps a broker layer in sync with a remote KV prefix.
+type {{.TypeName}}KVWatcher struct {
+	// Layer holds the decoded keys. Each update replaces it entirely, so deleted keys
+	// are dropped from the config.
+	Layer      *{{.TypeName}}Layer
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	errors     chan error
+}
+
+func new{{capitalize .TypeName}}KVWatcher(broker *{{.TypeName}}LayerBroker) *{{.TypeName}}KVWatcher {
+	return &{{.TypeName}}KVWatcher{
+		Layer:      broker.Layer(),
+		minBackoff: 500 * time.Millisecond,
+		maxBackoff: 30 * time.Second,
+		errors:     make(chan error, 1),
+	}
+}
+
+// Errors returns connection and decoding failures. Only the oldest unread error is
+// kept; the channel is closed once watching stops.
+func (w *{{.TypeName}}KVWatcher) Errors() <-chan error {
+	return w.errors
+}
+
+// run calls watch until ctx is done, reconnecting with jittered exponential backoff
+// when it fails. The backoff resets after a session outlives the maximum backoff.
+func (w *{{.TypeName}}KVWatcher) run(ctx context.Context, watch func(context.Context) error) {
+	defer close(w.errors)
+	backoff := w.minBackoff
+	for {
+		start := time.Now()
+		err := watch(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			w.report(err)
+		}
+		if time.Since(start) > w.maxBackoff {
+			backoff = w.minBackoff
+		}
+		delay := backoff/2 + rand.N(backoff/2+1)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		backoff = min(2*backoff, w.maxBackoff)
+	}
+}
+
+// apply replaces the layer with the decoded keys, keeping the previous contents if
+// they fail to decode or the broker's validator rejects them.
+{{- if .Tracing}} The load is traced
+// as a "{{.TypeName}}KVWatcher.load" span, a child of the span in ctx.
+{{- end}}
+func (w *{{.TypeName}}KVWatcher) apply({{if .Tracing}}ctx context.Context, {{end}}prefix string, kvs map[string][]byte) {
+{{- if .Tracing}}
+	ctx, span := w.Layer.broker.tracer.Start(ctx, "{{.TypeName}}KVWatcher.load", trace.WithAttributes(attribute.String("config.prefix", prefix), attribute.Int("config.keys", len(kvs))))
+	defer span.End()
+{{- end}}
+	p, err := {{ident "decode" .TypeName "KV"}}(prefix, kvs)
+	if err == nil {
+		err = w.Layer.{{if .Tracing}}ReplaceContext(ctx, p){{else}}Replace(p){{end}}
+	}
+	if err != nil {
+{{- if .Tracing}}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "KV layer not loaded")
+{{- end}}
+		w.report(err)
+	}
+}
+
+func (w *{{.TypeName}}KVWatcher) report(err error) {
+	select {
+	case w.errors <- err:
+	default:
+	}
+}
+`
+
+const kvTestTemplate = `// Code generated by sudo-gen integrations. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// {{lower .TypeName}}FlattenKV stores p as one key per leaf value under prefix.
+func {{lower .TypeName}}FlattenKV(t *testing.T, prefix string, p *{{partial .TypeName}}) map[string][]byte {
+	t.Helper()
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tree map[string]any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		t.Fatal(err)
+	}
+	kvs := make(map[string][]byte)
+	var flatten func(key string, v any)
+	flatten = func(key string, v any) {
+		if node, ok := v.(map[string]any); ok && len(node) > 0 {
+			for k, child := range node {
+				flatten(key+"/"+k, child)
+			}
+			return
+		}
+		value, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		kvs[key] = value
+	}
+	for k, v := range tree {
+		flatten(prefix+"/"+k, v)
+	}
+	return kvs
+}
+{{- if .StringField}}
+
+func Test{{capitalize (ident "decode" .TypeName "KV")}}(t *testing.T) {
+	value := "from-kv"
+	want := &{{partial .TypeName}}{ {{.StringField}}: &value}
+	got, err := {{ident "decode" .TypeName "KV"}}("config/app", {{lower .TypeName}}FlattenKV(t, "config/app", want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded %+v, want %+v", got, want)
+	}
+}
+
+func Test{{capitalize (ident "decode" .TypeName "KV")}}PlainString(t *testing.T) {
+	value := ""
+	kvs := {{lower .TypeName}}FlattenKV(t, "config/app", &{{partial .TypeName}}{ {{.StringField}}: &value})
+	for key := range kvs {
+		kvs[key] = []byte("unquoted value")
+	}
+	got, err := {{ident "decode" .TypeName "KV"}}("config/app", kvs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.{{.StringField}} == nil || *got.{{.StringField}} != "unquoted value" {
+		t.Errorf("expected {{.StringField}}=unquoted value, got %v", got.{{.StringField}})
+	}
+}
+{{- end}}
+
+func Test{{capitalize (ident "decode" .TypeName "KV")}}Empty(t *testing.T) {
+	got, err := {{ident "decode" .TypeName "KV"}}("config/app", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, &{{partial .TypeName}}{}) {
+		t.Errorf("expected an empty partial, got %+v", got)
+	}
+}
+
+func Test{{capitalize .TypeName}}KVWatcherReconnects(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w := new{{capitalize .TypeName}}KVWatcher({{ident "new" .TypeName "LayerBroker"}}(nil))
+	w.minBackoff, w.maxBackoff = time.Millisecond, 10*time.Millisecond
+	attempts := 0
+	connected := make(chan struct{})
+	go w.run(ctx, func(ctx context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("connection refused")
+		}
+		close(connected)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	select {
+	case <-connected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("watcher did not reconnect")
+	}
+	if err := <-w.Errors(); err == nil || err.Error() != "connection refused" {
+		t.Errorf("expected the connection error to be reported, got %v", err)
+	}
+	cancel()
+	for range w.Errors() {
+	}
+}
+`
+
+const etcdTemplate = `// Code generated by sudo-gen integrations. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+	"fmt"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// {{ident "watch" .TypeName "EtcdLayer"}} binds the etcd keys under prefix to a new layer of broker
+// (see {{ident "decode" .TypeName "KV"}} for the key layout) and re-applies them on every watch
+// event until ctx is done. Lost connections and compacted revisions are recovered by
+// reloading the prefix with backoff.
+func {{ident "watch" .TypeName "EtcdLayer"}}(ctx context.Context, client *clientv3.Client, broker *{{.TypeName}}LayerBroker, prefix string) *{{.TypeName}}KVWatcher {
+	w := new{{capitalize .TypeName}}KVWatcher(broker)
+	go w.run(ctx, func(ctx context.Context) error {
+		return {{lower .TypeName}}WatchEtcd(ctx, client, prefix, w)
+	})
+	return w
+}
+
+func {{lower .TypeName}}WatchEtcd(ctx context.Context, client *clientv3.Client, prefix string, w *{{.TypeName}}KVWatcher) error {
+	resp, err := client.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return fmt.Errorf("loading %s: %w", prefix, err)
+	}
+	kvs := make(map[string][]byte, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		kvs[string(kv.Key)] = kv.Value
+	}
+	w.apply({{if .Tracing}}ctx, {{end}}prefix, kvs)
+	ctx, cancel := context.WithCancel(clientv3.WithRequireLeader(ctx))
+	defer cancel()
+	events := client.Watch(ctx, prefix, clientv3.WithPrefix(), clientv3.WithRev(resp.Header.Revision+1))
+	for wresp := range events {
+		if err := wresp.Err(); err != nil {
+			return fmt.Errorf("watching %s: %w", prefix, err)
+		}
+		for _, ev := range wresp.Events {
+			switch ev.Type {
+			case clientv3.EventTypePut:
+				kvs[string(ev.Kv.Key)] = ev.Kv.Value
+			case clientv3.EventTypeDelete:
+				delete(kvs, string(ev.Kv.Key))
+			}
+		}
+		w.apply({{if .Tracing}}ctx, {{end}}prefix, kvs)
+	}
+	return fmt.Errorf("watching %s: watch channel closed", prefix)
+}
+`
+
+const consulTemplate = `// Code generated by sudo-gen integrations. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// {{ident "watch" .TypeName "ConsulLayer"}} binds the Consul keys under prefix to a new layer of
+// broker (see {{ident "decode" .TypeName "KV"}} for the key layout) and re-applies them whenever a
+// blocking query reports a change, until ctx is done. Failed queries are retried with
+// backoff.
+func {{ident "watch" .TypeName "ConsulLayer"}}(ctx context.Context, client *api.Client, broker *{{.TypeName}}LayerBroker, prefix string) *{{.TypeName}}KVWatcher {
+	w := new{{capitalize .TypeName}}KVWatcher(broker)
+	go w.run(ctx, func(ctx context.Context) error {
+		return {{lower .TypeName}}WatchConsul(ctx, client, prefix, w)
+	})
+	return w
+}
+
+func {{lower .TypeName}}WatchConsul(ctx context.Context, client *api.Client, prefix string, w *{{.TypeName}}KVWatcher) error {
+	var index uint64
+	for {
+		opts := (&api.QueryOptions{WaitIndex: index}).WithContext(ctx)
+		pairs, meta, err := client.KV().List(prefix, opts)
+		if err != nil {
+			return fmt.Errorf("listing %s: %w", prefix, err)
+		}
+		switch {
+		case index != 0 && meta.LastIndex == index:
+			continue // The blocking query timed out without changes
+		case meta.LastIndex < index:
+			index = 0 // The index went backwards, e.g. after a snapshot restore
+		default:
+			index = max(meta.LastIndex, 1)
+		}
+		kvs := make(map[string][]byte, len(pairs))
+		for _, pair := range pairs {
+			kvs[pair.Key] = pair.Value
+		}
+		w.apply({{if .Tracing}}ctx, {{end}}prefix, kvs)
+	}
+}
+`
@@ -1,8 +1,13 @@
 package layerbroker
 
 import (
+	"cmp"
+	"flag"
 	"fmt"
+	"go/token"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"text/template"
 
@@ -20,38 +25,178 @@ func (s *Subtool) Name() string { return "layerbroker" }
 
 // Description returns the subtool description.
 func (s *Subtool) Description() string {
-	return "Generate thread-safe LayerBroker with ordered layers and subscriptions (no reflection)"
+	return "Generate thread-safe LayerBroker with ordered layers and subscriptions"
+}
+
+// Doc describes the struct tags the subtool honors and the files it generates.
+func (s *Subtool) Doc() codegen.SubtoolDoc {
+	return codegen.SubtoolDoc{
+		Tags: []codegen.DocEntry{
+			{Name: `sudo:"secret"`, Description: "Masked by the HTTP handler (-http) and redacted in audit entries (-audit)"},
+			{Name: `json:"name"`, Description: "Key of the field in the HTTP API and its JSON layers"},
+			codegen.ExcludeTagDoc(s.Name()),
+		},
+		Files: []codegen.DocEntry{
+			{Name: "{source}_layerbroker.go", Description: "Thread-safe LayerBroker with Layer() and Subscribe methods, plus the merge, copy and equals output"},
+			{Name: "{source}_layerbroker_http.go", Description: "{Type}HTTPHandler admin API (with -http)"},
+			{Name: "{source}_filelayer.go", Description: "Watch{Type}FileLayer file watcher (with -watch)"},
+			{Name: "{source}_reload.go", Description: "Run{Type}SignalReloader SIGHUP handler (with -sighup)"},
+			{Name: "{source}_layerbroker_example_test.go", Description: "Example{Type}LayerBroker, shown by godoc (with -examples, for types with a string field)"},
+		},
+	}
+}
+
+// DefineFlags defines the flags of the layerbroker subcommand, including those of the
+// merge, copy and equals generators it runs.
+func (s *Subtool) DefineFlags(fs *flag.FlagSet, cfg *codegen.GeneratorConfig) {
+	fs.BoolVar(&cfg.GenerateJSON, "json", false, "Generate JSON marshalling with layer state")
+	fs.BoolVar(&cfg.GenerateHTTP, "http", false, "Generate an http.Handler admin API for config and layers")
+	fs.IntVar(&cfg.History, "history", 0, "Number of merged configs to keep for History and Rollback (0 disables)")
+	fs.Var((*codegen.ListFlag)(&cfg.LayerGroups), "groups", "Comma-separated layer groups with a fixed relative order, lowest priority first (e.g. defaults,file,env)")
+	fs.BoolVar(&cfg.GenerateProvenance, "provenance", false, "Record which layer provides each field, reported by Explain")
+	fs.BoolVar(&cfg.GenerateAudit, "audit", false, "Generate an audit sink option receiving every layer change with the fields it altered")
+	fs.BoolVar(&cfg.GenerateTracing, "otel", false, "Generate With{Type}TracerProvider, tracing merges, notifications and file layer loads with OpenTelemetry")
+	fs.BoolVar(&cfg.GenerateWatch, "watch", false, "Generate a file watcher that reloads a config file into a layer (requires fsnotify)")
+	fs.BoolVar(&cfg.GenerateSIGHUP, "sighup", false, "Generate Run{Type}SignalReloader, reloading layers from loader functions on SIGHUP")
+	(&merge.Subtool{}).DefineFlags(fs, cfg)
+	copy.AddFlags(fs, cfg)
+	equals.AddFlags(fs, cfg)
 }
 
 // Run executes the layerbroker code generation.
 // It automatically generates the required dependencies (merge, copy, and equals).
 func (s *Subtool) Run(cfg codegen.GeneratorConfig) error {
-	// Generate dependencies first
+	if cfg.History < 0 {
+		return fmt.Errorf("history size must not be negative, got %d", cfg.History)
+	}
+	if err := checkLayerGroups(cfg.LayerGroups); err != nil {
+		return err
+	}
+	if cfg.Index == nil {
+		cfg.Index = codegen.NewPackageIndex()
+	}
+	info, err := cfg.Index.ParseStruct(cfg.SourceDir, cfg.SourceFile, cfg.Source, cfg.TypeName)
+	if err != nil {
+		return fmt.Errorf("parsing struct: %w", err)
+	}
+	if info.Generic() {
+		return fmt.Errorf("%s is generic, which layerbroker doesn't support", info.Name)
+	}
+	// The broker merges partials field by field, so it follows the merge selection
+	selection := codegen.NewFieldSelection(cfg, "merge")
+	selection.Apply(info)
+	values := codegen.NewValueTypes(cfg.Index, cfg.SourceDir, cfg.ValueTypes)
+	values.Apply(info)
+	nested, err := cfg.Index.FindNestedStructs(cfg.SourceDir, cfg.Source, info, values)
+	if err != nil {
+		return fmt.Errorf("finding nested structs: %w", err)
+	}
+	clones, err := codegen.NewCloneTypes(cfg.CloneFuncs, cfg.EqualFuncs)
+	if err != nil {
+		return err
+	}
+	clones.Apply(info)
+	structs := []*codegen.StructInfo{info}
+	for _, st := range nested {
+		selection.Apply(st)
+		structs = append(structs, st)
+	}
+	// Reported here, the dependencies don't warn about the same fields again
+	cfg, err = codegen.CheckUnsupported(cfg, s.Name(), codegen.UnsupportedFields(structs))
+	if err != nil {
+		return err
+	}
+	// Generate dependencies first, parsing the package once for all of them
 	mergeTool := &merge.Subtool{}
-	if err := mergeTool.Run(cfg); err != nil {
+	if err := mergeTool.Run(dependencyConfig(cfg, mergeTool)); err != nil {
 		return fmt.Errorf("generating merge dependency: %w", err)
 	}
 	copyTool := &copy.Subtool{MethodName: "Copy"}
-	if err := copyTool.Run(cfg); err != nil {
+	if err := copyTool.Run(dependencyConfig(cfg, copyTool)); err != nil {
 		return fmt.Errorf("generating copy dependency: %w", err)
 	}
 	equalsTool := &equals.Subtool{MethodName: "Equal"}
-	if err := equalsTool.Run(cfg); err != nil {
+	if err := equalsTool.Run(dependencyConfig(cfg, equalsTool)); err != nil {
 		return fmt.Errorf("generating equals dependency: %w", err)
 	}
-	info, err := codegen.ParseStruct(cfg.SourceDir, cfg.SourceFile, cfg.TypeName)
-	if err != nil {
-		return fmt.Errorf("parsing struct: %w", err)
-	}
 	if err := generateLayerBrokerFile(cfg, info); err != nil {
 		return err
 	}
+	if cfg.GenerateHTTP {
+		if err := generateHTTPFiles(cfg, info); err != nil {
+			return err
+		}
+	}
+	if cfg.GenerateWatch {
+		if err := generateFileWatcherFiles(cfg, info); err != nil {
+			return err
+		}
+	}
+	if cfg.GenerateSIGHUP {
+		if err := generateSignalReloaderFiles(cfg, info); err != nil {
+			return err
+		}
+	}
+	if cfg.GenerateExamples {
+		if err := generateLayerBrokerExampleFile(cfg, info); err != nil {
+			return err
+		}
+	}
 	if cfg.GenerateTest {
 		return generateLayerBrokerTestFile(cfg, info)
 	}
 	return nil
 }
 
+// dependencyConfig returns cfg for running the dependency tool, which writes the
+// TODOs of skipped fields in its own main file.
+func dependencyConfig(cfg codegen.GeneratorConfig, tool codegen.Documenter) codegen.GeneratorConfig {
+	cfg.MainFile = tool.Doc().MainFile(cfg)
+	return cfg
+}
+
+// generateLayerBrokerExampleFile generates the godoc example of the broker, which
+// layers a partial overriding a string field of the type over its base. Types
+// without one get no example. The merge dependency rejects unexported types.
+func generateLayerBrokerExampleFile(cfg codegen.GeneratorConfig, info *codegen.StructInfo) error {
+	data := testTemplateData{
+		Package:     cfg.OutputPkg,
+		TypeName:    info.Name,
+		StringField: firstStringField(info),
+	}
+	if data.StringField == "" {
+		return nil
+	}
+	baseName := strings.TrimSuffix(cfg.SourceFile, ".go")
+	gen := codegen.NewTemplateGenerator(cfg, templateFuncs())
+	return gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_layerbroker_example_test.go"), layerBrokerExampleTemplate, data)
+}
+
+// generateHTTPFiles generates the admin http.Handler for the broker, and its tests
+// with -tests.
+func generateHTTPFiles(cfg codegen.GeneratorConfig, info *codegen.StructInfo) error {
+	baseName := strings.TrimSuffix(cfg.SourceFile, ".go")
+	data := testTemplateData{
+		Package:     cfg.OutputPkg,
+		TypeName:    info.Name,
+		StringField: firstStringField(info),
+		Provenance:  cfg.GenerateProvenance,
+	}
+	secrets, err := secretPaths(cfg, info)
+	if err != nil {
+		return err
+	}
+	data.Secrets = secrets
+	gen := codegen.NewTemplateGenerator(cfg, templateFuncs())
+	if err := gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_layerbroker_http.go"), httpHandlerTemplate, data); err != nil {
+		return err
+	}
+	if cfg.GenerateTest {
+		return gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_layerbroker_http_test.go"), httpHandlerTestTemplate, data)
+	}
+	return nil
+}
+
 func generateLayerBrokerFile(cfg codegen.GeneratorConfig, info *codegen.StructInfo) error {
 	baseName := strings.TrimSuffix(cfg.SourceFile, ".go")
 	outputFile := filepath.Join(cfg.OutputDir, baseName+"_layerbroker.go")
@@ -70,12 +215,175 @@ func generateLayerBrokerFile(cfg codegen.GeneratorConfig, info *codegen.StructIn
 		NeedsTimeImport:    needsTime,
 		NeedsReflectImport: false, // No longer using reflect.DeepEqual
 		GenerateJSON:       cfg.GenerateJSON,
+		Clear:              cfg.GenerateClear,
+		History:            cfg.History,
+		Groups:             cfg.LayerGroups,
 		ExternalImports:    externalImports,
+		Audit:              cfg.GenerateAudit,
+		Tracing:            cfg.GenerateTracing,
+	}
+	if cfg.GenerateAudit {
+		secrets, err := secretPaths(cfg, info)
+		if err != nil {
+			return err
+		}
+		for _, secret := range secrets {
+			if !slices.Contains(data.AuditSecrets, secret.Field) {
+				data.AuditSecrets = append(data.AuditSecrets, secret.Field)
+			}
+		}
 	}
-	gen := codegen.NewTemplateGenerator(templateFuncs())
+	if cfg.GenerateProvenance {
+		explain, err := explainStructs(cfg, info)
+		if err != nil {
+			return err
+		}
+		data.Explain = explain
+	}
+	gen := codegen.NewTemplateGenerator(cfg, templateFuncs())
 	return gen.GenerateFile(outputFile, layerBrokerTemplate, data)
 }
 
+// checkLayerGroups reports whether the -groups names can be turned into distinct
+// constants.
+func checkLayerGroups(groups []string) error {
+	seen := make(map[string]string)
+	for _, group := range groups {
+		if !token.IsIdentifier(group) {
+			return fmt.Errorf("layer group %q is not a valid Go identifier", group)
+		}
+		if other, ok := seen[capitalize(group)]; ok {
+			return fmt.Errorf("layer groups %q and %q would generate the same constant", other, group)
+		}
+		seen[capitalize(group)] = group
+	}
+	return nil
+}
+
+// explainStruct is a struct whose partials Explain walks to find the layer providing
+// each field.
+type explainStruct struct {
+	Name   string
+	Fields []explainField
+}
+
+// explainField is a field of an explainStruct.
+type explainField struct {
+	Name    string
+	Nested  string // Local struct type whose partial is walked for the field, if any
+	Replace bool   // The nested struct is replaced as a whole (merge=replace)
+}
+
+// explainStructs returns info and the local structs whose partials are nested in its
+// partial, as the merge generator sees them. Structs from other packages, and those
+// in slices and maps, are reported as a single field.
+func explainStructs(cfg codegen.GeneratorConfig, info *codegen.StructInfo) ([]explainStruct, error) {
+	values := codegen.NewValueTypes(cfg.Index, cfg.SourceDir, cfg.ValueTypes)
+	nested, err := cfg.Index.FindNestedStructs(cfg.SourceDir, cfg.Source, info, values)
+	if err != nil {
+		return nil, fmt.Errorf("finding nested structs: %w", err)
+	}
+	selection := codegen.NewFieldSelection(cfg, "merge")
+	local := map[string]*codegen.StructInfo{info.Name: info}
+	for _, st := range nested {
+		if st.Package == "" {
+			selection.Apply(st)
+			local[st.Name] = st
+		}
+	}
+	var result []explainStruct
+	queue, seen := []*codegen.StructInfo{info}, map[string]bool{info.Name: true}
+	for len(queue) > 0 {
+		st := queue[0]
+		queue = queue[1:]
+		es := explainStruct{Name: st.Name}
+		for _, f := range st.Fields {
+			ef := explainField{Name: f.Name}
+			if nested, ok := local[f.TypeName]; ok && isLocalStruct(f) {
+				ef.Nested = f.TypeName
+				ef.Replace = merge.FieldMode(cfg.MergeStructs, f) == codegen.MergeReplace
+				if !seen[nested.Name] {
+					seen[nested.Name] = true
+					queue = append(queue, nested)
+				}
+			}
+			es.Fields = append(es.Fields, ef)
+		}
+		result = append(result, es)
+	}
+	return result, nil
+}
+
+// secretPath is the location of a field tagged sudo:"secret" in the JSON encoding of
+// a config or its partial.
+type secretPath struct {
+	Field  string   // Top-level field holding the secret, as named in a FieldChange
+	Keys   []string // Object keys from the root; "*" matches every key of a map
+	Direct bool     // No slice or map on the way, so the path can be written as nested objects
+	String bool     // The secret is a plain string
+}
+
+// JSON returns a JSON document setting the secret at p to value. It is only valid for
+// Direct paths.
+func (p secretPath) JSON(value string) string {
+	doc := strconv.Quote(value)
+	for _, key := range slices.Backward(p.Keys) {
+		doc = "{" + strconv.Quote(key) + ":" + doc + "}"
+	}
+	return doc
+}
+
+// secretPaths returns the paths of the secret fields of info and the local structs
+// nested in it, which the admin HTTP handler masks.
+func secretPaths(cfg codegen.GeneratorConfig, info *codegen.StructInfo) ([]secretPath, error) {
+	values := codegen.NewValueTypes(cfg.Index, cfg.SourceDir, cfg.ValueTypes)
+	nested, err := cfg.Index.FindNestedStructs(cfg.SourceDir, cfg.Source, info, values)
+	if err != nil {
+		return nil, fmt.Errorf("finding nested structs: %w", err)
+	}
+	selection := codegen.NewFieldSelection(cfg, "merge")
+	local := make(map[string]*codegen.StructInfo)
+	for _, st := range nested {
+		if st.Package == "" {
+			selection.Apply(st)
+			local[st.Name] = st
+		}
+	}
+	var paths []secretPath
+	// Recursive types are only walked once on each path
+	walking := map[string]bool{info.Name: true}
+	var walk func(st *codegen.StructInfo, field string, keys []string, direct bool)
+	walk = func(st *codegen.StructInfo, field string, keys []string, direct bool) {
+		for _, f := range st.Fields {
+			key, _, _ := strings.Cut(f.StructTag().Get("json"), ",")
+			if key == "-" {
+				continue
+			}
+			if key == "" {
+				key = f.Name
+			}
+			top := cmp.Or(field, f.Name)
+			path := append(slices.Clone(keys), key)
+			if f.Secret {
+				paths = append(paths, secretPath{Field: top, Keys: path, Direct: direct, String: f.Type == "string"})
+				continue
+			}
+			inner, ok := local[f.StructTypeName]
+			if !ok || f.IsValue || walking[inner.Name] {
+				continue
+			}
+			if f.IsMap {
+				path = append(path, "*")
+			}
+			walking[inner.Name] = true
+			walk(inner, top, path, direct && !f.IsSlice && !f.IsMap)
+			delete(walking, inner.Name)
+		}
+	}
+	walk(info, "", nil, true)
+	return paths, nil
+}
+
 // collectExternalImports gathers imports for external packages used by fields.
 func collectExternalImports(info *codegen.StructInfo) []codegen.ImportInfo {
 	// Build a map of package name to import info
@@ -105,6 +413,45 @@ func collectExternalImports(info *codegen.StructInfo) []codegen.ImportInfo {
 	return imports
 }
 
+// generateFileWatcherFiles generates the fsnotify-based file layer watcher, and its
+// tests with -tests.
+func generateFileWatcherFiles(cfg codegen.GeneratorConfig, info *codegen.StructInfo) error {
+	baseName := strings.TrimSuffix(cfg.SourceFile, ".go")
+	data := testTemplateData{
+		Package:     cfg.OutputPkg,
+		TypeName:    info.Name,
+		StringField: firstStringField(info),
+		Tracing:     cfg.GenerateTracing,
+	}
+	gen := codegen.NewTemplateGenerator(cfg, templateFuncs())
+	if err := gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_filelayer.go"), fileWatcherTemplate, data); err != nil {
+		return err
+	}
+	if cfg.GenerateTest {
+		return gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_filelayer_test.go"), fileWatcherTestTemplate, data)
+	}
+	return nil
+}
+
+// generateSignalReloaderFiles generates the SIGHUP layer reloader, and its tests with
+// -tests.
+func generateSignalReloaderFiles(cfg codegen.GeneratorConfig, info *codegen.StructInfo) error {
+	baseName := strings.TrimSuffix(cfg.SourceFile, ".go")
+	data := testTemplateData{
+		Package:     cfg.OutputPkg,
+		TypeName:    info.Name,
+		StringField: firstStringField(info),
+	}
+	gen := codegen.NewTemplateGenerator(cfg, templateFuncs())
+	if err := gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_reload.go"), signalReloaderTemplate, data); err != nil {
+		return err
+	}
+	if cfg.GenerateTest {
+		return gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_reload_test.go"), signalReloaderTestTemplate, data)
+	}
+	return nil
+}
+
 type templateData struct {
 	Package            string
 	TypeName           string
@@ -112,18 +459,36 @@ type templateData struct {
 	NeedsTimeImport    bool
 	NeedsReflectImport bool
 	GenerateJSON       bool
+	Clear              bool     // Partials can clear fields (see merge -clear)
+	History            int      // Number of configs kept for History and Rollback
+	Groups             []string // Layer groups, lowest priority first
 	ExternalImports    []codegen.ImportInfo
+	Explain            []explainStruct // Structs walked by Explain; nil without -provenance
+	Audit              bool            // Generate an audit sink option
+	AuditSecrets       []string        // Top-level fields holding secrets, redacted in audit entries
+	Tracing            bool            // Record OpenTelemetry spans (With{Type}TracerProvider)
 }
 
 func templateFuncs() template.FuncMap {
 	return template.FuncMap{
-		"lower":         strings.ToLower,
-		"partialType":   func(name string) string { return name + "Partial" },
-		"isLocalStruct": isLocalStruct,
-		"isExported":    isExported,
-		"brokerType":    brokerTypeName,
-		"layerType":     layerTypeName,
-		"newBroker":     newBrokerName,
+		"lower":              strings.ToLower,
+		"isLocalStruct":      isLocalStruct,
+		"isExported":         isExported,
+		"brokerType":         brokerTypeName,
+		"layerType":          layerTypeName,
+		"newBroker":          newBrokerName,
+		"handlerType":        handlerTypeName,
+		"handlerLayerType":   handlerLayerTypeName,
+		"newHandler":         newHandlerName,
+		"capitalize":         capitalize,
+		"watcherType":        watcherTypeName,
+		"watchFunc":          watchFuncName,
+		"reloaderFunc":       reloaderFuncName,
+		"withValidator":      withValidatorName,
+		"withAuditSink":      withAuditSinkName,
+		"withTracerProvider": withTracerProviderName,
+		"errValidation":      errValidationName,
+		"groupConst":         groupConstName,
 	}
 }
 
@@ -156,6 +521,75 @@ func newBrokerName(typeName string) string {
 	return "new" + strings.ToUpper(typeName[:1]) + typeName[1:] + "LayerBroker"
 }
 
+func handlerTypeName(typeName string) string {
+	return typeName + "HTTPHandler"
+}
+
+func handlerLayerTypeName(typeName string) string {
+	return typeName + "HTTPLayer"
+}
+
+func newHandlerName(typeName string) string {
+	if isExported(typeName) {
+		return "New" + typeName + "HTTPHandler"
+	}
+	return "new" + capitalize(typeName) + "HTTPHandler"
+}
+
+func watcherTypeName(typeName string) string {
+	return typeName + "FileWatcher"
+}
+
+func watchFuncName(typeName string) string {
+	if isExported(typeName) {
+		return "Watch" + typeName + "FileLayer"
+	}
+	return "watch" + capitalize(typeName) + "FileLayer"
+}
+
+func reloaderFuncName(typeName string) string {
+	if isExported(typeName) {
+		return "Run" + typeName + "SignalReloader"
+	}
+	return "run" + capitalize(typeName) + "SignalReloader"
+}
+
+func withValidatorName(typeName string) string {
+	if isExported(typeName) {
+		return "With" + typeName + "Validator"
+	}
+	return "with" + capitalize(typeName) + "Validator"
+}
+
+func withAuditSinkName(typeName string) string {
+	if isExported(typeName) {
+		return "With" + typeName + "AuditSink"
+	}
+	return "with" + capitalize(typeName) + "AuditSink"
+}
+
+func withTracerProviderName(typeName string) string {
+	if isExported(typeName) {
+		return "With" + typeName + "TracerProvider"
+	}
+	return "with" + capitalize(typeName) + "TracerProvider"
+}
+
+func errValidationName(typeName string) string {
+	if isExported(typeName) {
+		return "Err" + typeName + "ValidationFailed"
+	}
+	return "err" + capitalize(typeName) + "ValidationFailed"
+}
+
+func groupConstName(typeName, group string) string {
+	return typeName + "Group" + capitalize(group)
+}
+
+func capitalize(name string) string {
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
 func isLocalStruct(f codegen.FieldInfo) bool {
 	return f.IsStruct && f.TypePkg == "" && !f.IsSlice && !f.IsMap
 }
@@ -165,11 +599,8 @@ func generateLayerBrokerTestFile(cfg codegen.GeneratorConfig, info *codegen.Stru
 	outputFile := filepath.Join(cfg.OutputDir, baseName+"_layerbroker_test.go")
 
 	// Find first string and int fields for test examples
-	var stringField, intField string
+	stringField, intField := firstStringField(info), ""
 	for _, f := range info.Fields {
-		if stringField == "" && f.TypeName == "string" && !f.IsPointer && !f.IsSlice && !f.IsMap {
-			stringField = f.Name
-		}
 		if intField == "" && (f.TypeName == "int" || f.TypeName == "int32" || f.TypeName == "int64") && !f.IsPointer && !f.IsSlice && !f.IsMap {
 			intField = f.Name
 		}
@@ -178,7 +609,7 @@ func generateLayerBrokerTestFile(cfg codegen.GeneratorConfig, info *codegen.Stru
 	// Check if time.Time field exists
 	needsTime := false
 	for _, f := range info.Fields {
-		if f.TypePkg == "time" {
+		if f.TypePkg == "time" && f.TypeName == "Time" {
 			needsTime = true
 			break
 		}
@@ -191,12 +622,32 @@ func generateLayerBrokerTestFile(cfg codegen.GeneratorConfig, info *codegen.Stru
 		IntField:     intField,
 		Fields:       info.Fields,
 		GenerateJSON: cfg.GenerateJSON,
+		Clear:        cfg.GenerateClear,
+		Provenance:   cfg.GenerateProvenance,
+		Audit:        cfg.GenerateAudit,
+		Tracing:      cfg.GenerateTracing,
+		History:      cfg.History,
+		Groups:       cfg.LayerGroups,
 		NeedsTime:    needsTime,
 	}
-	gen := codegen.NewTemplateGenerator(templateFuncs())
+	if err := codegen.WriteHelpers(cfg, codegen.HelperPtr); err != nil {
+		return err
+	}
+	gen := codegen.NewTemplateGenerator(cfg, templateFuncs())
 	return gen.GenerateFile(outputFile, layerBrokerTestTemplate, data)
 }
 
+// firstStringField returns the first plain string field of info that isn't secret,
+// used by test examples.
+func firstStringField(info *codegen.StructInfo) string {
+	for _, f := range info.Fields {
+		if f.TypeName == "string" && !f.IsPointer && !f.IsSlice && !f.IsMap && !f.Secret {
+			return f.Name
+		}
+	}
+	return ""
+}
+
 type testTemplateData struct {
 	Package      string
 	TypeName     string
@@ -204,5 +655,12 @@ type testTemplateData struct {
 	IntField     string
 	Fields       []codegen.FieldInfo
 	GenerateJSON bool
+	Clear        bool
+	Provenance   bool
+	Audit        bool
+	History      int
+	Groups       []string
 	NeedsTime    bool
+	Secrets      []secretPath // Masked by the HTTP handler
+	Tracing      bool         // Trace file loads, for the file watcher
 }
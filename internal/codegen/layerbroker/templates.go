@@ -33,14 +33,14 @@ const layerBrokerTemplate = `// Code generated by sudo-gen layerbroker. DO NOT E
 //
 //	// Create a layer (e.g., for file-based config)
 //	fileLayer := broker.Layer()
-//	fileLayer.Set(&{{.TypeName}}Partial{Name: ptr("from-file")})
+//	fileLayer.Set(&{{partial .TypeName}}{Name: ptr("from-file")})
 //
 //	// Create another layer (e.g., for environment variables)
 //	envLayer := broker.Layer()
-//	envLayer.Set(&{{.TypeName}}Partial{Name: ptr("from-env")})
+//	envLayer.Set(&{{partial .TypeName}}{Name: ptr("from-env")})
 //
 //	// Later updates from any layer are applied immediately
-//	fileLayer.Set(&{{.TypeName}}Partial{Name: ptr("updated-from-file")})
+//	fileLayer.Set(&{{partial .TypeName}}{Name: ptr("updated-from-file")})
 //
 // # Subscribing to Field Changes
 //
@@ -56,7 +56,24 @@ const layerBrokerTemplate = `// Code generated by sudo-gen layerbroker. DO NOT E
 //	defer unsub() // Clean up when done
 //
 // Subscribers are only notified when the value actually changes. Setting the same
-// value again does not trigger a notification.
+// value again does not trigger a notification. Subscribe observes the whole config
+// instead of a single field.
+//
+// # Validation
+//
+// Pass {{withValidator .TypeName}} to check every config a layer change would produce.
+// A change that fails validation is undone, the current config stays in place, and
+// the layer method returns a *{{.TypeName}}ValidationError naming the layer:
+//
+//	broker := {{newBroker .TypeName}}(nil, {{withValidator .TypeName}}(func(cfg {{.TypeName}}) error {
+//	    if cfg.Name == "" {
+//	        return errors.New("name is required")
+//	    }
+//	    return nil
+//	}))
+//	if err := broker.Layer().Named("env").Set(envPartial); errors.Is(err, {{errValidation .TypeName}}) {
+//	    log.Println("rejected:", err)
+//	}
 //
 // # Thread Safety
 //
@@ -69,25 +86,44 @@ const layerBrokerTemplate = `// Code generated by sudo-gen layerbroker. DO NOT E
 // # Dependencies
 //
 // This generated code requires the following to also be generated:
-//   - {{.TypeName}}Partial (from: sudo-gen merge)
-//   - {{.TypeName}}.Copy() (from: sudo-gen copy)
+//   - {{partial .TypeName}} (from: sudo-gen merge)
+//   - {{.TypeName}}.{{method "Copy"}}() and {{method "CopyInto"}}() (from: sudo-gen copy)
 package {{.Package}}
 
 import (
+{{- if .Tracing}}
+	"context"
+{{- end}}
 {{- if .GenerateJSON}}
 	"encoding/json"
+{{- end}}
+	"errors"
+{{- if .History}}
+	"fmt"
 {{- end}}
 {{- if .NeedsReflectImport}}
 	"reflect"
+{{- end}}
+	"slices"
+	"strconv"
+{{- if .Explain}}
+	"strings"
 {{- end}}
 	"sync"
 	"sync/atomic"
-{{- if .NeedsTimeImport}}
+{{- if or .NeedsTimeImport .History .Audit}}
 	"time"
 {{- end}}
 {{- range .ExternalImports}}
 	{{if .Alias}}{{.Alias}} {{end}}"{{.Path}}"
 {{- end}}
+{{- if .Tracing}}
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+{{- end}}
 )
 
 // {{brokerType .TypeName}} provides thread-safe access to {{.TypeName}} with ordered layer updates and subscriptions.
@@ -97,42 +133,240 @@ type {{brokerType .TypeName}} struct {
 	mu        sync.Mutex // protects subscribers, layers, and serializes writes
 	nextSubID int
 	layers    []*{{layerType .TypeName}}
+	created   int // Number of layers ever created, for default layer names
+	validate  func({{.TypeName}}) error // Set by {{withValidator .TypeName}}
+{{- if .Audit}}
+	audit     {{.TypeName}}AuditSink // Set by {{withAuditSink .TypeName}}
+{{- end}}
+{{- if .Tracing}}
+	tracer    trace.Tracer // Set by {{withTracerProvider .TypeName}}
+{{- end}}
+{{- if .History}}
+	history   [{{.History}}]{{.TypeName}}Snapshot // Ring buffer of recent configs, see History
+	snapshots int                 // Number of configs ever recorded in history
+{{- end}}
+	subscribers map[int]func(*{{.TypeName}})
 {{- range .Fields}}
 	subs{{.Name}} map[int]func({{if .IsPointer}}*{{end}}{{if .TypePkg}}{{.TypePkg}}.{{end}}{{.TypeName}})
 {{- end}}
 }
 
+// {{errValidation .TypeName}} is matched by every error returned for a layer change
+// that the validator of a {{brokerType .TypeName}} rejected.
+var {{errValidation .TypeName}} = errors.New("config validation failed")
+
+// {{.TypeName}}ValidationError reports a layer change rejected by the validator of a
+// {{brokerType .TypeName}}. It matches {{errValidation .TypeName}} with errors.Is and
+// unwraps to the error returned by the validator.
+type {{.TypeName}}ValidationError struct {
+	Layer string // Name of the layer whose change was rejected
+	Err   error
+}
+
+func (e *{{.TypeName}}ValidationError) Error() string {
+	return "layer " + strconv.Quote(e.Layer) + ": " + {{errValidation .TypeName}}.Error() + ": " + e.Err.Error()
+}
+
+func (e *{{.TypeName}}ValidationError) Is(target error) bool {
+	return target == {{errValidation .TypeName}}
+}
+
+func (e *{{.TypeName}}ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// {{brokerType .TypeName}}Option configures a broker created by {{newBroker .TypeName}}.
+type {{brokerType .TypeName}}Option func(*{{brokerType .TypeName}})
+
+// {{withValidator .TypeName}} makes the broker check every config a layer change
+// produces with validate. A change that fails validation is undone, keeping the
+// current config, and the layer method returns a *{{.TypeName}}ValidationError. Changes
+// that leave the config as it is are not validated, and neither is the initial config.
+func {{withValidator .TypeName}}(validate func({{.TypeName}}) error) {{brokerType .TypeName}}Option {
+	return func(b *{{brokerType .TypeName}}) {
+		b.validate = validate
+	}
+}
+{{- if .Audit}}
+
+// {{.TypeName}}AuditEntry records a layer change the broker applied.
+type {{.TypeName}}AuditEntry struct {
+	Layer   string                 // Name of the layer (see Named)
+	Actor   string                 // Who changed the layer (see As); empty if unknown
+	Time    time.Time              // When the change took effect
+	Changes []{{.TypeName}}FieldChange // Top-level fields the change altered; empty if it altered none
+}
+
+// {{.TypeName}}AuditSink receives an entry for every layer change a {{brokerType .TypeName}}
+// applies, including the changes that leave the config as it is, such as values a
+// higher layer overrides. Changes rejected by the validator are not audited.
+type {{.TypeName}}AuditSink interface {
+	// Audit is called with the broker locked, in the order the changes are applied,
+	// so it must not call the broker. The values of entry must not be modified.
+	Audit(entry {{.TypeName}}AuditEntry)
+}
+
+// {{.TypeName}}AuditFunc adapts a function to a {{.TypeName}}AuditSink.
+type {{.TypeName}}AuditFunc func(entry {{.TypeName}}AuditEntry)
+
+// Audit calls f(entry).
+func (f {{.TypeName}}AuditFunc) Audit(entry {{.TypeName}}AuditEntry) {
+	f(entry)
+}
+
+// {{withAuditSink .TypeName}} makes the broker report every layer change it applies to
+// sink, along with the fields the change altered.
+{{- if .AuditSecrets}} Fields holding values tagged
+// sudo:"secret" are reported with both values replaced by "[REDACTED]".
+{{- end}}
+func {{withAuditSink .TypeName}}(sink {{.TypeName}}AuditSink) {{brokerType .TypeName}}Option {
+	return func(b *{{brokerType .TypeName}}) {
+		b.audit = sink
+	}
+}
+{{- end}}
+{{- if .Tracing}}
+
+// {{withTracerProvider .TypeName}} records OpenTelemetry spans with a tracer from tp, so
+// config changes show up in traces: "{{brokerType .TypeName}}.merge" for recomputing and
+// validating the config after a layer change, and "{{brokerType .TypeName}}.notify" for
+// calling subscribers. Pass a context to SetContext or ReplaceContext to make them part
+// of a trace. Without this option, nothing is traced.
+func {{withTracerProvider .TypeName}}(tp trace.TracerProvider) {{brokerType .TypeName}}Option {
+	return func(b *{{brokerType .TypeName}}) {
+		b.tracer = tp.Tracer("{{brokerType .TypeName}}")
+	}
+}
+{{- end}}
+
 // {{newBroker .TypeName}} creates a new LayerBroker wrapping the given config.
 // If cfg is nil, an empty config is used.
-func {{newBroker .TypeName}}(cfg *{{.TypeName}}) *{{brokerType .TypeName}} {
+func {{newBroker .TypeName}}(cfg *{{.TypeName}}, opts ...{{brokerType .TypeName}}Option) *{{brokerType .TypeName}} {
 	if cfg == nil {
 		cfg = &{{.TypeName}}{}
 	}
 	b := &{{brokerType .TypeName}}{
-		base: cfg.Copy(),
+		base: cfg.{{method "Copy"}}(),
+{{- if .Tracing}}
+		tracer: noop.NewTracerProvider().Tracer(""),
+{{- end}}
+		subscribers: make(map[int]func(*{{.TypeName}})),
 {{- range .Fields}}
 		subs{{.Name}}: make(map[int]func({{if .IsPointer}}*{{end}}{{if .TypePkg}}{{.TypePkg}}.{{end}}{{.TypeName}})),
 {{- end}}
 	}
-	b.config.Store(cfg.Copy())
+	for _, opt := range opts {
+		opt(b)
+	}
+	b.config.Store(cfg.{{method "Copy"}}())
+{{- if .History}}
+	b.record(b.config.Load())
+{{- end}}
 	return b
 }
 
 // Get returns a deep copy of the current configuration.
 // This is a lock-free operation using atomic pointer load.
 func (b *{{brokerType .TypeName}}) Get() *{{.TypeName}} {
-	return b.config.Load().Copy()
+	return b.config.Load().{{method "Copy"}}()
 }
 
-// Layer returns a new layer for applying partial changes.
+// Ranks order the layers of a broker: a layer is always above every layer of a lower
+// rank, and above the earlier layers of its own rank.{{if .Groups}} Each group is a rank of its
+// own, below the layers created by Layer.{{end}}
+const (
+	{{lower .TypeName}}RankLayer = {{if .Groups}}{{len .Groups}} + {{end}}iota
+	{{lower .TypeName}}RankTop
+)
+
+// Layer returns a new layer for applying partial changes.{{if .Groups}} It takes priority
+// over the layers of every group.{{end}}
 func (b *{{brokerType .TypeName}}) Layer() *{{layerType .TypeName}} {
+	return b.newLayer({{lower .TypeName}}RankLayer)
+}
+
+// TopLayer returns a new layer that takes priority over every layer created by Layer,
+// including those created after it, for overrides that must always win. Top layers
+// are ordered among themselves like other layers, the most recent one winning.
+func (b *{{brokerType .TypeName}}) TopLayer() *{{layerType .TypeName}} {
+	return b.newLayer({{lower .TypeName}}RankTop)
+}
+{{- if .Groups}}
+
+// {{.TypeName}}LayerGroup is a group of broker layers with a fixed priority relative to
+// the other groups. Layers within a group are ordered like other layers, the most
+// recent one winning.
+type {{.TypeName}}LayerGroup int
+
+// Layer groups, lowest priority first.
+const (
+{{- range $i, $g := .Groups}}
+	{{groupConst $.TypeName $g}}{{if eq $i 0}} {{$.TypeName}}LayerGroup = iota{{end}}
+{{- end}}
+)
+
+// String returns the name of the group.
+func (g {{.TypeName}}LayerGroup) String() string {
+	switch g {
+{{- range .Groups}}
+	case {{groupConst $.TypeName .}}:
+		return "{{.}}"
+{{- end}}
+	}
+	return "{{.TypeName}}LayerGroup(" + strconv.Itoa(int(g)) + ")"
+}
+
+// GroupLayer returns a new layer in group. It takes priority over the layers of lower
+// groups and the earlier layers of its own group, whenever they were created, and is
+// overridden by the layers of higher groups and those created by Layer and TopLayer.
+func (b *{{brokerType .TypeName}}) GroupLayer(group {{.TypeName}}LayerGroup) *{{layerType .TypeName}} {
+	if group < 0 || group >= {{len .Groups}} {
+		panic("unknown layer group " + group.String())
+	}
+	return b.newLayer(int(group))
+}
+{{- end}}
+
+// newLayer returns a new layer of the given rank.
+func (b *{{brokerType .TypeName}}) newLayer(rank int) *{{layerType .TypeName}} {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	l := &{{layerType .TypeName}}{broker: b}
-	b.layers = append(b.layers, l)
+	l := &{{layerType .TypeName}}{broker: b, rank: rank}
+	b.created++
+	l.name = "layer " + strconv.Itoa(b.created)
+	b.layers = {{lower .TypeName}}InsertLayer(b.layers, l)
 	return l
 }
 
+// {{lower .TypeName}}InsertLayer inserts l into layers above every layer of the same or a
+// lower rank.
+func {{lower .TypeName}}InsertLayer(layers []*{{layerType .TypeName}}, l *{{layerType .TypeName}}) []*{{layerType .TypeName}} {
+	i := len(layers)
+	for i > 0 && layers[i-1].rank > l.rank {
+		i--
+	}
+	return slices.Insert(layers, i, l)
+}
+
+// Subscribe subscribes to changes anywhere in the configuration. The callback is
+// invoked immediately with the current configuration, and with the new configuration
+// whenever a change alters it. The configuration passed to callback is shared and
+// must not be modified. Returns an unsubscribe function.
+func (b *{{brokerType .TypeName}}) Subscribe(callback func(*{{.TypeName}})) func() {
+	b.mu.Lock()
+	id := b.nextSubID
+	b.nextSubID++
+	b.subscribers[id] = callback
+	cfg := b.config.Load()
+	b.mu.Unlock()
+	callback(cfg)
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers, id)
+	}
+}
+
 {{range .Fields}}
 // Subscribe{{.Name}} subscribes to changes on {{.Name}}.
 // The callback is invoked immediately if the value is non-zero, and on future changes.
@@ -186,45 +420,164 @@ func (b *{{brokerType $.TypeName}}) Subscribe{{.Name}}(callback func({{if .IsPoi
 // {{layerType .TypeName}} applies partial updates to the LayerBroker.
 type {{layerType .TypeName}} struct {
 	broker  *{{brokerType .TypeName}}
-	partial *{{.TypeName}}Partial
+	partial *{{partial .TypeName}}
+	rank    int // See {{lower .TypeName}}RankLayer
+{{- if .History}}
+	snapshot *{{.TypeName}} // Config the layer replaces all lower layers with, set by Rollback
+{{- end}}
+	name    string // See Named
+{{- if .Audit}}
+	actor   string // See As
+{{- end}}
 }
 
 // Set applies the partial and notifies subscribers for changed fields.
 // Uses copy-on-write: copies the config, applies changes, then atomically swaps.
-func (l *{{layerType .TypeName}}) Set(p *{{.TypeName}}Partial) {
+// If the broker's validator rejects the result, the layer is left as it was and a
+// *{{.TypeName}}ValidationError is returned.
+func (l *{{layerType .TypeName}}) Set(p *{{partial .TypeName}}) error {
+{{- if .Tracing}}
+	return l.SetContext(context.Background(), p)
+}
+
+// SetContext is Set, recording the spans of the change as children of the span in ctx
+// (see {{withTracerProvider .TypeName}}).
+func (l *{{layerType .TypeName}}) SetContext(ctx context.Context, p *{{partial .TypeName}}) error {
+{{- end}}
 	if p == nil {
-		return
+		return nil
 	}
 	l.broker.mu.Lock()
 	defer l.broker.mu.Unlock()
-	if l.partial == nil {
-		l.partial = &{{.TypeName}}Partial{}
+	prev := l.partial
+	l.partial = &{{partial .TypeName}}{}
+	if prev != nil {
+		*l.partial = *prev
 	}
 	l.mergePartial(p)
-	newCfg := l.broker.recompute()
-	oldCfg := l.broker.config.Load()
+	if err := l.broker.publish({{if .Tracing}}ctx, {{end}}l); err != nil {
+		l.partial = prev
+		return err
+	}
+	return nil
+}
+
+// Replace discards everything previously set on the layer and applies p in its
+// place, notifying subscribers for changed fields. A nil p clears the layer. If the
+// broker's validator rejects the result, the layer keeps its previous contents.
+func (l *{{layerType .TypeName}}) Replace(p *{{partial .TypeName}}) error {
+{{- if .Tracing}}
+	return l.ReplaceContext(context.Background(), p)
+}
+
+// ReplaceContext is Replace, recording the spans of the change as children of the span
+// in ctx (see {{withTracerProvider .TypeName}}).
+func (l *{{layerType .TypeName}}) ReplaceContext(ctx context.Context, p *{{partial .TypeName}}) error {
+{{- end}}
+	l.broker.mu.Lock()
+	defer l.broker.mu.Unlock()
+	prev := l.partial
+	l.partial = p
+	if err := l.broker.publish({{if .Tracing}}ctx, {{end}}l); err != nil {
+		l.partial = prev
+		return err
+	}
+	return nil
+}
+
+// Remove detaches the layer from the broker, so its values no longer contribute to
+// the config, and notifies subscribers for fields that change as a result. Calling
+// Set on a removed layer has no effect on the config. If the broker's validator
+// rejects the config without the layer, the layer stays attached.
+func (l *{{layerType .TypeName}}) Remove() error {
+	l.broker.mu.Lock()
+	defer l.broker.mu.Unlock()
+	i := slices.Index(l.broker.layers, l)
+	if i < 0 {
+		return nil
+	}
+	l.broker.layers = slices.Delete(l.broker.layers, i, i+1)
+	if err := l.broker.publish({{if .Tracing}}context.Background(), {{end}}l); err != nil {
+		l.broker.layers = slices.Insert(l.broker.layers, i, l)
+		return err
+	}
+	return nil
+}
+
+// publish recomputes the config, notifies subscribers for changed fields and stores
+// the result. If the config changed and the validator rejects it, nothing is stored
+// and the error is reported against layer. The caller must hold b.mu.
+func (b *{{brokerType .TypeName}}) publish({{if .Tracing}}ctx context.Context, {{end}}layer *{{layerType .TypeName}}) error {
+{{- if .Tracing}}
+	_, span := b.tracer.Start(ctx, "{{brokerType .TypeName}}.merge", trace.WithAttributes(attribute.String("config.layer", layer.name)))
+{{- end}}
+	newCfg := b.recompute(b.layers)
+	oldCfg := b.config.Load()
+	if b.validate != nil && !{{equal "oldCfg" "newCfg"}} {
+		if err := b.validate(*newCfg); err != nil {
+{{- if .Tracing}}
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "config rejected by validator")
+			span.End()
+{{- end}}
+			return &{{.TypeName}}ValidationError{Layer: layer.name, Err: err}
+		}
+	}
+{{- if .Tracing}}
+	span.End()
+	_, span = b.tracer.Start(ctx, "{{brokerType .TypeName}}.notify")
+	defer span.End()
+{{- end}}
 {{- range .Fields}}
 {{- if not (and .IsPointer (isLocalStruct .))}}
 	if old, new := oldCfg.{{.Name}}, newCfg.{{.Name}}; !{{lower $.TypeName}}Equal{{.Name}}(old, new) {
-		for _, cb := range l.broker.subs{{.Name}} {
+		for _, cb := range b.subs{{.Name}} {
 			cb(new)
 		}
 	}
 {{- end}}
 {{- end}}
-	l.broker.config.Store(newCfg)
+	b.config.Store(newCfg)
+	if !{{equal "oldCfg" "newCfg"}} {
+{{- if .History}}
+		b.record(newCfg)
+{{- end}}
+		for _, cb := range b.subscribers {
+			cb(newCfg)
+		}
+	}
+{{- if .Audit}}
+	if b.audit != nil {
+		b.audit.Audit({{.TypeName}}AuditEntry{
+			Layer:   layer.name,
+			Actor:   layer.actor,
+			Time:    time.Now(),
+			Changes: {{lower .TypeName}}AuditChanges(oldCfg, newCfg),
+		})
+	}
+{{- end}}
+	return nil
 }
 
 {{- range .Fields}}
 {{- if not (and .IsPointer (isLocalStruct .))}}
 func {{lower $.TypeName}}Equal{{.Name}}(a, b {{.Type}}) bool {
-{{- if .IsSlice}}
+{{- if and .Recipe .Recipe.Equal}}
+{{- if .Recipe.Nilable}}
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+{{- end}}
+	return {{.Recipe.EqualOf "a" "b"}}
+{{- else if .IsBytes}}
+	return bytes.Equal(a, b)
+{{- else if .IsSlice}}
 	if len(a) != len(b) {
 		return false
 	}
 	for i := range a {
 {{- if and .StructTypeName (eq .TypePkg "")}}
-		if !a[i].Equal(&b[i]) {
+		if !a[i].{{method "Equal"}}(&b[i]) {
 			return false
 		}
 {{- else}}
@@ -252,7 +605,7 @@ func {{lower $.TypeName}}Equal{{.Name}}(a, b {{.Type}}) bool {
 {{- else if and (eq .TypePkg "time") (eq .TypeName "Time")}}
 	return a.Equal(b)
 {{- else if and .IsStruct (not .IsPointer) (eq .TypePkg "")}}
-	return a.Equal(&b)
+	return a.{{method "Equal"}}(&b)
 {{- else}}
 	return a == b
 {{- end}}
@@ -261,7 +614,13 @@ func {{lower $.TypeName}}Equal{{.Name}}(a, b {{.Type}}) bool {
 {{- end}}
 
 // mergePartial merges the given partial into the layer's accumulated partial.
-func (l *{{layerType .TypeName}}) mergePartial(p *{{.TypeName}}Partial) {
+func (l *{{layerType .TypeName}}) mergePartial(p *{{partial .TypeName}}) {
+{{- if .Clear}}
+	// Clears go first, so that values set by p take precedence as in ApplyPartial
+	for _, name := range p.Clear {
+		l.partial.ClearField(name)
+	}
+{{- end}}
 {{- range .Fields}}
 	if p.{{.Name}} != nil {
 		l.partial.{{.Name}} = p.{{.Name}}
@@ -269,21 +628,253 @@ func (l *{{layerType .TypeName}}) mergePartial(p *{{.TypeName}}Partial) {
 {{- end}}
 }
 
-// recompute rebuilds the config from base and all layer partials.
-func (b *{{brokerType .TypeName}}) recompute() *{{.TypeName}} {
-	cfg := b.base.Copy()
+
+// Named sets the name the layer is reported under, by validation errors{{if .Explain}} and
+// Explain{{end}}, and returns the layer. Layers are called "layer 1", "layer 2", ... in
+// order of creation until they are named.
+func (l *{{layerType .TypeName}}) Named(name string) *{{layerType .TypeName}} {
+	l.broker.mu.Lock()
+	defer l.broker.mu.Unlock()
+	l.name = name
+	return l
+}
+
+// Name returns the name of the layer (see Named).
+func (l *{{layerType .TypeName}}) Name() string {
+	l.broker.mu.Lock()
+	defer l.broker.mu.Unlock()
+	return l.name
+}
+{{- if .Audit}}
+
+// As sets who the following changes of the layer are audited as, such as the user
+// of an admin API, and returns the layer.
+func (l *{{layerType .TypeName}}) As(actor string) *{{layerType .TypeName}} {
+	l.broker.mu.Lock()
+	defer l.broker.mu.Unlock()
+	l.actor = actor
+	return l
+}
+{{- end}}
+{{- if .Explain}}
+
+// Explain returns the name of the layer providing the current value of each field set
+// by a layer, keyed by the dotted path of the field as in ApplySparse ("Database.Host").
+// Fields that are missing have the value of the base config. A nested struct that a
+// layer cleared or replaced as a whole is reported under its own path, and slices and
+// maps under the last layer that set them.
+func (b *{{brokerType .TypeName}}) Explain() map[string]string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	sources := make(map[string]string)
 	for _, layer := range b.layers {
+{{- if .History}}
+		if layer.snapshot != nil {
+			clear(sources)
+{{- range .Fields}}
+			sources["{{.Name}}"] = layer.name
+{{- end}}
+		}
+{{- end}}
+		if layer.partial != nil {
+			{{lower .TypeName}}Explain{{.TypeName}}(sources, "", layer.partial, layer.name)
+		}
+	}
+	return sources
+}
+{{- range .Explain}}
+
+// {{lower $.TypeName}}Explain{{.Name}} records layer as the source of the fields p sets.
+func {{lower $.TypeName}}Explain{{.Name}}(sources map[string]string, prefix string, p *{{partial .Name}}, layer string) {
+{{- if $.Clear}}
+	for _, name := range p.Clear {
+		{{lower $.TypeName}}ExplainSet(sources, prefix+name, layer)
+	}
+{{- end}}
+{{- range .Fields}}
+	if p.{{.Name}} != nil {
+{{- if not .Nested}}
+		{{lower $.TypeName}}ExplainSet(sources, prefix+"{{.Name}}", layer)
+{{- else}}
+{{- if .Replace}}
+		{{lower $.TypeName}}ExplainSet(sources, prefix+"{{.Name}}", layer)
+{{- end}}
+		{{lower $.TypeName}}Explain{{.Nested}}(sources, prefix+"{{.Name}}.", p.{{.Name}}, layer)
+{{- end}}
+	}
+{{- end}}
+}
+{{- end}}
+
+// {{lower .TypeName}}ExplainSet records layer as the source of path, which replaces the
+// sources of any fields nested in it.
+func {{lower .TypeName}}ExplainSet(sources map[string]string, path, layer string) {
+	for p := range sources {
+		if strings.HasPrefix(p, path+".") {
+			delete(sources, p)
+		}
+	}
+	sources[path] = layer
+}
+{{- end}}
+
+// recompute rebuilds the config from base and the partials of layers.
+func (b *{{brokerType .TypeName}}) recompute(layers []*{{layerType .TypeName}}) *{{.TypeName}} {
+	cfg := b.base.{{method "Copy"}}()
+	for _, layer := range layers {
+{{- if .History}}
+		if layer.snapshot != nil {
+			// cfg isn't published yet, so its slices and maps can be reused
+			layer.snapshot.{{method "CopyInto"}}(cfg)
+		}
+{{- end}}
 		if layer.partial != nil {
-			cfg.ApplyPartial(layer.partial)
+			cfg.{{method "ApplyPartial"}}(layer.partial)
 		}
 	}
 	return cfg
 }
+
+// {{.TypeName}}FieldChange is a top-level field of {{.TypeName}} whose value a change alters.
+type {{.TypeName}}FieldChange struct {
+	Field string ` + "`" + `json:"field"` + "`" + `
+	Old   any    ` + "`" + `json:"old"` + "`" + `
+	New   any    ` + "`" + `json:"new"` + "`" + `
+}
+
+// PreviewLayer returns the config that setting p on the layer called name would
+// produce, and the fields that would change, without applying anything. If several
+// layers have the name the highest one is previewed, and if none has it, a new layer
+// created by Layer is. When the broker's validator rejects the previewed config, its
+// *{{.TypeName}}ValidationError is returned along with the preview, so that a confirm
+// step can show what would be rejected.
+func (b *{{brokerType .TypeName}}) PreviewLayer(name string, p *{{partial .TypeName}}) (*{{.TypeName}}, []{{.TypeName}}FieldChange, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	preview := &{{layerType .TypeName}}{broker: b, partial: &{{partial .TypeName}}{}, rank: {{lower .TypeName}}RankLayer, name: name}
+	layers := slices.Clone(b.layers)
+	i := len(layers) - 1
+	for i >= 0 && layers[i].name != name {
+		i--
+	}
+	if i >= 0 {
+		if layers[i].partial != nil {
+			*preview.partial = *layers[i].partial
+		}
+{{- if .History}}
+		preview.snapshot = layers[i].snapshot
+{{- end}}
+		layers[i] = preview
+	} else {
+		layers = {{lower .TypeName}}InsertLayer(layers, preview)
+	}
+	if p != nil {
+		preview.mergePartial(p)
+	}
+	oldCfg := b.config.Load()
+	newCfg := b.recompute(layers)
+	changes := {{lower .TypeName}}Changes(oldCfg, newCfg)
+	if b.validate != nil && !{{equal "oldCfg" "newCfg"}} {
+		if err := b.validate(*newCfg); err != nil {
+			return newCfg, changes, &{{.TypeName}}ValidationError{Layer: name, Err: err}
+		}
+	}
+	return newCfg, changes, nil
+}
+
+// {{lower .TypeName}}Changes returns the top-level fields whose values differ between
+// old and new.
+func {{lower .TypeName}}Changes(old, new *{{.TypeName}}) []{{.TypeName}}FieldChange {
+	var changes []{{.TypeName}}FieldChange
+{{- range .Fields}}
+{{- if and .IsPointer (isLocalStruct .)}}
+	if !{{equal (print "old." .Name) (print "new." .Name)}} {
+{{- else}}
+	if !{{lower $.TypeName}}Equal{{.Name}}(old.{{.Name}}, new.{{.Name}}) {
+{{- end}}
+		changes = append(changes, {{$.TypeName}}FieldChange{Field: "{{.Name}}", Old: old.{{.Name}}, New: new.{{.Name}}})
+	}
+{{- end}}
+	return changes
+}
+{{- if .Audit}}
+{{- if .AuditSecrets}}
+
+// {{lower .TypeName}}AuditRedacted replaces the values of fields holding secrets in audit entries.
+const {{lower .TypeName}}AuditRedacted = "[REDACTED]"
+{{- end}}
+
+// {{lower .TypeName}}AuditChanges returns the changes between old and new reported to
+// the audit sink{{if .AuditSecrets}}, with the values of fields holding secrets redacted{{end}}.
+func {{lower .TypeName}}AuditChanges(old, new *{{.TypeName}}) []{{.TypeName}}FieldChange {
+	changes := {{lower .TypeName}}Changes(old, new)
+{{- if .AuditSecrets}}
+	for i, change := range changes {
+		switch change.Field {
+		case {{range $i, $f := .AuditSecrets}}{{if $i}}, {{end}}"{{$f}}"{{end}}:
+			changes[i].Old, changes[i].New = {{lower .TypeName}}AuditRedacted, {{lower .TypeName}}AuditRedacted
+		}
+	}
+{{- end}}
+	return changes
+}
+{{- end}}
+{{- if .History}}
+
+// {{.TypeName}}Snapshot is a merged configuration recorded in the history of a
+// {{brokerType .TypeName}}.
+type {{.TypeName}}Snapshot struct {
+	Config *{{.TypeName}}
+	Time   time.Time // When the config took effect
+}
+
+// record adds cfg to the history, dropping the oldest snapshot once it holds
+// {{.History}}. The caller must hold b.mu.
+func (b *{{brokerType .TypeName}}) record(cfg *{{.TypeName}}) {
+	b.history[b.snapshots%len(b.history)] = {{.TypeName}}Snapshot{Config: cfg, Time: time.Now()}
+	b.snapshots++
+}
+
+// History returns the last {{.History}} merged configurations, newest first, so that
+// History()[0] is the current config and History()[n] is the one Rollback(n) restores.
+func (b *{{brokerType .TypeName}}) History() []{{.TypeName}}Snapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := min(b.snapshots, len(b.history))
+	history := make([]{{.TypeName}}Snapshot, n)
+	for i := range history {
+		s := b.history[(b.snapshots-1-i)%len(b.history)]
+		history[i] = {{.TypeName}}Snapshot{Config: s.Config.{{method "Copy"}}(), Time: s.Time}
+	}
+	return history
+}
+
+// Rollback restores the config from n changes ago (see History) by adding a top layer
+// that replaces every layer below it with that snapshot. Removing the returned layer
+// undoes the rollback. Layers created with TopLayer after it still apply on top. The
+// layer is not added if the broker's validator rejects the restored config.
+func (b *{{brokerType .TypeName}}) Rollback(n int) (*{{layerType .TypeName}}, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if kept := min(b.snapshots, len(b.history)); n < 1 || n >= kept {
+		return nil, fmt.Errorf("cannot roll back %d changes: history holds %d previous configs", n, kept-1)
+	}
+	l := &{{layerType .TypeName}}{broker: b, rank: {{lower .TypeName}}RankTop, snapshot: b.history[(b.snapshots-1-n)%len(b.history)].Config}
+	b.created++
+	l.name = "rollback " + strconv.Itoa(n)
+	b.layers = {{lower .TypeName}}InsertLayer(b.layers, l)
+	if err := b.publish({{if .Tracing}}context.Background(), {{end}}l); err != nil {
+		b.layers = slices.DeleteFunc(b.layers, func(layer *{{layerType .TypeName}}) bool { return layer == l })
+		return nil, err
+	}
+	return l, nil
+}
+{{- end}}
 {{if .GenerateJSON}}
 // {{brokerType .TypeName}}State represents the serializable state of the broker.
 type {{brokerType .TypeName}}State struct {
 	Base   *{{.TypeName}}          ` + "`" + `json:"base"` + "`" + `
-	Layers []*{{.TypeName}}Partial ` + "`" + `json:"layers"` + "`" + `
+	Layers []*{{partial .TypeName}} ` + "`" + `json:"layers"` + "`" + `
 	Final  *{{.TypeName}}          ` + "`" + `json:"final"` + "`" + `
 }
 
@@ -291,7 +882,7 @@ type {{brokerType .TypeName}}State struct {
 func (b *{{brokerType .TypeName}}) MarshalJSON() ([]byte, error) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	layers := make([]*{{.TypeName}}Partial, 0, len(b.layers))
+	layers := make([]*{{partial .TypeName}}, 0, len(b.layers))
 	for _, layer := range b.layers {
 		layers = append(layers, layer.partial)
 	}
@@ -310,18 +901,35 @@ const layerBrokerTestTemplate = `// Code generated by sudo-gen layerbroker. DO N
 package {{.Package}}
 
 import (
+{{- if and .Tracing .StringField}}
+	"context"
+{{- end}}
 {{- if .GenerateJSON}}
 	"encoding/json"
+{{- end}}
+{{- if .StringField}}
+	"errors"
+{{- end}}
+{{- if and .Tracing .StringField}}
+	"slices"
+{{- end}}
+{{- if or (and .History .StringField) (and .StringField .IntField)}}
+	"strconv"
+{{- end}}
+{{- if and .StringField .IntField}}
+	"sync"
+	"sync/atomic"
 {{- end}}
 	"testing"
 {{- if .NeedsTime}}
 	"time"
 {{- end}}
-)
+{{- if and .Tracing .StringField}}
 
-func {{lower .TypeName}}Ptr[T any](v T) *T {
-	return &v
-}
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+{{- end}}
+)
 {{if and .StringField .IntField}}
 func Test{{brokerType .TypeName}}SubscriptionOrder(t *testing.T) {
 	broker := {{newBroker .TypeName}}(&{{.TypeName}}{ {{.StringField}}: "initial", {{.IntField}}: 8080})
@@ -342,7 +950,7 @@ func Test{{brokerType .TypeName}}SubscriptionOrder(t *testing.T) {
 	if len(intUpdates) != 1 || intUpdates[0] != 8080 {
 		t.Fatalf("expected initial {{.IntField}} callback, got %v", intUpdates)
 	}
-	layer1.Set(&{{.TypeName}}Partial{ {{.StringField}}: {{lower .TypeName}}Ptr("updated")})
+	layer1.Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("updated")})
 	if len(stringUpdates) != 2 || stringUpdates[1] != "updated" {
 		t.Fatalf("expected {{.StringField}} update, got %v", stringUpdates)
 	}
@@ -350,7 +958,7 @@ func Test{{brokerType .TypeName}}SubscriptionOrder(t *testing.T) {
 		t.Fatalf("{{.IntField}} subscriber should not have been called, got %v", intUpdates)
 	}
 	layer2 := broker.Layer()
-	layer2.Set(&{{.TypeName}}Partial{ {{.IntField}}: {{lower .TypeName}}Ptr(9090)})
+	layer2.Set(&{{partial .TypeName}}{ {{.IntField}}: sudogenPtr(9090)})
 	if len(intUpdates) != 2 || intUpdates[1] != 9090 {
 		t.Fatalf("expected {{.IntField}} update, got %v", intUpdates)
 	}
@@ -370,8 +978,8 @@ func Test{{brokerType .TypeName}}LowerLayerOverridden(t *testing.T) {
 	broker := {{newBroker .TypeName}}(nil)
 	layer1 := broker.Layer()
 	layer2 := broker.Layer()
-	layer1.Set(&{{.TypeName}}Partial{ {{.StringField}}: {{lower .TypeName}}Ptr("one")})
-	layer2.Set(&{{.TypeName}}Partial{ {{.StringField}}: {{lower .TypeName}}Ptr("two"), {{.IntField}}: {{lower .TypeName}}Ptr(8080)})
+	layer1.Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("one")})
+	layer2.Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("two"), {{.IntField}}: sudogenPtr(8080)})
 	var updates []string
 	unsub := broker.Subscribe{{.StringField}}(func(v string) {
 		updates = append(updates, v)
@@ -380,7 +988,7 @@ func Test{{brokerType .TypeName}}LowerLayerOverridden(t *testing.T) {
 	if len(updates) != 1 || updates[0] != "two" {
 		t.Fatalf("expected initial callback with 'two', got %v", updates)
 	}
-	layer1.Set(&{{.TypeName}}Partial{ {{.StringField}}: {{lower .TypeName}}Ptr("three")})
+	layer1.Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("three")})
 	if len(updates) != 1 {
 		t.Fatalf("expected no update when lower layer is overridden, got %v", updates)
 	}
@@ -397,9 +1005,9 @@ func Test{{brokerType .TypeName}}MultipleLayersPriority(t *testing.T) {
 	layer3 := broker.Layer()
 	
 	// Set same field in all layers - last layer should win
-	layer1.Set(&{{.TypeName}}Partial{ {{.StringField}}: {{lower .TypeName}}Ptr("layer1")})
-	layer2.Set(&{{.TypeName}}Partial{ {{.StringField}}: {{lower .TypeName}}Ptr("layer2")})
-	layer3.Set(&{{.TypeName}}Partial{ {{.StringField}}: {{lower .TypeName}}Ptr("layer3")})
+	layer1.Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("layer1")})
+	layer2.Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("layer2")})
+	layer3.Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("layer3")})
 	
 	cfg := broker.Get()
 	if cfg.{{.StringField}} != "layer3" {
@@ -407,13 +1015,32 @@ func Test{{brokerType .TypeName}}MultipleLayersPriority(t *testing.T) {
 	}
 	
 	// Update layer2, but layer3 still wins
-	layer2.Set(&{{.TypeName}}Partial{ {{.StringField}}: {{lower .TypeName}}Ptr("layer2-updated")})
+	layer2.Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("layer2-updated")})
 	cfg = broker.Get()
 	if cfg.{{.StringField}} != "layer3" {
 		t.Errorf("expected {{.StringField}}=layer3 (higher layer should still win), got %s", cfg.{{.StringField}})
 	}
 }
 
+func Test{{brokerType .TypeName}}TopLayer(t *testing.T) {
+	broker := {{newBroker .TypeName}}(nil)
+	top := broker.TopLayer()
+	top.Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("top")})
+	layer := broker.Layer()
+	layer.Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("layer")})
+	if got := broker.Get().{{.StringField}}; got != "top" {
+		t.Errorf("expected {{.StringField}}=top (top layer should win over later layers), got %s", got)
+	}
+	top.Remove()
+	if got := broker.Get().{{.StringField}}; got != "layer" {
+		t.Errorf("expected {{.StringField}}=layer after removing the top layer, got %s", got)
+	}
+	broker.Layer().Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("newest")})
+	if got := broker.Get().{{.StringField}}; got != "newest" {
+		t.Errorf("expected {{.StringField}}=newest, got %s", got)
+	}
+}
+
 func Test{{brokerType .TypeName}}MultipleSubscribers(t *testing.T) {
 	broker := {{newBroker .TypeName}}(&{{.TypeName}}{ {{.StringField}}: "initial"})
 	var updates1, updates2 []string
@@ -432,7 +1059,7 @@ func Test{{brokerType .TypeName}}MultipleSubscribers(t *testing.T) {
 		t.Fatalf("expected both subscribers to get initial value")
 	}
 	
-	broker.Layer().Set(&{{.TypeName}}Partial{ {{.StringField}}: {{lower .TypeName}}Ptr("updated")})
+	broker.Layer().Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("updated")})
 	
 	if len(updates1) != 2 || updates1[1] != "updated" {
 		t.Errorf("expected subscriber1 to get update, got %v", updates1)
@@ -443,6 +1070,115 @@ func Test{{brokerType .TypeName}}MultipleSubscribers(t *testing.T) {
 }
 {{end}}
 {{if .StringField}}
+{{- if .Clear}}
+
+func Test{{brokerType .TypeName}}ClearField(t *testing.T) {
+	broker := {{newBroker .TypeName}}(&{{.TypeName}}{ {{.StringField}}: "base"})
+	lower := broker.Layer()
+	lower.Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("lower")})
+	layer := broker.Layer()
+	p := &{{partial .TypeName}}{}
+	p.ClearField("{{.StringField}}")
+	layer.Set(p)
+	if got := broker.Get().{{.StringField}}; got != "" {
+		t.Errorf("expected {{.StringField}} to be cleared over lower layers, got %s", got)
+	}
+	layer.Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("set")})
+	if got := broker.Get().{{.StringField}}; got != "set" {
+		t.Errorf("expected a later Set to override the clear, got %s", got)
+	}
+	layer.Set(p)
+	if got := broker.Get().{{.StringField}}; got != "" {
+		t.Errorf("expected a later clear to override the value, got %s", got)
+	}
+	layer.Remove()
+	if got := broker.Get().{{.StringField}}; got != "lower" {
+		t.Errorf("expected {{.StringField}}=lower after removing the clearing layer, got %s", got)
+	}
+}
+{{- end}}
+{{- if .Provenance}}
+
+func Test{{brokerType .TypeName}}Explain(t *testing.T) {
+	broker := {{newBroker .TypeName}}(&{{.TypeName}}{ {{.StringField}}: "base"})
+	if got := broker.Explain(); len(got) != 0 {
+		t.Errorf("expected no layer sources, got %v", got)
+	}
+	file := broker.Layer().Named("file")
+	file.Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("file")})
+	env := broker.Layer()
+	env.Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("env")})
+	if got := broker.Explain()["{{.StringField}}"]; got != env.Name() || got == "" {
+		t.Errorf("expected {{.StringField}} from the unnamed layer %q, got %q", env.Name(), got)
+	}
+	env.Remove()
+	if got := broker.Explain()["{{.StringField}}"]; got != "file" {
+		t.Errorf("expected {{.StringField}} from layer file, got %q", got)
+	}
+	file.Remove()
+	if got := broker.Explain(); len(got) != 0 {
+		t.Errorf("expected no layer sources after removing all layers, got %v", got)
+	}
+}
+{{- end}}
+{{- if .History}}
+
+func Test{{brokerType .TypeName}}Rollback(t *testing.T) {
+	broker := {{newBroker .TypeName}}(&{{.TypeName}}{ {{.StringField}}: "v0"})
+	layer := broker.Layer()
+	layer.Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("v1")})
+	layer.Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("v1")}) // unchanged, not recorded
+	layer.Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("v2")})
+	history := broker.History()
+	if len(history) != min(3, {{.History}}) || history[0].Config.{{.StringField}} != "v2" {
+		t.Fatalf("expected history v2, v1, v0, got %+v", history)
+	}
+{{- if gt .History 1}}
+	rollback, err := broker.Rollback(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := broker.Get().{{.StringField}}; got != "v1" {
+		t.Errorf("expected {{.StringField}}=v1 after rolling back, got %s", got)
+	}
+	layer.Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("v3")})
+	if got := broker.Get().{{.StringField}}; got != "v1" {
+		t.Errorf("expected the rollback to hide later changes to lower layers, got %s", got)
+	}
+	rollback.Remove()
+	if got := broker.Get().{{.StringField}}; got != "v3" {
+		t.Errorf("expected {{.StringField}}=v3 after removing the rollback, got %s", got)
+	}
+{{- end}}
+	if _, err := broker.Rollback(0); err == nil {
+		t.Error("expected an error rolling back to the current config")
+	}
+	if _, err := broker.Rollback({{.History}}); err == nil {
+		t.Error("expected an error rolling back beyond the history")
+	}
+}
+
+func Test{{brokerType .TypeName}}HistoryLimit(t *testing.T) {
+	broker := {{newBroker .TypeName}}(nil)
+	layer := broker.Layer()
+	for i := range {{.History}} + 2 {
+		layer.Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr(strconv.Itoa(i))})
+	}
+	history := broker.History()
+	if len(history) != {{.History}} {
+		t.Fatalf("expected {{.History}} snapshots, got %d", len(history))
+	}
+	if got, want := history[0].Config.{{.StringField}}, strconv.Itoa({{.History}}+1); got != want {
+		t.Errorf("expected newest snapshot %s, got %s", want, got)
+	}
+	for i := 1; i < len(history); i++ {
+		if history[i].Time.After(history[i-1].Time) {
+			t.Errorf("expected snapshots newest first, got %v after %v", history[i].Time, history[i-1].Time)
+		}
+	}
+}
+{{- end}}
+
 func Test{{brokerType .TypeName}}Unsubscribe(t *testing.T) {
 	broker := {{newBroker .TypeName}}(&{{.TypeName}}{ {{.StringField}}: "test"})
 	var updates []string
@@ -452,12 +1188,12 @@ func Test{{brokerType .TypeName}}Unsubscribe(t *testing.T) {
 	if len(updates) != 1 {
 		t.Fatalf("expected 1 update, got %d", len(updates))
 	}
-	broker.Layer().Set(&{{.TypeName}}Partial{ {{.StringField}}: {{lower .TypeName}}Ptr("changed")})
+	broker.Layer().Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("changed")})
 	if len(updates) != 2 {
 		t.Fatalf("expected 2 updates, got %d", len(updates))
 	}
 	unsub()
-	broker.Layer().Set(&{{.TypeName}}Partial{ {{.StringField}}: {{lower .TypeName}}Ptr("ignored")})
+	broker.Layer().Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("ignored")})
 	if len(updates) != 2 {
 		t.Fatalf("expected 2 updates after unsubscribe, got %d", len(updates))
 	}
@@ -466,11 +1202,229 @@ func Test{{brokerType .TypeName}}Unsubscribe(t *testing.T) {
 	}
 }
 
-func Test{{brokerType .TypeName}}SubscribeToEmptyField(t *testing.T) {
-	broker := {{newBroker .TypeName}}(nil)
-	var callCount int
-	unsub := broker.Subscribe{{.StringField}}(func(v string) {
-		callCount++
+func Test{{brokerType .TypeName}}RemoveLayer(t *testing.T) {
+	broker := {{newBroker .TypeName}}(&{{.TypeName}}{ {{.StringField}}: "base"})
+	layer := broker.Layer()
+	layer.Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("layer")})
+	var updates []string
+	unsub := broker.Subscribe{{.StringField}}(func(v string) {
+		updates = append(updates, v)
+	})
+	defer unsub()
+	layer.Remove()
+	if len(updates) != 2 || updates[1] != "base" {
+		t.Fatalf("expected update back to base, got %v", updates)
+	}
+	layer.Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("ignored")})
+	if got := broker.Get().{{.StringField}}; got != "base" {
+		t.Errorf("removed layer should not apply, got {{.StringField}}=%s", got)
+	}
+}
+
+func Test{{brokerType .TypeName}}Subscribe(t *testing.T) {
+	broker := {{newBroker .TypeName}}(nil)
+	var updates []*{{.TypeName}}
+	unsub := broker.Subscribe(func(cfg *{{.TypeName}}) {
+		updates = append(updates, cfg)
+	})
+	defer unsub()
+	if len(updates) != 1 {
+		t.Fatalf("expected initial callback, got %d", len(updates))
+	}
+	layer := broker.Layer()
+	layer.Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("changed")})
+	if len(updates) != 2 || updates[1].{{.StringField}} != "changed" {
+		t.Fatalf("expected update with {{.StringField}}=changed, got %d updates", len(updates))
+	}
+	layer.Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("changed")})
+	if len(updates) != 2 {
+		t.Fatalf("expected no update when nothing changed, got %d updates", len(updates))
+	}
+}
+
+func Test{{brokerType .TypeName}}ReplaceLayer(t *testing.T) {
+	broker := {{newBroker .TypeName}}(&{{.TypeName}}{ {{.StringField}}: "base"})
+	layer := broker.Layer()
+	layer.Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("first")})
+	layer.Replace(&{{partial .TypeName}}{})
+	if got := broker.Get().{{.StringField}}; got != "base" {
+		t.Errorf("expected Replace to drop earlier values, got {{.StringField}}=%s", got)
+	}
+	layer.Replace(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("second")})
+	if got := broker.Get().{{.StringField}}; got != "second" {
+		t.Errorf("expected {{.StringField}}=second, got %s", got)
+	}
+	layer.Replace(nil)
+	if got := broker.Get().{{.StringField}}; got != "base" {
+		t.Errorf("expected nil Replace to clear the layer, got {{.StringField}}=%s", got)
+	}
+}
+
+func Test{{brokerType .TypeName}}Validator(t *testing.T) {
+	// The initial config is not validated, so the base can hold a rejected value
+	broker := {{newBroker .TypeName}}(&{{.TypeName}}{ {{.StringField}}: "invalid"}, {{withValidator .TypeName}}(func(cfg {{.TypeName}}) error {
+		if cfg.{{.StringField}} == "invalid" {
+			return errors.New("{{.StringField}} is invalid")
+		}
+		return nil
+	}))
+	layer := broker.Layer().Named("override")
+	if err := layer.Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("valid")}); err != nil {
+		t.Fatal(err)
+	}
+	var updates int
+	unsub := broker.Subscribe(func(*{{.TypeName}}) {
+		updates++
+	})
+	defer unsub()
+	err := layer.Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("invalid")})
+	var verr *{{.TypeName}}ValidationError
+	if !errors.Is(err, {{errValidation .TypeName}}) || !errors.As(err, &verr) || verr.Layer != "override" {
+		t.Fatalf("expected a validation error for layer override, got %v", err)
+	}
+	if got := broker.Get().{{.StringField}}; got != "valid" {
+		t.Errorf("expected the rejected change to keep {{.StringField}}=valid, got %s", got)
+	}
+	if got := layer.partial.{{.StringField}}; got == nil || *got != "valid" {
+		t.Errorf("expected the rejected change to leave the layer as it was, got %v", got)
+	}
+	if err := layer.Replace(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("invalid")}); !errors.Is(err, {{errValidation .TypeName}}) {
+		t.Errorf("expected Replace to be rejected, got %v", err)
+	}
+	if err := layer.Remove(); !errors.Is(err, {{errValidation .TypeName}}) {
+		t.Errorf("expected Remove to be rejected, got %v", err)
+	}
+	if updates != 1 {
+		t.Errorf("expected no notifications for rejected changes, got %d", updates-1)
+	}
+	if err := layer.Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("still attached")}); err != nil {
+		t.Fatal(err)
+	}
+	if got := broker.Get().{{.StringField}}; got != "still attached" {
+		t.Errorf("expected the layer to stay attached, got {{.StringField}}=%s", got)
+	}
+}
+{{- if .Audit}}
+
+func Test{{brokerType .TypeName}}AuditSink(t *testing.T) {
+	var entries []{{.TypeName}}AuditEntry
+	broker := {{newBroker .TypeName}}(nil, {{withAuditSink .TypeName}}({{.TypeName}}AuditFunc(func(entry {{.TypeName}}AuditEntry) {
+		entries = append(entries, entry)
+	})))
+	layer := broker.Layer().Named("admin").As("alice")
+	if err := layer.Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("audited")}); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected one audit entry, got %d", len(entries))
+	}
+	entry := entries[0]
+	if entry.Layer != "admin" || entry.Actor != "alice" || entry.Time.IsZero() {
+		t.Errorf("expected an entry for layer admin by alice, got %+v", entry)
+	}
+	if len(entry.Changes) != 1 || entry.Changes[0].Field != "{{.StringField}}" || entry.Changes[0].New != "audited" {
+		t.Errorf("expected the change of {{.StringField}}, got %+v", entry.Changes)
+	}
+	if err := layer.Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("audited")}); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || len(entries[1].Changes) != 0 {
+		t.Errorf("expected an entry without changes for a change keeping the config, got %+v", entries)
+	}
+}
+{{- end}}
+{{- if .Tracing}}
+
+// {{lower .TypeName}}SpanRecorder is a trace.TracerProvider recording the names of the
+// spans its tracers start.
+type {{lower .TypeName}}SpanRecorder struct {
+	noop.TracerProvider
+	spans *[]string
+}
+
+func (r {{lower .TypeName}}SpanRecorder) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return {{lower .TypeName}}SpanTracer{spans: r.spans}
+}
+
+type {{lower .TypeName}}SpanTracer struct {
+	noop.Tracer
+	spans *[]string
+}
+
+func (t {{lower .TypeName}}SpanTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	*t.spans = append(*t.spans, name)
+	return t.Tracer.Start(ctx, name, opts...)
+}
+
+func Test{{brokerType .TypeName}}TracerProvider(t *testing.T) {
+	var spans []string
+	broker := {{newBroker .TypeName}}(nil, {{withTracerProvider .TypeName}}({{lower .TypeName}}SpanRecorder{spans: &spans}))
+	if err := broker.Layer().SetContext(context.Background(), &{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("traced")}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"{{brokerType .TypeName}}.merge", "{{brokerType .TypeName}}.notify"}
+	if !slices.Equal(spans, want) {
+		t.Errorf("expected spans %v, got %v", want, spans)
+	}
+}
+{{- end}}
+
+{{- if gt (len .Groups) 1}}
+
+func Test{{brokerType .TypeName}}LayerGroups(t *testing.T) {
+	broker := {{newBroker .TypeName}}(nil)
+	broker.GroupLayer({{groupConst .TypeName (index .Groups 1)}}).Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("high")})
+	broker.GroupLayer({{groupConst .TypeName (index .Groups 0)}}).Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("low")})
+	if got := broker.Get().{{.StringField}}; got != "high" {
+		t.Errorf("expected the higher group to win over a later layer of a lower group, got {{.StringField}}=%s", got)
+	}
+	layer := broker.Layer()
+	layer.Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("layer")})
+	if got := broker.Get().{{.StringField}}; got != "layer" {
+		t.Errorf("expected Layer to win over every group, got {{.StringField}}=%s", got)
+	}
+	layer.Remove()
+	broker.GroupLayer({{groupConst .TypeName (index .Groups 1)}}).Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("newer")})
+	if got := broker.Get().{{.StringField}}; got != "newer" {
+		t.Errorf("expected the most recent layer of a group to win, got {{.StringField}}=%s", got)
+	}
+	if got := {{groupConst .TypeName (index .Groups 0)}}.String(); got != "{{index .Groups 0}}" {
+		t.Errorf("expected group name {{index .Groups 0}}, got %s", got)
+	}
+}
+{{- end}}
+
+func Test{{brokerType .TypeName}}PreviewLayer(t *testing.T) {
+	broker := {{newBroker .TypeName}}(&{{.TypeName}}{ {{.StringField}}: "base"})
+	layer := broker.Layer().Named("file")
+	layer.Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("file")})
+	cfg, changes, err := broker.PreviewLayer("file", &{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("preview")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.{{.StringField}} != "preview" {
+		t.Errorf("expected previewed {{.StringField}}=preview, got %s", cfg.{{.StringField}})
+	}
+	if len(changes) != 1 || changes[0].Field != "{{.StringField}}" || changes[0].Old != "file" || changes[0].New != "preview" {
+		t.Errorf("expected {{.StringField}} to change from file to preview, got %+v", changes)
+	}
+	if got := broker.Get().{{.StringField}}; got != "file" {
+		t.Errorf("expected the preview not to apply, got {{.StringField}}=%s", got)
+	}
+	if got := layer.partial.{{.StringField}}; got == nil || *got != "file" {
+		t.Errorf("expected the preview to leave the layer as it was, got %v", got)
+	}
+	// A name no layer has previews a new layer
+	if _, changes, err := broker.PreviewLayer("env", &{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("file")}); err != nil || len(changes) != 0 {
+		t.Errorf("expected no changes from a new layer repeating the current value, got %+v, %v", changes, err)
+	}
+}
+
+func Test{{brokerType .TypeName}}SubscribeToEmptyField(t *testing.T) {
+	broker := {{newBroker .TypeName}}(nil)
+	var callCount int
+	unsub := broker.Subscribe{{.StringField}}(func(v string) {
+		callCount++
 	})
 	defer unsub()
 	
@@ -480,7 +1434,7 @@ func Test{{brokerType .TypeName}}SubscribeToEmptyField(t *testing.T) {
 	}
 	
 	// Should be called when field is set
-	broker.Layer().Set(&{{.TypeName}}Partial{ {{.StringField}}: {{lower .TypeName}}Ptr("test")})
+	broker.Layer().Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("test")})
 	if callCount != 1 {
 		t.Errorf("expected 1 call after setting field, got %d", callCount)
 	}
@@ -498,12 +1452,12 @@ func Test{{brokerType .TypeName}}NoChangeNoNotify(t *testing.T) {
 		t.Fatalf("expected 1 update, got %d", len(updates))
 	}
 	// Setting to same value should NOT trigger callback
-	broker.Layer().Set(&{{.TypeName}}Partial{ {{.IntField}}: {{lower .TypeName}}Ptr(42)})
+	broker.Layer().Set(&{{partial .TypeName}}{ {{.IntField}}: sudogenPtr(42)})
 	if len(updates) != 1 {
 		t.Fatalf("expected 1 update (no change), got %d", len(updates))
 	}
 	// Setting to different value should trigger callback
-	broker.Layer().Set(&{{.TypeName}}Partial{ {{.IntField}}: {{lower .TypeName}}Ptr(100)})
+	broker.Layer().Set(&{{partial .TypeName}}{ {{.IntField}}: sudogenPtr(100)})
 	if len(updates) != 2 || updates[1] != 100 {
 		t.Fatalf("expected 2 updates with 100, got %v", updates)
 	}
@@ -518,7 +1472,7 @@ func Test{{brokerType .TypeName}}ZeroValueUpdate(t *testing.T) {
 	defer unsub()
 	
 	// Setting to zero value should trigger callback
-	broker.Layer().Set(&{{.TypeName}}Partial{ {{.IntField}}: {{lower .TypeName}}Ptr(0)})
+	broker.Layer().Set(&{{partial .TypeName}}{ {{.IntField}}: sudogenPtr(0)})
 	if len(updates) != 2 || updates[1] != 0 {
 		t.Errorf("expected zero value update, got %v", updates)
 	}
@@ -559,12 +1513,89 @@ func Test{{brokerType .TypeName}}ConcurrentLayerCreation(t *testing.T) {
 		<-done
 	}
 }
+{{- if and .StringField .IntField}}
+
+// Test{{brokerType .TypeName}}ConcurrentWriters races layer writers and removals against
+// readers and subscribers, and is meant to be run with go test -race. Every write sets
+// {{.StringField}} and {{.IntField}} together, so a config where they disagree is a torn read.
+func Test{{brokerType .TypeName}}ConcurrentWriters(t *testing.T) {
+	torn := func(cfg *{{.TypeName}}) bool {
+		return cfg.{{.StringField}} != strconv.Itoa(cfg.{{.IntField}})
+	}
+	broker := {{newBroker .TypeName}}(&{{.TypeName}}{ {{.StringField}}: "0", {{.IntField}}: 0})
+	var last atomic.Pointer[{{.TypeName}}]
+	unsub := broker.Subscribe(func(cfg *{{.TypeName}}) {
+		if torn(cfg) {
+			t.Errorf("subscriber received a torn config: {{.StringField}}=%q {{.IntField}}=%d", cfg.{{.StringField}}, cfg.{{.IntField}})
+		}
+		last.Store(cfg)
+	})
+	defer unsub()
+
+	const writers, iterations = 8, 100
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	for range 4 {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if cfg := broker.Get(); torn(cfg) {
+					t.Errorf("Get returned a torn config: {{.StringField}}=%q {{.IntField}}=%d", cfg.{{.StringField}}, cfg.{{.IntField}})
+					return
+				}
+				broker.Subscribe{{.StringField}}(func(string) {})()
+			}
+		}()
+	}
+	var wg sync.WaitGroup
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			layer := broker.Layer()
+			for i := range iterations {
+				v := w*iterations + i + 1
+				if err := layer.Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr(strconv.Itoa(v)), {{.IntField}}: sudogenPtr(v)}); err != nil {
+					t.Error(err)
+					return
+				}
+				if i%10 == 9 {
+					if err := layer.Remove(); err != nil {
+						t.Error(err)
+						return
+					}
+					layer = broker.Layer()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := broker.TopLayer().Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("-1"), {{.IntField}}: sudogenPtr(-1)}); err != nil {
+		t.Fatal(err)
+	}
+	close(stop)
+	readers.Wait()
+	if got := last.Load(); got == nil || got.{{.StringField}} != "-1" || got.{{.IntField}} != -1 {
+		t.Errorf("expected the last notification to carry the final config, got %+v", got)
+	}
+	if got := broker.Get(); got.{{.StringField}} != "-1" || got.{{.IntField}} != -1 {
+		t.Errorf("expected the final config to stay in place, got {{.StringField}}=%q {{.IntField}}=%d", got.{{.StringField}}, got.{{.IntField}})
+	}
+}
+{{- end}}
 
 func Test{{brokerType .TypeName}}BaseConfigPreserved(t *testing.T) {
 	{{if .StringField}}
 	broker := {{newBroker .TypeName}}(&{{.TypeName}}{ {{.StringField}}: "base"})
 	layer := broker.Layer()
-	layer.Set(&{{.TypeName}}Partial{ {{.StringField}}: {{lower .TypeName}}Ptr("layer")})
+	layer.Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("layer")})
 	
 	cfg := broker.Get()
 	if cfg.{{.StringField}} != "layer" {
@@ -579,7 +1610,7 @@ func Test{{brokerType .TypeName}}BaseConfigPreserved(t *testing.T) {
 	}
 	{{else}}
 	broker := {{newBroker .TypeName}}(&{{.TypeName}}{})
-	broker.Layer().Set(&{{.TypeName}}Partial{})
+	broker.Layer().Set(&{{partial .TypeName}}{})
 	cfg := broker.Get()
 	if cfg == nil {
 		t.Error("Get() should not return nil")
@@ -597,7 +1628,7 @@ func Test{{brokerType $.TypeName}}Subscribe{{.Name}}(t *testing.T) {
 	if len(updates) != 1 || updates[0] != 42 {
 		t.Fatalf("expected initial callback with 42, got %v", updates)
 	}
-	broker.Layer().Set(&{{$.TypeName}}Partial{ {{.Name}}: {{lower $.TypeName}}Ptr({{.TypeName}}(100))})
+	broker.Layer().Set(&{{partial $.TypeName}}{ {{.Name}}: sudogenPtr({{.TypeName}}(100))})
 	if len(updates) != 2 || updates[1] != 100 {
 		t.Fatalf("expected update callback with 100, got %v", updates)
 	}
@@ -613,7 +1644,7 @@ func Test{{brokerType $.TypeName}}Subscribe{{.Name}}(t *testing.T) {
 	if len(updates) != 1 || updates[0] != 42 {
 		t.Fatalf("expected initial callback with 42, got %v", updates)
 	}
-	broker.Layer().Set(&{{$.TypeName}}Partial{ {{.Name}}: {{lower $.TypeName}}Ptr({{.TypeName}}(100))})
+	broker.Layer().Set(&{{partial $.TypeName}}{ {{.Name}}: sudogenPtr({{.TypeName}}(100))})
 	if len(updates) != 2 || updates[1] != 100 {
 		t.Fatalf("expected update callback with 100, got %v", updates)
 	}
@@ -629,7 +1660,7 @@ func Test{{brokerType $.TypeName}}Subscribe{{.Name}}(t *testing.T) {
 	if len(updates) != 1 || updates[0] != 3.14 {
 		t.Fatalf("expected initial callback with 3.14, got %v", updates)
 	}
-	broker.Layer().Set(&{{$.TypeName}}Partial{ {{.Name}}: {{lower $.TypeName}}Ptr(2.71)})
+	broker.Layer().Set(&{{partial $.TypeName}}{ {{.Name}}: sudogenPtr(2.71)})
 	if len(updates) != 2 || updates[1] != 2.71 {
 		t.Fatalf("expected update callback with 2.71, got %v", updates)
 	}
@@ -645,7 +1676,7 @@ func Test{{brokerType $.TypeName}}Subscribe{{.Name}}(t *testing.T) {
 	if len(updates) != 1 || !updates[0] {
 		t.Fatalf("expected initial callback with true, got %v", updates)
 	}
-	broker.Layer().Set(&{{$.TypeName}}Partial{ {{.Name}}: {{lower $.TypeName}}Ptr(false)})
+	broker.Layer().Set(&{{partial $.TypeName}}{ {{.Name}}: sudogenPtr(false)})
 	if len(updates) != 2 || updates[1] {
 		t.Fatalf("expected update callback with false, got %v", updates)
 	}
@@ -663,7 +1694,7 @@ func Test{{brokerType $.TypeName}}Subscribe{{.Name}}Pointer(t *testing.T) {
 	if len(updates) != 1 || updates[0] == nil || *updates[0] != "initial" {
 		t.Fatalf("expected initial callback with 'initial', got %v", updates)
 	}
-	broker.Layer().Set(&{{$.TypeName}}Partial{ {{.Name}}: {{lower $.TypeName}}Ptr("updated")})
+	broker.Layer().Set(&{{partial $.TypeName}}{ {{.Name}}: sudogenPtr("updated")})
 	if len(updates) != 2 || updates[1] == nil || *updates[1] != "updated" {
 		t.Fatalf("expected update callback with 'updated', got %v", updates)
 	}
@@ -682,7 +1713,7 @@ func Test{{brokerType $.TypeName}}Subscribe{{.Name}}Slice(t *testing.T) {
 		t.Fatalf("expected 1 initial callback, got %d", callCount)
 	}
 	// Set a new slice
-	broker.Layer().Set(&{{$.TypeName}}Partial{ {{.Name}}: make({{.TypeName}}, 3)})
+	broker.Layer().Set(&{{partial $.TypeName}}{ {{.Name}}: make({{.TypeName}}, 3)})
 	if callCount != 2 {
 		t.Fatalf("expected 2 callbacks after update, got %d", callCount)
 	}
@@ -728,7 +1759,7 @@ func Test{{brokerType $.TypeName}}Subscribe{{.Name}}Time(t *testing.T) {
 		t.Fatalf("expected initial callback with current time, got %v", updates)
 	}
 	later := now.Add(time.Hour)
-	broker.Layer().Set(&{{$.TypeName}}Partial{ {{.Name}}: &later})
+	broker.Layer().Set(&{{partial $.TypeName}}{ {{.Name}}: &later})
 	if len(updates) != 2 || !updates[1].Equal(later) {
 		t.Fatalf("expected update callback with later time, got %v", updates)
 	}
@@ -751,8 +1782,7 @@ func Test{{brokerType $.TypeName}}Subscribe{{.Name}}TimePointer(t *testing.T) {
 {{if .GenerateJSON}}
 func Test{{brokerType .TypeName}}MarshalJSON(t *testing.T) {
 	broker := {{newBroker .TypeName}}(nil)
-	layer := broker.Layer()
-	{{if .StringField}}layer.Set(&{{.TypeName}}Partial{ {{.StringField}}: {{lower .TypeName}}Ptr("test")}){{end}}
+	{{if .StringField}}broker.Layer().Set(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("test")}){{else}}broker.Layer(){{end}}
 	data, err := json.Marshal(broker)
 	if err != nil {
 		t.Fatalf("MarshalJSON failed: %v", err)
@@ -792,13 +1822,13 @@ func Test{{brokerType .TypeName}}SetAllFieldTypes(t *testing.T) {
 	broker := {{newBroker .TypeName}}(nil)
 	layer := broker.Layer()
 	// Test setting all field types to exercise mergePartial
-	partial := &{{.TypeName}}Partial{}
-{{range .Fields}}{{if not .IsSlice}}{{if not .IsMap}}{{if not .IsPointer}}{{if not .IsStruct}}{{if eq .TypeName "string"}}	partial.{{.Name}} = {{lower $.TypeName}}Ptr("test")
-{{else if eq .TypeName "int"}}	partial.{{.Name}} = {{lower $.TypeName}}Ptr(42)
-{{else if eq .TypeName "int32"}}	partial.{{.Name}} = {{lower $.TypeName}}Ptr(int32(42))
-{{else if eq .TypeName "int64"}}	partial.{{.Name}} = {{lower $.TypeName}}Ptr(int64(42))
-{{else if eq .TypeName "float64"}}	partial.{{.Name}} = {{lower $.TypeName}}Ptr(3.14)
-{{else if eq .TypeName "bool"}}	partial.{{.Name}} = {{lower $.TypeName}}Ptr(true)
+	partial := &{{partial .TypeName}}{}
+{{range .Fields}}{{if not .IsSlice}}{{if not .IsMap}}{{if not .IsPointer}}{{if not .IsStruct}}{{if eq .TypeName "string"}}	partial.{{.Name}} = sudogenPtr("test")
+{{else if eq .TypeName "int"}}	partial.{{.Name}} = sudogenPtr(42)
+{{else if eq .TypeName "int32"}}	partial.{{.Name}} = sudogenPtr(int32(42))
+{{else if eq .TypeName "int64"}}	partial.{{.Name}} = sudogenPtr(int64(42))
+{{else if eq .TypeName "float64"}}	partial.{{.Name}} = sudogenPtr(3.14)
+{{else if eq .TypeName "bool"}}	partial.{{.Name}} = sudogenPtr(true)
 {{end}}{{end}}{{end}}{{end}}{{end}}{{end}}
 	layer.Set(partial)
 	cfg := broker.Get()
@@ -810,7 +1840,7 @@ func Test{{brokerType .TypeName}}SetAllFieldTypes(t *testing.T) {
 func Test{{brokerType .TypeName}}SetSliceAndMapFields(t *testing.T) {
 	broker := {{newBroker .TypeName}}(nil)
 	layer := broker.Layer()
-	partial := &{{.TypeName}}Partial{}
+	partial := &{{partial .TypeName}}{}
 {{range .Fields}}{{if .IsSlice}}	partial.{{.Name}} = make({{.TypeName}}, 1)
 {{else if .IsMap}}	partial.{{.Name}} = make({{.TypeName}})
 {{end}}{{end}}
@@ -824,8 +1854,8 @@ func Test{{brokerType .TypeName}}SetSliceAndMapFields(t *testing.T) {
 func Test{{brokerType .TypeName}}SetPointerFields(t *testing.T) {
 	broker := {{newBroker .TypeName}}(nil)
 	layer := broker.Layer()
-	partial := &{{.TypeName}}Partial{}
-{{range .Fields}}{{if and .IsPointer (not .IsStruct) (eq .TypePkg "") (eq .TypeName "string")}}	partial.{{.Name}} = {{lower $.TypeName}}Ptr("test")
+	partial := &{{partial .TypeName}}{}
+{{range .Fields}}{{if and .IsPointer (not .IsStruct) (eq .TypePkg "") (eq .TypeName "string")}}	partial.{{.Name}} = sudogenPtr("test")
 {{end}}{{end}}
 	layer.Set(partial)
 	cfg := broker.Get()
@@ -837,8 +1867,8 @@ func Test{{brokerType .TypeName}}SetPointerFields(t *testing.T) {
 func Test{{brokerType $.TypeName}}SetNestedStruct{{.Name}}(t *testing.T) {
 	broker := {{newBroker $.TypeName}}(nil)
 	layer := broker.Layer()
-	partial := &{{$.TypeName}}Partial{
-		{{.Name}}: &{{.TypeName}}Partial{},
+	partial := &{{partial $.TypeName}}{
+		{{.Name}}: &{{partial .TypeName}}{},
 	}
 	layer.Set(partial)
 	cfg := broker.Get()
@@ -852,7 +1882,7 @@ func Test{{brokerType .TypeName}}SetTimeFields(t *testing.T) {
 	broker := {{newBroker .TypeName}}(nil)
 	layer := broker.Layer()
 	now := time.Now()
-	partial := &{{.TypeName}}Partial{}
+	partial := &{{partial .TypeName}}{}
 {{range .Fields}}{{if and (not .IsPointer) (eq .TypePkg "time") (eq .TypeName "Time")}}	partial.{{.Name}} = &now
 {{end}}{{end}}
 {{range .Fields}}{{if and .IsPointer (eq .TypePkg "time") (eq .TypeName "Time")}}	partial.{{.Name}} = &now
@@ -865,3 +1895,939 @@ func Test{{brokerType .TypeName}}SetTimeFields(t *testing.T) {
 }
 {{end}}
 `
+
+const httpHandlerTemplate = `// Code generated by sudo-gen layerbroker. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+)
+
+// {{handlerType .TypeName}} exposes a {{brokerType .TypeName}} as a runtime configuration
+// admin API:
+//
+//	GET    /config                 merged configuration as JSON
+//	GET    /config/stream          Server-Sent Events stream of the merged configuration
+//	GET    /layers                 named layers in priority order, lowest first
+//	PUT    /layers/{name}          apply a {{partial .TypeName}} to the named layer, creating it on first use
+//	POST   /layers/{name}/preview  the result of a PUT with the same body, without applying it
+//	DELETE /layers/{name}          remove the named layer
+{{- if .Provenance}}
+//	GET    /explain                name of the layer providing each field, as returned by Explain
+{{- end}}
+//
+// Layers created through the handler are appended above all existing layers. Layers
+// created directly with Layer() are not listed. A PUT or DELETE that the broker's
+// validator rejects fails with 422 Unprocessable Entity and leaves the layer as it was.
+// A preview the validator rejects is still returned, with the error set.
+{{- if .Secrets}}
+//
+// Fields tagged sudo:"secret" are masked in every response, including the config
+// stream and the changes of a preview. They can still be set with PUT.
+{{- end}}
+type {{handlerType .TypeName}} struct {
+	broker *{{brokerType .TypeName}}
+	mux    *http.ServeMux
+	mu     sync.Mutex // protects layers and names
+	layers map[string]*{{layerType .TypeName}}
+	names  []string
+}
+
+// {{handlerLayerType .TypeName}} is a named layer as listed by GET /layers.
+type {{handlerLayerType .TypeName}} struct {
+	Name    string            ` + "`" + `json:"name"` + "`" + `
+	Partial *{{partial .TypeName}} ` + "`" + `json:"partial"` + "`" + `
+}
+
+// {{.TypeName}}HTTPPreview is the response of POST /layers/{name}/preview.
+type {{.TypeName}}HTTPPreview struct {
+	Config  *{{.TypeName}}              ` + "`" + `json:"config"` + "`" + `
+	Changes []{{.TypeName}}FieldChange  ` + "`" + `json:"changes"` + "`" + `
+	Error   string                      ` + "`" + `json:"error,omitempty"` + "`" + `
+}
+
+// {{newHandler .TypeName}} returns an http.Handler serving the admin API for broker.
+func {{newHandler .TypeName}}(broker *{{brokerType .TypeName}}) *{{handlerType .TypeName}} {
+	h := &{{handlerType .TypeName}}{
+		broker: broker,
+		mux:    http.NewServeMux(),
+		layers: make(map[string]*{{layerType .TypeName}}),
+	}
+	h.mux.HandleFunc("GET /config", h.getConfig)
+	h.mux.HandleFunc("GET /config/stream", h.streamConfig)
+	h.mux.HandleFunc("GET /layers", h.listLayers)
+	h.mux.HandleFunc("PUT /layers/{name}", h.putLayer)
+	h.mux.HandleFunc("POST /layers/{name}/preview", h.previewLayer)
+	h.mux.HandleFunc("DELETE /layers/{name}", h.deleteLayer)
+{{- if .Provenance}}
+	h.mux.HandleFunc("GET /explain", h.explain)
+{{- end}}
+	return h
+}
+
+// ServeHTTP implements http.Handler.
+func (h *{{handlerType .TypeName}}) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *{{handlerType .TypeName}}) getConfig(w http.ResponseWriter, r *http.Request) {
+{{- if .Secrets}}
+	{{lower .TypeName}}WriteJSON(w, {{lower .TypeName}}MaskSecrets(h.broker.Get(), ""))
+{{- else}}
+	{{lower .TypeName}}WriteJSON(w, h.broker.Get())
+{{- end}}
+}
+
+// streamConfig sends the merged configuration as a "config" event when the client
+// connects and again after every change. Slow clients skip to the latest snapshot.
+func (h *{{handlerType .TypeName}}) streamConfig(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	updates := make(chan *{{.TypeName}}, 1)
+	unsub := h.broker.Subscribe(func(cfg *{{.TypeName}}) {
+		for {
+			select {
+			case updates <- cfg:
+				return
+			default:
+			}
+			select {
+			case <-updates:
+			default:
+			}
+		}
+	})
+	defer unsub()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case cfg := <-updates:
+{{- if .Secrets}}
+			data, err := json.Marshal({{lower .TypeName}}MaskSecrets(cfg, ""))
+{{- else}}
+			data, err := json.Marshal(cfg)
+{{- end}}
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: config\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func (h *{{handlerType .TypeName}}) listLayers(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.broker.mu.Lock()
+	defer h.broker.mu.Unlock()
+	layers := make([]{{handlerLayerType .TypeName}}, 0, len(h.names))
+	for _, name := range h.names {
+		partial := h.layers[name].partial
+		if partial == nil {
+			partial = &{{partial .TypeName}}{}
+		}
+		layers = append(layers, {{handlerLayerType .TypeName}}{Name: name, Partial: partial})
+	}
+{{- if .Secrets}}
+	{{lower .TypeName}}WriteJSON(w, {{lower .TypeName}}MaskSecrets(layers, "", "partial"))
+{{- else}}
+	{{lower .TypeName}}WriteJSON(w, layers)
+{{- end}}
+}
+
+func (h *{{handlerType .TypeName}}) putLayer(w http.ResponseWriter, r *http.Request) {
+	var p {{partial .TypeName}}
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		http.Error(w, "decoding partial: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	name := r.PathValue("name")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	layer, ok := h.layers[name]
+	if !ok {
+		layer = h.broker.Layer().Named(name)
+	}
+	if err := layer.Set(&p); err != nil {
+		if !ok {
+			layer.Remove()
+		}
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if !ok {
+		h.layers[name] = layer
+		h.names = append(h.names, name)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *{{handlerType .TypeName}}) previewLayer(w http.ResponseWriter, r *http.Request) {
+	var p {{partial .TypeName}}
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		http.Error(w, "decoding partial: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	cfg, changes, err := h.broker.PreviewLayer(r.PathValue("name"), &p)
+	preview := {{.TypeName}}HTTPPreview{Config: cfg, Changes: changes}
+	if preview.Changes == nil {
+		preview.Changes = []{{.TypeName}}FieldChange{}
+	}
+	if err != nil {
+		preview.Error = err.Error()
+	}
+{{- if .Secrets}}
+	for i, change := range preview.Changes {
+		preview.Changes[i].Old = {{lower .TypeName}}MaskSecrets(change.Old, change.Field)
+		preview.Changes[i].New = {{lower .TypeName}}MaskSecrets(change.New, change.Field)
+	}
+	{{lower .TypeName}}WriteJSON(w, {{lower .TypeName}}MaskSecrets(preview, "", "config"))
+{{- else}}
+	{{lower .TypeName}}WriteJSON(w, preview)
+{{- end}}
+}
+
+func (h *{{handlerType .TypeName}}) deleteLayer(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	layer, ok := h.layers[name]
+	if !ok {
+		http.Error(w, "layer "+name+" not found", http.StatusNotFound)
+		return
+	}
+	if err := layer.Remove(); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	delete(h.layers, name)
+	h.names = slices.DeleteFunc(h.names, func(n string) bool { return n == name })
+	w.WriteHeader(http.StatusNoContent)
+}
+{{- if .Provenance}}
+
+func (h *{{handlerType .TypeName}}) explain(w http.ResponseWriter, r *http.Request) {
+	{{lower .TypeName}}WriteJSON(w, h.broker.Explain())
+}
+{{- end}}
+
+func {{lower .TypeName}}WriteJSON(w http.ResponseWriter, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, "encoding response: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+{{- if .Secrets}}
+
+// {{lower .TypeName}}SecretPaths locates the fields tagged sudo:"secret" in the JSON
+// encoding of a {{.TypeName}} or its partial. A "*" key matches every key of a map, and
+// arrays are walked element by element.
+var {{lower .TypeName}}SecretPaths = []struct {
+	field string   // Top-level field holding the secret
+	keys  []string // Object keys from the root
+}{
+{{- range .Secrets}}
+	{ {{printf "%q" .Field}}, []string{ {{range $i, $key := .Keys}}{{if $i}}, {{end}}{{printf "%q" $key}}{{end}} } },
+{{- end}}
+}
+
+// {{lower .TypeName}}SecretMask replaces the values of secret fields in responses.
+const {{lower .TypeName}}SecretMask = "********"
+
+// {{lower .TypeName}}MaskSecrets returns v, encoded as JSON and decoded again, with the
+// secrets of the config found at prefix replaced by {{lower .TypeName}}SecretMask. If field
+// is set, v holds the value of that top-level field rather than a whole config.
+func {{lower .TypeName}}MaskSecrets(v any, field string, prefix ...string) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v // Writing v fails in the same way
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var masked any
+	if err := dec.Decode(&masked); err != nil {
+		return v
+	}
+	for _, secret := range {{lower .TypeName}}SecretPaths {
+		keys := secret.keys
+		if field != "" {
+			if secret.field != field {
+				continue
+			}
+			keys = keys[1:]
+		}
+		masked = {{lower .TypeName}}MaskPath(masked, slices.Concat(prefix, keys))
+	}
+	return masked
+}
+
+// {{lower .TypeName}}MaskPath masks the values at keys below v, which is decoded JSON.
+// Null and zero values are left as they are, so that unset secrets can be told from
+// set ones.
+func {{lower .TypeName}}MaskPath(v any, keys []string) any {
+	if len(keys) == 0 {
+		switch v {
+		case nil, "", false, json.Number("0"):
+			return v
+		}
+		return {{lower .TypeName}}SecretMask
+	}
+	switch v := v.(type) {
+	case []any:
+		for i := range v {
+			v[i] = {{lower .TypeName}}MaskPath(v[i], keys)
+		}
+	case map[string]any:
+		for key := range v {
+			if keys[0] == "*" || keys[0] == key {
+				v[key] = {{lower .TypeName}}MaskPath(v[key], keys[1:])
+			}
+		}
+	}
+	return v
+}
+{{- end}}
+`
+
+const httpHandlerTestTemplate = `// Code generated by sudo-gen layerbroker. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+{{- if .StringField}}
+	"errors"
+{{- end}}
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func {{lower .TypeName}}ServeAdmin(t *testing.T, h http.Handler, method, path string, body []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, path, bytes.NewReader(body)))
+	return rec
+}
+
+func Test{{capitalize (handlerType .TypeName)}}GetConfig(t *testing.T) {
+	h := {{newHandler .TypeName}}({{newBroker .TypeName}}(nil))
+	rec := {{lower .TypeName}}ServeAdmin(t, h, http.MethodGet, "/config", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var cfg {{.TypeName}}
+	if err := json.Unmarshal(rec.Body.Bytes(), &cfg); err != nil {
+		t.Fatalf("response is not a {{.TypeName}}: %v", err)
+	}
+}
+
+func Test{{capitalize (handlerType .TypeName)}}LayerLifecycle(t *testing.T) {
+	broker := {{newBroker .TypeName}}(nil)
+	h := {{newHandler .TypeName}}(broker)
+{{- if .StringField}}
+	body, err := json.Marshal(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("from-http")})
+	if err != nil {
+		t.Fatal(err)
+	}
+{{- else}}
+	body := []byte("{}")
+{{- end}}
+	if rec := {{lower .TypeName}}ServeAdmin(t, h, http.MethodPut, "/layers/admin", body); rec.Code != http.StatusNoContent {
+		t.Fatalf("PUT: expected 204, got %d: %s", rec.Code, rec.Body)
+	}
+{{- if .StringField}}
+	if got := broker.Get().{{.StringField}}; got != "from-http" {
+		t.Errorf("expected {{.StringField}}=from-http, got %s", got)
+	}
+{{- end}}
+	rec := {{lower .TypeName}}ServeAdmin(t, h, http.MethodGet, "/layers", nil)
+	var layers []{{handlerLayerType .TypeName}}
+	if err := json.Unmarshal(rec.Body.Bytes(), &layers); err != nil {
+		t.Fatalf("GET /layers: %v", err)
+	}
+	if len(layers) != 1 || layers[0].Name != "admin" {
+		t.Fatalf("expected layer admin, got %+v", layers)
+	}
+{{- if and .Provenance .StringField}}
+	rec = {{lower .TypeName}}ServeAdmin(t, h, http.MethodGet, "/explain", nil)
+	var sources map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &sources); err != nil {
+		t.Fatalf("GET /explain: %v", err)
+	}
+	if sources["{{.StringField}}"] != "admin" {
+		t.Errorf("expected {{.StringField}} to come from layer admin, got %v", sources)
+	}
+{{- end}}
+	if rec := {{lower .TypeName}}ServeAdmin(t, h, http.MethodDelete, "/layers/admin", nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("DELETE: expected 204, got %d", rec.Code)
+	}
+{{- if .StringField}}
+	if got := broker.Get().{{.StringField}}; got != "" {
+		t.Errorf("expected {{.StringField}} to be reset after DELETE, got %s", got)
+	}
+{{- end}}
+	if rec := {{lower .TypeName}}ServeAdmin(t, h, http.MethodDelete, "/layers/admin", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("second DELETE: expected 404, got %d", rec.Code)
+	}
+}
+
+func Test{{capitalize (handlerType .TypeName)}}StreamConfig(t *testing.T) {
+	h := {{newHandler .TypeName}}({{newBroker .TypeName}}(nil))
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/config/stream", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected text/event-stream, got %q", ct)
+	}
+	events := bufio.NewReader(resp.Body)
+	if _, err := {{lower .TypeName}}ReadEvent(events); err != nil {
+		t.Fatalf("reading initial snapshot: %v", err)
+	}
+{{- if .StringField}}
+	body, err := json.Marshal(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("streamed")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	{{lower .TypeName}}ServeAdmin(t, h, http.MethodPut, "/layers/stream", body)
+	cfg, err := {{lower .TypeName}}ReadEvent(events)
+	if err != nil {
+		t.Fatalf("reading update: %v", err)
+	}
+	if cfg.{{.StringField}} != "streamed" {
+		t.Errorf("expected {{.StringField}}=streamed, got %s", cfg.{{.StringField}})
+	}
+{{- end}}
+}
+
+// {{lower .TypeName}}ReadEvent reads one Server-Sent Event and decodes its data.
+func {{lower .TypeName}}ReadEvent(r *bufio.Reader) (*{{.TypeName}}, error) {
+	var data string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" && data != "" {
+			break
+		}
+		if v, ok := strings.CutPrefix(line, "data: "); ok {
+			data = v
+		}
+	}
+	var cfg {{.TypeName}}
+	if err := json.Unmarshal([]byte(data), &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func Test{{capitalize (handlerType .TypeName)}}PutInvalidPartial(t *testing.T) {
+	h := {{newHandler .TypeName}}({{newBroker .TypeName}}(nil))
+	rec := {{lower .TypeName}}ServeAdmin(t, h, http.MethodPut, "/layers/bad", []byte("{not json"))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rec.Code)
+	}
+}
+{{- if .StringField}}
+
+func Test{{capitalize (handlerType .TypeName)}}PreviewLayer(t *testing.T) {
+	broker := {{newBroker .TypeName}}(nil)
+	h := {{newHandler .TypeName}}(broker)
+	body, err := json.Marshal(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("previewed")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := {{lower .TypeName}}ServeAdmin(t, h, http.MethodPost, "/layers/admin/preview", body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var preview {{.TypeName}}HTTPPreview
+	if err := json.Unmarshal(rec.Body.Bytes(), &preview); err != nil {
+		t.Fatal(err)
+	}
+	if preview.Config.{{.StringField}} != "previewed" || len(preview.Changes) != 1 || preview.Error != "" {
+		t.Errorf("expected a preview changing {{.StringField}}, got %+v", preview)
+	}
+	if got := broker.Get().{{.StringField}}; got != "" {
+		t.Errorf("expected the preview not to apply, got {{.StringField}}=%s", got)
+	}
+	if rec := {{lower .TypeName}}ServeAdmin(t, h, http.MethodDelete, "/layers/admin", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected the preview not to create the layer, got %d", rec.Code)
+	}
+}
+
+func Test{{capitalize (handlerType .TypeName)}}PutRejected(t *testing.T) {
+	broker := {{newBroker .TypeName}}(nil, {{withValidator .TypeName}}(func(cfg {{.TypeName}}) error {
+		return errors.New("rejected")
+	}))
+	h := {{newHandler .TypeName}}(broker)
+	body, err := json.Marshal(&{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("from-http")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec := {{lower .TypeName}}ServeAdmin(t, h, http.MethodPut, "/layers/admin", body); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("PUT: expected 422, got %d: %s", rec.Code, rec.Body)
+	}
+	if got := broker.Get().{{.StringField}}; got != "" {
+		t.Errorf("expected the rejected layer not to apply, got {{.StringField}}=%s", got)
+	}
+	if rec := {{lower .TypeName}}ServeAdmin(t, h, http.MethodDelete, "/layers/admin", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected the rejected layer not to be created, got %d", rec.Code)
+	}
+}
+{{- end}}
+{{- range .Secrets}}
+{{- if and .Direct .String}}
+
+func Test{{capitalize (handlerType $.TypeName)}}MasksSecrets(t *testing.T) {
+	h := {{newHandler $.TypeName}}({{newBroker $.TypeName}}(nil))
+	if rec := {{lower $.TypeName}}ServeAdmin(t, h, http.MethodPut, "/layers/admin", []byte({{printf "%q" (.JSON "hunter2")}})); rec.Code != http.StatusNoContent {
+		t.Fatalf("PUT: expected 204, got %d: %s", rec.Code, rec.Body)
+	}
+	for _, req := range []struct{ method, path, body string }{
+		{http.MethodGet, "/config", ""},
+		{http.MethodGet, "/layers", ""},
+		{http.MethodPost, "/layers/admin/preview", {{printf "%q" (.JSON "hunter3")}}},
+	} {
+		rec := {{lower $.TypeName}}ServeAdmin(t, h, req.method, req.path, []byte(req.body))
+		if body := rec.Body.String(); strings.Contains(body, "hunter") || !strings.Contains(body, {{lower $.TypeName}}SecretMask) {
+			t.Errorf("%s %s: expected {{.Field}} to be masked, got %s", req.method, req.path, body)
+		}
+	}
+}
+{{- break}}
+{{- end}}
+{{- end}}
+`
+
+const fileWatcherTemplate = `// Code generated by sudo-gen layerbroker. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+{{- if .Tracing}}
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+{{- end}}
+)
+
+// {{.TypeName}}FileFormat decodes the contents of a config file into a {{partial .TypeName}}.
+// json.Unmarshal and yaml.Unmarshal both satisfy it.
+type {{.TypeName}}FileFormat func(data []byte, v any) error
+
+// {{.TypeName}}FileJSON decodes JSON config files.
+var {{.TypeName}}FileJSON {{.TypeName}}FileFormat = json.Unmarshal
+
+// {{watcherType .TypeName}} keeps a broker layer in sync with a config file.
+type {{watcherType .TypeName}} struct {
+	// Layer holds the file's contents. Each reload replaces it entirely, so values
+	// deleted from the file are dropped from the config.
+	Layer  *{{layerType .TypeName}}
+	errors chan error
+}
+
+// {{watchFunc .TypeName}} loads the config file at path into a new layer of broker and
+// reloads it whenever the file changes, until ctx is done. The initial load must
+// succeed; later failures keep the last good contents and are reported on Errors.
+{{- if .Tracing}} Each
+// load is traced as a "{{watcherType .TypeName}}.load" span, the initial one as a child
+// of the span in ctx.
+{{- end}}
+func {{watchFunc .TypeName}}(ctx context.Context, broker *{{brokerType .TypeName}}, path string, format {{.TypeName}}FileFormat) (*{{watcherType .TypeName}}, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("resolving %s: %w", path, err)
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("creating file watcher: %w", err)
+	}
+	// Watch the directory: editors and deploy tools replace files by renaming, which
+	// would drop a watch on the file itself.
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("watching %s: %w", path, err)
+	}
+	w := &{{watcherType .TypeName}}{Layer: broker.Layer(), errors: make(chan error, 1)}
+	if err := w.reload({{if .Tracing}}ctx, {{end}}path, format); err != nil {
+		w.Layer.Remove()
+		watcher.Close()
+		return nil, err
+	}
+	go w.run(ctx, watcher, path, format)
+	return w, nil
+}
+
+// Errors returns reload failures. Only the oldest unread error is kept; the channel
+// is closed once watching stops.
+func (w *{{watcherType .TypeName}}) Errors() <-chan error {
+	return w.errors
+}
+
+func (w *{{watcherType .TypeName}}) run(ctx context.Context, watcher *fsnotify.Watcher, path string, format {{.TypeName}}FileFormat) {
+	defer close(w.errors)
+	defer watcher.Close()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != path || !event.Has(fsnotify.Write|fsnotify.Create) {
+				continue
+			}
+			if err := w.reload({{if .Tracing}}ctx, {{end}}path, format); err != nil {
+				w.report(err)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			w.report(err)
+		}
+	}
+}
+
+// reload replaces the layer with the contents of the config file, keeping the previous
+// contents if the file fails to load or the broker's validator rejects it.
+func (w *{{watcherType .TypeName}}) reload({{if .Tracing}}ctx context.Context, {{end}}path string, format {{.TypeName}}FileFormat) {{if .Tracing}}(err error){{else}}error{{end}} {
+{{- if .Tracing}}
+	ctx, span := w.Layer.broker.tracer.Start(ctx, "{{watcherType .TypeName}}.load", trace.WithAttributes(attribute.String("config.file", path)))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "config file not loaded")
+		}
+		span.End()
+	}()
+{{- end}}
+	p, err := {{lower .TypeName}}LoadFile(path, format)
+	if err != nil {
+		return err
+	}
+	return w.Layer.{{if .Tracing}}ReplaceContext(ctx, p){{else}}Replace(p){{end}}
+}
+
+func (w *{{watcherType .TypeName}}) report(err error) {
+	select {
+	case w.errors <- err:
+	default:
+	}
+}
+
+func {{lower .TypeName}}LoadFile(path string, format {{.TypeName}}FileFormat) (*{{partial .TypeName}}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+	var p {{partial .TypeName}}
+	if err := format(data, &p); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", path, err)
+	}
+	return &p, nil
+}
+`
+
+const fileWatcherTestTemplate = `// Code generated by sudo-gen layerbroker. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func {{lower .TypeName}}WriteFile(t *testing.T, path string, p *{{partial .TypeName}}) {
+	t.Helper()
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test{{capitalize (watchFunc .TypeName)}}(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	path := filepath.Join(t.TempDir(), "config.json")
+{{- if .StringField}}
+	{{lower .TypeName}}WriteFile(t, path, &{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("from-file")})
+{{- else}}
+	{{lower .TypeName}}WriteFile(t, path, &{{partial .TypeName}}{})
+{{- end}}
+	broker := {{newBroker .TypeName}}(nil)
+	w, err := {{watchFunc .TypeName}}(ctx, broker, path, {{.TypeName}}FileJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+{{- if .StringField}}
+	if got := broker.Get().{{.StringField}}; got != "from-file" {
+		t.Fatalf("expected {{.StringField}}=from-file, got %s", got)
+	}
+	{{lower .TypeName}}WriteFile(t, path, &{{partial .TypeName}}{ {{.StringField}}: sudogenPtr("reloaded")})
+	deadline := time.Now().Add(5 * time.Second)
+	for broker.Get().{{.StringField}} != "reloaded" {
+		if time.Now().After(deadline) {
+			t.Fatalf("file change was not applied, {{.StringField}}=%s", broker.Get().{{.StringField}})
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+{{- end}}
+	if err := os.WriteFile(path, []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-w.Errors():
+		if err == nil {
+			t.Fatal("expected a decode error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("invalid file was not reported")
+	}
+{{- if .StringField}}
+	if got := broker.Get().{{.StringField}}; got != "reloaded" {
+		t.Errorf("expected last good contents to be kept, got {{.StringField}}=%s", got)
+	}
+{{- end}}
+}
+
+func Test{{capitalize (watchFunc .TypeName)}}MissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.json")
+	if _, err := {{watchFunc .TypeName}}(context.Background(), {{newBroker .TypeName}}(nil), path, {{.TypeName}}FileJSON); err == nil {
+		t.Fatal("expected an error for a missing file")
+	}
+}
+`
+
+const signalReloaderTemplate = `// Code generated by sudo-gen layerbroker. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// {{.TypeName}}ReloadFunc loads the partials of the layers a {{reloaderFunc .TypeName}} keeps,
+// lowest priority first, for example from a config file and then the environment.
+type {{.TypeName}}ReloadFunc func(ctx context.Context) ([]*{{partial .TypeName}}, error)
+
+// {{reloaderFunc .TypeName}} loads partials with load into new layers of broker, one
+// layer per partial, and loads them again whenever the process receives SIGHUP, until
+// ctx is done. Each reload replaces the layers entirely, so values dropped from a
+// source are dropped from the config. The initial load must succeed; later failures,
+// including validation errors, keep the previous layers and are passed to onError,
+// which may be nil.
+func {{reloaderFunc .TypeName}}(ctx context.Context, broker *{{brokerType .TypeName}}, load {{.TypeName}}ReloadFunc, onError func(error)) error {
+	// Subscribe before loading, so that a SIGHUP sent once the config is in place is
+	// never lost or left to terminate the process.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+	partials, err := load(ctx)
+	if err != nil {
+		return err
+	}
+	var layers []*{{layerType .TypeName}}
+	if err := {{lower .TypeName}}ReplaceLayers(broker, &layers, partials); err != nil {
+		for _, l := range layers {
+			l.Remove()
+		}
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-signals:
+			partials, err := load(ctx)
+			if err == nil {
+				err = {{lower .TypeName}}ReplaceLayers(broker, &layers, partials)
+			}
+			if err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// {{lower .TypeName}}ReplaceLayers replaces the contents of *layers with partials, adding
+// layers to broker for partials beyond the existing ones and clearing layers without a
+// partial.
+func {{lower .TypeName}}ReplaceLayers(broker *{{brokerType .TypeName}}, layers *[]*{{layerType .TypeName}}, partials []*{{partial .TypeName}}) error {
+	for len(*layers) < len(partials) {
+		*layers = append(*layers, broker.Layer())
+	}
+	for i, l := range *layers {
+		var p *{{partial .TypeName}}
+		if i < len(partials) {
+			p = partials[i]
+		}
+		if err := l.Replace(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+`
+
+const signalReloaderTestTemplate = `// Code generated by sudo-gen layerbroker. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func Test{{capitalize (reloaderFunc .TypeName)}}(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	broker := {{newBroker .TypeName}}(nil)
+	var loads atomic.Int32
+	load := func(context.Context) ([]*{{partial .TypeName}}, error) {
+{{- if .StringField}}
+		v := "load " + strconv.Itoa(int(loads.Add(1)))
+		return []*{{partial .TypeName}}{ { {{.StringField}}: &v}}, nil
+{{- else}}
+		loads.Add(1)
+		return []*{{partial .TypeName}}{ {}}, nil
+{{- end}}
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- {{reloaderFunc .TypeName}}(ctx, broker, load, nil)
+	}()
+	{{lower .TypeName}}WaitFor(t, func() bool { return loads.Load() == 1 })
+{{- if .StringField}}
+	{{lower .TypeName}}WaitFor(t, func() bool { return broker.Get().{{.StringField}} == "load 1" })
+{{- end}}
+	process, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := process.Signal(syscall.SIGHUP); err != nil {
+		t.Skipf("can't send SIGHUP on this platform: %v", err)
+	}
+	{{lower .TypeName}}WaitFor(t, func() bool { return loads.Load() == 2 })
+{{- if .StringField}}
+	{{lower .TypeName}}WaitFor(t, func() bool { return broker.Get().{{.StringField}} == "load 2" })
+{{- end}}
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("expected nil after cancellation, got %v", err)
+	}
+}
+
+func Test{{capitalize (reloaderFunc .TypeName)}}InitialError(t *testing.T) {
+	want := errors.New("no config")
+	load := func(context.Context) ([]*{{partial .TypeName}}, error) {
+		return nil, want
+	}
+	if err := {{reloaderFunc .TypeName}}(context.Background(), {{newBroker .TypeName}}(nil), load, nil); !errors.Is(err, want) {
+		t.Errorf("expected the initial load error, got %v", err)
+	}
+}
+
+func {{lower .TypeName}}WaitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the reloader")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+`
+
+const layerBrokerExampleTemplate = `// Code generated by sudo-gen layerbroker. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"fmt"
+)
+
+func Example{{brokerType .TypeName}}() {
+	broker := {{newBroker .TypeName}}(&{{.TypeName}}{ {{.StringField}}: "default"})
+	unsubscribe := broker.Subscribe(func(c *{{.TypeName}}) {
+		fmt.Println("subscriber:", c.{{.StringField}})
+	})
+	defer unsubscribe()
+	fromFile := "from file"
+	if err := broker.Layer().Named("file").Set(&{{partial .TypeName}}{ {{.StringField}}: &fromFile}); err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println("merged:", broker.Get().{{.StringField}})
+	// Output:
+	// subscriber: default
+	// subscriber: from file
+	// merged: from file
+}
+`
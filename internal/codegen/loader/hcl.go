@@ -0,0 +1,79 @@
+package loader
+
+import (
+	"strings"
+
+	"github.com/bobcob7/sudo-gen/internal/codegen"
+)
+
+// hclBody is a generated struct that hclsimple decodes the body of a config struct
+// into: the top level of a file, or a block.
+type hclBody struct {
+	Type   string // e.g. configHCLDatabaseConfig
+	Struct string // Name of the config struct
+	Fields []hclField
+}
+
+// hclField is a field of an hclBody.
+type hclField struct {
+	Name  string // Go field name, the same as in the config struct
+	Key   string // JSON key of the field in the partial
+	HCL   string // Name in HCL, from the hcl tag or else the JSON key
+	Kind  string // attr, label or block
+	List  bool   // A block that may repeat, decoded into a slice
+	Block string // Type of the body of a block
+}
+
+// newHCLBodies builds the bodies of structs, the first of which is the root. Fields
+// of local struct types are blocks, unless tagged hcl:",attr", and fields tagged
+// hcl:",label" take the labels of the block they are in, in field order.
+func newHCLBodies(root string, structs []*codegen.StructInfo, local map[string]*codegen.StructInfo) []hclBody {
+	var bodies []hclBody
+	for _, st := range structs {
+		body := hclBody{Type: hclBodyType(root, st.Name), Struct: st.Name}
+		for _, f := range decodedFields(st) {
+			name, kind := hclTag(f)
+			hf := hclField{Name: f.Name, Key: f.Key, HCL: name, Kind: kind}
+			if nested := localStruct(f.FieldInfo, local); nested != nil && kind != "attr" && kind != "label" {
+				hf.Kind, hf.List, hf.Block = "block", f.IsSlice, hclBodyType(root, nested.Name)
+			} else if kind != "label" {
+				hf.Kind = "attr"
+			}
+			body.Fields = append(body.Fields, hf)
+		}
+		bodies = append(bodies, body)
+	}
+	return bodies
+}
+
+// hclTag returns the HCL name of f and the kind its hcl tag sets, if any.
+func hclTag(f decodedField) (string, string) {
+	name, opts, _ := strings.Cut(f.StructTag().Get("hcl"), ",")
+	if name == "" {
+		name = f.Key
+	}
+	switch opts {
+	case "label", "block", "attr":
+		return name, opts
+	}
+	return name, ""
+}
+
+// hclLabels returns the HCL labels of a block of st, quoted, with the given value.
+func hclLabels(st *codegen.StructInfo, value string) string {
+	var labels string
+	for _, f := range decodedFields(st) {
+		if _, kind := hclTag(f); kind == "label" {
+			labels += " " + `"` + value + `"`
+		}
+	}
+	return labels
+}
+
+// hclBodyType returns the name of the generated body struct of a config struct.
+func hclBodyType(root, name string) string {
+	if name == root {
+		return strings.ToLower(root[:1]) + root[1:] + "HCL"
+	}
+	return strings.ToLower(root[:1]) + root[1:] + "HCL" + capitalize(name)
+}
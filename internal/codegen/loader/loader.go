@@ -0,0 +1,418 @@
+// Package loader implements the loader code generation subtool.
+package loader
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/bobcob7/sudo-gen/internal/codegen"
+)
+
+// Subtool implements the loader code generator.
+type Subtool struct{}
+
+// Name returns the subtool name.
+func (s *Subtool) Name() string { return "loader" }
+
+// Description returns the subtool description.
+func (s *Subtool) Description() string {
+	return "Generate functions decoding partials from JSON, YAML, TOML and HCL files"
+}
+
+// Doc describes the struct tags the subtool honors and the files it generates.
+func (s *Subtool) Doc() codegen.SubtoolDoc {
+	return codegen.SubtoolDoc{
+		Tags: []codegen.DocEntry{
+			{Name: `json:"name"`, Description: "Key of the field in documents of every format"},
+			{Name: `hcl:"name,block"`, Description: "Name and kind of the field in HCL documents"},
+			{Name: `sudo:"deprecated=use Field"`, Description: "Reported to a warning option when a document sets the field"},
+			codegen.ExcludeTagDoc(s.Name()),
+		},
+		Files: []codegen.DocEntry{
+			{Name: "{source}_loader.go", Description: "Decode{Type}Partial and Load{Type}PartialFile, with strict mode and Load{Type}Profile (and Load{Type}PartialsFromYAML with yaml, Load{Type}PartialFileChain with -extends, Migrate{Type}Partial with migrations in sudo-gen.yaml)"},
+			{Name: "{source}_loader_hcl.go", Description: "Load{Type}PartialFromHCL (with -formats=...,hcl)"},
+			{Name: "{source}_loader_mapstructure.go", Description: "{Type}DecodeHook and Decode{Type}PartialMap (with -mapstructure)"},
+		},
+	}
+}
+
+// extendsKey is the top-level key naming the parent of a document, with -extends.
+const extendsKey = "extends"
+
+// format is a file format that partials can be loaded from.
+type format struct {
+	Name       string   // -formats entry, e.g. yaml
+	Const      string   // Suffix of the generated format constant, e.g. YAML
+	Extensions []string // File extensions, with the dot
+	Import     string   // Package decoding the format in the loader file, if any
+}
+
+// formats lists the supported -formats entries in the order they are generated.
+var formats = []format{
+	{Name: "json", Const: "JSON", Extensions: []string{".json"}},
+	{Name: "yaml", Const: "YAML", Extensions: []string{".yaml", ".yml"}, Import: "gopkg.in/yaml.v3"},
+	{Name: "toml", Const: "TOML", Extensions: []string{".toml"}, Import: "github.com/BurntSushi/toml"},
+	{Name: "hcl", Const: "HCL", Extensions: []string{".hcl"}}, // Decoded in a file of its own
+}
+
+// ExtendsDoc returns a document of the format that extends the file at path, as a Go
+// string literal, or "" for formats that can't extend files.
+func (f format) ExtendsDoc(path string) string {
+	switch f.Name {
+	case "json":
+		return strconv.Quote(fmt.Sprintf(`{%q: %q}`, extendsKey, path))
+	case "yaml":
+		return strconv.Quote(fmt.Sprintf("%s: %q\n", extendsKey, path))
+	case "toml":
+		return strconv.Quote(fmt.Sprintf("%s = %q\n", extendsKey, path))
+	}
+	return ""
+}
+
+// DefineFlags defines the flags of the loader subcommand.
+func (s *Subtool) DefineFlags(fs *flag.FlagSet, cfg *codegen.GeneratorConfig) {
+	fs.Var((*codegen.ListFlag)(&cfg.Formats), "formats", "Comma-separated formats to decode partials from (json, yaml, toml, hcl; default: json)")
+	fs.BoolVar(&cfg.GenerateMapstructure, "mapstructure", false, "Also generate a mapstructure decode hook and Decode{Type}PartialMap for map[string]any input")
+	fs.BoolVar(&cfg.LoaderExtends, "extends", false, `Reserve the top-level "extends" key for the path of a parent file, and generate Load{Type}PartialFileChain loading the files a file extends`)
+	fs.BoolVar(&cfg.LoaderExpandEnv, "expand-env", false, "Expand ${VAR} and ${VAR:-default} in the string values of loaded documents, from the environment or a With{Type}EnvLookup option")
+	fs.BoolVar(&cfg.LoaderSecretRefs, "secret-refs", false, "Resolve string values such as secretref+vault://path#key with the resolver a With{Type}SecretResolver option gives for the scheme")
+}
+
+// Run executes the loader code generation. The generated functions decode into the
+// partial generated by merge for the same type.
+func (s *Subtool) Run(cfg codegen.GeneratorConfig) error {
+	selected, err := selectFormats(cfg.Formats)
+	if err != nil {
+		return err
+	}
+	info, err := cfg.Index.ParseStruct(cfg.SourceDir, cfg.SourceFile, cfg.Source, cfg.TypeName)
+	if err != nil {
+		return fmt.Errorf("parsing struct: %w", err)
+	}
+	// Select fields the way merge does, so that the keys match the fields of the partial
+	selection := codegen.NewFieldSelection(cfg, "merge")
+	selection.Apply(info)
+	values := codegen.NewValueTypes(cfg.Index, cfg.SourceDir, cfg.ValueTypes)
+	nested, err := cfg.Index.FindNestedStructs(cfg.SourceDir, cfg.Source, info, values)
+	if err != nil {
+		return fmt.Errorf("finding nested structs: %w", err)
+	}
+	structs := []*codegen.StructInfo{info}
+	local := make(map[string]*codegen.StructInfo)
+	for _, st := range nested {
+		if st.Package == "" {
+			selection.Apply(st)
+			structs = append(structs, st)
+			local[st.Name] = st
+		}
+	}
+	cfg, err = codegen.CheckUnsupported(cfg, s.Name(), codegen.UnsupportedFields(structs))
+	if err != nil {
+		return err
+	}
+	deprecations, err := codegen.Deprecations(info, local)
+	if err != nil {
+		return err
+	}
+	data := templateData{
+		Package:      cfg.OutputPkg,
+		TypeName:     info.Name,
+		Formats:      selected,
+		JSON:         selected[0].Name == "json",
+		YAML:         slices.ContainsFunc(selected, func(f format) bool { return f.Name == "yaml" }),
+		Mapstructure: cfg.GenerateMapstructure,
+		Deprecated:   len(deprecations) > 0,
+		Extends:      cfg.LoaderExtends,
+		ExpandEnv:    cfg.LoaderExpandEnv,
+		SecretRefs:   cfg.LoaderSecretRefs,
+	}
+	if data.Extends {
+		for _, f := range decodedFields(info) {
+			if strings.EqualFold(f.Key, extendsKey) {
+				return fmt.Errorf("-extends reserves the %q key, which %s.%s is decoded from", extendsKey, info.Name, f.Name)
+			}
+		}
+	}
+	for _, f := range selected {
+		if f.Import != "" {
+			data.Imports = append(data.Imports, f.Import)
+		}
+	}
+	for i, st := range structs {
+		data.Checkers = append(data.Checkers, newKeyChecker(info.Name, st, local))
+		if i == 0 && data.Extends {
+			data.Checkers[0].Cases = append(data.Checkers[0].Cases, keyCase{Key: extendsKey, Name: extendsKey})
+		}
+		data.URLs = data.URLs || hasURLs(st)
+	}
+	if data.Migrations, err = newMigrations(cfg.Project, info, local); err != nil {
+		return err
+	}
+	if slices.Contains(cfg.Formats, "hcl") {
+		data.HCLBodies = newHCLBodies(info.Name, structs, local)
+	}
+	baseName := strings.TrimSuffix(cfg.SourceFile, ".go")
+	gen := codegen.NewTemplateGenerator(cfg, templateFuncs())
+	if err := gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_loader.go"), loaderTemplate, data); err != nil {
+		return err
+	}
+	if data.HCLBodies != nil {
+		if err := gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_loader_hcl.go"), hclTemplate, data); err != nil {
+			return err
+		}
+	}
+	if data.Mapstructure {
+		if err := gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_loader_mapstructure.go"), mapstructureTemplate, data); err != nil {
+			return err
+		}
+	}
+	if cfg.GenerateTest {
+		data.Sample = newSample(info, local, selected)
+		data.Sample.Deprecated = deprecatedSample(deprecations)
+		data.Sample.Migration = newMigrationSample(data.Migrations)
+		return gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_loader_test.go"), loaderTestTemplate, data)
+	}
+	return nil
+}
+
+// selectFormats resolves -formats entries, defaulting to JSON.
+func selectFormats(names []string) ([]format, error) {
+	if len(names) == 0 {
+		names = []string{"json"}
+	}
+	var selected []format
+	for _, f := range formats {
+		if slices.Contains(names, f.Name) {
+			selected = append(selected, f)
+		}
+	}
+	for _, name := range names {
+		if !slices.ContainsFunc(formats, func(f format) bool { return f.Name == name }) {
+			return nil, fmt.Errorf("unknown loader format %q (supported: json, yaml, toml, hcl)", name)
+		}
+	}
+	return selected, nil
+}
+
+type templateData struct {
+	Package      string
+	TypeName     string
+	Formats      []format
+	JSON         bool      // JSON is one of the formats
+	YAML         bool      // YAML is one of the formats, so multi-document streams can be loaded
+	Imports      []string  // Packages decoding the formats other than JSON
+	HCLBodies    []hclBody // Bodies decoded by hclsimple, with hcl among the formats
+	Mapstructure bool      // Generate DecodeHook and DecodePartialMap
+	URLs         bool      // A struct has url.URL fields, which DecodeHook parses from strings
+	Deprecated   bool      // The partial has deprecated fields, reported to a warning option
+	Extends      bool      // Documents name the file they extend with a top-level extends key
+	ExpandEnv    bool      // String values expand ${VAR} references, looked up by a load option
+	SecretRefs   bool      // String values reference secrets, resolved by load options
+	Migrations   []migration
+	Checkers     []keyChecker
+	Sample       sample // Documents decoded by generated tests
+}
+
+// LoadOptions reports whether loading takes options, for deprecation warnings, the
+// lookup of environment variables or secret resolvers.
+func (d templateData) LoadOptions() bool {
+	return d.Deprecated || d.Resolves()
+}
+
+// Resolves reports whether the string values of decoded documents are resolved, by
+// expanding environment variables or retrieving the secrets they reference.
+func (d templateData) Resolves() bool {
+	return d.ExpandEnv || d.SecretRefs
+}
+
+// Rewrites reports whether decoded documents are rewritten, by migrations or the
+// resolution of their string values, before the partial is decoded from them.
+func (d templateData) Rewrites() bool {
+	return len(d.Migrations) > 0 || d.Resolves()
+}
+
+// Options returns the parameter declaring load options, if there are any.
+func (d templateData) Options() string {
+	if !d.LoadOptions() {
+		return ""
+	}
+	return ", opts ..." + d.TypeName + "LoadOption"
+}
+
+// PassOptions returns the argument passing on the load options, if there are any.
+func (d templateData) PassOptions() string {
+	if !d.LoadOptions() {
+		return ""
+	}
+	return ", opts..."
+}
+
+// keyChecker is a generated function reporting keys of a decoded document that
+// match no field of a struct, or whose values are of the wrong kind.
+type keyChecker struct {
+	Func  string
+	Cases []keyCase
+}
+
+// Keys returns the keys of the struct as written, quoted and separated by commas, to
+// suggest in place of unknown keys.
+func (c keyChecker) Keys() string {
+	quoted := make([]string, len(c.Cases))
+	for i, kc := range c.Cases {
+		quoted[i] = strconv.Quote(kc.Name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// keyCase is a key of a struct with the kind of value its field is decoded from and
+// the checker of the struct nested under it, if any.
+type keyCase struct {
+	Key   string // Lowercased, as encoding/json matches keys case-insensitively
+	Name  string // As written in the json tag
+	Kind  string // Such as "an integer", or "" for fields decoded from any value
+	Check string
+}
+
+// newKeyChecker builds the checker for st.
+func newKeyChecker(root string, st *codegen.StructInfo, local map[string]*codegen.StructInfo) keyChecker {
+	c := keyChecker{Func: checkerName(root, st.Name)}
+	for _, f := range decodedFields(st) {
+		kc := keyCase{Key: strings.ToLower(f.Key), Name: f.Key, Kind: valueKind(f.FieldInfo, local)}
+		if nested := localStruct(f.FieldInfo, local); nested != nil {
+			kc.Check = checkerName(root, nested.Name)
+		}
+		c.Cases = append(c.Cases, kc)
+	}
+	return c
+}
+
+// valueKind returns the kind of JSON value that encoding/json decodes f from, as it
+// reads in errors, or "" for the types of other packages and the named types of the
+// package that aren't structs, which may decode themselves from any value.
+func valueKind(f codegen.FieldInfo, local map[string]*codegen.StructInfo) string {
+	switch {
+	case f.IsBytes:
+		return "a string"
+	case f.IsSlice:
+		return "a list"
+	case f.IsMap:
+		return "an object"
+	case localStruct(f, local) != nil:
+		return "an object"
+	case f.TypePkg != "" || f.IsValue:
+		return ""
+	}
+	switch f.TypeName {
+	case "string":
+		return "a string"
+	case "bool":
+		return "a boolean"
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "uintptr":
+		return "an integer"
+	case "float32", "float64":
+		return "a number"
+	}
+	return ""
+}
+
+// decodedField is a field of a struct with the JSON object key it is decoded from.
+type decodedField struct {
+	codegen.FieldInfo
+	Key string
+}
+
+// decodedFields returns the fields of st that encoding/json decodes. Keys that more
+// than one field claims are left out, because encoding/json decodes none of those
+// fields.
+func decodedFields(st *codegen.StructInfo) []decodedField {
+	var fields []decodedField
+	claims := make(map[string]int)
+	for _, f := range st.Fields {
+		if key, ok := jsonKey(f); ok {
+			fields = append(fields, decodedField{FieldInfo: f, Key: key})
+			claims[strings.ToLower(key)]++
+		}
+	}
+	return slices.DeleteFunc(fields, func(f decodedField) bool {
+		return claims[strings.ToLower(f.Key)] > 1
+	})
+}
+
+// hasURLs reports whether st has url.URL or *url.URL fields.
+func hasURLs(st *codegen.StructInfo) bool {
+	return slices.ContainsFunc(st.Fields, func(f codegen.FieldInfo) bool {
+		return !f.IsSlice && !f.IsMap && f.TypeName == "URL" && importPath(st.Imports, f.TypePkg) == "net/url"
+	})
+}
+
+// localStruct returns the struct of the package that f holds directly, through a
+// pointer or in a slice, or nil if it holds none.
+func localStruct(f codegen.FieldInfo, local map[string]*codegen.StructInfo) *codegen.StructInfo {
+	if f.TypePkg != "" || f.IsMap || f.IsValue {
+		return nil
+	}
+	return local[f.StructTypeName]
+}
+
+// checkerName returns the name of the document checker of a struct.
+func checkerName(root, name string) string {
+	if name == root {
+		return "check" + capitalize(root) + "Doc"
+	}
+	return "check" + capitalize(root) + capitalize(name) + "Doc"
+}
+
+// jsonKey returns the JSON object key of f, reporting false for fields that
+// encoding/json skips.
+func jsonKey(f codegen.FieldInfo) (string, bool) {
+	name, _, _ := strings.Cut(f.StructTag().Get("json"), ",")
+	switch name {
+	case "-":
+		return "", false
+	case "":
+		return f.Name, true
+	}
+	return name, true
+}
+
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"lower":          strings.ToLower,
+		"capitalize":     capitalize,
+		"stringsLiteral": stringsLiteral,
+		"ident":          ident,
+		"plural":         plural,
+	}
+}
+
+// plural returns the plural of the type name, naming functions returning several
+// values of it, such as LoadConfigPatchesFromYAML.
+func plural(name string) string {
+	switch {
+	case strings.HasSuffix(name, "s"), strings.HasSuffix(name, "x"), strings.HasSuffix(name, "z"),
+		strings.HasSuffix(name, "ch"), strings.HasSuffix(name, "sh"):
+		return name + "es"
+	case strings.HasSuffix(name, "y") && len(name) > 1 && !strings.ContainsRune("aeiou", rune(name[len(name)-2])):
+		return name[:len(name)-1] + "ies"
+	}
+	return name + "s"
+}
+
+func capitalize(name string) string {
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// ident builds a generated name such as DecodeConfigPartial, keeping it unexported
+// (decodeConfigPartial) when the type is unexported.
+func ident(verb, typeName, suffix string) string {
+	if ast.IsExported(typeName) {
+		return capitalize(verb) + typeName + suffix
+	}
+	return verb + capitalize(typeName) + suffix
+}
@@ -0,0 +1,90 @@
+package loader
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bobcob7/sudo-gen/internal/codegen"
+)
+
+// migration is a key of documents renamed before they are decoded, as declared in
+// the migrations of sudo-gen.yaml.
+type migration struct {
+	From, To         string   // Dotted keys
+	FromKeys, ToKeys []string // The keys along the paths
+	ToString         bool     // The new key is a string field
+}
+
+// newMigrations returns the migrations that the project file declares for info. Their
+// new keys must name fields of the partial, outside slices and maps.
+func newMigrations(project codegen.ProjectFile, info *codegen.StructInfo, local map[string]*codegen.StructInfo) ([]migration, error) {
+	var result []migration
+	for _, m := range project.Migrations[info.Name] {
+		mg := migration{From: m.From, To: m.To, FromKeys: strings.Split(m.From, "."), ToKeys: strings.Split(m.To, ".")}
+		if hasPrefix(mg.FromKeys, mg.ToKeys) || hasPrefix(mg.ToKeys, mg.FromKeys) {
+			return nil, fmt.Errorf("%s: migration of %s from %s to %s: one key holds the other", project.Path, info.Name, m.From, m.To)
+		}
+		leaf, err := keyField(info, local, mg.ToKeys)
+		if err != nil {
+			return nil, fmt.Errorf("%s: migration of %s to %s: %w", project.Path, info.Name, m.To, err)
+		}
+		mg.ToString = leaf.Type == "string" || leaf.Type == "*string"
+		result = append(result, mg)
+	}
+	return result, nil
+}
+
+// keyField returns the field that a path of keys decodes into, through nested
+// structs outside slices and maps.
+func keyField(st *codegen.StructInfo, local map[string]*codegen.StructInfo, keys []string) (codegen.FieldInfo, error) {
+	var field codegen.FieldInfo
+	for i, key := range keys {
+		if i > 0 {
+			if st = localStruct(field, local); st == nil || field.IsSlice {
+				return codegen.FieldInfo{}, fmt.Errorf("%s is not an object", strings.Join(keys[:i], "."))
+			}
+		}
+		j := slices.IndexFunc(decodedFields(st), func(f decodedField) bool { return f.Key == key })
+		if j < 0 {
+			return codegen.FieldInfo{}, fmt.Errorf("%s has no field with key %q", st.Name, key)
+		}
+		field = decodedFields(st)[j].FieldInfo
+	}
+	return field, nil
+}
+
+// hasPrefix reports whether keys starts with prefix.
+func hasPrefix(keys, prefix []string) bool {
+	return len(prefix) <= len(keys) && slices.Equal(keys[:len(prefix)], prefix)
+}
+
+// migrationSample is a document that generated tests migrate.
+type migrationSample struct {
+	Doc      string // Document setting the old key of the first migration, as a Go map literal
+	JSON     string // The same document as a Go string literal, if the new key is a string field
+	From, To string // The keys along the paths, as Go []string literals
+}
+
+// newMigrationSample returns the sample of the first of migrations, if any.
+func newMigrationSample(migrations []migration) *migrationSample {
+	if len(migrations) == 0 {
+		return nil
+	}
+	m := migrations[0]
+	v := []sampleValue{{keys: m.FromKeys, value: "value"}}
+	s := &migrationSample{Doc: mapLiteral(v), From: stringsLiteral(m.FromKeys), To: stringsLiteral(m.ToKeys)}
+	if m.ToString {
+		s.JSON = strconv.Quote(document("json", v))
+	}
+	return s
+}
+
+func stringsLiteral(keys []string) string {
+	quoted := make([]string, len(keys))
+	for i, key := range keys {
+		quoted[i] = strconv.Quote(key)
+	}
+	return "[]string{" + strings.Join(quoted, ", ") + "}"
+}
@@ -0,0 +1,246 @@
+package loader
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bobcob7/sudo-gen/internal/codegen"
+)
+
+// sample holds the documents that generated tests decode, setting a top-level string
+// field and a duration field where the config has them.
+type sample struct {
+	Docs          []sampleDoc
+	Map           string // The values as a Go map[string]any literal
+	StringCheck   string // Expression over p that is true unless the string field decoded
+	StringKey     string // Key of the string field
+	DurationCheck string // Expression over p that is true unless the duration field decoded
+	DurationPath  string // Dotted key path of the duration field
+	NestedKey     string // Key of a nested struct, below which an unknown key is tested
+	Deprecated    string // JSON document setting a deprecated string field, as a Go string literal
+	Migration     *migrationSample
+	StringFormat  format // First format other than HCL, in which StringDoc writes, if the config has a string field
+	str           sampleValue
+}
+
+// StringDoc returns a document in StringFormat setting the string field to value, as a
+// Go string literal.
+func (s sample) StringDoc(value string) string {
+	v := s.str
+	v.value = value
+	return strconv.Quote(document(s.StringFormat.Name, []sampleValue{v}))
+}
+
+// Typo returns a misspelling of the key of the string field, with its last letter
+// doubled.
+func (s sample) Typo() string {
+	return s.StringKey + s.StringKey[len(s.StringKey)-1:]
+}
+
+// Doc returns the sample document in the named format, as a Go string literal.
+func (s sample) Doc(name string) string {
+	for _, doc := range s.Docs {
+		if doc.Format.Name == name {
+			return doc.Text
+		}
+	}
+	return `""`
+}
+
+// sampleDoc is a document in a format, as a Go string literal.
+type sampleDoc struct {
+	Format format
+	Text   string
+}
+
+// sampleValue is a value set by the sample documents.
+type sampleValue struct {
+	keys   []string
+	blocks []string // HCL names of the keys, the ones of blocks followed by their labels
+	value  string
+}
+
+// newSample builds the sample documents for info in each of the formats.
+func newSample(info *codegen.StructInfo, local map[string]*codegen.StructInfo, selected []format) sample {
+	var s sample
+	var values []sampleValue
+	for _, f := range decodedFields(info) {
+		if f.TypeName == "string" && f.TypePkg == "" && !f.IsPointer && !f.IsSlice && !f.IsMap {
+			s.StringCheck = fmt.Sprintf(`p.%s == nil || *p.%s != "value"`, f.Name, f.Name)
+			s.StringKey = f.Key
+			name, _ := hclTag(f)
+			s.str = sampleValue{keys: []string{f.Key}, blocks: []string{name}, value: "value"}
+			values = append(values, s.str)
+			for _, format := range selected {
+				if format.Name != "hcl" {
+					s.StringFormat = format
+					break
+				}
+			}
+			break
+		}
+	}
+	for _, f := range decodedFields(info) {
+		if nested := localStruct(f.FieldInfo, local); nested != nil && !f.IsSlice {
+			s.NestedKey = f.Key
+			break
+		}
+	}
+	if steps, ok := findDuration(info, local, map[string]bool{info.Name: true}); ok {
+		v := sampleValue{value: "1m30s"}
+		var checks []string
+		expr := "p"
+		for _, step := range steps {
+			expr += "." + step.Name
+			checks = append(checks, expr+" == nil")
+			name, _ := hclTag(step.decodedField)
+			if step.nested != nil {
+				name += hclLabels(step.nested, "label")
+			}
+			v.keys = append(v.keys, step.Key)
+			v.blocks = append(v.blocks, name)
+		}
+		s.DurationCheck = strings.Join(append(checks, "*"+expr+" != 90*time.Second"), " || ")
+		s.DurationPath = strings.Join(v.keys, ".")
+		values = append(values, v)
+	}
+	s.Map = mapLiteral(values)
+	for _, f := range selected {
+		s.Docs = append(s.Docs, sampleDoc{Format: f, Text: strconv.Quote(document(f.Name, values))})
+	}
+	return s
+}
+
+// deprecatedSample returns a JSON document setting the first deprecated string field
+// of deprecations, as a Go string literal, or "" if there is none.
+func deprecatedSample(deprecations []codegen.Deprecation) string {
+	for _, d := range deprecations {
+		leaf := d.Path[len(d.Path)-1]
+		if strings.TrimPrefix(leaf.Type, "*") != "string" {
+			continue
+		}
+		v := sampleValue{value: "value"}
+		for _, f := range d.Path {
+			key, ok := jsonKey(f)
+			if !ok {
+				break
+			}
+			v.keys = append(v.keys, key)
+		}
+		if len(v.keys) == len(d.Path) {
+			return strconv.Quote(document("json", []sampleValue{v}))
+		}
+	}
+	return ""
+}
+
+// pathStep is a field on the path to a value, with the struct it holds, if any.
+type pathStep struct {
+	decodedField
+	nested *codegen.StructInfo
+}
+
+// findDuration returns the path to a time.Duration field of st, through the nested
+// structs that are not in slices or maps. Structs reached through fields tagged
+// hcl:",attr" are skipped, as HCL documents set those with an object.
+func findDuration(st *codegen.StructInfo, local map[string]*codegen.StructInfo, seen map[string]bool) ([]pathStep, bool) {
+	for _, f := range decodedFields(st) {
+		if f.TypeName == "Duration" && importPath(st.Imports, f.TypePkg) == "time" && !f.IsSlice && !f.IsMap {
+			return []pathStep{{decodedField: f}}, true
+		}
+	}
+	for _, f := range decodedFields(st) {
+		nested := localStruct(f.FieldInfo, local)
+		if _, kind := hclTag(f); nested == nil || f.IsSlice || kind == "attr" || seen[nested.Name] {
+			continue
+		}
+		seen[nested.Name] = true
+		if steps, ok := findDuration(nested, local, seen); ok {
+			return append([]pathStep{{decodedField: f, nested: nested}}, steps...), true
+		}
+	}
+	return nil, false
+}
+
+// importPath resolves a package name used in a field type to its import path.
+func importPath(imports []codegen.ImportInfo, pkg string) string {
+	for _, imp := range imports {
+		name := imp.Alias
+		if name == "" {
+			name = imp.Path[strings.LastIndex(imp.Path, "/")+1:]
+		}
+		if name == pkg {
+			return imp.Path
+		}
+	}
+	return ""
+}
+
+// document writes values as a document in the named format. Values are listed
+// top-level first, so TOML tables follow the top-level keys.
+func document(name string, values []sampleValue) string {
+	var b strings.Builder
+	switch name {
+	case "json":
+		b.WriteString("{")
+		for i, v := range values {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			for _, key := range v.keys[:len(v.keys)-1] {
+				fmt.Fprintf(&b, "%q: {", key)
+			}
+			fmt.Fprintf(&b, "%q: %q", v.keys[len(v.keys)-1], v.value)
+			b.WriteString(strings.Repeat("}", len(v.keys)-1))
+		}
+		b.WriteString("}")
+	case "yaml":
+		for _, v := range values {
+			for depth, key := range v.keys[:len(v.keys)-1] {
+				fmt.Fprintf(&b, "%s%q:\n", strings.Repeat("  ", depth), key)
+			}
+			fmt.Fprintf(&b, "%s%q: %q\n", strings.Repeat("  ", len(v.keys)-1), v.keys[len(v.keys)-1], v.value)
+		}
+	case "toml":
+		for _, v := range values {
+			if len(v.keys) > 1 {
+				var table []string
+				for _, key := range v.keys[:len(v.keys)-1] {
+					table = append(table, strconv.Quote(key))
+				}
+				fmt.Fprintf(&b, "[%s]\n", strings.Join(table, "."))
+			}
+			fmt.Fprintf(&b, "%q = %q\n", v.keys[len(v.keys)-1], v.value)
+		}
+	case "hcl":
+		for _, v := range values {
+			for depth, block := range v.blocks[:len(v.blocks)-1] {
+				fmt.Fprintf(&b, "%s%s {\n", strings.Repeat("  ", depth), block)
+			}
+			fmt.Fprintf(&b, "%s%s = %q\n", strings.Repeat("  ", len(v.blocks)-1), v.blocks[len(v.blocks)-1], v.value)
+			for depth := len(v.blocks) - 2; depth >= 0; depth-- {
+				fmt.Fprintf(&b, "%s}\n", strings.Repeat("  ", depth))
+			}
+		}
+	}
+	return b.String()
+}
+
+// mapLiteral writes values as a Go map[string]any literal.
+func mapLiteral(values []sampleValue) string {
+	var b strings.Builder
+	b.WriteString("map[string]any{")
+	for i, v := range values {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		for _, key := range v.keys[:len(v.keys)-1] {
+			fmt.Fprintf(&b, "%q: map[string]any{", key)
+		}
+		fmt.Fprintf(&b, "%q: %q", v.keys[len(v.keys)-1], v.value)
+		b.WriteString(strings.Repeat("}", len(v.keys)-1))
+	}
+	b.WriteString("}")
+	return b.String()
+}
@@ -0,0 +1,1604 @@
+package loader
+
+const loaderTemplate = `// Code generated by sudo-gen loader. DO NOT EDIT.
+
+// {{ident "decode" (partial .TypeName) ""}} and {{ident "load" (partial .TypeName) "File"}} read a {{partial .TypeName}} from
+// {{range $i, $f := .Formats}}{{if $i}}, {{end}}{{.Const}}{{end}} documents. Every format is decoded with the partial's json
+// tags, durations are written as strings such as "1m30s", and URLs and IP addresses
+// as strings too:
+//
+//	p, err := {{ident "load" (partial .TypeName) "File"}}("config.{{(index .Formats 0).Name}}", true)
+//
+// # Dependencies
+//
+// This generated code requires the following to also be generated:
+//   - {{partial .TypeName}} (from: sudo-gen merge)
+package {{.Package}}
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+{{- if .YAML}}
+	"io"
+{{- end}}
+	"io/fs"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+{{- if .ExpandEnv}}
+	"regexp"
+{{- end}}
+	"slices"
+	"strconv"
+	"strings"
+{{- if .Imports}}
+{{range .Imports}}
+	"{{.}}"
+{{- end}}
+{{- end}}
+)
+
+// {{.TypeName}}Format is a file format that a {{partial .TypeName}} can be decoded from.
+type {{.TypeName}}Format string
+
+// Formats of {{partial .TypeName}} documents.
+const (
+{{- range .Formats}}
+	{{$.TypeName}}Format{{.Const}} {{$.TypeName}}Format = "{{.Name}}"
+{{- end}}
+)
+
+var (
+	// {{ident "err" .TypeName "UnknownFormat"}} is returned for a format or file extension that was not generated.
+	{{ident "err" .TypeName "UnknownFormat"}} = errors.New("unknown {{.TypeName}} format")
+	// {{ident "err" .TypeName "UnknownField"}} is returned in strict mode for a key that matches no field.
+	{{ident "err" .TypeName "UnknownField"}} = errors.New("unknown {{.TypeName}} field")
+{{- if .Extends}}
+	// {{ident "err" .TypeName "ExtendsCycle"}} is returned for a file that extends itself, directly or
+	// through its parents.
+	{{ident "err" .TypeName "ExtendsCycle"}} = errors.New("{{.TypeName}} file extends itself")
+{{- end}}
+{{- if .SecretRefs}}
+	// {{ident "err" .TypeName "SecretRef"}} is returned for a secret reference without a scheme, or
+	// with a scheme that no resolver was given for.
+	{{ident "err" .TypeName "SecretRef"}} = errors.New("unresolved {{.TypeName}} secret reference")
+{{- end}}
+)
+
+// {{.TypeName}}FormatFromPath returns the format of a file from its extension.
+func {{.TypeName}}FormatFromPath(path string) ({{.TypeName}}Format, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+{{- range .Formats}}
+	case {{range $i, $ext := .Extensions}}{{if $i}}, {{end}}"{{$ext}}"{{end}}:
+		return {{$.TypeName}}Format{{.Const}}, nil
+{{- end}}
+	default:
+		return "", fmt.Errorf("%w: %q", {{ident "err" .TypeName "UnknownFormat"}}, ext)
+	}
+}
+
+// {{ident "load" (partial .TypeName) "File"}} reads the file at path and decodes it in the format
+// given by its extension (see {{ident "decode" (partial .TypeName) ""}}).
+func {{ident "load" (partial .TypeName) "File"}}(path string, strict bool{{.Options}}) (*{{partial .TypeName}}, error) {
+	format, err := {{.TypeName}}FormatFromPath(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+{{- if .HCLBodies}}
+	if format == {{.TypeName}}FormatHCL {
+		// Diagnostics name the file
+		return {{ident "load" (partial .TypeName) "FromHCL"}}(path, data, strict{{.PassOptions}})
+	}
+{{- end}}
+	p, err := {{ident "decode" (partial .TypeName) ""}}(data, format, strict{{.PassOptions}})
+	if fieldErrs := {{lower .TypeName}}FieldErrors(err); len(fieldErrs) > 0 {
+		for _, fieldErr := range fieldErrs {
+			fieldErr.File = path
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+{{- if .Extends}}
+// {{ident "load" (partial .TypeName) "FileChain"}} loads the file at path as {{ident "load" (partial .TypeName) "File"}} does,
+// along with the files it extends. A document names the file it is loaded over with
+// a top-level "extends" key, relative to its own directory, and that file may extend
+// another one in turn. The partials are returned lowest priority first, ending with
+// the one of path, for setting on broker layers in order. HCL files can't extend
+// other files.
+func {{ident "load" (partial .TypeName) "FileChain"}}(path string, strict bool{{.Options}}) ([]*{{partial .TypeName}}, error) {
+	var chain []string
+	for path != "" {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		if i := slices.Index(chain, abs); i >= 0 {
+			return nil, fmt.Errorf("%w: %s", {{ident "err" .TypeName "ExtendsCycle"}}, strings.Join(append(chain[i:], abs), " extends "))
+		}
+		chain = append(chain, abs)
+		parent, err := {{lower .TypeName}}Extends(abs)
+		if err != nil {
+			return nil, err
+		}
+		if parent != "" && !filepath.IsAbs(parent) {
+			parent = filepath.Join(filepath.Dir(abs), parent)
+		}
+		path = parent
+	}
+	partials := make([]*{{partial .TypeName}}, 0, len(chain))
+	for i := len(chain) - 1; i >= 0; i-- {
+		p, err := {{ident "load" (partial .TypeName) "File"}}(chain[i], strict{{.PassOptions}})
+		if err != nil {
+			return nil, err
+		}
+		partials = append(partials, p)
+	}
+	return partials, nil
+}
+
+// {{lower .TypeName}}Extends returns the top-level "extends" key of the file at path, or "" if
+// it has none.
+func {{lower .TypeName}}Extends(path string) (string, error) {
+	format, err := {{.TypeName}}FormatFromPath(path)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var doc struct {
+		Extends string ` + "`" + `json:"extends" yaml:"extends" toml:"extends"` + "`" + `
+	}
+	switch format {
+{{- range .Formats}}
+{{- if eq .Name "json"}}
+	case {{$.TypeName}}FormatJSON:
+		err = json.Unmarshal(data, &doc)
+{{- else if eq .Name "yaml"}}
+	case {{$.TypeName}}FormatYAML:
+		err = yaml.Unmarshal(data, &doc)
+{{- else if eq .Name "toml"}}
+	case {{$.TypeName}}FormatTOML:
+		err = toml.Unmarshal(data, &doc)
+{{- end}}
+{{- end}}
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	return doc.Extends, nil
+}
+
+{{end -}}
+// {{ident "load" .TypeName "Profile"}} loads the config files of a profile from dir, lowest priority
+// first: config.{ext}, followed by config.{profile}.{ext} for each of the
+// comma-separated profiles, so that "production,eu" layers config.eu.yaml over
+// config.production.yaml over config.yaml. The extension of each file is one of a
+// generated format. The base file must exist, while a profile without a file is
+// skipped. An empty profile loads the base file alone.
+func {{ident "load" .TypeName "Profile"}}(dir, profile string, strict bool{{.Options}}) ([]*{{partial .TypeName}}, error) {
+	names := []string{"config"}
+	for _, name := range strings.Split(profile, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, "config."+name)
+		}
+	}
+	var partials []*{{partial .TypeName}}
+	for i, name := range names {
+		path, err := {{lower .TypeName}}ProfileFile(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		if path == "" {
+			if i == 0 {
+				return nil, fmt.Errorf("%w: no %s file in %s", fs.ErrNotExist, name, dir)
+			}
+			continue
+		}
+		p, err := {{ident "load" (partial .TypeName) "File"}}(path, strict{{.PassOptions}})
+		if err != nil {
+			return nil, err
+		}
+		partials = append(partials, p)
+	}
+	return partials, nil
+}
+
+// {{lower .TypeName}}ProfileFile returns the path of the file in dir named name with the
+// extension of a generated format, or "" if there is none. More than one is an error.
+func {{lower .TypeName}}ProfileFile(dir, name string) (string, error) {
+	found := ""
+	for _, ext := range []string{ {{- range $i, $f := .Formats}}{{range $j, $ext := .Extensions}}{{if or $i $j}}, {{end}}"{{$ext}}"{{end}}{{end -}} } {
+		path := filepath.Join(dir, name+ext)
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return "", err
+		}
+		if found != "" {
+			return "", fmt.Errorf("both %s and %s exist", found, path)
+		}
+		found = path
+	}
+	return found, nil
+}
+
+// {{ident "decode" (partial .TypeName) ""}} decodes a document in the given format. Documents other
+// than JSON are converted to JSON first, so keys are the json names of the fields and
+// durations are decoded from strings as in JSON. In strict mode, a key that matches no
+// field of the partial is an error wrapping {{ident "err" .TypeName "UnknownField"}}; otherwise it is ignored.
+// Unknown keys and values of the wrong kind for their field, such as a string for an
+// integer, are reported as a *{{.TypeName}}FieldError with the path of the key and, in
+// JSON and YAML documents, its line and column. All of them are reported at once, and
+// an unknown key close to the key of a field suggests it as a misspelling.
+{{- if .ExpandEnv}}
+// ${VAR} and ${VAR:-default} references in string values are expanded from the
+// environment, or from a {{ident "with" .TypeName "EnvLookup"}} option, before the partial is decoded.
+{{- end}}
+{{- if .SecretRefs}}
+// String values such as secretref+vault://secret/data/db#password are replaced with the
+// secret that the {{ident "with" .TypeName "SecretResolver"}} option of their scheme resolves.
+{{- end}}
+func {{ident "decode" (partial .TypeName) ""}}(data []byte, format {{.TypeName}}Format, strict bool{{.Options}}) (*{{partial .TypeName}}, error) {
+	source := data
+	var doc map[string]any
+	switch format {
+{{- range .Formats}}
+	case {{$.TypeName}}Format{{.Const}}:
+{{- if and (eq .Name "json") $.Rewrites}}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&doc); err != nil {
+			return nil, err
+		}
+{{- else if eq .Name "json"}}
+		if strict {
+			dec := json.NewDecoder(bytes.NewReader(data))
+			dec.UseNumber()
+			if err := dec.Decode(&doc); err != nil {
+				return nil, err
+			}
+			if err := {{(index $.Checkers 0).Func}}(doc, "", true); err != nil {
+				return nil, {{lower $.TypeName}}Locate(err, data, format)
+			}
+		}
+{{- else if eq .Name "hcl"}}
+		return {{ident "load" (partial $.TypeName) "FromHCL"}}("config.hcl", data, strict{{$.PassOptions}})
+{{- else}}
+{{- if eq .Name "yaml"}}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+{{- else if eq .Name "toml"}}
+		if err := toml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+{{- end}}
+{{- if not $.Rewrites}}
+		if strict {
+			if err := {{(index $.Checkers 0).Func}}(doc, "", true); err != nil {
+				return nil, {{lower $.TypeName}}Locate(err, data, format)
+			}
+		}
+		converted, err := json.Marshal(doc)
+		if err != nil {
+			return nil, fmt.Errorf("converting {{.Const}} to JSON: %w", err)
+		}
+		data = converted
+{{- end}}
+{{- end}}
+{{- end}}
+	default:
+		return nil, fmt.Errorf("%w: %q", {{ident "err" .TypeName "UnknownFormat"}}, format)
+	}
+{{- if .Rewrites}}
+{{- if .Resolves}}
+	resolved, err := {{lower .TypeName}}Resolve(doc, opts)
+	if err != nil {
+		return nil, err
+	}
+	doc = resolved
+{{- end}}
+{{- if .Migrations}}
+	doc = {{ident "migrate" (partial .TypeName) ""}}(doc)
+{{- end}}
+	if strict {
+		if err := {{(index .Checkers 0).Func}}(doc, "", true); err != nil {
+			return nil, {{lower .TypeName}}Locate(err, source, format)
+		}
+	}
+	converted, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("converting %s to JSON: %w", format, err)
+	}
+	data = converted
+{{- end}}
+	var p {{partial .TypeName}}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, {{lower .TypeName}}DecodeError(err, data, source, format)
+	}
+{{- if .Deprecated}}
+	return {{lower .TypeName}}Loaded(&p, opts), nil
+{{- else}}
+	return &p, nil
+{{- end}}
+}
+{{- if .YAML}}
+
+// {{ident "load" (plural (partial .TypeName)) "FromYAML"}} decodes each document of a multi-document YAML stream,
+// such as a base config followed by overlays, each starting with "---", as
+// {{ident "decode" (partial .TypeName) ""}} does. The partials are in the order of the documents, so that
+// setting them on broker layers in turn makes each overlay win over the documents
+// before it. Empty documents are skipped.
+func {{ident "load" (plural (partial .TypeName)) "FromYAML"}}(r io.Reader, strict bool{{.Options}}) ([]*{{partial .TypeName}}, error) {
+	dec := yaml.NewDecoder(r)
+	var partials []*{{partial .TypeName}}
+	for i := 1; ; i++ {
+		var node yaml.Node
+		if err := dec.Decode(&node); errors.Is(err, io.EOF) {
+			return partials, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("document %d: %w", i, err)
+		}
+		if len(node.Content) == 0 || node.Content[0].ShortTag() == "!!null" {
+			continue
+		}
+		data, err := yaml.Marshal(&node)
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %w", i, err)
+		}
+		p, err := {{ident "decode" (partial .TypeName) ""}}(data, {{.TypeName}}FormatYAML, strict{{.PassOptions}})
+		for _, fieldErr := range {{lower .TypeName}}FieldErrors(err) {
+			// Locate the key in the stream rather than the document marshaled from it
+			fieldErr.Line, fieldErr.Column = {{lower .TypeName}}YAMLPosition(&node, fieldErr.Path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %w", i, err)
+		}
+		partials = append(partials, p)
+	}
+}
+{{- end}}
+{{- if .LoadOptions}}
+
+// {{.TypeName}}LoadOption configures how a {{partial .TypeName}} is loaded.
+type {{.TypeName}}LoadOption func(*{{lower .TypeName}}LoadOptions)
+
+type {{lower .TypeName}}LoadOptions struct {
+{{- if .Deprecated}}
+	warn   func({{.TypeName}}Deprecation)
+{{- end}}
+{{- if .ExpandEnv}}
+	lookup func(string) (string, bool)
+{{- end}}
+{{- if .SecretRefs}}
+	resolvers map[string]{{.TypeName}}SecretResolver
+{{- end}}
+}
+{{- if .Deprecated}}
+
+// {{ident "with" .TypeName "DeprecationWarning"}} calls warn for each field tagged sudo:"deprecated" that a
+// loaded partial sets, such as to log that a config file needs updating.
+func {{ident "with" .TypeName "DeprecationWarning"}}(warn func({{.TypeName}}Deprecation)) {{.TypeName}}LoadOption {
+	return func(o *{{lower .TypeName}}LoadOptions) {
+		o.warn = warn
+	}
+}
+{{- end}}
+{{- if .ExpandEnv}}
+
+// {{ident "with" .TypeName "EnvLookup"}} looks up the variables that string values reference with
+// lookup instead of os.LookupEnv, such as to expand them from a map in tests.
+func {{ident "with" .TypeName "EnvLookup"}}(lookup func(string) (string, bool)) {{.TypeName}}LoadOption {
+	return func(o *{{lower .TypeName}}LoadOptions) {
+		o.lookup = lookup
+	}
+}
+{{- end}}
+
+{{- if .SecretRefs}}
+
+// {{ident "with" .TypeName "SecretResolver"}} resolves the string values that reference a secret with
+// the given scheme, such as secretref+vault://secret/data/db#password for "vault", with
+// resolver. Loading a reference to a scheme without a resolver is an error wrapping
+// {{ident "err" .TypeName "SecretRef"}}.
+func {{ident "with" .TypeName "SecretResolver"}}(scheme string, resolver {{.TypeName}}SecretResolver) {{.TypeName}}LoadOption {
+	return func(o *{{lower .TypeName}}LoadOptions) {
+		if o.resolvers == nil {
+			o.resolvers = make(map[string]{{.TypeName}}SecretResolver)
+		}
+		o.resolvers[scheme] = resolver
+	}
+}
+{{- end}}
+
+// {{lower .TypeName}}Options applies opts over the default load options.
+func {{lower .TypeName}}Options(opts []{{.TypeName}}LoadOption) {{lower .TypeName}}LoadOptions {
+{{- if .ExpandEnv}}
+	o := {{lower .TypeName}}LoadOptions{lookup: os.LookupEnv}
+{{- else}}
+	var o {{lower .TypeName}}LoadOptions
+{{- end}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+{{- end}}
+{{- if .Deprecated}}
+
+// {{lower .TypeName}}Loaded reports the deprecated fields of a loaded partial as opts ask,
+// and returns it.
+func {{lower .TypeName}}Loaded(p *{{partial .TypeName}}, opts []{{.TypeName}}LoadOption) *{{partial .TypeName}} {
+	if o := {{lower .TypeName}}Options(opts); o.warn != nil {
+		for _, d := range p.Deprecations() {
+			o.warn(d)
+		}
+	}
+	return p
+}
+{{- end}}
+{{- if .Resolves}}
+
+// {{lower .TypeName}}Resolve returns a copy of doc in which the string values, at any depth,
+{{- if and .ExpandEnv .SecretRefs}}
+// have their ${VAR} references expanded and are then resolved if they reference a secret.
+{{- else if .ExpandEnv}}
+// have their ${VAR} references expanded.
+{{- else}}
+// are resolved if they reference a secret.
+{{- end}}
+func {{lower .TypeName}}Resolve(doc map[string]any, opts []{{.TypeName}}LoadOption) (map[string]any, error) {
+	if doc == nil {
+		return nil, nil
+	}
+	o := {{lower .TypeName}}Options(opts)
+	resolved, err := {{lower .TypeName}}ResolveStrings(doc, "", func(path, s string) (string, error) {
+{{- if .ExpandEnv}}
+		s = {{lower .TypeName}}ExpandEnv(s, o.lookup)
+{{- end}}
+{{- if .SecretRefs}}
+		return {{lower .TypeName}}ResolveSecret(s, path, o.resolvers)
+{{- else}}
+		return s, nil
+{{- end}}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resolved.(map[string]any), nil
+}
+
+// {{lower .TypeName}}ResolveStrings returns a copy of v in which each string value is replaced
+// by what resolve returns for it and its key path, such as "servers[0].host".
+func {{lower .TypeName}}ResolveStrings(v any, path string, resolve func(path, s string) (string, error)) (any, error) {
+	switch v := v.(type) {
+	case string:
+		return resolve(path, v)
+	case map[string]any:
+		resolved := make(map[string]any, len(v))
+		for key, value := range v {
+			keyPath := key
+			if path != "" {
+				keyPath = path + "." + key
+			}
+			r, err := {{lower .TypeName}}ResolveStrings(value, keyPath, resolve)
+			if err != nil {
+				return nil, err
+			}
+			resolved[key] = r
+		}
+		return resolved, nil
+	case []any:
+		resolved := make([]any, len(v))
+		for i, value := range v {
+			r, err := {{lower .TypeName}}ResolveStrings(value, fmt.Sprintf("%s[%d]", path, i), resolve)
+			if err != nil {
+				return nil, err
+			}
+			resolved[i] = r
+		}
+		return resolved, nil
+	case []map[string]any:
+		resolved := make([]map[string]any, len(v))
+		for i, value := range v {
+			r, err := {{lower .TypeName}}ResolveStrings(value, fmt.Sprintf("%s[%d]", path, i), resolve)
+			if err != nil {
+				return nil, err
+			}
+			resolved[i] = r.(map[string]any)
+		}
+		return resolved, nil
+	default:
+		return v, nil
+	}
+}
+{{- end}}
+{{- if .ExpandEnv}}
+
+// {{lower .TypeName}}EnvRef matches a ${VAR} or ${VAR:-default} reference in a string value.
+var {{lower .TypeName}}EnvRef = regexp.MustCompile(` + "`" + `\$\{([A-Za-z_][A-Za-z0-9_]*)(:-[^}]*)?\}` + "`" + `)
+
+// {{lower .TypeName}}ExpandEnv replaces the ${VAR} references in s with the values lookup
+// returns. A ${VAR:-default} reference is replaced with the default when the variable
+// is unset or empty, and a ${VAR} reference to an unset variable with the empty
+// string, as in the shell.
+func {{lower .TypeName}}ExpandEnv(s string, lookup func(string) (string, bool)) string {
+	return {{lower .TypeName}}EnvRef.ReplaceAllStringFunc(s, func(ref string) string {
+		m := {{lower .TypeName}}EnvRef.FindStringSubmatch(ref)
+		if value, ok := lookup(m[1]); ok && (value != "" || m[2] == "") {
+			return value
+		}
+		return strings.TrimPrefix(m[2], ":-")
+	})
+}
+{{- end}}
+{{- if .SecretRefs}}
+
+// {{.TypeName}}SecretRef is a reference to a secret in a string value, written as
+// secretref+{scheme}://{path}#{key}, such as secretref+vault://secret/data/db#password.
+type {{.TypeName}}SecretRef struct {
+	Scheme string // Selects the resolver, e.g. vault
+	Path   string // Location of the secret in the store, e.g. secret/data/db
+	Key    string // Entry of the secret, after the #, if any
+}
+
+// String returns the reference as it is written in documents.
+func (r {{.TypeName}}SecretRef) String() string {
+	s := "secretref+" + r.Scheme + "://" + r.Path
+	if r.Key != "" {
+		s += "#" + r.Key
+	}
+	return s
+}
+
+// {{.TypeName}}SecretResolver retrieves the secrets referenced by string values, from a secret
+// store such as Vault or a cloud secret manager.
+type {{.TypeName}}SecretResolver interface {
+	ResolveSecret(ref {{.TypeName}}SecretRef) (string, error)
+}
+
+// {{.TypeName}}SecretResolverFunc adapts a function to a {{.TypeName}}SecretResolver.
+type {{.TypeName}}SecretResolverFunc func(ref {{.TypeName}}SecretRef) (string, error)
+
+// ResolveSecret returns f(ref).
+func (f {{.TypeName}}SecretResolverFunc) ResolveSecret(ref {{.TypeName}}SecretRef) (string, error) {
+	return f(ref)
+}
+
+// {{lower .TypeName}}ResolveSecret returns s, or the secret it references if it starts with
+// secretref+, from the resolver of its scheme.
+func {{lower .TypeName}}ResolveSecret(s, path string, resolvers map[string]{{.TypeName}}SecretResolver) (string, error) {
+	rest, ok := strings.CutPrefix(s, "secretref+")
+	if !ok {
+		return s, nil
+	}
+	scheme, location, ok := strings.Cut(rest, "://")
+	if !ok || scheme == "" {
+		return "", fmt.Errorf("%w: %s: %q has no scheme", {{ident "err" .TypeName "SecretRef"}}, path, s)
+	}
+	ref := {{.TypeName}}SecretRef{Scheme: scheme}
+	ref.Path, ref.Key, _ = strings.Cut(location, "#")
+	resolver, ok := resolvers[scheme]
+	if !ok {
+		return "", fmt.Errorf("%w: %s: no resolver for the %q scheme", {{ident "err" .TypeName "SecretRef"}}, path, scheme)
+	}
+	secret, err := resolver.ResolveSecret(ref)
+	if err != nil {
+		return "", fmt.Errorf("resolving %s for %s: %w", ref, path, err)
+	}
+	return secret, nil
+}
+{{- end}}
+
+// {{.TypeName}}FieldError is the error for a key of a document that matches no field in strict
+// mode, or whose value is of the wrong kind for its field, such as a string for an
+// integer. Line and Column locate the key in JSON and YAML documents, and File names
+// the file {{ident "load" (partial .TypeName) "File"}} read. The errors of all the keys of a
+// document are joined with errors.Join, one per line:
+//
+//	config.yaml:14:3: database.port: must be an integer
+//	config.yaml:17:3: database.hots: unknown {{.TypeName}} field, did you mean "host"?
+type {{.TypeName}}FieldError struct {
+	File   string
+	Line   int // Starting at 1, or 0 if the key wasn't located
+	Column int
+	Path   string // Keys from the top of the document, with list indexes, e.g. jobs[1].title
+	Err    error  // Wraps {{ident "err" .TypeName "UnknownField"}} for a key that matches no field
+}
+
+func (e *{{.TypeName}}FieldError) Error() string {
+	var location []string
+	if e.File != "" {
+		location = append(location, e.File)
+	}
+	if e.Line > 0 {
+		location = append(location, strconv.Itoa(e.Line), strconv.Itoa(e.Column))
+	}
+	if len(location) == 0 {
+		return e.Path + ": " + e.Err.Error()
+	}
+	return strings.Join(location, ":") + ": " + e.Path + ": " + e.Err.Error()
+}
+
+func (e *{{.TypeName}}FieldError) Unwrap() error {
+	return e.Err
+}
+{{- range .Checkers}}
+
+// {{.Func}} returns the errors for the keys of doc, in sorted order, whose values
+// are of the wrong kind for their field or, in strict mode, that match no field. Keys
+// are matched case-insensitively, as encoding/json does.
+func {{.Func}}(doc map[string]any, path string, strict bool) error {
+	var errs []error
+	for _, key := range slices.Sorted(maps.Keys(doc)) {
+		switch strings.ToLower(key) {
+{{- range .Cases}}
+		case "{{.Key}}":
+{{- if .Kind}}
+			if !{{lower $.TypeName}}IsKind(doc[key], "{{.Kind}}") {
+				errs = append(errs, &{{$.TypeName}}FieldError{Path: path + key, Err: errors.New("must be {{.Kind}}")})
+{{- if .Check}}
+			} else if err := {{lower $.TypeName}}CheckNested(doc[key], path+key, strict, {{.Check}}); err != nil {
+				errs = append(errs, err)
+{{- end}}
+			}
+{{- else if .Check}}
+			if err := {{lower $.TypeName}}CheckNested(doc[key], path+key, strict, {{.Check}}); err != nil {
+				errs = append(errs, err)
+			}
+{{- end}}
+{{- end}}
+		default:
+			if strict {
+				errs = append(errs, {{lower $.TypeName}}UnknownKey(path, key, {{.Keys}}))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+{{- end}}
+
+// {{lower .TypeName}}CheckNested checks the objects in a decoded value at path with check: the
+// value itself, or each object in a list.
+func {{lower .TypeName}}CheckNested(value any, path string, strict bool, check func(map[string]any, string, bool) error) error {
+	switch v := value.(type) {
+	case map[string]any:
+		return check(v, path+".", strict)
+	case []map[string]any:
+		var errs []error
+		for i, object := range v {
+			errs = append(errs, check(object, fmt.Sprintf("%s[%d].", path, i), strict))
+		}
+		return errors.Join(errs...)
+	case []any:
+		var errs []error
+		for i, elem := range v {
+			if object, ok := elem.(map[string]any); ok {
+				errs = append(errs, check(object, fmt.Sprintf("%s[%d].", path, i), strict))
+			}
+		}
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+// {{lower .TypeName}}UnknownKey returns the error for a key at path that matches none of the
+// keys of its struct, suggesting the nearest of them if it is close enough to be a
+// misspelling.
+func {{lower .TypeName}}UnknownKey(path, key string, keys ...string) error {
+	suggestion, best := "", len(key)/2+1
+	for _, k := range keys {
+		if d := {{lower .TypeName}}Distance(strings.ToLower(key), strings.ToLower(k)); d < best {
+			suggestion, best = k, d
+		}
+	}
+	if suggestion == "" {
+		return &{{.TypeName}}FieldError{Path: path + key, Err: {{ident "err" .TypeName "UnknownField"}}}
+	}
+	return &{{.TypeName}}FieldError{Path: path + key, Err: fmt.Errorf("%w, did you mean %q?", {{ident "err" .TypeName "UnknownField"}}, suggestion)}
+}
+
+// {{lower .TypeName}}Distance returns the Levenshtein distance between a and b: the number of
+// runes to insert, delete or substitute to turn one into the other.
+func {{lower .TypeName}}Distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := range ra {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i + 1
+		for j := range rb {
+			cost := 1
+			if ra[i] == rb[j] {
+				cost = 0
+			}
+			cur[j+1] = min(prev[j+1]+1, cur[j]+1, prev[j]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
+
+// {{lower .TypeName}}FieldErrors returns the field errors that err joins, or err itself if it is
+// one.
+func {{lower .TypeName}}FieldErrors(err error) []*{{.TypeName}}FieldError {
+	switch e := err.(type) {
+	case *{{.TypeName}}FieldError:
+		return []*{{.TypeName}}FieldError{e}
+	case interface{ Unwrap() []error }:
+		var fieldErrs []*{{.TypeName}}FieldError
+		for _, err := range e.Unwrap() {
+			fieldErrs = append(fieldErrs, {{lower .TypeName}}FieldErrors(err)...)
+		}
+		return fieldErrs
+	}
+	return nil
+}
+
+// {{lower .TypeName}}IsKind reports whether a decoded value is null or of a kind of JSON value,
+// such as "an integer". Values of types that no document decodes to, such as the
+// time.Time of a YAML timestamp, are of any kind.
+func {{lower .TypeName}}IsKind(value any, kind string) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return kind == "a string"
+	case bool:
+		return kind == "a boolean"
+	case json.Number:
+		return kind == "a number" || kind == "an integer" && !strings.ContainsAny(string(v), ".eE")
+	case int, int64, uint64:
+		return kind == "a number" || kind == "an integer"
+	case float64:
+		return kind == "a number" || kind == "an integer" && v == math.Trunc(v)
+	case []any, []map[string]any:
+		return kind == "a list"
+	case map[string]any:
+		return kind == "an object"
+	}
+	return true
+}
+
+// {{lower .TypeName}}Locate sets the lines and columns of the keys of the field errors in err,
+// from the JSON or YAML document they were decoded from, and returns err.
+func {{lower .TypeName}}Locate(err error, data []byte, format {{.TypeName}}Format) error {
+	fieldErrs := {{lower .TypeName}}FieldErrors(err)
+	switch format {
+{{- if .JSON}}
+	case {{.TypeName}}FormatJSON:
+		for _, fieldErr := range fieldErrs {
+			fieldErr.Line, fieldErr.Column = {{lower .TypeName}}JSONPosition(data, fieldErr.Path)
+		}
+{{- end}}
+{{- if .YAML}}
+	case {{.TypeName}}FormatYAML:
+		var node yaml.Node
+		if len(fieldErrs) == 0 || yaml.Unmarshal(data, &node) != nil {
+			break
+		}
+		for _, fieldErr := range fieldErrs {
+			fieldErr.Line, fieldErr.Column = {{lower .TypeName}}YAMLPosition(&node, fieldErr.Path)
+		}
+{{- end}}
+	}
+	return err
+}
+
+// {{lower .TypeName}}DecodeError returns the errors for the values of the wrong kind in data,
+// the JSON that a partial failed to decode from with err, located in source, the
+// document in format that data was converted from. It returns err if there are none.
+func {{lower .TypeName}}DecodeError(err error, data, source []byte, format {{.TypeName}}Format) error {
+	var doc map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if dec.Decode(&doc) != nil {
+		return err
+	}
+	if kindErr := {{(index .Checkers 0).Func}}(doc, "", false); kindErr != nil {
+		return {{lower .TypeName}}Locate(kindErr, source, format)
+	}
+	return err
+}
+
+// {{lower .TypeName}}PathStep is a key of a path such as jobs[1].title, with the index of the
+// list element under it, or -1.
+type {{lower .TypeName}}PathStep struct {
+	key   string
+	index int
+}
+
+// {{lower .TypeName}}Path splits a path into its steps.
+func {{lower .TypeName}}Path(path string) []{{lower .TypeName}}PathStep {
+	var steps []{{lower .TypeName}}PathStep
+	for _, part := range strings.Split(path, ".") {
+		step := {{lower .TypeName}}PathStep{index: -1}
+		key, index, ok := strings.Cut(part, "[")
+		step.key = key
+		if ok {
+			i, err := strconv.Atoi(strings.TrimSuffix(index, "]"))
+			if err != nil {
+				return nil
+			}
+			step.index = i
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+{{- if .JSON}}
+
+// {{lower .TypeName}}JSONPosition returns the line and column of the last key or list element of
+// path in a JSON document, or zeros if the document has none.
+func {{lower .TypeName}}JSONPosition(data []byte, path string) (line, column int) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	offset := -1
+	for _, step := range {{lower .TypeName}}Path(path) {
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+			return 0, 0
+		}
+		offset = -1
+		for dec.More() {
+			start := {{lower .TypeName}}SkipSpace(data, dec.InputOffset())
+			tok, err := dec.Token()
+			if err != nil {
+				return 0, 0
+			}
+			if tok == step.key {
+				offset = start
+				break
+			}
+			var skipped json.RawMessage
+			if err := dec.Decode(&skipped); err != nil {
+				return 0, 0
+			}
+		}
+		if offset < 0 {
+			return 0, 0
+		}
+		if step.index < 0 {
+			continue
+		}
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+			return 0, 0
+		}
+		for i := 0; i < step.index; i++ {
+			var skipped json.RawMessage
+			if err := dec.Decode(&skipped); err != nil {
+				return 0, 0
+			}
+		}
+		if !dec.More() {
+			return 0, 0
+		}
+		offset = {{lower .TypeName}}SkipSpace(data, dec.InputOffset())
+	}
+	if offset < 0 {
+		return 0, 0
+	}
+	line = bytes.Count(data[:offset], []byte("\n")) + 1
+	return line, offset - bytes.LastIndexByte(data[:offset], '\n')
+}
+
+// {{lower .TypeName}}SkipSpace returns the offset of the first byte of data from offset on that
+// is not white space or a separator.
+func {{lower .TypeName}}SkipSpace(data []byte, offset int64) int {
+	i := int(offset)
+	for i < len(data) && strings.IndexByte(" \t\r\n,:", data[i]) >= 0 {
+		i++
+	}
+	return i
+}
+{{- end}}
+{{- if .YAML}}
+
+// {{lower .TypeName}}YAMLPosition returns the line and column of the last key or list element of
+// path in a YAML node, or zeros if the node has none.
+func {{lower .TypeName}}YAMLPosition(node *yaml.Node, path string) (line, column int) {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	for _, step := range {{lower .TypeName}}Path(path) {
+		if node.Kind == yaml.AliasNode {
+			node = node.Alias
+		}
+		if node.Kind != yaml.MappingNode {
+			return 0, 0
+		}
+		var value *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if key := node.Content[i]; key.Value == step.key {
+				line, column = key.Line, key.Column
+				value = node.Content[i+1]
+				break
+			}
+		}
+		if value == nil {
+			return 0, 0
+		}
+		node = value
+		if step.index < 0 {
+			continue
+		}
+		if node.Kind == yaml.AliasNode {
+			node = node.Alias
+		}
+		if node.Kind != yaml.SequenceNode || step.index >= len(node.Content) {
+			return 0, 0
+		}
+		node = node.Content[step.index]
+		line, column = node.Line, node.Column
+	}
+	return line, column
+}
+{{- end}}
+{{- if .Migrations}}
+{{- $lower := lower .TypeName}}
+
+// {{ident "migrate" (partial .TypeName) ""}} returns a copy of doc, a decoded {{.TypeName}} document, with the keys
+// that moved renamed as the migrations of sudo-gen.yaml declare:
+//
+{{- range .Migrations}}
+//	{{.From}} -> {{.To}}
+{{- end}}
+//
+// Keys are matched exactly. A key stays in place if its new key is set too, or if a
+// value on the way to the new key is not an object. The objects along renamed keys are
+// copied, so doc is left unchanged. Documents are migrated before they are decoded.
+func {{ident "migrate" (partial .TypeName) ""}}(doc map[string]any) map[string]any {
+	doc = maps.Clone(doc)
+	for _, m := range {{$lower}}Migrations {
+		if _, ok := {{$lower}}Lookup(doc, m.to); ok {
+			continue
+		}
+		if value, ok := {{$lower}}Lookup(doc, m.from); ok && {{$lower}}Store(doc, m.to, value) {
+			{{$lower}}Delete(doc, m.from)
+		}
+	}
+	return doc
+}
+
+// {{$lower}}Migrations are the keys of {{.TypeName}} documents that moved, in the order
+// they are renamed.
+var {{$lower}}Migrations = []struct{ from, to []string }{
+{{- range .Migrations}}
+	{ {{stringsLiteral .FromKeys}}, {{stringsLiteral .ToKeys}} },
+{{- end}}
+}
+
+// {{$lower}}Lookup returns the value at a path of keys of doc.
+func {{$lower}}Lookup(doc map[string]any, path []string) (any, bool) {
+	for _, key := range path[:len(path)-1] {
+		nested, ok := doc[key].(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		doc = nested
+	}
+	value, ok := doc[path[len(path)-1]]
+	return value, ok
+}
+
+// {{$lower}}Store sets the value at a path of keys of doc, copying the objects on the
+// way and creating the missing ones. It reports false if a value on the way is not an
+// object.
+func {{$lower}}Store(doc map[string]any, path []string, value any) bool {
+	for _, key := range path[:len(path)-1] {
+		var nested map[string]any
+		switch v := doc[key].(type) {
+		case nil:
+			nested = make(map[string]any)
+		case map[string]any:
+			nested = maps.Clone(v)
+		default:
+			return false
+		}
+		doc[key] = nested
+		doc = nested
+	}
+	doc[path[len(path)-1]] = value
+	return true
+}
+
+// {{$lower}}Delete removes the value at a path of keys of doc, copying the objects on
+// the way.
+func {{$lower}}Delete(doc map[string]any, path []string) {
+	for _, key := range path[:len(path)-1] {
+		nested, ok := doc[key].(map[string]any)
+		if !ok {
+			return
+		}
+		nested = maps.Clone(nested)
+		doc[key] = nested
+		doc = nested
+	}
+	delete(doc, path[len(path)-1])
+}
+{{- end}}
+`
+
+const loaderTestTemplate = `// Code generated by sudo-gen loader. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test{{ident "decode" (partial .TypeName) ""}}(t *testing.T) {
+	tests := []struct {
+		format {{.TypeName}}Format
+		doc    string
+	}{
+{{- range .Sample.Docs}}
+		{ {{$.TypeName}}Format{{.Format.Const}}, {{.Text}} },
+{{- end}}
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			for _, strict := range []bool{false, true} {
+				p, err := {{ident "decode" (partial .TypeName) ""}}([]byte(tt.doc), tt.format, strict)
+				if err != nil {
+					t.Fatalf("strict=%v: %v", strict, err)
+				}
+{{- with .Sample.StringCheck}}
+				if {{.}} {
+					t.Errorf("strict=%v: string field not decoded", strict)
+				}
+{{- end}}
+{{- with .Sample.DurationCheck}}
+				if {{.}} {
+					t.Errorf("strict=%v: {{$.Sample.DurationPath}} not decoded from a duration string", strict)
+				}
+{{- end}}
+			}
+		})
+	}
+}
+{{- with index .Formats 0}}
+
+func Test{{ident "load" $.TypeName "Profile"}}(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := {{ident "load" $.TypeName "Profile"}}(dir, "", true); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist without a base file, got %v", err)
+	}
+	for _, name := range []string{"config", "config.production"} {
+		if err := os.WriteFile(filepath.Join(dir, name+"{{index .Extensions 0}}"), []byte({{$.Sample.Doc .Name}}), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for profile, want := range map[string]int{"": 1, "production": 2, "staging": 1, "production, staging": 2} {
+		partials, err := {{ident "load" $.TypeName "Profile"}}(dir, profile, true)
+		if err != nil {
+			t.Fatalf("profile %q: %v", profile, err)
+		}
+		if len(partials) != want {
+			t.Errorf("profile %q: got %d partials, want %d", profile, len(partials), want)
+		}
+	}
+}
+{{- end}}
+{{- if .Extends}}
+{{- with index .Formats 0}}
+{{- if .ExtendsDoc "base"}}
+
+func Test{{ident "load" (partial $.TypeName) "FileChain"}}(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"base{{index .Extensions 0}}":    {{$.Sample.Doc .Name}},
+		"child{{index .Extensions 0}}":   {{.ExtendsDoc (print "base" (index .Extensions 0))}},
+		"a{{index .Extensions 0}}":       {{.ExtendsDoc (print "b" (index .Extensions 0))}},
+		"b{{index .Extensions 0}}":       {{.ExtendsDoc (print "a" (index .Extensions 0))}},
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	partials, err := {{ident "load" (partial $.TypeName) "FileChain"}}(filepath.Join(dir, "child{{index .Extensions 0}}"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(partials) != 2 {
+		t.Fatalf("got %d partials, want 2", len(partials))
+	}
+{{- with $.Sample.StringCheck}}
+	if p := partials[0]; {{.}} {
+		t.Error("string field of the extended file not decoded")
+	}
+{{- end}}
+	if _, err := {{ident "load" (partial $.TypeName) "FileChain"}}(filepath.Join(dir, "a{{index .Extensions 0}}"), true); !errors.Is(err, {{ident "err" $.TypeName "ExtendsCycle"}}) {
+		t.Errorf("expected {{ident "err" $.TypeName "ExtendsCycle"}}, got %v", err)
+	}
+}
+{{- end}}
+{{- end}}
+{{- end}}
+{{- if .YAML}}
+
+func Test{{ident "load" (plural (partial .TypeName)) "FromYAML"}}(t *testing.T) {
+	stream := "---\n" + {{.Sample.Doc "yaml"}} + "\n---\n# Overlay\n{}\n---\n"
+	partials, err := {{ident "load" (plural (partial .TypeName)) "FromYAML"}}(strings.NewReader(stream), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(partials) != 2 {
+		t.Fatalf("got %d partials, want 2", len(partials))
+	}
+{{- with .Sample.StringCheck}}
+	if p := partials[0]; {{.}} {
+		t.Error("string field of the first document not decoded")
+	}
+{{- end}}
+	if _, err := {{ident "load" (plural (partial .TypeName)) "FromYAML"}}(strings.NewReader("{}\n---\nno_such_field: 1\n"), true); !errors.Is(err, {{ident "err" .TypeName "UnknownField"}}) {
+		t.Errorf("expected {{ident "err" .TypeName "UnknownField"}} for the second document, got %v", err)
+	}
+}
+{{- end}}
+{{- if .JSON}}
+
+func Test{{ident "decode" (partial .TypeName) ""}}Strict(t *testing.T) {
+	docs := []string{
+		` + "`" + `{"no_such_field": 1}` + "`" + `,
+{{- with .Sample.NestedKey}}
+		` + "`" + `{"{{.}}": {"no_such_field": 1}}` + "`" + `,
+{{- end}}
+	}
+	for _, doc := range docs {
+		if _, err := {{ident "decode" (partial .TypeName) ""}}([]byte(doc), {{.TypeName}}FormatJSON, true); !errors.Is(err, {{ident "err" .TypeName "UnknownField"}}) {
+			t.Errorf("%s: expected {{ident "err" .TypeName "UnknownField"}}, got %v", doc, err)
+		}
+		if _, err := {{ident "decode" (partial .TypeName) ""}}([]byte(doc), {{.TypeName}}FormatJSON, false); err != nil {
+			t.Errorf("%s: unknown field not ignored: %v", doc, err)
+		}
+	}
+}
+{{- if .Sample.StringKey}}
+
+func Test{{ident "decode" (partial .TypeName) ""}}UnknownFields(t *testing.T) {
+	doc := {{printf "{%q: \"value\", \"no_such_field\": 1}" .Sample.Typo | printf "%q"}}
+	_, err := {{ident "decode" (partial .TypeName) ""}}([]byte(doc), {{.TypeName}}FormatJSON, true)
+	if fieldErrs := {{lower .TypeName}}FieldErrors(err); len(fieldErrs) != 2 {
+		t.Fatalf("got %v, want an error for each unknown key", err)
+	}
+	if want := {{printf "did you mean %q?" .Sample.StringKey | printf "%q"}}; !strings.Contains(err.Error(), want) {
+		t.Errorf("got %v, want the suggestion %s", err, want)
+	}
+}
+{{- end}}
+{{- with .Sample.Migration}}
+
+func Test{{ident "migrate" (partial $.TypeName) ""}}(t *testing.T) {
+	doc := {{.Doc}}
+	migrated := {{ident "migrate" (partial $.TypeName) ""}}(doc)
+	if value, _ := {{lower $.TypeName}}Lookup(migrated, {{.To}}); value != "value" {
+		t.Errorf("expected the value to move to its new key, got %v", migrated)
+	}
+	if _, ok := {{lower $.TypeName}}Lookup(migrated, {{.From}}); ok {
+		t.Errorf("expected the old key to be removed, got %v", migrated)
+	}
+	if _, ok := {{lower $.TypeName}}Lookup(doc, {{.From}}); !ok {
+		t.Error("expected the document to be left unchanged")
+	}
+{{- if .JSON}}
+	if _, err := {{ident "decode" (partial $.TypeName) ""}}([]byte({{.JSON}}), {{$.TypeName}}FormatJSON, true); err != nil {
+		t.Errorf("expected the old key to be migrated before the strict check: %v", err)
+	}
+{{- end}}
+}
+{{- end}}
+{{- with .Sample.Deprecated}}
+
+func Test{{ident "decode" (partial $.TypeName) ""}}DeprecationWarning(t *testing.T) {
+	var warnings []{{$.TypeName}}Deprecation
+	warn := {{ident "with" $.TypeName "DeprecationWarning"}}(func(d {{$.TypeName}}Deprecation) {
+		warnings = append(warnings, d)
+	})
+	if _, err := {{ident "decode" (partial $.TypeName) ""}}([]byte({{.}}), {{$.TypeName}}FormatJSON, true, warn); err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("expected a warning for the deprecated field, got %v", warnings)
+	}
+	warnings = nil
+	if _, err := {{ident "decode" (partial $.TypeName) ""}}([]byte("{}"), {{$.TypeName}}FormatJSON, true, warn); err != nil || len(warnings) != 0 {
+		t.Errorf("expected no warnings for an empty document, got %v (%v)", warnings, err)
+	}
+}
+{{- end}}
+{{- end}}
+{{- if and .Sample.StringKey (or .JSON .YAML)}}
+
+func Test{{ident "decode" (partial .TypeName) ""}}FieldError(t *testing.T) {
+	tests := []struct {
+		format       {{.TypeName}}Format
+		doc          string
+		line, column int
+	}{
+{{- if .JSON}}
+		{ {{.TypeName}}FormatJSON, {{printf "{\n  %q: 1\n}" .Sample.StringKey | printf "%q"}}, 2, 3},
+{{- end}}
+{{- if .YAML}}
+		{ {{.TypeName}}FormatYAML, {{printf "# %s\n%q: 1\n" .TypeName .Sample.StringKey | printf "%q"}}, 2, 1},
+{{- end}}
+	}
+	for _, tt := range tests {
+		for _, strict := range []bool{false, true} {
+			_, err := {{ident "decode" (partial .TypeName) ""}}([]byte(tt.doc), tt.format, strict)
+			var fieldErr *{{.TypeName}}FieldError
+			if !errors.As(err, &fieldErr) {
+				t.Fatalf("%s, strict=%v: expected a *{{.TypeName}}FieldError, got %v", tt.format, strict, err)
+			}
+			if fieldErr.Path != {{printf "%q" .Sample.StringKey}} || fieldErr.Line != tt.line || fieldErr.Column != tt.column {
+				t.Errorf("%s, strict=%v: got %v, want the key at %d:%d", tt.format, strict, err, tt.line, tt.column)
+			}
+		}
+	}
+}
+{{- end}}
+{{- if and .ExpandEnv .Sample.StringFormat.Name}}
+
+func Test{{ident "decode" (partial .TypeName) ""}}ExpandEnv(t *testing.T) {
+	env := map[string]string{"SUDO_GEN_VALUE": "value", "SUDO_GEN_EMPTY": ""}
+	lookup := {{ident "with" .TypeName "EnvLookup"}}(func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	})
+	for _, doc := range []string{
+		{{.Sample.StringDoc "${SUDO_GEN_VALUE}"}},
+		{{.Sample.StringDoc "${SUDO_GEN_UNSET:-value}"}},
+		{{.Sample.StringDoc "${SUDO_GEN_EMPTY:-value}"}},
+		{{.Sample.StringDoc "val${SUDO_GEN_UNSET}ue"}},
+	} {
+		p, err := {{ident "decode" (partial .TypeName) ""}}([]byte(doc), {{.TypeName}}Format{{.Sample.StringFormat.Const}}, true, lookup)
+		if err != nil {
+			t.Fatalf("%s: %v", doc, err)
+		}
+		if {{.Sample.StringCheck}} {
+			t.Errorf("%s: string field not expanded to %q", doc, "value")
+		}
+	}
+}
+{{- end}}
+{{- if and .SecretRefs .Sample.StringFormat.Name}}
+
+func Test{{ident "decode" (partial .TypeName) ""}}SecretRefs(t *testing.T) {
+	errStore := errors.New("store unavailable")
+	resolver := {{ident "with" .TypeName "SecretResolver"}}("test", {{.TypeName}}SecretResolverFunc(func(ref {{.TypeName}}SecretRef) (string, error) {
+		if ref.Path == "unavailable" {
+			return "", errStore
+		}
+		if ref.Path != "db/creds" || ref.Key != "password" {
+			t.Errorf("resolving %s: unexpected path or key", ref)
+		}
+		return "value", nil
+	}))
+	p, err := {{ident "decode" (partial .TypeName) ""}}([]byte({{.Sample.StringDoc "secretref+test://db/creds#password"}}), {{.TypeName}}Format{{.Sample.StringFormat.Const}}, true, resolver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if {{.Sample.StringCheck}} {
+		t.Errorf("string field not resolved to %q", "value")
+	}
+	for doc, want := range map[string]error{
+		{{.Sample.StringDoc "secretref+other://db/creds#password"}}: {{ident "err" .TypeName "SecretRef"}},
+		{{.Sample.StringDoc "secretref+db/creds"}}:                  {{ident "err" .TypeName "SecretRef"}},
+		{{.Sample.StringDoc "secretref+test://unavailable"}}:        errStore,
+	} {
+		if _, err := {{ident "decode" (partial .TypeName) ""}}([]byte(doc), {{.TypeName}}Format{{.Sample.StringFormat.Const}}, true, resolver); !errors.Is(err, want) {
+			t.Errorf("%s: err = %v, want %v", doc, err, want)
+		}
+	}
+}
+{{- end}}
+{{- if .Mapstructure}}
+
+func Test{{ident "decode" (partial .TypeName) "Map"}}(t *testing.T) {
+	for _, strict := range []bool{false, true} {
+		p, err := {{ident "decode" (partial .TypeName) "Map"}}({{.Sample.Map}}, strict)
+		if err != nil {
+			t.Fatalf("strict=%v: %v", strict, err)
+		}
+{{- with .Sample.StringCheck}}
+		if {{.}} {
+			t.Errorf("strict=%v: string field not decoded", strict)
+		}
+{{- end}}
+{{- with .Sample.DurationCheck}}
+		if {{.}} {
+			t.Errorf("strict=%v: {{$.Sample.DurationPath}} not decoded from a duration string", strict)
+		}
+{{- end}}
+	}
+	m := map[string]any{"no_such_field": 1}
+	if _, err := {{ident "decode" (partial .TypeName) "Map"}}(m, true); !errors.Is(err, {{ident "err" .TypeName "UnknownField"}}) {
+		t.Errorf("expected {{ident "err" .TypeName "UnknownField"}}, got %v", err)
+	}
+	if _, err := {{ident "decode" (partial .TypeName) "Map"}}(m, false); err != nil {
+		t.Errorf("unknown field not ignored: %v", err)
+	}
+}
+{{- end}}
+{{- if .HCLBodies}}
+
+func Test{{ident "load" (partial .TypeName) "FromHCL"}}Strict(t *testing.T) {
+	docs := []string{
+		"no_such_field = 1\n",
+		"no_such_block {\n}\n",
+	}
+	for _, doc := range docs {
+		if _, err := {{ident "load" (partial .TypeName) "FromHCL"}}("config.hcl", []byte(doc), true); !errors.Is(err, {{ident "err" .TypeName "UnknownField"}}) {
+			t.Errorf("%q: expected {{ident "err" .TypeName "UnknownField"}}, got %v", doc, err)
+		}
+		if _, err := {{ident "load" (partial .TypeName) "FromHCL"}}("config.hcl", []byte(doc), false); err != nil {
+			t.Errorf("%q: unknown field not ignored: %v", doc, err)
+		}
+	}
+}
+{{- end}}
+
+func Test{{ident "load" (partial .TypeName) "File"}}(t *testing.T) {
+	dir := t.TempDir()
+{{- range .Sample.Docs}}
+	if err := os.WriteFile(filepath.Join(dir, "config{{index .Format.Extensions 0}}"), []byte({{.Text}}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := {{ident "load" (partial $.TypeName) "File"}}(filepath.Join(dir, "config{{index .Format.Extensions 0}}"), true); err != nil {
+		t.Errorf("{{.Format.Name}}: %v", err)
+	}
+{{- end}}
+	if _, err := {{ident "load" (partial .TypeName) "File"}}(filepath.Join(dir, "config.ini"), false); !errors.Is(err, {{ident "err" .TypeName "UnknownFormat"}}) {
+		t.Errorf("expected {{ident "err" .TypeName "UnknownFormat"}} for .ini, got %v", err)
+	}
+}
+`
+
+const hclTemplate = `// Code generated by sudo-gen loader. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsimple"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// {{ident "load" (partial .TypeName) "FromHCL"}} decodes HCL source into a {{partial .TypeName}} with hclsimple.
+// filename appears in error messages and selects the syntax: HCL's JSON syntax for
+// names ending in .json, and native syntax otherwise. Nested structs are blocks,
+// except for fields tagged hcl:",attr", and the labels of a block set its fields tagged
+// hcl:",label". Names are taken from hcl tags, or else from json tags. In strict mode,
+// an argument or block that matches no field is an error wrapping {{ident "err" .TypeName "UnknownField"}}.
+{{- if .ExpandEnv}}
+// Unlike the other formats, ${VAR} references in HCL strings are not expanded.
+{{- end}}
+func {{ident "load" (partial .TypeName) "FromHCL"}}(filename string, src []byte, strict bool{{.Options}}) (*{{partial .TypeName}}, error) {
+	var body {{(index .HCLBodies 0).Type}}
+	if err := hclsimple.Decode(filename, src, nil, &body); err != nil {
+		return nil, err
+	}
+	doc, err := body.object("", strict)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("converting HCL to JSON: %w", err)
+	}
+	var p {{partial .TypeName}}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+{{- if .Deprecated}}
+	return {{lower .TypeName}}Loaded(&p, opts), nil
+{{- else}}
+	return &p, nil
+{{- end}}
+}
+{{- range .HCLBodies}}
+
+// {{.Type}} is the HCL body of a {{.Struct}}.
+type {{.Type}} struct {
+{{- range .Fields}}
+{{- if eq .Kind "label"}}
+	{{.Name}} string ` + "`" + `hcl:"{{.HCL}},label"` + "`" + `
+{{- else if and (eq .Kind "block") .List}}
+	{{.Name}} []{{.Block}} ` + "`" + `hcl:"{{.HCL}},block"` + "`" + `
+{{- else if eq .Kind "block"}}
+	{{.Name}} *{{.Block}} ` + "`" + `hcl:"{{.HCL}},block"` + "`" + `
+{{- else}}
+	{{.Name}} hcl.Expression ` + "`" + `hcl:"{{.HCL}},optional"` + "`" + `
+{{- end}}
+{{- end}}
+	HCLRemain hcl.Body ` + "`" + `hcl:",remain"` + "`" + `
+}
+
+// object returns the JSON object of the partial that b sets, keyed by json names.
+func (b *{{.Type}}) object(path string, strict bool) (map[string]any, error) {
+	if strict {
+		if err := {{lower $.TypeName}}HCLCheckRemain(b.HCLRemain, path); err != nil {
+			return nil, err
+		}
+	}
+	doc := make(map[string]any)
+{{- range .Fields}}
+{{- if eq .Kind "label"}}
+	doc["{{.Key}}"] = b.{{.Name}}
+{{- else if and (eq .Kind "block") .List}}
+	if b.{{.Name}} != nil {
+		list := make([]any, 0, len(b.{{.Name}}))
+		for i := range b.{{.Name}} {
+			object, err := b.{{.Name}}[i].object(path+"{{.Key}}.", strict)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, object)
+		}
+		doc["{{.Key}}"] = list
+	}
+{{- else if eq .Kind "block"}}
+	if b.{{.Name}} != nil {
+		object, err := b.{{.Name}}.object(path+"{{.Key}}.", strict)
+		if err != nil {
+			return nil, err
+		}
+		doc["{{.Key}}"] = object
+	}
+{{- else}}
+	if err := {{lower $.TypeName}}HCLValue(doc, "{{.Key}}", b.{{.Name}}); err != nil {
+		return nil, err
+	}
+{{- end}}
+{{- end}}
+	return doc, nil
+}
+{{- end}}
+
+// {{lower .TypeName}}HCLValue sets doc[key] to the JSON encoding of the value of an argument,
+// unless it is absent or null.
+func {{lower .TypeName}}HCLValue(doc map[string]any, key string, expr hcl.Expression) error {
+	if expr == nil {
+		return nil
+	}
+	value, diags := expr.Value(nil)
+	if diags.HasErrors() {
+		return diags
+	}
+	if value.IsNull() {
+		return nil
+	}
+	data, err := ctyjson.SimpleJSONValue{Value: value}.MarshalJSON()
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	doc[key] = json.RawMessage(data)
+	return nil
+}
+
+// {{lower .TypeName}}HCLCheckRemain returns an error for the first argument or block left in
+// body after decoding, which matches no field.
+func {{lower .TypeName}}HCLCheckRemain(body hcl.Body, path string) error {
+	if body == nil {
+		return nil
+	}
+	attrs, diags := body.JustAttributes()
+	if len(attrs) > 0 {
+		return fmt.Errorf("%w: %s%s", {{ident "err" .TypeName "UnknownField"}}, path, slices.Sorted(maps.Keys(attrs))[0])
+	}
+	if diags.HasErrors() {
+		// JustAttributes rejects the blocks that are left
+		return fmt.Errorf("%w: %s", {{ident "err" .TypeName "UnknownField"}}, diags.Error())
+	}
+	return nil
+}
+`
+
+const mapstructureTemplate = `// Code generated by sudo-gen loader. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- if .URLs}}
+	"net/url"
+	"reflect"
+{{- end}}
+	"time"
+
+	"github.com/go-viper/mapstructure/v2"
+)
+
+// {{.TypeName}}DecodeHook returns the decode hooks that {{ident "decode" (partial .TypeName) "Map"}} uses, for
+// decoding parts of a {{.TypeName}} with a mapstructure.DecoderConfig of your own. Strings
+// are converted to durations ("1m30s"), to times (RFC 3339),{{if .URLs}} to URLs,{{end}} and to types
+// implementing encoding.TextUnmarshaler, such as enums, netip.Addr and net.IP.
+func {{.TypeName}}DecodeHook() mapstructure.DecodeHookFunc {
+	return mapstructure.ComposeDecodeHookFunc(
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToTimeHookFunc(time.RFC3339),
+{{- if .URLs}}
+		{{lower .TypeName}}StringToURLHook,
+{{- end}}
+		mapstructure.TextUnmarshallerHookFunc(),
+	)
+}
+{{- if .URLs}}
+
+// {{lower .TypeName}}StringToURLHook converts strings to url.URL values with url.Parse.
+func {{lower .TypeName}}StringToURLHook(from, to reflect.Type, data any) (any, error) {
+	s, ok := data.(string)
+	if !ok || to != reflect.TypeFor[url.URL]() {
+		return data, nil
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	return *u, nil
+}
+{{- end}}
+
+// {{ident "decode" (partial .TypeName) "Map"}} decodes a map, such as Helm values or Terraform outputs, into a
+// {{partial .TypeName}} with mapstructure. Keys are the json names of the fields, and
+// values are converted with {{.TypeName}}DecodeHook. In strict mode, a key that matches
+// no field is an error wrapping {{ident "err" .TypeName "UnknownField"}}; otherwise it is ignored.
+func {{ident "decode" (partial .TypeName) "Map"}}(m map[string]any, strict bool{{.Options}}) (*{{partial .TypeName}}, error) {
+{{- if .Resolves}}
+	m, err := {{lower .TypeName}}Resolve(m, opts)
+	if err != nil {
+		return nil, err
+	}
+{{- end}}
+{{- if .Migrations}}
+	m = {{ident "migrate" (partial .TypeName) ""}}(m)
+{{- end}}
+	if strict {
+		if err := {{(index .Checkers 0).Func}}(m, "", true); err != nil {
+			return nil, err
+		}
+	}
+	var p {{partial .TypeName}}
+	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: {{.TypeName}}DecodeHook(),
+		Result:     &p,
+		TagName:    "json",
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := dec.Decode(m); err != nil {
+		return nil, err
+	}
+{{- if .Deprecated}}
+	return {{lower .TypeName}}Loaded(&p, opts), nil
+{{- else}}
+	return &p, nil
+{{- end}}
+}
+`
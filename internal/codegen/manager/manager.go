@@ -0,0 +1,150 @@
+// Package manager implements the manager code generation subtool.
+package manager
+
+import (
+	"fmt"
+	"go/ast"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/bobcob7/sudo-gen/internal/codegen"
+)
+
+// Subtool implements the manager code generator.
+type Subtool struct{}
+
+// Name returns the subtool name.
+func (s *Subtool) Name() string { return "manager" }
+
+// Description returns the subtool description.
+func (s *Subtool) Description() string {
+	return "Generate a mutex-guarded config manager with path-based getters, setters and subscriptions"
+}
+
+// Doc describes the struct tags the subtool honors and the files it generates.
+func (s *Subtool) Doc() codegen.SubtoolDoc {
+	return codegen.SubtoolDoc{
+		Tags: []codegen.DocEntry{
+			{Name: `json:"name"`, Description: "Segment of the field in dotted paths"},
+			codegen.ExcludeTagDoc(s.Name()),
+		},
+		Files: []codegen.DocEntry{
+			{Name: "{source}_manager.go", Description: "{Type}Manager with GetPath, SetPath and Subscribe by dotted path, and a {Type}Path constant per path"},
+		},
+	}
+}
+
+// Run executes the manager code generation. The generated manager builds on the
+// copy and equals output for the same type.
+func (s *Subtool) Run(cfg codegen.GeneratorConfig) error {
+	info, err := cfg.Index.ParseStruct(cfg.SourceDir, cfg.SourceFile, cfg.Source, cfg.TypeName)
+	if err != nil {
+		return fmt.Errorf("parsing struct: %w", err)
+	}
+	selection := codegen.NewFieldSelection(cfg, s.Name())
+	selection.Apply(info)
+	values := codegen.NewValueTypes(cfg.Index, cfg.SourceDir, cfg.ValueTypes)
+	values.Apply(info)
+	nested, err := cfg.Index.FindNestedStructs(cfg.SourceDir, cfg.Source, info, values)
+	if err != nil {
+		return fmt.Errorf("finding nested structs: %w", err)
+	}
+	structs := []*codegen.StructInfo{info}
+	local := make(map[string]*codegen.StructInfo)
+	for _, st := range nested {
+		if st.Package == "" {
+			selection.Apply(st)
+			values.Apply(st)
+			structs = append(structs, st)
+			local[st.Name] = st
+		}
+	}
+	cfg, err = codegen.CheckUnsupported(cfg, s.Name(), codegen.UnsupportedFields(structs))
+	if err != nil {
+		return err
+	}
+	c := &pathCollector{typeName: info.Name, equal: cfg.Method("Equal"), style: cfg.Project.Style, local: local, seen: map[string]bool{info.Name: true}}
+	if err := c.collect(info, "c", "", "", nil); err != nil {
+		return err
+	}
+	if len(c.paths) == 0 {
+		return fmt.Errorf("%s has no fields to manage", info.Name)
+	}
+	data := templateData{
+		Package:  cfg.OutputPkg,
+		TypeName: info.Name,
+		Paths:    c.paths,
+		Imports:  collectImports(structs),
+	}
+	data.StringPath, data.NestedStringPath = testPaths(c.paths)
+	baseName := strings.TrimSuffix(cfg.SourceFile, ".go")
+	gen := codegen.NewTemplateGenerator(cfg, templateFuncs())
+	if err := gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_manager.go"), managerTemplate, data); err != nil {
+		return err
+	}
+	if cfg.GenerateTest {
+		return gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_manager_test.go"), managerTestTemplate, data)
+	}
+	return nil
+}
+
+type templateData struct {
+	Package          string
+	TypeName         string
+	Paths            []configPath
+	Imports          []codegen.ImportInfo // Imports of the managed structs; unused ones are dropped
+	StringPath       *configPath          // A top-level string path used by generated tests
+	NestedStringPath *configPath          // A string path below a struct pointer used by generated tests
+}
+
+// collectImports returns the imports of structs, which paths may need to name the
+// types of their values.
+func collectImports(structs []*codegen.StructInfo) []codegen.ImportInfo {
+	var imports []codegen.ImportInfo
+	for _, st := range structs {
+		for _, imp := range st.Imports {
+			if !slices.Contains(imports, imp) {
+				imports = append(imports, imp)
+			}
+		}
+	}
+	return imports
+}
+
+// testPaths returns the string paths that generated tests set, if any.
+func testPaths(paths []configPath) (top, nested *configPath) {
+	for i, p := range paths {
+		if p.Type != "string" {
+			continue
+		}
+		if top == nil && !strings.Contains(p.Path, ".") {
+			top = &paths[i]
+		}
+		if nested == nil && len(p.Parents) > 0 {
+			nested = &paths[i]
+		}
+	}
+	return top, nested
+}
+
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"lower": strings.ToLower,
+		"ident": ident,
+	}
+}
+
+func capitalize(name string) string {
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// ident builds a generated name such as NewConfigManager, keeping it unexported
+// (newConfigManager) when the type is unexported.
+func ident(verb, typeName, suffix string) string {
+	if ast.IsExported(typeName) {
+		return capitalize(verb) + typeName + suffix
+	}
+	return verb + capitalize(typeName) + suffix
+}
@@ -0,0 +1,168 @@
+package manager
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bobcob7/sudo-gen/internal/codegen"
+)
+
+// configPath is a value in the config that the manager can get, set and subscribe
+// to by its dotted path.
+type configPath struct {
+	Path    string      // Dotted path of json field names, e.g. database.host
+	Const   string      // Generated constant holding Path, e.g. ConfigPathDatabaseHost
+	Func    string      // Suffix of the generated accessor functions, e.g. DatabaseHost
+	Expr    string      // Expression reading the value from c, e.g. c.Database.Host
+	Type    string      // Go type of the value
+	Parents []parentRef // Nested struct pointers on the way, nil-checked and allocated
+	Shared  bool        // The value shares memory with the config, so it is copied in and out
+	Differ  string      // Expression reporting whether a and b, both of Type, differ
+}
+
+// parentRef is a pointer to a nested struct on the way to a path.
+type parentRef struct {
+	Expr string // e.g. c.Database
+	Type string // e.g. DatabaseConfig
+}
+
+// pathCollector walks a config struct and the local structs nested in it.
+type pathCollector struct {
+	typeName string
+	equal    string        // Generated Equal method of local structs
+	style    codegen.Style // Receivers of the Equal methods
+	local    map[string]*codegen.StructInfo
+	seen     map[string]bool // Struct types on the current path, to stop at recursive types
+	paths    []configPath
+}
+
+// collect adds the paths of the fields of st, which is reached from the config
+// through expr.
+func (c *pathCollector) collect(st *codegen.StructInfo, expr, prefix, funcPrefix string, parents []parentRef) error {
+	for _, f := range st.Fields {
+		p := configPath{
+			Path:    prefix + pathKey(f),
+			Func:    funcPrefix + f.Name,
+			Expr:    expr + "." + f.Name,
+			Type:    f.Type,
+			Parents: parents,
+		}
+		p.Const = c.typeName + "Path" + p.Func
+		p.Shared = isShared(f, c.local)
+		p.Differ = c.differExpr(f.Type, "a", "b")
+		for _, existing := range c.paths {
+			if existing.Path == p.Path {
+				return fmt.Errorf("%s.%s: path %q is already used by another field", st.Name, f.Name, p.Path)
+			}
+		}
+		c.paths = append(c.paths, p)
+		nested, ok := c.local[f.StructTypeName]
+		if !ok || f.TypePkg != "" || f.IsSlice || f.IsMap || f.IsValue || c.seen[nested.Name] {
+			continue
+		}
+		next := parents
+		if f.IsPointer {
+			next = append(parents[:len(parents):len(parents)], parentRef{Expr: p.Expr, Type: nested.Name})
+		}
+		c.seen[nested.Name] = true
+		err := c.collect(nested, p.Expr, p.Path+".", p.Func, next)
+		delete(c.seen, nested.Name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pathKey returns the path segment of f: its json name, or its Go name without one.
+func pathKey(f codegen.FieldInfo) string {
+	if name, _, _ := strings.Cut(f.StructTag().Get("json"), ","); name != "" && name != "-" {
+		return name
+	}
+	return f.Name
+}
+
+// isShared reports whether a value of the type of f can share memory with the config
+// it was read from.
+func isShared(f codegen.FieldInfo, local map[string]*codegen.StructInfo) bool {
+	if f.IsPointer || f.IsSlice || f.IsMap || f.Type == "any" || f.Type == "interface{}" {
+		return true
+	}
+	// Copies of local structs are shallow, so they share whatever the struct holds
+	_, ok := local[f.StructTypeName]
+	return ok && f.TypePkg == ""
+}
+
+// differExpr returns a boolean expression reporting whether a and b, of Go type typ,
+// differ. Local structs are compared with their generated equal methods, and types
+// without a known comparison through their %#v formatting, as the hash generator
+// does.
+func (c *pathCollector) differExpr(typ, a, b string) string {
+	if isComparable(typ) {
+		return a + " != " + b
+	}
+	if typ == "time.Time" {
+		return "!" + receiver(a) + ".Equal(" + b + ")"
+	}
+	if _, ok := c.local[typ]; ok {
+		return "!(&" + a + ")." + c.equal + "(&" + b + ")"
+	}
+	if elem, ok := strings.CutPrefix(typ, "*"); ok {
+		if _, ok := c.local[elem]; ok {
+			return "!" + c.style.EqualCall(c.equal, a, b)
+		}
+		return fmt.Sprintf("(%s == nil) != (%s == nil) || %s != nil && %s", a, b, a, c.differExpr(elem, "*"+a, "*"+b))
+	}
+	if elem, ok := strings.CutPrefix(typ, "[]"); ok {
+		if isComparable(elem) {
+			return fmt.Sprintf("!slices.Equal(%s, %s)", a, b)
+		}
+		if _, ok := c.local[elem]; ok {
+			return fmt.Sprintf("!slices.EqualFunc(%s, %s, func(x, y %s) bool { return (&x).%s(&y) })", a, b, elem, c.equal)
+		}
+	}
+	if key, val, ok := splitMapType(typ); ok && isComparable(key) && isComparable(val) {
+		return fmt.Sprintf("!maps.Equal(%s, %s)", a, b)
+	}
+	return fmt.Sprintf(`fmt.Sprintf("%%#v", %s) != fmt.Sprintf("%%#v", %s)`, a, b)
+}
+
+// receiver parenthesizes a dereference so that a method can be called on it.
+func receiver(expr string) string {
+	if strings.HasPrefix(expr, "*") {
+		return "(" + expr + ")"
+	}
+	return expr
+}
+
+// isComparable reports whether values of typ can be compared with ==, with the same
+// result as comparing them field by field.
+func isComparable(typ string) bool {
+	switch typ {
+	case "string", "bool", "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "uintptr",
+		"byte", "rune", "float32", "float64", "time.Duration":
+		return true
+	}
+	return false
+}
+
+// splitMapType splits map[K]V into K and V.
+func splitMapType(typ string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(typ, "map[")
+	if !ok {
+		return "", "", false
+	}
+	depth := 1
+	for i, r := range rest {
+		switch r {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return rest[:i], rest[i+1:], true
+			}
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,362 @@
+package manager
+
+const managerTemplate = `// Code generated by sudo-gen manager. DO NOT EDIT.
+
+// {{.TypeName}}Manager holds a {{.TypeName}} behind a mutex and addresses its values by
+// dotted paths of json field names, such as {{(index .Paths 0).Const}}:
+//
+//	m := {{ident "new" .TypeName "Manager"}}(cfg)
+//	v, err := m.GetPath({{(index .Paths 0).Const}})
+//	err = m.SetPath({{(index .Paths 0).Const}}, v)
+//	unsub, err := m.Subscribe({{(index .Paths 0).Const}}, func(v any) {
+//	    log.Println("changed to", v)
+//	})
+//	defer unsub()
+//
+// # Dependencies
+//
+// This generated code requires the following to also be generated:
+//   - {{.TypeName}}.{{method "Copy"}}() (from: sudo-gen copy)
+//   - {{.TypeName}}.{{method "Equal"}}() (from: sudo-gen equals)
+package {{.Package}}
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+{{- range .Imports}}
+	{{if .Alias}}{{.Alias}} {{end}}"{{.Path}}"
+{{- end}}
+)
+
+// Paths of the values of {{.TypeName}}, for {{.TypeName}}Manager.
+const (
+{{- range .Paths}}
+	{{.Const}} = "{{.Path}}"
+{{- end}}
+)
+
+var (
+	// {{ident "err" .TypeName "UnknownPath"}} is returned for a path that names no value of {{.TypeName}}.
+	{{ident "err" .TypeName "UnknownPath"}} = errors.New("unknown {{.TypeName}} path")
+	// {{ident "err" .TypeName "PathType"}} is returned by SetPath for a value of the wrong type.
+	{{ident "err" .TypeName "PathType"}} = errors.New("wrong type for {{.TypeName}} path")
+)
+
+// {{.TypeName}}Manager provides thread-safe access to a {{.TypeName}}, with getters, setters
+// and subscriptions addressing its values by path. Changes are copy-on-write: the
+// config a manager holds is never modified in place.
+type {{.TypeName}}Manager struct {
+	writeMu     sync.Mutex   // Serializes changes and the notifications they send
+	mu          sync.RWMutex // Guards config and subscribers
+	config      *{{.TypeName}}
+	subscribers []{{lower .TypeName}}ManagerSub
+	nextSubID   int
+}
+
+// {{lower .TypeName}}ManagerSub is a subscription to the value at a path.
+type {{lower .TypeName}}ManagerSub struct {
+	id       int
+	path     string
+	callback func(any)
+}
+
+// {{ident "new" .TypeName "Manager"}} returns a manager holding a copy of cfg, or an empty
+// {{.TypeName}} if cfg is nil.
+func {{ident "new" .TypeName "Manager"}}(cfg *{{.TypeName}}) *{{.TypeName}}Manager {
+	if cfg == nil {
+		cfg = &{{.TypeName}}{}
+	}
+	return &{{.TypeName}}Manager{config: cfg.{{method "Copy"}}()}
+}
+
+// Get returns a deep copy of the current configuration.
+func (m *{{.TypeName}}Manager) Get() *{{.TypeName}} {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.config.{{method "Copy"}}()
+}
+
+// Set replaces the configuration with a copy of cfg, or an empty {{.TypeName}} if cfg
+// is nil, and notifies the subscribers of the paths whose values change.
+func (m *{{.TypeName}}Manager) Set(cfg *{{.TypeName}}) {
+	if cfg == nil {
+		cfg = &{{.TypeName}}{}
+	}
+	m.writeMu.Lock()
+	defer m.writeMu.Unlock()
+	m.commit(cfg.{{method "Copy"}}())
+}
+
+// Update calls fn with a copy of the configuration and stores the result, notifying
+// the subscribers of the paths whose values change. Concurrent updates are applied
+// one at a time, so none of them is lost.
+func (m *{{.TypeName}}Manager) Update(fn func(cfg *{{.TypeName}})) {
+	m.writeMu.Lock()
+	defer m.writeMu.Unlock()
+	next := m.Get()
+	fn(next)
+	m.commit(next)
+}
+
+// GetPath returns the value at path. A value below a nil struct pointer is the zero
+// value of its type. Slices, maps, pointers and structs are returned as copies.
+func (m *{{.TypeName}}Manager) GetPath(path string) (any, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	switch path {
+{{- range .Paths}}
+	case {{.Const}}:
+{{- if .Shared}}
+		return {{lower $.TypeName}}Get{{.Func}}(m.config.{{method "Copy"}}()), nil
+{{- else}}
+		return {{lower $.TypeName}}Get{{.Func}}(m.config), nil
+{{- end}}
+{{- end}}
+	}
+	return nil, fmt.Errorf("%w: %q", {{ident "err" .TypeName "UnknownPath"}}, path)
+}
+
+// SetPath sets the value at path to a copy of value, which must have the exact type
+// of the field, allocating the nested structs on the way, and notifies the
+// subscribers of the paths whose values change.
+func (m *{{.TypeName}}Manager) SetPath(path string, value any) error {
+	m.writeMu.Lock()
+	defer m.writeMu.Unlock()
+	c := m.Get()
+	switch path {
+{{- range .Paths}}
+	case {{.Const}}:
+		v, ok := value.({{.Type}})
+		if !ok {
+			return fmt.Errorf("%w: %s is {{.Type}}, not %T", {{ident "err" $.TypeName "PathType"}}, path, value)
+		}
+{{- range .Parents}}
+		if {{.Expr}} == nil {
+			{{.Expr}} = &{{.Type}}{}
+		}
+{{- end}}
+		{{.Expr}} = v
+{{- if .Shared}}
+		// The value still belongs to the caller
+		c = c.{{method "Copy"}}()
+{{- end}}
+{{- end}}
+	default:
+		return fmt.Errorf("%w: %q", {{ident "err" .TypeName "UnknownPath"}}, path)
+	}
+	m.commit(c)
+	return nil
+}
+
+// Subscribe subscribes to the value at path. The callback is invoked immediately with
+// the current value, and with the new value whenever a change alters it. The value
+// passed to callback is shared with the manager and must not be modified. Callbacks
+// can read the manager but not change it. Returns an unsubscribe function.
+func (m *{{.TypeName}}Manager) Subscribe(path string, callback func(value any)) (func(), error) {
+	m.writeMu.Lock()
+	defer m.writeMu.Unlock()
+	m.mu.Lock()
+	v, ok := {{lower .TypeName}}ManagerValue(path, m.config)
+	if !ok {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("%w: %q", {{ident "err" .TypeName "UnknownPath"}}, path)
+	}
+	id := m.nextSubID
+	m.nextSubID++
+	m.subscribers = append(m.subscribers, {{lower .TypeName}}ManagerSub{id: id, path: path, callback: callback})
+	m.mu.Unlock()
+	callback(v)
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.subscribers = slices.DeleteFunc(m.subscribers, func(s {{lower .TypeName}}ManagerSub) bool {
+			return s.id == id
+		})
+	}, nil
+}
+
+// commit stores cfg and notifies the subscribers of the paths whose values it changes,
+// in the order they subscribed. Callbacks run without m.mu held, so they can read the
+// manager. The caller must hold m.writeMu.
+func (m *{{.TypeName}}Manager) commit(cfg *{{.TypeName}}) {
+	m.mu.Lock()
+	old := m.config
+	m.config = cfg
+	subs := slices.Clone(m.subscribers)
+	m.mu.Unlock()
+	if {{equal "old" "cfg"}} {
+		return
+	}
+	changed := make(map[string]bool)
+	for _, s := range subs {
+		c, ok := changed[s.path]
+		if !ok {
+			c = {{lower .TypeName}}ManagerChanged(s.path, old, cfg)
+			changed[s.path] = c
+		}
+		if c {
+			v, _ := {{lower .TypeName}}ManagerValue(s.path, cfg)
+			s.callback(v)
+		}
+	}
+}
+
+// {{lower .TypeName}}ManagerValue returns the value at path in c.
+func {{lower .TypeName}}ManagerValue(path string, c *{{.TypeName}}) (any, bool) {
+	switch path {
+{{- range .Paths}}
+	case {{.Const}}:
+		return {{lower $.TypeName}}Get{{.Func}}(c), true
+{{- end}}
+	}
+	return nil, false
+}
+
+// {{lower .TypeName}}ManagerChanged reports whether the value at path differs between
+// old and new.
+func {{lower .TypeName}}ManagerChanged(path string, old, new *{{.TypeName}}) bool {
+	switch path {
+{{- range .Paths}}
+	case {{.Const}}:
+		a, b := {{lower $.TypeName}}Get{{.Func}}(old), {{lower $.TypeName}}Get{{.Func}}(new)
+		return {{.Differ}}
+{{- end}}
+	}
+	return false
+}
+{{range .Paths}}
+// {{lower $.TypeName}}Get{{.Func}} returns the value at {{.Path}} in c.
+func {{lower $.TypeName}}Get{{.Func}}(c *{{$.TypeName}}) (v {{.Type}}) {
+{{- range .Parents}}
+	if {{.Expr}} == nil {
+		return v
+	}
+{{- end}}
+	return {{.Expr}}
+}
+{{end}}`
+
+const managerTestTemplate = `// Code generated by sudo-gen manager. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test{{.TypeName}}ManagerNil(t *testing.T) {
+	m := {{ident "new" .TypeName "Manager"}}(nil)
+	if m.Get() == nil {
+		t.Fatal("Get returned nil")
+	}
+	m.Set(nil)
+	if m.Get() == nil {
+		t.Fatal("Get returned nil after Set(nil)")
+	}
+}
+
+func Test{{.TypeName}}ManagerUnknownPath(t *testing.T) {
+	m := {{ident "new" .TypeName "Manager"}}(nil)
+	if _, err := m.GetPath("no.such.path"); !errors.Is(err, {{ident "err" .TypeName "UnknownPath"}}) {
+		t.Errorf("GetPath: expected {{ident "err" .TypeName "UnknownPath"}}, got %v", err)
+	}
+	if err := m.SetPath("no.such.path", 1); !errors.Is(err, {{ident "err" .TypeName "UnknownPath"}}) {
+		t.Errorf("SetPath: expected {{ident "err" .TypeName "UnknownPath"}}, got %v", err)
+	}
+	if _, err := m.Subscribe("no.such.path", func(any) {}); !errors.Is(err, {{ident "err" .TypeName "UnknownPath"}}) {
+		t.Errorf("Subscribe: expected {{ident "err" .TypeName "UnknownPath"}}, got %v", err)
+	}
+}
+{{- with .StringPath}}
+
+func Test{{$.TypeName}}ManagerSetPath(t *testing.T) {
+	m := {{ident "new" $.TypeName "Manager"}}(nil)
+	if err := m.SetPath({{.Const}}, "value"); err != nil {
+		t.Fatalf("SetPath: %v", err)
+	}
+	if v, err := m.GetPath({{.Const}}); err != nil || v != "value" {
+		t.Errorf("GetPath = %v, %v; expected value", v, err)
+	}
+	if got := m.Get().{{.Func}}; got != "value" {
+		t.Errorf("Get().{{.Func}} = %q, expected value", got)
+	}
+	if err := m.SetPath({{.Const}}, 42); !errors.Is(err, {{ident "err" $.TypeName "PathType"}}) {
+		t.Errorf("expected {{ident "err" $.TypeName "PathType"}}, got %v", err)
+	}
+}
+
+func Test{{$.TypeName}}ManagerSubscribe(t *testing.T) {
+	m := {{ident "new" $.TypeName "Manager"}}(nil)
+	var got []any
+	unsub, err := m.Subscribe({{.Const}}, func(v any) {
+		got = append(got, v)
+	})
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if len(got) != 1 || got[0] != "" {
+		t.Fatalf("expected an immediate call with the current value, got %v", got)
+	}
+	if err := m.SetPath({{.Const}}, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetPath({{.Const}}, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[1] != "a" {
+		t.Fatalf("expected one notification for the change, got %v", got)
+	}
+	m.Update(func(cfg *{{$.TypeName}}) {
+		cfg.{{.Func}} = "b"
+	})
+	if len(got) != 3 || got[2] != "b" {
+		t.Fatalf("expected a notification for the update, got %v", got)
+	}
+	unsub()
+	if err := m.SetPath({{.Const}}, "c"); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 {
+		t.Errorf("expected no notification after unsubscribing, got %v", got)
+	}
+}
+
+func Test{{$.TypeName}}ManagerSubscriberReads(t *testing.T) {
+	m := {{ident "new" $.TypeName "Manager"}}(nil)
+	var seen string
+	unsub, err := m.Subscribe({{.Const}}, func(any) {
+		seen = m.Get().{{.Func}}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsub()
+	if err := m.SetPath({{.Const}}, "value"); err != nil {
+		t.Fatal(err)
+	}
+	if seen != "value" {
+		t.Errorf("subscriber read %q, expected value", seen)
+	}
+}
+{{- end}}
+{{- with .NestedStringPath}}
+
+func Test{{$.TypeName}}ManagerSetNestedPath(t *testing.T) {
+	m := {{ident "new" $.TypeName "Manager"}}(nil)
+	if v, err := m.GetPath({{.Const}}); err != nil || v != "" {
+		t.Errorf("GetPath below a nil struct = %v, %v; expected the zero value", v, err)
+	}
+	if err := m.SetPath({{.Const}}, "nested"); err != nil {
+		t.Fatalf("SetPath: %v", err)
+	}
+	if v, err := m.GetPath({{.Const}}); err != nil || v != "nested" {
+		t.Errorf("GetPath = %v, %v; expected nested", v, err)
+	}
+}
+{{- end}}
+`
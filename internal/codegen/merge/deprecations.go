@@ -0,0 +1,113 @@
+package merge
+
+import (
+	"strings"
+
+	"github.com/bobcob7/sudo-gen/internal/codegen"
+)
+
+// deprecation is a deprecated field of the root or of a nested partial, addressed
+// through the partials that hold it.
+type deprecation struct {
+	partialPath
+	Message     string
+	New         string // Expression of a value that sets the field, for generated tests
+	Replacement *partialPath
+}
+
+// partialPath is a field of a partial reached through the nested partials holding it.
+type partialPath struct {
+	Path   string // Dotted Go field names, e.g. Database.URL
+	fields []string
+	steps  []string // Partial types of the nested structs on the way
+}
+
+// newDeprecations returns the deprecated fields reachable from the partial of root.
+func newDeprecations(root *codegen.StructInfo, structs []*codegen.StructInfo, names *partialNames) ([]deprecation, error) {
+	local := make(map[string]*codegen.StructInfo)
+	external := make(map[string]bool)
+	for _, st := range structs {
+		if st.Package == "" {
+			local[st.Name] = st
+		} else {
+			external[st.Package+"."+st.Name] = true
+		}
+	}
+	found, err := codegen.Deprecations(root, local)
+	if err != nil {
+		return nil, err
+	}
+	var result []deprecation
+	for _, d := range found {
+		dep := deprecation{partialPath: newPartialPath(d.Path, names), Message: d.Message}
+		f := d.Path[len(d.Path)-1]
+		switch {
+		case f.IsSlice || f.IsMap:
+			dep.New = f.Type + "{}"
+		case f.TypePkg == "" && local[f.TypeName] != nil, external[f.TypePkg+"."+f.TypeName]:
+			dep.New = "&" + names.field(f) + "{}"
+		default:
+			dep.New = "new(" + strings.TrimPrefix(f.Type, "*") + ")"
+		}
+		if d.Replacement != nil {
+			r := newPartialPath(d.Replacement, names)
+			dep.Replacement = &r
+		}
+		result = append(result, dep)
+	}
+	return result, nil
+}
+
+func newPartialPath(path []codegen.FieldInfo, names *partialNames) partialPath {
+	p := partialPath{Path: codegen.JoinFieldPath(path)}
+	for i, f := range path {
+		p.fields = append(p.fields, f.Name)
+		if i < len(path)-1 {
+			p.steps = append(p.steps, names.field(f))
+		}
+	}
+	return p
+}
+
+// Set returns an expression reporting whether the partial recv sets the field.
+func (p partialPath) Set(recv string) string {
+	var conds []string
+	for i := range p.fields {
+		conds = append(conds, selector(recv, p.fields[:i+1])+" != nil")
+	}
+	return strings.Join(conds, " && ")
+}
+
+// Unset returns an expression reporting whether the partial recv leaves the field
+// unset.
+func (p partialPath) Unset(recv string) string {
+	var conds []string
+	for i := range p.fields {
+		conds = append(conds, selector(recv, p.fields[:i+1])+" == nil")
+	}
+	return strings.Join(conds, " || ")
+}
+
+// Field returns the selector of the field on the partial recv.
+func (p partialPath) Field(recv string) string {
+	return selector(recv, p.fields)
+}
+
+// partialStep is a nested partial on the way to a field.
+type partialStep struct {
+	Expr string
+	Type string
+}
+
+// Steps returns the nested partials on the way to the field on recv.
+func (p partialPath) Steps(recv string) []partialStep {
+	steps := make([]partialStep, len(p.steps))
+	for i, typ := range p.steps {
+		steps[i] = partialStep{Expr: selector(recv, p.fields[:i+1]), Type: typ}
+	}
+	return steps
+}
+
+func selector(recv string, fields []string) string {
+	return recv + "." + strings.Join(fields, ".")
+}
@@ -2,8 +2,12 @@
 package merge
 
 import (
+	"flag"
 	"fmt"
+	"go/ast"
+	"maps"
 	"path/filepath"
+	"slices"
 	"strings"
 	"text/template"
 
@@ -21,17 +25,80 @@ func (s *Subtool) Description() string {
 	return "Generate partial types and ApplyPartial methods for config merging"
 }
 
+// Doc describes the struct tags the subtool honors and the files it generates.
+func (s *Subtool) Doc() codegen.SubtoolDoc {
+	return codegen.SubtoolDoc{
+		Tags: []codegen.DocEntry{
+			{Name: `json:"name"`, Description: "Key of the field in JSON partials and patches; -tag-source picks the key -tags copies"},
+			{Name: `sudo:"merge=replace"`, Description: "Overrides -merge-structs for a nested struct field (merge=deep or merge=replace)"},
+			{Name: `sudo:"deprecated=use Field"`, Description: "With -migrate-deprecated, ApplyPartial also sets the named replacement"},
+			codegen.ExcludeTagDoc(s.Name()),
+		},
+		Files: []codegen.DocEntry{
+			{Name: "{source}_partial.go", Description: "Partial version of the type with pointer fields"},
+			{Name: "{source}_merge.go", Description: "ApplyPartial method for merging partials"},
+			{Name: "{source}_mergepatch.go", Description: "ApplyJSONMergePatch and ToJSONMergePatch (with -merge-patch)"},
+			{Name: "{source}_jsonpatch.go", Description: "Diff{Type}AsJSONPatch (with -json-patch)"},
+			{Name: "{source}_merge_bench_test.go", Description: "Benchmark{Type}ApplyPartial (with -bench)"},
+			{Name: "{source}_merge_example_test.go", Description: "Example{Type}_ApplyPartial, shown by godoc (with -examples, for types with a string field)"},
+		},
+	}
+}
+
+// DefineFlags defines the flags of the merge subcommand.
+func (s *Subtool) DefineFlags(fs *flag.FlagSet, cfg *codegen.GeneratorConfig) {
+	fs.Var((*codegen.ListFlag)(&cfg.Tags), "tags", "Comma-separated tag keys to emit on partial fields (e.g. json,yaml,mapstructure)")
+	fs.StringVar(&cfg.TagSource, "tag-source", codegen.DefaultTagSource, "Tag key (json, yaml, toml, env, ...) that -tags values are derived from")
+	fs.StringVar((*string)(&cfg.External), "external", string(codegen.ExternalPassthrough), "How to merge struct fields from other packages (partial, passthrough, error)")
+	fs.StringVar((*string)(&cfg.MergeStructs), "merge-structs", string(codegen.MergeDeep), "How to apply partials of nested struct fields (deep, replace)")
+	fs.BoolVar(&cfg.GenerateClear, "clear", false, "Let partials reset fields to their zero value with ClearField or a JSON null")
+	fs.BoolVar(&cfg.MigrateDeprecated, "migrate-deprecated", false, `Let ApplyPartial set the replacement named by a sudo:"deprecated=use Field" tag`)
+	fs.BoolVar(&cfg.GenerateMergePatch, "merge-patch", false, "Also generate ApplyJSONMergePatch and ToJSONMergePatch (RFC 7386) on the root type")
+	fs.BoolVar(&cfg.GenerateJSONPatch, "json-patch", false, "Also generate Diff{Type}AsJSONPatch, returning the JSON Patch (RFC 6902) between two values")
+	fs.BoolVar(&cfg.GenerateBench, "bench", false, "Generate Benchmark{Type}ApplyPartial in a _bench_test.go file")
+	fs.BoolVar(&cfg.GenerateExamples, "examples", false, "Generate Example{Type}_ApplyPartial, a godoc example of merging a partial, in an _example_test.go file")
+	fs.BoolVar(&cfg.UTCTimes, "utc", false, "Convert the time.Time values ApplyPartial and ApplySparse store to UTC (and compare them with Equal)")
+}
+
 // Run executes the merge code generation.
 func (s *Subtool) Run(cfg codegen.GeneratorConfig) error {
-	info, err := codegen.ParseStruct(cfg.SourceDir, cfg.SourceFile, cfg.TypeName)
+	info, err := cfg.Index.ParseStruct(cfg.SourceDir, cfg.SourceFile, cfg.Source, cfg.TypeName)
 	if err != nil {
 		return fmt.Errorf("parsing struct: %w", err)
 	}
-	nested, err := codegen.FindNestedStructs(cfg.SourceDir, cfg.SourceFile, info)
+	selection := codegen.NewFieldSelection(cfg, s.Name())
+	selection.Apply(info)
+	values := codegen.NewValueTypes(cfg.Index, cfg.SourceDir, cfg.ValueTypes)
+	nested, err := cfg.Index.FindNestedStructs(cfg.SourceDir, cfg.Source, info, values)
 	if err != nil {
 		return fmt.Errorf("finding nested structs: %w", err)
 	}
-	allStructs := append([]*codegen.StructInfo{info}, nested...)
+	for _, st := range nested {
+		selection.Apply(st)
+	}
+	names := newPartialNames(cfg)
+	allStructs, err := selectExternal(cfg.External, names, append([]*codegen.StructInfo{info}, nested...))
+	if err != nil {
+		return err
+	}
+	cfg, err = codegen.CheckUnsupported(cfg, s.Name(), codegen.UnsupportedFields(allStructs))
+	if err != nil {
+		return err
+	}
+	if err := names.register(allStructs); err != nil {
+		return err
+	}
+	if err := names.checkDeclared(partialFile(cfg)); err != nil {
+		return err
+	}
+	if err := checkMethodNames(allStructs, names); err != nil {
+		return err
+	}
+	if cfg.GenerateClear {
+		if err := checkClearNames(allStructs, names); err != nil {
+			return err
+		}
+	}
 
 	// Build map of external structs for template functions
 	externalStructs := make(map[string]bool)
@@ -40,88 +107,722 @@ func (s *Subtool) Run(cfg codegen.GeneratorConfig) error {
 			externalStructs[st.Package+"."+st.Name] = true
 		}
 	}
+	replaced, err := replacedFields(cfg.MergeStructs, allStructs, externalStructs)
+	if err != nil {
+		return err
+	}
+	funcs := templateFuncs(externalStructs, replaced, names, cfg.UTCTimes)
 
 	// Collect imports from all structs (root and nested)
 	allImports := collectAllImports(allStructs)
-	if err := generatePartialFile(cfg, allStructs, allImports, externalStructs); err != nil {
+	if err := generatePartialFile(cfg, allStructs, allImports, externalStructs, funcs); err != nil {
 		return fmt.Errorf("generating partial file: %w", err)
 	}
-	// For merge file, only include imports for external struct types we generate helpers for
-	mergeImports := collectMergeImports(allStructs, externalStructs)
-	if err := generateMergeFile(cfg, allStructs, externalStructs, mergeImports); err != nil {
+	deprecations, err := newDeprecations(info, allStructs, names)
+	if err != nil {
+		return err
+	}
+	if err := checkGeneric(cfg, info, deprecations); err != nil {
+		return err
+	}
+	if err := generateMergeFile(cfg, allStructs, allImports, deprecations, funcs); err != nil {
 		return fmt.Errorf("generating merge file: %w", err)
 	}
+	if info.Generic() {
+		// ApplyPartial copies the values of type parameters with HelperCopyValue
+		if err := codegen.WriteHelpers(cfg, codegen.HelperCopyValue); err != nil {
+			return err
+		}
+	}
+	if cfg.GenerateMergePatch {
+		if err := generateMergePatchFile(cfg, allStructs, allImports, funcs); err != nil {
+			return fmt.Errorf("generating merge patch file: %w", err)
+		}
+	}
+	if cfg.GenerateJSONPatch {
+		if err := generateJSONPatchFile(cfg, allStructs, funcs); err != nil {
+			return fmt.Errorf("generating JSON patch file: %w", err)
+		}
+	}
 	if cfg.GenerateTest {
-		if err := generateMergeTestFile(cfg, allStructs, externalStructs); err != nil {
+		if err := generateMergeTestFile(cfg, allStructs, deprecations, funcs); err != nil {
 			return fmt.Errorf("generating merge test file: %w", err)
 		}
 	}
+	if cfg.GenerateBench {
+		if err := generateMergeBenchFile(cfg, allStructs, funcs); err != nil {
+			return fmt.Errorf("generating merge bench file: %w", err)
+		}
+	}
+	if cfg.GenerateExamples {
+		if err := generateMergeExampleFile(cfg, info, funcs); err != nil {
+			return fmt.Errorf("generating merge example file: %w", err)
+		}
+	}
 	return nil
 }
 
-func generatePartialFile(cfg codegen.GeneratorConfig, structs []*codegen.StructInfo, imports []codegen.ImportInfo, externalStructs map[string]bool) error {
-	baseName := strings.TrimSuffix(cfg.SourceFile, ".go")
-	outputFile := filepath.Join(cfg.OutputDir, baseName+"_partial.go")
+// checkGeneric returns an error for the flags and tags that don't support a generic
+// root type.
+func checkGeneric(cfg codegen.GeneratorConfig, info *codegen.StructInfo, deprecations []deprecation) error {
+	if !info.Generic() {
+		return nil
+	}
+	if cfg.GenerateTest || cfg.GenerateBench || cfg.GenerateExamples || cfg.GenerateMergePatch || cfg.GenerateJSONPatch {
+		return fmt.Errorf("%s is generic, which -tests, -bench, -examples, -merge-patch and -json-patch don't support", info.Name)
+	}
+	if len(deprecations) > 0 {
+		return fmt.Errorf("%s is generic, which sudo:\"deprecated\" fields don't support", info.Name)
+	}
+	return nil
+}
+
+// partialFile returns the path of the file declaring the partial types.
+func partialFile(cfg codegen.GeneratorConfig) string {
+	return filepath.Join(cfg.OutputDir, strings.TrimSuffix(cfg.SourceFile, ".go")+"_partial.go")
+}
+
+func generatePartialFile(cfg codegen.GeneratorConfig, structs []*codegen.StructInfo, imports []codegen.ImportInfo, externalStructs map[string]bool, funcs template.FuncMap) error {
+	outputFile := partialFile(cfg)
+	hasDurations, hasURLs := false, false
+	for _, s := range structs {
+		if len(durationFields(s)) > 0 {
+			hasDurations = true
+		}
+		if len(urlFields(s)) > 0 {
+			hasURLs = true
+		}
+	}
+	imports = partialImports(structs, imports, externalStructs)
+	if hasDurations {
+		imports = withImports(imports, "encoding/json", "fmt")
+	}
+	if hasURLs {
+		imports = withImports(imports, "encoding/json", "fmt", "net/url")
+	}
+	if cfg.GenerateClear {
+		imports = withImports(imports, "encoding/json", "slices")
+	}
 	data := struct {
-		Package string
-		Imports []codegen.ImportInfo
-		Structs []*codegen.StructInfo
+		Package      string
+		Imports      []codegen.ImportInfo
+		Structs      []*codegen.StructInfo
+		DurationType string
+		URLType      string
+		Clear        bool
 	}{
 		Package: cfg.OutputPkg,
 		Imports: imports,
 		Structs: structs,
+		Clear:   cfg.GenerateClear,
+	}
+	if hasDurations {
+		data.DurationType = durationTypeName(structs[0].Name)
+	}
+	if hasURLs {
+		data.URLType = urlTypeName(structs[0].Name)
+	}
+	funcs = maps.Clone(funcs)
+	funcs["partialTag"] = func(f codegen.FieldInfo) string {
+		return codegen.PartialTag(f, cfg.Tags, cfg.TagSource)
 	}
-	gen := codegen.NewTemplateGenerator(templateFuncs(externalStructs))
+	funcs["clearTag"] = func() string {
+		return clearTag(cfg.Tags)
+	}
+	gen := codegen.NewTemplateGenerator(cfg, funcs)
 	return gen.GenerateFile(outputFile, partialTemplate, data)
 }
 
-func generateMergeFile(cfg codegen.GeneratorConfig, structs []*codegen.StructInfo, externalStructs map[string]bool, imports []codegen.ImportInfo) error {
+func generateMergeFile(cfg codegen.GeneratorConfig, structs []*codegen.StructInfo, imports []codegen.ImportInfo, deprecations []deprecation, funcs template.FuncMap) error {
 	baseName := strings.TrimSuffix(cfg.SourceFile, ".go")
 	outputFile := filepath.Join(cfg.OutputDir, baseName+"_merge.go")
+	data := struct {
+		Package      string
+		Root         string
+		Structs      []*codegen.StructInfo
+		Imports      []codegen.ImportInfo
+		Clear        bool
+		Deprecations []deprecation
+		Replacements bool // Whether a deprecation names a replacement
+		Migrate      bool // Whether ApplyPartial of the root sets the replacements
+	}{
+		Package:      cfg.OutputPkg,
+		Root:         structs[0].Name,
+		Structs:      structs,
+		Imports:      imports,
+		Clear:        cfg.GenerateClear,
+		Deprecations: deprecations,
+	}
+	for _, d := range deprecations {
+		if d.Replacement != nil {
+			data.Replacements = true
+		}
+	}
+	if cfg.MigrateDeprecated {
+		if !data.Replacements {
+			return fmt.Errorf("-migrate-deprecated: no field of %s is tagged sudo:\"deprecated=use <field>\" with a replacement", structs[0].Name)
+		}
+		data.Migrate = true
+	}
+	gen := codegen.NewTemplateGenerator(cfg, funcs)
+	return gen.GenerateFile(outputFile, mergeTemplate, data)
+}
+
+func generateMergePatchFile(cfg codegen.GeneratorConfig, structs []*codegen.StructInfo, imports []codegen.ImportInfo, funcs template.FuncMap) error {
+	baseName := strings.TrimSuffix(cfg.SourceFile, ".go")
+	outputFile := filepath.Join(cfg.OutputDir, baseName+"_mergepatch.go")
 	data := struct {
 		Package string
+		Root    string
 		Structs []*codegen.StructInfo
 		Imports []codegen.ImportInfo
 	}{
 		Package: cfg.OutputPkg,
+		Root:    structs[0].Name,
 		Structs: structs,
-		Imports: imports,
+		Imports: mergePatchImports(structs, imports),
 	}
-	gen := codegen.NewTemplateGenerator(templateFuncs(externalStructs))
-	return gen.GenerateFile(outputFile, mergeTemplate, data)
+	gen := codegen.NewTemplateGenerator(cfg, funcs)
+	return gen.GenerateFile(outputFile, mergePatchTemplate, data)
 }
 
-func generateMergeTestFile(cfg codegen.GeneratorConfig, structs []*codegen.StructInfo, externalStructs map[string]bool) error {
+func generateJSONPatchFile(cfg codegen.GeneratorConfig, structs []*codegen.StructInfo, funcs template.FuncMap) error {
 	baseName := strings.TrimSuffix(cfg.SourceFile, ".go")
-	outputFile := filepath.Join(cfg.OutputDir, baseName+"_merge_test.go")
+	outputFile := filepath.Join(cfg.OutputDir, baseName+"_jsonpatch.go")
 	data := struct {
 		Package string
+		Root    string
 		Structs []*codegen.StructInfo
 	}{
 		Package: cfg.OutputPkg,
+		Root:    structs[0].Name,
 		Structs: structs,
 	}
-	gen := codegen.NewTemplateGenerator(templateFuncs(externalStructs))
+	gen := codegen.NewTemplateGenerator(cfg, funcs)
+	return gen.GenerateFile(outputFile, jsonPatchTemplate, data)
+}
+
+// mergePatchImports filters imports down to the packages of the types merge patches
+// name: those of the map keys and the fields reset to zero values of local structs.
+func mergePatchImports(structs []*codegen.StructInfo, imports []codegen.ImportInfo) []codegen.ImportInfo {
+	used := make(map[string]bool)
+	for _, st := range structs {
+		if st.Package != "" {
+			continue
+		}
+		for _, f := range st.Fields {
+			if pkg, _, ok := strings.Cut(f.MapKeyType, "."); f.IsMap && ok {
+				used[pkg] = true
+			}
+			if !f.IsPointer && !f.IsSlice && !f.IsMap && f.TypePkg != "" {
+				used[f.TypePkg] = true
+			}
+		}
+	}
+	var result []codegen.ImportInfo
+	for _, imp := range imports {
+		name := imp.Alias
+		if name == "" {
+			name = filepath.Base(imp.Path)
+		}
+		if used[name] {
+			result = append(result, imp)
+		}
+	}
+	return result
+}
+
+func generateMergeTestFile(cfg codegen.GeneratorConfig, structs []*codegen.StructInfo, deprecations []deprecation, funcs template.FuncMap) error {
+	baseName := strings.TrimSuffix(cfg.SourceFile, ".go")
+	outputFile := filepath.Join(cfg.OutputDir, baseName+"_merge_test.go")
+	data := struct {
+		Package      string
+		Root         string
+		Structs      []*codegen.StructInfo
+		JSON         bool
+		Clear        bool
+		Deprecations []deprecation
+		MergePatch   bool
+		JSONPatch    bool
+	}{
+		Package:      cfg.OutputPkg,
+		Root:         structs[0].Name,
+		Structs:      structs,
+		Clear:        cfg.GenerateClear,
+		Deprecations: deprecations,
+		MergePatch:   cfg.GenerateMergePatch,
+		JSONPatch:    cfg.GenerateJSONPatch,
+	}
+	for _, s := range structs {
+		if len(durationFields(s)) > 0 || len(urlFields(s)) > 0 {
+			data.JSON = true
+		}
+	}
+	if err := codegen.WriteHelpers(cfg, codegen.HelperPtr); err != nil {
+		return err
+	}
+	gen := codegen.NewTemplateGenerator(cfg, funcs)
 	return gen.GenerateFile(outputFile, mergeTestTemplate, data)
 }
 
-func templateFuncs(externalStructs map[string]bool) template.FuncMap {
+func generateMergeBenchFile(cfg codegen.GeneratorConfig, structs []*codegen.StructInfo, funcs template.FuncMap) error {
+	baseName := strings.TrimSuffix(cfg.SourceFile, ".go")
+	outputFile := filepath.Join(cfg.OutputDir, baseName+"_merge_bench_test.go")
+	data := struct {
+		Package string
+		Root    string
+		Sample  string
+	}{
+		Package: cfg.OutputPkg,
+		Root:    structs[0].Name,
+		Sample:  codegen.NewSampler(structs).Literal(structs[0].Name),
+	}
+	gen := codegen.NewTemplateGenerator(cfg, funcs)
+	return gen.GenerateFile(outputFile, mergeBenchTemplate, data)
+}
+
+// generateMergeExampleFile generates the godoc example of ApplyPartial, which overrides
+// a string field of the type. Types without one get no example.
+func generateMergeExampleFile(cfg codegen.GeneratorConfig, info *codegen.StructInfo, funcs template.FuncMap) error {
+	if !ast.IsExported(info.Name) {
+		return fmt.Errorf("-examples documents exported types, and %s is unexported", info.Name)
+	}
+	field := firstStringField(info)
+	if field == "" {
+		return nil
+	}
+	baseName := strings.TrimSuffix(cfg.SourceFile, ".go")
+	data := struct {
+		Package string
+		Root    string
+		Field   string
+	}{
+		Package: cfg.OutputPkg,
+		Root:    info.Name,
+		Field:   field,
+	}
+	gen := codegen.NewTemplateGenerator(cfg, funcs)
+	return gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_merge_example_test.go"), mergeExampleTemplate, data)
+}
+
+// firstStringField returns the name of the first exported string field of info, or ""
+// if there is none.
+func firstStringField(info *codegen.StructInfo) string {
+	for _, f := range info.Fields {
+		if f.TypeName == "string" && f.TypePkg == "" && !f.IsPointer && !f.IsSlice && !f.IsMap && ast.IsExported(f.Name) {
+			return f.Name
+		}
+	}
+	return ""
+}
+
+func templateFuncs(externalStructs, replaced map[string]bool, names *partialNames, utc bool) template.FuncMap {
+	needsConversion := needsConversionFunc(externalStructs)
 	return template.FuncMap{
-		"partialType":       partialTypeName,
-		"pointerType":       pointerTypeNameFunc(externalStructs),
-		"needsConversion":   needsConversionFunc(externalStructs),
-		"isExternal":        isExternalFunc(externalStructs),
-		"isExternalField":   isExternalFieldFunc(externalStructs),
-		"externalPartial":   externalPartialNameFunc(externalStructs),
+		"partialType": func(s *codegen.StructInfo) string {
+			return names.of(s) + codegen.TypeArgList(s.TypeParams)
+		},
+		"partialDecl": func(s *codegen.StructInfo) string {
+			return names.of(s) + codegen.TypeParamList(s.TypeParams)
+		},
+		"structType": func(s *codegen.StructInfo) string {
+			return s.Name + codegen.TypeArgList(s.TypeParams)
+		},
+		"copyValue": func(f codegen.FieldInfo, x string) string {
+			return codegen.CopyValue(*f.TypeParam, x)
+		},
+		"pointerType":     pointerTypeNameFunc(externalStructs, names),
+		"needsConversion": needsConversionFunc(externalStructs),
+		"isExternal":      isExternalFunc(externalStructs),
+		"isExternalField": isExternalFieldFunc(externalStructs),
+		"externalPartial": names.field,
+		"leafType":        leafTypeName,
+		"durationFields":  durationFields,
+		"urlFields":       urlFields,
+		"jsonFields":      jsonFields,
+		"sparseFields": func(s *codegen.StructInfo) []jsonField {
+			return sparseFields(s, func(f codegen.FieldInfo) bool {
+				return needsConversion(f) && !replaced[s.Name+"."+f.Name]
+			})
+		},
+		"emptyJSON": emptyJSON,
+		"lower":     strings.ToLower,
+		"underscores": func(path string) string {
+			return strings.ReplaceAll(path, ".", "_")
+		},
+		"replaces": func(s *codegen.StructInfo, f codegen.FieldInfo) bool {
+			return replaced[s.Name+"."+f.Name]
+		},
+		"utc": func(f codegen.FieldInfo) bool {
+			return utc && f.HoldsTime()
+		},
+		"nonZero":     nonZero,
+		"jsonOmitted": jsonOmitted,
+		"omitsEmpty": func(f jsonField) bool {
+			omitEmpty, _ := omitOptions(f.FieldInfo)
+			return omitEmpty
+		},
+		"omitsZero": func(f jsonField) bool {
+			_, omitZero := omitOptions(f.FieldInfo)
+			return omitZero
+		},
+		"pointerToken": strings.NewReplacer("~", "~0", "/", "~1").Replace,
+	}
+}
+
+// selectExternal applies the -external mode to structs, dropping the structs from
+// other packages unless partials are generated for them.
+func selectExternal(mode codegen.ExternalMode, names *partialNames, structs []*codegen.StructInfo) ([]*codegen.StructInfo, error) {
+	switch mode {
+	case codegen.ExternalPartial:
+		return structs, nil
+	case "", codegen.ExternalPassthrough, codegen.ExternalError:
+	default:
+		return nil, fmt.Errorf("unknown -external mode %q (supported: partial, passthrough, error)", mode)
+	}
+	var result []*codegen.StructInfo
+	for _, st := range structs {
+		if st.Package == "" {
+			result = append(result, st)
+			continue
+		}
+		if mode == codegen.ExternalError {
+			return nil, fmt.Errorf("%s has type %s.%s from package %s; use -external=partial to generate %s for it, or -external=passthrough to merge it as a whole value",
+				referringField(structs, st), st.Package, st.Name, st.ImportPath, names.of(st))
+		}
+	}
+	return result, nil
+}
+
+// replacedFields returns the fields ("Config.TLS") whose nested partial replaces the
+// existing struct instead of being merged into it, as set by their merge tag or mode.
+func replacedFields(mode codegen.MergeMode, structs []*codegen.StructInfo, externalStructs map[string]bool) (map[string]bool, error) {
+	switch mode {
+	case "", codegen.MergeDeep, codegen.MergeReplace:
+	default:
+		return nil, fmt.Errorf("unknown -merge-structs mode %q (supported: deep, replace)", mode)
+	}
+	needsConversion := needsConversionFunc(externalStructs)
+	replaced := make(map[string]bool)
+	for _, st := range structs {
+		if st.Package != "" {
+			continue
+		}
+		for _, f := range st.Fields {
+			fieldMode := FieldMode(mode, f)
+			if tag, ok := f.TagOption("merge"); ok {
+				switch {
+				case fieldMode != codegen.MergeDeep && fieldMode != codegen.MergeReplace:
+					return nil, fmt.Errorf("%s.%s: unknown merge mode %q (supported: deep, replace)", st.Name, f.Name, tag)
+				case !needsConversion(f):
+					return nil, fmt.Errorf("%s.%s: merge=%s only applies to struct fields with a partial type; %s is always replaced", st.Name, f.Name, tag, f.Type)
+				}
+			}
+			if fieldMode == codegen.MergeReplace && needsConversion(f) {
+				replaced[st.Name+"."+f.Name] = true
+			}
+		}
+	}
+	return replaced, nil
+}
+
+// FieldMode returns how the partial of f is applied when it is a nested struct: as
+// set by its merge tag, or mode otherwise.
+func FieldMode(mode codegen.MergeMode, f codegen.FieldInfo) codegen.MergeMode {
+	if tag, ok := f.TagOption("merge"); ok {
+		return codegen.MergeMode(tag)
+	}
+	if mode == "" {
+		return codegen.MergeDeep
+	}
+	return mode
+}
+
+// referringField returns the first field of structs ("Config.Limit") whose type is ext.
+func referringField(structs []*codegen.StructInfo, ext *codegen.StructInfo) string {
+	for _, st := range structs {
+		for _, f := range st.Fields {
+			if f.TypePkg == ext.Package && f.TypeName == ext.Name {
+				return st.Name + "." + f.Name
+			}
+		}
+	}
+	return "a field"
+}
+
+// partialMethods are the methods of every partial, and rootMethods those only the
+// partial of the root type has.
+var (
+	partialMethods = []string{"Merge", "IsEmpty", "Prune"}
+	rootMethods    = []string{"Overlay"}
+)
+
+// checkMethodNames reports structs with a field that a method of their partial would
+// collide with.
+func checkMethodNames(structs []*codegen.StructInfo, names *partialNames) error {
+	for i, st := range structs {
+		for _, f := range st.Fields {
+			if slices.Contains(partialMethods, f.Name) || i == 0 && slices.Contains(rootMethods, f.Name) {
+				return fmt.Errorf("%s.%s collides with the %s method of %s; rename the field or exclude it with sudo-gen:\"-merge\"",
+					qualifiedName(st), f.Name, f.Name, names.of(st))
+			}
+		}
+	}
+	return nil
+}
+
+// checkClearNames reports structs with a field that the Clear field or ClearField
+// method added to their partial by -clear would collide with.
+func checkClearNames(structs []*codegen.StructInfo, names *partialNames) error {
+	for _, st := range structs {
+		if st.Package != "" {
+			continue
+		}
+		for _, f := range st.Fields {
+			if f.Name == "Clear" || f.Name == "ClearField" {
+				return fmt.Errorf("%s.%s collides with the %s that -clear adds to %s; rename the field or exclude it with sudo-gen:\"-merge\"",
+					st.Name, f.Name, f.Name, names.of(st))
+			}
+		}
+	}
+	return nil
+}
+
+// clearTag returns the struct tag of the Clear field of partials, which is never
+// decoded directly under any of the emitted tag keys.
+func clearTag(keys []string) string {
+	parts := []string{`json:"-"`}
+	for _, key := range keys {
+		if key != "json" {
+			parts = append(parts, key+`:"-"`)
+		}
+	}
+	return "`" + strings.Join(parts, " ") + "`"
+}
+
+// qualifiedName returns the name of st with its import path if it is external.
+func qualifiedName(st *codegen.StructInfo) string {
+	if st.Package == "" {
+		return st.Name
+	}
+	return st.ImportPath + "." + st.Name
+}
+
+// leafTypeName returns the value type accepted by a sparse update of f.
+func leafTypeName(f codegen.FieldInfo) string {
+	if f.IsPointer {
+		return strings.TrimPrefix(f.Type, "*")
+	}
+	return f.Type
+}
+
+// nonZero returns the condition that the field f of c doesn't hold its zero value,
+// for fields that are neither pointers, slices, maps nor structs with partials.
+func nonZero(f codegen.FieldInfo) string {
+	return zeroCondition("c."+f.Name, f, false)
+}
+
+// zeroCondition returns the condition that x, a value of the type of f, holds its
+// zero value, or doesn't if zero is false.
+func zeroCondition(x string, f codegen.FieldInfo, zero bool) string {
+	cmp, not := " != ", "!"
+	if zero {
+		cmp, not = " == ", ""
+	}
+	switch {
+	case f.TypePkg == "" && f.TypeName == "string":
+		return x + cmp + `""`
+	case f.TypePkg == "" && f.TypeName == "bool":
+		if zero {
+			return "!" + x
+		}
+		return x
+	case f.TypePkg == "" && (f.TypeName == "any" || f.TypeName == "error"):
+		return x + cmp + "nil"
+	case f.TypePkg == "" && isNumeric(f.TypeName), f.TypePkg == "time" && f.TypeName == "Duration":
+		return x + cmp + "0"
+	case f.TypePkg == "time" && f.TypeName == "Time":
+		return not + x + ".IsZero()"
+	}
+	// Named and external types may not be comparable
+	return not + "reflect.ValueOf(" + x + ").IsZero()"
+}
+
+// jsonOmitted returns the condition that encoding/json leaves the field f of recv out
+// of its encoding, as its omitempty or omitzero option does for empty or zero values.
+func jsonOmitted(recv string, f jsonField) string {
+	omitEmpty, omitZero := omitOptions(f.FieldInfo)
+	field := recv + "." + f.Name
+	switch {
+	case !omitEmpty && !omitZero:
+		return "false"
+	case f.IsPointer:
+		return field + " == nil"
+	case f.IsSlice, f.IsMap:
+		if omitEmpty {
+			return "len(" + field + ") == 0"
+		}
+		return field + " == nil"
+	case f.IsStruct:
+		if omitZero {
+			return "reflect.ValueOf(" + field + ").IsZero()"
+		}
+		// encoding/json never considers structs empty
+		return "false"
+	}
+	return zeroCondition(field, f.FieldInfo, true)
+}
+
+// omitOptions reports whether the json tag of f has the omitempty and omitzero options.
+func omitOptions(f codegen.FieldInfo) (omitEmpty, omitZero bool) {
+	_, opts, _ := strings.Cut(f.StructTag().Get("json"), ",")
+	options := strings.Split(opts, ",")
+	return slices.Contains(options, "omitempty"), slices.Contains(options, "omitzero")
+}
+
+// isNumeric reports whether name is a predeclared numeric type.
+func isNumeric(name string) bool {
+	switch name {
+	case "int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64", "uintptr",
+		"byte", "rune", "float32", "float64", "complex64", "complex128":
+		return true
+	}
+	return false
+}
+
+// jsonField is a field of a partial with the JSON object key it is decoded from.
+type jsonField struct {
+	codegen.FieldInfo
+	Key string
+}
+
+// jsonFields returns the fields of s that are visible to encoding/json.
+func jsonFields(s *codegen.StructInfo) []jsonField {
+	var fields []jsonField
+	for _, f := range s.Fields {
+		key := f.Name
+		if name, _, _ := strings.Cut(f.StructTag().Get("json"), ","); name == "-" {
+			continue
+		} else if name != "" {
+			key = name
+		}
+		fields = append(fields, jsonField{FieldInfo: f, Key: key})
+	}
+	return fields
+}
+
+// durationFields returns the time.Duration and *time.Duration fields of s that are
+// visible to encoding/json, whose partial decodes them from duration strings.
+func durationFields(s *codegen.StructInfo) []jsonField {
+	var fields []jsonField
+	for _, f := range jsonFields(s) {
+		if !f.IsSlice && !f.IsMap && f.TypeName == "Duration" && importPath(s.Imports, f.TypePkg) == "time" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// sparseFields returns the fields of s visible to encoding/json that omitempty
+// encodes by their value rather than by whether the partial sets them: slices and maps,
+// which it leaves out when set to empty values, and the nested partials for which
+// merged reports true, which it writes as {} when empty.
+func sparseFields(s *codegen.StructInfo, merged func(codegen.FieldInfo) bool) []jsonField {
+	var fields []jsonField
+	for _, f := range jsonFields(s) {
+		if f.IsSlice || f.IsMap || merged(f.FieldInfo) {
+			fields = append(fields, f)
+		}
 	}
+	return fields
 }
 
-func partialTypeName(s *codegen.StructInfo) string {
-	if s.Package != "" {
-		// External package struct: prefix with capitalized package name
-		return capitalize(s.Package) + s.Name + "Partial"
+// emptyJSON returns the encoding of an empty value of a slice or map field.
+func emptyJSON(f jsonField) string {
+	switch {
+	case f.IsMap:
+		return "{}"
+	case f.IsBytes:
+		return `""`
 	}
-	return s.Name + "Partial"
+	return "[]"
+}
+
+// urlFields returns the url.URL and *url.URL fields of s that are visible to
+// encoding/json, whose partial decodes them from URL strings.
+func urlFields(s *codegen.StructInfo) []jsonField {
+	var fields []jsonField
+	for _, f := range jsonFields(s) {
+		if !f.IsSlice && !f.IsMap && f.TypeName == "URL" && importPath(s.Imports, f.TypePkg) == "net/url" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// urlTypeName returns the name of the generated URL wrapper for a root type.
+func urlTypeName(root string) string {
+	return strings.ToLower(root[:1]) + root[1:] + "PartialURL"
+}
+
+// durationTypeName returns the name of the generated duration wrapper for a root type.
+func durationTypeName(root string) string {
+	return strings.ToLower(root[:1]) + root[1:] + "PartialDuration"
+}
+
+// importPath resolves a package name used in a field type to its import path.
+func importPath(imports []codegen.ImportInfo, pkg string) string {
+	for _, imp := range imports {
+		name := imp.Alias
+		if name == "" {
+			name = filepath.Base(imp.Path)
+		}
+		if name == pkg {
+			return imp.Path
+		}
+	}
+	return ""
+}
+
+// partialImports filters imports down to the packages still referenced by partial
+// field types. External structs are replaced by generated partials and drop out.
+func partialImports(structs []*codegen.StructInfo, imports []codegen.ImportInfo, externalStructs map[string]bool) []codegen.ImportInfo {
+	used := make(map[string]bool)
+	for _, s := range structs {
+		for _, f := range s.Fields {
+			if f.TypePkg != "" && !externalStructs[f.TypePkg+"."+f.TypeName] {
+				used[f.TypePkg] = true
+			}
+			for _, t := range []string{f.SliceType, f.MapKeyType, f.MapValType} {
+				if pkg, _, ok := strings.Cut(t, "."); ok {
+					used[pkg] = true
+				}
+			}
+		}
+	}
+	var result []codegen.ImportInfo
+	for _, imp := range imports {
+		name := imp.Alias
+		if name == "" {
+			name = filepath.Base(imp.Path)
+		}
+		if used[name] {
+			result = append(result, imp)
+		}
+	}
+	return result
+}
+
+// withImports returns imports with each of paths added unless already present.
+func withImports(imports []codegen.ImportInfo, paths ...string) []codegen.ImportInfo {
+	result := append([]codegen.ImportInfo(nil), imports...)
+	for _, path := range paths {
+		if importPath(result, filepath.Base(path)) != path {
+			result = append(result, codegen.ImportInfo{Path: path})
+		}
+	}
+	return result
 }
 
 func capitalize(s string) string {
@@ -131,15 +832,15 @@ func capitalize(s string) string {
 	return strings.ToUpper(s[:1]) + s[1:]
 }
 
-func pointerTypeNameFunc(externalStructs map[string]bool) func(f codegen.FieldInfo) string {
+func pointerTypeNameFunc(externalStructs map[string]bool, names *partialNames) func(f codegen.FieldInfo) string {
 	return func(f codegen.FieldInfo) string {
 		if f.IsPointer {
 			if f.IsStruct && f.TypePkg == "" {
-				return "*" + f.TypeName + "Partial"
+				return "*" + names.field(f)
 			}
 			// Check if this is an external struct we're generating partials for
 			if f.TypePkg != "" && externalStructs[f.TypePkg+"."+f.TypeName] {
-				return "*" + capitalize(f.TypePkg) + f.TypeName + "Partial"
+				return "*" + names.field(f)
 			}
 			if f.TypePkg != "" {
 				return "*" + f.TypePkg + "." + f.TypeName
@@ -150,11 +851,11 @@ func pointerTypeNameFunc(externalStructs map[string]bool) func(f codegen.FieldIn
 			return f.TypeName
 		}
 		if f.IsStruct && f.TypePkg == "" {
-			return "*" + f.TypeName + "Partial"
+			return "*" + names.field(f)
 		}
 		// Check if this is an external struct we're generating partials for
 		if f.TypePkg != "" && externalStructs[f.TypePkg+"."+f.TypeName] {
-			return "*" + capitalize(f.TypePkg) + f.TypeName + "Partial"
+			return "*" + names.field(f)
 		}
 		if f.TypePkg != "" {
 			return "*" + f.TypePkg + "." + f.TypeName
@@ -195,48 +896,6 @@ func isExternalFieldFunc(externalStructs map[string]bool) func(f codegen.FieldIn
 	}
 }
 
-func externalPartialNameFunc(externalStructs map[string]bool) func(f codegen.FieldInfo) string {
-	return func(f codegen.FieldInfo) string {
-		if f.TypePkg != "" && externalStructs[f.TypePkg+"."+f.TypeName] {
-			return capitalize(f.TypePkg) + f.TypeName + "Partial"
-		}
-		return f.TypeName + "Partial"
-	}
-}
-
-// collectMergeImports gathers imports needed for the merge file (only external struct packages).
-func collectMergeImports(structs []*codegen.StructInfo, externalStructs map[string]bool) []codegen.ImportInfo {
-	// Build a map of all available imports
-	allImports := make(map[string]codegen.ImportInfo)
-	for _, s := range structs {
-		for _, imp := range s.Imports {
-			pkgName := imp.Alias
-			if pkgName == "" {
-				pkgName = filepath.Base(imp.Path)
-			}
-			allImports[pkgName] = imp
-		}
-	}
-
-	// For merge file, we only need imports for external structs we're generating Apply helpers for
-	usedPkgs := make(map[string]bool)
-	for _, s := range structs {
-		for _, f := range s.Fields {
-			if f.TypePkg != "" && externalStructs[f.TypePkg+"."+f.TypeName] {
-				usedPkgs[f.TypePkg] = true
-			}
-		}
-	}
-
-	imports := make([]codegen.ImportInfo, 0)
-	for pkgName := range usedPkgs {
-		if imp, ok := allImports[pkgName]; ok {
-			imports = append(imports, imp)
-		}
-	}
-	return imports
-}
-
 // collectAllImports gathers imports from all structs that are actually used by fields.
 func collectAllImports(structs []*codegen.StructInfo) []codegen.ImportInfo {
 	// Build a map of all available imports
@@ -0,0 +1,93 @@
+package merge
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bobcob7/sudo-gen/internal/codegen"
+)
+
+// partialNames names the partial types of a run: the name of the struct followed by
+// the partial-suffix of sudo-gen.yaml, prefixed with the capitalized name its package is imported as
+// for structs of other packages (DurationTimestampPartial). Each name is registered
+// for the struct it is generated for, so that a name two structs would share, or one
+// the output package already declares, is reported with both declarations instead
+// of generating code that doesn't compile.
+type partialNames struct {
+	cfg    codegen.GeneratorConfig
+	byName map[string]*codegen.StructInfo
+}
+
+func newPartialNames(cfg codegen.GeneratorConfig) *partialNames {
+	return &partialNames{cfg: cfg, byName: make(map[string]*codegen.StructInfo)}
+}
+
+// of returns the name of the partial type of st.
+func (n *partialNames) of(st *codegen.StructInfo) string {
+	if st.Package != "" {
+		return capitalize(st.Package) + n.cfg.Partial(st.Name)
+	}
+	return n.cfg.Partial(st.Name)
+}
+
+// field returns the name of the partial type of the struct f holds, which is
+// external if f has a package.
+func (n *partialNames) field(f codegen.FieldInfo) string {
+	if f.TypePkg != "" {
+		return capitalize(f.TypePkg) + n.cfg.Partial(f.TypeName)
+	}
+	return n.cfg.Partial(f.TypeName)
+}
+
+// register registers the partial types of structs, reporting structs whose partial
+// types would have the same name, such as a local DurationTimestamp and an external
+// duration.Timestamp.
+func (n *partialNames) register(structs []*codegen.StructInfo) error {
+	for _, st := range structs {
+		name := n.of(st)
+		prev, ok := n.byName[name]
+		if !ok {
+			n.byName[name] = st
+			continue
+		}
+		return fmt.Errorf("%s (%s) and %s (%s) would both generate partial type %s; import one of the packages under a different alias, or use -external=passthrough",
+			qualifiedName(prev), prev.Pos, qualifiedName(st), st.Pos, name)
+	}
+	return nil
+}
+
+// checkDeclared reports registered partial types whose names the output package
+// already declares in another file than file, the partial file merge writes, such
+// as a hand-written ConfigPartial or the partial of a struct that another merge
+// output shares. Output packages that can't be parsed aren't checked.
+func (n *partialNames) checkDeclared(file string) error {
+	pkg, err := n.outputPackage()
+	if err != nil {
+		return nil
+	}
+	var conflicts []string
+	for _, name := range slices.Sorted(maps.Keys(n.byName)) {
+		pos, ok := pkg.TypeDecl(name, file)
+		if !ok {
+			continue
+		}
+		st := n.byName[name]
+		conflicts = append(conflicts, fmt.Sprintf("partial type %s of %s (%s) is already declared at %s", name, qualifiedName(st), st.Pos, pos))
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s; rename the declared types, or name the partials with another partial-suffix in %s", strings.Join(conflicts, "; "), codegen.ProjectFileName)
+}
+
+// outputPackage returns the package merge generates into, with the in-memory source
+// in place of the source file when they are the same package.
+func (n *partialNames) outputPackage() (*codegen.Package, error) {
+	if n.cfg.Source != nil && filepath.Clean(n.cfg.OutputDir) == filepath.Clean(n.cfg.SourceDir) {
+		return n.cfg.Index.Overlay(n.cfg.SourceDir, n.cfg.SourceFile, n.cfg.Source)
+	}
+	return n.cfg.Index.Package(n.cfg.OutputDir)
+}
@@ -13,10 +13,335 @@ import (
 {{end}}
 
 {{range .Structs}}
-type {{partialType .}} struct {
+{{- $s := .}}
+{{- $clear := and $.Clear (not (isExternal .))}}
+{{- $durations := durationFields .}}
+{{- $urls := urlFields .}}
+{{- $sparse := sparseFields .}}
+type {{partialDecl .}} struct {
+{{- range $i, $f := .Fields}}
+{{- range $j, $line := .Leading}}
+{{- if $line}}
+	{{$line}}
+{{- else if or $i $j}}
+{{end}}
+{{- end}}
+	{{.Name}} {{pointerType .}} {{partialTag .}}{{with .Trailing}} {{.}}{{end}}
+{{- end}}
+{{- if $clear}}
+
+	// Clear names the fields ApplyPartial resets to their zero value before applying
+	// the fields set above. Fields are added with ClearField.
+	Clear []string {{clearTag}}
+{{- end}}
+}
+{{- if $clear}}
+
+// ClearField marks the named field to be reset to its zero value, discarding any
+// value p sets for it. It reports false if {{partialType .}} has no such field.
+func (p *{{partialType .}}) ClearField(name string) bool {
+	switch name {
+{{- range .Fields}}
+	case "{{.Name}}":
+		p.{{.Name}} = nil
+{{- end}}
+	default:
+		return false
+	}
+	if !slices.Contains(p.Clear, name) {
+		p.Clear = append(p.Clear, name)
+	}
+	return true
+}
+{{- end}}
+
+// Merge returns p with the fields other sets applied over it, as applying p and then
+// other with ApplyPartial would: maps are merged key by key and nested partials field
+// by field. The result shares unmerged values with p and other, which must not be
+// modified while it is in use.
+{{- if $clear}} Fields other clears drop the values p sets for them.
+{{- end}}
+func (p {{partialType .}}) Merge(other {{partialType .}}) {{partialType .}} {
+{{- if $clear}}
+	p.Clear = slices.Clone(p.Clear)
+	for _, name := range other.Clear {
+		p.ClearField(name)
+	}
+{{- end}}
+{{- range .Fields}}
+{{- if .IsMap}}
+	if other.{{.Name}} != nil {
+		m := make({{pointerType .}}, len(p.{{.Name}})+len(other.{{.Name}}))
+		maps.Copy(m, p.{{.Name}})
+		maps.Copy(m, other.{{.Name}})
+		p.{{.Name}} = m
+	}
+{{- else if and (needsConversion .) (not (replaces $s .))}}
+	if other.{{.Name}} != nil {
+		if p.{{.Name}} == nil {
+			p.{{.Name}} = other.{{.Name}}
+		} else {
+			v := p.{{.Name}}.Merge(*other.{{.Name}})
+			p.{{.Name}} = &v
+		}
+	}
+{{- else}}
+	if other.{{.Name}} != nil {
+		p.{{.Name}} = other.{{.Name}}
+	}
+{{- end}}
+{{- end}}
+	return p
+}
+
+// IsEmpty reports whether p {{if $clear}}neither sets nor clears any field{{else}}sets no field{{end}}. Nested
+// partials that are empty themselves count as unset (see Prune).
+func (p *{{partialType .}}) IsEmpty() bool {
+	if p == nil {
+		return true
+	}
+{{- if $clear}}
+	if len(p.Clear) > 0 {
+		return false
+	}
+{{- end}}
+{{- range .Fields}}
+{{- if and (needsConversion .) (not (replaces $s .))}}
+	if !p.{{.Name}}.IsEmpty() {
+		return false
+	}
+{{- else}}
+	if p.{{.Name}} != nil {
+		return false
+	}
+{{- end}}
+{{- end}}
+	return true
+}
+
+// Prune sets the nested partials of p that are empty to nil, at every depth, so that
+// p encodes without empty objects. Applying a pruned partial no longer allocates the
+// structs of nil pointer fields that those partials would have left zero. Partials of
+// fields replaced as a whole are kept, since applying them resets the field.
+func (p *{{partialType .}}) Prune() {
+	if p == nil {
+		return
+	}
 {{- range .Fields}}
-	{{.Name}} {{pointerType .}} {{.Tag}}
+{{- if needsConversion .}}
+	p.{{.Name}}.Prune()
+{{- if not (replaces $s .)}}
+	if p.{{.Name}}.IsEmpty() {
+		p.{{.Name}} = nil
+	}
+{{- end}}
+{{- end}}
+{{- end}}
+}
+{{- if or $durations $urls $clear}}
+
+// UnmarshalJSON decodes p
+{{- if or $durations $urls}}, accepting
+{{- if $durations}} duration strings such as "1h30m" for time.Duration fields{{end}}
+{{- if and $durations $urls}} and{{end}}
+{{- if $urls}} URL strings for url.URL fields{{end}}.
+{{- if $clear}}
+// A null value clears the field (see ClearField).
+{{- end}}
+{{- else}}. A null value clears the field (see ClearField).
+{{- end}}
+func (p *{{partialType $s}}) UnmarshalJSON(data []byte) error {
+	type partial {{partialType $s}}
+	aux := struct {
+		*partial
+{{- range $durations}}
+		{{.Name}} *{{$.DurationType}} ` + "`" + `json:"{{.Key}}"` + "`" + `
+{{- end}}
+{{- range $urls}}
+		{{.Name}} *{{$.URLType}} ` + "`" + `json:"{{.Key}}"` + "`" + `
+{{- end}}
+	}{partial: (*partial)(p)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+{{- range $durations}}
+	if aux.{{.Name}} != nil {
+		p.{{.Name}} = (*time.Duration)(aux.{{.Name}})
+	}
+{{- end}}
+{{- range $urls}}
+	if aux.{{.Name}} != nil {
+		p.{{.Name}} = (*{{.TypePkg}}.URL)(aux.{{.Name}})
+	}
+{{- end}}
+{{- if and $clear (jsonFields $s)}}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+{{- range jsonFields $s}}
+	if string(fields["{{.Key}}"]) == "null" {
+		p.ClearField("{{.Name}}")
+	}
+{{- end}}
+{{- end}}
+	return nil
+}
+{{- end}}
+{{- if or $durations $urls $clear $sparse}}
+
+// MarshalJSON encodes p
+{{- if or $durations $urls}}, writing
+{{- if $durations}} time.Duration fields as duration strings{{end}}
+{{- if and $durations $urls}} and{{end}}
+{{- if $urls}} url.URL fields as URL strings{{end}}.
+{{- if $clear}}
+// Cleared fields are written as null, and fields that are neither set nor cleared
+// are left out.
+{{- end}}
+{{- else if $clear}}, writing cleared fields as null and leaving out
+// fields that are neither set nor cleared.
+{{- else}}, leaving out the fields it doesn't set.
 {{- end}}
+{{- $nestedSparse := false}}
+{{- $emptySparse := false}}
+{{- range $sparse}}{{if or .IsSlice .IsMap}}{{$emptySparse = true}}{{else}}{{$nestedSparse = true}}{{end}}{{end}}
+{{- if and $nestedSparse $emptySparse}}
+// Empty nested partials count as unset, as after Prune, while slices and maps set
+// to empty values are written.
+{{- else if $nestedSparse}}
+// Empty nested partials count as unset, as after Prune.
+{{- else if $emptySparse}}
+// Slices and maps set to empty values are written.
+{{- end}}
+func (p {{partialType $s}}) MarshalJSON() ([]byte, error) {
+	type partial {{partialType $s}}
+	aux := struct {
+		*partial
+{{- range $durations}}
+		{{.Name}} *{{$.DurationType}} ` + "`" + `json:"{{.Key}},omitempty"` + "`" + `
+{{- end}}
+{{- range $urls}}
+		{{.Name}} *{{$.URLType}} ` + "`" + `json:"{{.Key}},omitempty"` + "`" + `
+{{- end}}
+	}{
+		partial: (*partial)(&p),
+{{- range $durations}}
+		{{.Name}}: (*{{$.DurationType}})(p.{{.Name}}),
+{{- end}}
+{{- range $urls}}
+		{{.Name}}: (*{{$.URLType}})(p.{{.Name}}),
+{{- end}}
+	}
+{{- if or (and $clear (jsonFields $s)) $sparse}}
+	data, err := json.Marshal(aux)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+{{- range jsonFields $s}}
+{{- $nested := and (needsConversion .FieldInfo) (not (replaces $s .FieldInfo))}}
+{{- if or $clear $nested .IsSlice .IsMap}}
+{{- if $nested}}
+	if p.{{.Name}}.IsEmpty() {
+{{- else}}
+	if p.{{.Name}} == nil {
+{{- end}}
+{{- if $clear}}
+		if slices.Contains(p.Clear, "{{.Name}}") {
+			fields["{{.Key}}"] = json.RawMessage("null")
+		} else {
+			delete(fields, "{{.Key}}")
+		}
+{{- else}}
+		delete(fields, "{{.Key}}")
+{{- end}}
+{{- if or .IsSlice .IsMap}}
+	} else if len(p.{{.Name}}) == 0 {
+		fields["{{.Key}}"] = json.RawMessage({{printf "%q" (emptyJSON .)}})
+{{- end}}
+	}
+{{- end}}
+{{- end}}
+	return json.Marshal(fields)
+{{- else}}
+	return json.Marshal(aux)
+{{- end}}
+}
+{{- end}}
+{{end}}
+{{- with index .Structs 0}}
+// {{.Name}}SparseEntry sets a single leaf field of {{.Name}} addressed by a dotted
+// path of Go field names (e.g. "Database.Host"). Value must have the field's
+// type, or the pointed-to type for pointer fields.
+type {{.Name}}SparseEntry struct {
+	Path  string
+	Value any
+}
+
+// Overlay returns p with layers merged over it in order, the last one winning, to
+// compose the partials of a defaults, file and environment pipeline before applying
+// them to a {{.Name}}: defaults.Overlay(file, env). See Merge.
+func (p {{partialType .}}) Overlay(layers ...{{partialType .}}) {{partialType .}} {
+	for _, layer := range layers {
+		p = p.Merge(layer)
+	}
+	return p
+}
+{{end}}
+{{- if .DurationType}}
+// {{.DurationType}} is a time.Duration that encodes as a duration string and
+// decodes from either a duration string such as "1h30m" or integer nanoseconds.
+type {{.DurationType}} time.Duration
+
+// MarshalJSON encodes d as a duration string.
+func (d {{.DurationType}}) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON decodes d from a duration string or integer nanoseconds.
+func (d *{{.DurationType}}) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		var n int64
+		if err := json.Unmarshal(data, &n); err != nil {
+			return fmt.Errorf("duration must be a string such as \"1h30m\" or integer nanoseconds: %w", err)
+		}
+		*d = {{.DurationType}}(n)
+		return nil
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = {{.DurationType}}(v)
+	return nil
+}
+{{end}}
+{{- if .URLType}}
+// {{.URLType}} is a url.URL that encodes as a URL string and decodes from one.
+type {{.URLType}} url.URL
+
+// MarshalJSON encodes u as a URL string.
+func (u *{{.URLType}}) MarshalJSON() ([]byte, error) {
+	return json.Marshal((*url.URL)(u).String())
+}
+
+// UnmarshalJSON decodes u from a URL string, parsed with url.Parse.
+func (u *{{.URLType}}) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("URL must be a string such as \"https://example.com\": %w", err)
+	}
+	v, err := url.Parse(s)
+	if err != nil {
+		return err
+	}
+	*u = {{.URLType}}(*v)
+	return nil
 }
 {{end}}
 `
@@ -25,13 +350,130 @@ const mergeTemplate = `// Code generated by sudo-gen merge. DO NOT EDIT.
 
 package {{.Package}}
 
-{{if .Imports}}
 import (
+	"fmt"
+	"strings"
 {{- range .Imports}}
 	{{if .Alias}}{{.Alias}} {{end}}"{{.Path}}"
 {{- end}}
 )
-{{end}}
+
+{{define "sparseFields"}}
+{{- if not .Fields}}
+	return fmt.Errorf("unknown field %q", path)
+{{- else}}
+	name, rest, _ := strings.Cut(path, ".")
+	switch name {
+{{- range .Fields}}
+	case "{{.Name}}":
+{{- if needsConversion .}}
+		if rest == "" {
+			return fmt.Errorf("{{.Name}} is a struct, not a field")
+		}
+{{- if .IsPointer}}
+		if c.{{.Name}} == nil {
+			{{- if isExternalField .}}
+			c.{{.Name}} = &{{.TypePkg}}.{{.TypeName}}{}
+			{{- else}}
+			c.{{.Name}} = &{{.TypeName}}{}
+			{{- end}}
+		}
+		{{- if isExternalField .}}
+		return applySparse{{externalPartial .}}(c.{{.Name}}, rest, value)
+		{{- else}}
+		return c.{{.Name}}.applySparse(rest, value)
+		{{- end}}
+{{- else if isExternalField .}}
+		return applySparse{{externalPartial .}}(&c.{{.Name}}, rest, value)
+{{- else}}
+		return c.{{.Name}}.applySparse(rest, value)
+{{- end}}
+{{- else}}
+		if rest != "" {
+			return fmt.Errorf("{{.Name}} has no fields")
+		}
+		v, ok := value.({{leafType .}})
+		if !ok {
+			return fmt.Errorf("{{.Name}}: expected {{leafType .}}, got %T", value)
+		}
+{{- if and (utc .) (not .IsSlice) (not .IsMap)}}
+		v = v.UTC()
+{{- end}}
+{{- if .IsBytes}}
+		c.{{.Name}} = bytes.Clone(v)
+{{- else if and .IsSlice (utc .)}}
+		c.{{.Name}} = make({{.TypeName}}, len(v))
+		for i, t := range v {
+			c.{{.Name}}[i] = t.UTC()
+		}
+{{- else if .IsSlice}}
+		c.{{.Name}} = make({{.TypeName}}, len(v))
+		copy(c.{{.Name}}, v)
+{{- else if .IsMap}}
+		if c.{{.Name}} == nil {
+			c.{{.Name}} = make({{.TypeName}}, len(v))
+		}
+		for k, mv := range v {
+			c.{{.Name}}[k] = mv{{if utc .}}.UTC(){{end}}
+		}
+{{- else if .IsPointer}}
+		c.{{.Name}} = &v
+{{- else}}
+		c.{{.Name}} = v
+{{- end}}
+{{- end}}
+{{- end}}
+	default:
+		return fmt.Errorf("unknown field %q", name)
+	}
+	return nil
+{{- end}}
+{{- end}}
+
+{{define "toPartialFields"}}
+	var p {{partialType .}}
+{{- range .Fields}}
+{{- if .IsBytes}}
+	p.{{.Name}} = bytes.Clone(c.{{.Name}})
+{{- else if .IsSlice}}
+	if c.{{.Name}} != nil {
+		p.{{.Name}} = slices.Clone(c.{{.Name}})
+	}
+{{- else if .IsMap}}
+	if c.{{.Name}} != nil {
+		p.{{.Name}} = maps.Clone(c.{{.Name}})
+	}
+{{- else if and .IsPointer (needsConversion .)}}
+	if c.{{.Name}} != nil {
+		{{- if isExternalField .}}
+		n := to{{externalPartial .}}(c.{{.Name}}, skipZero)
+		{{- else}}
+		n := c.{{.Name}}.toPartial(skipZero)
+		{{- end}}
+		p.{{.Name}} = &n
+	}
+{{- else if .IsPointer}}
+	if c.{{.Name}} != nil {
+		v := *c.{{.Name}}
+		p.{{.Name}} = &v
+	}
+{{- else if needsConversion .}}
+	{{- if isExternalField .}}
+	if n := to{{externalPartial .}}(&c.{{.Name}}, skipZero); !skipZero || !n.IsEmpty() {
+	{{- else}}
+	if n := c.{{.Name}}.toPartial(skipZero); !skipZero || !n.IsEmpty() {
+	{{- end}}
+		p.{{.Name}} = &n
+	}
+{{- else}}
+	if !skipZero || {{nonZero .}} {
+		v := c.{{.Name}}
+		p.{{.Name}} = &v
+	}
+{{- end}}
+{{- end}}
+	return p
+{{- end}}
 
 {{range .Structs}}
 {{- if isExternal .}}
@@ -41,7 +483,18 @@ func apply{{partialType .}}(c *{{.Package}}.{{.Name}}, p *{{partialType .}}) {
 		return
 	}
 {{- range .Fields}}
-{{- if .IsSlice}}
+{{- if .IsBytes}}
+	if p.{{.Name}} != nil {
+		c.{{.Name}} = bytes.Clone(p.{{.Name}})
+	}
+{{- else if and .IsSlice (utc .)}}
+	if p.{{.Name}} != nil {
+		c.{{.Name}} = make({{.TypeName}}, len(p.{{.Name}}))
+		for i, t := range p.{{.Name}} {
+			c.{{.Name}}[i] = t.UTC()
+		}
+	}
+{{- else if .IsSlice}}
 	if p.{{.Name}} != nil {
 		c.{{.Name}} = make({{.TypeName}}, len(p.{{.Name}}))
 		copy(c.{{.Name}}, p.{{.Name}})
@@ -52,28 +505,90 @@ func apply{{partialType .}}(c *{{.Package}}.{{.Name}}, p *{{partialType .}}) {
 			c.{{.Name}} = make({{.TypeName}}, len(p.{{.Name}}))
 		}
 		for k, v := range p.{{.Name}} {
-			c.{{.Name}}[k] = v
+			c.{{.Name}}[k] = v{{if utc .}}.UTC(){{end}}
 		}
 	}
 {{- else if .IsPointer}}
 	if p.{{.Name}} != nil {
-		v := *p.{{.Name}}
+		v := {{if utc .}}p.{{.Name}}.UTC(){{else}}*p.{{.Name}}{{end}}
 		c.{{.Name}} = &v
 	}
 {{- else}}
 	if p.{{.Name}} != nil {
-		c.{{.Name}} = *p.{{.Name}}
+		c.{{.Name}} = {{if utc .}}p.{{.Name}}.UTC(){{else}}*p.{{.Name}}{{end}}
 	}
 {{- end}}
 {{- end}}
 }
+
+// applySparse{{partialType .}} applies a single sparse update to a {{.Package}}.{{.Name}}.
+func applySparse{{partialType .}}(c *{{.Package}}.{{.Name}}, path string, value any) error {
+{{- template "sparseFields" .}}
+}
+
+// to{{partialType .}} returns a partial setting the fields of c, or only
+// those not holding their zero value if skipZero is set.
+func to{{partialType .}}(c *{{.Package}}.{{.Name}}, skipZero bool) {{partialType .}} {
+{{- template "toPartialFields" .}}
+}
 {{- else}}
-func (c *{{.Name}}) ApplyPartial(p *{{partialType .}}) {
+{{- $s := .}}
+func (c *{{structType .}}) {{method "ApplyPartial"}}(p *{{partialType .}}) {
 	if c == nil || p == nil {
 		return
 	}
+{{- if and $.Migrate (eq .Name $.Root)}}
+	p = p.MigrateDeprecated()
+{{- end}}
+{{- if $.Clear}}
+	for _, name := range p.Clear {
+		switch name {
+{{- range .Fields}}
+		case "{{.Name}}":
+{{- if or .IsPointer .IsSlice .IsMap}}
+			c.{{.Name}} = nil
+{{- else}}
+			var zero {{.Type}}
+			c.{{.Name}} = zero
+{{- end}}
+{{- end}}
+		}
+	}
+{{- end}}
 {{- range .Fields}}
+{{- if .TypeParam}}
+	if p.{{.Name}} != nil {
 {{- if .IsSlice}}
+		c.{{.Name}} = make({{.TypeName}}, len(p.{{.Name}}))
+		for i, v := range p.{{.Name}} {
+			c.{{.Name}}[i] = {{copyValue . "v"}}
+		}
+{{- else if .IsMap}}
+		if c.{{.Name}} == nil {
+			c.{{.Name}} = make({{.TypeName}}, len(p.{{.Name}}))
+		}
+		for k, v := range p.{{.Name}} {
+			c.{{.Name}}[k] = {{copyValue . "v"}}
+		}
+{{- else if .IsPointer}}
+		v := {{copyValue . (print "*p." .Name)}}
+		c.{{.Name}} = &v
+{{- else}}
+		c.{{.Name}} = {{copyValue . (print "*p." .Name)}}
+{{- end}}
+	}
+{{- else if .IsBytes}}
+	if p.{{.Name}} != nil {
+		c.{{.Name}} = bytes.Clone(p.{{.Name}})
+	}
+{{- else if and .IsSlice (utc .)}}
+	if p.{{.Name}} != nil {
+		c.{{.Name}} = make({{.TypeName}}, len(p.{{.Name}}))
+		for i, t := range p.{{.Name}} {
+			c.{{.Name}}[i] = t.UTC()
+		}
+	}
+{{- else if .IsSlice}}
 	if p.{{.Name}} != nil {
 		c.{{.Name}} = make({{.TypeName}}, len(p.{{.Name}}))
 		copy(c.{{.Name}}, p.{{.Name}})
@@ -84,12 +599,20 @@ func (c *{{.Name}}) ApplyPartial(p *{{partialType .}}) {
 			c.{{.Name}} = make({{.TypeName}}, len(p.{{.Name}}))
 		}
 		for k, v := range p.{{.Name}} {
-			c.{{.Name}}[k] = v
+			c.{{.Name}}[k] = v{{if utc .}}.UTC(){{end}}
 		}
 	}
 {{- else if .IsPointer}}
 	{{- if needsConversion .}}
 	if p.{{.Name}} != nil {
+		{{- if replaces $s .}}
+		// Replaced as a whole rather than merged field by field
+		{{- if isExternalField .}}
+		c.{{.Name}} = &{{.TypePkg}}.{{.TypeName}}{}
+		{{- else}}
+		c.{{.Name}} = &{{.TypeName}}{}
+		{{- end}}
+		{{- else}}
 		if c.{{.Name}} == nil {
 			{{- if isExternalField .}}
 			c.{{.Name}} = &{{.TypePkg}}.{{.TypeName}}{}
@@ -97,35 +620,135 @@ func (c *{{.Name}}) ApplyPartial(p *{{partialType .}}) {
 			c.{{.Name}} = &{{.TypeName}}{}
 			{{- end}}
 		}
+		{{- end}}
 		{{- if isExternalField .}}
 		apply{{externalPartial .}}(c.{{.Name}}, p.{{.Name}})
 		{{- else}}
-		c.{{.Name}}.ApplyPartial(p.{{.Name}})
+		c.{{.Name}}.{{method "ApplyPartial"}}(p.{{.Name}})
 		{{- end}}
 	}
 	{{- else}}
 	if p.{{.Name}} != nil {
-		v := *p.{{.Name}}
+		v := {{if utc .}}p.{{.Name}}.UTC(){{else}}*p.{{.Name}}{{end}}
 		c.{{.Name}} = &v
 	}
 	{{- end}}
 {{- else if needsConversion .}}
 	if p.{{.Name}} != nil {
+	{{- if replaces $s .}}
+		// Replaced as a whole rather than merged field by field
+	{{- if isExternalField .}}
+		c.{{.Name}} = {{.TypePkg}}.{{.TypeName}}{}
+	{{- else}}
+		c.{{.Name}} = {{.TypeName}}{}
+	{{- end}}
+	{{- end}}
 	{{- if isExternalField .}}
 		apply{{externalPartial .}}(&c.{{.Name}}, p.{{.Name}})
 	{{- else}}
-		c.{{.Name}}.ApplyPartial(p.{{.Name}})
+		c.{{.Name}}.{{method "ApplyPartial"}}(p.{{.Name}})
 	{{- end}}
 	}
 {{- else}}
 	if p.{{.Name}} != nil {
-		c.{{.Name}} = *p.{{.Name}}
+		c.{{.Name}} = {{if utc .}}p.{{.Name}}.UTC(){{else}}*p.{{.Name}}{{end}}
 	}
 {{- end}}
 {{- end}}
 }
+{{- if eq .Name $.Root}}
+
+// {{method "ApplySparse"}} applies each entry to c in order without materializing nested partials.
+// Entries applied before a failing entry remain applied.
+func (c *{{structType .}}) {{method "ApplySparse"}}(entries ...{{.Name}}SparseEntry) error {
+	if c == nil {
+		return nil
+	}
+	for _, e := range entries {
+		if err := c.applySparse(e.Path, e.Value); err != nil {
+			return fmt.Errorf("sparse path %q: %w", e.Path, err)
+		}
+	}
+	return nil
+}
+{{- end}}
+
+func (c *{{structType .}}) applySparse(path string, value any) error {
+{{- template "sparseFields" .}}
+}
+
+// {{method "ToPartial"}} returns a partial setting every field of c, so that a snapshot of c
+// can be applied as a layer. Nil pointers, slices and maps are left unset, and the
+// others are copied shallowly.
+func (c {{structType .}}) {{method "ToPartial"}}() {{partialType .}} {
+	return c.toPartial(false)
+}
+
+// {{method "NonZeroPartial"}} returns a partial setting the fields of c that don't hold their zero
+// value, so that applying it leaves the fields c doesn't set untouched. Nested structs
+// that are zero as a whole are left unset too.
+func (c {{structType .}}) {{method "NonZeroPartial"}}() {{partialType .}} {
+	return c.toPartial(true)
+}
+
+func (c {{structType .}}) toPartial(skipZero bool) {{partialType .}} {
+{{- template "toPartialFields" .}}
+}
 {{- end}}
 {{end}}
+{{- if .Deprecations}}
+// {{.Root}}Deprecation is a field tagged sudo:"deprecated" that a partial sets.
+type {{.Root}}Deprecation struct {
+	Path    string // Dotted path of Go field names, e.g. "Database.URL"
+	Message string // Message of the deprecated tag option
+}
+
+// Deprecations returns the deprecated fields p sets, at any depth outside slices
+// and maps, so loaders can warn about them.
+func (p *{{partial .Root}}) Deprecations() []{{.Root}}Deprecation {
+	if p == nil {
+		return nil
+	}
+	var deprecations []{{.Root}}Deprecation
+{{- range .Deprecations}}
+	if {{.Set "p"}} {
+		deprecations = append(deprecations, {{$.Root}}Deprecation{Path: {{printf "%q" .Path}}, Message: {{printf "%q" .Message}}})
+	}
+{{- end}}
+	return deprecations
+}
+{{- if .Replacements}}
+
+// MigrateDeprecated returns a copy of p that also sets each replacement named by a
+// deprecated field it sets, unless p sets the replacement too. Nested partials are
+// copied before they change, so p is left unchanged.
+{{- if .Migrate}}
+// {{method "ApplyPartial"}} migrates each partial before applying it.
+{{- end}}
+func (p *{{partial .Root}}) MigrateDeprecated() *{{partial .Root}} {
+	if p == nil {
+		return nil
+	}
+	q := *p
+{{- range .Deprecations}}
+{{- if .Replacement}}
+	if {{.Set "q"}} && ({{.Replacement.Unset "q"}}) {
+{{- range .Replacement.Steps "q"}}
+		if {{.Expr}} == nil {
+			{{.Expr}} = &{{.Type}}{}
+		} else {
+			v := *{{.Expr}}
+			{{.Expr}} = &v
+		}
+{{- end}}
+		{{.Replacement.Field "q"}} = {{.Field "q"}}
+	}
+{{- end}}
+{{- end}}
+	return &q
+}
+{{- end}}
+{{- end}}
 `
 
 const mergeTestTemplate = `// Code generated by sudo-gen merge. DO NOT EDIT.
@@ -133,34 +756,54 @@ const mergeTestTemplate = `// Code generated by sudo-gen merge. DO NOT EDIT.
 package {{.Package}}
 
 import (
+{{- if or .JSON .Clear}}
+	"encoding/json"
+{{- end}}
+{{- if .Clear}}
+	"slices"
+{{- end}}
 	"testing"
+{{- if .JSON}}
+	"time"
+{{- end}}
 )
-
-func mergePtr[T any](v T) *T {
-	return &v
-}
 {{range .Structs}}
+{{- $s := .}}
 {{- if not (isExternal .)}}
 func Test{{.Name}}ApplyPartialNil(t *testing.T) {
 	var c *{{.Name}}
-	c.ApplyPartial(nil) // should not panic
+	c.{{method "ApplyPartial"}}(nil) // should not panic
 
 	c = &{{.Name}}{}
-	c.ApplyPartial(nil) // should not panic
+	c.{{method "ApplyPartial"}}(nil) // should not panic
 }
 
 func Test{{.Name}}ApplyPartialEmpty(t *testing.T) {
 	c := &{{.Name}}{}
 	p := &{{partialType .}}{}
-	c.ApplyPartial(p) // should not panic or change anything
+	c.{{method "ApplyPartial"}}(p) // should not panic or change anything
+}
+{{- range .Fields}}
+{{- if and (utc .) (not .IsPointer) (not .IsSlice) (not .IsMap)}}
+
+func Test{{$s.Name}}ApplyPartial_{{.Name}}UTC(t *testing.T) {
+	local := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("UTC+2", 2*60*60))
+	c := &{{$s.Name}}{}
+	c.{{method "ApplyPartial"}}(&{{partialType $s}}{ {{.Name}}: &local})
+	if c.{{.Name}}.Location() != time.UTC || !c.{{.Name}}.Equal(local) {
+		t.Errorf("expected {{.Name}} in UTC, got %v", c.{{.Name}})
+	}
 }
+{{- break}}
+{{- end}}
+{{- end}}
 {{- end}}
 {{- if not (isExternal .)}}
 {{$typeName := .Name}}{{range .Fields}}{{if not .IsSlice}}{{if not .IsMap}}{{if not .IsStruct}}{{if not .IsPointer}}{{if eq .TypeName "string"}}
 func Test{{$typeName}}ApplyPartial_{{.Name}}(t *testing.T) {
 	c := &{{$typeName}}{}
-	p := &{{$typeName}}Partial{ {{.Name}}: mergePtr("test") }
-	c.ApplyPartial(p)
+	p := &{{partial $typeName}}{ {{.Name}}: sudogenPtr("test") }
+	c.{{method "ApplyPartial"}}(p)
 	if c.{{.Name}} != "test" {
 		t.Errorf("expected {{.Name}}=test, got %s", c.{{.Name}})
 	}
@@ -168,8 +811,8 @@ func Test{{$typeName}}ApplyPartial_{{.Name}}(t *testing.T) {
 
 func Test{{$typeName}}ApplyPartial_{{.Name}}Overwrite(t *testing.T) {
 	c := &{{$typeName}}{ {{.Name}}: "original" }
-	p := &{{$typeName}}Partial{ {{.Name}}: mergePtr("updated") }
-	c.ApplyPartial(p)
+	p := &{{partial $typeName}}{ {{.Name}}: sudogenPtr("updated") }
+	c.{{method "ApplyPartial"}}(p)
 	if c.{{.Name}} != "updated" {
 		t.Errorf("expected {{.Name}}=updated, got %s", c.{{.Name}})
 	}
@@ -177,8 +820,8 @@ func Test{{$typeName}}ApplyPartial_{{.Name}}Overwrite(t *testing.T) {
 {{end}}{{if eq .TypeName "int"}}
 func Test{{$typeName}}ApplyPartial_{{.Name}}(t *testing.T) {
 	c := &{{$typeName}}{}
-	p := &{{$typeName}}Partial{ {{.Name}}: mergePtr(42) }
-	c.ApplyPartial(p)
+	p := &{{partial $typeName}}{ {{.Name}}: sudogenPtr(42) }
+	c.{{method "ApplyPartial"}}(p)
 	if c.{{.Name}} != 42 {
 		t.Errorf("expected {{.Name}}=42, got %d", c.{{.Name}})
 	}
@@ -186,8 +829,8 @@ func Test{{$typeName}}ApplyPartial_{{.Name}}(t *testing.T) {
 
 func Test{{$typeName}}ApplyPartial_{{.Name}}Overwrite(t *testing.T) {
 	c := &{{$typeName}}{ {{.Name}}: 100 }
-	p := &{{$typeName}}Partial{ {{.Name}}: mergePtr(42) }
-	c.ApplyPartial(p)
+	p := &{{partial $typeName}}{ {{.Name}}: sudogenPtr(42) }
+	c.{{method "ApplyPartial"}}(p)
 	if c.{{.Name}} != 42 {
 		t.Errorf("expected {{.Name}}=42, got %d", c.{{.Name}})
 	}
@@ -195,8 +838,8 @@ func Test{{$typeName}}ApplyPartial_{{.Name}}Overwrite(t *testing.T) {
 
 func Test{{$typeName}}ApplyPartial_{{.Name}}ZeroValue(t *testing.T) {
 	c := &{{$typeName}}{ {{.Name}}: 100 }
-	p := &{{$typeName}}Partial{ {{.Name}}: mergePtr(0) }
-	c.ApplyPartial(p)
+	p := &{{partial $typeName}}{ {{.Name}}: sudogenPtr(0) }
+	c.{{method "ApplyPartial"}}(p)
 	if c.{{.Name}} != 0 {
 		t.Errorf("expected {{.Name}}=0 (zero value should be applied), got %d", c.{{.Name}})
 	}
@@ -204,8 +847,8 @@ func Test{{$typeName}}ApplyPartial_{{.Name}}ZeroValue(t *testing.T) {
 {{end}}{{if eq .TypeName "bool"}}
 func Test{{$typeName}}ApplyPartial_{{.Name}}(t *testing.T) {
 	c := &{{$typeName}}{}
-	p := &{{$typeName}}Partial{ {{.Name}}: mergePtr(true) }
-	c.ApplyPartial(p)
+	p := &{{partial $typeName}}{ {{.Name}}: sudogenPtr(true) }
+	c.{{method "ApplyPartial"}}(p)
 	if !c.{{.Name}} {
 		t.Errorf("expected {{.Name}}=true, got %v", c.{{.Name}})
 	}
@@ -213,8 +856,8 @@ func Test{{$typeName}}ApplyPartial_{{.Name}}(t *testing.T) {
 
 func Test{{$typeName}}ApplyPartial_{{.Name}}False(t *testing.T) {
 	c := &{{$typeName}}{ {{.Name}}: true }
-	p := &{{$typeName}}Partial{ {{.Name}}: mergePtr(false) }
-	c.ApplyPartial(p)
+	p := &{{partial $typeName}}{ {{.Name}}: sudogenPtr(false) }
+	c.{{method "ApplyPartial"}}(p)
 	if c.{{.Name}} {
 		t.Errorf("expected {{.Name}}=false, got %v", c.{{.Name}})
 	}
@@ -222,8 +865,8 @@ func Test{{$typeName}}ApplyPartial_{{.Name}}False(t *testing.T) {
 {{end}}{{if or (eq .TypeName "int32") (eq .TypeName "int64") (eq .TypeName "float64")}}
 func Test{{$typeName}}ApplyPartial_{{.Name}}(t *testing.T) {
 	c := &{{$typeName}}{}
-	p := &{{$typeName}}Partial{ {{.Name}}: mergePtr({{.TypeName}}(42)) }
-	c.ApplyPartial(p)
+	p := &{{partial $typeName}}{ {{.Name}}: sudogenPtr({{.TypeName}}(42)) }
+	c.{{method "ApplyPartial"}}(p)
 	if c.{{.Name}} != 42 {
 		t.Errorf("expected {{.Name}}=42, got %v", c.{{.Name}})
 	}
@@ -233,8 +876,8 @@ func Test{{$typeName}}ApplyPartial_{{.Name}}(t *testing.T) {
 func Test{{$typeName}}ApplyPartial_{{.Name}}Slice(t *testing.T) {
 	c := &{{$typeName}}{}
 	newSlice := {{.TypeName}}{}
-	p := &{{$typeName}}Partial{ {{.Name}}: newSlice }
-	c.ApplyPartial(p)
+	p := &{{partial $typeName}}{ {{.Name}}: newSlice }
+	c.{{method "ApplyPartial"}}(p)
 	if c.{{.Name}} == nil {
 		t.Error("expected slice to be set")
 	}
@@ -243,19 +886,35 @@ func Test{{$typeName}}ApplyPartial_{{.Name}}Slice(t *testing.T) {
 func Test{{$typeName}}ApplyPartial_{{.Name}}SliceReplace(t *testing.T) {
 	c := &{{$typeName}}{ {{.Name}}: make({{.TypeName}}, 2) }
 	newSlice := make({{.TypeName}}, 3)
-	p := &{{$typeName}}Partial{ {{.Name}}: newSlice }
-	c.ApplyPartial(p)
+	p := &{{partial $typeName}}{ {{.Name}}: newSlice }
+	c.{{method "ApplyPartial"}}(p)
 	if len(c.{{.Name}}) != 3 {
 		t.Errorf("expected slice length 3, got %d", len(c.{{.Name}}))
 	}
 }
+{{- if .IsBytes}}
+
+func Test{{$typeName}}ApplyPartial_{{.Name}}Bytes(t *testing.T) {
+	c := &{{$typeName}}{ {{.Name}}: {{.TypeName}}("abc") }
+	p := &{{partial $typeName}}{ {{.Name}}: {{.TypeName}}("xy") }
+	c.{{method "ApplyPartial"}}(p)
+	p.{{.Name}}[0] = 'z'
+	if string(c.{{.Name}}) != "xy" {
+		t.Errorf("expected the partial's bytes to replace the field, got %q", c.{{.Name}})
+	}
+	c.{{method "ApplyPartial"}}(&{{partial $typeName}}{ {{.Name}}: {{.TypeName}}{} })
+	if c.{{.Name}} == nil || len(c.{{.Name}}) != 0 {
+		t.Errorf("expected empty bytes to replace the field, got %#v", c.{{.Name}})
+	}
+}
+{{- end}}
 {{end}}{{end}}
 {{$typeName := .Name}}{{range .Fields}}{{if .IsMap}}
 func Test{{$typeName}}ApplyPartial_{{.Name}}Map(t *testing.T) {
 	c := &{{$typeName}}{}
 	m := make({{.TypeName}})
-	p := &{{$typeName}}Partial{ {{.Name}}: m }
-	c.ApplyPartial(p)
+	p := &{{partial $typeName}}{ {{.Name}}: m }
+	c.{{method "ApplyPartial"}}(p)
 	if c.{{.Name}} == nil {
 		t.Error("expected map to be initialized")
 	}
@@ -264,8 +923,8 @@ func Test{{$typeName}}ApplyPartial_{{.Name}}Map(t *testing.T) {
 func Test{{$typeName}}ApplyPartial_{{.Name}}MapMerge(t *testing.T) {
 	c := &{{$typeName}}{ {{.Name}}: make({{.TypeName}}) }
 	m := make({{.TypeName}})
-	p := &{{$typeName}}Partial{ {{.Name}}: m }
-	c.ApplyPartial(p)
+	p := &{{partial $typeName}}{ {{.Name}}: m }
+	c.{{method "ApplyPartial"}}(p)
 	if c.{{.Name}} == nil {
 		t.Error("expected map to remain initialized")
 	}
@@ -280,8 +939,8 @@ func Test{{$typeName}}ApplyPartial_{{.Name}}MapWithValues(t *testing.T) {
 	{{- else}}
 	m := make({{.TypeName}})
 	{{- end}}
-	p := &{{$typeName}}Partial{ {{.Name}}: m }
-	c.ApplyPartial(p)
+	p := &{{partial $typeName}}{ {{.Name}}: m }
+	c.{{method "ApplyPartial"}}(p)
 	if c.{{.Name}} == nil {
 		t.Error("expected map to be initialized")
 	}
@@ -290,7 +949,7 @@ func Test{{$typeName}}ApplyPartial_{{.Name}}MapWithValues(t *testing.T) {
 	}
 }
 {{end}}{{end}}
-{{$typeName := .Name}}{{range .Fields}}{{if and .IsPointer (not .IsStruct)}}
+{{$typeName := .Name}}{{range .Fields}}{{if and .IsPointer (not .IsStruct) (not .TypePkg)}}
 func Test{{$typeName}}ApplyPartial_{{.Name}}Pointer(t *testing.T) {
 	c := &{{$typeName}}{}
 	{{- if eq .TypeName "string"}}
@@ -302,10 +961,10 @@ func Test{{$typeName}}ApplyPartial_{{.Name}}Pointer(t *testing.T) {
 	{{- else if eq .TypeName "bool"}}
 	val := true
 	{{- else}}
-	val := {{.TypeName}}{}
+	var val {{leafType .}}
 	{{- end}}
-	p := &{{$typeName}}Partial{ {{.Name}}: &val }
-	c.ApplyPartial(p)
+	p := &{{partial $typeName}}{ {{.Name}}: &val }
+	c.{{method "ApplyPartial"}}(p)
 	if c.{{.Name}} == nil {
 		t.Error("expected pointer to be set")
 	}
@@ -319,22 +978,677 @@ func Test{{$typeName}}ApplyPartial_{{.Name}}Pointer(t *testing.T) {
 {{$typeName := .Name}}{{range .Fields}}{{if and .IsPointer .IsStruct (eq .TypePkg "")}}
 func Test{{$typeName}}ApplyPartial_{{.Name}}NestedStruct(t *testing.T) {
 	c := &{{$typeName}}{}
-	p := &{{$typeName}}Partial{ {{.Name}}: &{{.TypeName}}Partial{} }
-	c.ApplyPartial(p)
+	p := &{{partial $typeName}}{ {{.Name}}: &{{partial .TypeName}}{} }
+	c.{{method "ApplyPartial"}}(p)
 	if c.{{.Name}} == nil {
 		t.Error("expected nested struct to be initialized")
 	}
 }
 
 func Test{{$typeName}}ApplyPartial_{{.Name}}NestedStructExisting(t *testing.T) {
-	c := &{{$typeName}}{ {{.Name}}: &{{.TypeName}}{} }
-	p := &{{$typeName}}Partial{ {{.Name}}: &{{.TypeName}}Partial{} }
-	c.ApplyPartial(p)
+	existing := &{{.TypeName}}{}
+	c := &{{$typeName}}{ {{.Name}}: existing }
+	p := &{{partial $typeName}}{ {{.Name}}: &{{partial .TypeName}}{} }
+	c.{{method "ApplyPartial"}}(p)
 	if c.{{.Name}} == nil {
 		t.Error("expected nested struct to remain set")
 	}
+{{- if replaces $s .}}
+	if c.{{.Name}} == existing {
+		t.Error("expected nested struct to be replaced (merge=replace)")
+	}
+{{- else}}
+	if c.{{.Name}} != existing {
+		t.Error("expected nested struct to be merged in place")
+	}
+{{- end}}
 }
 {{end}}{{end}}
 {{- end}}
 {{end}}
+{{- with index .Structs 0}}
+func Test{{.Name}}ApplySparseUnknownPath(t *testing.T) {
+	c := &{{.Name}}{}
+	if err := c.{{method "ApplySparse"}}({{.Name}}SparseEntry{Path: "DoesNotExist", Value: 1}); err == nil {
+		t.Error("expected error for unknown path")
+	}
+}
+{{$typeName := .Name}}{{range .Fields}}{{if and (eq .TypeName "string") (not .IsPointer) (not .IsSlice) (not .IsMap)}}
+func Test{{$typeName}}ApplySparse_{{.Name}}(t *testing.T) {
+	c := &{{$typeName}}{ {{.Name}}: "original" }
+	if err := c.{{method "ApplySparse"}}({{$typeName}}SparseEntry{Path: "{{.Name}}", Value: "updated"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.{{.Name}} != "updated" {
+		t.Errorf("expected {{.Name}}=updated, got %s", c.{{.Name}})
+	}
+}
+
+func Test{{$typeName}}ApplySparse_{{.Name}}WrongType(t *testing.T) {
+	c := &{{$typeName}}{ {{.Name}}: "original" }
+	if err := c.{{method "ApplySparse"}}({{$typeName}}SparseEntry{Path: "{{.Name}}", Value: 42}); err == nil {
+		t.Error("expected error for mismatched value type")
+	}
+	if c.{{.Name}} != "original" {
+		t.Errorf("expected {{.Name}} to be unchanged, got %s", c.{{.Name}})
+	}
+}
+{{- if $.Clear}}
+
+func Test{{$typeName}}ApplyPartial_{{.Name}}Clear(t *testing.T) {
+	c := &{{$typeName}}{ {{.Name}}: "original" }
+	p := &{{partial $typeName}}{}
+	if !p.ClearField("{{.Name}}") {
+		t.Fatal("expected {{.Name}} to be clearable")
+	}
+	c.{{method "ApplyPartial"}}(p)
+	if c.{{.Name}} != "" {
+		t.Errorf("expected {{.Name}} to be cleared, got %s", c.{{.Name}})
+	}
+	p.{{.Name}} = sudogenPtr("updated")
+	c.{{method "ApplyPartial"}}(p)
+	if c.{{.Name}} != "updated" {
+		t.Errorf("expected a set value to take precedence over a clear, got %s", c.{{.Name}})
+	}
+}
+{{- end}}
+{{end}}{{end}}
+{{- range .Fields}}
+{{- if and (eq .TypeName "string") (not .IsPointer) (not .IsSlice) (not .IsMap)}}
+
+func Test{{partial $typeName}}Merge_{{.Name}}(t *testing.T) {
+	base := {{partial $typeName}}{ {{.Name}}: sudogenPtr("base") }
+	override := {{partial $typeName}}{ {{.Name}}: sudogenPtr("override") }
+	if got := base.Merge(override); got.{{.Name}} == nil || *got.{{.Name}} != "override" {
+		t.Errorf("expected the override to win, got %v", got.{{.Name}})
+	}
+	if got := base.Merge({{partial $typeName}}{}); got.{{.Name}} == nil || *got.{{.Name}} != "base" {
+		t.Errorf("expected an unset field to keep the base, got %v", got.{{.Name}})
+	}
+	if got := ({{partial $typeName}}{}).Overlay(base, override, {{partial $typeName}}{}); got.{{.Name}} == nil || *got.{{.Name}} != "override" {
+		t.Errorf("expected the last layer setting {{.Name}} to win, got %v", got.{{.Name}})
+	}
+{{- if $.Clear}}
+	var cleared {{partial $typeName}}
+	cleared.ClearField("{{.Name}}")
+	if got := base.Merge(cleared); got.{{.Name}} != nil || !slices.Contains(got.Clear, "{{.Name}}") {
+		t.Errorf("expected the clear to drop the base value, got %v, %v", got.{{.Name}}, got.Clear)
+	}
+	if len(base.Clear) != 0 {
+		t.Errorf("Merge modified its receiver: %v", base.Clear)
+	}
+{{- end}}
+	if *base.{{.Name}} != "base" {
+		t.Errorf("Merge modified its receiver: %s", *base.{{.Name}})
+	}
+}
+{{- break}}
+{{- end}}
+{{- end}}
+
+func Test{{partial $typeName}}IsEmpty(t *testing.T) {
+	var p *{{partial $typeName}}
+	if !p.IsEmpty() {
+		t.Error("expected a nil partial to be empty")
+	}
+	if !(&{{partial $typeName}}{}).IsEmpty() {
+		t.Error("expected a zero partial to be empty")
+	}
+{{- range .Fields}}
+{{- if and (eq .TypeName "string") (not .IsPointer) (not .IsSlice) (not .IsMap)}}
+	if (&{{partial $typeName}}{ {{.Name}}: sudogenPtr("set")}).IsEmpty() {
+		t.Error("expected a partial setting {{.Name}} not to be empty")
+	}
+{{- break}}
+{{- end}}
+{{- end}}
+}
+{{- $root := .}}
+{{- range .Fields}}
+{{- if and (needsConversion .) (not (isExternalField .)) (not (replaces $root .))}}
+
+func Test{{partial $typeName}}Prune_{{.Name}}(t *testing.T) {
+	p := &{{partial $typeName}}{ {{.Name}}: &{{partial .TypeName}}{}}
+	if !p.IsEmpty() {
+		t.Error("expected a partial with an empty nested partial to be empty")
+	}
+	p.Prune()
+	if p.{{.Name}} != nil {
+		t.Errorf("expected the empty nested partial to be pruned, got %v", p.{{.Name}})
+	}
+}
+{{- break}}
+{{- end}}
+{{- end}}
+{{- with sparseFields $root}}
+
+func Test{{partial $typeName}}MarshalJSONSparse(t *testing.T) {
+	// Keys are looked up in the encoding, since fields without omitempty are
+	// written as null when unset.
+	fields := func(p {{partial $typeName}}) map[string]json.RawMessage {
+		t.Helper()
+		data, err := json.Marshal(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			t.Fatal(err)
+		}
+		return fields
+	}
+{{- range .}}
+{{- if or .IsSlice .IsMap}}
+	if got := string(fields({{partial $typeName}}{ {{.Name}}: {{pointerType .FieldInfo}}{}})[{{printf "%q" .Key}}]); got != {{printf "%q" (emptyJSON .)}} {
+		t.Errorf("expected {{.Name}} set to an empty value to be written, got %s", got)
+	}
+{{- else if not (isExternalField .FieldInfo)}}
+	if got, ok := fields({{partial $typeName}}{ {{.Name}}: &{{partial .TypeName}}{}})[{{printf "%q" .Key}}]; ok {
+		t.Errorf("expected the empty nested partial {{.Name}} to be left out, got %s", got)
+	}
+{{- end}}
+{{- end}}
+}
+{{- end}}
+
+func Test{{$typeName}}ToPartial(t *testing.T) {
+{{- range .Fields}}
+{{- if and (eq .TypeName "string") (not .IsPointer) (not .IsSlice) (not .IsMap)}}
+	var c {{$typeName}}
+	c.{{.Name}} = "snapshot"
+	p := c.{{method "ToPartial"}}()
+	var got {{$typeName}}
+	got.{{method "ApplyPartial"}}(&p)
+	if got.{{.Name}} != "snapshot" {
+		t.Errorf("expected the partial to restore {{.Name}}, got %q", got.{{.Name}})
+	}
+	if p := c.{{method "NonZeroPartial"}}(); p.{{.Name}} == nil || *p.{{.Name}} != "snapshot" {
+		t.Errorf("expected the non-zero partial to set {{.Name}}, got %v", p.{{.Name}})
+	}
+{{- break}}
+{{- end}}
+{{- end}}
+	if p := ({{$typeName}}{}).{{method "NonZeroPartial"}}(); !p.IsEmpty() {
+		t.Errorf("expected the non-zero partial of a zero {{$typeName}} to be empty, got %+v", p)
+	}
+}
+{{- if $.MergePatch}}
+{{- range jsonFields .}}
+{{- if and (eq .TypeName "string") (not .IsPointer) (not .IsSlice) (not .IsMap)}}
+
+func Test{{$typeName}}JSONMergePatch(t *testing.T) {
+	var c {{$typeName}}
+	if err := c.{{method "ApplyJSONMergePatch"}}([]byte("{\"{{.Key}}\":\"patched\"}")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.{{.Name}} != "patched" {
+		t.Errorf("expected the patch to set {{.Name}}, got %q", c.{{.Name}})
+	}
+	base := c
+	base.{{.Name}} = "base"
+	patch, err := c.{{method "ToJSONMergePatch"}}(base)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(patch) != "{\"{{.Key}}\":\"patched\"}" {
+		t.Errorf("expected a patch of the differing field, got %s", patch)
+	}
+	if err := base.{{method "ApplyJSONMergePatch"}}(patch); err != nil || base.{{.Name}} != "patched" {
+		t.Errorf("expected the patch to turn base into c, got %q (%v)", base.{{.Name}}, err)
+	}
+	if patch, err := c.{{method "ToJSONMergePatch"}}(c); err != nil || string(patch) != "{}" {
+		t.Errorf("expected an empty patch between equal values, got %s (%v)", patch, err)
+	}
+	if err := c.{{method "ApplyJSONMergePatch"}}([]byte("{\"{{.Key}}\":null}")); err != nil || c.{{.Name}} != "" {
+		t.Errorf("expected null to reset {{.Name}}, got %q (%v)", c.{{.Name}}, err)
+	}
+	if err := c.{{method "ApplyJSONMergePatch"}}([]byte("[]")); err == nil {
+		t.Error("expected an error for a patch that isn't an object")
+	}
+}
+{{- break}}
+{{- end}}
+{{- end}}
+{{- end}}
+{{- if $.JSONPatch}}
+{{- range jsonFields .}}
+{{- if and (eq .TypeName "string") (not .IsPointer) (not .IsSlice) (not .IsMap)}}
+
+func TestDiff{{$typeName}}AsJSONPatch(t *testing.T) {
+	var from, to {{$typeName}}
+	to.{{.Name}} = "patched"
+	ops, err := Diff{{$typeName}}AsJSONPatch(from, to)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ops) != 1 || ops[0].Op != "{{if or (omitsEmpty .) (omitsZero .)}}add{{else}}replace{{end}}" || ops[0].Path != "/{{pointerToken .Key}}" || string(ops[0].Value) != "\"patched\"" {
+		t.Errorf("expected a single operation setting {{.Name}}, got %+v", ops)
+	}
+	ops, err = Diff{{$typeName}}AsJSONPatch(to, from)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ops) != 1 || ops[0].Op != "{{if or (omitsEmpty .) (omitsZero .)}}remove{{else}}replace{{end}}" || ops[0].Path != "/{{pointerToken .Key}}" {
+		t.Errorf("expected a single operation resetting {{.Name}}, got %+v", ops)
+	}
+	if ops, err := Diff{{$typeName}}AsJSONPatch(to, to); err != nil || len(ops) != 0 {
+		t.Errorf("expected no operations between equal values, got %+v (%v)", ops, err)
+	}
+}
+{{- break}}
+{{- end}}
+{{- end}}
+{{- end}}
+{{- end}}
+{{- if .Clear}}
+{{- range .Structs}}
+{{- $partial := partialType .}}
+{{- if not (isExternal .)}}
+{{- with jsonFields .}}
+{{- with index . 0}}
+
+func Test{{$partial}}JSONNull_{{.Name}}(t *testing.T) {
+	var p {{$partial}}
+	if err := json.Unmarshal([]byte("{\"{{.Key}}\":null}"), &p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Contains(p.Clear, "{{.Name}}") {
+		t.Fatalf("expected null to clear {{.Name}}, got %v", p.Clear)
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("unexpected marshal error: %v", err)
+	}
+	if string(data) != "{\"{{.Key}}\":null}" {
+		t.Errorf("expected the clear to be written as null, got %s", data)
+	}
+	if data, err := json.Marshal({{$partial}}{}); err != nil || string(data) != "{}" {
+		t.Errorf("expected unset fields to be left out, got %s (%v)", data, err)
+	}
+}
+{{- end}}
+{{- end}}
+{{- end}}
+{{- end}}
+{{- end}}
+{{- range .Structs}}
+{{- $partial := partialType .}}
+{{- with durationFields .}}
+{{- with index . 0}}
+
+func Test{{$partial}}JSONDuration_{{.Name}}(t *testing.T) {
+	var p {{$partial}}
+	if err := json.Unmarshal([]byte("{\"{{.Key}}\":\"1h30m\"}"), &p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.{{.Name}} == nil || *p.{{.Name}} != 90*time.Minute {
+		t.Fatalf("expected {{.Name}}=1h30m, got %v", p.{{.Name}})
+	}
+	if err := json.Unmarshal([]byte("{\"{{.Key}}\":1000000000}"), &p); err != nil {
+		t.Fatalf("unexpected error for nanoseconds: %v", err)
+	}
+	if *p.{{.Name}} != time.Second {
+		t.Errorf("expected {{.Name}}=1s, got %v", *p.{{.Name}})
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("unexpected marshal error: %v", err)
+	}
+	var roundTrip {{$partial}}
+	if err := json.Unmarshal(data, &roundTrip); err != nil {
+		t.Fatalf("unexpected error decoding %s: %v", data, err)
+	}
+	if roundTrip.{{.Name}} == nil || *roundTrip.{{.Name}} != time.Second {
+		t.Errorf("expected round-tripped {{.Name}}=1s, got %s", data)
+	}
+	if err := json.Unmarshal([]byte("{\"{{.Key}}\":\"soon\"}"), &p); err == nil {
+		t.Error("expected error for invalid duration")
+	}
+}
+{{- end}}
+{{- end}}
+{{- with urlFields .}}
+{{- with index . 0}}
+
+func Test{{$partial}}JSONURL_{{.Name}}(t *testing.T) {
+	var p {{$partial}}
+	if err := json.Unmarshal([]byte("{\"{{.Key}}\":\"https://example.com/a?b=c\"}"), &p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.{{.Name}} == nil || p.{{.Name}}.Host != "example.com" || p.{{.Name}}.RawQuery != "b=c" {
+		t.Fatalf("expected {{.Name}}=https://example.com/a?b=c, got %v", p.{{.Name}})
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("unexpected marshal error: %v", err)
+	}
+	var roundTrip {{$partial}}
+	if err := json.Unmarshal(data, &roundTrip); err != nil {
+		t.Fatalf("unexpected error decoding %s: %v", data, err)
+	}
+	if roundTrip.{{.Name}} == nil || roundTrip.{{.Name}}.String() != "https://example.com/a?b=c" {
+		t.Errorf("expected round-tripped {{.Name}}=https://example.com/a?b=c, got %s", data)
+	}
+	if err := json.Unmarshal([]byte("{\"{{.Key}}\":\"://\"}"), &p); err == nil {
+		t.Error("expected error for invalid URL")
+	}
+}
+{{- end}}
+{{- end}}
+{{- end}}
+{{- range .Deprecations}}
+
+func Test{{partial $.Root}}Deprecations_{{.Path | underscores}}(t *testing.T) {
+	p := &{{partial $.Root}}{}
+	if d := p.Deprecations(); len(d) != 0 {
+		t.Fatalf("expected no deprecations for an empty partial, got %v", d)
+	}
+{{- range .Steps "p"}}
+	{{.Expr}} = &{{.Type}}{}
+{{- end}}
+	{{.Field "p"}} = {{.New}}
+	d := p.Deprecations()
+	if len(d) != 1 || d[0].Path != {{printf "%q" .Path}} || d[0].Message != {{printf "%q" .Message}} {
+		t.Errorf("expected the deprecation of {{.Path}}, got %v", d)
+	}
+{{- if .Replacement}}
+	migrated := p.MigrateDeprecated()
+	if {{.Replacement.Unset "migrated"}} {
+		t.Fatal("expected MigrateDeprecated to set {{.Replacement.Path}}")
+	}
+	if {{.Replacement.Set "p"}} {
+		t.Error("expected MigrateDeprecated to leave p unchanged")
+	}
+{{- end}}
+}
+{{- end}}
+`
+
+const mergeBenchTemplate = `// Code generated by sudo-gen merge. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// {{lower .Root}}MergeBenchPartial returns a {{partial .Root}} setting every field, built by
+// decoding a populated {{.Root}}, for benchmarks.
+func {{lower .Root}}MergeBenchPartial(b *testing.B) *{{partial .Root}} {
+	data, err := json.Marshal(&{{.Sample}})
+	if err != nil {
+		b.Fatal(err)
+	}
+	var p {{partial .Root}}
+	if err := json.Unmarshal(data, &p); err != nil {
+		b.Fatal(err)
+	}
+	return &p
+}
+
+func Benchmark{{.Root}}ApplyPartial(b *testing.B) {
+	p := {{lower .Root}}MergeBenchPartial(b)
+	var c {{.Root}}
+	b.ReportAllocs()
+	for b.Loop() {
+		c.{{method "ApplyPartial"}}(p)
+	}
+}
+`
+
+const mergeExampleTemplate = `// Code generated by sudo-gen merge. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"fmt"
+)
+
+func Example{{.Root}}_{{method "ApplyPartial"}}() {
+	c := &{{.Root}}{ {{.Field}}: "default"}
+	override := "override"
+	c.{{method "ApplyPartial"}}(&{{partial .Root}}{ {{.Field}}: &override})
+	fmt.Println(c.{{.Field}})
+	// Output: override
+}
+`
+
+const mergePatchTemplate = `// Code generated by sudo-gen merge. DO NOT EDIT.
+
+package {{.Package}}
+
+{{if .Imports}}
+import (
+{{- range .Imports}}
+	{{if .Alias}}{{.Alias}} {{end}}"{{.Path}}"
+{{- end}}
+)
+{{end}}
+
+{{range .Structs}}
+{{- if not (isExternal .)}}
+{{- $s := .}}
+{{- if eq .Name $.Root}}
+// {{method "ApplyJSONMergePatch"}} applies a JSON Merge Patch (RFC 7386) to c, as sent to an
+// HTTP PATCH endpoint: members of the patch set fields, null resets a field to its
+// zero value or deletes a map entry, and objects patch nested structs and maps. The
+// patch is decoded into a {{partialType .}} and applied with {{method "ApplyPartial"}}, so arrays
+// replace slices as a whole and nested structs follow their merge mode. c is left
+// unchanged if the patch isn't a JSON object or doesn't decode.
+func (c *{{.Name}}) {{method "ApplyJSONMergePatch"}}(patch []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &fields); err != nil {
+		return fmt.Errorf("merge patch must be a JSON object: %w", err)
+	}
+	if fields == nil {
+		return errors.New("merge patch must be a JSON object, not null")
+	}
+	var p {{partialType .}}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return err
+	}
+	c.{{method "ApplyPartial"}}(&p)
+	return c.applyMergePatchNulls(fields)
+}
+
+// {{method "ToJSONMergePatch"}} returns the JSON Merge Patch (RFC 7386) that turns base into c
+// when applied with {{method "ApplyJSONMergePatch"}}: the fields of c that differ from base, null
+// for those c resets and the map entries it deletes. Nested structs merged field by
+// field only hold their differing fields. The patch is {} if c equals base.
+func (c {{.Name}}) {{method "ToJSONMergePatch"}}(base {{.Name}}) ([]byte, error) {
+	return json.Marshal(c.mergePatch(base))
+}
+{{- end}}
+
+// applyMergePatchNulls resets the fields of c that are null in the members of a
+// merge patch, and deletes the null entries of the maps and nested structs it patches.
+func (c *{{.Name}}) applyMergePatchNulls(fields map[string]json.RawMessage) error {
+{{- range jsonFields .}}
+	if raw := fields["{{.Key}}"]; string(raw) == "null" {
+{{- if or .IsPointer .IsSlice .IsMap}}
+		c.{{.Name}} = nil
+{{- else}}
+		var zero {{.Type}}
+		c.{{.Name}} = zero
+{{- end}}
+	}
+{{- if .IsMap}} else if raw != nil {
+		var entries map[{{.MapKeyType}}]json.RawMessage
+		if err := json.Unmarshal(raw, &entries); err != nil {
+			return fmt.Errorf("{{.Key}}: %w", err)
+		}
+		for k, v := range entries {
+			if string(v) == "null" {
+				delete(c.{{.Name}}, k)
+			}
+		}
+	}
+{{- else if and (needsConversion .FieldInfo) (not (isExternalField .FieldInfo))}} else if raw != nil {{- if .IsPointer}} && c.{{.Name}} != nil{{end}} {
+		var nested map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &nested); err != nil {
+			return fmt.Errorf("{{.Key}}: %w", err)
+		}
+		if err := c.{{.Name}}.applyMergePatchNulls(nested); err != nil {
+			return fmt.Errorf("{{.Key}}.%w", err)
+		}
+	}
+{{- end}}
+{{- end}}
+	return nil
+}
+
+// mergePatch returns the members of the merge patch turning base into c.
+func (c {{.Name}}) mergePatch(base {{.Name}}) map[string]any {
+	patch := make(map[string]any)
+{{- range jsonFields .}}
+{{- if and (needsConversion .FieldInfo) (not (isExternalField .FieldInfo)) (not (replaces $s .FieldInfo))}}
+{{- if .IsPointer}}
+	switch {
+	case c.{{.Name}} == nil:
+		if base.{{.Name}} != nil {
+			patch["{{.Key}}"] = nil
+		}
+	case base.{{.Name}} == nil:
+		patch["{{.Key}}"] = c.{{.Name}}
+	default:
+		if nested := c.{{.Name}}.mergePatch(*base.{{.Name}}); len(nested) > 0 {
+			patch["{{.Key}}"] = nested
+		}
+	}
+{{- else}}
+	if nested := c.{{.Name}}.mergePatch(base.{{.Name}}); len(nested) > 0 {
+		patch["{{.Key}}"] = nested
+	}
+{{- end}}
+{{- else if .IsMap}}
+	if c.{{.Name}} == nil {
+		if base.{{.Name}} != nil {
+			patch["{{.Key}}"] = nil
+		}
+	} else {
+		entries := make(map[{{.MapKeyType}}]any)
+		for k, v := range c.{{.Name}} {
+			if bv, ok := base.{{.Name}}[k]; !ok || !reflect.DeepEqual(v, bv) {
+				entries[k] = v
+			}
+		}
+		for k := range base.{{.Name}} {
+			if _, ok := c.{{.Name}}[k]; !ok {
+				entries[k] = nil
+			}
+		}
+		if len(entries) > 0 || base.{{.Name}} == nil {
+			patch["{{.Key}}"] = entries
+		}
+	}
+{{- else}}
+	if !reflect.DeepEqual(c.{{.Name}}, base.{{.Name}}) {
+		patch["{{.Key}}"] = c.{{.Name}}
+	}
+{{- end}}
+{{- end}}
+	return patch
+}
+{{- end}}
+{{end}}
+`
+
+const jsonPatchTemplate = `// Code generated by sudo-gen merge. DO NOT EDIT.
+
+package {{.Package}}
+
+// {{.Root}}PatchOperation is an operation of a JSON Patch (RFC 6902) on the JSON
+// encoding of a {{.Root}}.
+type {{.Root}}PatchOperation struct {
+	Op    string          ` + "`" + `json:"op"` + "`" + `              // "add", "remove" or "replace"
+	Path  string          ` + "`" + `json:"path"` + "`" + `            // JSON Pointer (RFC 6901) to a member, e.g. "/database/host"
+	Value json.RawMessage ` + "`" + `json:"value,omitempty"` + "`" + ` // Encoded value of the member; empty for "remove"
+}
+
+// Diff{{.Root}}AsJSONPatch returns the JSON Patch (RFC 6902) turning the JSON encoding of
+// from into that of to, so config changes can be transported or audited in a standard
+// format. Nested structs and string-keyed maps are patched member by member, and
+// other values, including slices, are replaced as a whole. Members omitted from the
+// encoding by omitempty are added and removed rather than replaced. Operations are
+// ordered by field, and map entries by key.
+func Diff{{.Root}}AsJSONPatch(from, to {{.Root}}) ([]{{.Root}}PatchOperation, error) {
+	return to.jsonPatch(from, "", nil)
+}
+
+// {{lower .Root}}PointerEscaper escapes map keys as JSON Pointer reference tokens.
+var {{lower .Root}}PointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// {{lower .Root}}PatchMember appends the operation turning the member at path from the
+// value from into to, given whether either is omitted from the encoding.
+func {{lower .Root}}PatchMember(ops []{{.Root}}PatchOperation, path string, from, to any, fromOmitted, toOmitted bool) ([]{{.Root}}PatchOperation, error) {
+	switch {
+	case fromOmitted && toOmitted, reflect.DeepEqual(from, to):
+		return ops, nil
+	case toOmitted:
+		return append(ops, {{.Root}}PatchOperation{Op: "remove", Path: path}), nil
+	}
+	value, err := json.Marshal(to)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	op := "replace"
+	if fromOmitted {
+		op = "add"
+	}
+	return append(ops, {{.Root}}PatchOperation{Op: op, Path: path, Value: value}), nil
+}
+{{range .Structs}}
+{{- if not (isExternal .)}}
+
+// jsonPatch appends the operations turning from into c to ops, at the JSON Pointer
+// prefix of c.
+func (c {{.Name}}) jsonPatch(from {{.Name}}, prefix string, ops []{{$.Root}}PatchOperation) ([]{{$.Root}}PatchOperation, error) {
+{{- if jsonFields .}}
+	var err error
+{{- end}}
+{{- range jsonFields .}}
+{{- $path := printf "prefix + %q" (printf "/%s" (pointerToken .Key))}}
+{{- if and (needsConversion .FieldInfo) (not (isExternalField .FieldInfo))}}
+{{- if .IsPointer}}
+	if c.{{.Name}} != nil && from.{{.Name}} != nil {
+		ops, err = c.{{.Name}}.jsonPatch(*from.{{.Name}}, {{$path}}, ops)
+	} else {
+		ops, err = {{lower $.Root}}PatchMember(ops, {{$path}}, from.{{.Name}}, c.{{.Name}}, {{jsonOmitted "from" .}}, {{jsonOmitted "c" .}})
+	}
+{{- else}}
+	ops, err = c.{{.Name}}.jsonPatch(from.{{.Name}}, {{$path}}, ops)
+{{- end}}
+{{- else if and .IsMap (eq .MapKeyType "string")}}
+{{- $entry := printf "prefix + %q" (printf "/%s/" (pointerToken .Key))}}
+{{- if omitsEmpty .}}
+	if len(c.{{.Name}}) > 0 && len(from.{{.Name}}) > 0 {
+{{- else}}
+	if c.{{.Name}} != nil && from.{{.Name}} != nil {
+{{- end}}
+		for _, k := range slices.Sorted(maps.Keys(from.{{.Name}})) {
+			if _, ok := c.{{.Name}}[k]; !ok {
+				ops = append(ops, {{$.Root}}PatchOperation{Op: "remove", Path: {{$entry}} + {{lower $.Root}}PointerEscaper.Replace(k)})
+			}
+		}
+		for _, k := range slices.Sorted(maps.Keys(c.{{.Name}})) {
+			v, ok := from.{{.Name}}[k]
+			if ops, err = {{lower $.Root}}PatchMember(ops, {{$entry}}+{{lower $.Root}}PointerEscaper.Replace(k), v, c.{{.Name}}[k], !ok, false); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		ops, err = {{lower $.Root}}PatchMember(ops, {{$path}}, from.{{.Name}}, c.{{.Name}}, {{jsonOmitted "from" .}}, {{jsonOmitted "c" .}})
+	}
+{{- else}}
+	ops, err = {{lower $.Root}}PatchMember(ops, {{$path}}, from.{{.Name}}, c.{{.Name}}, {{jsonOmitted "from" .}}, {{jsonOmitted "c" .}})
+{{- end}}
+	if err != nil {
+		return nil, err
+	}
+{{- end}}
+	return ops, nil
+}
+{{- end}}
+{{- end}}
 `
@@ -0,0 +1,193 @@
+// Package options implements the options code generation subtool.
+package options
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/bobcob7/sudo-gen/internal/codegen"
+)
+
+// Subtool implements the functional options code generator.
+type Subtool struct{}
+
+// Name returns the subtool name.
+func (s *Subtool) Name() string { return "options" }
+
+// Description returns the subtool description.
+func (s *Subtool) Description() string {
+	return "Generate a New{Type}(opts...) constructor with a With{Field} option per field"
+}
+
+// Doc describes the struct tags the subtool honors and the files it generates.
+func (s *Subtool) Doc() codegen.SubtoolDoc {
+	return codegen.SubtoolDoc{
+		Tags: []codegen.DocEntry{
+			codegen.ExcludeTagDoc(s.Name()),
+		},
+		Files: []codegen.DocEntry{
+			{Name: "{source}_options.go", Description: "{Type}Option, New{Type} and a With{Field} option per field"},
+		},
+	}
+}
+
+// DefineFlags defines the flags of the options subcommand.
+func (s *Subtool) DefineFlags(fs *flag.FlagSet, cfg *codegen.GeneratorConfig) {
+	fs.StringVar(&cfg.ValidateMethod, "validate", "", "Method of the type returning an error that New{Type} calls last (default: Validate, if the type declares it)")
+}
+
+// Run executes the options code generation. When the type declares defaults, the
+// constructor starts from the SetDefaults method generated by the defaults subtool.
+func (s *Subtool) Run(cfg codegen.GeneratorConfig) error {
+	info, err := cfg.Index.ParseStruct(cfg.SourceDir, cfg.SourceFile, cfg.Source, cfg.TypeName)
+	if err != nil {
+		return fmt.Errorf("parsing struct: %w", err)
+	}
+	selection := codegen.NewFieldSelection(cfg, s.Name())
+	selection.Apply(info)
+	nested, err := cfg.Index.FindNestedStructs(cfg.SourceDir, cfg.Source, info, codegen.NewValueTypes(cfg.Index, cfg.SourceDir, cfg.ValueTypes))
+	if err != nil {
+		return fmt.Errorf("finding nested structs: %w", err)
+	}
+	data := templateData{
+		Package:     cfg.OutputPkg,
+		TypeName:    info.Name,
+		TestName:    capitalize(info.Name),
+		Option:      info.Name + "Option",
+		Constructor: ident("new", info.Name, ""),
+		Defaults:    declaresDefaults(append([]*codegen.StructInfo{info}, nested...)),
+		Validate:    cfg.ValidateMethod,
+	}
+	if data.Defaults {
+		data.DefaultConstructor = ident("default", info.Name, "")
+	}
+	if data.Validate == "" {
+		if _, ok := cfg.Index.Methods(cfg.SourceDir, info.Name)["Validate"]; ok {
+			data.Validate = "Validate"
+		}
+	}
+	if len(info.Fields) == 0 {
+		return fmt.Errorf("%s has no fields to generate options for", info.Name)
+	}
+	imports := make(map[string]codegen.ImportInfo)
+	for _, f := range info.Fields {
+		for _, pkg := range packagesOf(f.TypeExpr) {
+			addImport(imports, info.Imports, pkg)
+		}
+		data.Fields = append(data.Fields, fieldData{FieldInfo: f, Option: optionName(info.Name, f.Name)})
+	}
+	for _, imp := range imports {
+		data.Imports = append(data.Imports, imp)
+	}
+	sort.Slice(data.Imports, func(i, j int) bool { return data.Imports[i].Path < data.Imports[j].Path })
+	if cfg.GenerateTest {
+		sample, err := codegen.SampleLiteral(cfg)
+		if err != nil {
+			return err
+		}
+		data.Sample = sample
+	}
+	baseName := strings.TrimSuffix(cfg.SourceFile, ".go")
+	gen := codegen.NewTemplateGenerator(cfg, templateFuncs())
+	if err := gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_options.go"), optionsTemplate, data); err != nil {
+		return err
+	}
+	if cfg.GenerateTest {
+		return gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_options_test.go"), optionsTestTemplate, data)
+	}
+	return nil
+}
+
+type templateData struct {
+	Package            string
+	TypeName           string
+	TestName           string // Exported form of TypeName for test function names
+	Option             string // Name of the option type, ConfigOption
+	Constructor        string // Name of the constructor, NewConfig
+	Defaults           bool   // The type declares defaults, applied with SetDefaults first
+	DefaultConstructor string // Constructor generated by the defaults subtool, for tests
+	Validate           string // Method validating the built value last, if any
+	Sample             string // Literal of a populated TypeName, for tests
+	Fields             []fieldData
+	Imports            []codegen.ImportInfo
+}
+
+type fieldData struct {
+	codegen.FieldInfo
+	Option string // Name of the option setting the field, WithPort
+}
+
+// declaresDefaults reports whether any of the local structs declares a default, in
+// which case the defaults subtool generates SetDefaults on the root type.
+func declaresDefaults(structs []*codegen.StructInfo) bool {
+	for _, st := range structs {
+		if st.Package != "" {
+			continue
+		}
+		for _, f := range st.Fields {
+			if f.Default != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// packagesOf returns the names of the packages referenced by a type expression.
+func packagesOf(expr ast.Expr) []string {
+	var pkgs []string
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok {
+				pkgs = append(pkgs, id.Name)
+			}
+			return false
+		}
+		return true
+	})
+	return pkgs
+}
+
+func addImport(imports map[string]codegen.ImportInfo, available []codegen.ImportInfo, pkg string) {
+	for _, imp := range available {
+		name := imp.Alias
+		if name == "" {
+			name = filepath.Base(imp.Path)
+		}
+		if name == pkg {
+			imports[imp.Path] = imp
+			return
+		}
+	}
+}
+
+// optionName returns the name of the option setting field, WithPort, unexported
+// (withPort) when the type is.
+func optionName(typeName, field string) string {
+	if ast.IsExported(typeName) {
+		return "With" + field
+	}
+	return "with" + field
+}
+
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{}
+}
+
+func capitalize(name string) string {
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// ident builds a generated function name such as NewConfig, keeping it unexported
+// (newConfig) when the type is unexported.
+func ident(verb, typeName, suffix string) string {
+	if ast.IsExported(typeName) {
+		return capitalize(verb) + typeName + suffix
+	}
+	return verb + capitalize(typeName) + suffix
+}
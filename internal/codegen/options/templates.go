@@ -0,0 +1,86 @@
+package options
+
+const optionsTemplate = `// Code generated by sudo-gen options. DO NOT EDIT.
+
+package {{.Package}}
+{{if .Imports}}
+import (
+{{- range .Imports}}
+	{{if .Alias}}{{.Alias}} {{end}}"{{.Path}}"
+{{- end}}
+)
+{{end}}
+// {{.Option}} sets a field of the {{.TypeName}} built by {{.Constructor}}.
+type {{.Option}} func(*{{.TypeName}})
+
+// {{.Constructor}} returns a {{.TypeName}} built from opts, applied in order{{if .Defaults}} over its
+// declared defaults{{end}}.
+{{- if .Validate}}
+// {{.Validate}} checks the result last, and its error is returned.{{end}}
+func {{.Constructor}}(opts ...{{.Option}}) (*{{.TypeName}}, error) {
+	c := &{{.TypeName}}{}
+{{- if .Defaults}}
+	c.{{method "SetDefaults"}}()
+{{- end}}
+	for _, opt := range opts {
+		opt(c)
+	}
+{{- if .Validate}}
+	if err := c.{{.Validate}}(); err != nil {
+		return nil, err
+	}
+{{- end}}
+	return c, nil
+}
+{{range .Fields}}
+// {{.Option}} sets {{.Name}}.
+func {{.Option}}(v {{.Type}}) {{$.Option}} {
+	return func(c *{{$.TypeName}}) {
+		c.{{.Name}} = v
+	}
+}
+{{end}}
+`
+
+const optionsTestTemplate = `// Code generated by sudo-gen options. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test{{.TestName}}Options(t *testing.T) {
+	want := {{.Sample}}
+	var got {{.TypeName}}
+	for _, opt := range []{{.Option}}{
+{{- range .Fields}}
+		{{.Option}}(want.{{.Name}}),
+{{- end}}
+	} {
+		opt(&got)
+	}
+{{- range .Fields}}
+	if !reflect.DeepEqual(got.{{.Name}}, want.{{.Name}}) {
+		t.Errorf("{{.Option}}: {{.Name}} = %v, want %v", got.{{.Name}}, want.{{.Name}})
+	}
+{{- end}}
+}
+{{- if .Defaults}}
+
+func Test{{.TestName}}OptionsDefaults(t *testing.T) {
+	c, err := {{.Constructor}}()
+	if err != nil {
+{{- if .Validate}}
+		t.Skipf("{{.Validate}} rejects the defaults: %v", err)
+{{- else}}
+		t.Fatal(err)
+{{- end}}
+	}
+	if !reflect.DeepEqual(c, {{.DefaultConstructor}}()) {
+		t.Errorf("{{.Constructor}}() = %+v, want the defaults", c)
+	}
+}
+{{- end}}
+`
@@ -0,0 +1,243 @@
+package codegen
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// TemplateName returns the name of the file in a -templates directory overriding
+// the template of outputFile: its base name without the source file's, plus .tmpl.
+// The template of config_copy.go, generated from config.go, is copy.go.tmpl.
+func TemplateName(sourceFile, outputFile string) string {
+	name := filepath.Base(outputFile)
+	name = strings.TrimPrefix(name, strings.TrimSuffix(filepath.Base(sourceFile), ".go")+"_")
+	return name + ".tmpl"
+}
+
+// parse parses the embedded template of outputFile and, if g.TemplatesDir has one,
+// its override on top. An override replaces the templates it defines with
+// {{define}}, so it can change a part of the embedded template; if it has a body
+// besides, the body replaces the embedded one. The override is checked against
+// the type of data before it is used.
+func (g *TemplateGenerator) parse(outputFile, tmplText string, data any) (*template.Template, error) {
+	tmpl, err := template.New("gen").Funcs(g.funcs()).Parse(tmplText)
+	if err != nil {
+		return nil, fmt.Errorf("parsing template: %w", err)
+	}
+	if g.TemplatesDir == "" {
+		return tmpl, nil
+	}
+	name := TemplateName(g.SourceFile, outputFile)
+	text, err := os.ReadFile(filepath.Join(g.TemplatesDir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return tmpl, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading template override: %w", err)
+	}
+	override, err := tmpl.New(name).Parse(string(text))
+	if err != nil {
+		return nil, fmt.Errorf("parsing template override: %w", err)
+	}
+	if override.Tree != nil && !parse.IsEmptyTree(override.Tree.Root) {
+		tmpl = override
+	}
+	v := &templateValidator{set: tmpl, funcs: g.funcs(), file: name, seen: make(map[string]bool)}
+	v.template(tmpl.Name(), reflect.TypeOf(data))
+	if len(v.errs) > 0 {
+		return nil, fmt.Errorf("template override %s doesn't match the data of %s:\n\t%s", filepath.Join(g.TemplatesDir, name), filepath.Base(outputFile), strings.Join(v.errs, "\n\t"))
+	}
+	return tmpl, nil
+}
+
+// templateValidator checks the fields a template refers to against the type of
+// its data, in every branch rather than only those an execution takes. It follows
+// the type of dot through with, range and template calls, and stops checking where
+// the type can't be known, such as in variables other than $ and results of
+// functions returning interfaces. Only nodes from the override file are reported.
+type templateValidator struct {
+	set   *template.Template
+	funcs template.FuncMap
+	file  string
+	seen  map[string]bool // Templates checked, by name and type of dot
+	errs  []string
+	tree  *parse.Tree  // Template being checked
+	root  reflect.Type // Type of $ in the template being checked
+}
+
+// template checks the named template with dot of type dot.
+func (v *templateValidator) template(name string, dot reflect.Type) {
+	key := name + "\x00" + fmt.Sprint(dot)
+	t := v.set.Lookup(name)
+	if t == nil || t.Tree == nil || v.seen[key] {
+		return
+	}
+	v.seen[key] = true
+	outerTree, outerRoot := v.tree, v.root
+	v.tree, v.root = t.Tree, dot
+	v.list(t.Tree.Root, dot)
+	v.tree, v.root = outerTree, outerRoot
+}
+
+func (v *templateValidator) list(list *parse.ListNode, dot reflect.Type) {
+	if list == nil {
+		return
+	}
+	for _, node := range list.Nodes {
+		v.node(node, dot)
+	}
+}
+
+func (v *templateValidator) node(node parse.Node, dot reflect.Type) {
+	switch n := node.(type) {
+	case *parse.ActionNode:
+		v.pipe(n.Pipe, dot)
+	case *parse.IfNode:
+		v.pipe(n.Pipe, dot)
+		v.list(n.List, dot)
+		v.list(n.ElseList, dot)
+	case *parse.WithNode:
+		v.list(n.List, v.pipe(n.Pipe, dot))
+		v.list(n.ElseList, dot)
+	case *parse.RangeNode:
+		v.list(n.List, elemType(v.pipe(n.Pipe, dot)))
+		v.list(n.ElseList, dot)
+	case *parse.TemplateNode:
+		var arg reflect.Type
+		if n.Pipe != nil {
+			arg = v.pipe(n.Pipe, dot)
+		}
+		v.template(n.Name, arg)
+	case *parse.ListNode:
+		v.list(n, dot)
+	}
+}
+
+// pipe checks the commands of a pipeline and returns the type of its result, or
+// nil if it isn't known.
+func (v *templateValidator) pipe(pipe *parse.PipeNode, dot reflect.Type) reflect.Type {
+	if pipe == nil {
+		return nil
+	}
+	var result reflect.Type
+	for _, cmd := range pipe.Cmds {
+		result = v.command(cmd, dot)
+	}
+	return result
+}
+
+func (v *templateValidator) command(cmd *parse.CommandNode, dot reflect.Type) reflect.Type {
+	for _, arg := range cmd.Args[1:] {
+		v.operand(arg, dot)
+	}
+	if ident, ok := cmd.Args[0].(*parse.IdentifierNode); ok {
+		if fn, ok := v.funcs[ident.Ident]; ok {
+			if t := reflect.TypeOf(fn); t.NumOut() > 0 {
+				return t.Out(0)
+			}
+		}
+		return nil
+	}
+	return v.operand(cmd.Args[0], dot)
+}
+
+// operand checks an operand and returns its type, or nil if it isn't known.
+func (v *templateValidator) operand(node parse.Node, dot reflect.Type) reflect.Type {
+	switch n := node.(type) {
+	case *parse.DotNode:
+		return dot
+	case *parse.FieldNode:
+		return v.fields(n, dot, n.Ident)
+	case *parse.VariableNode:
+		if n.Ident[0] != "$" {
+			return nil
+		}
+		return v.fields(n, v.root, n.Ident[1:])
+	case *parse.PipeNode:
+		return v.pipe(n, dot)
+	case *parse.ChainNode:
+		v.operand(n.Node, dot)
+	}
+	return nil
+}
+
+// fields returns the type of the chain of fields idents of a value of type t,
+// reporting those t doesn't have.
+func (v *templateValidator) fields(node parse.Node, t reflect.Type, idents []string) reflect.Type {
+	for _, ident := range idents {
+		if t == nil {
+			return nil
+		}
+		if m, ok := t.MethodByName(ident); ok {
+			t = resultType(m.Type)
+			continue
+		}
+		if t.Kind() == reflect.Pointer {
+			if m, ok := t.Elem().MethodByName(ident); ok {
+				t = resultType(m.Type)
+				continue
+			}
+			t = t.Elem()
+		} else if m, ok := reflect.PointerTo(t).MethodByName(ident); ok {
+			t = resultType(m.Type)
+			continue
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			f, ok := t.FieldByName(ident)
+			if !ok || !f.IsExported() {
+				v.report(node, fmt.Sprintf("can't evaluate field %s in type %s", ident, t))
+				return nil
+			}
+			t = f.Type
+		case reflect.Map:
+			t = t.Elem()
+		default:
+			return nil
+		}
+	}
+	return t
+}
+
+// report records an error at node if it is part of the override file.
+func (v *templateValidator) report(node parse.Node, msg string) {
+	if v.tree.ParseName != v.file {
+		return
+	}
+	location, _ := v.tree.ErrorContext(node)
+	v.errs = append(v.errs, location+": "+msg)
+}
+
+// resultType returns the type of the first result of a method, or nil if it has
+// none or returns an interface.
+func resultType(method reflect.Type) reflect.Type {
+	if method.NumOut() == 0 || method.Out(0).Kind() == reflect.Interface {
+		return nil
+	}
+	return method.Out(0)
+}
+
+// elemType returns the type of the elements range visits in a value of type t, or
+// nil if it isn't known.
+func elemType(t reflect.Type) reflect.Type {
+	if t == nil {
+		return nil
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Chan:
+		return t.Elem()
+	case reflect.Int:
+		return t
+	}
+	return nil
+}
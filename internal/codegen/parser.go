@@ -1,6 +1,7 @@
 package codegen
 
 import (
+	"errors"
 	"fmt"
 	"go/ast"
 	"go/parser"
@@ -8,28 +9,60 @@ import (
 	"go/types"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 )
 
 // ParseStruct parses a Go source file and extracts struct information.
-func ParseStruct(dir, filename, typeName string) (*StructInfo, error) {
-	fset := token.NewFileSet()
-	fullPath := filepath.Join(dir, filename)
-	f, err := parser.ParseFile(fset, fullPath, nil, parser.ParseComments)
+// If src is non-nil it is parsed in place of the file contents.
+func (x *PackageIndex) ParseStruct(dir, filename string, src []byte, typeName string) (*StructInfo, error) {
+	return x.parseStruct(dir, filename, src, typeName, false)
+}
+
+// ParseStructUnexported is like ParseStruct, but unexported fields are included too,
+// as are those of the local structs FindNestedStructs finds from the result. It is
+// only valid for code generated into the package declaring the struct.
+func (x *PackageIndex) ParseStructUnexported(dir, filename string, src []byte, typeName string) (*StructInfo, error) {
+	return x.parseStruct(dir, filename, src, typeName, true)
+}
+
+func (x *PackageIndex) parseStruct(dir, filename string, src []byte, typeName string, unexported bool) (*StructInfo, error) {
+	f, fset, err := x.File(filepath.Join(dir, filename), src)
 	if err != nil {
 		return nil, fmt.Errorf("parsing file: %w", err)
 	}
 	imports := collectImports(f)
-	targetStruct, targetName, err := findStructType(f, typeName)
+	spec, err := findStructType(f, typeName)
 	if err != nil {
 		return nil, err
 	}
-	fields := parseStructFields(targetStruct, imports)
-	return &StructInfo{
-		Name:    targetName,
-		Fields:  fields,
-		Imports: imports,
-	}, nil
+	info := &StructInfo{
+		Name:       spec.Name.Name,
+		Imports:    imports,
+		Unexported: unexported,
+		TypeParams: TypeParamsOf(spec.TypeParams),
+		Pos:        fset.Position(spec.Pos()).String(),
+	}
+	info.Fields, info.Unsupported = parseStructFields(fset, f.Comments, info.Name, spec.Type.(*ast.StructType), info.TypeParams, imports, unexported)
+	return info, nil
+}
+
+// ParsePackageName returns the package name declared in src.
+func ParsePackageName(src []byte) (string, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, parser.PackageClauseOnly)
+	if err != nil {
+		return "", fmt.Errorf("parsing package clause: %w", err)
+	}
+	return f.Name.Name, nil
+}
+
+// parseSourceFile parses src if non-nil, otherwise the file at path.
+func parseSourceFile(fset *token.FileSet, path string, src []byte) (*ast.File, error) {
+	if src != nil {
+		return parser.ParseFile(fset, path, src, parser.ParseComments)
+	}
+	return parser.ParseFile(fset, path, nil, parser.ParseComments)
 }
 
 func collectImports(f *ast.File) []ImportInfo {
@@ -45,7 +78,8 @@ func collectImports(f *ast.File) []ImportInfo {
 	return imports
 }
 
-func findStructType(f *ast.File, typeName string) (*ast.StructType, string, error) {
+// findStructType returns the declaration of the struct type typeName in f.
+func findStructType(f *ast.File, typeName string) (*ast.TypeSpec, error) {
 	for _, decl := range f.Decls {
 		genDecl, ok := decl.(*ast.GenDecl)
 		if !ok || genDecl.Tok != token.TYPE {
@@ -56,37 +90,175 @@ func findStructType(f *ast.File, typeName string) (*ast.StructType, string, erro
 			if !ok || typeSpec.Name.Name != typeName {
 				continue
 			}
-			structType, ok := typeSpec.Type.(*ast.StructType)
-			if !ok {
-				return nil, "", fmt.Errorf("type %s is not a struct", typeName)
+			if _, ok := typeSpec.Type.(*ast.StructType); !ok {
+				return nil, fmt.Errorf("type %s is not a struct", typeName)
 			}
-			return structType, typeSpec.Name.Name, nil
+			return typeSpec, nil
 		}
 	}
-	return nil, "", fmt.Errorf("type %s not found", typeName)
+	return nil, fmt.Errorf("type %s not found", typeName)
 }
 
-func parseStructFields(st *ast.StructType, imports []ImportInfo) []FieldInfo {
+// parseStructFields returns the named fields of the struct name, skipping unexported
+// ones unless unexported is set. Embedded fields and fields of unsupported types are
+// returned separately (see UnsupportedType), as are those referring to the type
+// parameters params in ways HeldTypeParam doesn't support. comments are those of the
+// declaring file, from which the Leading lines of the fields are taken.
+func parseStructFields(fset *token.FileSet, comments []*ast.CommentGroup, name string, st *ast.StructType, params []TypeParam, imports []ImportInfo, unexported bool) ([]FieldInfo, []UnsupportedField) {
 	fields := make([]FieldInfo, 0, len(st.Fields.List))
-	for _, field := range st.Fields.List {
+	var skipped []UnsupportedField
+	prev := st.Fields.Opening
+	for i, field := range st.Fields.List {
+		leading := leadingLines(fset, comments, prev, field)
+		prev = field.End()
+		if field.Comment != nil {
+			prev = field.Comment.End()
+		}
+		tag := ""
+		if field.Tag != nil {
+			tag = field.Tag.Value
+		}
 		if len(field.Names) == 0 {
-			continue // Skip embedded fields
+			skipped = append(skipped, UnsupportedField{
+				Struct: name,
+				Field:  FieldInfo{Name: strings.TrimPrefix(exprToString(field.Type), "*"), Type: exprToString(field.Type), Tag: tag},
+				Pos:    fset.Position(field.Pos()).String(),
+				Reason: "embedded fields are not supported; name the field",
+			})
+			continue
 		}
-		for _, name := range field.Names {
-			if !ast.IsExported(name.Name) {
+		for _, ident := range field.Names {
+			if !unexported && !ast.IsExported(ident.Name) {
+				continue
+			}
+			reason := UnsupportedType(field.Type)
+			param, held := HeldTypeParam(field.Type, params)
+			if reason == "" && !held && MentionsTypeParam(field.Type, params) {
+				reason = TypeParamReason
+			}
+			if reason != "" {
+				skipped = append(skipped, UnsupportedField{
+					Struct: name,
+					Field:  FieldInfo{Name: ident.Name, Type: exprToString(field.Type), Tag: tag},
+					Pos:    fset.Position(ident.Pos()).String(),
+					Reason: reason,
+				})
 				continue
 			}
 			fi := parseFieldType(field.Type, imports)
-			fi.Name = name.Name
+			fi.Name = ident.Name
+			fi.Pos = fset.Position(ident.Pos()).String()
 			fi.TypeExpr = field.Type
 			fi.Type = exprToString(field.Type)
-			if field.Tag != nil {
-				fi.Tag = field.Tag.Value
+			fi.Tag = tag
+			fi.Default = fieldDefault(field, fi)
+			fi.Doc = fieldDoc(fset, st.Fields.List, i)
+			fi.Secret = fi.TagFlag(SecretOption)
+			fi.Leading, leading = leading, nil
+			if field.Comment != nil {
+				fi.Trailing = commentText(field.Comment)
+			}
+			if held {
+				fi.markTypeParam(param)
 			}
 			fields = append(fields, fi)
 		}
 	}
-	return fields
+	return fields, skipped
+}
+
+// leadingLines returns the comments between the source position prev, the end of
+// the previous field or the opening brace of the struct, and field as written, with
+// "" for each run of blank lines.
+func leadingLines(fset *token.FileSet, comments []*ast.CommentGroup, prev token.Pos, field *ast.Field) []string {
+	var lines []string
+	line := fset.Position(prev).Line
+	for _, group := range comments {
+		if group.Pos() <= prev || group.End() > field.Pos() {
+			continue
+		}
+		for _, c := range group.List {
+			if fset.Position(c.Pos()).Line > line+1 {
+				lines = append(lines, "")
+			}
+			lines = append(lines, c.Text)
+			line = fset.Position(c.End()).Line
+		}
+	}
+	if fset.Position(field.Pos()).Line > line+1 {
+		lines = append(lines, "")
+	}
+	return lines
+}
+
+// commentText returns the comments of group as written, on one line.
+func commentText(group *ast.CommentGroup) string {
+	texts := make([]string, len(group.List))
+	for i, c := range group.List {
+		texts[i] = c.Text
+	}
+	return strings.Join(texts, " ")
+}
+
+// defaultCommentPattern matches "Default: value" in a field's doc or line comment.
+var defaultCommentPattern = regexp.MustCompile(`(?i)(?:^|\s)default:\s*(.+?)\s*$`)
+
+// fieldDefault returns the declared default for a field. A default:"..." tag takes
+// precedence over a "Default: ..." comment.
+func fieldDefault(field *ast.Field, fi FieldInfo) string {
+	if v, ok := fi.StructTag().Lookup("default"); ok {
+		return v
+	}
+	for _, group := range []*ast.CommentGroup{field.Doc, field.Comment} {
+		if group == nil {
+			continue
+		}
+		for _, line := range strings.Split(group.Text(), "\n") {
+			m := defaultCommentPattern.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			if unquoted, err := strconv.Unquote(m[1]); err == nil {
+				return unquoted
+			}
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// fieldDoc returns the doc comment of fields[i], or its line comment if it has none,
+// as a single line. "Default: ..." is left out, being reported as the default. A
+// comment above a group of fields, such as "// Basic types", heads a section rather
+// than documenting the first field of it, and is left out too.
+func fieldDoc(fset *token.FileSet, fields []*ast.Field, i int) string {
+	field := fields[i]
+	doc := field.Doc
+	if doc != nil && i+1 < len(fields) {
+		next := fields[i+1]
+		end := field.End()
+		if field.Comment != nil {
+			end = field.Comment.End()
+		}
+		if next.Doc == nil && fset.Position(next.Pos()).Line == fset.Position(end).Line+1 {
+			doc = nil
+		}
+	}
+	for _, group := range []*ast.CommentGroup{doc, field.Comment} {
+		if group == nil {
+			continue
+		}
+		var lines []string
+		for _, line := range strings.Split(group.Text(), "\n") {
+			if line = strings.TrimSpace(defaultCommentPattern.ReplaceAllString(line, "")); line != "" {
+				lines = append(lines, line)
+			}
+		}
+		if len(lines) > 0 {
+			return strings.Join(lines, " ")
+		}
+	}
+	return ""
 }
 
 func parseFieldType(expr ast.Expr, imports []ImportInfo) FieldInfo {
@@ -117,6 +289,7 @@ func parseFieldType(expr ast.Expr, imports []ImportInfo) FieldInfo {
 			fi.SliceType = elemInfo.TypeName
 		}
 		fi.TypeName = "[]" + fi.SliceType
+		fi.IsBytes = t.Len == nil && elemInfo.TypePkg == "" && !elemInfo.IsPointer && (elemInfo.TypeName == "byte" || elemInfo.TypeName == "uint8")
 		if !isBasicType(elemInfo.TypeName) && elemInfo.TypePkg == "" {
 			fi.StructTypeName = elemInfo.TypeName
 			fi.NeedsDeep = true
@@ -187,8 +360,9 @@ func exprToString(expr ast.Expr) string {
 	}
 }
 
-// FindTypeAfterGenerateDirective finds the struct type declared immediately after a go:generate directive.
-func FindTypeAfterGenerateDirective(dir, filename, generatorName string) (string, error) {
+// FindTypeAfterGenerateDirective finds the struct type declared immediately after a go:generate
+// directive running the sudo-gen subcommand.
+func FindTypeAfterGenerateDirective(dir, filename, subcommand string) (string, error) {
 	fset := token.NewFileSet()
 	fullPath := filepath.Join(dir, filename)
 	f, err := parser.ParseFile(fset, fullPath, nil, parser.ParseComments)
@@ -201,7 +375,7 @@ func FindTypeAfterGenerateDirective(dir, filename, generatorName string) (string
 			continue
 		}
 		for _, comment := range genDecl.Doc.List {
-			if strings.Contains(comment.Text, "go:generate") && strings.Contains(comment.Text, generatorName) {
+			if name, _, ok := ParseDirective(comment.Text); ok && name == subcommand {
 				for _, spec := range genDecl.Specs {
 					typeSpec, ok := spec.(*ast.TypeSpec)
 					if !ok {
@@ -214,7 +388,7 @@ func FindTypeAfterGenerateDirective(dir, filename, generatorName string) (string
 			}
 		}
 	}
-	return "", fmt.Errorf("no struct type found after go:generate %s directive", generatorName)
+	return "", fmt.Errorf("no struct type found after go:generate sudo-gen %s directive", subcommand)
 }
 
 // FindTypeAfterLine finds the struct type declared immediately after the given line number.
@@ -245,17 +419,68 @@ func FindTypeAfterLine(filename string, lineNum int) (string, error) {
 	return "", fmt.Errorf("no struct type found after line %d", lineNum)
 }
 
+// FindTypeAtLine finds the struct type whose declaration (including its doc comment)
+// spans the given line.
+func FindTypeAtLine(filename string, src []byte, lineNum int) (string, error) {
+	fset := token.NewFileSet()
+	f, err := parseSourceFile(fset, filename, src)
+	if err != nil {
+		return "", fmt.Errorf("parsing file: %w", err)
+	}
+	for _, decl := range f.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			typeSpec, ok := spec.(*ast.TypeSpec)
+			if !ok {
+				continue
+			}
+			if _, ok := typeSpec.Type.(*ast.StructType); !ok {
+				continue
+			}
+			start, end := typeSpec.Pos(), typeSpec.End()
+			if len(genDecl.Specs) == 1 {
+				start, end = genDecl.Pos(), genDecl.End()
+			}
+			if typeSpec.Doc != nil {
+				start = typeSpec.Doc.Pos()
+			} else if genDecl.Doc != nil && len(genDecl.Specs) == 1 {
+				start = genDecl.Doc.Pos()
+			}
+			if fset.Position(start).Line <= lineNum && lineNum <= fset.Position(end).Line {
+				return typeSpec.Name.Name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no struct type found at line %d", lineNum)
+}
+
 // FindNestedStructs finds all struct types referenced by the given struct.
-// It searches all .go files in the directory to find nested types.
+// It searches src (if non-nil) and then all .go files in the directory to find nested types.
 // It also finds external package structs and marks them appropriately.
-func FindNestedStructs(dir, filename string, info *StructInfo) ([]*StructInfo, error) {
+// Fields of info and the nested structs whose types have value semantics according
+// to values are marked IsValue and not descended into.
+func (x *PackageIndex) FindNestedStructs(dir string, src []byte, info *StructInfo, values *ValueTypes) ([]*StructInfo, error) {
 	seen := make(map[string]bool)
 	seen[info.Name] = true
-	return findNestedStructsRecursive(dir, info, seen)
+	values.Apply(info)
+	return x.findNestedStructsRecursive(dir, src, info, seen, values)
+}
+
+// findLocalStruct looks up a struct declared in src before falling back to the package directory.
+func (x *PackageIndex) findLocalStruct(dir string, src []byte, typeName string, unexported bool) (*StructInfo, error) {
+	if src != nil {
+		if info, err := x.parseStruct(dir, "", src, typeName, unexported); err == nil {
+			return info, nil
+		}
+	}
+	return x.findStructInPackage(dir, typeName, unexported)
 }
 
 // findNestedStructsRecursive is the internal recursive implementation that tracks seen types.
-func findNestedStructsRecursive(dir string, info *StructInfo, seen map[string]bool) ([]*StructInfo, error) {
+func (x *PackageIndex) findNestedStructsRecursive(dir string, src []byte, info *StructInfo, seen map[string]bool, values *ValueTypes) ([]*StructInfo, error) {
 	var nested []*StructInfo
 
 	// Build import path map from all collected imports
@@ -271,16 +496,21 @@ func findNestedStructsRecursive(dir string, info *StructInfo, seen map[string]bo
 	for _, field := range info.Fields {
 		// Handle local package structs
 		if field.StructTypeName != "" && field.TypePkg == "" && !seen[field.StructTypeName] {
-			nestedInfo, err := FindStructInPackage(dir, field.StructTypeName)
+			nestedInfo, err := x.findLocalStruct(dir, src, field.StructTypeName, info.Unexported)
+			if errors.Is(err, ErrAmbiguousType) {
+				return nil, err
+			}
 			if err != nil {
 				continue // Type might be external or not found
 			}
 			seen[field.StructTypeName] = true
+			values.Apply(nestedInfo)
 			nested = append(nested, nestedInfo)
-			subNested, err := findNestedStructsRecursive(dir, nestedInfo, seen)
-			if err == nil {
-				nested = append(nested, subNested...)
+			subNested, err := x.findNestedStructsRecursive(dir, src, nestedInfo, seen, values)
+			if err != nil {
+				return nil, err
 			}
+			nested = append(nested, subNested...)
 			continue
 		}
 
@@ -295,11 +525,14 @@ func findNestedStructsRecursive(dir string, info *StructInfo, seen map[string]bo
 				continue
 			}
 			// Try to find and parse the external struct
-			extInfo, err := FindExternalStruct(dir, importPath, field.TypeName)
+			extInfo, err := x.FindExternalStruct(dir, importPath, field.TypeName)
 			if err != nil {
 				continue // External struct not parseable
 			}
+			// Refer to the struct by the name it is imported as, which may be an alias
+			extInfo.Package = field.TypePkg
 			seen[key] = true
+			values.Apply(extInfo)
 			nested = append(nested, extInfo)
 		}
 	}
@@ -308,52 +541,30 @@ func findNestedStructsRecursive(dir string, info *StructInfo, seen map[string]bo
 
 // FindExternalStruct finds a struct type in an external package.
 // It resolves the import path relative to the source directory.
-func FindExternalStruct(sourceDir, importPath, typeName string) (*StructInfo, error) {
+func (x *PackageIndex) FindExternalStruct(sourceDir, importPath, typeName string) (*StructInfo, error) {
 	// Resolve the external package directory
 	// First try relative to current module
 	extDir := resolveImportPath(sourceDir, importPath)
 	if extDir == "" {
 		return nil, fmt.Errorf("cannot resolve import path: %s", importPath)
 	}
-
-	fset := token.NewFileSet()
-	pkgs, err := parser.ParseDir(fset, extDir, func(fi os.FileInfo) bool {
-		return !strings.HasSuffix(fi.Name(), "_test.go")
-	}, parser.ParseComments)
+	pkg, err := x.Package(extDir)
 	if err != nil {
 		return nil, fmt.Errorf("parsing external package: %w", err)
 	}
-
-	for _, pkg := range pkgs {
-		for _, f := range pkg.Files {
-			imports := collectImports(f)
-			for _, decl := range f.Decls {
-				genDecl, ok := decl.(*ast.GenDecl)
-				if !ok || genDecl.Tok != token.TYPE {
-					continue
-				}
-				for _, spec := range genDecl.Specs {
-					typeSpec, ok := spec.(*ast.TypeSpec)
-					if !ok || typeSpec.Name.Name != typeName {
-						continue
-					}
-					structType, ok := typeSpec.Type.(*ast.StructType)
-					if !ok {
-						continue // Not a struct (could be type alias)
-					}
-					fields := parseStructFields(structType, imports)
-					return &StructInfo{
-						Name:       typeSpec.Name.Name,
-						Fields:     fields,
-						Imports:    imports,
-						Package:    pkg.Name,
-						ImportPath: importPath,
-					}, nil
-				}
-			}
-		}
+	st, err := pkg.Struct(typeName)
+	if err != nil {
+		return nil, err
+	}
+	info := &StructInfo{
+		Name:       typeName,
+		Imports:    st.Imports,
+		Package:    st.Package,
+		ImportPath: importPath,
+		Pos:        st.Pos.String(),
 	}
-	return nil, fmt.Errorf("type %s not found in package %s", typeName, importPath)
+	info.Fields, info.Unsupported = parseStructFields(pkg.Fset, pkg.Files[st.File].Comments, typeName, st.Type, nil, st.Imports, false)
+	return info, nil
 }
 
 // resolveImportPath resolves an import path to a directory path.
@@ -393,45 +604,64 @@ func resolveImportPath(sourceDir, importPath string) string {
 	return ""
 }
 
-// FindStructInPackage searches all .go files in the directory for a struct type.
-func FindStructInPackage(dir, typeName string) (*StructInfo, error) {
-	fset := token.NewFileSet()
-	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
-		return !strings.HasSuffix(fi.Name(), "_test.go")
-	}, parser.ParseComments)
+// ImportPathForDir returns the import path of the package in dir, derived from the
+// enclosing module's go.mod.
+func ImportPathForDir(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
 	if err != nil {
-		return nil, fmt.Errorf("parsing directory: %w", err)
+		return "", fmt.Errorf("resolving directory: %w", err)
 	}
-	for _, pkg := range pkgs {
-		for filename, f := range pkg.Files {
-			imports := collectImports(f)
-			for _, decl := range f.Decls {
-				genDecl, ok := decl.(*ast.GenDecl)
-				if !ok || genDecl.Tok != token.TYPE {
+	for root := dir; ; {
+		content, err := os.ReadFile(filepath.Join(root, "go.mod"))
+		if err == nil {
+			for _, line := range strings.Split(string(content), "\n") {
+				modulePath, ok := strings.CutPrefix(strings.TrimSpace(line), "module ")
+				if !ok {
 					continue
 				}
-				for _, spec := range genDecl.Specs {
-					typeSpec, ok := spec.(*ast.TypeSpec)
-					if !ok || typeSpec.Name.Name != typeName {
-						continue
-					}
-					structType, ok := typeSpec.Type.(*ast.StructType)
-					if !ok {
-						continue
-					}
-					fields := parseStructFields(structType, imports)
-					return &StructInfo{
-						Name:    typeSpec.Name.Name,
-						Fields:  fields,
-						Imports: imports,
-						// Store which file the struct was found in
-						SourceFile: filepath.Base(filename),
-					}, nil
+				rel, err := filepath.Rel(root, dir)
+				if err != nil {
+					return "", fmt.Errorf("resolving directory: %w", err)
 				}
+				if rel == "." {
+					return strings.TrimSpace(modulePath), nil
+				}
+				return strings.TrimSpace(modulePath) + "/" + filepath.ToSlash(rel), nil
 			}
+			return "", fmt.Errorf("no module directive in %s", filepath.Join(root, "go.mod"))
+		}
+		parent := filepath.Dir(root)
+		if parent == root {
+			return "", fmt.Errorf("no go.mod found above %s", dir)
 		}
+		root = parent
+	}
+}
+
+// FindStructInPackage searches all .go files in the directory for a struct type.
+func (x *PackageIndex) FindStructInPackage(dir, typeName string) (*StructInfo, error) {
+	return x.findStructInPackage(dir, typeName, false)
+}
+
+func (x *PackageIndex) findStructInPackage(dir, typeName string, unexported bool) (*StructInfo, error) {
+	pkg, err := x.Package(dir)
+	if err != nil {
+		return nil, fmt.Errorf("parsing directory: %w", err)
+	}
+	st, err := pkg.Struct(typeName)
+	if err != nil {
+		return nil, err
+	}
+	info := &StructInfo{
+		Name:       typeName,
+		Imports:    st.Imports,
+		Unexported: unexported,
+		// Store which file the struct was found in
+		SourceFile: filepath.Base(st.File),
+		Pos:        st.Pos.String(),
 	}
-	return nil, fmt.Errorf("type %s not found in package", typeName)
+	info.Fields, info.Unsupported = parseStructFields(pkg.Fset, pkg.Files[st.File].Comments, typeName, st.Type, nil, st.Imports, unexported)
+	return info, nil
 }
 
 // CollectRequiredImports determines which imports are needed for generated code.
@@ -0,0 +1,97 @@
+package codegen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectFileName is the name of the file holding settings shared by the generators
+// of a project. It is looked up in the source directory and its parents, up to the
+// module root.
+const ProjectFileName = "sudo-gen.yaml"
+
+// ProjectFile holds the settings of a sudo-gen.yaml file:
+//
+//	invocation: tool
+//	templates: tools/sudo-gen
+//	partial-suffix: Patch
+//	migrations:
+//	  Config:
+//	    - from: db_host
+//	      to: database.host
+type ProjectFile struct {
+	Path          string                 `yaml:"-"`              // File the settings were read from; empty without one
+	Invocation    Invocation             `yaml:"invocation"`     // How go:generate directives run sudo-gen; default binary
+	Templates     string                 `yaml:"templates"`      // Directory of template overrides (see -templates), relative to the file
+	PartialSuffix string                 `yaml:"partial-suffix"` // Suffix of the partial types of merge and the functions named after them; default Partial
+	Migrations    map[string][]Migration `yaml:"migrations"`     // Keys of config documents that moved, by type name
+	Style         Style                  `yaml:"style"`          // Code style of the generated code
+}
+
+// Migration renames a key of a config document. Keys are dotted paths of the json
+// names of fields, such as database.host.
+type Migration struct {
+	From string `yaml:"from"`
+	To   string `yaml:"to"`
+}
+
+// FindProjectFile reads the sudo-gen.yaml file in dir or in the closest of its
+// parents, stopping at the directory holding go.mod. It returns an empty ProjectFile
+// if there is none.
+func FindProjectFile(dir string) (ProjectFile, error) {
+	for {
+		path := filepath.Join(dir, ProjectFileName)
+		data, err := os.ReadFile(path)
+		if err == nil {
+			return parseProjectFile(path, data)
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return ProjectFile{}, err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ProjectFile{}, nil
+		}
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return ProjectFile{}, nil
+		}
+		dir = parent
+	}
+}
+
+// parseProjectFile decodes the contents of a sudo-gen.yaml file, rejecting unknown
+// settings so that typos don't go unnoticed.
+func parseProjectFile(path string, data []byte) (ProjectFile, error) {
+	pf := ProjectFile{Path: path}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&pf); err != nil && !errors.Is(err, io.EOF) {
+		return ProjectFile{}, fmt.Errorf("%s: %w", path, err)
+	}
+	inv, err := ParseInvocation(string(pf.Invocation))
+	if err != nil {
+		return ProjectFile{}, fmt.Errorf("%s: %w", path, err)
+	}
+	pf.Invocation = inv
+	if pf.PartialSuffix != "" && (!token.IsIdentifier(pf.PartialSuffix) || !token.IsExported(pf.PartialSuffix)) {
+		return ProjectFile{}, fmt.Errorf("%s: partial-suffix %q must be an exported identifier, like Patch", path, pf.PartialSuffix)
+	}
+	if err := pf.Style.validate(); err != nil {
+		return ProjectFile{}, fmt.Errorf("%s: %w", path, err)
+	}
+	for typeName, migrations := range pf.Migrations {
+		for i, m := range migrations {
+			if m.From == "" || m.To == "" {
+				return ProjectFile{}, fmt.Errorf("%s: migration %d of %s needs both from and to", path, i+1, typeName)
+			}
+		}
+	}
+	return pf, nil
+}
@@ -0,0 +1,129 @@
+// Package providers implements the providers code generation subtool.
+package providers
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/bobcob7/sudo-gen/internal/codegen"
+)
+
+// Subtool implements the dependency injection providers code generator.
+type Subtool struct{}
+
+// Name returns the subtool name.
+func (s *Subtool) Name() string { return "providers" }
+
+// Description returns the subtool description.
+func (s *Subtool) Description() string {
+	return "Generate dependency injection providers building the broker from default, file and env layers"
+}
+
+// Doc describes the struct tags the subtool honors and the files it generates.
+func (s *Subtool) Doc() codegen.SubtoolDoc {
+	return codegen.SubtoolDoc{
+		Files: []codegen.DocEntry{
+			{Name: "{source}_providers.go", Description: "Provide{Type}LayerBroker, Provide{Type}, {Type}File and {Type}EnvLoader"},
+			{Name: "{source}_fx.go", Description: "{Type}Module, an fx.Option providing the broker and config (with -frameworks=fx)"},
+			{Name: "{source}_wire.go", Description: "{Type}ProviderSet, a wire.ProviderSet of the providers (with -frameworks=wire)"},
+		},
+	}
+}
+
+// frameworks maps each supported -frameworks entry to its template.
+var frameworks = map[string]string{
+	"fx":   fxTemplate,
+	"wire": wireTemplate,
+}
+
+// DefineFlags defines the flags of the providers subcommand.
+func (s *Subtool) DefineFlags(fs *flag.FlagSet, cfg *codegen.GeneratorConfig) {
+	fs.Var((*codegen.ListFlag)(&cfg.DIFrameworks), "frameworks", "Comma-separated dependency injection frameworks to declare the providers for (fx, wire)")
+}
+
+// Run executes the providers code generation. The generated providers build on the
+// layerbroker and loader output for the same type, and on the defaults output when
+// the type declares defaults.
+func (s *Subtool) Run(cfg codegen.GeneratorConfig) error {
+	for _, framework := range cfg.DIFrameworks {
+		if _, ok := frameworks[framework]; !ok {
+			return fmt.Errorf("unknown dependency injection framework %q (supported: fx, wire)", framework)
+		}
+	}
+	info, err := cfg.Index.ParseStruct(cfg.SourceDir, cfg.SourceFile, cfg.Source, cfg.TypeName)
+	if err != nil {
+		return fmt.Errorf("parsing struct: %w", err)
+	}
+	nested, err := cfg.Index.FindNestedStructs(cfg.SourceDir, cfg.Source, info, codegen.NewValueTypes(cfg.Index, cfg.SourceDir, cfg.ValueTypes))
+	if err != nil {
+		return fmt.Errorf("finding nested structs: %w", err)
+	}
+	data := templateData{
+		Package:  cfg.OutputPkg,
+		TypeName: info.Name,
+		Defaults: declaresDefaults(append([]*codegen.StructInfo{info}, nested...)),
+	}
+	baseName := strings.TrimSuffix(cfg.SourceFile, ".go")
+	gen := codegen.NewTemplateGenerator(cfg, templateFuncs())
+	if err := gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_providers.go"), providersTemplate, data); err != nil {
+		return err
+	}
+	if cfg.GenerateTest {
+		if err := gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_providers_test.go"), providersTestTemplate, data); err != nil {
+			return err
+		}
+	}
+	for _, framework := range cfg.DIFrameworks {
+		if err := gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_"+framework+".go"), frameworks[framework], data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type templateData struct {
+	Package  string
+	TypeName string
+	Defaults bool // The type declares defaults, so the broker starts from Default{Type}
+}
+
+// declaresDefaults reports whether any of the local structs declares a default, in
+// which case the defaults subtool generates the Default{Type} constructor.
+func declaresDefaults(structs []*codegen.StructInfo) bool {
+	for _, st := range structs {
+		if st.Package != "" {
+			continue
+		}
+		for _, f := range st.Fields {
+			if f.Default != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"capitalize": capitalize,
+		"ident":      ident,
+		"lower":      strings.ToLower,
+	}
+}
+
+func capitalize(name string) string {
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// ident builds a generated function name such as ProvideConfig, keeping it
+// unexported (provideConfig) when the type is unexported.
+func ident(verb, typeName, suffix string) string {
+	if ast.IsExported(typeName) {
+		return capitalize(verb) + typeName + suffix
+	}
+	return verb + capitalize(typeName) + suffix
+}
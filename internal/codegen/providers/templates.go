@@ -0,0 +1,177 @@
+package providers
+
+const providersTemplate = `// Code generated by sudo-gen providers. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"fmt"
+)
+
+// {{.TypeName}}File is the path of the file {{ident "provide" .TypeName "LayerBroker"}} loads into the
+// "file" layer of the broker, in a format {{ident "load" (partial .TypeName) "File"}} reads. An empty path
+// loads no file.
+type {{.TypeName}}File string
+
+// {{.TypeName}}EnvLoader returns the partial {{ident "provide" .TypeName "LayerBroker"}} puts into the
+// "env" layer of the broker, above the file, usually read from environment variables.
+// A nil loader loads no environment layer.
+type {{.TypeName}}EnvLoader func() (*{{partial .TypeName}}, error)
+
+// {{ident "provide" .TypeName "LayerBroker"}} returns a broker with {{if .Defaults}}the defaults of {{.TypeName}}{{else}}an empty {{.TypeName}}{{end}} as its base,
+// the "file" layer loaded from file above it and the "env" layer from env on top, so
+// that the environment wins. It is the provider of the broker for dependency
+// injection frameworks, which build the {{.TypeName}} with {{ident "provide" .TypeName ""}}.
+func {{ident "provide" .TypeName "LayerBroker"}}(file {{.TypeName}}File, env {{.TypeName}}EnvLoader) (*{{.TypeName}}LayerBroker, error) {
+{{- if .Defaults}}
+	broker := {{ident "new" .TypeName "LayerBroker"}}({{ident "default" .TypeName ""}}())
+{{- else}}
+	broker := {{ident "new" .TypeName "LayerBroker"}}(&{{.TypeName}}{})
+{{- end}}
+	if file != "" {
+		p, err := {{ident "load" (partial .TypeName) "File"}}(string(file), false)
+		if err != nil {
+			return nil, fmt.Errorf("loading config file: %w", err)
+		}
+		if err := broker.Layer().Named("file").Set(p); err != nil {
+			return nil, err
+		}
+	}
+	if env != nil {
+		p, err := env()
+		if err != nil {
+			return nil, fmt.Errorf("loading config environment: %w", err)
+		}
+		if err := broker.Layer().Named("env").Set(p); err != nil {
+			return nil, err
+		}
+	}
+	return broker, nil
+}
+
+// {{ident "provide" .TypeName ""}} returns the merged config of broker.
+func {{ident "provide" .TypeName ""}}(broker *{{.TypeName}}LayerBroker) *{{.TypeName}} {
+	return broker.Get()
+}
+`
+
+const providersTestTemplate = `// Code generated by sudo-gen providers. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// {{lower .TypeName}}EmptyFile writes an empty config file in a format the loader reads.
+func {{lower .TypeName}}EmptyFile(t *testing.T) string {
+	t.Helper()
+	for _, name := range []string{"config.json", "config.yaml", "config.toml", "config.hcl"} {
+		if _, err := {{.TypeName}}FormatFromPath(name); err != nil {
+			continue
+		}
+		path := filepath.Join(t.TempDir(), name)
+		content := map[string]string{"config.json": "{}", "config.yaml": "{}"}[name]
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	t.Fatal("the loader reads none of the formats")
+	return ""
+}
+
+func Test{{capitalize .TypeName}}Providers(t *testing.T) {
+	path := {{lower .TypeName}}EmptyFile(t)
+	loaded := false
+	env := func() (*{{partial .TypeName}}, error) {
+		loaded = true
+		return &{{partial .TypeName}}{}, nil
+	}
+	broker, err := {{ident "provide" .TypeName "LayerBroker"}}({{.TypeName}}File(path), env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded {
+		t.Error("the env loader was not called")
+	}
+{{- if .Defaults}}
+	want := {{ident "default" .TypeName ""}}()
+{{- else}}
+	want := &{{.TypeName}}{}
+{{- end}}
+	if got := {{ident "provide" .TypeName ""}}(broker); !reflect.DeepEqual(got, want) {
+		t.Errorf("{{ident "provide" .TypeName ""}}() = %+v, want %+v", got, want)
+	}
+}
+
+func Test{{capitalize .TypeName}}ProvidersNoSources(t *testing.T) {
+	broker, err := {{ident "provide" .TypeName "LayerBroker"}}("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if broker.Get() == nil {
+		t.Error("broker has no config")
+	}
+}
+
+func Test{{capitalize .TypeName}}ProvidersErrors(t *testing.T) {
+	if _, err := {{ident "provide" .TypeName "LayerBroker"}}({{.TypeName}}File(filepath.Join(t.TempDir(), "missing.json")), nil); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+	errEnv := errors.New("env unavailable")
+	env := func() (*{{partial .TypeName}}, error) { return nil, errEnv }
+	if _, err := {{ident "provide" .TypeName "LayerBroker"}}("", env); !errors.Is(err, errEnv) {
+		t.Errorf("err = %v, want %v", err, errEnv)
+	}
+}
+`
+
+const fxTemplate = `// Code generated by sudo-gen providers. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"go.uber.org/fx"
+)
+
+// {{.TypeName}}Module provides the *{{.TypeName}}LayerBroker and the *{{.TypeName}} built by
+// {{ident "provide" .TypeName "LayerBroker"}} to an fx application. The {{.TypeName}}File and {{.TypeName}}EnvLoader
+// are optional:
+//
+//	fx.New({{.TypeName}}Module, fx.Supply({{.TypeName}}File("/etc/app/config.json")))
+var {{.TypeName}}Module = fx.Module("{{lower .TypeName}}",
+	fx.Provide(provide{{capitalize .TypeName}}LayerBrokerFx, {{ident "provide" .TypeName ""}}),
+)
+
+// provide{{capitalize .TypeName}}Params are the optional dependencies of {{.TypeName}}Module.
+type provide{{capitalize .TypeName}}Params struct {
+	fx.In
+	File {{.TypeName}}File      ` + "`optional:\"true\"`" + `
+	Env  {{.TypeName}}EnvLoader ` + "`optional:\"true\"`" + `
+}
+
+func provide{{capitalize .TypeName}}LayerBrokerFx(p provide{{capitalize .TypeName}}Params) (*{{.TypeName}}LayerBroker, error) {
+	return {{ident "provide" .TypeName "LayerBroker"}}(p.File, p.Env)
+}
+`
+
+const wireTemplate = `// Code generated by sudo-gen providers. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"github.com/google/wire"
+)
+
+// {{.TypeName}}ProviderSet provides the *{{.TypeName}}LayerBroker and the *{{.TypeName}} built by
+// {{ident "provide" .TypeName "LayerBroker"}} to wire injectors, which must provide the {{.TypeName}}File
+// and {{.TypeName}}EnvLoader, here with a provider function loadEnv returning the loader:
+//
+//	wire.Build({{.TypeName}}ProviderSet, wire.Value({{.TypeName}}File("/etc/app/config.json")), loadEnv)
+var {{.TypeName}}ProviderSet = wire.NewSet({{ident "provide" .TypeName "LayerBroker"}}, {{ident "provide" .TypeName ""}})
+`
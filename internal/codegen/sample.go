@@ -0,0 +1,190 @@
+package codegen
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+)
+
+// sampleLen is the number of elements in sample slices and maps.
+const sampleLen = 3
+
+// Sampler builds Go expressions for representative values of parsed structs, with
+// every field the sampler knows how to fill set. Generated benchmarks use them so
+// that slices, maps and nested structs are copied, compared and merged rather than
+// skipped as nil.
+type Sampler struct {
+	structs  map[string]*StructInfo // Local structs by name
+	visiting map[string]bool        // Structs being built, to stop at recursive types
+}
+
+// NewSampler returns a Sampler for the given structs. Structs from other packages
+// are ignored, and fields of their types are left as zero values.
+func NewSampler(structs []*StructInfo) *Sampler {
+	s := &Sampler{structs: make(map[string]*StructInfo), visiting: make(map[string]bool)}
+	for _, st := range structs {
+		if st.Package == "" {
+			s.structs[st.Name] = st
+		}
+	}
+	return s
+}
+
+// Literal returns a composite literal of the named struct. Fields of types
+// without a known sample, such as local non-struct named types, are omitted.
+func (s *Sampler) Literal(name string) string {
+	st := s.structs[name]
+	if st == nil || s.visiting[name] {
+		return name + "{}"
+	}
+	s.visiting[name] = true
+	defer delete(s.visiting, name)
+	var b strings.Builder
+	b.WriteString(name + "{\n")
+	for _, f := range st.Fields {
+		if f.TypeExpr == nil {
+			continue
+		}
+		if v, ok := s.value(f.TypeExpr); ok {
+			fmt.Fprintf(&b, "%s: %s,\n", f.Name, v)
+		}
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// value returns a sample expression of type expr.
+func (s *Sampler) value(expr ast.Expr) (string, bool) {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		switch t.Name {
+		case "string":
+			return `"value"`, true
+		case "bool":
+			return "true", true
+		case "int", "int8", "int16", "int32", "int64",
+			"uint", "uint8", "uint16", "uint32", "uint64", "uintptr", "byte", "rune":
+			return "42", true
+		case "float32", "float64":
+			return "1.5", true
+		case "any":
+			return `"value"`, true
+		}
+		if _, ok := s.structs[t.Name]; ok && !s.visiting[t.Name] {
+			return s.Literal(t.Name), true
+		}
+	case *ast.InterfaceType:
+		if t.Methods == nil || len(t.Methods.List) == 0 {
+			return `"value"`, true
+		}
+	case *ast.SelectorExpr:
+		switch exprToString(t) {
+		case "time.Time":
+			return "time.Unix(1700000000, 0)", true
+		case "time.Duration":
+			return "time.Second", true
+		}
+	case *ast.StarExpr:
+		if ident, ok := t.X.(*ast.Ident); ok {
+			if _, local := s.structs[ident.Name]; local {
+				if s.visiting[ident.Name] {
+					return "", false
+				}
+				return "&" + s.Literal(ident.Name), true
+			}
+		}
+		v, ok := s.value(t.X)
+		if !ok {
+			return "", false
+		}
+		typ := exprToString(t.X)
+		if isNumericType(typ) {
+			// Untyped constants would otherwise default to int or float64
+			v = typ + "(" + v + ")"
+		}
+		return fmt.Sprintf("func() *%s { v := %s; return &v }()", typ, v), true
+	case *ast.ArrayType:
+		if t.Len != nil {
+			return "", false
+		}
+		elem, ok := s.value(t.Elt)
+		if !ok {
+			return "", false
+		}
+		elem = elideType(t.Elt, elem)
+		elems := make([]string, sampleLen)
+		for i := range elems {
+			elems[i] = elem
+		}
+		return fmt.Sprintf("%s{%s}", exprToString(t), strings.Join(elems, ", ")), true
+	case *ast.MapType:
+		val, ok := s.value(t.Value)
+		if !ok {
+			return "", false
+		}
+		val = elideType(t.Value, val)
+		entries := make([]string, 0, sampleLen)
+		for i := range sampleLen {
+			key, ok := sampleKey(t.Key, i)
+			if !ok {
+				return "", false
+			}
+			entries = append(entries, key+": "+val)
+		}
+		return fmt.Sprintf("%s{%s}", exprToString(t), strings.Join(entries, ", ")), true
+	}
+	return "", false
+}
+
+// elideType drops the type of a composite literal element, as gofmt -s would.
+func elideType(typ ast.Expr, v string) string {
+	name := strings.TrimPrefix(exprToString(typ), "*")
+	if rest, ok := strings.CutPrefix(strings.TrimPrefix(v, "&"), name+"{"); ok {
+		return "{" + rest
+	}
+	return v
+}
+
+func isNumericType(typ string) bool {
+	switch typ {
+	case "int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64", "uintptr", "byte", "rune",
+		"float32", "float64":
+		return true
+	}
+	return false
+}
+
+// sampleKey returns the i-th of a set of distinct map keys of type expr.
+func sampleKey(expr ast.Expr, i int) (string, bool) {
+	ident, ok := expr.(*ast.Ident)
+	if !ok {
+		return "", false
+	}
+	switch ident.Name {
+	case "string":
+		return fmt.Sprintf(`"key%d"`, i), true
+	case "int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64", "uintptr", "byte", "rune":
+		return fmt.Sprint(i + 1), true
+	}
+	return "", false
+}
+
+// SampleLiteral returns Sampler.Literal for the root type of cfg, sampling the
+// local structs it refers to as well.
+func SampleLiteral(cfg GeneratorConfig) (string, error) {
+	parse := cfg.Index.ParseStruct
+	if cfg.IncludeUnexported {
+		parse = cfg.Index.ParseStructUnexported
+	}
+	info, err := parse(cfg.SourceDir, cfg.SourceFile, cfg.Source, cfg.TypeName)
+	if err != nil {
+		return "", fmt.Errorf("parsing struct: %w", err)
+	}
+	nested, err := cfg.Index.FindNestedStructs(cfg.SourceDir, cfg.Source, info, NewValueTypes(cfg.Index, cfg.SourceDir, cfg.ValueTypes))
+	if err != nil {
+		return "", fmt.Errorf("finding nested structs: %w", err)
+	}
+	return NewSampler(append([]*StructInfo{info}, nested...)).Literal(info.Name), nil
+}
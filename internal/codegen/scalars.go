@@ -0,0 +1,134 @@
+package codegen
+
+import (
+	"go/ast"
+	"go/build"
+	"go/token"
+	"go/types"
+)
+
+// ScalarType is a type defined over a basic type or time.Duration, like
+// type Port uint16, whose values can be parsed from a single string.
+type ScalarType struct {
+	Name string
+	Kind string // Basic type it is defined over, or "duration" for time.Duration
+}
+
+// ScalarType returns the type name declared in dir if it is a ScalarType. Defined
+// types are followed to the type they are defined over, so type Timeout Duration is
+// a duration if Duration is one.
+func (x *PackageIndex) ScalarType(dir, name string) (ScalarType, bool) {
+	pkg, err := x.Package(dir)
+	if err != nil {
+		return ScalarType{}, false
+	}
+	seen := make(map[string]bool)
+	for typeName := name; !seen[typeName]; {
+		seen[typeName] = true
+		spec, imports := pkg.typeSpec(typeName)
+		if spec == nil || spec.Assign.IsValid() || spec.TypeParams != nil {
+			return ScalarType{}, false
+		}
+		switch t := spec.Type.(type) {
+		case *ast.Ident:
+			if isScalarKind(t.Name) {
+				return ScalarType{Name: name, Kind: t.Name}, true
+			}
+			typeName = t.Name
+		case *ast.SelectorExpr:
+			if pkgIdent, ok := t.X.(*ast.Ident); ok && importPathFor(imports, pkgIdent.Name) == "time" && t.Sel.Name == "Duration" {
+				return ScalarType{Name: name, Kind: "duration"}, true
+			}
+			return ScalarType{}, false
+		default:
+			return ScalarType{}, false
+		}
+	}
+	return ScalarType{}, false
+}
+
+// typeSpec returns the declaration of the named type at package scope and the
+// imports of its file, or nil if there is none.
+func (p *Package) typeSpec(name string) (*ast.TypeSpec, []ImportInfo) {
+	for _, f := range p.Files {
+		for _, decl := range f.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				if typeSpec, ok := spec.(*ast.TypeSpec); ok && typeSpec.Name.Name == name {
+					return typeSpec, collectImports(f)
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+func isScalarKind(name string) bool {
+	switch name {
+	case "string", "bool", "float32", "float64",
+		"int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64":
+		return true
+	}
+	return false
+}
+
+// Methods returns the methods of a pointer to the type name declared in dir, which
+// include those with value receivers, mapped to the file declaring each.
+func (x *PackageIndex) Methods(dir, name string) map[string]string {
+	pkg := x.Types(dir)
+	if pkg == nil {
+		return nil
+	}
+	obj, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return nil
+	}
+	methods := make(map[string]string)
+	mset := types.NewMethodSet(types.NewPointer(obj.Type()))
+	for i := range mset.Len() {
+		fn := mset.At(i).Obj()
+		methods[fn.Name()] = x.fset.Position(fn.Pos()).Filename
+	}
+	return methods
+}
+
+// TypeDir returns the directory of the package declaring a type referenced as
+// pkg.Name from a file in dir with the given imports: dir itself for an empty pkg.
+// It returns "" if the package can't be found.
+func TypeDir(dir string, imports []ImportInfo, pkg string) string {
+	if pkg == "" {
+		return dir
+	}
+	path := importPathFor(imports, pkg)
+	if path == "" {
+		return ""
+	}
+	if pkgDir := resolveImportPath(dir, path); pkgDir != "" {
+		return pkgDir
+	}
+	bp, err := build.Import(path, dir, build.FindOnly)
+	if err != nil {
+		return ""
+	}
+	return bp.Dir
+}
+
+// Samples returns two different values of the type as strings, in the form its
+// String method would print them, for generated tests.
+func (s ScalarType) Samples() (string, string) {
+	switch s.Kind {
+	case "string":
+		return "flag", "layer"
+	case "bool":
+		return "true", "false"
+	case "float32", "float64":
+		return "1.5", "2.5"
+	case "duration":
+		return "1m30s", "5s"
+	}
+	return "42", "7"
+}
@@ -0,0 +1,349 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Style is the code style of generated code, set in the style section of
+// sudo-gen.yaml, for teams whose lint rules disagree with the defaults:
+//
+//	style:
+//	  receiver: first
+//	  equal-receiver: value
+//	  errors: join
+//	  nolint: [exhaustive, exhaustruct, funlen]
+//
+// Receivers, errors and nolint directives are rewritten in the output of every
+// template, including overridden ones, so templates are written in the default style.
+type Style struct {
+	// Receiver names the receivers of the methods generated on config types: "c"
+	// (default), "cfg", or "first" for the lowercased first letter of the type.
+	Receiver string `yaml:"receiver"`
+	// EqualReceiver is "pointer" (default) or "value": Equal methods then take a value
+	// receiver, and callers check for nil configs before calling them.
+	EqualReceiver string `yaml:"equal-receiver"`
+	// Errors is "wrap" (default) to wrap errors with fmt.Errorf and %w, or "join" to
+	// combine them with their context using errors.Join.
+	Errors string `yaml:"errors"`
+	// NoLint names the golangci-lint linters whose issues generated declarations are
+	// excluded from, with //nolint directives on the declarations they could report:
+	// exhaustive on those with switch statements, exhaustruct on those with composite
+	// literals, and any other linter on functions.
+	NoLint []string `yaml:"nolint"`
+}
+
+// validate reports settings of s with unknown values.
+func (s Style) validate() error {
+	if !slices.Contains([]string{"", "c", "cfg", "first"}, s.Receiver) {
+		return fmt.Errorf("style receiver %q is not c, cfg or first", s.Receiver)
+	}
+	if !slices.Contains([]string{"", "pointer", "value"}, s.EqualReceiver) {
+		return fmt.Errorf("style equal-receiver %q is not pointer or value", s.EqualReceiver)
+	}
+	if !slices.Contains([]string{"", "wrap", "join"}, s.Errors) {
+		return fmt.Errorf("style errors %q is not wrap or join", s.Errors)
+	}
+	for _, linter := range s.NoLint {
+		if !lintNamePattern.MatchString(linter) {
+			return fmt.Errorf("style nolint %q is not a linter name", linter)
+		}
+	}
+	return nil
+}
+
+// ValueEqual reports whether Equal methods take value receivers.
+func (s Style) ValueEqual() bool {
+	return s.EqualReceiver == "value"
+}
+
+// EqualCall returns the condition that a and b, pointers to the same config type,
+// are equal, calling its Equal method with the given name. With value receivers, a
+// is checked for nil first.
+func (s Style) EqualCall(method, a, b string) string {
+	if !s.ValueEqual() {
+		return a + "." + method + "(" + b + ")"
+	}
+	return fmt.Sprintf("((%s == nil) == (%s == nil) && (%s == nil || %s.%s(%s)))", a, b, a, a, method, b)
+}
+
+// receiverName returns the receiver name of the methods of typeName.
+func (s Style) receiverName(typeName string) string {
+	switch s.Receiver {
+	case "cfg":
+		return "cfg"
+	case "first":
+		r, _ := utf8.DecodeRuneInString(typeName)
+		return string(unicode.ToLower(r))
+	}
+	return "c"
+}
+
+// apply rewrites src, generated in the default style, in style s. src is returned
+// unchanged if it doesn't parse, leaving the syntax error for the formatter to report.
+func (s Style) apply(src []byte) ([]byte, error) {
+	if s.Receiver == "" && s.Errors == "" && len(s.NoLint) == 0 {
+		return src, nil
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return src, nil
+	}
+	var edits []styleEdit
+	for _, decl := range f.Decls {
+		if linters := s.noLintOf(decl); len(linters) > 0 {
+			directive := "//nolint:" + strings.Join(linters, ",") + " // generated code\n"
+			edits = append(edits, styleEdit{fset.Position(decl.Pos()).Offset, fset.Position(decl.Pos()).Offset, directive})
+		}
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		renames, err := s.renameReceiver(fset, fn)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, renames...)
+		if s.Errors == "join" {
+			edits = append(edits, joinErrors(fset, src, fn)...)
+		}
+	}
+	slices.SortFunc(edits, func(a, b styleEdit) int { return b.start - a.start })
+	for _, e := range edits {
+		src = slices.Concat(src[:e.start], []byte(e.text), src[e.end:])
+	}
+	return src, nil
+}
+
+// lintNamePattern matches the names of golangci-lint linters.
+var lintNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// noLintOf returns the linters of s.NoLint that could report issues in decl. Struct
+// literals are told from others by their syntax, so literals of named slice and
+// map types count as struct literals.
+func (s Style) noLintOf(decl ast.Decl) []string {
+	if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+		return nil
+	}
+	var switches, literals bool
+	ast.Inspect(decl, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SwitchStmt, *ast.TypeSwitchStmt:
+			switches = true
+		case *ast.CompositeLit:
+			switch n.Type.(type) {
+			case *ast.ArrayType, *ast.MapType:
+			default:
+				literals = true
+			}
+		}
+		return true
+	})
+	_, isFunc := decl.(*ast.FuncDecl)
+	var linters []string
+	for _, linter := range s.NoLint {
+		switch linter {
+		case "exhaustive":
+			if switches {
+				linters = append(linters, linter)
+			}
+		case "exhaustruct":
+			if literals {
+				linters = append(linters, linter)
+			}
+		default:
+			if isFunc {
+				linters = append(linters, linter)
+			}
+		}
+	}
+	return linters
+}
+
+// styleEdit replaces the bytes of the source from start to end with text.
+type styleEdit struct {
+	start, end int
+	text       string
+}
+
+// receiverWord matches the default receiver name as a word of a doc comment.
+var receiverWord = regexp.MustCompile(`\bc\b`)
+
+// renameReceiver returns the edits renaming the receiver c of the method fn, and
+// the references to it in its body and doc comment, to the name of the style.
+func (s Style) renameReceiver(fset *token.FileSet, fn *ast.FuncDecl) ([]styleEdit, error) {
+	if fn.Recv == nil || len(fn.Recv.List) != 1 || len(fn.Recv.List[0].Names) != 1 {
+		return nil, nil
+	}
+	recv := fn.Recv.List[0].Names[0]
+	typ := fn.Recv.List[0].Type
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	typeName, ok := typ.(*ast.Ident)
+	if !ok || recv.Name != "c" {
+		return nil, nil
+	}
+	name := s.receiverName(typeName.Name)
+	if name == recv.Name {
+		return nil, nil
+	}
+	edits := []styleEdit{{fset.Position(recv.Pos()).Offset, fset.Position(recv.End()).Offset, name}}
+	var conflict error
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		ident, ok := n.(*ast.Ident)
+		if !ok || ident.Obj == nil {
+			return true
+		}
+		switch {
+		case ident.Obj == recv.Obj:
+			edits = append(edits, styleEdit{fset.Position(ident.Pos()).Offset, fset.Position(ident.End()).Offset, name})
+		case ident.Name == name && conflict == nil:
+			conflict = fmt.Errorf("style receiver %s of %s.%s conflicts with the variable %s declared in it", name, typeName.Name, fn.Name.Name, name)
+		}
+		return true
+	})
+	if conflict != nil {
+		return nil, conflict
+	}
+	if fn.Doc != nil {
+		for _, c := range fn.Doc.List {
+			start := fset.Position(c.Pos()).Offset
+			for _, m := range receiverWord.FindAllStringIndex(c.Text, -1) {
+				edits = append(edits, styleEdit{start + m[0], start + m[1], name})
+			}
+		}
+	}
+	return edits, nil
+}
+
+// joinErrors returns the edits rewriting the fmt.Errorf calls of fn that wrap an
+// error with %w to errors.Join calls: fmt.Errorf("reading %s: %w", path, err) becomes
+// errors.Join(fmt.Errorf("reading %s", path), err).
+func joinErrors(fset *token.FileSet, src []byte, fn *ast.FuncDecl) []styleEdit {
+	var edits []styleEdit
+	ast.Inspect(fn, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || !isFmtErrorf(call) || len(call.Args) == 0 {
+			return true
+		}
+		text := func(e ast.Expr) string {
+			return string(src[fset.Position(e.Pos()).Offset:fset.Position(e.End()).Offset])
+		}
+		if joined, ok := joinedErrorf(call, text); ok {
+			edits = append(edits, styleEdit{fset.Position(call.Pos()).Offset, fset.Position(call.End()).Offset, joined})
+			return false
+		}
+		return true
+	})
+	return edits
+}
+
+func isFmtErrorf(call *ast.CallExpr) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Errorf" {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	return ok && pkg.Name == "fmt" && pkg.Obj == nil
+}
+
+// joinedErrorf returns the errors.Join call replacing call, a fmt.Errorf call, or
+// false if it doesn't wrap exactly one error or its format isn't a literal.
+func joinedErrorf(call *ast.CallExpr, text func(ast.Expr) string) (string, bool) {
+	lit, ok := call.Args[0].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING || call.Ellipsis.IsValid() {
+		return "", false
+	}
+	format, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return "", false
+	}
+	verbs, ok := formatVerbs(format)
+	if !ok || len(verbs) != len(call.Args)-1 {
+		return "", false
+	}
+	wrapped := -1
+	for i, v := range verbs {
+		if format[v.end-1] != 'w' {
+			continue
+		}
+		if wrapped >= 0 {
+			return "", false
+		}
+		wrapped = i
+	}
+	if wrapped < 0 {
+		return "", false
+	}
+	args := call.Args[1:]
+	before := strings.TrimRight(format[:verbs[wrapped].start], " :.")
+	after := strings.TrimLeft(format[verbs[wrapped].end:], " :.")
+	var parts []string
+	if part := errorExpr(before, args[:wrapped], text); part != "" {
+		parts = append(parts, part)
+	}
+	parts = append(parts, text(args[wrapped]))
+	if part := errorExpr(after, args[wrapped+1:], text); part != "" {
+		parts = append(parts, part)
+	}
+	return "errors.Join(" + strings.Join(parts, ", ") + ")", true
+}
+
+// errorExpr returns an expression creating an error with the message format, or ""
+// for an empty one.
+func errorExpr(format string, args []ast.Expr, text func(ast.Expr) string) string {
+	if format == "" {
+		return ""
+	}
+	if len(args) == 0 {
+		return "errors.New(" + strconv.Quote(strings.ReplaceAll(format, "%%", "%")) + ")"
+	}
+	var b bytes.Buffer
+	b.WriteString("fmt.Errorf(" + strconv.Quote(format))
+	for _, arg := range args {
+		b.WriteString(", " + text(arg))
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+// formatVerb is the position of a verb, such as %q or %-8s, in a format string.
+type formatVerb struct {
+	start, end int
+}
+
+// formatVerbs returns the verbs of format that take an argument, or false if one takes
+// its width or precision from an argument or selects its argument by index.
+func formatVerbs(format string) ([]formatVerb, bool) {
+	var verbs []formatVerb
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		start := i
+		for i++; i < len(format) && strings.IndexByte("+-# 0123456789.", format[i]) >= 0; i++ {
+		}
+		if i == len(format) {
+			return nil, false
+		}
+		switch format[i] {
+		case '%':
+			continue
+		case '*', '[':
+			return nil, false
+		}
+		verbs = append(verbs, formatVerb{start, i + 1})
+	}
+	return verbs, true
+}
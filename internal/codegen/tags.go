@@ -0,0 +1,111 @@
+package codegen
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// StructTag returns the unquoted tag of f.
+func (f FieldInfo) StructTag() reflect.StructTag {
+	if f.Tag == "" {
+		return ""
+	}
+	tag, err := strconv.Unquote(f.Tag)
+	if err != nil {
+		return ""
+	}
+	return reflect.StructTag(tag)
+}
+
+// OptionTagKey is the struct tag key holding per-field generator options as a
+// comma-separated list of name=value entries, e.g. `sudo:"flag=service.port,merge=replace"`.
+const OptionTagKey = "sudo"
+
+// TagOption returns the value of the named option in the sudo tag of f.
+func (f FieldInfo) TagOption(name string) (string, bool) {
+	for _, entry := range strings.Split(f.StructTag().Get(OptionTagKey), ",") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(entry), name+"="); ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// TagFlag reports whether the sudo tag of f lists the named option without a value,
+// as in `sudo:"secret"`.
+func (f FieldInfo) TagFlag(name string) bool {
+	return HasTagFlag(f.StructTag(), name)
+}
+
+// HasTagFlag reports whether the sudo entry of tag lists the named option without a value.
+func HasTagFlag(tag reflect.StructTag, name string) bool {
+	for _, entry := range strings.Split(tag.Get(OptionTagKey), ",") {
+		if strings.TrimSpace(entry) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SecretOption is the sudo tag option marking a field that holds a credential, such
+// as a password or API key. Generators redact, mask or compare such fields in
+// constant time as their options ask.
+const SecretOption = "secret"
+
+// DefaultTagSource is the tag key partial tags are derived from when none is configured.
+const DefaultTagSource = "json"
+
+// PartialTag returns the struct tag literal for the partial version of f.
+// The original tag is preserved, and each key in keys that is not already present
+// is added with the value of the source tag key (or the field name if unset).
+// An empty source means DefaultTagSource.
+func PartialTag(f FieldInfo, keys []string, source string) string {
+	if len(keys) == 0 {
+		return f.Tag
+	}
+	if source == "" {
+		source = DefaultTagSource
+	}
+	tag := f.StructTag()
+	parts := tagParts(tag)
+	derived, ok := tag.Lookup(source)
+	if !ok {
+		derived = f.Name
+	}
+	for _, key := range keys {
+		if _, exists := tag.Lookup(key); exists {
+			continue
+		}
+		parts = append(parts, key+":"+strconv.Quote(derived))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "`" + strings.Join(parts, " ") + "`"
+}
+
+// tagParts splits a struct tag into its key:"value" pairs, preserving order.
+func tagParts(tag reflect.StructTag) []string {
+	var parts []string
+	s := strings.TrimSpace(string(tag))
+	for s != "" {
+		i := strings.Index(s, `:"`)
+		if i <= 0 {
+			break
+		}
+		j := i + 2
+		for j < len(s) && s[j] != '"' {
+			if s[j] == '\\' {
+				j++
+			}
+			j++
+		}
+		if j >= len(s) {
+			break
+		}
+		parts = append(parts, s[:j+1])
+		s = strings.TrimSpace(s[j+1:])
+	}
+	return parts
+}
@@ -0,0 +1,116 @@
+package codegen
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+)
+
+// TypeParam is a type parameter of a generic struct, such as T in Box[T any].
+type TypeParam struct {
+	Name       string
+	Constraint string // As written, e.g. "any" or "Copier[T]"
+}
+
+// Copier reports whether p is constrained by Copier[p], so that generated code
+// copies its values with their Copy method rather than HelperCopyValue.
+func (p TypeParam) Copier() bool {
+	return p.Constraint == "Copier["+p.Name+"]"
+}
+
+// TypeParamsOf returns the type parameters declared by list, the TypeParams of a
+// type spec, or nil if it is nil.
+func TypeParamsOf(list *ast.FieldList) []TypeParam {
+	if list == nil {
+		return nil
+	}
+	var params []TypeParam
+	for _, field := range list.List {
+		for _, name := range field.Names {
+			params = append(params, TypeParam{Name: name.Name, Constraint: types.ExprString(field.Type)})
+		}
+	}
+	return params
+}
+
+// TypeParamList returns the declaration of params, like "[K comparable, V any]", or
+// "" if there are none.
+func TypeParamList(params []TypeParam) string {
+	if len(params) == 0 {
+		return ""
+	}
+	decls := make([]string, len(params))
+	for i, p := range params {
+		decls[i] = p.Name + " " + p.Constraint
+	}
+	return "[" + strings.Join(decls, ", ") + "]"
+}
+
+// TypeArgList returns params as the type arguments instantiating the generic type
+// with its own parameters, like "[K, V]" in the receiver of a method, or "" if there
+// are none.
+func TypeArgList(params []TypeParam) string {
+	if len(params) == 0 {
+		return ""
+	}
+	names := make([]string, len(params))
+	for i, p := range params {
+		names[i] = p.Name
+	}
+	return "[" + strings.Join(names, ", ") + "]"
+}
+
+// HeldTypeParam returns the type parameter among params that a field of type expr
+// holds as itself (T), through a pointer (*T), or as the element of a slice ([]T) or
+// the value of a map (map[K]T), and false if it holds none that way.
+func HeldTypeParam(expr ast.Expr, params []TypeParam) (TypeParam, bool) {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		expr = t.X
+	case *ast.ArrayType:
+		expr = t.Elt
+	case *ast.MapType:
+		expr = t.Value
+	}
+	if ident, ok := expr.(*ast.Ident); ok {
+		for _, p := range params {
+			if p.Name == ident.Name {
+				return p, true
+			}
+		}
+	}
+	return TypeParam{}, false
+}
+
+// MentionsTypeParam reports whether expr refers to any of params at any depth.
+func MentionsTypeParam(expr ast.Expr, params []TypeParam) bool {
+	found := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			return false // A type of another package, like other.T
+		case *ast.Ident:
+			for _, p := range params {
+				found = found || p.Name == n.Name
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// TypeParamReason is the reason fields refer to a type parameter in a way
+// HeldTypeParam doesn't support are skipped.
+const TypeParamReason = "type parameters are supported in fields of type T, *T, []T and map[K]T"
+
+// CopyValue returns the expression copying x, a value of the type parameter p: its
+// Copy method if p is constrained by Copier[p], and HelperCopyValue otherwise.
+func CopyValue(p TypeParam, x string) string {
+	if p.Copier() {
+		if strings.HasPrefix(x, "*") {
+			x = "(" + x + ")"
+		}
+		return x + ".Copy()"
+	}
+	return HelperCopyValue + "(" + x + ")"
+}
@@ -1,36 +1,82 @@
 // Package codegen provides shared types and utilities for code generation tools.
 package codegen
 
-import "go/ast"
+import (
+	"fmt"
+	"go/ast"
+	"path"
+	"strings"
+)
 
 // StructInfo holds information about a parsed struct type.
 type StructInfo struct {
-	Name       string
-	Fields     []FieldInfo
-	Imports    []ImportInfo
-	SourceFile string // The file where this struct was found (for nested structs)
-	Package    string // Package name if this is an external package struct (e.g., "duration")
-	ImportPath string // Full import path for external package structs
+	Name        string
+	Fields      []FieldInfo
+	Imports     []ImportInfo
+	SourceFile  string             // The file where this struct was found (for nested structs)
+	Package     string             // Package name or import alias if this is an external package struct (e.g., "duration")
+	ImportPath  string             // Full import path for external package structs
+	Unexported  bool               // Fields include unexported ones (see ParseStructUnexported)
+	Unsupported []UnsupportedField // Fields left out of Fields because no generator can handle them
+	TypeParams  []TypeParam        // Type parameters of a generic struct
+	Pos         string             // Position of the declaration, file:line:column
+}
+
+// Generic reports whether the struct declares type parameters.
+func (s *StructInfo) Generic() bool {
+	return len(s.TypeParams) > 0
 }
 
 // FieldInfo holds information about a struct field.
 type FieldInfo struct {
 	Name           string
-	Type           string     // Full type string (e.g., "[]string", "map[string]any")
-	TypeExpr       ast.Expr   // Original AST expression
-	TypeName       string     // Base type name (e.g., "string", "Tag")
-	TypePkg        string     // Package prefix if any (e.g., "time" for time.Time)
-	IsPointer      bool       // Field is a pointer type
-	IsSlice        bool       // Field is a slice
-	IsMap          bool       // Field is a map
-	IsStruct       bool       // Field is a named struct type (not basic)
-	MapKeyType     string     // Key type for maps
-	MapValType     string     // Value type for maps
-	SliceType      string     // Element type for slices
-	Tag            string     // Struct tag
-	NeedsDeep      bool       // Requires deep copy (for copy generator)
-	StructTypeName string     // Name of struct type for calling methods
-	SliceElemIsPtr bool       // Slice element is pointer to struct
+	Pos            string       // Position of the field name, file:line:column
+	Type           string       // Full type string (e.g., "[]string", "map[string]any")
+	TypeExpr       ast.Expr     // Original AST expression
+	TypeName       string       // Base type name (e.g., "string", "Tag")
+	TypePkg        string       // Package prefix if any (e.g., "time" for time.Time)
+	IsPointer      bool         // Field is a pointer type
+	IsSlice        bool         // Field is a slice
+	IsBytes        bool         // Field is a []byte, copied with bytes.Clone and compared with bytes.Equal
+	IsMap          bool         // Field is a map
+	IsStruct       bool         // Field is a named struct type (not basic)
+	MapKeyType     string       // Key type for maps
+	MapValType     string       // Value type for maps
+	SliceType      string       // Element type for slices
+	Tag            string       // Struct tag
+	NeedsDeep      bool         // Requires deep copy (for copy generator)
+	StructTypeName string       // Name of struct type for calling methods
+	SliceElemIsPtr bool         // Slice element is pointer to struct
+	Default        string       // Declared default from a default:"..." tag or "Default: ..." comment
+	Doc            string       // Doc or line comment of the field, without a "Default: ..." line
+	Leading        []string     // Comments above the field in the source struct as written, "" for blank lines
+	Trailing       string       // Line comment of the field in the source struct, as written
+	IsValue        bool         // Opaque type handled as a single value (see ValueTypes)
+	Secret         bool         // Tagged sudo:"secret" (see SecretOption)
+	Recipe         *CloneRecipe // Clone and comparison of a type with pointer semantics, like *big.Int (see CloneTypes)
+	TypeParam      *TypeParam   // Type parameter of the struct the field holds, like T in []T
+}
+
+// HoldsTime reports whether f is a time.Time, a pointer to one, or a slice or map of
+// them.
+func (f FieldInfo) HoldsTime() bool {
+	switch {
+	case f.IsSlice:
+		return f.SliceType == "time.Time" && !strings.HasPrefix(f.Type, "[]*")
+	case f.IsMap:
+		return f.MapValType == "time.Time" && !strings.HasSuffix(f.Type, "*time.Time")
+	}
+	return f.TypePkg == "time" && f.TypeName == "Time"
+}
+
+// markTypeParam marks f as holding the type parameter p, clearing the struct
+// information that would otherwise make generators recurse into it.
+func (f *FieldInfo) markTypeParam(p TypeParam) {
+	f.TypeParam = &p
+	f.IsStruct = false
+	f.StructTypeName = ""
+	f.SliceElemIsPtr = false
+	f.NeedsDeep = false
 }
 
 // ImportInfo holds information about an import.
@@ -41,12 +87,160 @@ type ImportInfo struct {
 
 // GeneratorConfig holds common configuration for generators.
 type GeneratorConfig struct {
-	TypeName     string
-	SourceFile   string
-	SourceDir    string
-	SourcePkg    string
-	OutputDir    string
-	OutputPkg    string
-	GenerateTest bool
-	GenerateJSON bool // For layerbroker: generate JSON marshalling methods
+	TypeName             string
+	SourceFile           string
+	Source               []byte // Source contents read from stdin; nil to read SourceFile from disk
+	SourceDir            string
+	SourcePkg            string
+	OutputDir            string
+	OutputPkg            string
+	GenerateTest         bool
+	IncludeUnexported    bool         // For copy and equals: also process unexported fields
+	GenerateExplainDiff  bool         // For equals: also generate Diff and ExplainNotEqual
+	ConstantTimeSecrets  bool         // For equals: compare secret string and []byte fields in constant time
+	FloatEpsilon         string       // For equals: tolerance of float comparisons, as written; "" compares them exactly
+	SkipNested           bool         // For equals: skip slices and maps of slices or maps with a warning instead of failing
+	ValidateMethod       string       // For options: method New{Type} validates the value with; "" for Validate, if declared
+	UTCTimes             bool         // For merge and equals: store time.Time values in UTC and compare them all with Equal
+	RedactSecrets        bool         // For copy: also generate Redacted, a copy with secret fields cleared
+	EncryptSecrets       bool         // For copy: also generate EncryptSecrets and DecryptSecrets, transforming secret fields with a keyring
+	GenerateWith         bool         // For copy: also generate With and With{Field} helpers returning modified copies
+	GenerateBench        bool         // For copy, merge and equals: generate _bench_test.go files with benchmarks over a populated value
+	GenerateExamples     bool         // For copy, merge and layerbroker: generate _example_test.go files with Example functions for godoc
+	GenerateJSON         bool         // For layerbroker: generate JSON marshalling methods
+	GenerateHTTP         bool         // For layerbroker: generate an http.Handler admin API
+	GenerateWatch        bool         // For layerbroker: generate an fsnotify file watcher feeding a layer
+	GenerateSIGHUP       bool         // For layerbroker: generate a SIGHUP handler reloading layers
+	GenerateTenant       bool         // For context: generate HTTP middleware resolving per-tenant overrides
+	GenerateProvenance   bool         // For layerbroker: record which layer provides each field (Explain)
+	GenerateAudit        bool         // For layerbroker: report every layer change to an audit sink option
+	GenerateTracing      bool         // For layerbroker and integrations: record OpenTelemetry spans (With{Type}TracerProvider)
+	History              int          // For layerbroker: number of merged configs kept for Rollback; 0 disables
+	LayerGroups          []string     // For layerbroker: names of the layer groups, lowest priority first
+	ConvertTo            string       // For convert: target type that TypeName is converted into
+	ConvertBidirectional bool         // For convert: also generate the reverse conversion
+	ProtoFile            string       // For convert: protoc-gen-go output whose messages are converted into TypeName
+	IntegrationSources   []string     // For integrations: KV stores to generate adapters for ("etcd", "consul")
+	DIFrameworks         []string     // For providers: dependency injection frameworks to declare the providers for ("fx", "wire")
+	Formats              []string     // For loader: file formats partials are decoded from ("json", "yaml", "toml", "hcl")
+	GenerateMapstructure bool         // For loader: generate a mapstructure decode hook and DecodePartialMap
+	LoaderExtends        bool         // For loader: documents may name a parent file with a top-level "extends" key
+	LoaderExpandEnv      bool         // For loader: expand ${VAR} and ${VAR:-default} in the string values of documents
+	LoaderSecretRefs     bool         // For loader: resolve secretref+scheme://path#key string values with resolvers
+	Project              ProjectFile  // Settings of the sudo-gen.yaml file of the source directory, such as the migrations of the loader
+	External             ExternalMode // For merge: how fields of struct types from other packages are merged
+	MergeStructs         MergeMode    // For merge: how partials of nested struct fields are applied (see MergeMode)
+	GenerateClear        bool         // For merge: partials can reset fields to their zero value (Clear, JSON null)
+	MigrateDeprecated    bool         // For merge: ApplyPartial sets the replacements of deprecated fields
+	GenerateMergePatch   bool         // For merge: generate JSON Merge Patch (RFC 7386) methods on the root type
+	GenerateJSONPatch    bool         // For merge: generate Diff{Type}AsJSONPatch, a JSON Patch (RFC 6902) diff
+	Tags                 []string     // Tag keys to emit on every partial field (e.g. "yaml", "mapstructure")
+	TagSource            string       // Tag key that emitted tags are derived from (default "json")
+	ValueTypes           []string     // Types to treat as opaque values in addition to those with marshaling methods
+	CloneFuncs           []string     // Type=Func functions copy uses to clone values of types (see CloneTypes)
+	EqualFuncs           []string     // Type=Func functions equals uses to compare values of types (see CloneTypes)
+	Fields               []string     // If set, only these fields are generated (see FieldSelection)
+	ExcludeFields        []string     // Fields that are never generated (see FieldSelection)
+	MethodPrefix         string       // Prepended to the names of generated methods on config structs (see Method)
+	Banner               Banner       // License header, build constraint and generated comment of every file
+	TemplatesDir         string       // Directory of templates overriding the embedded ones, named after the generated files (see TemplateName)
+	Implements           Implements   // Interfaces the type is asserted to implement in a generated file (-implements)
+	Strict               bool         // Fail on fields of unsupported types instead of skipping them (see CheckUnsupported)
+	TODOs                []string     // TODO comments added to MainFile, for skipped fields
+	MainFile             string       // Generated file holding the TODOs and the -implements assertions; empty adds the TODOs to every non-test file
+	Mode                 OutputMode
+	Force                bool                                    // In ModeWrite, rewrite files even if their content is unchanged
+	Capture              func(path string, content []byte) error // Receives generated files in ModeCapture
+	Index                *PackageIndex                           // Parsed source packages shared by the subtools of a run; nil parses on demand
 }
+
+// Method returns the name of a generated method with the configured MethodPrefix:
+// GenCopy for Copy with -prefix=Gen. Templates call it as {{method "Copy"}}.
+func (c GeneratorConfig) Method(name string) string {
+	return c.MethodPrefix + name
+}
+
+// DefaultPartialSuffix names the partial types of merge when sudo-gen.yaml doesn't
+// set partial-suffix.
+const DefaultPartialSuffix = "Partial"
+
+// Partial returns the name of the partial type merge generates for the struct name
+// with the partial-suffix of sudo-gen.yaml: ConfigPatch for Config with Patch.
+// Functions named after a partial type, such as LoadConfigPatchFile, are named after
+// this too. Templates call it as {{partial .TypeName}}.
+func (c GeneratorConfig) Partial(name string) string {
+	return partialName(name, c.Project.PartialSuffix)
+}
+
+func partialName(name, suffix string) string {
+	if suffix == "" {
+		return name + DefaultPartialSuffix
+	}
+	return name + suffix
+}
+
+// CrossPackage reports whether the output goes into another package than the source
+// types (-package). Generated code can't declare methods on the types there or reach
+// their unexported fields, so generators supporting it generate functions instead.
+func (c GeneratorConfig) CrossPackage() bool {
+	return c.OutputPkg != c.SourcePkg
+}
+
+// SourceImport returns the import of the source package for code generated into
+// another package (see CrossPackage), and the qualifier of its types, like "config.".
+func (c GeneratorConfig) SourceImport() (ImportInfo, string, error) {
+	importPath, err := ImportPathForDir(c.SourceDir)
+	if err != nil {
+		return ImportInfo{}, "", fmt.Errorf("resolving the import path of package %s: %w", c.SourcePkg, err)
+	}
+	imp := ImportInfo{Path: importPath}
+	if path.Base(importPath) != c.SourcePkg {
+		imp.Alias = c.SourcePkg
+	}
+	return imp, c.SourcePkg + ".", nil
+}
+
+// ExternalMode controls how the merge generator handles fields whose type is a struct
+// from another package.
+type ExternalMode string
+
+const (
+	// ExternalPassthrough merges external structs as whole values, like scalars. It is
+	// the default.
+	ExternalPassthrough ExternalMode = "passthrough"
+	// ExternalPartial generates a partial type and apply functions for each external
+	// struct, named after the package it is imported as (DurationTimestampPartial).
+	ExternalPartial ExternalMode = "partial"
+	// ExternalError fails generation when an external struct field is found.
+	ExternalError ExternalMode = "error"
+)
+
+// MergeMode controls how ApplyPartial applies the partial of a nested struct field.
+// A field's sudo:"merge=..." tag takes precedence over GeneratorConfig.MergeStructs.
+type MergeMode string
+
+const (
+	// MergeDeep applies the nested partial field by field, keeping the fields of the
+	// existing struct that the partial doesn't set. It is the default.
+	MergeDeep MergeMode = "deep"
+	// MergeReplace replaces the whole nested struct with one built from the partial
+	// alone, resetting the fields the partial doesn't set to their zero values.
+	MergeReplace MergeMode = "replace"
+)
+
+// OutputMode controls what happens to generated files.
+type OutputMode int
+
+const (
+	// ModeWrite writes generated files to disk.
+	ModeWrite OutputMode = iota
+	// ModeDryRun prints the files that would be written without touching them.
+	ModeDryRun
+	// ModeDiff prints a unified diff against the existing files without touching them.
+	ModeDiff
+	// ModeCapture hands generated code to GeneratorConfig.Capture instead of writing files.
+	ModeCapture
+	// ModeDumpData writes the data of each template to stdout as JSON (see TemplateDump)
+	// instead of generating code.
+	ModeDumpData
+)
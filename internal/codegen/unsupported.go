@@ -0,0 +1,116 @@
+package codegen
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"os"
+	"slices"
+	"strings"
+)
+
+// UnsupportedField is a struct field no generator can handle, such as a func or a
+// fixed-size array. Parsing leaves such fields out of StructInfo.Fields and records
+// them in StructInfo.Unsupported instead, so that generators report them rather
+// than generate wrong code.
+type UnsupportedField struct {
+	Struct string
+	Field  FieldInfo // Only Name, Type and Tag are set; Name is the type of an embedded field
+	Pos    string    // file:line of the field
+	Reason string
+}
+
+func (u UnsupportedField) String() string {
+	return fmt.Sprintf("%s: %s.%s: %s", u.Pos, u.Struct, u.Field.Name, u.Reason)
+}
+
+// UnsupportedType returns why fields of type expr can't be generated for, or ""
+// if they can.
+func UnsupportedType(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.FuncType:
+		return "func types are not supported"
+	case *ast.ChanType:
+		return "channel types are not supported"
+	case *ast.StructType:
+		return "anonymous struct types are not supported; declare a named type"
+	case *ast.IndexExpr, *ast.IndexListExpr:
+		return "instantiated generic types are not supported"
+	case *ast.ArrayType:
+		if t.Len != nil {
+			return "fixed-size arrays are not supported; use a slice"
+		}
+		return UnsupportedType(t.Elt)
+	case *ast.MapType:
+		if reason := UnsupportedType(t.Key); reason != "" {
+			return reason
+		}
+		return UnsupportedType(t.Value)
+	case *ast.StarExpr:
+		return UnsupportedType(t.X)
+	case *ast.ParenExpr:
+		return UnsupportedType(t.X)
+	}
+	return ""
+}
+
+// SkipFields moves the fields of structs a generator can't handle, those for which
+// reason returns why, to their Unsupported fields, for generators supporting fewer
+// types than the parser.
+func SkipFields(structs []*StructInfo, reason func(FieldInfo) string) {
+	for _, st := range structs {
+		fields := st.Fields[:0]
+		for _, f := range st.Fields {
+			r := reason(f)
+			if r == "" {
+				fields = append(fields, f)
+				continue
+			}
+			st.Unsupported = append(st.Unsupported, UnsupportedField{
+				Struct: st.Name,
+				Field:  FieldInfo{Name: f.Name, Type: f.Type, Tag: f.Tag},
+				Pos:    f.Pos,
+				Reason: r,
+			})
+		}
+		st.Fields = fields
+	}
+}
+
+// UnsupportedFields returns the unsupported fields of structs.
+func UnsupportedFields(structs []*StructInfo) []UnsupportedField {
+	var fields []UnsupportedField
+	for _, st := range structs {
+		fields = append(fields, st.Unsupported...)
+	}
+	return fields
+}
+
+// CheckUnsupported handles the fields generator skips because it can't handle
+// them. In strict mode they fail generation, with the position of each. Otherwise
+// each is printed as a warning on stderr, and the returned config adds a TODO
+// comment naming it to every file generated with it. Fields cfg already has a TODO
+// for, because a generator running this one reported them, aren't repeated.
+func CheckUnsupported(cfg GeneratorConfig, generator string, fields []UnsupportedField) (GeneratorConfig, error) {
+	if len(fields) == 0 {
+		return cfg, nil
+	}
+	if cfg.Strict {
+		msgs := make([]string, len(fields))
+		for i, f := range fields {
+			msgs[i] = f.String()
+		}
+		return cfg, errors.New("unsupported fields (remove -strict to skip them):\n\t" + strings.Join(msgs, "\n\t"))
+	}
+	todos := slices.Clone(cfg.TODOs)
+	for _, f := range fields {
+		todo := fmt.Sprintf("%s.%s is not generated: %s", f.Struct, f.Field.Name, f.Reason)
+		if slices.Contains(todos, todo) {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "warning: %s (skipped by %s)\n", f, generator)
+		todos = append(todos, todo)
+	}
+	cfg.TODOs = todos
+	return cfg, nil
+}
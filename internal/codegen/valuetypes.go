@@ -0,0 +1,166 @@
+package codegen
+
+import (
+	"go/types"
+	"path/filepath"
+	"strings"
+)
+
+// valueMethods are the marshaling methods that mark a type as opaque. Types that
+// control their own encoding (uuid.UUID, netip.Addr, time.Time, ...) are copied,
+// compared, and merged as a single value instead of field by field.
+var valueMethods = []string{
+	"MarshalText", "UnmarshalText",
+	"MarshalJSON", "UnmarshalJSON",
+	"MarshalBinary", "UnmarshalBinary",
+}
+
+// ValueTypes decides which named types have value semantics. A nil *ValueTypes
+// treats every type as a regular struct.
+type ValueTypes struct {
+	dir    string
+	listed map[string]bool
+	index  *PackageIndex // Type-checks packages for their method sets
+}
+
+// NewValueTypes returns a ValueTypes for a source package in dir. Each entry in
+// listed names a type to treat as a value regardless of its methods, either by
+// local name ("Secret"), package-qualified name ("uuid.UUID"), or import path
+// ("github.com/google/uuid.UUID"). Packages are type-checked through index, or
+// through a ValueTypes' own index if it is nil.
+func NewValueTypes(index *PackageIndex, dir string, listed []string) *ValueTypes {
+	if index == nil {
+		index = NewPackageIndex()
+	}
+	v := &ValueTypes{dir: dir, listed: make(map[string]bool), index: index}
+	for _, name := range listed {
+		v.listed[name] = true
+	}
+	return v
+}
+
+// Apply marks the fields of info whose type has value semantics, clearing the
+// struct information that would otherwise make generators recurse into them.
+func (v *ValueTypes) Apply(info *StructInfo) {
+	if v == nil {
+		return
+	}
+	dir := v.dir
+	if info.ImportPath != "" {
+		dir = resolveImportPath(v.dir, info.ImportPath)
+	}
+	for i := range info.Fields {
+		f := &info.Fields[i]
+		if !v.isValueField(dir, info.Imports, *f) {
+			continue
+		}
+		f.IsValue = true
+		f.IsStruct = false
+		f.StructTypeName = ""
+		f.SliceElemIsPtr = false
+		f.NeedsDeep = false
+	}
+}
+
+func (v *ValueTypes) isValueField(dir string, imports []ImportInfo, f FieldInfo) bool {
+	switch {
+	case f.IsSlice:
+		return v.isValueName(dir, imports, strings.TrimPrefix(f.SliceType, "*"))
+	case f.IsMap:
+		return v.isValueName(dir, imports, strings.TrimPrefix(f.MapValType, "*"))
+	case f.TypePkg != "":
+		return v.IsValue(dir, imports, f.TypePkg, f.TypeName)
+	case f.StructTypeName != "":
+		return v.IsValue(dir, imports, "", f.StructTypeName)
+	}
+	return false
+}
+
+func (v *ValueTypes) isValueName(dir string, imports []ImportInfo, name string) bool {
+	if isBasicType(name) || name == "" {
+		return false
+	}
+	if pkg, typeName, ok := strings.Cut(name, "."); ok {
+		return v.IsValue(dir, imports, pkg, typeName)
+	}
+	return v.IsValue(dir, imports, "", name)
+}
+
+// IsValue reports whether the type pkg.name, as referenced from a file in dir with
+// the given imports, has value semantics. An empty pkg means a type declared in dir.
+func (v *ValueTypes) IsValue(dir string, imports []ImportInfo, pkg, name string) bool {
+	if v == nil {
+		return false
+	}
+	if pkg == "" {
+		return v.listed[name] || v.hasValueMethods(dir, name) || v.isDefinedBasic(dir, name)
+	}
+	path := importPathFor(imports, pkg)
+	if v.listed[pkg+"."+name] || (path != "" && v.listed[path+"."+name]) {
+		return true
+	}
+	pkgDir := TypeDir(v.dir, imports, pkg)
+	if pkgDir == "" {
+		return false
+	}
+	return v.hasValueMethods(pkgDir, name)
+}
+
+// hasValueMethods reports whether the named type declared in dir, or a pointer to
+// it, has any of valueMethods.
+func (v *ValueTypes) hasValueMethods(dir, name string) bool {
+	pkg := v.index.Types(dir)
+	if pkg == nil {
+		return false
+	}
+	obj, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return false
+	}
+	mset := types.NewMethodSet(types.NewPointer(obj.Type()))
+	for _, m := range valueMethods {
+		if mset.Lookup(pkg, m) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// isDefinedBasic reports whether the named type declared in dir is defined over a
+// basic type, like type LogLevel string, which holds a single value.
+func (v *ValueTypes) isDefinedBasic(dir, name string) bool {
+	pkg := v.index.Types(dir)
+	if pkg == nil {
+		return false
+	}
+	obj, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return false
+	}
+	_, ok = obj.Type().Underlying().(*types.Basic)
+	return ok
+}
+
+// emptyImporter satisfies imports with empty packages so a single package can be
+// type-checked without loading its dependencies.
+type emptyImporter struct{}
+
+func (emptyImporter) Import(path string) (*types.Package, error) {
+	pkg := types.NewPackage(path, filepath.Base(path))
+	pkg.MarkComplete()
+	return pkg, nil
+}
+
+// importPathFor resolves a package name used in a type expression to its import path.
+func importPathFor(imports []ImportInfo, pkg string) string {
+	for _, imp := range imports {
+		name := imp.Alias
+		if name == "" {
+			name = filepath.Base(imp.Path)
+		}
+		if name == pkg {
+			return imp.Path
+		}
+	}
+	return ""
+}
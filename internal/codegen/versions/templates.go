@@ -0,0 +1,151 @@
+package versions
+
+const versionsTemplate = `// Code generated by sudo-gen versions. DO NOT EDIT.
+
+// {{ident "load" .Base "AnyVersion"}} decodes a JSON document of any version of {{.Base}}, detecting the
+// version from its "{{.Latest.Key}}" key, and converts it up to the latest, {{.Latest.Type}}:
+//
+//	cfg, err := {{ident "load" .Base "AnyVersion"}}(data)
+//
+// Each version is converted into the next one field by field, then by a handwritten
+// function for the fields that changed, if the package declares one.
+//
+// # Dependencies
+//
+// The field by field conversions are generated alongside, by sudo-gen convert.
+package {{.Package}}
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// {{.Base}}LatestVersion is the version of {{.Latest.Type}}, the latest version of {{.Base}}.
+const {{.Base}}LatestVersion = {{.Latest.Number}}
+
+// {{ident "err" .Base "UnknownVersion"}} is returned for a document without a version of {{.Base}}.
+var {{ident "err" .Base "UnknownVersion"}} = errors.New("unknown {{.Base}} version")
+
+// {{ident "detect" .Base "Version"}} returns the version number of a JSON {{.Base}} document from
+// its "{{.Latest.Key}}" key: {{range $i, $v := .Versions}}{{if $i}}, {{end}}{{.Value}} for {{.Number}}{{end}}.
+func {{ident "detect" .Base "Version"}}(data []byte) (int, error) {
+	var doc struct {
+		Version *{{.Latest.FieldType}} ` + "`" + `json:"{{.Latest.Key}}"` + "`" + `
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return 0, err
+	}
+	if doc.Version == nil {
+		return 0, fmt.Errorf("%w: no {{.Latest.Key}} key", {{ident "err" .Base "UnknownVersion"}})
+	}
+	switch *doc.Version {
+{{- range .Versions}}
+	case {{.Value}}:
+		return {{.Number}}, nil
+{{- end}}
+	}
+	return 0, fmt.Errorf("%w: %v", {{ident "err" .Base "UnknownVersion"}}, *doc.Version)
+}
+{{- range $step := .Steps}}
+
+// {{.Name}} converts a {{.From.Type}} into a {{.To.Type}} with its {{.To.Field}} set to {{.To.Value}}.
+// Fields are converted by {{.Auto}}
+{{- if .HasHook}}, then by {{.Hook}}
+// for the fields that changed.
+{{- else}}.
+{{- end}}
+{{- if and (not .HasHook) (or .Unmapped .Dropped)}}
+//
+{{- with .Unmapped}}
+// Fields of {{$step.To.Type}} left zero: {{join . ", "}}.
+{{- end}}
+{{- with .Dropped}}
+// Fields of {{$step.From.Type}} that are dropped: {{join . ", "}}.
+{{- end}}
+// Declare func {{.Hook}}(in *{{.From.Type}}, out *{{.To.Type}}) error
+// to convert them.
+{{- end}}
+func {{.Name}}(in *{{.From.Type}}) (*{{.To.Type}}, error) {
+	out := {{.Auto}}(in)
+	if out == nil {
+		return nil, nil
+	}
+{{- if .HasHook}}
+	if err := {{.Hook}}(in, out); err != nil {
+		return nil, fmt.Errorf("converting {{$.Base}} v{{.From.Number}} to v{{.To.Number}}: %w", err)
+	}
+{{- end}}
+	out.{{.To.Field}} = {{.To.Value}}
+	return out, nil
+}
+{{- end}}
+
+// {{ident "load" .Base "AnyVersion"}} decodes a JSON document of any version of {{.Base}} and converts it
+// to {{.Latest.Type}}.
+func {{ident "load" .Base "AnyVersion"}}(data []byte) (*{{.Latest.Type}}, error) {
+	version, err := {{ident "detect" .Base "Version"}}(data)
+	if err != nil {
+		return nil, err
+	}
+	switch version {
+{{- range .Versions}}
+	case {{.Number}}:
+		v{{.Number}} := &{{.Type}}{}
+		if err := json.Unmarshal(data, v{{.Number}}); err != nil {
+			return nil, err
+		}
+{{- range $.StepsFrom .Number}}
+		v{{.To.Number}}, err := {{.Name}}(v{{.From.Number}})
+		if err != nil {
+			return nil, err
+		}
+{{- end}}
+		return v{{$.Latest.Number}}, nil
+{{- end}}
+	}
+	return nil, fmt.Errorf("%w: %d", {{ident "err" .Base "UnknownVersion"}}, version)
+}
+`
+
+const versionsTestTemplate = `// Code generated by sudo-gen versions. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test{{capitalize (ident "detect" .Base "Version")}}(t *testing.T) {
+	tests := []struct {
+		doc  string
+		want int
+	}{
+{{- range .Versions}}
+		{` + "`" + `{"{{.Key}}": {{.JSON}}}` + "`" + `, {{.Number}}},
+{{- end}}
+	}
+	for _, tt := range tests {
+		got, err := {{ident "detect" .Base "Version"}}([]byte(tt.doc))
+		if err != nil || got != tt.want {
+			t.Errorf("{{ident "detect" .Base "Version"}}(%s) = %d, %v, want %d", tt.doc, got, err, tt.want)
+		}
+	}
+	for _, doc := range []string{` + "`" + `{}` + "`" + `, ` + "`" + `{"{{.Latest.Key}}": {{.UnknownJSON}}}` + "`" + `} {
+		if _, err := {{ident "detect" .Base "Version"}}([]byte(doc)); !errors.Is(err, {{ident "err" .Base "UnknownVersion"}}) {
+			t.Errorf("{{ident "detect" .Base "Version"}}(%s): expected {{ident "err" .Base "UnknownVersion"}}, got %v", doc, err)
+		}
+	}
+}
+
+func Test{{capitalize (ident "load" .Base "AnyVersion")}}(t *testing.T) {
+{{- range .Versions}}
+	if got, err := {{ident "load" $.Base "AnyVersion"}}([]byte(` + "`" + `{"{{.Key}}": {{.JSON}}}` + "`" + `)); err != nil {
+		t.Errorf("version {{.Number}}: %v", err)
+	} else if got.{{$.Latest.Field}} != {{$.Latest.Value}} {
+		t.Errorf("version {{.Number}}: {{$.Latest.Field}} = %v, want %v", got.{{$.Latest.Field}}, {{$.Latest.Value}})
+	}
+{{- end}}
+}
+`
@@ -0,0 +1,268 @@
+// Package versions implements the versions code generation subtool.
+package versions
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/bobcob7/sudo-gen/internal/codegen"
+	"github.com/bobcob7/sudo-gen/internal/codegen/convert"
+)
+
+// VersionOption is the sudo tag option marking the field of each version of a config
+// that documents declare their version in, as in `sudo:"version"`. With a value, as in
+// `sudo:"version=example.com/v1"`, it sets the value of the version; otherwise a
+// string field holds "v1", "v2", ... and an integer field 1, 2, ...
+const VersionOption = "version"
+
+// Subtool implements the versions code generator.
+type Subtool struct{}
+
+// Name returns the subtool name.
+func (s *Subtool) Name() string { return "versions" }
+
+// Description returns the subtool description.
+func (s *Subtool) Description() string {
+	return "Generate version detection, conversion chains and LoadAnyVersion for ConfigV1, ConfigV2, ..."
+}
+
+// Doc describes the struct tags the subtool honors and the files it generates.
+func (s *Subtool) Doc() codegen.SubtoolDoc {
+	return codegen.SubtoolDoc{
+		Tags: []codegen.DocEntry{
+			{Name: `sudo:"version"`, Description: `Field documents declare their version in; sudo:"version=example.com/v1" sets its value`},
+			{Name: `json:"name"`, Description: "Key of the field in documents"},
+		},
+		Files: []codegen.DocEntry{
+			{Name: "{source}_versions.go", Description: "Detect{Base}Version, Convert{Base}V1ToV2, ... and Load{Base}AnyVersion"},
+			{Name: "{source}_versions_convert.go", Description: "Field by field conversions between the versions"},
+		},
+	}
+}
+
+var versionSuffix = regexp.MustCompile(`^(.+)V([0-9]+)$`)
+
+// Run executes the versions code generation. The versions are the structs of the
+// package named like the type, with any version number.
+func (s *Subtool) Run(cfg codegen.GeneratorConfig) error {
+	m := versionSuffix.FindStringSubmatch(cfg.TypeName)
+	if m == nil {
+		return fmt.Errorf("%s has no version suffix; name the versions of a config like ConfigV1 and ConfigV2", cfg.TypeName)
+	}
+	base := m[1]
+	pkg := cfg.Index.Types(cfg.SourceDir)
+	if pkg == nil {
+		return fmt.Errorf("type-checking the package in %s failed", cfg.SourceDir)
+	}
+	var versions []version
+	for _, name := range pkg.Scope().Names() {
+		vm := versionSuffix.FindStringSubmatch(name)
+		if vm == nil || vm[1] != base {
+			continue
+		}
+		if _, ok := pkg.Scope().Lookup(name).Type().Underlying().(*types.Struct); !ok {
+			continue
+		}
+		n, err := strconv.Atoi(vm[2])
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		versions = append(versions, version{Number: n, Type: name})
+	}
+	slices.SortFunc(versions, func(a, b version) int { return cmp.Compare(a.Number, b.Number) })
+	if len(versions) < 2 {
+		return fmt.Errorf("%s needs at least two versions, such as %sV1 and %sV2", base, base, base)
+	}
+	for i := range versions {
+		if i > 0 && versions[i].Number != versions[i-1].Number+1 {
+			return fmt.Errorf("%s has no version between %s and %s", base, versions[i-1].Type, versions[i].Type)
+		}
+		if err := versions[i].resolve(cfg); err != nil {
+			return err
+		}
+		if versions[i].FieldType != versions[0].FieldType || versions[i].Key != versions[0].Key {
+			return fmt.Errorf("%s.%s: version fields of %s must all be %s with json key %q", versions[i].Type, versions[i].Field, base, versions[0].FieldType, versions[0].Key)
+		}
+	}
+	baseName := strings.TrimSuffix(cfg.SourceFile, ".go")
+	var pairs [][2]string
+	for i := range versions[:len(versions)-1] {
+		pairs = append(pairs, [2]string{versions[i].Type, versions[i+1].Type})
+	}
+	conversions, err := convert.GenerateConversions(cfg, filepath.Join(cfg.OutputDir, baseName+"_versions_convert.go"), pairs...)
+	if err != nil {
+		return fmt.Errorf("generating conversions: %w", err)
+	}
+	data := templateData{Package: cfg.OutputPkg, Base: base, Versions: versions}
+	for i, c := range conversions {
+		from, to := versions[i], versions[i+1]
+		step := step{
+			Name:     ident("convert", base, fmt.Sprintf("V%dToV%d", from.Number, to.Number)),
+			Hook:     "convert" + capitalize(base) + fmt.Sprintf("V%dToV%dFields", from.Number, to.Number),
+			Auto:     c.Name,
+			From:     from,
+			To:       to,
+			Unmapped: slices.DeleteFunc(c.Unmapped, func(name string) bool { return name == to.Field }),
+			Dropped:  slices.DeleteFunc(c.Dropped, func(name string) bool { return name == from.Field }),
+		}
+		if _, ok := pkg.Scope().Lookup(step.Hook).(*types.Func); ok {
+			step.HasHook = true
+		}
+		data.Steps = append(data.Steps, step)
+	}
+	gen := codegen.NewTemplateGenerator(cfg, templateFuncs())
+	if err := gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_versions.go"), versionsTemplate, data); err != nil {
+		return err
+	}
+	if cfg.GenerateTest {
+		return gen.GenerateFile(filepath.Join(cfg.OutputDir, baseName+"_versions_test.go"), versionsTestTemplate, data)
+	}
+	return nil
+}
+
+type templateData struct {
+	Package  string
+	Base     string
+	Versions []version // Oldest first
+	Steps    []step    // Conversions from each version to the next
+}
+
+// Latest returns the latest version.
+func (d templateData) Latest() version {
+	return d.Versions[len(d.Versions)-1]
+}
+
+// StepsFrom returns the conversions from version n to the latest.
+func (d templateData) StepsFrom(n int) []step {
+	return d.Steps[n-d.Versions[0].Number:]
+}
+
+// UnknownJSON returns a JSON value of the version field that is no version, for
+// generated tests.
+func (d templateData) UnknownJSON() string {
+	if d.Latest().FieldType == "string" {
+		return `"no-such-version"`
+	}
+	unknown := 0
+	for _, v := range d.Versions {
+		n, _ := strconv.Atoi(v.Value)
+		unknown = max(unknown, n+1)
+	}
+	return strconv.Itoa(unknown)
+}
+
+// version is a version of a config and the field declaring it.
+type version struct {
+	Number    int
+	Type      string
+	Field     string
+	FieldType string // string, or an integer type
+	Key       string // json key of Field
+	Value     string // Go literal of the value of Field
+}
+
+// resolve finds the version field of v.
+func (v *version) resolve(cfg codegen.GeneratorConfig) error {
+	info, err := cfg.Index.ParseStruct(cfg.SourceDir, cfg.SourceFile, cfg.Source, v.Type)
+	if err != nil {
+		if info, err = cfg.Index.FindStructInPackage(cfg.SourceDir, v.Type); err != nil {
+			return err
+		}
+	}
+	for _, f := range info.Fields {
+		value, tagged := f.TagOption(VersionOption)
+		if !tagged && !f.TagFlag(VersionOption) {
+			continue
+		}
+		if v.Field != "" {
+			return fmt.Errorf("%s has more than one field tagged sudo:%q", v.Type, VersionOption)
+		}
+		v.Field, v.FieldType, v.Key = f.Name, f.Type, jsonKey(f)
+		switch {
+		case f.Type == "string" && tagged:
+			v.Value = strconv.Quote(value)
+		case f.Type == "string":
+			v.Value = strconv.Quote("v" + strconv.Itoa(v.Number))
+		case isInteger(f.Type) && tagged:
+			if _, err := strconv.Atoi(value); err != nil {
+				return fmt.Errorf("%s.%s: version %q is not an integer", v.Type, f.Name, value)
+			}
+			v.Value = value
+		case isInteger(f.Type):
+			v.Value = strconv.Itoa(v.Number)
+		default:
+			return fmt.Errorf("%s.%s: version fields must be strings or integers, not %s", v.Type, f.Name, f.Type)
+		}
+	}
+	if v.Field == "" {
+		return fmt.Errorf("%s has no field tagged sudo:%q holding its version", v.Type, VersionOption)
+	}
+	return nil
+}
+
+// JSON returns the value of the version field as JSON, for generated tests.
+func (v version) JSON() string {
+	if v.FieldType != "string" {
+		return v.Value
+	}
+	s, _ := strconv.Unquote(v.Value)
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+// step is the conversion of a version into the next one.
+type step struct {
+	Name     string // Generated conversion, setting the version field
+	Hook     string // Handwritten function converting the fields that changed
+	HasHook  bool   // Whether the package declares Hook
+	Auto     string // Conversion generated by convert, matching fields by name or json tag
+	From, To version
+	Unmapped []string // Fields of To that Auto leaves zero
+	Dropped  []string // Fields of From that Auto ignores
+}
+
+func jsonKey(f codegen.FieldInfo) string {
+	name, _, _ := strings.Cut(f.StructTag().Get("json"), ",")
+	if name == "" || name == "-" {
+		return f.Name
+	}
+	return name
+}
+
+func isInteger(name string) bool {
+	switch name {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+		return true
+	}
+	return false
+}
+
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"capitalize": capitalize,
+		"ident":      ident,
+		"join":       strings.Join,
+	}
+}
+
+func capitalize(name string) string {
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// ident builds a generated name such as DetectConfigVersion, keeping it unexported
+// (detectConfigVersion) when the type is unexported.
+func ident(verb, typeName, suffix string) string {
+	if ast.IsExported(typeName) {
+		return capitalize(verb) + typeName + suffix
+	}
+	return verb + capitalize(typeName) + suffix
+}
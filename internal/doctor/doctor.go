@@ -0,0 +1,431 @@
+// Package doctor implements sudo-gen doctor, which checks the go:generate
+// directives of a module and the files they generated, and reports each problem
+// along with the way to fix it.
+package doctor
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bobcob7/sudo-gen/internal/codegen"
+)
+
+// Checker parses the arguments of a directive running subcommand like the
+// subcommand does, returning the struct type they name, if any.
+type Checker func(subcommand string, args []string) (typeName string, err error)
+
+// Doctor checks the directives and generated files of the packages in a directory.
+type Doctor struct {
+	Subcommands []string // Subcommands that generate code
+	Check       Checker
+	Version     string // Version of sudo-gen; files stamped by another version are stale
+}
+
+// directive is a go:generate directive running sudo-gen.
+type directive struct {
+	file       string
+	line       int
+	command    string // Command running sudo-gen, e.g. "go tool sudo-gen"
+	subcommand string
+	args       []string
+}
+
+// generatedFile is a file generated by sudo-gen.
+type generatedFile struct {
+	path string
+	src  []byte
+}
+
+// Run checks the packages in dir, and those below it if recursive, and returns the
+// problems found ordered by file and line.
+func (d *Doctor) Run(dir string, recursive bool) ([]codegen.Diagnostic, error) {
+	dirs, err := packageDirs(dir, recursive)
+	if err != nil {
+		return nil, err
+	}
+	var directives []directive
+	var generated []generatedFile
+	for _, pkgDir := range dirs {
+		ds, gs, err := scanDir(pkgDir)
+		if err != nil {
+			return nil, err
+		}
+		directives = append(directives, ds...)
+		generated = append(generated, gs...)
+	}
+	diags := checkEnvironment()
+	index := codegen.NewPackageIndex()
+	for _, dv := range directives {
+		diags = append(diags, d.checkDirective(index, dv)...)
+	}
+	diags = append(diags, checkInvocations(directives)...)
+	diags = append(diags, d.checkGenerated(generated, directives)...)
+	diags = append(diags, checkImports(generated)...)
+	slices.SortStableFunc(diags, func(a, b codegen.Diagnostic) int {
+		return cmp.Or(cmp.Compare(a.File, b.File), cmp.Compare(a.Line, b.Line))
+	})
+	return diags, nil
+}
+
+// packageDirs returns dir and, if recursive, the directories below it that go
+// ./... would match: vendor, testdata and directories starting with . or _ are
+// skipped, and so are nested modules.
+func packageDirs(dir string, recursive bool) ([]string, error) {
+	if !recursive {
+		return []string{dir}, nil
+	}
+	var dirs []string
+	err := filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if path != dir {
+			name := entry.Name()
+			if name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+		}
+		dirs = append(dirs, path)
+		return nil
+	})
+	return dirs, err
+}
+
+// scanDir returns the sudo-gen directives of the Go files in dir, and the files
+// in it that sudo-gen generated.
+func scanDir(dir string) ([]directive, []generatedFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	var directives []directive
+	var generated []generatedFile
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".go") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		if codegen.IsGenerated(src) {
+			generated = append(generated, generatedFile{path: path, src: src})
+			continue
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(src))
+		scanner.Buffer(nil, len(src)+1)
+		for line := 1; scanner.Scan(); line++ {
+			text := scanner.Text()
+			subcommand, args, ok := codegen.ParseDirective(text)
+			if !ok {
+				continue
+			}
+			command, _, _ := strings.Cut(strings.TrimPrefix(text, "//go:generate "), " "+subcommand)
+			directives = append(directives, directive{file: path, line: line, command: command, subcommand: subcommand, args: args})
+		}
+	}
+	return directives, generated, nil
+}
+
+// checkEnvironment checks GOFILE and GOPACKAGE, which go generate sets for the
+// file of a directive, when doctor itself is run by one.
+func checkEnvironment() []codegen.Diagnostic {
+	file, pkg := os.Getenv("GOFILE"), os.Getenv("GOPACKAGE")
+	hint := "run sudo-gen through go generate, which sets GOFILE and GOPACKAGE for the file of each directive"
+	switch {
+	case file == "" && pkg == "":
+		return nil
+	case file == "" || pkg == "":
+		return []codegen.Diagnostic{{Message: "only one of GOFILE and GOPACKAGE is set", Hint: hint}}
+	}
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return []codegen.Diagnostic{{Message: fmt.Sprintf("GOFILE names %s, which can't be read: %v", file, err), Hint: hint}}
+	}
+	name, err := codegen.ParsePackageName(src)
+	if err == nil && name != pkg {
+		return []codegen.Diagnostic{{File: file, Message: fmt.Sprintf("GOPACKAGE is %s, but %s declares package %s", pkg, file, name), Hint: hint}}
+	}
+	return nil
+}
+
+// checkDirective checks that the subcommand of dv exists, that its arguments
+// parse, and that the struct it generates for can be found.
+func (d *Doctor) checkDirective(index *codegen.PackageIndex, dv directive) []codegen.Diagnostic {
+	diag := codegen.Diagnostic{File: dv.file, Line: dv.line}
+	if dv.subcommand == "doctor" {
+		return nil
+	}
+	if !slices.Contains(d.Subcommands, dv.subcommand) {
+		diag.Message = fmt.Sprintf("unknown subcommand %q", dv.subcommand)
+		diag.Hint = "run sudo-gen -help for the subcommands"
+		if name := closest(dv.subcommand, d.Subcommands); name != "" {
+			diag.Hint = fmt.Sprintf("did you mean %q?", name)
+		}
+		return []codegen.Diagnostic{diag}
+	}
+	var diags []codegen.Diagnostic
+	if slices.Contains(dv.args, "-stdin") {
+		diag.Message = "-stdin reads the struct from standard input, which go generate doesn't provide"
+		diag.Hint = "drop -stdin; under go generate, sudo-gen reads the file of the directive"
+		diags = append(diags, diag)
+	}
+	typeName, err := d.Check(dv.subcommand, dv.args)
+	if err != nil {
+		diag.Message = err.Error()
+		diag.Hint = fmt.Sprintf("run sudo-gen help %s for its flags", dv.subcommand)
+		return append(diags, diag)
+	}
+	dir := filepath.Dir(dv.file)
+	if typeName == "" {
+		_, err = codegen.FindTypeAfterGenerateDirective(dir, filepath.Base(dv.file), dv.subcommand)
+		if err != nil {
+			_, err = codegen.FindTypeAfterLine(dv.file, dv.line)
+		}
+		if err != nil {
+			diag.Message = "no struct type follows the directive"
+			diag.Hint = "place the directive directly above the struct, or name it with -type"
+			diags = append(diags, diag)
+		}
+		return diags
+	}
+	// flagvalue generates for scalar types, so any type will do
+	pkg := index.Types(dir)
+	if pkg == nil {
+		return diags
+	}
+	if _, ok := pkg.Scope().Lookup(typeName).(*types.TypeName); !ok {
+		diag.Message = fmt.Sprintf("type %s is not declared in package %s", typeName, pkg.Name())
+		diag.Hint = "fix the name given with -type, or declare the type in the package"
+		diags = append(diags, diag)
+	}
+	return diags
+}
+
+// checkInvocations checks that the command the directives run sudo-gen with is
+// available: sudo-gen on $PATH, or a tool directive for go tool.
+func checkInvocations(directives []directive) []codegen.Diagnostic {
+	var diags []codegen.Diagnostic
+	checked := make(map[string]bool)
+	for _, dv := range directives {
+		switch {
+		case dv.command == "sudo-gen" && !checked[dv.command]:
+			checked[dv.command] = true
+			if _, err := exec.LookPath("sudo-gen"); err != nil {
+				diags = append(diags, codegen.Diagnostic{
+					File:    dv.file,
+					Line:    dv.line,
+					Message: "sudo-gen is not installed on $PATH",
+					Hint:    fmt.Sprintf("go install %s@latest, or run it with go tool (see sudo-gen init -invocation)", codegen.ModulePath),
+				})
+			}
+		case dv.command == "go tool sudo-gen":
+			gomod := findGoMod(filepath.Dir(dv.file))
+			if checked[gomod] {
+				continue
+			}
+			checked[gomod] = true
+			if !hasTool(gomod) {
+				diags = append(diags, codegen.Diagnostic{
+					File:    dv.file,
+					Line:    dv.line,
+					Message: "go tool sudo-gen needs a tool directive for sudo-gen in go.mod",
+					Hint:    fmt.Sprintf("go get -tool %s", codegen.ModulePath),
+				})
+			}
+		}
+	}
+	return diags
+}
+
+// findGoMod returns the go.mod of the module holding dir, or "" if there is none.
+func findGoMod(dir string) string {
+	for {
+		path := filepath.Join(dir, "go.mod")
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// hasTool reports whether the go.mod at path declares sudo-gen as a tool, on a
+// tool line or in a tool block.
+func hasTool(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	inBlock := false
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+		case inBlock && fields[0] == ")":
+			inBlock = false
+		case inBlock && fields[0] == codegen.ModulePath:
+			return true
+		case fields[0] == "tool" && len(fields) > 1:
+			if fields[1] == "(" {
+				inBlock = true
+			} else if fields[1] == codegen.ModulePath {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkGenerated finds generated files that are stale: unstamped, stamped by
+// another version of sudo-gen, or generated by a command no directive runs.
+func (d *Doctor) checkGenerated(generated []generatedFile, directives []directive) []codegen.Diagnostic {
+	commands := make(map[string]bool)
+	subcommands := make(map[string]bool)
+	for _, dv := range directives {
+		commands[codegen.CommandLine(dv.subcommand, dv.args)] = true
+		subcommands[dv.subcommand] = true
+	}
+	var diags []codegen.Diagnostic
+	for _, g := range generated {
+		diag := codegen.Diagnostic{File: g.path, Line: 1, Hint: "run go generate to regenerate it"}
+		stamp, ok := codegen.ReadStamp(g.src)
+		switch {
+		case !ok:
+			diag.Message = "generated by a version of sudo-gen that didn't stamp its files"
+		case d.Version != "(devel)" && stamp.Version != d.Version:
+			diag.Message = fmt.Sprintf("generated by sudo-gen %s, not %s", stamp.Version, d.Version)
+		case stamp.Command == "" || commands[stamp.Command]:
+			continue
+		default:
+			subcommand, _, _ := codegen.ParseDirective("//go:generate sudo-gen " + stamp.Command)
+			if subcommands[subcommand] {
+				diag.Message = fmt.Sprintf("generated by sudo-gen %s, which no directive runs anymore", stamp.Command)
+				diag.Hint = "run go generate, and delete the file if it isn't regenerated"
+			} else {
+				diag.Message = fmt.Sprintf("no directive runs sudo-gen %s, which generated it", subcommand)
+				diag.Hint = "delete the file, or restore the directive it came from"
+			}
+		}
+		diags = append(diags, diag)
+	}
+	return diags
+}
+
+// checkImports checks that the imports of generated files resolve with go list,
+// by directory.
+func checkImports(generated []generatedFile) []codegen.Diagnostic {
+	type importSite struct {
+		file string
+		line int
+	}
+	var dirs []string
+	sites := make(map[string]map[string]importSite) // By directory and import path
+	for _, g := range generated {
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, g.path, g.src, parser.ImportsOnly)
+		if err != nil {
+			continue
+		}
+		dir := filepath.Dir(g.path)
+		for _, spec := range f.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil || isStandard(path) {
+				continue
+			}
+			if sites[dir] == nil {
+				sites[dir] = make(map[string]importSite)
+				dirs = append(dirs, dir)
+			}
+			if _, ok := sites[dir][path]; !ok {
+				sites[dir][path] = importSite{g.path, fset.Position(spec.Pos()).Line}
+			}
+		}
+	}
+	var diags []codegen.Diagnostic
+	for _, dir := range dirs {
+		paths := slices.Sorted(maps.Keys(sites[dir]))
+		// -mod=readonly reports missing requirements rather than adding them to go.mod
+		cmd := exec.Command("go", append([]string{"list", "-mod=readonly", "-e", "-f", "{{.ImportPath}}\t{{if .Error}}{{.Error.Err}}{{end}}"}, paths...)...)
+		cmd.Dir = dir
+		out, err := cmd.Output()
+		if err != nil {
+			return append(diags, codegen.Diagnostic{File: dir, Message: fmt.Sprintf("go list failed: %v", err), Hint: "check that the go command is on $PATH"})
+		}
+		for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+			path, msg, _ := strings.Cut(line, "\t")
+			site, ok := sites[dir][path]
+			if !ok || msg == "" {
+				continue
+			}
+			diags = append(diags, codegen.Diagnostic{
+				File:    site.file,
+				Line:    site.line,
+				Message: fmt.Sprintf("import %q doesn't resolve: %s", path, msg),
+				Hint:    "go get " + path,
+			})
+		}
+	}
+	return diags
+}
+
+// isStandard reports whether path is in the standard library, whose import paths
+// have no dot in their first element.
+func isStandard(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
+
+// closest returns the name in names closest to name, if it is a likely typo.
+func closest(name string, names []string) string {
+	best, bestDist := "", 3
+	for _, n := range names {
+		if d := distance(name, n); d < bestDist {
+			best, bestDist = n, d
+		}
+	}
+	return best
+}
+
+// distance returns the Levenshtein distance between a and b.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,193 @@
+// Package lsphelper implements a line-delimited JSON protocol that lets editor
+// extensions offer sudo-gen code actions for the struct under the cursor.
+//
+// Each request is a single JSON object on its own line:
+//
+//	{"id": 1, "method": "codeActions", "file": "/abs/config.go", "line": 12}
+//	{"id": 2, "method": "preview", "file": "/abs/config.go", "line": 12, "generator": "copy"}
+//	{"id": 3, "method": "apply", "file": "/abs/config.go", "line": 12, "generator": "copy"}
+//
+// An optional "content" field carries the unsaved editor buffer for file, and an
+// optional "args" field the flags of the generator as in its go:generate directive,
+// such as ["-tests"]. Generators run with the settings of the
+// project's sudo-gen.yaml as under go generate, and apply writes files the same way.
+// Each request produces exactly one response line with the same id. Code actions
+// carry the go:generate directive an editor can insert above the struct, running
+// sudo-gen as the invocation setting of the project's sudo-gen.yaml says.
+package lsphelper
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/bobcob7/sudo-gen/internal/codegen"
+)
+
+// Runner runs the named generator with the flags args on the type cfg names in its
+// source, as go generate would for a directive with args above the type, emitting
+// the files in cfg.Mode.
+type Runner func(generator string, args []string, cfg codegen.GeneratorConfig) error
+
+// Server answers code action requests for a fixed set of generators.
+type Server struct {
+	Generators []string
+	Run        Runner
+	Version    string // Version of sudo-gen pinned by go run directives
+}
+
+// Request is a single code action request.
+type Request struct {
+	ID        int      `json:"id"`
+	Method    string   `json:"method"`
+	File      string   `json:"file"`
+	Line      int      `json:"line"`
+	Content   *string  `json:"content,omitempty"`
+	Generator string   `json:"generator,omitempty"`
+	Args      []string `json:"args,omitempty"` // Flags of the generator, as in its go:generate directive
+	Tests     bool     `json:"tests,omitempty"`
+}
+
+// Response is the reply to a Request.
+type Response struct {
+	ID      int      `json:"id"`
+	Type    string   `json:"type,omitempty"`
+	Actions []Action `json:"actions,omitempty"`
+	Files   []File   `json:"files,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// Action is a code action offered for the struct at the requested position.
+type Action struct {
+	Title     string `json:"title"`
+	Generator string `json:"generator"`
+	Directive string `json:"directive"` // go:generate line running the generator
+}
+
+// File is a generated file, either previewed or written.
+type File struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+// Serve reads requests from r until EOF and writes one response per request to w.
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	enc := json.NewEncoder(w)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var req Request
+		resp := Response{}
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			resp.Error = fmt.Sprintf("decoding request: %v", err)
+		} else {
+			resp = s.Handle(req)
+		}
+		if err := enc.Encode(resp); err != nil {
+			return fmt.Errorf("writing response: %w", err)
+		}
+	}
+	return scanner.Err()
+}
+
+// Handle answers a single request.
+func (s *Server) Handle(req Request) Response {
+	resp := Response{ID: req.ID}
+	var src []byte
+	if req.Content != nil {
+		src = []byte(*req.Content)
+	}
+	typeName, err := codegen.FindTypeAtLine(req.File, src, req.Line)
+	if err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+	resp.Type = typeName
+	switch req.Method {
+	case "codeActions":
+		project, err := codegen.FindProjectFile(filepath.Dir(req.File))
+		if err != nil {
+			resp.Error = err.Error()
+			return resp
+		}
+		for _, name := range s.Generators {
+			resp.Actions = append(resp.Actions, Action{
+				Title:     fmt.Sprintf("Generate %s for %s", name, typeName),
+				Generator: name,
+				Directive: codegen.Directive(project.Invocation, s.Version, name),
+			})
+		}
+	case "preview", "apply":
+		files, err := s.generate(req, src, typeName)
+		if err != nil {
+			resp.Error = err.Error()
+			return resp
+		}
+		if req.Method == "apply" {
+			if err := writeFiles(files); err != nil {
+				resp.Error = err.Error()
+				return resp
+			}
+		}
+		resp.Files = files
+	default:
+		resp.Error = fmt.Sprintf("unknown method: %s", req.Method)
+	}
+	return resp
+}
+
+// generate runs the requested generator and captures its output. src is nil
+// unless the request carried an unsaved buffer.
+func (s *Server) generate(req Request, src []byte, typeName string) ([]File, error) {
+	pkgSrc := src
+	if pkgSrc == nil {
+		var err error
+		pkgSrc, err = os.ReadFile(req.File)
+		if err != nil {
+			return nil, fmt.Errorf("reading file: %w", err)
+		}
+	}
+	pkgName, err := codegen.ParsePackageName(pkgSrc)
+	if err != nil {
+		return nil, err
+	}
+	var files []File
+	args := req.Args
+	if req.Tests {
+		args = append(slices.Clone(args), "-tests")
+	}
+	dir := filepath.Dir(req.File)
+	cfg := codegen.GeneratorConfig{
+		TypeName:   typeName,
+		SourceFile: filepath.Base(req.File),
+		Source:     src,
+		SourceDir:  dir,
+		SourcePkg:  pkgName,
+		Mode:       codegen.ModeCapture,
+		Capture: func(path string, content []byte) error {
+			files = append(files, File{Path: path, Content: string(content)})
+			return nil
+		},
+	}
+	if err := s.Run(req.Generator, args, cfg); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// writeFiles writes the captured files as generators do, leaving unchanged ones alone.
+func writeFiles(files []File) error {
+	for _, f := range files {
+		if _, err := codegen.WriteFile(f.Path, []byte(f.Content), false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+// Package scaffold implements sudo-gen init, which sets up a struct for the
+// layered-config pattern: it adds the recommended go:generate directives above the
+// struct, writes a sudo-gen.yaml for the module and, optionally, a wiring file
+// loading config files into the layers of a broker.
+package scaffold
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/bobcob7/sudo-gen/internal/codegen"
+)
+
+// Options configures Init.
+type Options struct {
+	File       string             // Go file declaring the struct
+	TypeName   string             // Struct to set up; the first struct of File if empty
+	Invocation codegen.Invocation // How the directives run sudo-gen
+	Version    string             // Version of sudo-gen pinned by go run directives
+	Wiring     bool               // Also write {source}_wiring.go, loading config files into a broker
+}
+
+// Directives are the subcommands and arguments of the recommended directives: the
+// broker, with the partial, merge, copy and equals code it runs, and the file loader.
+var Directives = [][]string{
+	{"layerbroker", "-tests"},
+	{"loader", "-tests"},
+}
+
+// Change is a file written by Init.
+type Change struct {
+	Path    string
+	Created bool // The file didn't exist before
+}
+
+// Init sets up the struct of opts and returns the files it wrote. Directives already
+// above the struct, an existing sudo-gen.yaml and an existing wiring file are left
+// as they are.
+func Init(opts Options) ([]Change, error) {
+	src, err := os.ReadFile(opts.File)
+	if err != nil {
+		return nil, fmt.Errorf("reading file: %w", err)
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, opts.File, src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("parsing file: %w", err)
+	}
+	decl, spec, err := findStruct(f, opts.TypeName)
+	if err != nil {
+		return nil, err
+	}
+	var changes []Change
+	if !hasDirective(decl) {
+		out, err := insertDirectives(fset, src, decl, spec, opts)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(opts.File, out, 0644); err != nil {
+			return nil, fmt.Errorf("writing file: %w", err)
+		}
+		changes = append(changes, Change{Path: opts.File})
+	}
+	dir := filepath.Dir(opts.File)
+	project, err := codegen.FindProjectFile(dir)
+	if err != nil {
+		return nil, err
+	}
+	if project.Path == "" {
+		path := filepath.Join(moduleRoot(dir), codegen.ProjectFileName)
+		if err := os.WriteFile(path, projectFile(opts.Invocation), 0644); err != nil {
+			return nil, fmt.Errorf("writing %s: %w", codegen.ProjectFileName, err)
+		}
+		changes = append(changes, Change{Path: path, Created: true})
+	}
+	if opts.Wiring {
+		path := strings.TrimSuffix(opts.File, ".go") + "_wiring.go"
+		written, err := writeWiring(path, f.Name.Name, spec.Name.Name, project)
+		if err != nil {
+			return nil, err
+		}
+		if written {
+			changes = append(changes, Change{Path: path, Created: true})
+		}
+	}
+	return changes, nil
+}
+
+// findStruct returns the declaration of the struct named typeName in f, or of its
+// first struct if typeName is empty.
+func findStruct(f *ast.File, typeName string) (*ast.GenDecl, *ast.TypeSpec, error) {
+	for _, decl := range f.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			if typeName != "" && typeSpec.Name.Name != typeName {
+				continue
+			}
+			if _, ok := typeSpec.Type.(*ast.StructType); ok {
+				return genDecl, typeSpec, nil
+			}
+			if typeName != "" {
+				return nil, nil, fmt.Errorf("type %s is not a struct", typeName)
+			}
+		}
+	}
+	if typeName != "" {
+		return nil, nil, fmt.Errorf("struct type %s not found", typeName)
+	}
+	return nil, nil, errors.New("no struct type found")
+}
+
+// hasDirective reports whether a sudo-gen directive is above decl already.
+func hasDirective(decl *ast.GenDecl) bool {
+	if decl.Doc == nil {
+		return false
+	}
+	for _, c := range decl.Doc.List {
+		if strings.HasPrefix(c.Text, "//go:generate ") && strings.Contains(c.Text, "sudo-gen") {
+			return true
+		}
+	}
+	return false
+}
+
+// insertDirectives returns src with the recommended directives inserted on the
+// lines above decl, below its doc comment. Structs of a grouped declaration are
+// named with -type, since the directives are above the group.
+func insertDirectives(fset *token.FileSet, src []byte, decl *ast.GenDecl, spec *ast.TypeSpec, opts Options) ([]byte, error) {
+	var lines bytes.Buffer
+	for _, args := range Directives {
+		if decl.Lparen.IsValid() {
+			args = append(args[:len(args):len(args)], "-type="+spec.Name.Name)
+		}
+		lines.WriteString(codegen.Directive(opts.Invocation, opts.Version, args...))
+		lines.WriteString("\n")
+	}
+	offset := fset.Position(decl.Pos()).Offset
+	offset = bytes.LastIndexByte(src[:offset], '\n') + 1
+	out := make([]byte, 0, len(src)+lines.Len())
+	out = append(out, src[:offset]...)
+	out = append(out, lines.Bytes()...)
+	out = append(out, src[offset:]...)
+	formatted, err := format.Source(out)
+	if err != nil {
+		return nil, fmt.Errorf("formatting %s: %w", opts.File, err)
+	}
+	return formatted, nil
+}
+
+// moduleRoot returns the closest directory to dir holding go.mod, or dir if none does.
+func moduleRoot(dir string) string {
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, "go.mod")); err == nil {
+			return d
+		}
+		if filepath.Dir(d) == d {
+			return dir
+		}
+	}
+}
+
+// projectFile returns the contents of a new sudo-gen.yaml.
+func projectFile(inv codegen.Invocation) []byte {
+	return []byte(`# Settings shared by the sudo-gen directives of this module
+
+# How go:generate directives run sudo-gen: binary, tool or run
+invocation: ` + string(inv) + `
+
+# Suffix of the partial types of merge, and of the functions named after them
+# partial-suffix: Partial
+
+# Keys of config documents that moved, renamed by the generated loader
+# migrations:
+#   Config:
+#     - from: old_key
+#       to: new.key
+`)
+}
+
+// writeWiring writes the wiring code of typeName to path, unless the file exists,
+// calling the loader functions named after the partial types of project.
+func writeWiring(path, pkgName, typeName string, project codegen.ProjectFile) (bool, error) {
+	if _, err := os.Stat(path); err == nil {
+		return false, nil
+	}
+	tmpl := template.Must(template.New("wiring").Funcs(template.FuncMap{
+		"ident":   ident,
+		"partial": codegen.GeneratorConfig{Project: project}.Partial,
+	}).Parse(wiringTemplate))
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, struct{ Package, TypeName string }{pkgName, typeName}); err != nil {
+		return false, fmt.Errorf("executing template: %w", err)
+	}
+	out, err := format.Source(buf.Bytes())
+	if err != nil {
+		return false, fmt.Errorf("formatting %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, out, 0644); err != nil {
+		return false, fmt.Errorf("writing file: %w", err)
+	}
+	return true, nil
+}
+
+// ident returns the name of a function of typeName, exported if typeName is.
+func ident(verb, typeName, suffix string) string {
+	if ast.IsExported(typeName) {
+		return capitalize(verb) + typeName + suffix
+	}
+	return verb + capitalize(typeName) + suffix
+}
+
+func capitalize(s string) string {
+	r := []rune(s)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
@@ -0,0 +1,28 @@
+package scaffold
+
+// wiringTemplate is the wiring file written by Init with -wiring. It belongs to the
+// user, so it has no generated comment.
+const wiringTemplate = `package {{.Package}}
+
+import "fmt"
+
+// {{ident "load" .TypeName "Layers"}} returns a broker layering the config files at paths over
+// cfg, each on a layer named after its path and above the files before it. Files are
+// decoded strictly, so unknown keys are errors.
+//
+// sudo-gen init wrote this function as a starting point and won't overwrite it: add
+// defaults, environment variables or flags as layers of their own here.
+func {{ident "load" .TypeName "Layers"}}(cfg *{{.TypeName}}, paths ...string) (*{{.TypeName}}LayerBroker, error) {
+	broker := {{ident "new" .TypeName "LayerBroker"}}(cfg)
+	for _, path := range paths {
+		p, err := {{ident "load" (partial .TypeName) "File"}}(path, true)
+		if err != nil {
+			return nil, err
+		}
+		if err := broker.Layer().Named(path).Set(p); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return broker, nil
+}
+`
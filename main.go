@@ -17,27 +17,139 @@
 //
 //	merge    Generate partial types and ApplyPartial methods for config merging
 //	copy     Generate deep copy methods for structs
+//	hash     Generate deterministic Hash methods fingerprinting struct values
+//	canonical  Generate MarshalCanonical methods producing byte-stable JSON
+//	defaults Generate SetDefaults methods and a DefaultConfig-style constructor
+//	options  Generate a NewConfig(opts...) constructor with a WithField option per field
+//	convert  Generate a function converting one struct into another (-to=Target),
+//	         or from a protobuf message (-proto=file.pb.go)
+//	cli      Generate cobra config dump, validate and explain commands for the layer broker
+//	providers  Generate ProvideConfig providers and fx or wire declarations for dependency injection
+//	integrations  Generate adapters binding etcd or Consul KV prefixes to broker layers
+//	flags    Generate a feature-flag overlay for fields tagged sudo:"flag=key"
+//	context  Generate NewContext, FromContext and WithOverrides helpers for request-scoped config
+//	envdoc   Generate a Markdown table of the environment variables bound with env tags
+//	manager  Generate a mutex-guarded config manager with path-based getters, setters and subscriptions
+//	loader   Generate functions decoding partials from JSON, YAML, TOML and HCL files
+//	enum     Generate Parse, IsValid and ValidateEnums for fields restricted to a set of string values
+//	versions Generate version detection, conversion chains and LoadAnyVersion for ConfigV1, ConfigV2, ...
+//	flagvalue  Generate String, Set and Type methods making scalar types flag.Value and pflag.Value
+//	lsp-helper  Serve editor code actions as line-delimited JSON on stdin/stdout
+//	init     Add the recommended directives above a struct and write a sudo-gen.yaml
+//	doctor   Check the directives and generated files of a module, printing fixes
+//	help     Print the flags, struct tags and generated files of a subcommand
+//	version  Print the version of sudo-gen
 //
-// Flags:
+// Flags (a subcommand rejects the flags of other subcommands; see sudo-gen <subcommand> -help):
 //
 //	-type     The name of the struct type (inferred if directive is above the type)
 //	-output   Output directory for generated files (default: same as source)
-//	-package  Package name for generated files (default: same as source)
+//	-package  Package name for generated files (default: same as source; copy and equals generate functions into another package)
 //	-method   For copy: name of the generated method (default: Copy)
+//	-prefix   Prefix of the generated methods, e.g. Gen for GenCopy, GenEqual and GenApplyPartial
+//	-include-unexported  For copy and equals: also process unexported fields
+//	-explain-diff  For equals: also generate Diff and ExplainNotEqual, listing differing fields
+//	-constant-time-secrets  For equals: compare sudo:"secret" string and []byte fields in constant time
+//	-float-epsilon  For equals: compare float fields within a tolerance, with NaN equal to NaN
+//	-redact   For copy: also generate Redacted, a copy with sudo:"secret" fields cleared
+//	-encrypt  For copy: also generate EncryptSecrets and DecryptSecrets, transforming sudo:"secret" fields with a keyring
+//	-with     For copy: also generate With(opts...) and With{Field}(v) returning modified copies
+//	-graph    For copy: copy pointers shared within the struct once and cycles as cycles
+//	-bench    For copy, merge and equals (and layerbroker): also generate _bench_test.go benchmarks
+//	-examples  For copy, merge and layerbroker: also generate _example_test.go godoc examples
+//	-from, -to  For convert: source (default: the -type or directive type) and target types
+//	-bidirectional  For convert: also generate the reverse conversion and a round-trip test
+//	-proto    For convert: protoc-gen-go file to convert messages from (replaces -to)
+//	-sources  For integrations: comma-separated KV stores (etcd, consul)
+//	-frameworks  For providers: comma-separated dependency injection frameworks (fx, wire)
+//	-formats  For loader: comma-separated file formats (json, yaml, toml, hcl; default: json)
+//	-mapstructure  For loader: also generate {Type}DecodeHook and Decode{Type}PartialMap
+//	-extends  For loader: files extend a parent file named by their "extends" key (Load{Type}PartialFileChain)
+//	-expand-env  For loader: expand ${VAR} and ${VAR:-default} in string values (With{Type}EnvLookup)
+//	-secret-refs  For loader: resolve secretref+scheme://path#key string values (With{Type}SecretResolver)
+//	-http     For layerbroker: also generate an http.Handler admin API
+//	-watch    For layerbroker: also generate a file watcher feeding a layer (uses fsnotify)
+//	-sighup   For layerbroker: also generate a SIGHUP handler reloading layers from loader functions
+//	-validate  For options and cli: method validating the config (default: Validate, if declared)
+//	-tenant   For context: also generate HTTP middleware layering per-tenant overrides over a snapshot
+//	-provenance  For layerbroker: Explain, reporting which named layer set each field
+//	-audit    For layerbroker: With{Type}AuditSink, receiving every layer change with the fields it altered
+//	-otel     For layerbroker and integrations: OpenTelemetry spans for merges, notifications and layer loads
+//	-history  For layerbroker: keep the last N merged configs for History and Rollback
+//	-groups   For layerbroker: comma-separated layer groups with a fixed order, lowest first
+//	-dry-run  Print the files that would be written without writing them
+//	-diff     Print a unified diff against existing output without writing it
+//	-force    Rewrite generated files even if their content is unchanged
+//	-strict   Fail on fields of unsupported types instead of skipping them with a warning
+//	-o        Write generated code to stdout with -o - (otherwise same as -output)
+//	-stdin    Read the struct source from stdin instead of $GOFILE (requires -type)
+//	-source-file  With -stdin, the file the source comes from (default: $GOFILE; required with -json-errors)
+//	-json-errors  Report errors on stderr as JSON diagnostics
+//	-build-tags  Build constraint for a //go:build line in every generated file
+//	-header-file  Template file with a license header for every generated file
+//	-generated-comment  Replaces the "Code generated by sudo-gen ... DO NOT EDIT." line
+//	-templates  Directory of templates overriding the embedded ones (e.g. copy.go.tmpl)
+//	-dump-data  Write the data of each template as JSON instead of generating code
+//	-tags     For merge: comma-separated tag keys to emit on partial fields
+//	-tag-source  For merge: tag key that -tags values are derived from (default: json)
+//	-external  For merge: partial, passthrough or error for structs from other packages
+//	-merge-structs  For merge: deep or replace nested struct fields (also: sudo:"merge=replace" tags)
+//	-clear    For merge: partials can reset fields to their zero value (ClearField, JSON null)
+//	-migrate-deprecated  For merge: ApplyPartial sets the replacements of deprecated fields
+//	-merge-patch  For merge: JSON Merge Patch (RFC 7386) methods on the root type
+//	-json-patch  For merge: Diff{Type}AsJSONPatch, a JSON Patch (RFC 6902) between two values
+//	-utc      For merge and equals: store time.Time fields in UTC and compare them all with Equal
+//	-value-types  Comma-separated types to treat as opaque values (in addition to marshalers)
+//	-clone-funcs  For copy and layerbroker: comma-separated Type=Func functions cloning values of types like *money.Amount
+//	-equal-funcs  For equals and layerbroker: comma-separated Type=Func functions comparing values of types
+//	-fields   Comma-separated fields to generate; all others are skipped
+//	-exclude-fields  Comma-separated fields to skip (also: sudo-gen:"-" or sudo-gen:"-merge" tags)
+//	-implements  Comma-separated interfaces the type must implement, asserted in the main generated file
+//
+// Generated files are stamped with the version of sudo-gen and the command line that
+// generated them, e.g. "// Generated by sudo-gen v1.2.0: copy -tests".
+//
+// Settings shared by a project, such as the key renames of the loader, are read
+// from a sudo-gen.yaml file in the source directory or its closest parent.
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"go/token"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime/debug"
 	"strconv"
+	"strings"
 
 	"github.com/bobcob7/sudo-gen/internal/codegen"
+	"github.com/bobcob7/sudo-gen/internal/codegen/canonical"
+	"github.com/bobcob7/sudo-gen/internal/codegen/cli"
+	"github.com/bobcob7/sudo-gen/internal/codegen/context"
+	"github.com/bobcob7/sudo-gen/internal/codegen/convert"
 	"github.com/bobcob7/sudo-gen/internal/codegen/copy"
+	"github.com/bobcob7/sudo-gen/internal/codegen/defaults"
+	"github.com/bobcob7/sudo-gen/internal/codegen/enum"
+	"github.com/bobcob7/sudo-gen/internal/codegen/envdoc"
 	"github.com/bobcob7/sudo-gen/internal/codegen/equals"
+	"github.com/bobcob7/sudo-gen/internal/codegen/flags"
+	"github.com/bobcob7/sudo-gen/internal/codegen/flagvalue"
+	"github.com/bobcob7/sudo-gen/internal/codegen/hash"
+	"github.com/bobcob7/sudo-gen/internal/codegen/integrations"
 	"github.com/bobcob7/sudo-gen/internal/codegen/layerbroker"
+	"github.com/bobcob7/sudo-gen/internal/codegen/loader"
+	"github.com/bobcob7/sudo-gen/internal/codegen/manager"
 	"github.com/bobcob7/sudo-gen/internal/codegen/merge"
+	optionsgen "github.com/bobcob7/sudo-gen/internal/codegen/options"
+	"github.com/bobcob7/sudo-gen/internal/codegen/providers"
+	"github.com/bobcob7/sudo-gen/internal/codegen/versions"
+	"github.com/bobcob7/sudo-gen/internal/doctor"
+	"github.com/bobcob7/sudo-gen/internal/lsphelper"
+	"github.com/bobcob7/sudo-gen/internal/scaffold"
 )
 
 func main() {
@@ -51,65 +163,383 @@ func main() {
 		os.Exit(0)
 	}
 	os.Args = append(os.Args[:1], os.Args[2:]...)
-	var (
-		typeName     string
-		outputDir    string
-		pkgName      string
-		methodName   string
-		generateTest bool
-		generateJSON bool
-	)
-	flag.StringVar(&typeName, "type", "", "Name of the struct type (inferred if directive is above the type)")
-	flag.StringVar(&outputDir, "output", "", "Output directory for generated files (default: same as source)")
-	flag.StringVar(&pkgName, "package", "", "Package name for generated files (default: same as source)")
-	flag.StringVar(&methodName, "method", "Copy", "For copy: name of the generated copy method")
-	flag.BoolVar(&generateTest, "tests", false, "Generate unit tests for the generated code")
-	flag.BoolVar(&generateJSON, "json", false, "For layerbroker: generate JSON marshalling with layer state")
-	flag.Parse()
-	sourceFile := os.Getenv("GOFILE")
-	if sourceFile == "" {
-		fmt.Fprintln(os.Stderr, "error: GOFILE environment variable not set (are you running via go generate?)")
-		os.Exit(1)
+	if subcommand == "lsp-helper" {
+		if err := runLSPHelper(); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		return
 	}
-	sourceDir, err := os.Getwd()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error getting working directory: %v\n", err)
-		os.Exit(1)
+	if subcommand == "init" {
+		if err := runInit(os.Args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		return
 	}
-	if typeName == "" {
-		typeName, err = detectTypeName(subcommand, sourceDir, sourceFile)
+	if subcommand == "doctor" {
+		ok, err := runDoctor(os.Args[1:])
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "error: %v\n", err)
-			fmt.Fprintln(os.Stderr, "hint: use -type=TypeName or place the directive directly above the struct")
+		}
+		if err != nil || !ok {
 			os.Exit(1)
 		}
+		return
+	}
+	if subcommand == "version" {
+		fmt.Println("sudo-gen " + toolVersion())
+		return
 	}
-	if outputDir == "" {
-		outputDir = sourceDir
+	if subcommand == "help" {
+		if err := runHelp(os.Args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		return
 	}
-	sourcePkg := os.Getenv("GOPACKAGE")
-	if pkgName == "" {
-		pkgName = sourcePkg
+	subtool := lookupSubtool(subcommand)
+	if subtool == nil {
+		fmt.Fprintf(os.Stderr, "error: unknown subcommand: %s\nRun 'sudo-gen -help' for usage.\n", subcommand)
+		os.Exit(1)
 	}
-	cfg := codegen.GeneratorConfig{
-		TypeName:     typeName,
-		SourceFile:   sourceFile,
-		SourceDir:    sourceDir,
-		SourcePkg:    sourcePkg,
-		OutputDir:    outputDir,
-		OutputPkg:    pkgName,
-		GenerateTest: generateTest,
-		GenerateJSON: generateJSON,
+	var opts options
+	var cfg codegen.GeneratorConfig
+	fs := newFlagSet(subtool, &opts, &cfg)
+	fs.Parse(os.Args[1:])
+	cfg, err := buildConfig(subcommand, cfg, opts)
+	if err == nil {
+		err = runSubtool(subtool, cfg, opts.outFlag == "-", os.Stdout)
 	}
-	if err := runSubcommand(subcommand, cfg, methodName); err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+	if err != nil {
+		reportError(os.Stderr, err, cfg, opts.jsonErrors)
 		os.Exit(1)
 	}
 }
 
+// runSubtool runs subtool with cfg. With toStdout, the generated file is written to
+// stdout instead: cfg is in ModeCapture, and subcommands generating more than one file
+// are rejected, since their package clauses would follow each other on one stream.
+func runSubtool(subtool codegen.Subtool, cfg codegen.GeneratorConfig, toStdout bool, stdout io.Writer) error {
+	if !toStdout {
+		return subtool.Run(cfg)
+	}
+	var paths []string
+	files := make(map[string][]byte)
+	cfg.Capture = func(path string, content []byte) error {
+		// The shared helpers file is regenerated by each generator needing it
+		if _, ok := files[path]; !ok {
+			paths = append(paths, path)
+		}
+		files[path] = content
+		return nil
+	}
+	if err := subtool.Run(cfg); err != nil {
+		return err
+	}
+	switch len(paths) {
+	case 0:
+		return nil
+	case 1:
+		if _, err := stdout.Write(files[paths[0]]); err != nil {
+			return fmt.Errorf("writing to stdout: %w", err)
+		}
+		return nil
+	}
+	names := make([]string, len(paths))
+	for i, path := range paths {
+		names[i] = filepath.Base(path)
+	}
+	return &hintError{
+		err:  fmt.Errorf("-o - writes a single file to stdout, but %s generates %d: %s", subtool.Name(), len(paths), strings.Join(names, ", ")),
+		hint: "write the files to a directory with -output, or drop the flags generating the extra files, such as -tests",
+	}
+}
+
+// newFlagSet returns the flag set of a subcommand: the shared flags, stored in opts,
+// and those of the subtool, stored in cfg or the subtool.
+func newFlagSet(subtool codegen.Subtool, opts *options, cfg *codegen.GeneratorConfig) *flag.FlagSet {
+	fs := flag.NewFlagSet("sudo-gen "+subtool.Name(), flag.ExitOnError)
+	fs.Usage = func() { printSubtoolUsage(fs, subtool) }
+	defineCommonFlags(fs, opts)
+	if definer, ok := subtool.(codegen.FlagDefiner); ok {
+		definer.DefineFlags(fs, cfg)
+	}
+	return fs
+}
+
+// defineCommonFlags defines the flags shared by every subcommand on fs. Flags of a
+// single subtool are defined by the subtool (see codegen.FlagDefiner).
+func defineCommonFlags(fs *flag.FlagSet, opts *options) {
+	fs.StringVar(&opts.typeName, "type", "", "Name of the struct type (inferred if directive is above the type)")
+	fs.StringVar(&opts.outputDir, "output", "", "Output directory for generated files (default: same as source)")
+	fs.StringVar(&opts.pkgName, "package", "", "Package name for generated files (default: same as source; copy and equals generate functions into another package)")
+	fs.StringVar(&opts.prefix, "prefix", "", "Prefix of the names of generated methods (e.g. Gen for GenCopy, GenEqual and GenApplyPartial)")
+	fs.BoolVar(&opts.generateTest, "tests", false, "Generate unit tests for the generated code")
+	fs.BoolVar(&opts.dryRun, "dry-run", false, "Print the files that would be written without writing them")
+	fs.BoolVar(&opts.showDiff, "diff", false, "Print a unified diff against existing output without writing it")
+	fs.BoolVar(&opts.force, "force", false, "Rewrite generated files even if their content is unchanged")
+	fs.BoolVar(&opts.strict, "strict", false, "Fail on fields of unsupported types (func, chan, arrays, ...) instead of skipping them with a warning")
+	fs.StringVar(&opts.outFlag, "o", "", "Write generated code to stdout with -o - (otherwise same as -output)")
+	fs.BoolVar(&opts.useStdin, "stdin", false, "Read the struct source from stdin instead of $GOFILE (requires -type)")
+	fs.StringVar(&opts.sourceFile, "source-file", "", "With -stdin, the name of the file the source comes from, naming generated files and reported in errors (default: $GOFILE; required with -json-errors outside go generate)")
+	fs.BoolVar(&opts.jsonErrors, "json-errors", false, "Report errors on stderr as JSON diagnostics")
+	fs.StringVar(&opts.buildTags, "build-tags", "", "Build constraint expression for a //go:build line in generated files (e.g. !codeanalysis)")
+	fs.StringVar(&opts.headerFile, "header-file", "", "Template file with a license header for generated files ({{.Year}} and {{.File}} are available)")
+	fs.StringVar(&opts.templatesDir, "templates", "", "Directory of templates overriding the embedded ones, named after the generated files (e.g. copy.go.tmpl for config_copy.go)")
+	fs.BoolVar(&opts.dumpData, "dump-data", false, "Write the data of each template as JSON to stdout instead of generating code")
+	fs.StringVar(&opts.generatedComment, "generated-comment", "", "Generated file comment; must match \"Code generated ... DO NOT EDIT.\" ({generator} is the subcommand)")
+	fs.Var((*codegen.ListFlag)(&opts.valueTypes), "value-types", "Comma-separated types to copy, compare and merge as opaque values (e.g. uuid.UUID,Secret)")
+	fs.Var((*codegen.ListFlag)(&opts.fields), "fields", "Comma-separated fields to generate; others are skipped (Type.Field for nested types)")
+	fs.Var((*codegen.ListFlag)(&opts.excludeFields), "exclude-fields", "Comma-separated fields to skip (Type.Field for nested types)")
+	fs.Var((*codegen.ListFlag)(&opts.implements), "implements", "Comma-separated interfaces the type must implement, asserted at compile time (e.g. io.Closer,Snapshotter)")
+}
+
+// options holds the parsed command-line flags.
+type options struct {
+	typeName         string
+	outputDir        string
+	pkgName          string
+	prefix           string
+	generateTest     bool
+	dryRun           bool
+	showDiff         bool
+	force            bool
+	strict           bool
+	outFlag          string
+	useStdin         bool
+	sourceFile       string
+	jsonErrors       bool
+	buildTags        string
+	headerFile       string
+	generatedComment string
+	templatesDir     string
+	dumpData         bool
+	valueTypes       []string
+	fields           []string
+	excludeFields    []string
+	implements       []string
+}
+
+// hintError is an error with a suggestion for how to fix it.
+type hintError struct {
+	err  error
+	hint string
+}
+
+func (e *hintError) Error() string { return e.err.Error() }
+
+func (e *hintError) Unwrap() error { return e.err }
+
+// buildConfig resolves the generator configuration from flags and the go generate environment.
+// cfg holds the values of the subtool's own flags. The returned config is populated as
+// far as resolution got, even on error, so that errors can be reported against the
+// source file and type.
+func buildConfig(subcommand string, cfg codegen.GeneratorConfig, opts options) (codegen.GeneratorConfig, error) {
+	cfg.SourceFile = os.Getenv("GOFILE")
+	cfg.SourcePkg = os.Getenv("GOPACKAGE")
+	cfg, err := applyOptions(subcommand, cfg, opts, os.Args[1:])
+	if err != nil {
+		return cfg, err
+	}
+	if opts.sourceFile != "" && !opts.useStdin {
+		return cfg, errors.New("-source-file requires -stdin")
+	}
+	if opts.useStdin {
+		if cfg.TypeName == "" {
+			return cfg, errors.New("-stdin requires -type")
+		}
+		if opts.sourceFile != "" {
+			cfg.SourceFile = opts.sourceFile
+		}
+		if cfg.SourceFile == "" && opts.jsonErrors {
+			return cfg, errors.New("-stdin with -json-errors requires -source-file, naming the file the diagnostics refer to")
+		}
+		cfg.Source, cfg.SourceFile, cfg.SourcePkg, err = readStdinSource(cfg.TypeName, cfg.SourceFile, cfg.SourcePkg)
+		if err != nil {
+			return cfg, err
+		}
+	}
+	if cfg.SourceFile == "" {
+		return cfg, errors.New("GOFILE environment variable not set (are you running via go generate?)")
+	}
+	sourceDir, err := os.Getwd()
+	if err != nil {
+		return cfg, fmt.Errorf("getting working directory: %w", err)
+	}
+	return resolveConfig(subcommand, cfg, opts, sourceDir)
+}
+
+// applyOptions sets the fields of cfg given by the flags in opts, parsed from args.
+func applyOptions(subcommand string, cfg codegen.GeneratorConfig, opts options, args []string) (codegen.GeneratorConfig, error) {
+	cfg.OutputDir = opts.outputDir
+	cfg.OutputPkg = opts.pkgName
+	cfg.GenerateTest = opts.generateTest
+	cfg.ValueTypes = opts.valueTypes
+	cfg.Fields = opts.fields
+	cfg.ExcludeFields = opts.excludeFields
+	cfg.MethodPrefix = opts.prefix
+	cfg.Index = codegen.NewPackageIndex()
+	cfg.Banner = codegen.Banner{
+		BuildTags:        opts.buildTags,
+		GeneratedComment: opts.generatedComment,
+		Stamp:            codegen.Stamp{Version: toolVersion(), Command: codegen.CommandLine(subcommand, args)},
+	}
+	if opts.typeName != "" {
+		// convert's -from sets the type too
+		if cfg.TypeName != "" && cfg.TypeName != opts.typeName {
+			return cfg, errors.New("-from and -type name different types")
+		}
+		cfg.TypeName = opts.typeName
+	}
+	if _, err := codegen.NewCloneTypes(cfg.CloneFuncs, cfg.EqualFuncs); err != nil {
+		return cfg, err
+	}
+	toStdout := opts.outFlag == "-"
+	if boolCount(opts.dryRun, opts.showDiff, toStdout, opts.dumpData) > 1 {
+		return cfg, errors.New("-dry-run, -diff, -o - and -dump-data are mutually exclusive")
+	}
+	cfg.Mode = outputMode(opts.dryRun, opts.showDiff, toStdout, opts.dumpData)
+	cfg.Force = opts.force
+	cfg.Strict = opts.strict
+	if opts.outFlag != "" && !toStdout {
+		cfg.OutputDir = opts.outFlag
+	}
+	return cfg, nil
+}
+
+// resolveConfig completes cfg for the source file cfg names in sourceDir: it reads
+// the sudo-gen.yaml settings, resolves the type and output, and validates the result.
+func resolveConfig(subcommand string, cfg codegen.GeneratorConfig, opts options, sourceDir string) (codegen.GeneratorConfig, error) {
+	var err error
+	cfg.SourceDir = sourceDir
+	if cfg.Project, err = codegen.FindProjectFile(sourceDir); err != nil {
+		return cfg, err
+	}
+	cfg.TemplatesDir = opts.templatesDir
+	if cfg.TemplatesDir == "" && cfg.Project.Templates != "" {
+		cfg.TemplatesDir = filepath.Join(filepath.Dir(cfg.Project.Path), cfg.Project.Templates)
+	}
+	if cfg.TemplatesDir != "" {
+		if info, err := os.Stat(cfg.TemplatesDir); err != nil || !info.IsDir() {
+			return cfg, fmt.Errorf("templates directory %s doesn't exist", cfg.TemplatesDir)
+		}
+	}
+	if cfg.TypeName == "" {
+		cfg.TypeName, err = detectTypeName(subcommand, sourceDir, cfg.SourceFile)
+		if err != nil {
+			return cfg, &hintError{err: err, hint: "use -type=TypeName or place the directive directly above the struct"}
+		}
+	}
+	if cfg.OutputDir == "" {
+		cfg.OutputDir = sourceDir
+	}
+	if cfg.OutputPkg == "" {
+		cfg.OutputPkg = cfg.SourcePkg
+	}
+	if documenter, ok := lookupSubtool(subcommand).(codegen.Documenter); ok {
+		cfg.MainFile = documenter.Doc().MainFile(cfg)
+	}
+	if cfg.Implements, err = newImplements(subcommand, cfg, opts.implements); err != nil {
+		return cfg, err
+	}
+	if opts.headerFile != "" {
+		header, err := os.ReadFile(opts.headerFile)
+		if err != nil {
+			return cfg, fmt.Errorf("reading header file: %w", err)
+		}
+		cfg.Banner.Header = string(header)
+	}
+	if err := cfg.Banner.Validate(); err != nil {
+		return cfg, err
+	}
+	if cfg.IncludeUnexported && cfg.CrossPackage() {
+		return cfg, errors.New("-include-unexported requires generating into the source package")
+	}
+	if cfg.CrossPackage() && !crossPackage[subcommand] {
+		return cfg, &hintError{
+			err:  fmt.Errorf("%s must generate into package %s, the package of %s, not %s", subcommand, cfg.SourcePkg, cfg.TypeName, cfg.OutputPkg),
+			hint: "drop -package; only copy, equals and envdoc generate into other packages",
+		}
+	}
+	if cfg.MethodPrefix != "" && (!token.IsIdentifier(cfg.MethodPrefix) || !token.IsExported(cfg.MethodPrefix)) {
+		return cfg, fmt.Errorf("-prefix %q must be an exported identifier, like Gen", cfg.MethodPrefix)
+	}
+	return cfg, nil
+}
+
+// reportError writes err to w, either as plain text or as JSON diagnostics.
+func reportError(w io.Writer, err error, cfg codegen.GeneratorConfig, asJSON bool) {
+	var hint string
+	var he *hintError
+	if errors.As(err, &he) {
+		hint = he.hint
+	}
+	if asJSON {
+		diags := codegen.Diagnostics(err, cfg)
+		for i := range diags {
+			diags[i].Hint = hint
+		}
+		enc := json.NewEncoder(w)
+		if encErr := enc.Encode(struct {
+			Errors []codegen.Diagnostic `json:"errors"`
+		}{diags}); encErr == nil {
+			return
+		}
+	}
+	fmt.Fprintf(w, "error: %v\n", err)
+	if hint != "" {
+		fmt.Fprintf(w, "hint: %s\n", hint)
+	}
+}
+
+func outputMode(dryRun, showDiff, toStdout, dumpData bool) codegen.OutputMode {
+	switch {
+	case dumpData:
+		return codegen.ModeDumpData
+	case dryRun:
+		return codegen.ModeDryRun
+	case showDiff:
+		return codegen.ModeDiff
+	case toStdout:
+		return codegen.ModeCapture // See runSubtool
+	default:
+		return codegen.ModeWrite
+	}
+}
+
+func boolCount(values ...bool) int {
+	n := 0
+	for _, v := range values {
+		if v {
+			n++
+		}
+	}
+	return n
+}
+
+// readStdinSource reads struct source from stdin. When neither go generate nor
+// -source-file names the source file, generated files are named after the type, and
+// the package is taken from the package clause.
+func readStdinSource(typeName, sourceFile, sourcePkg string) ([]byte, string, string, error) {
+	source, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("reading stdin: %w", err)
+	}
+	if sourceFile == "" {
+		sourceFile = strings.ToLower(typeName) + ".go"
+	}
+	if sourcePkg == "" {
+		sourcePkg, err = codegen.ParsePackageName(source)
+		if err != nil {
+			return nil, "", "", err
+		}
+	}
+	return source, sourceFile, sourcePkg, nil
+}
+
 func detectTypeName(subcommand, sourceDir, sourceFile string) (string, error) {
-	generatorName := "sudo-gen " + subcommand
-	typeName, err := codegen.FindTypeAfterGenerateDirective(sourceDir, sourceFile, generatorName)
+	typeName, err := codegen.FindTypeAfterGenerateDirective(sourceDir, sourceFile, subcommand)
 	if err == nil {
 		return typeName, nil
 	}
@@ -123,30 +553,311 @@ func detectTypeName(subcommand, sourceDir, sourceFile string) (string, error) {
 	return "", err
 }
 
-func runSubcommand(name string, cfg codegen.GeneratorConfig, methodName string) error {
-	switch name {
-	case "merge":
-		subtool := &merge.Subtool{}
-		return subtool.Run(cfg)
-	case "copy":
-		subtool := &copy.Subtool{MethodName: methodName}
-		return subtool.Run(cfg)
-	case "layerbroker":
-		subtool := &layerbroker.Subtool{}
-		return subtool.Run(cfg)
-	case "equals":
-		eqMethodName := methodName
-		if eqMethodName == "Copy" {
-			eqMethodName = "Equal"
+// crossPackage lists the subcommands that can generate into another package than the
+// source types (-package): copy and equals generate functions instead of methods there.
+var crossPackage = map[string]bool{"copy": true, "equals": true, "envdoc": true}
+
+// generatorNames lists the subcommands that generate code, in the order they are offered to editors.
+var generatorNames = []string{"copy", "merge", "equals", "hash", "canonical", "defaults", "layerbroker"}
+
+// runLSPHelper serves editor code action requests on stdin/stdout.
+func runLSPHelper() error {
+	server := &lsphelper.Server{
+		Generators: generatorNames,
+		Version:    toolVersion(),
+		Run: func(name string, args []string, src codegen.GeneratorConfig) error {
+			subtool := lookupSubtool(name)
+			if subtool == nil {
+				return fmt.Errorf("unknown subcommand: %s", name)
+			}
+			cfg, err := lspConfig(subtool, args, src)
+			if err != nil {
+				return err
+			}
+			return subtool.Run(cfg)
+		},
+	}
+	return server.Serve(os.Stdin, os.Stdout)
+}
+
+// lspConfig builds the config go generate would run subtool with for a directive
+// with args above the type of src, emitting the files in the mode of src.
+func lspConfig(subtool codegen.Subtool, args []string, src codegen.GeneratorConfig) (codegen.GeneratorConfig, error) {
+	var opts options
+	var cfg codegen.GeneratorConfig
+	fs := newFlagSet(subtool, &opts, &cfg)
+	fs.Init(fs.Name(), flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if err := fs.Parse(args); err != nil {
+		return cfg, fmt.Errorf("%s %s: %w", subtool.Name(), strings.Join(args, " "), err)
+	}
+	if opts.typeName == "" {
+		opts.typeName = src.TypeName
+	}
+	// go generate runs directives in the source directory, which paths are relative to
+	for _, path := range []*string{&opts.outputDir, &opts.outFlag, &opts.headerFile, &opts.templatesDir} {
+		if *path != "" && *path != "-" && !filepath.IsAbs(*path) {
+			*path = filepath.Join(src.SourceDir, *path)
 		}
-		subtool := &equals.Subtool{MethodName: eqMethodName}
-		return subtool.Run(cfg)
-	default:
-		return fmt.Errorf("unknown subcommand: %s", name)
 	}
+	cfg.SourceFile, cfg.SourcePkg, cfg.Source = src.SourceFile, src.SourcePkg, src.Source
+	cfg, err := applyOptions(subtool.Name(), cfg, opts, args)
+	if err != nil {
+		return cfg, err
+	}
+	cfg, err = resolveConfig(subtool.Name(), cfg, opts, src.SourceDir)
+	cfg.Mode, cfg.Capture = src.Mode, src.Capture
+	return cfg, err
+}
+
+// subtools lists the subcommands that generate code, in the order of the usage text.
+var subtools = []codegen.Subtool{
+	&merge.Subtool{},
+	&copy.Subtool{},
+	&equals.Subtool{},
+	&hash.Subtool{},
+	&canonical.Subtool{},
+	&defaults.Subtool{},
+	&optionsgen.Subtool{},
+	&convert.Subtool{},
+	&layerbroker.Subtool{},
+	&integrations.Subtool{},
+	&providers.Subtool{},
+	&cli.Subtool{},
+	&flags.Subtool{},
+	&context.Subtool{},
+	&envdoc.Subtool{},
+	&manager.Subtool{},
+	&loader.Subtool{},
+	&enum.Subtool{},
+	&versions.Subtool{},
+	&flagvalue.Subtool{},
 }
 
+// newImplements returns the -implements assertions of cfg, in its main file.
+func newImplements(subcommand string, cfg codegen.GeneratorConfig, entries []string) (codegen.Implements, error) {
+	if len(entries) == 0 {
+		return codegen.Implements{}, nil
+	}
+	if cfg.MainFile == "" {
+		return codegen.Implements{}, fmt.Errorf("-implements: %s generates no Go file to assert in", subcommand)
+	}
+	return codegen.NewImplements(cfg, cfg.MainFile, entries)
+}
+
+// lookupSubtool returns the subtool named name, or nil if there is none.
+func lookupSubtool(name string) codegen.Subtool {
+	for _, subtool := range subtools {
+		if subtool.Name() == name {
+			return subtool
+		}
+	}
+	return nil
+}
+
+// runInit sets up a struct for the layered-config pattern (see scaffold.Init).
+func runInit(args []string) error {
+	fs := flag.NewFlagSet("sudo-gen init", flag.ExitOnError)
+	opts := scaffold.Options{Version: toolVersion()}
+	fs.StringVar(&opts.File, "file", os.Getenv("GOFILE"), "Go file declaring the struct (default: $GOFILE)")
+	fs.StringVar(&opts.TypeName, "type", "", "Name of the struct type (default: the first struct of the file)")
+	invocation := fs.String("invocation", "", "How directives run sudo-gen: binary, tool or run (default: the invocation of sudo-gen.yaml, or binary)")
+	fs.BoolVar(&opts.Wiring, "wiring", false, "Also write {source}_wiring.go, loading config files into the layers of a broker")
+	fs.Parse(args)
+	if opts.File == "" {
+		return errors.New("-file is required outside of go generate")
+	}
+	if *invocation == "" {
+		project, err := codegen.FindProjectFile(filepath.Dir(opts.File))
+		if err != nil {
+			return err
+		}
+		*invocation = string(project.Invocation)
+	}
+	var err error
+	if opts.Invocation, err = codegen.ParseInvocation(*invocation); err != nil {
+		return err
+	}
+	changes, err := scaffold.Init(opts)
+	if err != nil {
+		return err
+	}
+	for _, change := range changes {
+		if change.Created {
+			fmt.Printf("Created: %s\n", change.Path)
+		} else {
+			fmt.Printf("Updated: %s\n", change.Path)
+		}
+	}
+	if opts.Invocation == codegen.InvokeTool {
+		fmt.Printf("Run 'go get -tool %s' to add sudo-gen to go.mod, then 'go generate'.\n", codegen.ModulePath)
+	} else {
+		fmt.Println("Run 'go generate' to generate the code.")
+	}
+	return nil
+}
+
+// runDoctor checks the packages matched by its argument, a directory or a dir/...
+// pattern (default ./...), and prints the problems found. It reports whether there
+// were none.
+func runDoctor(args []string) (bool, error) {
+	fs := flag.NewFlagSet("sudo-gen doctor", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "Print the problems as JSON diagnostics")
+	fs.Parse(args)
+	pattern := "./..."
+	if fs.NArg() > 0 {
+		pattern = fs.Arg(0)
+	}
+	dir, recursive := strings.CutSuffix(pattern, "/...")
+	var names []string
+	for _, subtool := range subtools {
+		names = append(names, subtool.Name())
+	}
+	d := &doctor.Doctor{Subcommands: names, Check: checkDirective, Version: toolVersion()}
+	diags, err := d.Run(filepath.Clean(dir), recursive)
+	if err != nil {
+		return false, err
+	}
+	if *asJSON {
+		if diags == nil {
+			diags = []codegen.Diagnostic{}
+		}
+		err := json.NewEncoder(os.Stdout).Encode(struct {
+			Errors []codegen.Diagnostic `json:"errors"`
+		}{diags})
+		return len(diags) == 0, err
+	}
+	for _, diag := range diags {
+		switch {
+		case diag.File == "":
+			fmt.Printf("%s\n", diag.Message)
+		case diag.Line == 0:
+			fmt.Printf("%s: %s\n", diag.File, diag.Message)
+		default:
+			fmt.Printf("%s:%d: %s\n", diag.File, diag.Line, diag.Message)
+		}
+		fmt.Printf("\thint: %s\n", diag.Hint)
+	}
+	if len(diags) == 0 {
+		fmt.Println("No problems found.")
+	} else {
+		fmt.Printf("%d problems found.\n", len(diags))
+	}
+	return len(diags) == 0, nil
+}
+
+// checkDirective parses the arguments of a directive running subcommand like
+// sudo-gen would, returning the type they name with -type (or convert's -from).
+func checkDirective(subcommand string, args []string) (string, error) {
+	var opts options
+	var cfg codegen.GeneratorConfig
+	fs := newFlagSet(lookupSubtool(subcommand), &opts, &cfg)
+	fs.Init(fs.Name(), flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+	if fs.NArg() > 0 {
+		return "", fmt.Errorf("unexpected argument %q; flags come after the subcommand", fs.Arg(0))
+	}
+	if opts.typeName != "" {
+		return opts.typeName, nil
+	}
+	return cfg.TypeName, nil
+}
+
+// version is the version of sudo-gen, set at build time with
+// -ldflags "-X main.version=v1.2.0". Without it, the module version is used.
+var version string
+
+// toolVersion returns the version of sudo-gen stamped into generated files.
+func toolVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
+
+// runHelp prints the usage of the subcommand named by args, or of sudo-gen without one.
+func runHelp(args []string) error {
+	if len(args) == 0 {
+		printUsage()
+		return nil
+	}
+	subtool := lookupSubtool(args[0])
+	if subtool == nil {
+		return fmt.Errorf("unknown subcommand: %s", args[0])
+	}
+	fs := newFlagSet(subtool, new(options), new(codegen.GeneratorConfig))
+	fs.SetOutput(os.Stdout)
+	printSubtoolUsage(fs, subtool)
+	return nil
+}
+
+// printSubtoolUsage prints the usage of a subcommand with the flags defined on fs, and
+// the struct tags and files documented by the subtool, to the output of fs.
+func printSubtoolUsage(fs *flag.FlagSet, subtool codegen.Subtool) {
+	w := fs.Output()
+	fmt.Fprintf(w, "sudo-gen %s - %s\n\nUsage:\n  //go:generate sudo-gen %s [flags]\n\nFlags:\n", subtool.Name(), subtool.Description(), subtool.Name())
+	fs.PrintDefaults()
+	documenter, ok := subtool.(codegen.Documenter)
+	if !ok {
+		return
+	}
+	doc := documenter.Doc()
+	if len(doc.Tags) > 0 {
+		fmt.Fprintf(w, "\nStruct tags:\n")
+		printDocEntries(w, "  ", doc.Tags)
+	}
+	if len(doc.Files) > 0 {
+		fmt.Fprintf(w, "\nGenerated files:\n")
+		printDocEntries(w, "  ", doc.Files)
+	}
+}
+
+// printDocEntries writes entries as a list with aligned descriptions, wrapped to fit
+// the usage text.
+func printDocEntries(w io.Writer, indent string, entries []codegen.DocEntry) {
+	const nameWidth, lineWidth = 24, 92
+	margin := strings.Repeat(" ", len(indent)+nameWidth+3)
+	for _, entry := range entries {
+		line := fmt.Sprintf("%s%-*s -", indent, nameWidth, entry.Name)
+		for _, word := range strings.Fields(entry.Description) {
+			if len(line)+1+len(word) > lineWidth && len(line) > len(margin) {
+				fmt.Fprintln(w, line)
+				line = margin[:len(margin)-1]
+			}
+			line += " " + word
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+// printUsage prints the usage of sudo-gen, with the flags defined by the flag sets of
+// the subcommands and the files they document.
 func printUsage() {
+	var list, common, own, files strings.Builder
+	shared := flag.NewFlagSet("sudo-gen", flag.ContinueOnError)
+	shared.SetOutput(&common)
+	defineCommonFlags(shared, new(options))
+	shared.PrintDefaults()
+	for _, subtool := range subtools {
+		fmt.Fprintf(&list, "  %-12s %s\n", subtool.Name(), subtool.Description())
+		if definer, ok := subtool.(codegen.FlagDefiner); ok {
+			fs := flag.NewFlagSet("sudo-gen "+subtool.Name(), flag.ContinueOnError)
+			fs.SetOutput(&own)
+			definer.DefineFlags(fs, new(codegen.GeneratorConfig))
+			fmt.Fprintf(&own, "\n%s:\n", subtool.Name())
+			fs.PrintDefaults()
+		}
+		if documenter, ok := subtool.(codegen.Documenter); ok {
+			fmt.Fprintf(&files, "  %s:\n", subtool.Name())
+			printDocEntries(&files, "    ", documenter.Doc().Files)
+		}
+	}
 	fmt.Fprintf(os.Stderr, `sudo-gen - Unified code generation tool for Go structs
 
 Usage:
@@ -154,45 +865,51 @@ Usage:
   type Config struct { ... }
 
 Subcommands:
-  merge        Generate partial types and ApplyPartial methods for config merging
-  copy         Generate deep copy methods for structs
-  equals       Generate type-safe equality comparison methods for structs
-  layerbroker  Generate thread-safe LayerBroker with ordered layers and subscriptions
+%s  lsp-helper   Serve editor code actions as line-delimited JSON on stdin/stdout
+  init         Add the recommended directives above a struct and write a sudo-gen.yaml
+  doctor       Check the directives and generated files of a module, printing fixes
+  help         Print the flags, struct tags and generated files of a subcommand
+  version      Print the version of sudo-gen
 
 Examples:
   //go:generate sudo-gen merge
   //go:generate sudo-gen copy
   //go:generate sudo-gen equals
+  //go:generate sudo-gen hash
+  //go:generate sudo-gen canonical
+  //go:generate sudo-gen defaults
+  //go:generate sudo-gen options -validate=ValidateEnums
+  //go:generate sudo-gen convert -to=Config
+  //go:generate sudo-gen convert -proto=pb/config.pb.go -type=Config
+  //go:generate sudo-gen integrations -sources=etcd,consul
+  //go:generate sudo-gen providers -frameworks=fx,wire
+  //go:generate sudo-gen cli
+  //go:generate sudo-gen manager
+  //go:generate sudo-gen loader -formats=json,yaml,toml
+  //go:generate sudo-gen enum
+  //go:generate sudo-gen versions -type=ConfigV2
+  //go:generate sudo-gen flagvalue
+  //go:generate sudo-gen flagvalue -type=Port
+  //go:generate sudo-gen context
+  //go:generate sudo-gen envdoc
+  //go:generate sudo-gen flags
   //go:generate sudo-gen merge -type=Config
   //go:generate sudo-gen copy -method=Clone
   //go:generate sudo-gen equals -method=Equals
+  //go:generate sudo-gen layerbroker -prefix=Gen
+  //go:generate sudo-gen copy -include-unexported
+  //go:generate sudo-gen layerbroker -tests -bench
 
-Flags:
-  -type string
-        Name of the struct type (inferred if directive is above the type)
-  -output string
-        Output directory for generated files (default: same as source)
-  -package string
-        Package name for generated files (default: same as source)
-  -method string
-        For copy: name of the generated copy method (default: Copy)
-  -tests
-        Generate unit tests for the generated code
-  -json
-        For layerbroker: generate JSON marshalling with layer state
-  -help
-        Show this help message
-
+Flags shared by every subcommand:
+%s
+Flags of a single subcommand (a subcommand rejects the flags of the others; sudo-gen
+help <subcommand> also lists the struct tags it honors):
+%s
 Generated Files:
-  merge:
-    {source}_partial.go      - Partial version of the type with pointer fields
-    {source}_merge.go        - ApplyPartial method for merging partials
-  copy:
-    {type}_copy.go           - Deep copy method for the struct
-  equals:
-    {source}_equals.go       - Type-safe Equal method for the struct
-  layerbroker:
-    {source}_layerbroker.go  - Thread-safe LayerBroker with Layer() and Subscribe methods
-
-`)
+%s  shared:
+    zz_sudogen_helpers.go    - Helper functions shared by the generated files of the
+                               package, declared once (and zz_sudogen_helpers_test.go
+                               for those of generated tests)
+
+`, list.String(), common.String(), own.String(), files.String())
 }
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bobcob7/sudo-gen/internal/codegen"
+)
+
+func TestRunSubtoolStdout(t *testing.T) {
+	dir := t.TempDir()
+	src := "package demo\n\ntype Config struct {\n\tName string\n\tTags []string\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "config.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		subcommand string
+		args       []string
+		wantErr    string
+	}{
+		{subcommand: "copy"},
+		{subcommand: "copy", args: []string{"-tests"}, wantErr: "copy generates 2: config_copy.go, config_copy_test.go"},
+		{subcommand: "merge", wantErr: "merge generates 2: config_partial.go, config_merge.go"},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(append([]string{tt.subcommand}, tt.args...), " "), func(t *testing.T) {
+			subtool := lookupSubtool(tt.subcommand)
+			var opts options
+			var cfg codegen.GeneratorConfig
+			fs := newFlagSet(subtool, &opts, &cfg)
+			if err := fs.Parse(append([]string{"-type=Config", "-o", "-"}, tt.args...)); err != nil {
+				t.Fatal(err)
+			}
+			cfg.SourceFile, cfg.SourcePkg = "config.go", "demo"
+			cfg, err := applyOptions(tt.subcommand, cfg, opts, nil)
+			if err == nil {
+				cfg, err = resolveConfig(tt.subcommand, cfg, opts, dir)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var stdout bytes.Buffer
+			err = runSubtool(subtool, cfg, opts.outFlag == "-", &stdout)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("runSubtool() error = %v, want %q", err, tt.wantErr)
+				}
+				if stdout.Len() != 0 {
+					t.Errorf("runSubtool() wrote %d bytes to stdout after failing", stdout.Len())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := strings.Count(stdout.String(), "\npackage demo\n"); n != 1 {
+				t.Errorf("stdout holds %d package clauses, want 1:\n%s", n, stdout.String())
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Errorf("%d files in the source directory after writing to stdout, want 1", len(entries))
+			}
+		})
+	}
+}
+
+func TestBuildConfigStdinSourceFile(t *testing.T) {
+	src := "package demo\n\ntype Config struct {\n\tName string\n}\n"
+	tests := []struct {
+		name     string
+		args     []string
+		wantFile string
+		wantErr  string
+	}{
+		{name: "named after the type", args: []string{"-stdin"}, wantFile: "config.go"},
+		{name: "named by -source-file", args: []string{"-stdin", "-source-file=settings.go"}, wantFile: "settings.go"},
+		{name: "json errors", args: []string{"-stdin", "-json-errors", "-source-file=settings.go"}, wantFile: "settings.go"},
+		{name: "json errors without a file", args: []string{"-stdin", "-json-errors"}, wantErr: "-stdin with -json-errors requires -source-file"},
+		{name: "source file without stdin", args: []string{"-source-file=settings.go"}, wantErr: "-source-file requires -stdin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			t.Setenv("GOFILE", "")
+			t.Setenv("GOPACKAGE", "")
+			stdin, err := os.CreateTemp(t.TempDir(), "stdin")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := stdin.WriteString(src); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := stdin.Seek(0, 0); err != nil {
+				t.Fatal(err)
+			}
+			defer func(orig *os.File) { os.Stdin = orig }(os.Stdin)
+			os.Stdin = stdin
+			subtool := lookupSubtool("copy")
+			var opts options
+			var cfg codegen.GeneratorConfig
+			fs := newFlagSet(subtool, &opts, &cfg)
+			if err := fs.Parse(append([]string{"-type=Config"}, tt.args...)); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err = buildConfig("copy", cfg, opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("buildConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.SourceFile != tt.wantFile {
+				t.Errorf("SourceFile = %q, want %q", cfg.SourceFile, tt.wantFile)
+			}
+		})
+	}
+}